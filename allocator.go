@@ -0,0 +1,64 @@
+package osm
+
+import "sync/atomic"
+
+// IDAllocator hands out the negative placeholder ids used for new
+// elements in an osmChange upload. The server replaces them with real ids,
+// see DiffResult. It is safe for concurrent use and the zero value is
+// ready to use, with the first id being -1.
+type IDAllocator struct {
+	last int64
+}
+
+// NewIDAllocator returns an allocator where the next id is start.
+// Start must be negative, if not -1 is used.
+func NewIDAllocator(start int64) *IDAllocator {
+	if start >= 0 {
+		start = -1
+	}
+
+	return &IDAllocator{last: start + 1}
+}
+
+// Next returns the next negative id. The ids are unique across
+// all the element types.
+func (a *IDAllocator) Next() int64 {
+	return atomic.AddInt64(&a.last, -1)
+}
+
+// NewNode returns a new node with the next id.
+func (a *IDAllocator) NewNode(lat, lon float64, tags Tags) *Node {
+	return &Node{
+		ID:      NodeID(a.Next()),
+		Lat:     lat,
+		Lon:     lon,
+		Visible: true,
+		Tags:    tags,
+	}
+}
+
+// NewWay returns a new way with the next id containing the nodes.
+func (a *IDAllocator) NewWay(tags Tags, nodes ...NodeID) *Way {
+	w := &Way{
+		ID:      WayID(a.Next()),
+		Visible: true,
+		Tags:    tags,
+		Nodes:   make(WayNodes, 0, len(nodes)),
+	}
+
+	for _, id := range nodes {
+		w.Nodes = append(w.Nodes, WayNode{ID: id})
+	}
+
+	return w
+}
+
+// NewRelation returns a new relation with the next id and the members.
+func (a *IDAllocator) NewRelation(tags Tags, members ...Member) *Relation {
+	return &Relation{
+		ID:      RelationID(a.Next()),
+		Visible: true,
+		Tags:    tags,
+		Members: Members(members),
+	}
+}
@@ -0,0 +1,65 @@
+package osm
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestIDAllocator(t *testing.T) {
+	var a IDAllocator
+	if id := a.Next(); id != -1 {
+		t.Errorf("incorrect first id: %v", id)
+	}
+
+	a = *NewIDAllocator(-10)
+	n := a.NewNode(1, 2, Tags{{Key: "amenity", Value: "cafe"}})
+	if n.ID != -10 || !n.Visible {
+		t.Errorf("incorrect node: %+v", n)
+	}
+
+	w := a.NewWay(nil, n.ID, -5)
+	if w.ID != -11 || len(w.Nodes) != 2 || w.Nodes[1].ID != -5 {
+		t.Errorf("incorrect way: %+v", w)
+	}
+
+	r := a.NewRelation(nil, Member{Type: TypeWay, Ref: int64(w.ID)})
+	if r.ID != -12 || len(r.Members) != 1 {
+		t.Errorf("incorrect relation: %+v", r)
+	}
+
+	if id := NewIDAllocator(5).Next(); id != -1 {
+		t.Errorf("positive start should use -1: %v", id)
+	}
+}
+
+func TestIDAllocator_concurrent(t *testing.T) {
+	a := NewIDAllocator(-1)
+
+	var (
+		wg   sync.WaitGroup
+		lk   sync.Mutex
+		seen = make(map[int64]bool)
+	)
+
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				id := a.Next()
+
+				lk.Lock()
+				if seen[id] {
+					t.Errorf("duplicate id: %v", id)
+				}
+				seen[id] = true
+				lk.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(seen) != 1000 {
+		t.Errorf("incorrect number of ids: %v", len(seen))
+	}
+}
@@ -0,0 +1,175 @@
+package annotate
+
+import (
+	"context"
+
+	"github.com/paulmach/osm"
+	"github.com/paulmach/osm/annotate/internal/core"
+)
+
+// AugmentedDiff builds an augmented diff, like the ones from the overpass
+// api, from a change, e.g. a replication osc file, and a base datasource
+// with the data before the change. Every modify and delete gets an old and
+// new version, see Change. In addition the way nodes and node members of the
+// old and new versions are annotated with their locations, so the geometry
+// before and after can be compared without depending on overpass.
+//
+// The old locations are the latest versions in the base, before the version
+// in the change if the node is also changed. The new locations are from the
+// change, or the base if the node did not change. Ways and relations only
+// affected through their nodes changing are not included.
+// The elements in the change are modified, the base data is not.
+func AugmentedDiff(
+	ctx context.Context,
+	change *osm.Change,
+	base osm.HistoryDatasourcer,
+	opts ...Option,
+) (*osm.Diff, error) {
+	computeOpts := &core.Options{}
+	for _, o := range opts {
+		err := o(computeOpts)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	diff, err := Change(ctx, change, base, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	l := &locator{
+		ctx:           ctx,
+		base:          base,
+		changed:       make(map[osm.NodeID]*osm.Node),
+		ignoreMissing: computeOpts.IgnoreMissingChildren,
+	}
+
+	for _, o := range []*osm.OSM{change.Create, change.Modify, change.Delete} {
+		if o == nil {
+			continue
+		}
+
+		for _, n := range o.Nodes {
+			if c := l.changed[n.ID]; c == nil || c.Version < n.Version {
+				l.changed[n.ID] = n
+			}
+		}
+	}
+
+	for _, a := range diff.Actions {
+		if a.Old != nil {
+			// copy so the base data is not modified
+			for i, w := range a.Old.Ways {
+				a.Old.Ways[i] = w.Copy()
+			}
+
+			for i, r := range a.Old.Relations {
+				a.Old.Relations[i] = r.Copy()
+			}
+
+			if err := l.annotate(a.Old, false); err != nil {
+				return nil, err
+			}
+		}
+
+		if a.New != nil {
+			if err := l.annotate(a.New, true); err != nil {
+				return nil, err
+			}
+		}
+
+		if a.OSM != nil {
+			if err := l.annotate(a.OSM, true); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return diff, nil
+}
+
+// locator finds the old and new locations of nodes.
+type locator struct {
+	ctx           context.Context
+	base          osm.HistoryDatasourcer
+	changed       map[osm.NodeID]*osm.Node
+	ignoreMissing bool
+}
+
+func (l *locator) annotate(o *osm.OSM, new bool) error {
+	for _, w := range o.Ways {
+		for i, wn := range w.Nodes {
+			n, err := l.node(wn.ID, new)
+			if err != nil {
+				return err
+			}
+
+			if n == nil {
+				continue
+			}
+
+			w.Nodes[i].Version = n.Version
+			w.Nodes[i].ChangesetID = n.ChangesetID
+			w.Nodes[i].Lat, w.Nodes[i].Lon = n.Lat, n.Lon
+		}
+	}
+
+	for _, r := range o.Relations {
+		for i, m := range r.Members {
+			if m.Type != osm.TypeNode {
+				continue
+			}
+
+			n, err := l.node(osm.NodeID(m.Ref), new)
+			if err != nil {
+				return err
+			}
+
+			if n == nil {
+				continue
+			}
+
+			r.Members[i].Version = n.Version
+			r.Members[i].ChangesetID = n.ChangesetID
+			r.Members[i].Lat, r.Members[i].Lon = n.Lat, n.Lon
+		}
+	}
+
+	return nil
+}
+
+// node returns the new, or old, version of the node.
+// Returns nil if the node is not found and missing children are ignored.
+func (l *locator) node(id osm.NodeID, new bool) (*osm.Node, error) {
+	c := l.changed[id]
+	if new && c != nil {
+		return c, nil
+	}
+
+	nodes, err := l.base.NodeHistory(l.ctx, id)
+	if err != nil && !l.base.NotFound(err) {
+		return nil, err
+	}
+
+	var result *osm.Node
+	for _, n := range nodes {
+		if c != nil && n.Version >= c.Version {
+			continue
+		}
+
+		if result == nil || n.Version > result.Version {
+			result = n
+		}
+	}
+
+	if result == nil || !result.Visible {
+		if l.ignoreMissing {
+			return nil, nil
+		}
+
+		return nil, &NoVisibleChildError{ID: id.FeatureID()}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,95 @@
+package annotate
+
+import (
+	"context"
+	"testing"
+
+	"github.com/paulmach/osm"
+)
+
+func TestAugmentedDiff(t *testing.T) {
+	ctx := context.Background()
+
+	base := (&osm.OSM{
+		Nodes: osm.Nodes{
+			{ID: 1, Version: 1, Visible: true, Lat: 1, Lon: 1},
+			{ID: 2, Version: 1, Visible: true, Lat: 2, Lon: 2},
+			{ID: 3, Version: 1, Visible: true, Lat: 3, Lon: 3},
+		},
+		Ways: osm.Ways{
+			{ID: 1, Version: 1, Visible: true, Nodes: osm.WayNodes{{ID: 1}, {ID: 2}}},
+		},
+		Relations: osm.Relations{
+			{ID: 1, Version: 1, Visible: true, Members: osm.Members{{Type: osm.TypeNode, Ref: 3}}},
+		},
+	}).HistoryDatasource()
+
+	change := &osm.Change{
+		Modify: &osm.OSM{
+			Nodes: osm.Nodes{
+				{ID: 2, Version: 2, Lat: 20, Lon: 20},
+			},
+			Ways: osm.Ways{
+				{ID: 1, Version: 2, Nodes: osm.WayNodes{{ID: 1}, {ID: 2}, {ID: 3}}},
+			},
+		},
+		Delete: &osm.OSM{
+			Relations: osm.Relations{{ID: 1, Version: 2}},
+		},
+	}
+
+	diff, err := AugmentedDiff(ctx, change, base)
+	if err != nil {
+		t.Fatalf("adiff error: %v", err)
+	}
+
+	if len(diff.Actions) != 3 {
+		t.Fatalf("incorrect number of actions: %v", len(diff.Actions))
+	}
+
+	way := diff.Actions[1]
+	old := way.Old.Ways[0]
+	if len(old.Nodes) != 2 || old.Nodes[1].Lat != 2 || old.Nodes[1].Version != 1 {
+		t.Errorf("incorrect old way nodes: %v", old.Nodes)
+	}
+
+	new := way.New.Ways[0]
+	if len(new.Nodes) != 3 || new.Nodes[1].Lat != 20 || new.Nodes[1].Version != 2 || new.Nodes[2].Lat != 3 {
+		t.Errorf("incorrect new way nodes: %v", new.Nodes)
+	}
+
+	rel := diff.Actions[2]
+	if rel.Type != osm.ActionDelete || rel.Old.Relations[0].Members[0].Lat != 3 {
+		t.Errorf("incorrect relation: %v", rel.Old.Relations[0])
+	}
+
+	// base data should not be modified
+	ways, _ := base.WayHistory(ctx, 1)
+	if ways[0].Nodes[0].Lat != 0 {
+		t.Errorf("should not modify the base data")
+	}
+}
+
+func TestAugmentedDiff_missing(t *testing.T) {
+	ctx := context.Background()
+	base := (&osm.OSM{}).HistoryDatasource()
+
+	change := &osm.Change{
+		Create: &osm.OSM{
+			Ways: osm.Ways{{ID: 1, Version: 1, Nodes: osm.WayNodes{{ID: 1}}}},
+		},
+	}
+
+	if _, err := AugmentedDiff(ctx, change, base); err == nil {
+		t.Errorf("should error on missing node")
+	}
+
+	diff, err := AugmentedDiff(ctx, change, base, IgnoreMissingChildren(true))
+	if err != nil {
+		t.Fatalf("adiff error: %v", err)
+	}
+
+	if len(diff.Actions) != 1 {
+		t.Errorf("incorrect actions: %v", diff.Actions)
+	}
+}
@@ -0,0 +1,223 @@
+package osm
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// An Annotation is application metadata for an element, e.g. who has it
+// locked in a task manager style editing tool. It is kept out of the tags
+// so it is never uploaded, see Annotations.
+type Annotation struct {
+	// LockOwner is the user, or session, with the element locked.
+	LockOwner string `json:"lock_owner,omitempty"`
+
+	// TaskID is the task the element is part of.
+	TaskID string `json:"task_id,omitempty"`
+
+	// Data is any other application metadata.
+	Data map[string]string `json:"data,omitempty"`
+}
+
+// Annotations is a sidecar of element annotations by feature id. It is
+// carried through a pipeline next to the data, like Provenance, and saved
+// as json. Created elements with negative ids can not be annotated since
+// their ids are not valid feature ids.
+type Annotations map[FeatureID]*Annotation
+
+// LockError is returned when locking an element locked by someone else.
+type LockError struct {
+	ID    FeatureID
+	Owner string
+}
+
+// Error returns an error message with the lock owner.
+func (e *LockError) Error() string {
+	return fmt.Sprintf("osm: %v is locked by %s", e.ID, e.Owner)
+}
+
+// Get returns the annotation of the element, nil if it has none.
+func (as Annotations) Get(id FeatureID) *Annotation {
+	return as[id]
+}
+
+// annotation returns the annotation of the element, creating it if needed.
+func (as Annotations) annotation(id FeatureID) *Annotation {
+	a := as[id]
+	if a == nil {
+		a = &Annotation{}
+		as[id] = a
+	}
+
+	return a
+}
+
+// Lock locks the element for the owner. Returns a *LockError if it is
+// already locked by someone else, locking again by the owner is allowed.
+func (as Annotations) Lock(id FeatureID, owner string) error {
+	if a := as[id]; a != nil && a.LockOwner != "" && a.LockOwner != owner {
+		return &LockError{ID: id, Owner: a.LockOwner}
+	}
+
+	as.annotation(id).LockOwner = owner
+	return nil
+}
+
+// Unlock releases the lock of the owner on the element. Returns a
+// *LockError if it is locked by someone else.
+func (as Annotations) Unlock(id FeatureID, owner string) error {
+	a := as[id]
+	if a == nil || a.LockOwner == "" {
+		return nil
+	}
+
+	if a.LockOwner != owner {
+		return &LockError{ID: id, Owner: a.LockOwner}
+	}
+
+	a.LockOwner = ""
+	return nil
+}
+
+// SetTask sets the task id of the element.
+func (as Annotations) SetTask(id FeatureID, task string) {
+	as.annotation(id).TaskID = task
+}
+
+// Task returns the elements that are part of the task, sorted.
+func (as Annotations) Task(task string) FeatureIDs {
+	var ids FeatureIDs
+	for id, a := range as {
+		if a.TaskID == task {
+			ids = append(ids, id)
+		}
+	}
+
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// An AnnotationConflict is a value that is different in the
+// annotations being merged.
+type AnnotationConflict struct {
+	ID FeatureID
+
+	// Field is lock_owner, task_id or the data key.
+	Field string
+
+	Value, Other string
+}
+
+// Merge adds the annotations from other, e.g. from another stage of
+// a pipeline or another user. Values missing in the receiver are copied,
+// values that are different are kept and returned as conflicts, sorted
+// by id and field. Other is not modified.
+func (as Annotations) Merge(other Annotations) []AnnotationConflict {
+	var conflicts []AnnotationConflict
+	merge := func(id FeatureID, field string, v *string, o string) {
+		switch {
+		case o == "" || *v == o:
+		case *v == "":
+			*v = o
+		default:
+			conflicts = append(conflicts, AnnotationConflict{ID: id, Field: field, Value: *v, Other: o})
+		}
+	}
+
+	for id, o := range other {
+		if o == nil {
+			continue
+		}
+
+		a := as.annotation(id)
+		merge(id, "lock_owner", &a.LockOwner, o.LockOwner)
+		merge(id, "task_id", &a.TaskID, o.TaskID)
+
+		for k, ov := range o.Data {
+			if a.Data == nil {
+				a.Data = make(map[string]string, len(o.Data))
+			}
+
+			v := a.Data[k]
+			merge(id, k, &v, ov)
+			a.Data[k] = v
+		}
+	}
+
+	sort.Slice(conflicts, func(i, j int) bool {
+		if conflicts[i].ID != conflicts[j].ID {
+			return conflicts[i].ID < conflicts[j].ID
+		}
+
+		return conflicts[i].Field < conflicts[j].Field
+	})
+
+	return conflicts
+}
+
+// For returns the annotations of the elements in the data, e.g. to carry
+// them to the output of a filter. The annotations are shared, not copied.
+func (as Annotations) For(o *OSM) Annotations {
+	result := make(Annotations)
+	for _, id := range o.FeatureIDs() {
+		if a := as[id]; a != nil {
+			result[id] = a
+		}
+	}
+
+	return result
+}
+
+// MarshalJSON encodes the annotations as an object keyed by
+// the "type/ref" feature ids.
+func (as Annotations) MarshalJSON() ([]byte, error) {
+	m := make(map[string]*Annotation, len(as))
+	for id, a := range as {
+		m[id.String()] = a
+	}
+
+	return json.Marshal(m)
+}
+
+// UnmarshalJSON decodes the annotations encoded by MarshalJSON.
+func (as *Annotations) UnmarshalJSON(data []byte) error {
+	m := make(map[string]*Annotation)
+	if err := json.Unmarshal(data, &m); err != nil {
+		return err
+	}
+
+	*as = make(Annotations, len(m))
+	for k, a := range m {
+		id, err := ParseFeatureID(k)
+		if err != nil {
+			return err
+		}
+
+		(*as)[id] = a
+	}
+
+	return nil
+}
+
+// ReadAnnotations reads the annotations written by WriteTo.
+func ReadAnnotations(r io.Reader) (Annotations, error) {
+	var as Annotations
+	if err := json.NewDecoder(r).Decode(&as); err != nil {
+		return nil, err
+	}
+
+	return as, nil
+}
+
+// WriteTo writes the annotations, as json, to the writer.
+func (as Annotations) WriteTo(w io.Writer) (int64, error) {
+	data, err := json.Marshal(as)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := w.Write(data)
+	return int64(n), err
+}
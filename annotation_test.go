@@ -0,0 +1,115 @@
+package osm
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestAnnotations_Lock(t *testing.T) {
+	as := Annotations{}
+	id := NodeID(1).FeatureID()
+
+	if err := as.Lock(id, "alice"); err != nil {
+		t.Fatalf("lock error: %v", err)
+	}
+
+	if err := as.Lock(id, "alice"); err != nil {
+		t.Errorf("relock by owner should work: %v", err)
+	}
+
+	err := as.Lock(id, "bob")
+	if e, ok := err.(*LockError); !ok || e.Owner != "alice" {
+		t.Errorf("expected lock error, got %v", err)
+	}
+
+	if err := as.Unlock(id, "bob"); err == nil {
+		t.Errorf("unlock by other should fail")
+	}
+
+	if err := as.Unlock(id, "alice"); err != nil {
+		t.Fatalf("unlock error: %v", err)
+	}
+
+	if err := as.Lock(id, "bob"); err != nil {
+		t.Errorf("lock after unlock should work: %v", err)
+	}
+}
+
+func TestAnnotations_Merge(t *testing.T) {
+	n1, n2, w1 := NodeID(1).FeatureID(), NodeID(2).FeatureID(), WayID(1).FeatureID()
+
+	as := Annotations{
+		n1: {LockOwner: "alice", Data: map[string]string{"note": "a"}},
+		w1: {TaskID: "1"},
+	}
+
+	other := Annotations{
+		n1: {LockOwner: "bob", TaskID: "2", Data: map[string]string{"note": "b", "color": "red"}},
+		n2: {TaskID: "2"},
+		w1: {TaskID: "1", LockOwner: "bob"},
+	}
+
+	conflicts := as.Merge(other)
+	expected := []AnnotationConflict{
+		{ID: n1, Field: "lock_owner", Value: "alice", Other: "bob"},
+		{ID: n1, Field: "note", Value: "a", Other: "b"},
+	}
+
+	if !reflect.DeepEqual(conflicts, expected) {
+		t.Errorf("incorrect conflicts: %+v", conflicts)
+	}
+
+	a := as.Get(n1)
+	if a.LockOwner != "alice" || a.TaskID != "2" || a.Data["color"] != "red" || a.Data["note"] != "a" {
+		t.Errorf("incorrect merge: %+v", a)
+	}
+
+	if as.Get(w1).LockOwner != "bob" {
+		t.Errorf("missing lock should be copied: %+v", as.Get(w1))
+	}
+
+	if ids := as.Task("2"); !reflect.DeepEqual(ids, FeatureIDs{n1, n2}) {
+		t.Errorf("incorrect task ids: %v", ids)
+	}
+
+	if other[n1].LockOwner != "bob" {
+		t.Errorf("other should not be modified")
+	}
+}
+
+func TestAnnotations_For(t *testing.T) {
+	as := Annotations{}
+	as.SetTask(NodeID(1).FeatureID(), "1")
+	as.SetTask(NodeID(2).FeatureID(), "1")
+
+	result := as.For(&OSM{Nodes: Nodes{{ID: 2}, {ID: 3}}})
+	if len(result) != 1 || result.Get(NodeID(2).FeatureID()) == nil {
+		t.Errorf("incorrect annotations: %v", result)
+	}
+}
+
+func TestAnnotations_WriteTo(t *testing.T) {
+	as := Annotations{}
+	as.Lock(WayID(5).FeatureID(), "alice")
+	as.SetTask(WayID(5).FeatureID(), "12")
+
+	buf := &bytes.Buffer{}
+	if _, err := as.WriteTo(buf); err != nil {
+		t.Fatalf("write error: %v", err)
+	}
+
+	expected := `{"way/5":{"lock_owner":"alice","task_id":"12"}}`
+	if v := buf.String(); v != expected {
+		t.Errorf("incorrect json: %v", v)
+	}
+
+	result, err := ReadAnnotations(buf)
+	if err != nil {
+		t.Fatalf("read error: %v", err)
+	}
+
+	if !reflect.DeepEqual(result, as) {
+		t.Errorf("incorrect annotations: %+v", result)
+	}
+}
@@ -0,0 +1,122 @@
+package osm
+
+import "context"
+
+// A ChangesetDatasourcer defines an interface to changeset metadata.
+// It is implemented by osmapi.Datasource.
+type ChangesetDatasourcer interface {
+	Changeset(context.Context, ChangesetID) (*Changeset, error)
+	NotFound(error) bool
+}
+
+// A ChangesetDatasource wraps a map to implement the ChangesetDatasourcer
+// interface, for example loaded from the changesets dump.
+type ChangesetDatasource map[ChangesetID]*Changeset
+
+var _ ChangesetDatasourcer = ChangesetDatasource{}
+
+// Changeset returns the changeset for the given id from the map.
+func (ds ChangesetDatasource) Changeset(ctx context.Context, id ChangesetID) (*Changeset, error) {
+	c := ds[id]
+	if c == nil {
+		return nil, errNotFound
+	}
+
+	return c, nil
+}
+
+// NotFound returns true if the error returned is a not found error.
+func (ds ChangesetDatasource) NotFound(err error) bool {
+	return err == errNotFound
+}
+
+// EditAttribution is an element version and the changeset that introduced it.
+type EditAttribution struct {
+	Element Element
+
+	// Changeset is nil if not found in the datasource.
+	Changeset *Changeset
+}
+
+// Comment returns the comment of the changeset.
+func (a EditAttribution) Comment() string {
+	if a.Changeset == nil {
+		return ""
+	}
+
+	return a.Changeset.Comment()
+}
+
+// Source returns the source of the changeset.
+func (a EditAttribution) Source() string {
+	if a.Changeset == nil {
+		return ""
+	}
+
+	return a.Changeset.Source()
+}
+
+// Editor returns the name of the editor used for the changeset,
+// see Changeset.Editor.
+func (a EditAttribution) Editor() string {
+	if a.Changeset == nil {
+		return ""
+	}
+
+	return a.Changeset.Editor()
+}
+
+// An Attributor joins elements with the changesets that introduced them.
+// Changesets are cached so each is only fetched from the datasource once.
+// It is not safe for concurrent use.
+type Attributor struct {
+	ds    ChangesetDatasourcer
+	cache map[ChangesetID]*Changeset
+}
+
+// NewAttributor creates an attributor using the changeset datasource.
+func NewAttributor(ds ChangesetDatasourcer) *Attributor {
+	return &Attributor{
+		ds:    ds,
+		cache: make(map[ChangesetID]*Changeset),
+	}
+}
+
+// Attribute returns the element with the changeset of its ChangesetID.
+// Changesets not found in the datasource are nil, other datasource errors
+// are returned.
+func (a *Attributor) Attribute(ctx context.Context, e Element) (EditAttribution, error) {
+	id := elementChangesetID(e)
+	if c, ok := a.cache[id]; ok {
+		return EditAttribution{Element: e, Changeset: c}, nil
+	}
+
+	c, err := a.ds.Changeset(ctx, id)
+	if err != nil {
+		if !a.ds.NotFound(err) {
+			return EditAttribution{}, err
+		}
+		c = nil
+	}
+
+	a.cache[id] = c
+	return EditAttribution{Element: e, Changeset: c}, nil
+}
+
+// Attribute joins the elements with the changesets that introduced them,
+// answering "what edit introduced this" for each element in one call.
+func Attribute(ctx context.Context, ds ChangesetDatasourcer, elements Elements) ([]EditAttribution, error) {
+	a := NewAttributor(ds)
+
+	result := make([]EditAttribution, 0, len(elements))
+	for _, e := range elements {
+		attr, err := a.Attribute(ctx, e)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, attr)
+	}
+
+	return result, nil
+}
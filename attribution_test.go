@@ -0,0 +1,73 @@
+package osm
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+type countingChangesetDatasource struct {
+	ChangesetDatasource
+	calls int
+	err   error
+}
+
+func (ds *countingChangesetDatasource) Changeset(ctx context.Context, id ChangesetID) (*Changeset, error) {
+	ds.calls++
+	if ds.err != nil {
+		return nil, ds.err
+	}
+
+	return ds.ChangesetDatasource.Changeset(ctx, id)
+}
+
+func TestAttribute(t *testing.T) {
+	ctx := context.Background()
+	ds := &countingChangesetDatasource{
+		ChangesetDatasource: ChangesetDatasource{
+			10: {
+				ID: 10,
+				Tags: Tags{
+					{Key: "comment", Value: "add shops"},
+					{Key: "source", Value: "survey"},
+					{Key: "created_by", Value: "JOSM/1.5 (18907 en)"},
+				},
+			},
+		},
+	}
+
+	elements := Elements{
+		&Node{ID: 1, ChangesetID: 10},
+		&Way{ID: 1, ChangesetID: 10},
+		&Relation{ID: 1, ChangesetID: 11},
+	}
+
+	result, err := Attribute(ctx, ds, elements)
+	if err != nil {
+		t.Fatalf("attribute error: %v", err)
+	}
+
+	if len(result) != 3 {
+		t.Fatalf("incorrect number of results: %v", len(result))
+	}
+
+	a := result[1]
+	if a.Element != elements[1] || a.Comment() != "add shops" || a.Source() != "survey" || a.Editor() != "JOSM" {
+		t.Errorf("incorrect attribution: %+v", a)
+	}
+
+	a = result[2]
+	if a.Changeset != nil || a.Comment() != "" || a.Source() != "" || a.Editor() != "" {
+		t.Errorf("missing changeset should be nil: %+v", a)
+	}
+
+	if ds.calls != 2 {
+		t.Errorf("should cache changesets, %d calls", ds.calls)
+	}
+
+	// other errors are returned
+	ds.err = errors.New("failed")
+	if _, err := Attribute(ctx, ds, elements); err == nil {
+		t.Errorf("should return datasource error")
+	}
+}
@@ -10,6 +10,9 @@ import (
 
 // Change is the structure of a changeset to be
 // uploaded or downloaded from the server.
+// When marshalled to xml the delete block lists the relations, then the
+// ways, then the nodes, since the api processes the elements in order
+// and rejects deleting a node that is still used by a way.
 // See: http://wiki.openstreetmap.org/wiki/OsmChange
 type Change struct {
 	Version   float64 `xml:"version,attr,omitempty"`
@@ -66,12 +69,22 @@ func (c *Change) HistoryDatasource() *HistoryDatasource {
 }
 
 // Marshal encodes the osm change data using protocol buffers.
-func (c *Change) Marshal() ([]byte, error) {
-	ss := &stringSet{}
-	encoded := marshalChange(c, ss, true)
+func (c *Change) Marshal(opts ...MarshalOption) ([]byte, error) {
+	mo := newMarshalOptions(opts)
+
+	ss := mo.newStringSet(func(ss *stringSet) {
+		marshalChange(c, ss, true, mo.rounding)
+	})
+
+	encoded := marshalChange(c, ss, true, mo.rounding)
 	encoded.Strings = ss.Strings()
+	if encoded.Size() != 0 {
+		// empty data is read the same by all versions
+		encoded.SchemaVersion = schemaVersion()
+		encoded.Dictionary = mo.dictionaryVersion()
+	}
 
-	return proto.Marshal(encoded)
+	return marshalProto(encoded, mo)
 }
 
 // UnmarshalChange will unmarshal the data into a Change object.
@@ -83,18 +96,36 @@ func UnmarshalChange(data []byte) (*Change, error) {
 		return nil, err
 	}
 
-	return unmarshalChange(pbf, pbf.GetStrings(), nil)
+	if err := verifyChecksum(data, pbf.Checksum); err != nil {
+		return nil, err
+	}
+
+	legacy, err := checkSchemaVersion(pbf.GetSchemaVersion())
+	if err != nil {
+		return nil, err
+	}
+
+	if legacy {
+		upgradeChangeV1(pbf)
+	}
+
+	ss, err := stringTable(pbf.GetDictionary(), pbf.GetStrings())
+	if err != nil {
+		return nil, err
+	}
+
+	return unmarshalChange(pbf, ss, nil)
 }
 
-func marshalChange(c *Change, ss *stringSet, includeChangeset bool) *osmpb.Change {
+func marshalChange(c *Change, ss *stringSet, includeChangeset bool, mode RoundingMode) *osmpb.Change {
 	if c == nil {
 		return nil
 	}
 
 	return &osmpb.Change{
-		Create: marshalOSM(c.Create, ss, includeChangeset),
-		Modify: marshalOSM(c.Modify, ss, includeChangeset),
-		Delete: marshalOSM(c.Delete, ss, includeChangeset),
+		Create: marshalOSM(c.Create, ss, includeChangeset, mode),
+		Modify: marshalOSM(c.Modify, ss, includeChangeset, mode),
+		Delete: marshalOSM(c.Delete, ss, includeChangeset, mode),
 	}
 }
 
@@ -178,7 +209,13 @@ func marshalInnerChange(e *xml.Encoder, name string, o *OSM) error {
 		return err
 	}
 
-	if err := o.marshalInnerXML(e); err != nil {
+	if name == "delete" {
+		// the api processes the elements in order so relations
+		// and ways must be deleted before the nodes they contain.
+		if err := o.marshalInnerDeleteXML(e); err != nil {
+			return err
+		}
+	} else if err := o.marshalInnerXML(e); err != nil {
 		return err
 	}
 
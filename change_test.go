@@ -5,6 +5,7 @@ import (
 	"context"
 	"encoding/xml"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -192,6 +193,37 @@ func TestChange_MarshalXML(t *testing.T) {
 	}
 }
 
+func TestChange_MarshalXML_deleteOrder(t *testing.T) {
+	c := Change{
+		Create: &OSM{
+			Nodes:     Nodes{&Node{ID: 1}},
+			Relations: Relations{&Relation{ID: 3}},
+		},
+		Delete: &OSM{
+			Nodes:     Nodes{&Node{ID: 1}},
+			Ways:      Ways{&Way{ID: 2}},
+			Relations: Relations{&Relation{ID: 3}},
+		},
+	}
+
+	data, err := xml.Marshal(c)
+	if err != nil {
+		t.Fatalf("xml marshal error: %v", err)
+	}
+
+	// create keeps nodes first, delete lists the parents first
+	create := string(data[:bytes.Index(data, []byte("<delete>"))])
+	if strings.Index(create, "<node") > strings.Index(create, "<relation") {
+		t.Errorf("create should list nodes first, got: %s", create)
+	}
+
+	del := string(data[bytes.Index(data, []byte("<delete>")):])
+	r, w, n := strings.Index(del, "<relation"), strings.Index(del, "<way"), strings.Index(del, "<node")
+	if r < 0 || w < 0 || n < 0 || !(r < w && w < n) {
+		t.Errorf("delete should list relations, ways, then nodes, got: %s", del)
+	}
+}
+
 func TestChange_Marshal(t *testing.T) {
 	c1 := loadChange(t, "testdata/changeset_38162206.osc")
 	cleanXMLNameFromChange(c1)
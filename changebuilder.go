@@ -0,0 +1,195 @@
+package osm
+
+import (
+	"errors"
+	"fmt"
+)
+
+// MaxChangesetEdits is the maximum number of edits the osm api
+// allows in a single changeset.
+const MaxChangesetEdits = 10000
+
+// ChangeBuilder accumulates the creates, modifies and deletes for an upload
+// and builds them into one or more changes, each under the edit limit.
+// The zero value is ready to use and splits at MaxChangesetEdits.
+// It is not safe for concurrent use.
+type ChangeBuilder struct {
+	// MaxEdits is the maximum number of elements in each change.
+	// If zero MaxChangesetEdits is used.
+	MaxEdits int
+
+	create, modify, delete *OSM
+	seen                   map[diffKey]bool
+}
+
+// NewChangeBuilder returns a change builder using the api edit limit.
+func NewChangeBuilder() *ChangeBuilder {
+	return &ChangeBuilder{MaxEdits: MaxChangesetEdits}
+}
+
+// Create adds a new element. It must not have a version,
+// and usually has a negative id, see IDAllocator.
+func (b *ChangeBuilder) Create(e Element) error {
+	if v := elementVersion(e); v != 0 {
+		return fmt.Errorf("osm: created %s %d must not have a version, has %d", elementType(e), elementRef(e), v)
+	}
+
+	return b.add(&b.create, e)
+}
+
+// Modify adds an updated element. The version must be the current version
+// on the server, the one being modified, or the upload will fail.
+func (b *ChangeBuilder) Modify(e Element) error {
+	if v := elementVersion(e); v <= 0 {
+		return fmt.Errorf("osm: modified %s %d must have a version", elementType(e), elementRef(e))
+	}
+
+	return b.add(&b.modify, e)
+}
+
+// Delete adds an element to be deleted. The version must be the current
+// version on the server.
+func (b *ChangeBuilder) Delete(e Element) error {
+	if v := elementVersion(e); v <= 0 {
+		return fmt.Errorf("osm: deleted %s %d must have a version", elementType(e), elementRef(e))
+	}
+
+	return b.add(&b.delete, e)
+}
+
+func (b *ChangeBuilder) add(o **OSM, e Element) error {
+	switch e.(type) {
+	case *Node, *Way, *Relation:
+	default:
+		return fmt.Errorf("osm: unsupported element type %T", e)
+	}
+
+	key := diffKey{elementType(e), elementRef(e)}
+	if b.seen[key] {
+		return fmt.Errorf("osm: %s %d already in change", key.t, key.ref)
+	}
+
+	if b.seen == nil {
+		b.seen = make(map[diffKey]bool)
+	}
+	b.seen[key] = true
+
+	if *o == nil {
+		*o = &OSM{}
+	}
+	(*o).Append(e)
+
+	return nil
+}
+
+// Len returns the number of edits added.
+func (b *ChangeBuilder) Len() int {
+	return len(b.seen)
+}
+
+// Build returns the edits as changes with at most MaxEdits elements each.
+// The edits are ordered dependency-first: creates of nodes, ways and
+// relations, then modifies in the same order, followed by deletes of
+// relations, ways and nodes. The changes must be uploaded in order and,
+// since later changes may reference elements created in earlier ones, the
+// DiffResult of each upload should be applied to the remaining changes.
+func (b *ChangeBuilder) Build() ([]*Change, error) {
+	max := b.MaxEdits
+	if max == 0 {
+		max = MaxChangesetEdits
+	}
+
+	if max < 0 {
+		return nil, errors.New("osm: max edits must be positive")
+	}
+
+	var (
+		result  []*Change
+		current *Change
+		count   int
+	)
+
+	add := func(appendTo func(*Change, Object), o Object) {
+		if current == nil || count == max {
+			current = &Change{}
+			result = append(result, current)
+			count = 0
+		}
+
+		appendTo(current, o)
+		count++
+	}
+
+	each := func(o *OSM, deletes bool, appendTo func(*Change, Object)) {
+		if o == nil {
+			return
+		}
+
+		var elements Elements
+		if deletes {
+			// elements must be deleted before the things they contain
+			for _, r := range o.Relations {
+				elements = append(elements, r)
+			}
+			for _, w := range o.Ways {
+				elements = append(elements, w)
+			}
+			for _, n := range o.Nodes {
+				elements = append(elements, n)
+			}
+		} else {
+			elements = o.Elements()
+		}
+
+		for _, e := range elements {
+			add(appendTo, e)
+		}
+	}
+
+	each(b.create, false, (*Change).AppendCreate)
+	each(b.modify, false, (*Change).AppendModify)
+	each(b.delete, true, (*Change).AppendDelete)
+
+	return result, nil
+}
+
+func elementVersion(e Element) int {
+	switch e := e.(type) {
+	case *Node:
+		return e.Version
+	case *Way:
+		return e.Version
+	case *Relation:
+		return e.Version
+	}
+
+	return 0
+}
+
+// elementType and elementRef are used instead of the FeatureID
+// since negative ids do not pack into one.
+func elementType(e Element) Type {
+	switch e.(type) {
+	case *Node:
+		return TypeNode
+	case *Way:
+		return TypeWay
+	case *Relation:
+		return TypeRelation
+	}
+
+	return ""
+}
+
+func elementRef(e Element) int64 {
+	switch e := e.(type) {
+	case *Node:
+		return int64(e.ID)
+	case *Way:
+		return int64(e.ID)
+	case *Relation:
+		return int64(e.ID)
+	}
+
+	return 0
+}
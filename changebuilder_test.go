@@ -0,0 +1,98 @@
+package osm
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+func TestChangeBuilder(t *testing.T) {
+	a := NewIDAllocator(-1)
+	b := NewChangeBuilder()
+	b.MaxEdits = 3
+
+	n1 := a.NewNode(1, 1, nil)
+	n2 := a.NewNode(2, 2, nil)
+	w := a.NewWay(nil, n1.ID, n2.ID)
+
+	// added out of order to check the dependency ordering
+	for _, e := range []Element{w, n1, n2} {
+		if err := b.Create(e); err != nil {
+			t.Fatalf("create error: %v", err)
+		}
+	}
+
+	if err := b.Modify(&Node{ID: 10, Version: 2}); err != nil {
+		t.Fatalf("modify error: %v", err)
+	}
+
+	if err := b.Delete(&Node{ID: 11, Version: 1}); err != nil {
+		t.Fatalf("delete error: %v", err)
+	}
+
+	if err := b.Delete(&Way{ID: 12, Version: 5}); err != nil {
+		t.Fatalf("delete error: %v", err)
+	}
+
+	if b.Len() != 6 {
+		t.Errorf("incorrect length: %v", b.Len())
+	}
+
+	changes, err := b.Build()
+	if err != nil {
+		t.Fatalf("build error: %v", err)
+	}
+
+	if len(changes) != 2 {
+		t.Fatalf("incorrect number of changes: %v", len(changes))
+	}
+
+	c := changes[0]
+	if len(c.Create.Nodes) != 2 || len(c.Create.Ways) != 1 || c.Modify != nil || c.Delete != nil {
+		t.Errorf("incorrect first change: %+v", c)
+	}
+
+	c = changes[1]
+	if c.Create != nil || len(c.Modify.Nodes) != 1 || len(c.Delete.Nodes) != 1 || len(c.Delete.Ways) != 1 {
+		t.Errorf("incorrect second change: %+v", c)
+	}
+
+	data, err := xml.Marshal(c)
+	if err != nil {
+		t.Fatalf("marshal error: %v", err)
+	}
+
+	del := string(data[strings.Index(string(data), "<delete>"):])
+	if strings.Index(del, "<way") > strings.Index(del, "<node") {
+		t.Errorf("ways should be deleted before nodes: %s", del)
+	}
+}
+
+func TestChangeBuilder_errors(t *testing.T) {
+	b := &ChangeBuilder{}
+
+	if err := b.Create(&Node{ID: -1, Version: 1}); err == nil {
+		t.Errorf("create with version should error")
+	}
+
+	if err := b.Modify(&Way{ID: 1}); err == nil {
+		t.Errorf("modify without version should error")
+	}
+
+	if err := b.Delete(&Relation{ID: 1}); err == nil {
+		t.Errorf("delete without version should error")
+	}
+
+	if err := b.Modify(&Node{ID: 1, Version: 1}); err != nil {
+		t.Fatalf("modify error: %v", err)
+	}
+
+	if err := b.Delete(&Node{ID: 1, Version: 1}); err == nil {
+		t.Errorf("duplicate element should error")
+	}
+
+	b.MaxEdits = -1
+	if _, err := b.Build(); err == nil {
+		t.Errorf("negative max edits should error")
+	}
+}
@@ -2,7 +2,9 @@ package osm
 
 import (
 	"encoding/xml"
+	"strings"
 	"time"
+	"unicode"
 
 	"github.com/gogo/protobuf/proto"
 	"github.com/paulmach/osm/internal/osmpb"
@@ -85,17 +87,151 @@ func (c *Changeset) Source() string {
 	return c.Tags.Find("source")
 }
 
+// ImagerySources is a helper and returns the list of imagery sources
+// from the semicolon separated imagery used tag.
+func (c *Changeset) ImagerySources() []string {
+	return c.Tags.AllValues("imagery_used")
+}
+
+// editors are the names, the prefix of the created_by tag, of common editors.
+var editors = []string{
+	"iD", "JOSM", "Potlatch", "StreetComplete", "Vespucci", "Go Map!!",
+	"MAPS.ME", "Organic Maps", "OsmAnd", "RapiD", "Rapid", "Every Door",
+	"Merkaartor", "OsmHydrant", "MapComplete", "Level0", "CoMaps",
+}
+
+// Editor is a helper and returns the name of the editor, without the
+// version, from the created by tag. For example "JOSM/1.5 (18907 en)"
+// returns "JOSM". Unknown editors return the text up to the first
+// slash or space.
+func (c *Changeset) Editor() string {
+	cb := strings.TrimSpace(c.CreatedBy())
+	for _, e := range editors {
+		if !strings.HasPrefix(cb, e) {
+			continue
+		}
+
+		// make sure the whole name matched, eg. "iDEditor" is not "iD".
+		rest := cb[len(e):]
+		if rest == "" || rest[0] == ' ' || rest[0] == '/' || rest[0] == '-' {
+			return e
+		}
+	}
+
+	if i := strings.IndexAny(cb, "/ "); i > 0 {
+		return cb[:i]
+	}
+
+	return cb
+}
+
+// Hashtags is a helper and returns the hashtags, including the #, from
+// the hashtags tag and the changeset comment. Duplicates are removed
+// ignoring case and the order of first appearance is kept.
+func (c *Changeset) Hashtags() []string {
+	var result []string
+	seen := make(map[string]bool)
+	add := func(h string) {
+		if len(h) <= 1 {
+			return
+		}
+
+		l := strings.ToLower(h)
+		if !seen[l] {
+			seen[l] = true
+			result = append(result, h)
+		}
+	}
+
+	for _, v := range c.Tags.AllValues("hashtags") {
+		if !strings.HasPrefix(v, "#") {
+			v = "#" + v
+		}
+		add(v)
+	}
+
+	comment := c.Comment()
+	for i := 0; i < len(comment); i++ {
+		if comment[i] != '#' {
+			continue
+		}
+
+		end := strings.IndexFunc(comment[i+1:], func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' && r != '-'
+		})
+		if end == -1 {
+			end = len(comment) - i - 1
+		}
+
+		add(comment[i : i+1+end])
+		i += end
+	}
+
+	return result
+}
+
+// IsImport is a heuristic and returns true if the changeset looks like
+// an import. This is the case if the import tag is yes or if the comment,
+// source or hashtags mention an import.
+func (c *Changeset) IsImport() bool {
+	if c.Tags.Find("import") == "yes" {
+		return true
+	}
+
+	if mentionsImport(c.Comment()) || mentionsImport(c.Source()) {
+		return true
+	}
+
+	for _, h := range c.Hashtags() {
+		if strings.Contains(strings.ToLower(h), "import") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// mentionsImport returns true if one of the words is import, imports or imported.
+func mentionsImport(s string) bool {
+	words := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+
+	for _, w := range words {
+		if w == "import" || w == "imports" || w == "imported" {
+			return true
+		}
+	}
+
+	return false
+}
+
 // Bot is a helper and returns true if the bot tag is a yes.
 func (c *Changeset) Bot() bool {
 	// As of July 5, 2015: 300k yes, 123 no, 8 other
 	return c.Tags.Find("bot") == "yes"
 }
 
-// Marshal encodes the changeset data using protocol buffers.
-// Does not encode the changeset discussion.
-func (c *Changeset) Marshal() ([]byte, error) {
-	ss := &stringSet{}
+// Marshal encodes the changeset data, including the discussion,
+// using protocol buffers.
+func (c *Changeset) Marshal(opts ...MarshalOption) ([]byte, error) {
+	mo := newMarshalOptions(opts)
+
+	ss := mo.newStringSet(func(ss *stringSet) {
+		marshalChangeset(c, ss, mo.rounding)
+	})
+
+	encoded := marshalChangeset(c, ss, mo.rounding)
+	encoded.Strings = ss.Strings()
+	if encoded.Size() != 0 {
+		// empty data is read the same by all versions
+		encoded.SchemaVersion = schemaVersion()
+		encoded.Dictionary = mo.dictionaryVersion()
+	}
+	return marshalProto(encoded, mo)
+}
 
+func marshalChangeset(c *Changeset, ss *stringSet, mode RoundingMode) *osmpb.Changeset {
 	var userSid *uint32
 	if c.User != "" {
 		v := ss.Add(c.User)
@@ -126,20 +262,47 @@ func (c *Changeset) Marshal() ([]byte, error) {
 
 	if c.MinLat != 0 || c.MaxLat != 0 || c.MinLon != 0 || c.MaxLon != 0 {
 		encoded.Bounds = &osmpb.Bounds{
-			MinLat: geoToInt64(c.MinLat),
-			MaxLat: geoToInt64(c.MaxLat),
-			MinLon: geoToInt64(c.MinLon),
-			MaxLon: geoToInt64(c.MaxLon),
+			MinLat: GeoToInt64(c.MinLat, mode),
+			MaxLat: GeoToInt64(c.MaxLat, mode),
+			MinLon: GeoToInt64(c.MinLon, mode),
+			MaxLon: GeoToInt64(c.MaxLon, mode),
+		}
+	}
+
+	if c.ChangesCount != 0 {
+		encoded.ChangesCount = proto.Int32(int32(c.ChangesCount))
+	}
+
+	if c.CommentsCount != 0 {
+		encoded.CommentsCount = proto.Int32(int32(c.CommentsCount))
+	}
+
+	if c.Discussion != nil {
+		encoded.Comments = make([]*osmpb.ChangesetComment, 0, len(c.Discussion.Comments))
+		for _, com := range c.Discussion.Comments {
+			ec := &osmpb.ChangesetComment{
+				Timestamp: timeToUnixPointer(com.Timestamp),
+				Text:      proto.String(com.Text),
+			}
+
+			if com.UserID != 0 {
+				ec.UserId = proto.Int32(int32(com.UserID))
+			}
+
+			if com.User != "" {
+				ec.UserSid = proto.Uint32(ss.Add(com.User))
+			}
+
+			encoded.Comments = append(encoded.Comments, ec)
 		}
 	}
 
 	if c.Change != nil &&
 		(c.Change.Create != nil || c.Change.Modify != nil || c.Change.Delete != nil) {
-		encoded.Change = marshalChange(c.Change, ss, false)
+		encoded.Change = marshalChange(c.Change, ss, false, mode)
 	}
 
-	encoded.Strings = ss.Strings()
-	return proto.Marshal(encoded)
+	return encoded
 }
 
 // UnmarshalChangeset will unmarshal the data into a OSM object.
@@ -150,23 +313,68 @@ func UnmarshalChangeset(data []byte) (*Changeset, error) {
 		return nil, err
 	}
 
-	ss := encoded.GetStrings()
+	if err := verifyChecksum(data, encoded.Checksum); err != nil {
+		return nil, err
+	}
+
+	legacy, err := checkSchemaVersion(encoded.GetSchemaVersion())
+	if err != nil {
+		return nil, err
+	}
+
+	if legacy {
+		upgradeChangeV1(encoded.Change)
+	}
+
+	ss, err := stringTable(encoded.GetDictionary(), encoded.GetStrings())
+	if err != nil {
+		return nil, err
+	}
+
 	tags, err := tagsFromStrings(ss, encoded.GetKeys(), encoded.GetVals())
 	if err != nil {
 		return nil, err
 	}
 
 	cs := &Changeset{
-		ID:        ChangesetID(encoded.GetId()),
-		UserID:    UserID(encoded.GetUserId()),
-		CreatedAt: unixToTime(encoded.GetCreatedAt()),
-		ClosedAt:  unixToTime(encoded.GetClosedAt()),
-		Open:      encoded.GetOpen(),
-		Tags:      tags,
+		ID:            ChangesetID(encoded.GetId()),
+		UserID:        UserID(encoded.GetUserId()),
+		CreatedAt:     unixToTime(encoded.GetCreatedAt()),
+		ClosedAt:      unixToTime(encoded.GetClosedAt()),
+		Open:          encoded.GetOpen(),
+		ChangesCount:  int(encoded.GetChangesCount()),
+		CommentsCount: int(encoded.GetCommentsCount()),
+		Tags:          tags,
 	}
 
 	if encoded.UserSid != nil {
-		cs.User = ss[encoded.GetUserSid()]
+		cs.User, err = stringAt(ss, encoded.GetUserSid())
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(encoded.Comments) > 0 {
+		cs.Discussion = &ChangesetDiscussion{
+			Comments: make([]*ChangesetComment, 0, len(encoded.Comments)),
+		}
+
+		for _, ec := range encoded.Comments {
+			com := &ChangesetComment{
+				UserID:    UserID(ec.GetUserId()),
+				Timestamp: unixToTime(ec.GetTimestamp()),
+				Text:      ec.GetText(),
+			}
+
+			if ec.UserSid != nil {
+				com.User, err = stringAt(ss, ec.GetUserSid())
+				if err != nil {
+					return nil, err
+				}
+			}
+
+			cs.Discussion.Comments = append(cs.Discussion.Comments, com)
+		}
 	}
 
 	if encoded.Bounds != nil {
@@ -0,0 +1,190 @@
+package osm
+
+import "github.com/paulmach/orb"
+
+// HasBounds returns true if the changeset has a bounding box. The api does
+// not return one for changesets without edits with a location, and it is
+// often missing for old changesets and in some dumps.
+func (c *Changeset) HasBounds() bool {
+	return c.MinLat != 0 || c.MaxLat != 0 || c.MinLon != 0 || c.MaxLon != 0
+}
+
+// ComputeBounds sets the bounding box of the changeset from the edits in
+// c.Change if it is missing. Returns true if the bounds were set. Way nodes
+// and relation members must be annotated to contribute their locations.
+func (c *Changeset) ComputeBounds() bool {
+	if c.HasBounds() || c.Change == nil {
+		return false
+	}
+
+	var ext extent
+	for _, o := range []*OSM{c.Change.Create, c.Change.Modify, c.Change.Delete} {
+		if o == nil {
+			continue
+		}
+
+		for _, e := range o.Elements() {
+			ext.add(e)
+		}
+	}
+
+	return ext.apply(c)
+}
+
+// EditCentroid returns the centroid of the locations of an edited element:
+// the node location, the average of the way node locations or the average
+// of the relation member locations. Way nodes and relation members must be
+// annotated. Returns false if the element has no locations.
+func EditCentroid(e Element) (orb.Point, bool) {
+	var (
+		sum   orb.Point
+		count int
+	)
+
+	eachLocation(e, func(p orb.Point) {
+		sum[0] += p[0]
+		sum[1] += p[1]
+		count++
+	})
+
+	if count == 0 {
+		return orb.Point{}, false
+	}
+
+	return orb.Point{sum[0] / float64(count), sum[1] / float64(count)}, true
+}
+
+// ChangesetExtents computes the bounding boxes and centroids of changesets
+// from their edits, for example from a full history stream. Elements are
+// grouped by their ChangesetID. It is not safe for concurrent use.
+type ChangesetExtents struct {
+	extents map[ChangesetID]*extent
+}
+
+// NewChangesetExtents creates a new changeset extents accumulator.
+func NewChangesetExtents() *ChangesetExtents {
+	return &ChangesetExtents{extents: make(map[ChangesetID]*extent)}
+}
+
+// Add adds the locations of the element to its changeset.
+func (ce *ChangesetExtents) Add(e Element) {
+	id := elementChangesetID(e)
+	ext := ce.extents[id]
+	if ext == nil {
+		ext = &extent{}
+		ce.extents[id] = ext
+	}
+
+	ext.add(e)
+}
+
+// Bound returns the bounding box of all the edit locations in the changeset.
+func (ce *ChangesetExtents) Bound(id ChangesetID) (orb.Bound, bool) {
+	ext := ce.extents[id]
+	if ext == nil || ext.edits == 0 {
+		return orb.Bound{}, false
+	}
+
+	return ext.bound, true
+}
+
+// Centroid returns the average of the edit centroids in the changeset,
+// see EditCentroid. Each edit is weighted equally, so a long way does not
+// pull the centroid more than a single node.
+func (ce *ChangesetExtents) Centroid(id ChangesetID) (orb.Point, bool) {
+	ext := ce.extents[id]
+	if ext == nil || ext.edits == 0 {
+		return orb.Point{}, false
+	}
+
+	n := float64(ext.edits)
+	return orb.Point{ext.centroids[0] / n, ext.centroids[1] / n}, true
+}
+
+// Apply sets the bounding box of the changeset if it is missing.
+// Returns true if the bounds were set.
+func (ce *ChangesetExtents) Apply(c *Changeset) bool {
+	if c.HasBounds() {
+		return false
+	}
+
+	ext := ce.extents[c.ID]
+	if ext == nil {
+		return false
+	}
+
+	return ext.apply(c)
+}
+
+type extent struct {
+	bound     orb.Bound
+	centroids orb.Point
+	edits     int
+}
+
+func (ext *extent) add(e Element) {
+	c, ok := EditCentroid(e)
+	if !ok {
+		return
+	}
+
+	if ext.edits == 0 {
+		ext.bound = orb.Bound{Min: c, Max: c}
+	}
+
+	eachLocation(e, func(p orb.Point) {
+		ext.bound = ext.bound.Extend(p)
+	})
+
+	ext.centroids[0] += c[0]
+	ext.centroids[1] += c[1]
+	ext.edits++
+}
+
+func (ext *extent) apply(c *Changeset) bool {
+	if ext.edits == 0 {
+		return false
+	}
+
+	c.MinLat = ext.bound.Min.Lat()
+	c.MaxLat = ext.bound.Max.Lat()
+	c.MinLon = ext.bound.Min.Lon()
+	c.MaxLon = ext.bound.Max.Lon()
+
+	return true
+}
+
+// eachLocation calls f for the locations of the element that are set.
+func eachLocation(e Element, f func(orb.Point)) {
+	switch e := e.(type) {
+	case *Node:
+		if e.Lat != 0 || e.Lon != 0 {
+			f(e.Point())
+		}
+	case *Way:
+		for _, wn := range e.Nodes {
+			if wn.Lat != 0 || wn.Lon != 0 {
+				f(wn.Point())
+			}
+		}
+	case *Relation:
+		for _, m := range e.Members {
+			if m.Lat != 0 || m.Lon != 0 {
+				f(m.Point())
+			}
+		}
+	}
+}
+
+func elementChangesetID(e Element) ChangesetID {
+	switch e := e.(type) {
+	case *Node:
+		return e.ChangesetID
+	case *Way:
+		return e.ChangesetID
+	case *Relation:
+		return e.ChangesetID
+	}
+
+	return 0
+}
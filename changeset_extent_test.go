@@ -0,0 +1,133 @@
+package osm
+
+import (
+	"testing"
+
+	"github.com/paulmach/orb"
+)
+
+func TestChangeset_ComputeBounds(t *testing.T) {
+	c := &Changeset{
+		ID: 1,
+		Change: &Change{
+			Create: &OSM{
+				Nodes: Nodes{{ID: -1, Lat: 1, Lon: 2}},
+			},
+			Modify: &OSM{
+				Ways: Ways{{ID: 1, Nodes: WayNodes{{ID: 1, Lat: 3, Lon: 1}, {ID: 2, Lat: 0.5, Lon: 4}}}},
+			},
+			Delete: &OSM{
+				// deleted nodes often have no location
+				Nodes: Nodes{{ID: 5}},
+			},
+		},
+	}
+
+	if c.HasBounds() {
+		t.Errorf("should not have bounds")
+	}
+
+	if !c.ComputeBounds() {
+		t.Fatalf("should compute bounds")
+	}
+
+	expected := &Bounds{MinLat: 0.5, MaxLat: 3, MinLon: 1, MaxLon: 4}
+	if b := c.Bounds(); *b != *expected {
+		t.Errorf("incorrect bounds: %+v", b)
+	}
+
+	// existing bounds are not changed
+	c.Change.Create.Nodes[0].Lat = 10
+	if c.ComputeBounds() {
+		t.Errorf("should not compute existing bounds")
+	}
+
+	// no locations
+	c = &Changeset{Change: &Change{Delete: &OSM{Nodes: Nodes{{ID: 5}}}}}
+	if c.ComputeBounds() || c.HasBounds() {
+		t.Errorf("should not set bounds without locations")
+	}
+}
+
+func TestEditCentroid(t *testing.T) {
+	cases := []struct {
+		name     string
+		element  Element
+		expected orb.Point
+		ok       bool
+	}{
+		{
+			name:     "node",
+			element:  &Node{Lat: 1, Lon: 2},
+			expected: orb.Point{2, 1},
+			ok:       true,
+		},
+		{
+			name:     "way",
+			element:  &Way{Nodes: WayNodes{{Lat: 0, Lon: 1}, {Lat: 2, Lon: 3}}},
+			expected: orb.Point{2, 1},
+			ok:       true,
+		},
+		{
+			name:     "relation",
+			element:  &Relation{Members: Members{{Lat: 1, Lon: 1}, {Lat: 3, Lon: 3}, {Type: TypeRelation}}},
+			expected: orb.Point{2, 2},
+			ok:       true,
+		},
+		{
+			name:    "not annotated",
+			element: &Way{Nodes: WayNodes{{ID: 1}, {ID: 2}}},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			p, ok := EditCentroid(tc.element)
+			if ok != tc.ok {
+				t.Fatalf("incorrect ok: %v", ok)
+			}
+
+			if p != tc.expected {
+				t.Errorf("incorrect centroid: %v", p)
+			}
+		})
+	}
+}
+
+func TestChangesetExtents(t *testing.T) {
+	ce := NewChangesetExtents()
+
+	ce.Add(&Node{ID: 1, ChangesetID: 10, Lat: 1, Lon: 1})
+	ce.Add(&Way{ID: 1, ChangesetID: 10, Nodes: WayNodes{{Lat: 3, Lon: 3}, {Lat: 3, Lon: 7}}})
+	ce.Add(&Node{ID: 2, ChangesetID: 11, Lat: 5, Lon: 5})
+	ce.Add(&Node{ID: 3, ChangesetID: 12})
+
+	b, ok := ce.Bound(10)
+	if !ok || b != (orb.Bound{Min: orb.Point{1, 1}, Max: orb.Point{7, 3}}) {
+		t.Errorf("incorrect bound: %v %v", b, ok)
+	}
+
+	// average of the edit centroids, (1, 1) and (5, 3)
+	c, ok := ce.Centroid(10)
+	if !ok || c != (orb.Point{3, 2}) {
+		t.Errorf("incorrect centroid: %v %v", c, ok)
+	}
+
+	if _, ok := ce.Bound(12); ok {
+		t.Errorf("should not have bound without locations")
+	}
+
+	if _, ok := ce.Centroid(13); ok {
+		t.Errorf("should not have centroid for unknown changeset")
+	}
+
+	cs := &Changeset{ID: 11}
+	if !ce.Apply(cs) || cs.MinLat != 5 || cs.MaxLon != 5 {
+		t.Errorf("incorrect applied bounds: %+v", cs.Bounds())
+	}
+
+	cs = &Changeset{ID: 10, MinLat: 1, MaxLat: 2, MinLon: 1, MaxLon: 2}
+	if ce.Apply(cs) || cs.MaxLat != 2 {
+		t.Errorf("should not change existing bounds")
+	}
+}
@@ -196,6 +196,133 @@ func TestChangeset_tags(t *testing.T) {
 	}
 }
 
+func TestChangeset_ImagerySources(t *testing.T) {
+	c := &Changeset{Tags: Tags{{Key: "imagery_used", Value: "Bing aerial imagery;Esri World Imagery; Mapbox"}}}
+
+	expected := []string{"Bing aerial imagery", "Esri World Imagery", "Mapbox"}
+	if v := c.ImagerySources(); !reflect.DeepEqual(v, expected) {
+		t.Errorf("incorrect sources: %q", v)
+	}
+
+	c = &Changeset{}
+	if v := c.ImagerySources(); v != nil {
+		t.Errorf("should be nil: %v", v)
+	}
+}
+
+func TestChangeset_Editor(t *testing.T) {
+	cases := []struct {
+		createdBy string
+		editor    string
+	}{
+		{createdBy: "iD 2.27.3", editor: "iD"},
+		{createdBy: "JOSM/1.5 (18907 en)", editor: "JOSM"},
+		{createdBy: "StreetComplete 57.1", editor: "StreetComplete"},
+		{createdBy: "Go Map!! 4.1.0", editor: "Go Map!!"},
+		{createdBy: "Potlatch 2.5", editor: "Potlatch"},
+		{createdBy: "iDEditor", editor: "iDEditor"},
+		{createdBy: "osmtools/0.1", editor: "osmtools"},
+		{createdBy: "", editor: ""},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.createdBy, func(t *testing.T) {
+			c := &Changeset{Tags: Tags{{Key: "created_by", Value: tc.createdBy}}}
+			if v := c.Editor(); v != tc.editor {
+				t.Errorf("incorrect editor: %v != %v", v, tc.editor)
+			}
+		})
+	}
+}
+
+func TestChangeset_Hashtags(t *testing.T) {
+	cases := []struct {
+		name     string
+		tags     Tags
+		expected []string
+	}{
+		{
+			name:     "hashtags tag",
+			tags:     Tags{{Key: "hashtags", Value: "#hotosm-project-123;#missingmaps"}},
+			expected: []string{"#hotosm-project-123", "#missingmaps"},
+		},
+		{
+			name:     "from comment",
+			tags:     Tags{{Key: "comment", Value: "Add buildings #hotosm-project-123, #MissingMaps."}},
+			expected: []string{"#hotosm-project-123", "#MissingMaps"},
+		},
+		{
+			name: "duplicates",
+			tags: Tags{
+				{Key: "comment", Value: "roads #missingmaps #MissingMaps #osmgeoweek"},
+				{Key: "hashtags", Value: "#missingmaps"},
+			},
+			expected: []string{"#missingmaps", "#osmgeoweek"},
+		},
+		{
+			name:     "lone hash",
+			tags:     Tags{{Key: "comment", Value: "fix # 5"}},
+			expected: nil,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := &Changeset{Tags: tc.tags}
+			if v := c.Hashtags(); !reflect.DeepEqual(v, tc.expected) {
+				t.Errorf("incorrect hashtags: %q", v)
+			}
+		})
+	}
+}
+
+func TestChangeset_IsImport(t *testing.T) {
+	cases := []struct {
+		name     string
+		tags     Tags
+		expected bool
+	}{
+		{
+			name:     "import tag",
+			tags:     Tags{{Key: "import", Value: "yes"}},
+			expected: true,
+		},
+		{
+			name:     "comment",
+			tags:     Tags{{Key: "comment", Value: "Imported addresses from the county"}},
+			expected: true,
+		},
+		{
+			name:     "source",
+			tags:     Tags{{Key: "source", Value: "NHD import"}},
+			expected: true,
+		},
+		{
+			name:     "hashtag",
+			tags:     Tags{{Key: "comment", Value: "buildings #nycbuildingsimport"}},
+			expected: true,
+		},
+		{
+			name:     "important is not import",
+			tags:     Tags{{Key: "comment", Value: "fix an important road"}},
+			expected: false,
+		},
+		{
+			name:     "no tags",
+			expected: false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := &Changeset{Tags: tc.tags}
+			if v := c.IsImport(); v != tc.expected {
+				t.Errorf("incorrect import: %v", v)
+			}
+		})
+	}
+}
+
 func TestChangeset_bounds(t *testing.T) {
 	data := []byte(`
 <changeset id="36947173" created_at="2016-02-01T22:00:56Z" closed_at="2016-02-01T23:05:06Z" open="false" num_changes="9" user="florijn11" uid="1319603" min_lat="51.5871887" max_lat="51.6032569" min_lon="5.3214071" max_lon="5.33106" comments_count="0">
@@ -278,6 +405,95 @@ func TestChangeset_comments(t *testing.T) {
 	}
 }
 
+func TestChangesets_dump(t *testing.T) {
+	// the weekly changesets-latest.osm dump format
+	o := &OSM{}
+	err := xml.Unmarshal(readFile(t, "testdata/changesets-dump.osm"), o)
+	if err != nil {
+		t.Fatalf("unmarshal error: %v", err)
+	}
+
+	if l := len(o.Changesets); l != 3 {
+		t.Fatalf("incorrect number of changesets: %v", l)
+	}
+
+	cs := o.Changesets[0]
+	if cs.ID != 1 || cs.User != "Steve" || cs.ChangesCount != 2 || cs.Discussion != nil {
+		t.Errorf("incorrect changeset: %+v", cs)
+	}
+
+	if v := cs.ClosedAt; !v.Equal(time.Date(2005, 4, 9, 20, 54, 39, 0, time.UTC)) {
+		t.Errorf("incorrect closed at: %v", v)
+	}
+
+	cs = o.Changesets[1]
+	if cs.CommentsCount != 2 || len(cs.Discussion.Comments) != 2 {
+		t.Fatalf("incorrect discussion: %+v", cs)
+	}
+
+	if v := cs.Discussion.Comments[1].Text; v != "OK New to this & learning" {
+		t.Errorf("incorrect comment text: %v", v)
+	}
+
+	if v := cs.Bounds(); v.MinLat != 34.6591676 || v.MaxLon != -81.8788142 {
+		t.Errorf("incorrect bounds: %v", v)
+	}
+
+	cs = o.Changesets[2]
+	if !cs.Open || !cs.ClosedAt.IsZero() || cs.CreatedBy() != "JOSM/1.5 (18907 en)" {
+		t.Errorf("incorrect open changeset: %+v", cs)
+	}
+}
+
+func TestChangeset_Marshal(t *testing.T) {
+	cs := &Changeset{
+		ID:            40303151,
+		User:          "Glen Bundrick",
+		UserID:        4173877,
+		CreatedAt:     time.Date(2016, 6, 26, 15, 37, 47, 0, time.UTC),
+		ClosedAt:      time.Date(2016, 6, 26, 15, 37, 48, 0, time.UTC),
+		ChangesCount:  5,
+		CommentsCount: 2,
+		MinLat:        34.6591676,
+		MaxLat:        34.6594167,
+		MinLon:        -81.8789825,
+		MaxLon:        -81.8788142,
+		Tags:          Tags{{Key: "comment", Value: "Recent Doublewide addition"}},
+		Discussion: &ChangesetDiscussion{
+			Comments: []*ChangesetComment{
+				{
+					User:      "user_5359",
+					UserID:    5359,
+					Timestamp: time.Date(2016, 6, 26, 17, 22, 27, 0, time.UTC),
+					Text:      "Welcome to OSM!",
+				},
+				{
+					User:      "Glen Bundrick",
+					UserID:    4173877,
+					Timestamp: time.Date(2016, 6, 26, 20, 56, 11, 0, time.UTC),
+					Text:      "OK New to this and learning",
+				},
+			},
+		},
+	}
+
+	data, err := cs.Marshal()
+	if err != nil {
+		t.Fatalf("marshal error: %v", err)
+	}
+
+	cs2, err := UnmarshalChangeset(data)
+	if err != nil {
+		t.Fatalf("unmarshal error: %v", err)
+	}
+
+	if !reflect.DeepEqual(cs, cs2) {
+		t.Errorf("changesets not equal")
+		t.Logf("%+v", cs)
+		t.Logf("%+v", cs2)
+	}
+}
+
 func TestChangeset_MarshalXML(t *testing.T) {
 	cs := Changeset{
 		ID: 123,
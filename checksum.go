@@ -0,0 +1,92 @@
+package osm
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+
+	"github.com/gogo/protobuf/proto"
+)
+
+// ErrChecksumMismatch is returned when unmarshalling protocol buffer data
+// with a checksum that does not match the data. This usually means the data
+// was corrupted or truncated while stored.
+var ErrChecksumMismatch = errors.New("osm: checksum mismatch")
+
+// A MarshalOption changes how data is encoded by the Marshal methods.
+type MarshalOption func(*marshalOptions)
+
+type marshalOptions struct {
+	checksum      bool
+	rounding      RoundingMode
+	chunkSize     int
+	deterministic bool
+	dictionary    *Dictionary
+}
+
+func newMarshalOptions(opts []MarshalOption) *marshalOptions {
+	o := &marshalOptions{chunkSize: DefaultChunkSize}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+// WithChecksum will append a CRC32C checksum of the data to the encoding.
+// The Unmarshal functions verify the checksum, if present, and return
+// ErrChecksumMismatch if the data was modified. This is useful for long term
+// caches stored on disk or in object storage to catch silent corruption.
+// The result is still valid protocol buffer data and can be read by older
+// versions of this package, which will ignore the checksum.
+func WithChecksum() MarshalOption {
+	return func(o *marshalOptions) {
+		o.checksum = true
+	}
+}
+
+var crc32c = crc32.MakeTable(crc32.Castagnoli)
+
+// checksumTag is the key of the checksum field, number 17 and fixed32 type.
+var checksumTag = []byte{0x8d, 0x01}
+
+const checksumLen = 6
+
+// marshalProto encodes the message and appends the checksum field
+// if requested.
+func marshalProto(m proto.Message, o *marshalOptions) ([]byte, error) {
+	data, err := proto.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+
+	if !o.checksum {
+		return data, nil
+	}
+
+	var buf [4]byte
+	binary.LittleEndian.PutUint32(buf[:], crc32.Checksum(data, crc32c))
+
+	data = append(data, checksumTag...)
+	return append(data, buf[:]...), nil
+}
+
+// verifyChecksum validates the checksum, if set, of the decoded data.
+// The checksum must be the last field and match the preceding bytes.
+func verifyChecksum(data []byte, checksum *uint32) error {
+	if checksum == nil {
+		return nil
+	}
+
+	l := len(data) - checksumLen
+	if l < 0 || data[l] != checksumTag[0] || data[l+1] != checksumTag[1] {
+		return ErrChecksumMismatch
+	}
+
+	if binary.LittleEndian.Uint32(data[l+2:]) != *checksum ||
+		crc32.Checksum(data[:l], crc32c) != *checksum {
+		return ErrChecksumMismatch
+	}
+
+	return nil
+}
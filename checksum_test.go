@@ -0,0 +1,111 @@
+package osm
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestWithChecksum(t *testing.T) {
+	ts := time.Date(2018, 1, 2, 3, 4, 5, 0, time.UTC)
+	nodes := Nodes{
+		{ID: 1, Lat: 1, Lon: 2, Version: 3, Visible: true, Timestamp: ts, Tags: Tags{{Key: "name", Value: "corruptible"}}},
+	}
+	ways := Ways{
+		{ID: 2, Version: 1, Visible: true, Timestamp: ts, Nodes: WayNodes{{ID: 1}}, Tags: Tags{{Key: "name", Value: "corruptible"}}},
+	}
+
+	cases := []struct {
+		name      string
+		marshal   func(...MarshalOption) ([]byte, error)
+		unmarshal func([]byte) error
+	}{
+		{
+			name:    "osm",
+			marshal: (&OSM{Nodes: nodes, Ways: ways}).Marshal,
+			unmarshal: func(data []byte) error {
+				_, err := UnmarshalOSM(data)
+				return err
+			},
+		},
+		{
+			name:    "change",
+			marshal: (&Change{Create: &OSM{Nodes: nodes}}).Marshal,
+			unmarshal: func(data []byte) error {
+				_, err := UnmarshalChange(data)
+				return err
+			},
+		},
+		{
+			name:    "changeset",
+			marshal: (&Changeset{ID: 3, Tags: Tags{{Key: "comment", Value: "corruptible"}}}).Marshal,
+			unmarshal: func(data []byte) error {
+				_, err := UnmarshalChangeset(data)
+				return err
+			},
+		},
+		{
+			name:    "nodes",
+			marshal: nodes.Marshal,
+			unmarshal: func(data []byte) error {
+				_, err := UnmarshalNodes(data)
+				return err
+			},
+		},
+		{
+			name:    "ways",
+			marshal: ways.Marshal,
+			unmarshal: func(data []byte) error {
+				_, err := UnmarshalWays(data)
+				return err
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			plain, err := tc.marshal()
+			if err != nil {
+				t.Fatalf("marshal error: %v", err)
+			}
+
+			data, err := tc.marshal(WithChecksum())
+			if err != nil {
+				t.Fatalf("marshal error: %v", err)
+			}
+
+			if len(data) != len(plain)+checksumLen || !bytes.HasPrefix(data, plain) {
+				t.Errorf("checksum should be appended to the data")
+			}
+
+			if err := tc.unmarshal(data); err != nil {
+				t.Errorf("unmarshal error: %v", err)
+			}
+
+			// corrupt a string, still valid protobuf
+			i := bytes.Index(data, []byte("corruptible"))
+			if i == -1 {
+				t.Fatalf("string not found")
+			}
+
+			corrupt := append([]byte(nil), data...)
+			corrupt[i] = 'C'
+			if err := tc.unmarshal(corrupt); err != ErrChecksumMismatch {
+				t.Errorf("incorrect error: %v", err)
+			}
+
+			// corrupt the checksum
+			corrupt = append([]byte(nil), data...)
+			corrupt[len(corrupt)-1]++
+			if err := tc.unmarshal(corrupt); err != ErrChecksumMismatch {
+				t.Errorf("incorrect error: %v", err)
+			}
+
+			// checksum no longer the last field
+			corrupt = append(append([]byte(nil), data...), plain[:2]...)
+			if err := tc.unmarshal(corrupt); err == nil {
+				t.Errorf("expected error")
+			}
+		})
+	}
+}
@@ -0,0 +1,243 @@
+package osm
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+)
+
+// DefaultChunkSize is the encoded size, in bytes, above which the Marshal
+// methods split the nodes and osm data into chunks. Each chunk is its own
+// protocol buffer message so the data can be larger than the 2GB message
+// limit of most protocol buffer implementations.
+const DefaultChunkSize = 256 << 20
+
+// WithChunkSize sets the encoded size, in bytes, above which the nodes and
+// osm data are split into multiple independently encoded chunks. The chunks
+// are transparently reassembled by the Unmarshal functions. A value of zero
+// disables the chunking. The default is DefaultChunkSize.
+func WithChunkSize(n int) MarshalOption {
+	return func(o *marshalOptions) {
+		o.chunkSize = n
+	}
+}
+
+// chunkMagic starts chunked data. It is followed by the chunks, each
+// prefixed with its length as a uvarint. The first byte is field 9 with
+// wire type 7, which is not valid, so a single message never starts with it.
+var chunkMagic = []byte{0x4f, 0x53, 0x4d, 0x43} // OSMC
+
+// ErrInvalidChunk is returned when unmarshalling chunked data
+// that is truncated or has an invalid chunk length.
+var ErrInvalidChunk = errors.New("osm: invalid chunk")
+
+// encodeNodes returns the encoded nodes, split into multiple messages
+// if they are larger than the chunk size.
+func encodeNodes(ns Nodes, mo *marshalOptions) ([][]byte, error) {
+	ss := mo.newStringSet(func(ss *stringSet) {
+		marshalNodes(ns, ss, true, mo.rounding)
+	})
+
+	encoded := marshalNodes(ns, ss, true, mo.rounding)
+	encoded.Strings = ss.Strings()
+	if encoded.Size() != 0 {
+		// empty data is read the same by all versions
+		encoded.SchemaVersion = schemaVersion()
+		encoded.Dictionary = mo.dictionaryVersion()
+	}
+
+	if len(ns) < 2 || !mo.chunk(encoded.Size()) {
+		data, err := marshalProto(encoded, mo)
+		if err != nil {
+			return nil, err
+		}
+
+		return [][]byte{data}, nil
+	}
+
+	var chunks [][]byte
+	for _, part := range []Nodes{ns[:len(ns)/2], ns[len(ns)/2:]} {
+		c, err := encodeNodes(part, mo)
+		if err != nil {
+			return nil, err
+		}
+		chunks = append(chunks, c...)
+	}
+
+	return chunks, nil
+}
+
+func unmarshalNodeChunks(data []byte) (Nodes, error) {
+	chunks, err := splitChunks(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var result Nodes
+	for _, c := range chunks {
+		ns, err := UnmarshalNodes(c)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, ns...)
+	}
+
+	return result, nil
+}
+
+// encodeOSM returns the encoded osm data, split into multiple messages
+// if it is larger than the chunk size.
+func encodeOSM(o *OSM, mo *marshalOptions) ([][]byte, error) {
+	ss := mo.newStringSet(func(ss *stringSet) {
+		marshalOSM(o, ss, true, mo.rounding)
+	})
+
+	encoded := marshalOSM(o, ss, true, mo.rounding)
+	encoded.Strings = ss.Strings()
+	if encoded.Size() != 0 {
+		// empty data is read the same by all versions
+		encoded.SchemaVersion = schemaVersion()
+		encoded.Dictionary = mo.dictionaryVersion()
+	}
+
+	var a, b *OSM
+	if mo.chunk(encoded.Size()) {
+		a, b = o.split()
+	}
+
+	if a == nil {
+		data, err := marshalProto(encoded, mo)
+		if err != nil {
+			return nil, err
+		}
+
+		return [][]byte{data}, nil
+	}
+
+	var chunks [][]byte
+	for _, part := range []*OSM{a, b} {
+		c, err := encodeOSM(part, mo)
+		if err != nil {
+			return nil, err
+		}
+		chunks = append(chunks, c...)
+	}
+
+	return chunks, nil
+}
+
+// split divides the elements into two halves, keeping the order,
+// so they can be encoded separately. Returns nil if there is only
+// one element.
+func (o *OSM) split() (*OSM, *OSM) {
+	h := (len(o.Nodes) + len(o.Ways) + len(o.Relations)) / 2
+	if h == 0 {
+		return nil, nil
+	}
+
+	a := &OSM{Bounds: o.Bounds}
+	b := &OSM{}
+
+	n := h
+	if n > len(o.Nodes) {
+		n = len(o.Nodes)
+	}
+	a.Nodes, b.Nodes = o.Nodes[:n], o.Nodes[n:]
+	h -= n
+
+	n = h
+	if n > len(o.Ways) {
+		n = len(o.Ways)
+	}
+	a.Ways, b.Ways = o.Ways[:n], o.Ways[n:]
+	h -= n
+
+	a.Relations, b.Relations = o.Relations[:h], o.Relations[h:]
+	return a, b
+}
+
+func unmarshalOSMChunks(data []byte) (*OSM, error) {
+	chunks, err := splitChunks(data)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &OSM{}
+	for _, c := range chunks {
+		o, err := UnmarshalOSM(c)
+		if err != nil {
+			return nil, err
+		}
+
+		if result.Bounds == nil {
+			result.Bounds = o.Bounds
+		}
+
+		result.Nodes = append(result.Nodes, o.Nodes...)
+		result.Ways = append(result.Ways, o.Ways...)
+		result.Relations = append(result.Relations, o.Relations...)
+	}
+
+	return result, nil
+}
+
+// chunk returns true if an encoding of the size, plus the
+// checksum if requested, should be split into chunks.
+func (mo *marshalOptions) chunk(size int) bool {
+	if mo.checksum {
+		size += checksumLen
+	}
+
+	return mo.chunkSize > 0 && size > mo.chunkSize
+}
+
+// joinChunks returns the single message as is, or the
+// chunks after the magic bytes with their lengths.
+func joinChunks(chunks [][]byte) []byte {
+	if len(chunks) == 1 {
+		return chunks[0]
+	}
+
+	size := len(chunkMagic)
+	for _, c := range chunks {
+		size += binary.MaxVarintLen64 + len(c)
+	}
+
+	data := make([]byte, 0, size)
+	data = append(data, chunkMagic...)
+
+	var buf [binary.MaxVarintLen64]byte
+	for _, c := range chunks {
+		n := binary.PutUvarint(buf[:], uint64(len(c)))
+		data = append(data, buf[:n]...)
+		data = append(data, c...)
+	}
+
+	return data
+}
+
+// isChunked returns true if the data was written by joinChunks
+// as multiple chunks.
+func isChunked(data []byte) bool {
+	return bytes.HasPrefix(data, chunkMagic)
+}
+
+// splitChunks returns the chunks of the data written by joinChunks.
+// The chunks are slices of the data, not copies.
+func splitChunks(data []byte) ([][]byte, error) {
+	data = data[len(chunkMagic):]
+
+	var chunks [][]byte
+	for len(data) > 0 {
+		l, n := binary.Uvarint(data)
+		if n <= 0 || l > uint64(len(data)-n) {
+			return nil, ErrInvalidChunk
+		}
+
+		chunks = append(chunks, data[n:n+int(l)])
+		data = data[n+int(l):]
+	}
+
+	return chunks, nil
+}
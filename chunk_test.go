@@ -0,0 +1,178 @@
+package osm
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/paulmach/osm/internal/osmpb"
+)
+
+func TestWithChunkSize_nodes(t *testing.T) {
+	ns := chunkTestNodes(100)
+
+	data, err := ns.Marshal(WithChunkSize(500), WithChecksum())
+	if err != nil {
+		t.Fatalf("marshal error: %v", err)
+	}
+
+	// the chunks must not be one large message
+	if err := proto.Unmarshal(data, &osmpb.DenseNodes{}); err == nil {
+		t.Errorf("chunked data should not be a single message")
+	}
+
+	chunks, err := splitChunks(data)
+	if err != nil {
+		t.Fatalf("split error: %v", err)
+	}
+
+	if len(chunks) < 2 {
+		t.Fatalf("should split into chunks: %v", len(chunks))
+	}
+
+	var decoded Nodes
+	for i, c := range chunks {
+		if len(c) > 500 {
+			t.Errorf("chunk %d too large: %v", i, len(c))
+		}
+
+		// each chunk is decodable on its own
+		part, err := UnmarshalNodes(c)
+		if err != nil {
+			t.Fatalf("chunk %d unmarshal error: %v", i, err)
+		}
+		decoded = append(decoded, part...)
+	}
+
+	if !reflect.DeepEqual(decoded, ns) {
+		t.Errorf("chunk nodes not equal")
+	}
+
+	result, err := UnmarshalNodes(data)
+	if err != nil {
+		t.Fatalf("unmarshal error: %v", err)
+	}
+
+	if !reflect.DeepEqual(result, ns) {
+		t.Errorf("nodes not equal")
+	}
+
+	// chunking can be disabled
+	data, err = ns.Marshal(WithChunkSize(0))
+	if err != nil {
+		t.Fatalf("marshal error: %v", err)
+	}
+
+	if isChunked(data) {
+		t.Errorf("should not split into chunks")
+	}
+
+	if err := proto.Unmarshal(data, &osmpb.DenseNodes{}); err != nil {
+		t.Errorf("should be a single message: %v", err)
+	}
+}
+
+func TestWithChunkSize_osm(t *testing.T) {
+	o := &OSM{
+		Bounds: &Bounds{MinLat: 1, MaxLat: 2, MinLon: 3, MaxLon: 4},
+		Nodes:  chunkTestNodes(100),
+	}
+	o.Ways = append(o.Ways, loadOSM(t, "testdata/way-updates.osm").Ways...)
+	o.Relations = append(o.Relations, loadOSM(t, "testdata/relation-updates.osm").Relations...)
+
+	data, err := o.Marshal(WithChunkSize(500))
+	if err != nil {
+		t.Fatalf("marshal error: %v", err)
+	}
+
+	chunks, err := splitChunks(data)
+	if err != nil {
+		t.Fatalf("split error: %v", err)
+	}
+
+	if len(chunks) < 2 {
+		t.Fatalf("should split into chunks: %v", len(chunks))
+	}
+
+	size := len(chunkMagic)
+	for i, c := range chunks {
+		if _, err := UnmarshalOSM(c); err != nil {
+			t.Errorf("chunk %d unmarshal error: %v", i, err)
+		}
+
+		size += len(proto.EncodeVarint(uint64(len(c)))) + len(c)
+	}
+
+	if size != len(data) {
+		t.Errorf("data should only be the chunks: %v != %v", size, len(data))
+	}
+
+	result, err := UnmarshalOSM(data)
+	if err != nil {
+		t.Fatalf("unmarshal error: %v", err)
+	}
+
+	if !reflect.DeepEqual(result.Bounds, o.Bounds) {
+		t.Errorf("incorrect bounds: %v", result.Bounds)
+	}
+
+	if !reflect.DeepEqual(result.Nodes, o.Nodes) {
+		t.Errorf("nodes not equal")
+	}
+
+	if !reflect.DeepEqual(result.Ways, o.Ways) {
+		t.Errorf("ways not equal")
+	}
+
+	if !reflect.DeepEqual(result.Relations, o.Relations) {
+		t.Errorf("relations not equal")
+	}
+
+	// truncated chunked data
+	if _, err := UnmarshalOSM(data[:len(data)-10]); err != ErrInvalidChunk {
+		t.Errorf("expected invalid chunk error, got %v", err)
+	}
+}
+
+func TestOSM_split(t *testing.T) {
+	o := &OSM{
+		Nodes:     Nodes{{ID: 1}, {ID: 2}},
+		Ways:      Ways{{ID: 3}, {ID: 4}},
+		Relations: Relations{{ID: 5}},
+	}
+
+	a, b := o.split()
+	if v := a.Elements().ElementIDs(); len(v) != 2 {
+		t.Errorf("incorrect first half: %v", v)
+	}
+
+	if v := b.Elements().ElementIDs(); len(v) != 3 || v[0].Type() != TypeWay {
+		t.Errorf("incorrect second half: %v", v)
+	}
+
+	a, _ = (&OSM{Nodes: Nodes{{ID: 1}}}).split()
+	if a != nil {
+		t.Errorf("should not split one element")
+	}
+}
+
+func chunkTestNodes(n int) Nodes {
+	ts := time.Date(2018, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	ns := make(Nodes, 0, n)
+	for i := 0; i < n; i++ {
+		ns = append(ns, &Node{
+			ID:        NodeID(i + 1),
+			Lat:       float64(i) / 10,
+			Lon:       float64(-i) / 10,
+			Version:   1,
+			Visible:   true,
+			Timestamp: ts,
+			Tags:      Tags{{Key: "name", Value: fmt.Sprintf("node %d", i)}},
+		})
+	}
+
+	return ns
+}
@@ -0,0 +1,367 @@
+// Package clip truncates way and polygon geometry at the edge of a bound
+// or a convex polygon, instead of including or dropping whole ways.
+package clip
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/osm"
+)
+
+// ErrNotConvex is returned when creating a clipper from a polygon
+// that is not convex.
+var ErrNotConvex = errors.New("clip: polygon must be convex")
+
+// A Clipper clips geometry to a convex area. Lines are clipped using the
+// Cyrus–Beck algorithm, the generalization of Liang–Barsky, and rings using
+// Sutherland–Hodgman. It is safe for concurrent use.
+type Clipper struct {
+	// the counter-clockwise edges of the area,
+	// the inside is to the left of every edge.
+	edges [][2]orb.Point
+}
+
+// NewBound creates a clipper for the bound.
+func NewBound(b orb.Bound) *Clipper {
+	r := orb.Ring{
+		b.Min,
+		{b.Max[0], b.Min[1]},
+		b.Max,
+		{b.Min[0], b.Max[1]},
+		b.Min,
+	}
+
+	return newClipper(r)
+}
+
+// NewPolygon creates a clipper for the convex ring, in either orientation.
+// Returns ErrNotConvex if the ring is not convex, the algorithms
+// do not work with concave clip areas.
+func NewPolygon(r orb.Ring) (*Clipper, error) {
+	pts := r
+	if len(pts) > 1 && pts[0] == pts[len(pts)-1] {
+		pts = pts[:len(pts)-1]
+	}
+
+	if len(pts) < 3 {
+		return nil, errors.New("clip: polygon must have at least 3 points")
+	}
+
+	ring := make(orb.Ring, 0, len(pts)+1)
+	ring = append(ring, pts...)
+	ring = append(ring, pts[0])
+	if ring.Orientation() == orb.CW {
+		ring.Reverse()
+	}
+
+	n := len(ring) - 1
+	for i := 0; i < n; i++ {
+		if cross(ring[i], ring[(i+1)%n], ring[(i+2)%n]) < 0 {
+			return nil, ErrNotConvex
+		}
+	}
+
+	return newClipper(ring), nil
+}
+
+func newClipper(r orb.Ring) *Clipper {
+	c := &Clipper{edges: make([][2]orb.Point, 0, len(r)-1)}
+	for i := 0; i < len(r)-1; i++ {
+		if r[i] != r[i+1] {
+			c.edges = append(c.edges, [2]orb.Point{r[i], r[i+1]})
+		}
+	}
+
+	return c
+}
+
+// Way clips the geometry of the way. The way nodes must have locations,
+// i.e. be annotated. Closed ways that are polygons, see osm.Way.Polygon,
+// are clipped as polygons, other ways as lines. Returns nil if the way
+// is completely outside the area.
+func (c *Clipper) Way(w *osm.Way) (orb.Geometry, error) {
+	ls := make(orb.LineString, 0, len(w.Nodes))
+	for _, n := range w.Nodes {
+		if n.Lat == 0 && n.Lon == 0 {
+			return nil, fmt.Errorf("clip: way %d: node %d missing location", w.ID, n.ID)
+		}
+
+		ls = append(ls, orb.Point{n.Lon, n.Lat})
+	}
+
+	if w.Polygon() {
+		return c.Geometry(orb.Polygon{orb.Ring(ls)}), nil
+	}
+
+	return c.Geometry(ls), nil
+}
+
+// Geometry clips the geometry to the area. Points, lines and polygons,
+// and their multi and collection versions, are supported. Returns nil if
+// the result is empty or the geometry type is not supported.
+func (c *Clipper) Geometry(g orb.Geometry) orb.Geometry {
+	switch g := g.(type) {
+	case orb.Point:
+		if c.contains(g) {
+			return g
+		}
+	case orb.MultiPoint:
+		var mp orb.MultiPoint
+		for _, p := range g {
+			if c.contains(p) {
+				mp = append(mp, p)
+			}
+		}
+
+		if len(mp) > 0 {
+			return mp
+		}
+	case orb.LineString:
+		if mls := c.LineString(g); mls != nil {
+			return mls
+		}
+	case orb.MultiLineString:
+		var result orb.MultiLineString
+		for _, ls := range g {
+			result = append(result, c.LineString(ls)...)
+		}
+
+		if len(result) > 0 {
+			return result
+		}
+	case orb.Ring:
+		if r := c.Ring(g); r != nil {
+			return r
+		}
+	case orb.Polygon:
+		if p := c.Polygon(g); p != nil {
+			return p
+		}
+	case orb.MultiPolygon:
+		var result orb.MultiPolygon
+		for _, p := range g {
+			if p := c.Polygon(p); p != nil {
+				result = append(result, p)
+			}
+		}
+
+		if len(result) > 0 {
+			return result
+		}
+	case orb.Collection:
+		var result orb.Collection
+		for _, geo := range g {
+			if geo := c.Geometry(geo); geo != nil {
+				result = append(result, geo)
+			}
+		}
+
+		if len(result) > 0 {
+			return result
+		}
+	}
+
+	return nil
+}
+
+// LineString clips the line to the area. The result has multiple lines
+// if the line leaves and enters the area. Returns nil if the line
+// is completely outside.
+func (c *Clipper) LineString(ls orb.LineString) orb.MultiLineString {
+	if len(ls) == 1 {
+		if c.contains(ls[0]) {
+			return orb.MultiLineString{{ls[0]}}
+		}
+
+		return nil
+	}
+
+	var (
+		result  orb.MultiLineString
+		current orb.LineString
+	)
+
+	for i := 0; i < len(ls)-1; i++ {
+		p0, p1 := ls[i], ls[i+1]
+		enter, exit, ok := c.segment(p0, p1)
+		if !ok {
+			continue
+		}
+
+		start, end := interpolate(p0, p1, enter), interpolate(p0, p1, exit)
+		if enter == 0 && len(current) > 0 && current[len(current)-1] == start {
+			current = append(current, end)
+		} else {
+			if len(current) > 0 {
+				result = append(result, current)
+			}
+			current = orb.LineString{start, end}
+		}
+
+		if exit != 1 {
+			result = append(result, current)
+			current = nil
+		}
+	}
+
+	if len(current) > 0 {
+		result = append(result, current)
+	}
+
+	return result
+}
+
+// segment returns the parameter range of p0->p1 inside the area.
+func (c *Clipper) segment(p0, p1 orb.Point) (float64, float64, bool) {
+	enter, exit := 0.0, 1.0
+	for _, e := range c.edges {
+		f0 := cross(e[0], e[1], p0)
+		f1 := cross(e[0], e[1], p1)
+
+		if f0 < 0 && f1 < 0 {
+			return 0, 0, false
+		}
+
+		if f0 >= 0 && f1 >= 0 {
+			continue
+		}
+
+		t := f0 / (f0 - f1)
+		if f0 < 0 {
+			enter = max(enter, t)
+		} else {
+			exit = min(exit, t)
+		}
+
+		if enter > exit {
+			return 0, 0, false
+		}
+	}
+
+	if enter == exit && p0 != p1 {
+		// only touches the area
+		return 0, 0, false
+	}
+
+	return enter, exit, true
+}
+
+// Ring clips the ring to the area, the orientation is preserved.
+// Parts of the ring outside the area are replaced by the area's edge.
+// Returns nil if the ring is completely outside.
+func (c *Clipper) Ring(r orb.Ring) orb.Ring {
+	if len(r) == 0 {
+		return nil
+	}
+
+	pts := []orb.Point(r)
+	if pts[0] == pts[len(pts)-1] {
+		pts = pts[:len(pts)-1]
+	}
+
+	for _, e := range c.edges {
+		if len(pts) == 0 {
+			return nil
+		}
+
+		input := pts
+		pts = make([]orb.Point, 0, len(input)+2)
+
+		prev := input[len(input)-1]
+		prevIn := cross(e[0], e[1], prev) >= 0
+		for _, p := range input {
+			in := cross(e[0], e[1], p) >= 0
+			if in != prevIn {
+				pts = append(pts, edgeIntersection(e, prev, p))
+			}
+
+			if in {
+				pts = append(pts, p)
+			}
+
+			prev, prevIn = p, in
+		}
+	}
+
+	if len(pts) < 3 {
+		return nil
+	}
+
+	return append(orb.Ring(pts), pts[0])
+}
+
+// Polygon clips the rings of the polygon. Returns nil if the outer ring
+// is completely outside the area. Inner rings outside the area are removed.
+func (c *Clipper) Polygon(p orb.Polygon) orb.Polygon {
+	if len(p) == 0 {
+		return nil
+	}
+
+	outer := c.Ring(p[0])
+	if outer == nil {
+		return nil
+	}
+
+	result := orb.Polygon{outer}
+	for _, r := range p[1:] {
+		if r := c.Ring(r); r != nil {
+			result = append(result, r)
+		}
+	}
+
+	return result
+}
+
+func (c *Clipper) contains(p orb.Point) bool {
+	for _, e := range c.edges {
+		if cross(e[0], e[1], p) < 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// edgeIntersection returns where the segment a-b crosses the edge line.
+func edgeIntersection(e [2]orb.Point, a, b orb.Point) orb.Point {
+	fa := cross(e[0], e[1], a)
+	fb := cross(e[0], e[1], b)
+
+	return interpolate(a, b, fa/(fa-fb))
+}
+
+func interpolate(a, b orb.Point, t float64) orb.Point {
+	switch t {
+	case 0:
+		return a
+	case 1:
+		return b
+	}
+
+	return orb.Point{
+		a[0] + t*(b[0]-a[0]),
+		a[1] + t*(b[1]-a[1]),
+	}
+}
+
+// cross is the z component of (b-a) x (p-a),
+// positive if p is to the left of a->b.
+func cross(a, b, p orb.Point) float64 {
+	return (b[0]-a[0])*(p[1]-a[1]) - (b[1]-a[1])*(p[0]-a[0])
+}
+
+func min(a, b float64) float64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max(a, b float64) float64 {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,242 @@
+package clip
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/osm"
+)
+
+var bound = orb.Bound{Min: orb.Point{0, 0}, Max: orb.Point{10, 10}}
+
+func TestClipper_LineString(t *testing.T) {
+	cases := []struct {
+		name     string
+		input    orb.LineString
+		expected orb.MultiLineString
+	}{
+		{
+			name:     "inside",
+			input:    orb.LineString{{1, 1}, {2, 2}, {3, 1}},
+			expected: orb.MultiLineString{{{1, 1}, {2, 2}, {3, 1}}},
+		},
+		{
+			name:     "outside",
+			input:    orb.LineString{{-1, -1}, {-2, 5}, {-1, 11}},
+			expected: nil,
+		},
+		{
+			name:     "crosses",
+			input:    orb.LineString{{-5, 5}, {5, 5}, {15, 5}},
+			expected: orb.MultiLineString{{{0, 5}, {5, 5}, {10, 5}}},
+		},
+		{
+			name:     "leaves and enters",
+			input:    orb.LineString{{5, 5}, {15, 5}, {15, 8}, {5, 8}},
+			expected: orb.MultiLineString{{{5, 5}, {10, 5}}, {{10, 8}, {5, 8}}},
+		},
+		{
+			name:     "touches corner",
+			input:    orb.LineString{{-5, 5}, {5, 15}},
+			expected: nil,
+		},
+		{
+			name:     "single point",
+			input:    orb.LineString{{5, 5}},
+			expected: orb.MultiLineString{{{5, 5}}},
+		},
+	}
+
+	c := NewBound(bound)
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			result := c.LineString(tc.input)
+			if !reflect.DeepEqual(result, tc.expected) {
+				t.Errorf("incorrect result: %v", result)
+			}
+		})
+	}
+}
+
+func TestClipper_Ring(t *testing.T) {
+	c := NewBound(bound)
+
+	// inside
+	r := orb.Ring{{1, 1}, {2, 1}, {2, 2}, {1, 1}}
+	if result := c.Ring(r); !reflect.DeepEqual(result, r) {
+		t.Errorf("incorrect result: %v", result)
+	}
+
+	// overlaps the right edge
+	r = orb.Ring{{5, 2}, {15, 2}, {15, 4}, {5, 4}, {5, 2}}
+	expected := orb.Ring{{5, 2}, {10, 2}, {10, 4}, {5, 4}, {5, 2}}
+	result := c.Ring(r)
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("incorrect result: %v", result)
+	}
+
+	if result.Orientation() != r.Orientation() {
+		t.Errorf("should preserve orientation")
+	}
+
+	// outside
+	r = orb.Ring{{15, 2}, {25, 2}, {25, 4}, {15, 2}}
+	if result := c.Ring(r); result != nil {
+		t.Errorf("should be nil: %v", result)
+	}
+
+	// contains the area
+	r = orb.Ring{{-5, -5}, {15, -5}, {15, 15}, {-5, 15}, {-5, -5}}
+	result = c.Ring(r)
+	if b := result.Bound(); b != bound {
+		t.Errorf("should be the clip area: %v", result)
+	}
+}
+
+func TestClipper_Polygon(t *testing.T) {
+	c := NewBound(bound)
+
+	p := orb.Polygon{
+		{{5, 2}, {15, 2}, {15, 8}, {5, 8}, {5, 2}},
+		{{6, 3}, {6, 4}, {7, 4}, {6, 3}},
+		{{12, 3}, {12, 4}, {13, 4}, {12, 3}},
+	}
+
+	result := c.Polygon(p)
+	if len(result) != 2 {
+		t.Fatalf("should remove the inner ring outside: %v", result)
+	}
+
+	if !reflect.DeepEqual(result[1], p[1]) {
+		t.Errorf("incorrect inner ring: %v", result[1])
+	}
+
+	if result := c.Polygon(orb.Polygon{{{12, 3}, {12, 4}, {13, 4}, {12, 3}}}); result != nil {
+		t.Errorf("should be nil: %v", result)
+	}
+}
+
+func TestClipper_Geometry(t *testing.T) {
+	c := NewBound(bound)
+
+	cases := []struct {
+		name     string
+		input    orb.Geometry
+		expected orb.Geometry
+	}{
+		{
+			name:     "point inside",
+			input:    orb.Point{1, 1},
+			expected: orb.Point{1, 1},
+		},
+		{
+			name:     "point outside",
+			input:    orb.Point{11, 1},
+			expected: nil,
+		},
+		{
+			name:     "multi point",
+			input:    orb.MultiPoint{{1, 1}, {11, 1}},
+			expected: orb.MultiPoint{{1, 1}},
+		},
+		{
+			name:     "line string outside",
+			input:    orb.LineString{{11, 1}, {12, 1}},
+			expected: nil,
+		},
+		{
+			name:     "collection",
+			input:    orb.Collection{orb.Point{11, 1}, orb.LineString{{5, 5}, {15, 5}}},
+			expected: orb.Collection{orb.MultiLineString{{{5, 5}, {10, 5}}}},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			result := c.Geometry(tc.input)
+			if !reflect.DeepEqual(result, tc.expected) {
+				t.Errorf("incorrect result: %v", result)
+			}
+		})
+	}
+}
+
+func TestClipper_Way(t *testing.T) {
+	c := NewBound(bound)
+
+	w := &osm.Way{
+		ID: 1,
+		Nodes: osm.WayNodes{
+			{ID: 1, Lon: 5, Lat: 5},
+			{ID: 2, Lon: 15, Lat: 5},
+		},
+	}
+
+	g, err := c.Way(w)
+	if err != nil {
+		t.Fatalf("clip error: %v", err)
+	}
+
+	expected := orb.MultiLineString{{{5, 5}, {10, 5}}}
+	if !reflect.DeepEqual(g, expected) {
+		t.Errorf("incorrect geometry: %v", g)
+	}
+
+	// closed area
+	w.Nodes = osm.WayNodes{
+		{ID: 1, Lon: 5, Lat: 2},
+		{ID: 2, Lon: 15, Lat: 2},
+		{ID: 3, Lon: 15, Lat: 4},
+		{ID: 4, Lon: 5, Lat: 4},
+		{ID: 1, Lon: 5, Lat: 2},
+	}
+	w.Tags = osm.Tags{{Key: "building", Value: "yes"}}
+
+	g, err = c.Way(w)
+	if err != nil {
+		t.Fatalf("clip error: %v", err)
+	}
+
+	if _, ok := g.(orb.Polygon); !ok {
+		t.Errorf("should be a polygon: %T", g)
+	}
+
+	// outside
+	w.Nodes = osm.WayNodes{{ID: 1, Lon: 15, Lat: 5}, {ID: 2, Lon: 16, Lat: 5}}
+	w.Tags = nil
+	g, err = c.Way(w)
+	if err != nil || g != nil {
+		t.Errorf("should be nil: %v %v", g, err)
+	}
+
+	// not annotated
+	w.Nodes = osm.WayNodes{{ID: 1}, {ID: 2}}
+	if _, err := c.Way(w); err == nil {
+		t.Errorf("expected error")
+	}
+}
+
+func TestNewPolygon(t *testing.T) {
+	// clockwise triangle
+	c, err := NewPolygon(orb.Ring{{0, 0}, {0, 10}, {10, 0}, {0, 0}})
+	if err != nil {
+		t.Fatalf("create error: %v", err)
+	}
+
+	result := c.LineString(orb.LineString{{-5, 5}, {15, 5}})
+	expected := orb.MultiLineString{{{0, 5}, {5, 5}}}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("incorrect result: %v", result)
+	}
+
+	_, err = NewPolygon(orb.Ring{{0, 0}, {10, 0}, {5, 2}, {10, 10}, {0, 10}, {0, 0}})
+	if err != ErrNotConvex {
+		t.Errorf("incorrect error: %v", err)
+	}
+
+	_, err = NewPolygon(orb.Ring{{0, 0}, {10, 0}})
+	if err == nil {
+		t.Errorf("expected error for too few points")
+	}
+}
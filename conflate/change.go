@@ -0,0 +1,82 @@
+package conflate
+
+import (
+	"fmt"
+
+	"github.com/paulmach/osm"
+)
+
+// Build adds the edits for an import to the change builder. Unmatched
+// points are created as new nodes with ids from the allocator. Matched
+// nodes and ways get the tags of the point they do not already have,
+// existing values are never overwritten. Ambiguous results are skipped
+// since they need manual review.
+func (r Report) Build(b *osm.ChangeBuilder, a *osm.IDAllocator) error {
+	for _, result := range r {
+		switch result.Status {
+		case NoMatch:
+			p := result.Point
+			n := a.NewNode(p.Location.Lat(), p.Location.Lon(), append(osm.Tags(nil), p.Tags...))
+			if err := b.Create(n); err != nil {
+				return err
+			}
+		case Match:
+			e, err := merge(result.Best().Element, result.Point.Tags)
+			if err != nil {
+				return err
+			}
+
+			if e == nil {
+				continue
+			}
+
+			if err := b.Modify(e); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// merge returns a copy of the element with the missing tags added,
+// or nil if there are none.
+func merge(e osm.Element, tags osm.Tags) (osm.Element, error) {
+	switch e := e.(type) {
+	case *osm.Node:
+		if t, ok := mergeTags(e.Tags, tags); ok {
+			n := e.Copy()
+			n.Tags = t
+			return n, nil
+		}
+	case *osm.Way:
+		if t, ok := mergeTags(e.Tags, tags); ok {
+			w := e.Copy()
+			w.Tags = t
+			return w, nil
+		}
+	default:
+		return nil, fmt.Errorf("conflate: unsupported element type %T", e)
+	}
+
+	return nil, nil
+}
+
+func mergeTags(existing, add osm.Tags) (osm.Tags, bool) {
+	result := existing
+	changed := false
+	for _, t := range add {
+		if existing.Find(t.Key) != "" {
+			continue
+		}
+
+		if !changed {
+			result = append(osm.Tags(nil), existing...)
+			changed = true
+		}
+
+		result = append(result, t)
+	}
+
+	return result, changed
+}
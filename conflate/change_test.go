@@ -0,0 +1,74 @@
+package conflate
+
+import (
+	"testing"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/osm"
+)
+
+func TestReport_Build(t *testing.T) {
+	o := testOSM()
+	m, _ := NewMatcher()
+	m.AddOSM(o)
+
+	report := m.MatchAll([]*Point{
+		// adds the opening hours
+		{Location: orb.Point{4.0, 52.0}, Tags: osm.Tags{
+			{Key: "name", Value: "Café Central"},
+			{Key: "amenity", Value: "restaurant"},
+			{Key: "opening_hours", Value: "Mo-Fr 08:00-17:00"},
+		}},
+		// nothing new
+		{Location: orb.Point{4.0001, 52.0031}, Tags: osm.Tags{{Key: "name", Value: "Town Hall"}}},
+		// ambiguous, skipped
+		{Location: orb.Point{4.0, 52.00105}, Tags: osm.Tags{{Key: "name", Value: "The Anchor"}}},
+		// new
+		{Location: orb.Point{4.1, 52.1}, Tags: osm.Tags{{Key: "name", Value: "New Place"}}},
+	})
+
+	b := osm.NewChangeBuilder()
+	if err := report.Build(b, &osm.IDAllocator{}); err != nil {
+		t.Fatalf("build error: %v", err)
+	}
+
+	changes, err := b.Build()
+	if err != nil {
+		t.Fatalf("build error: %v", err)
+	}
+
+	if len(changes) != 1 {
+		t.Fatalf("incorrect number of changes: %v", len(changes))
+	}
+	c := changes[0]
+
+	if len(c.Create.Nodes) != 1 {
+		t.Fatalf("incorrect creates: %v", c.Create)
+	}
+
+	n := c.Create.Nodes[0]
+	if n.ID != -1 || n.Lat != 52.1 || n.Lon != 4.1 || n.Tags.Find("name") != "New Place" {
+		t.Errorf("incorrect created node: %+v", n)
+	}
+
+	if len(c.Modify.Nodes) != 1 || len(c.Modify.Ways) != 0 {
+		t.Fatalf("incorrect modifies: %v", c.Modify)
+	}
+
+	n = c.Modify.Nodes[0]
+	if n.ID != 1 || n.Version != 3 {
+		t.Errorf("incorrect modified node: %+v", n)
+	}
+
+	if v := n.Tags.Find("amenity"); v != "cafe" {
+		t.Errorf("should not overwrite existing tags: %v", v)
+	}
+
+	if v := n.Tags.Find("opening_hours"); v != "Mo-Fr 08:00-17:00" {
+		t.Errorf("should add missing tags: %v", v)
+	}
+
+	if len(o.Nodes[0].Tags) != 2 {
+		t.Errorf("should not modify the original: %v", o.Nodes[0].Tags)
+	}
+}
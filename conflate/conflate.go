@@ -0,0 +1,275 @@
+// Package conflate matches external point datasets against osm elements
+// by distance and name or tag similarity, for reviewing and preparing
+// imports.
+package conflate
+
+import (
+	"math"
+	"sort"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geo"
+	"github.com/paulmach/osm"
+)
+
+// Point is a feature in the external dataset.
+type Point struct {
+	// ID identifies the point in the external dataset, it is only
+	// used for reporting.
+	ID       string
+	Location orb.Point
+	Tags     osm.Tags
+}
+
+// Candidate is an osm element near a point with its match score.
+type Candidate struct {
+	Element  osm.Element
+	Location orb.Point
+	Tags     osm.Tags
+
+	// Distance to the point in meters.
+	Distance float64
+
+	// Score is the weighted average of the scorers, between 0 and 1.
+	Score float64
+}
+
+// Status is the outcome of matching a point.
+type Status int
+
+// The match statuses.
+const (
+	NoMatch Status = iota
+	Match
+	Ambiguous
+)
+
+var statusStrings = [...]string{
+	NoMatch:   "no match",
+	Match:     "match",
+	Ambiguous: "ambiguous",
+}
+
+func (s Status) String() string {
+	if s < 0 || int(s) >= len(statusStrings) {
+		return "unknown"
+	}
+
+	return statusStrings[s]
+}
+
+// Result is the outcome of matching a point.
+type Result struct {
+	Point  *Point
+	Status Status
+
+	// Candidates are the elements within the max distance scoring
+	// above the threshold, the best first.
+	Candidates []*Candidate
+}
+
+// Best returns the best candidate or nil if there are none.
+func (r *Result) Best() *Candidate {
+	if len(r.Candidates) == 0 {
+		return nil
+	}
+
+	return r.Candidates[0]
+}
+
+type entry struct {
+	element  osm.Element
+	location orb.Point
+	tags     osm.Tags
+}
+
+type weightedScorer struct {
+	scorer Scorer
+	weight float64
+}
+
+// Matcher matches points against a set of osm elements.
+// It is safe for concurrent matching once all elements have been added.
+type Matcher struct {
+	maxDistance float64
+	threshold   float64
+	margin      float64
+	scorers     []weightedScorer
+
+	cellSize float64
+	cells    map[[2]int][]*entry
+}
+
+// NewMatcher creates a new matcher. By default candidates must be within
+// 50 meters and are scored equally by distance and name similarity.
+func NewMatcher(opts ...Option) (*Matcher, error) {
+	m := &Matcher{
+		maxDistance: 50,
+		threshold:   0.6,
+		margin:      0.1,
+	}
+
+	for _, o := range opts {
+		if err := o(m); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(m.scorers) == 0 {
+		m.scorers = []weightedScorer{
+			{scorer: DistanceScorer(m.maxDistance), weight: 1},
+			{scorer: NameScorer(), weight: 1},
+		}
+	}
+
+	// about the max distance in latitude degrees
+	m.cellSize = m.maxDistance / 111000
+	m.cells = make(map[[2]int][]*entry)
+
+	return m, nil
+}
+
+// AddOSM adds the nodes and ways with tags. The ways are located at the
+// center of their bound so the way nodes must be annotated with their
+// locations. Elements without tags, e.g. the nodes of ways, are skipped.
+func (m *Matcher) AddOSM(o *osm.OSM) {
+	for _, n := range o.Nodes {
+		if len(n.Tags) != 0 {
+			m.Add(n, n.Point(), n.Tags)
+		}
+	}
+
+	for _, w := range o.Ways {
+		if len(w.Tags) == 0 || len(w.Nodes) == 0 {
+			continue
+		}
+
+		b := w.Nodes.Bound()
+		if b.IsZero() {
+			continue
+		}
+
+		m.Add(w, b.Center(), w.Tags)
+	}
+}
+
+// Add adds an element at the location to be matched against.
+func (m *Matcher) Add(e osm.Element, location orb.Point, tags osm.Tags) {
+	key := m.cell(location)
+	m.cells[key] = append(m.cells[key], &entry{
+		element:  e,
+		location: location,
+		tags:     tags,
+	})
+}
+
+// Match finds the best candidate for the point. The result is a match if
+// the best candidate is above the threshold and no other candidate is
+// within the ambiguity margin of it.
+func (m *Matcher) Match(p *Point) *Result {
+	result := &Result{Point: p}
+
+	b := geo.NewBoundAroundPoint(p.Location, m.maxDistance)
+	min, max := m.cell(b.Min), m.cell(b.Max)
+
+	for x := min[0]; x <= max[0]; x++ {
+		for y := min[1]; y <= max[1]; y++ {
+			for _, e := range m.cells[[2]int{x, y}] {
+				c := &Candidate{
+					Element:  e.element,
+					Location: e.location,
+					Tags:     e.tags,
+					Distance: geo.Distance(p.Location, e.location),
+				}
+
+				if c.Distance > m.maxDistance {
+					continue
+				}
+
+				c.Score = m.score(p, c)
+				if c.Score >= m.threshold {
+					result.Candidates = append(result.Candidates, c)
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(result.Candidates, func(i, j int) bool {
+		ci, cj := result.Candidates[i], result.Candidates[j]
+		if ci.Score != cj.Score {
+			return ci.Score > cj.Score
+		}
+
+		return ci.Distance < cj.Distance
+	})
+
+	switch {
+	case len(result.Candidates) == 0:
+		result.Status = NoMatch
+	case len(result.Candidates) > 1 &&
+		result.Candidates[0].Score-result.Candidates[1].Score < m.margin:
+		result.Status = Ambiguous
+	default:
+		result.Status = Match
+	}
+
+	return result
+}
+
+// MatchAll matches all the points.
+func (m *Matcher) MatchAll(points []*Point) Report {
+	report := make(Report, 0, len(points))
+	for _, p := range points {
+		report = append(report, m.Match(p))
+	}
+
+	return report
+}
+
+func (m *Matcher) score(p *Point, c *Candidate) float64 {
+	var total, weights float64
+	for _, s := range m.scorers {
+		total += s.weight * s.scorer(p, c)
+		weights += s.weight
+	}
+
+	if weights == 0 {
+		return 0
+	}
+
+	return total / weights
+}
+
+func (m *Matcher) cell(p orb.Point) [2]int {
+	return [2]int{
+		int(math.Floor(p.Lon() / m.cellSize)),
+		int(math.Floor(p.Lat() / m.cellSize)),
+	}
+}
+
+// Report is the results of matching a set of points.
+type Report []*Result
+
+// Count returns the number of results with the status.
+func (r Report) Count(s Status) int {
+	count := 0
+	for _, result := range r {
+		if result.Status == s {
+			count++
+		}
+	}
+
+	return count
+}
+
+// Filter returns the results with the status.
+func (r Report) Filter(s Status) Report {
+	var result Report
+	for _, res := range r {
+		if res.Status == s {
+			result = append(result, res)
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,142 @@
+package conflate
+
+import (
+	"testing"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/osm"
+)
+
+func testOSM() *osm.OSM {
+	return &osm.OSM{
+		Nodes: osm.Nodes{
+			{ID: 1, Version: 3, Lat: 52.0000, Lon: 4.0000, Tags: osm.Tags{{Key: "amenity", Value: "cafe"}, {Key: "name", Value: "Café Central"}}},
+			{ID: 2, Version: 1, Lat: 52.0010, Lon: 4.0000, Tags: osm.Tags{{Key: "amenity", Value: "pub"}, {Key: "name", Value: "The Anchor"}}},
+			{ID: 3, Version: 1, Lat: 52.0011, Lon: 4.0000, Tags: osm.Tags{{Key: "amenity", Value: "pub"}, {Key: "name", Value: "The Anchor"}}},
+			{ID: 4, Version: 1, Lat: 52.0020, Lon: 4.0000},
+		},
+		Ways: osm.Ways{
+			{
+				ID:      10,
+				Version: 2,
+				Tags:    osm.Tags{{Key: "building", Value: "yes"}, {Key: "name", Value: "Town Hall"}},
+				Nodes: osm.WayNodes{
+					{ID: 11, Lat: 52.0030, Lon: 4.0000},
+					{ID: 12, Lat: 52.0030, Lon: 4.0002},
+					{ID: 13, Lat: 52.0032, Lon: 4.0002},
+					{ID: 11, Lat: 52.0030, Lon: 4.0000},
+				},
+			},
+		},
+	}
+}
+
+func TestMatcher_Match(t *testing.T) {
+	m, err := NewMatcher()
+	if err != nil {
+		t.Fatalf("create error: %v", err)
+	}
+	m.AddOSM(testOSM())
+
+	cases := []struct {
+		name   string
+		point  *Point
+		status Status
+		best   osm.FeatureID
+	}{
+		{
+			name:   "match",
+			point:  &Point{Location: orb.Point{4.0001, 52.0001}, Tags: osm.Tags{{Key: "name", Value: "cafe central"}}},
+			status: Match,
+			best:   osm.NodeID(1).FeatureID(),
+		},
+		{
+			name:   "match way",
+			point:  &Point{Location: orb.Point{4.0001, 52.0031}, Tags: osm.Tags{{Key: "name", Value: "Town Hall"}}},
+			status: Match,
+			best:   osm.WayID(10).FeatureID(),
+		},
+		{
+			name:   "ambiguous",
+			point:  &Point{Location: orb.Point{4.0000, 52.00105}, Tags: osm.Tags{{Key: "name", Value: "The Anchor"}}},
+			status: Ambiguous,
+		},
+		{
+			name:   "name does not match",
+			point:  &Point{Location: orb.Point{4.0000, 52.0000}, Tags: osm.Tags{{Key: "name", Value: "Bakery Smit"}}},
+			status: NoMatch,
+		},
+		{
+			name:   "too far",
+			point:  &Point{Location: orb.Point{4.0100, 52.0000}, Tags: osm.Tags{{Key: "name", Value: "Café Central"}}},
+			status: NoMatch,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := m.Match(tc.point)
+			if r.Status != tc.status {
+				t.Fatalf("incorrect status: %v", r.Status)
+			}
+
+			if tc.status == Match && r.Best().Element.FeatureID() != tc.best {
+				t.Errorf("incorrect best: %v", r.Best().Element.FeatureID())
+			}
+		})
+	}
+}
+
+func TestMatcher_scorers(t *testing.T) {
+	m, err := NewMatcher(
+		MaxDistance(100),
+		Threshold(0.9),
+		WithScorer(TagScorer("amenity"), 1),
+	)
+	if err != nil {
+		t.Fatalf("create error: %v", err)
+	}
+	m.AddOSM(testOSM())
+
+	r := m.Match(&Point{Location: orb.Point{4.0, 52.0}, Tags: osm.Tags{{Key: "amenity", Value: "cafe"}}})
+	if r.Status != Match || r.Best().Element.FeatureID() != osm.NodeID(1).FeatureID() {
+		t.Errorf("incorrect result: %v %v", r.Status, r.Candidates)
+	}
+
+	if _, err := NewMatcher(MaxDistance(0)); err == nil {
+		t.Errorf("should error for invalid max distance")
+	}
+
+	if _, err := NewMatcher(WithScorer(nil, 1)); err == nil {
+		t.Errorf("should error for nil scorer")
+	}
+}
+
+func TestReport(t *testing.T) {
+	m, _ := NewMatcher()
+	m.AddOSM(testOSM())
+
+	report := m.MatchAll([]*Point{
+		{Location: orb.Point{4.0, 52.0}, Tags: osm.Tags{{Key: "name", Value: "Café Central"}}},
+		{Location: orb.Point{4.0, 52.0}, Tags: osm.Tags{{Key: "name", Value: "Other"}}},
+		{Location: orb.Point{5.0, 52.0}, Tags: osm.Tags{{Key: "name", Value: "Other"}}},
+	})
+
+	if v := report.Count(Match); v != 1 {
+		t.Errorf("incorrect match count: %v", v)
+	}
+
+	if v := report.Filter(NoMatch); len(v) != 2 {
+		t.Errorf("incorrect no match results: %v", v)
+	}
+}
+
+func TestStatus_String(t *testing.T) {
+	if v := Ambiguous.String(); v != "ambiguous" {
+		t.Errorf("incorrect string: %v", v)
+	}
+
+	if v := Status(10).String(); v != "unknown" {
+		t.Errorf("incorrect string: %v", v)
+	}
+}
@@ -0,0 +1,63 @@
+package conflate
+
+import "errors"
+
+// An Option is a setting for creating the matcher.
+type Option func(*Matcher) error
+
+// MaxDistance sets the distance in meters candidates must be within.
+// The default is 50 meters.
+func MaxDistance(meters float64) Option {
+	return func(m *Matcher) error {
+		if meters <= 0 {
+			return errors.New("conflate: max distance must be positive")
+		}
+
+		m.maxDistance = meters
+		return nil
+	}
+}
+
+// Threshold sets the minimum score, between 0 and 1, for a candidate.
+// The default is 0.6, with the default scorers a candidate at the point
+// with a completely different name does not match.
+func Threshold(score float64) Option {
+	return func(m *Matcher) error {
+		if score < 0 || score > 1 {
+			return errors.New("conflate: threshold must be between 0 and 1")
+		}
+
+		m.threshold = score
+		return nil
+	}
+}
+
+// AmbiguityMargin sets how much better than the second best candidate the
+// best must be to be a match. The default is 0.1.
+func AmbiguityMargin(margin float64) Option {
+	return func(m *Matcher) error {
+		if margin < 0 {
+			return errors.New("conflate: ambiguity margin must not be negative")
+		}
+
+		m.margin = margin
+		return nil
+	}
+}
+
+// WithScorer adds a scorer with a relative weight. If any scorers are
+// added they replace the default distance and name scorers.
+func WithScorer(s Scorer, weight float64) Option {
+	return func(m *Matcher) error {
+		if s == nil {
+			return errors.New("conflate: scorer is nil")
+		}
+
+		if weight <= 0 {
+			return errors.New("conflate: scorer weight must be positive")
+		}
+
+		m.scorers = append(m.scorers, weightedScorer{scorer: s, weight: weight})
+		return nil
+	}
+}
@@ -0,0 +1,132 @@
+package conflate
+
+import (
+	"strings"
+	"unicode"
+)
+
+// A Scorer rates how well a candidate matches a point,
+// from 0 (no match) to 1 (perfect match).
+type Scorer func(p *Point, c *Candidate) float64
+
+// DistanceScorer scores candidates linearly by distance, 1 at the point
+// and 0 at max meters away.
+func DistanceScorer(max float64) Scorer {
+	return func(p *Point, c *Candidate) float64 {
+		if max <= 0 || c.Distance >= max {
+			return 0
+		}
+
+		return 1 - c.Distance/max
+	}
+}
+
+// NameScorer compares the names of the point and the candidate using the
+// edit distance, ignoring case, punctuation and repeated whitespace.
+// The value of the first key present on both is used, if no keys are
+// given the "name" tag is compared. If either is missing the score is 0.
+func NameScorer(keys ...string) Scorer {
+	if len(keys) == 0 {
+		keys = []string{"name"}
+	}
+
+	return func(p *Point, c *Candidate) float64 {
+		for _, k := range keys {
+			a, b := p.Tags.Find(k), c.Tags.Find(k)
+			if a == "" || b == "" {
+				continue
+			}
+
+			return Similarity(a, b)
+		}
+
+		return 0
+	}
+}
+
+// TagScorer scores 1 if the point and candidate have the same
+// non-empty value for the key, 0 otherwise.
+func TagScorer(key string) Scorer {
+	return func(p *Point, c *Candidate) float64 {
+		v := p.Tags.Find(key)
+		if v != "" && v == c.Tags.Find(key) {
+			return 1
+		}
+
+		return 0
+	}
+}
+
+// Similarity returns the normalized edit distance similarity of the two
+// strings, from 0 (completely different) to 1 (the same). The strings are
+// lowercased and punctuation and repeated whitespace are removed first.
+func Similarity(a, b string) float64 {
+	ra, rb := normalize(a), normalize(b)
+	if len(ra) == 0 && len(rb) == 0 {
+		return 1
+	}
+
+	max := len(ra)
+	if len(rb) > max {
+		max = len(rb)
+	}
+
+	return 1 - float64(levenshtein(ra, rb))/float64(max)
+}
+
+func normalize(s string) []rune {
+	var (
+		result []rune
+		space  bool
+	)
+
+	for _, r := range strings.TrimSpace(s) {
+		switch {
+		case unicode.IsSpace(r):
+			space = true
+		case unicode.IsPunct(r):
+		default:
+			if space && len(result) > 0 {
+				result = append(result, ' ')
+			}
+			space = false
+			result = append(result, unicode.ToLower(r))
+		}
+	}
+
+	return result
+}
+
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+
+			curr[j] = minInt(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+
+		prev, curr = curr, prev
+	}
+
+	return prev[len(b)]
+}
+
+func minInt(v int, values ...int) int {
+	for _, x := range values {
+		if x < v {
+			v = x
+		}
+	}
+
+	return v
+}
@@ -0,0 +1,62 @@
+package conflate
+
+import (
+	"math"
+	"testing"
+
+	"github.com/paulmach/osm"
+)
+
+func TestSimilarity(t *testing.T) {
+	cases := []struct {
+		name     string
+		a, b     string
+		expected float64
+	}{
+		{name: "equal", a: "Main Street", b: "Main Street", expected: 1},
+		{name: "case and punctuation", a: "St. Mary's  Church", b: "st marys church", expected: 1},
+		{name: "one edit", a: "abcd", b: "abce", expected: 0.75},
+		{name: "different", a: "abc", b: "xyz", expected: 0},
+		{name: "empty", a: "", b: "", expected: 1},
+		{name: "one empty", a: "abc", b: "", expected: 0},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			v := Similarity(tc.a, tc.b)
+			if math.Abs(v-tc.expected) > 1e-9 {
+				t.Errorf("incorrect similarity: %v != %v", v, tc.expected)
+			}
+		})
+	}
+}
+
+func TestScorers(t *testing.T) {
+	p := &Point{Tags: osm.Tags{{Key: "name", Value: "A"}, {Key: "shop", Value: "bakery"}}}
+	c := &Candidate{Distance: 25, Tags: osm.Tags{{Key: "name:en", Value: "A"}, {Key: "shop", Value: "bakery"}}}
+
+	if v := DistanceScorer(100)(p, c); v != 0.75 {
+		t.Errorf("incorrect distance score: %v", v)
+	}
+
+	if v := DistanceScorer(10)(p, c); v != 0 {
+		t.Errorf("incorrect distance score: %v", v)
+	}
+
+	if v := NameScorer()(p, c); v != 0 {
+		t.Errorf("missing name should score 0: %v", v)
+	}
+
+	p.Tags = append(p.Tags, osm.Tag{Key: "name:en", Value: "A"})
+	if v := NameScorer("name", "name:en")(p, c); v != 1 {
+		t.Errorf("incorrect name score: %v", v)
+	}
+
+	if v := TagScorer("shop")(p, c); v != 1 {
+		t.Errorf("incorrect tag score: %v", v)
+	}
+
+	if v := TagScorer("amenity")(p, c); v != 0 {
+		t.Errorf("missing tag should score 0: %v", v)
+	}
+}
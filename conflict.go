@@ -0,0 +1,122 @@
+package osm
+
+import (
+	"context"
+	"fmt"
+)
+
+// A Conflict is an element in a local change that was modified or
+// deleted upstream since the local copy was made.
+type Conflict struct {
+	Action ActionType
+
+	// Local is the element in the change.
+	Local Element
+
+	// Remote is the current version of the element in the datasource.
+	// It is nil if the element does not exist remotely.
+	Remote Element
+}
+
+// RemoteDeleted returns true if the element was deleted upstream
+// or does not exist.
+func (c Conflict) RemoteDeleted() bool {
+	if c.Remote == nil {
+		return true
+	}
+
+	switch e := c.Remote.(type) {
+	case *Node:
+		return !e.Visible
+	case *Way:
+		return !e.Visible
+	case *Relation:
+		return !e.Visible
+	}
+
+	return false
+}
+
+// String returns a description of the conflict.
+func (c Conflict) String() string {
+	if c.Remote == nil {
+		return fmt.Sprintf("%s %s %d: not found upstream", c.Action, elementType(c.Local), elementRef(c.Local))
+	}
+
+	return fmt.Sprintf("%s %s %d: local version %d, upstream version %d",
+		c.Action, elementType(c.Local), elementRef(c.Local),
+		elementVersion(c.Local), elementVersion(c.Remote))
+}
+
+// DetectConflicts checks if the elements modified or deleted by the local
+// change have a different version upstream. The latest version in the
+// remote history is compared to the version in the change, which should be
+// the version the local edits are based on. Created elements are not
+// checked. Elements not found upstream are returned as conflicts with a nil
+// Remote, other datasource errors are returned.
+func DetectConflicts(ctx context.Context, local *Change, remote HistoryDatasourcer) ([]Conflict, error) {
+	if local == nil {
+		return nil, nil
+	}
+
+	var conflicts []Conflict
+	check := func(action ActionType, o *OSM) error {
+		if o == nil {
+			return nil
+		}
+
+		for _, e := range o.Elements() {
+			latest, err := latestVersion(ctx, remote, e)
+			if err != nil && !remote.NotFound(err) {
+				return err
+			}
+
+			if latest == nil {
+				conflicts = append(conflicts, Conflict{Action: action, Local: e})
+				continue
+			}
+
+			if elementVersion(latest) != elementVersion(e) {
+				conflicts = append(conflicts, Conflict{Action: action, Local: e, Remote: latest})
+			}
+		}
+
+		return nil
+	}
+
+	if err := check(ActionModify, local.Modify); err != nil {
+		return nil, err
+	}
+
+	if err := check(ActionDelete, local.Delete); err != nil {
+		return nil, err
+	}
+
+	return conflicts, nil
+}
+
+// latestVersion returns nil if the element does not exist.
+func latestVersion(ctx context.Context, ds HistoryDatasourcer, e Element) (Element, error) {
+	switch e := e.(type) {
+	case *Node:
+		h, err := ds.NodeHistory(ctx, e.ID)
+		if err != nil || len(h) == 0 {
+			return nil, err
+		}
+		return h[len(h)-1], nil
+	case *Way:
+		h, err := ds.WayHistory(ctx, e.ID)
+		if err != nil || len(h) == 0 {
+			return nil, err
+		}
+		return h[len(h)-1], nil
+	case *Relation:
+		h, err := ds.RelationHistory(ctx, e.ID)
+		if err != nil || len(h) == 0 {
+			return nil, err
+		}
+		return h[len(h)-1], nil
+	}
+
+	return nil, fmt.Errorf("osm: unsupported element type %T", e)
+}
@@ -0,0 +1,82 @@
+package osm
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestDetectConflicts(t *testing.T) {
+	ctx := context.Background()
+	remote := (&OSM{
+		Nodes: Nodes{
+			{ID: 1, Version: 1, Visible: true},
+			{ID: 1, Version: 2, Visible: true},
+			{ID: 2, Version: 1, Visible: true},
+			{ID: 3, Version: 1, Visible: true},
+			{ID: 3, Version: 2, Visible: false},
+		},
+		Ways: Ways{
+			{ID: 1, Version: 4, Visible: true},
+		},
+	}).HistoryDatasource()
+
+	local := &Change{
+		Create: &OSM{Nodes: Nodes{{ID: -1}}},
+		Modify: &OSM{
+			Nodes: Nodes{
+				{ID: 1, Version: 1}, // changed upstream
+				{ID: 2, Version: 1}, // ok
+			},
+			Ways: Ways{
+				{ID: 1, Version: 4}, // ok
+				{ID: 2, Version: 1}, // not found
+			},
+		},
+		Delete: &OSM{
+			Nodes: Nodes{{ID: 3, Version: 1}}, // deleted upstream
+		},
+	}
+
+	conflicts, err := DetectConflicts(ctx, local, remote)
+	if err != nil {
+		t.Fatalf("detect error: %v", err)
+	}
+
+	if len(conflicts) != 3 {
+		t.Fatalf("incorrect conflicts: %v", conflicts)
+	}
+
+	if c := conflicts[0]; c.Action != ActionModify || c.Remote.(*Node).Version != 2 || c.RemoteDeleted() {
+		t.Errorf("incorrect conflict: %v", c)
+	}
+
+	if c := conflicts[1]; c.Remote != nil || !c.RemoteDeleted() {
+		t.Errorf("incorrect conflict: %v", c)
+	}
+
+	if c := conflicts[2]; c.Action != ActionDelete || !c.RemoteDeleted() {
+		t.Errorf("incorrect conflict: %v", c)
+	}
+
+	if v := conflicts[0].String(); v != "modify node 1: local version 1, upstream version 2" {
+		t.Errorf("incorrect string: %v", v)
+	}
+}
+
+type errorDatasource struct {
+	HistoryDatasource
+}
+
+func (ds *errorDatasource) NodeHistory(ctx context.Context, id NodeID) (Nodes, error) {
+	return nil, errors.New("network error")
+}
+
+func TestDetectConflicts_error(t *testing.T) {
+	local := &Change{Modify: &OSM{Nodes: Nodes{{ID: 1, Version: 1}}}}
+
+	_, err := DetectConflicts(context.Background(), local, &errorDatasource{})
+	if err == nil {
+		t.Errorf("expected error")
+	}
+}
@@ -3,6 +3,7 @@ package osm
 import (
 	"context"
 	"errors"
+	"sync"
 )
 
 // A HistoryDatasourcer defines an interface to osm history data.
@@ -13,6 +14,13 @@ type HistoryDatasourcer interface {
 	NotFound(error) bool
 }
 
+// A HistoryStore is a history datasource that elements can be added to,
+// for use as a cache.
+type HistoryStore interface {
+	HistoryDatasourcer
+	Add(context.Context, ...Object) error
+}
+
 var errNotFound = errors.New("osm: feature not found")
 
 // A HistoryDatasource wraps maps to implement the HistoryDataSource interface.
@@ -23,6 +31,7 @@ type HistoryDatasource struct {
 }
 
 var _ HistoryDatasourcer = &HistoryDatasource{}
+var _ HistoryStore = &HistoryDatasource{}
 
 func (ds *HistoryDatasource) add(o *OSM, visible ...bool) {
 	if o == nil {
@@ -69,6 +78,25 @@ func (ds *HistoryDatasource) add(o *OSM, visible ...bool) {
 	}
 }
 
+// Add appends the nodes, ways and relations to the history in the maps.
+// Other types are ignored. It implements the HistoryStore interface.
+func (ds *HistoryDatasource) Add(ctx context.Context, objects ...Object) error {
+	o := &OSM{}
+	for _, obj := range objects {
+		switch obj := obj.(type) {
+		case *Node:
+			o.Nodes = append(o.Nodes, obj)
+		case *Way:
+			o.Ways = append(o.Ways, obj)
+		case *Relation:
+			o.Relations = append(o.Relations, obj)
+		}
+	}
+
+	ds.add(o)
+	return nil
+}
+
 // NodeHistory returns the history for the given id from the map.
 func (ds *HistoryDatasource) NodeHistory(ctx context.Context, id NodeID) (Nodes, error) {
 	if ds.Nodes == nil {
@@ -115,3 +143,273 @@ func (ds *HistoryDatasource) RelationHistory(ctx context.Context, id RelationID)
 func (ds *HistoryDatasource) NotFound(err error) bool {
 	return err == errNotFound
 }
+
+// An InMemoryDatasource indexes the data in an OSM object for lookup by id.
+// It implements the HistoryDatasourcer interface so it can be used for
+// annotation. The Node, Way, Relation and parent lookups use the latest
+// version of each element.
+type InMemoryDatasource struct {
+	history *HistoryDatasource
+
+	nodeWays         map[NodeID]Ways
+	elementRelations map[FeatureID]Relations
+}
+
+var _ HistoryDatasourcer = &InMemoryDatasource{}
+
+// NewInMemoryDatasource builds the id and parent indexes for the data.
+// The osm object should not be modified after the datasource is created.
+func NewInMemoryDatasource(o *OSM) *InMemoryDatasource {
+	ds := &InMemoryDatasource{
+		history:          &HistoryDatasource{},
+		nodeWays:         make(map[NodeID]Ways),
+		elementRelations: make(map[FeatureID]Relations),
+	}
+
+	if o == nil {
+		return ds
+	}
+
+	ds.history.add(o)
+	for _, nodes := range ds.history.Nodes {
+		nodes.SortByIDVersion()
+	}
+
+	for _, ways := range ds.history.Ways {
+		ways.SortByIDVersion()
+
+		w := ways[len(ways)-1]
+		seen := make(map[NodeID]struct{}, len(w.Nodes))
+		for _, wn := range w.Nodes {
+			if _, ok := seen[wn.ID]; ok {
+				continue
+			}
+
+			seen[wn.ID] = struct{}{}
+			ds.nodeWays[wn.ID] = append(ds.nodeWays[wn.ID], w)
+		}
+	}
+
+	for _, relations := range ds.history.Relations {
+		relations.SortByIDVersion()
+
+		r := relations[len(relations)-1]
+		seen := make(map[FeatureID]struct{}, len(r.Members))
+		for _, m := range r.Members {
+			fid := m.FeatureID()
+			if _, ok := seen[fid]; ok {
+				continue
+			}
+
+			seen[fid] = struct{}{}
+			ds.elementRelations[fid] = append(ds.elementRelations[fid], r)
+		}
+	}
+
+	// map iteration order is random
+	for _, ways := range ds.nodeWays {
+		ways.SortByIDVersion()
+	}
+
+	for _, relations := range ds.elementRelations {
+		relations.SortByIDVersion()
+	}
+
+	return ds
+}
+
+// Node returns the latest version of the node.
+func (ds *InMemoryDatasource) Node(ctx context.Context, id NodeID) (*Node, error) {
+	nodes := ds.history.Nodes[id]
+	if len(nodes) == 0 {
+		return nil, errNotFound
+	}
+
+	return nodes[len(nodes)-1], nil
+}
+
+// Way returns the latest version of the way.
+func (ds *InMemoryDatasource) Way(ctx context.Context, id WayID) (*Way, error) {
+	ways := ds.history.Ways[id]
+	if len(ways) == 0 {
+		return nil, errNotFound
+	}
+
+	return ways[len(ways)-1], nil
+}
+
+// Relation returns the latest version of the relation.
+func (ds *InMemoryDatasource) Relation(ctx context.Context, id RelationID) (*Relation, error) {
+	relations := ds.history.Relations[id]
+	if len(relations) == 0 {
+		return nil, errNotFound
+	}
+
+	return relations[len(relations)-1], nil
+}
+
+// WaysForNode returns the ways that contain the node, sorted by id.
+// Returns an empty list if the node is not part of any ways.
+func (ds *InMemoryDatasource) WaysForNode(ctx context.Context, id NodeID) (Ways, error) {
+	return ds.nodeWays[id], nil
+}
+
+// RelationsForElement returns the relations that have the element as
+// a member, sorted by id. The element version is ignored.
+func (ds *InMemoryDatasource) RelationsForElement(ctx context.Context, id ElementID) (Relations, error) {
+	return ds.elementRelations[id.FeatureID()], nil
+}
+
+// NodeHistory returns all the versions of the node sorted by version.
+func (ds *InMemoryDatasource) NodeHistory(ctx context.Context, id NodeID) (Nodes, error) {
+	return ds.history.NodeHistory(ctx, id)
+}
+
+// WayHistory returns all the versions of the way sorted by version.
+func (ds *InMemoryDatasource) WayHistory(ctx context.Context, id WayID) (Ways, error) {
+	return ds.history.WayHistory(ctx, id)
+}
+
+// RelationHistory returns all the versions of the relation sorted by version.
+func (ds *InMemoryDatasource) RelationHistory(ctx context.Context, id RelationID) (Relations, error) {
+	return ds.history.RelationHistory(ctx, id)
+}
+
+// NotFound returns true if the error returned is a not found error.
+func (ds *InMemoryDatasource) NotFound(err error) bool {
+	return err == errNotFound
+}
+
+// A CachedHistoryDatasource checks the cache, a local store, first and falls
+// back to the source, eg. the osm api, on a miss. The history returned by
+// the source is written back to the cache. Since the full history is cached
+// newer versions will not be fetched from the source once an element is
+// in the cache.
+//
+// It is safe for concurrent use if the source is, the cache reads and writes
+// are serialized since stores like HistoryDatasource are not safe for
+// concurrent use. The cache should not be modified directly while in use.
+type CachedHistoryDatasource struct {
+	Cache  HistoryStore
+	Source HistoryDatasourcer
+
+	// Logger, if set, receives warnings for histories that could not be
+	// written to the cache. They are still returned.
+	Logger Logger
+
+	mu sync.RWMutex
+}
+
+var _ HistoryDatasourcer = &CachedHistoryDatasource{}
+
+// NewCachedHistoryDatasource creates a write-through cache around the source.
+func NewCachedHistoryDatasource(cache HistoryStore, source HistoryDatasourcer) *CachedHistoryDatasource {
+	return &CachedHistoryDatasource{
+		Cache:  cache,
+		Source: source,
+	}
+}
+
+// NodeHistory returns the node history from the cache or from the source
+// on a miss.
+func (ds *CachedHistoryDatasource) NodeHistory(ctx context.Context, id NodeID) (Nodes, error) {
+	ds.mu.RLock()
+	nodes, err := ds.Cache.NodeHistory(ctx, id)
+	ds.mu.RUnlock()
+	if err == nil || !ds.Cache.NotFound(err) {
+		return nodes, err
+	}
+
+	nodes, err = ds.Source.NodeHistory(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	objects := make([]Object, len(nodes))
+	for i, n := range nodes {
+		objects[i] = n
+	}
+
+	ds.add(ctx, id.FeatureID(), objects)
+	return nodes, nil
+}
+
+// WayHistory returns the way history from the cache or from the source
+// on a miss.
+func (ds *CachedHistoryDatasource) WayHistory(ctx context.Context, id WayID) (Ways, error) {
+	ds.mu.RLock()
+	ways, err := ds.Cache.WayHistory(ctx, id)
+	ds.mu.RUnlock()
+	if err == nil || !ds.Cache.NotFound(err) {
+		return ways, err
+	}
+
+	ways, err = ds.Source.WayHistory(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	objects := make([]Object, len(ways))
+	for i, w := range ways {
+		objects[i] = w
+	}
+
+	ds.add(ctx, id.FeatureID(), objects)
+	return ways, nil
+}
+
+// RelationHistory returns the relation history from the cache or from
+// the source on a miss.
+func (ds *CachedHistoryDatasource) RelationHistory(ctx context.Context, id RelationID) (Relations, error) {
+	ds.mu.RLock()
+	relations, err := ds.Cache.RelationHistory(ctx, id)
+	ds.mu.RUnlock()
+	if err == nil || !ds.Cache.NotFound(err) {
+		return relations, err
+	}
+
+	relations, err = ds.Source.RelationHistory(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	objects := make([]Object, len(relations))
+	for i, r := range relations {
+		objects[i] = r
+	}
+
+	ds.add(ctx, id.FeatureID(), objects)
+	return relations, nil
+}
+
+// add writes the history to the cache. An error is only logged since the
+// history from the source is still valid, it will be fetched again.
+func (ds *CachedHistoryDatasource) add(ctx context.Context, id FeatureID, objects []Object) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	// another call may have added it since the miss
+	var err error
+	switch id.Type() {
+	case TypeNode:
+		_, err = ds.Cache.NodeHistory(ctx, id.NodeID())
+	case TypeWay:
+		_, err = ds.Cache.WayHistory(ctx, id.WayID())
+	case TypeRelation:
+		_, err = ds.Cache.RelationHistory(ctx, id.RelationID())
+	}
+
+	if err == nil || !ds.Cache.NotFound(err) {
+		return
+	}
+
+	if err := ds.Cache.Add(ctx, objects...); err != nil && ds.Logger != nil {
+		ds.Logger.Warn("osm: unable to add history to cache",
+			"id", id, "error", err)
+	}
+}
+
+// NotFound returns true if the error is a not found error from the source.
+func (ds *CachedHistoryDatasource) NotFound(err error) bool {
+	return ds.Source.NotFound(err) || ds.Cache.NotFound(err)
+}
@@ -2,6 +2,10 @@ package osm
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
 	"testing"
 )
 
@@ -90,3 +94,263 @@ func TestHistoryDatasource(t *testing.T) {
 	})
 
 }
+
+func TestInMemoryDatasource(t *testing.T) {
+	ctx := context.Background()
+	o := &OSM{
+		Nodes: Nodes{
+			{ID: 1, Version: 2},
+			{ID: 1, Version: 1},
+			{ID: 2, Version: 1},
+			{ID: 3, Version: 1},
+		},
+		Ways: Ways{
+			{ID: 10, Version: 1, Nodes: WayNodes{{ID: 1}, {ID: 3}}},
+			{ID: 10, Version: 2, Nodes: WayNodes{{ID: 1}, {ID: 2}, {ID: 1}}},
+			{ID: 9, Version: 1, Nodes: WayNodes{{ID: 2}, {ID: 3}}},
+		},
+		Relations: Relations{
+			{ID: 20, Version: 1, Members: Members{
+				{Type: TypeWay, Ref: 10},
+				{Type: TypeNode, Ref: 1},
+			}},
+			{ID: 21, Version: 1, Members: Members{
+				{Type: TypeRelation, Ref: 20},
+				{Type: TypeWay, Ref: 10},
+			}},
+		},
+	}
+
+	ds := NewInMemoryDatasource(o)
+
+	n, err := ds.Node(ctx, 1)
+	if err != nil {
+		t.Fatalf("node error: %v", err)
+	}
+
+	if n.Version != 2 {
+		t.Errorf("should return latest version: %v", n.Version)
+	}
+
+	w, err := ds.Way(ctx, 9)
+	if err != nil || w.ID != 9 {
+		t.Errorf("incorrect way: %v %v", w, err)
+	}
+
+	r, err := ds.Relation(ctx, 21)
+	if err != nil || r.ID != 21 {
+		t.Errorf("incorrect relation: %v %v", r, err)
+	}
+
+	if _, err := ds.Node(ctx, 100); !ds.NotFound(err) {
+		t.Errorf("should be not found error: %v", err)
+	}
+
+	if _, err := ds.Way(ctx, 100); !ds.NotFound(err) {
+		t.Errorf("should be not found error: %v", err)
+	}
+
+	if _, err := ds.Relation(ctx, 100); !ds.NotFound(err) {
+		t.Errorf("should be not found error: %v", err)
+	}
+
+	// uses the latest way version and nodes are only listed once
+	ways, _ := ds.WaysForNode(ctx, 1)
+	if ids := ways.IDs(); !reflect.DeepEqual(ids, []WayID{10}) {
+		t.Errorf("incorrect ways for node 1: %v", ids)
+	}
+
+	ways, _ = ds.WaysForNode(ctx, 2)
+	if ids := ways.IDs(); !reflect.DeepEqual(ids, []WayID{9, 10}) {
+		t.Errorf("incorrect ways for node 2: %v", ids)
+	}
+
+	ways, _ = ds.WaysForNode(ctx, 3)
+	if ids := ways.IDs(); !reflect.DeepEqual(ids, []WayID{9}) {
+		t.Errorf("incorrect ways for node 3: %v", ids)
+	}
+
+	relations, _ := ds.RelationsForElement(ctx, WayID(10).ElementID(2))
+	if ids := relations.IDs(); !reflect.DeepEqual(ids, []RelationID{20, 21}) {
+		t.Errorf("incorrect relations for way: %v", ids)
+	}
+
+	relations, _ = ds.RelationsForElement(ctx, RelationID(20).ElementID(1))
+	if ids := relations.IDs(); !reflect.DeepEqual(ids, []RelationID{21}) {
+		t.Errorf("incorrect relations for relation: %v", ids)
+	}
+
+	relations, _ = ds.RelationsForElement(ctx, NodeID(3).ElementID(1))
+	if len(relations) != 0 {
+		t.Errorf("should not have relations: %v", relations)
+	}
+
+	nodes, err := ds.NodeHistory(ctx, 1)
+	if err != nil {
+		t.Fatalf("history error: %v", err)
+	}
+
+	if nodes[0].Version != 1 || nodes[1].Version != 2 {
+		t.Errorf("history should be sorted: %v", nodes)
+	}
+
+	// input not modified
+	if o.Nodes[0].Version != 2 {
+		t.Errorf("should not modify input")
+	}
+}
+
+func TestInMemoryDatasource_nil(t *testing.T) {
+	ds := NewInMemoryDatasource(nil)
+	if _, err := ds.Node(context.Background(), 1); !ds.NotFound(err) {
+		t.Errorf("should be not found error: %v", err)
+	}
+}
+
+type countingDatasource struct {
+	*HistoryDatasource
+	calls int
+}
+
+func (ds *countingDatasource) NodeHistory(ctx context.Context, id NodeID) (Nodes, error) {
+	ds.calls++
+	return ds.HistoryDatasource.NodeHistory(ctx, id)
+}
+
+func (ds *countingDatasource) WayHistory(ctx context.Context, id WayID) (Ways, error) {
+	ds.calls++
+	return ds.HistoryDatasource.WayHistory(ctx, id)
+}
+
+func (ds *countingDatasource) RelationHistory(ctx context.Context, id RelationID) (Relations, error) {
+	ds.calls++
+	return ds.HistoryDatasource.RelationHistory(ctx, id)
+}
+
+func TestCachedHistoryDatasource(t *testing.T) {
+	ctx := context.Background()
+	source := &countingDatasource{
+		HistoryDatasource: (&OSM{
+			Nodes:     Nodes{{ID: 1, Version: 1}, {ID: 1, Version: 2}},
+			Ways:      Ways{{ID: 2, Version: 1}},
+			Relations: Relations{{ID: 3, Version: 1}},
+		}).HistoryDatasource(),
+	}
+
+	cache := &HistoryDatasource{}
+	ds := NewCachedHistoryDatasource(cache, source)
+
+	for i := 0; i < 2; i++ {
+		nodes, err := ds.NodeHistory(ctx, 1)
+		if err != nil || len(nodes) != 2 {
+			t.Errorf("incorrect nodes: %v %v", nodes, err)
+		}
+
+		ways, err := ds.WayHistory(ctx, 2)
+		if err != nil || len(ways) != 1 {
+			t.Errorf("incorrect ways: %v %v", ways, err)
+		}
+
+		relations, err := ds.RelationHistory(ctx, 3)
+		if err != nil || len(relations) != 1 {
+			t.Errorf("incorrect relations: %v %v", relations, err)
+		}
+	}
+
+	// second round should come from the cache
+	if source.calls != 3 {
+		t.Errorf("incorrect number of source calls: %v", source.calls)
+	}
+
+	if len(cache.Nodes[1]) != 2 || len(cache.Ways[2]) != 1 || len(cache.Relations[3]) != 1 {
+		t.Errorf("should write back to cache: %v", cache)
+	}
+
+	if _, err := ds.NodeHistory(ctx, 10); !ds.NotFound(err) {
+		t.Errorf("should be not found error: %v", err)
+	}
+
+	if _, err := ds.WayHistory(ctx, 10); !ds.NotFound(err) {
+		t.Errorf("should be not found error: %v", err)
+	}
+
+	if _, err := ds.RelationHistory(ctx, 10); !ds.NotFound(err) {
+		t.Errorf("should be not found error: %v", err)
+	}
+}
+
+func TestCachedHistoryDatasource_concurrent(t *testing.T) {
+	ctx := context.Background()
+	source := (&OSM{
+		Nodes: Nodes{{ID: 1, Version: 1}, {ID: 2, Version: 1}},
+	}).HistoryDatasource()
+
+	ds := NewCachedHistoryDatasource(&HistoryDatasource{}, source)
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			id := NodeID(i%2 + 1)
+			if nodes, err := ds.NodeHistory(ctx, id); err != nil || len(nodes) != 1 {
+				t.Errorf("incorrect nodes: %v %v", nodes, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	// histories are only added once
+	for _, id := range []NodeID{1, 2} {
+		if nodes, _ := ds.Cache.NodeHistory(ctx, id); len(nodes) != 1 {
+			t.Errorf("incorrect cached nodes: %v", nodes)
+		}
+	}
+}
+
+func TestCachedHistoryDatasource_cacheError(t *testing.T) {
+	ctx := context.Background()
+	source := (&OSM{Nodes: Nodes{{ID: 1, Version: 1}}}).HistoryDatasource()
+
+	logger := &testLogger{}
+	ds := NewCachedHistoryDatasource(&failingStore{HistoryDatasource: &HistoryDatasource{}}, source)
+	ds.Logger = logger
+
+	nodes, err := ds.NodeHistory(ctx, 1)
+	if err != nil || len(nodes) != 1 {
+		t.Errorf("should return the source history: %v %v", nodes, err)
+	}
+
+	if len(logger.warnings) != 1 {
+		t.Errorf("should log the cache error: %v", logger.warnings)
+	}
+}
+
+func TestHistoryDatasource_Add(t *testing.T) {
+	ds := &HistoryDatasource{}
+	err := ds.Add(context.Background(),
+		&Node{ID: 1}, &Changeset{ID: 2}, &Note{ID: 3}, &User{ID: 4})
+	if err != nil {
+		t.Fatalf("add error: %v", err)
+	}
+
+	if len(ds.Nodes) != 1 || len(ds.Ways) != 0 || len(ds.Relations) != 0 {
+		t.Errorf("should ignore other types: %v", ds)
+	}
+}
+
+type failingStore struct {
+	*HistoryDatasource
+}
+
+func (s *failingStore) Add(ctx context.Context, objects ...Object) error {
+	return errors.New("add failed")
+}
+
+type testLogger struct {
+	warnings []string
+}
+
+func (l *testLogger) Warn(msg string, keyvals ...interface{}) {
+	l.warnings = append(l.warnings, fmt.Sprintf("%s %v", msg, keyvals))
+}
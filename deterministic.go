@@ -0,0 +1,52 @@
+package osm
+
+import "sort"
+
+// WithDeterministic makes the Marshal methods produce byte identical output
+// for the same data, no matter the order of the tags or the order the
+// strings are first seen. The tags are written sorted by key and value and
+// the string table is sorted. This is required for content addressed storage
+// and diff based testing. The data is encoded twice so it is slower.
+func WithDeterministic() MarshalOption {
+	return func(o *marshalOptions) {
+		o.deterministic = true
+	}
+}
+
+// newStringSet returns the string set to encode with. For deterministic
+// output the collect function, the encoding, is run once to find all the
+// strings, which are then added in sorted order.
+func (o *marshalOptions) newStringSet(collect func(*stringSet)) *stringSet {
+	if !o.deterministic {
+		return &stringSet{dict: o.dictionary}
+	}
+
+	ss := &stringSet{sortTags: true, dict: o.dictionary}
+	collect(ss)
+
+	values := append([]string(nil), ss.Strings()...)
+	if len(values) > 0 && o.dictionary == nil {
+		// the first value is the reserved empty string
+		values = values[1:]
+	}
+	sort.Strings(values)
+
+	result := &stringSet{sortTags: true, dict: o.dictionary}
+	for _, v := range values {
+		result.Add(v)
+	}
+
+	return result
+}
+
+// tags returns the tags in the order they should be encoded,
+// a sorted copy for deterministic output.
+func (ss *stringSet) tags(ts Tags) Tags {
+	if !ss.sortTags || sort.IsSorted(tagsSort(ts)) {
+		return ts
+	}
+
+	sorted := append(Tags(nil), ts...)
+	sorted.SortByKeyValue()
+	return sorted
+}
@@ -0,0 +1,97 @@
+package osm
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestWithDeterministic(t *testing.T) {
+	data := func(tags Tags, user string) *OSM {
+		return &OSM{
+			Nodes: Nodes{
+				{ID: 1, User: user, Tags: tags},
+				{ID: 2, User: "other", Tags: Tags{{Key: "b", Value: "2"}}},
+			},
+			Ways: Ways{
+				{ID: 3, User: user, Tags: tags, Nodes: WayNodes{{ID: 1}, {ID: 2}}},
+			},
+			Relations: Relations{
+				{ID: 4, Tags: tags, Members: Members{{Type: TypeWay, Ref: 3, Role: "outer"}}},
+			},
+		}
+	}
+
+	o1 := data(Tags{{Key: "a", Value: "1"}, {Key: "z", Value: "26"}}, "user")
+	o2 := data(Tags{{Key: "z", Value: "26"}, {Key: "a", Value: "1"}}, "user")
+
+	d1, err := o1.Marshal(WithDeterministic())
+	if err != nil {
+		t.Fatalf("marshal error: %v", err)
+	}
+
+	d2, err := o2.Marshal(WithDeterministic())
+	if err != nil {
+		t.Fatalf("marshal error: %v", err)
+	}
+
+	if !bytes.Equal(d1, d2) {
+		t.Errorf("should be byte identical")
+	}
+
+	d2, err = o2.Marshal()
+	if err != nil {
+		t.Fatalf("marshal error: %v", err)
+	}
+
+	if bytes.Equal(d1, d2) {
+		t.Errorf("should be different without the option")
+	}
+
+	result, err := UnmarshalOSM(d1)
+	if err != nil {
+		t.Fatalf("unmarshal error: %v", err)
+	}
+
+	if !reflect.DeepEqual(result.Ways[0].Tags, o1.Ways[0].Tags) {
+		t.Errorf("incorrect tags: %v", result.Ways[0].Tags)
+	}
+
+	if !reflect.DeepEqual(o2.Ways[0].Tags, Tags{{Key: "z", Value: "26"}, {Key: "a", Value: "1"}}) {
+		t.Errorf("should not modify the input tags: %v", o2.Ways[0].Tags)
+	}
+
+	t.Run("nodes", func(t *testing.T) {
+		d1, _ := o1.Nodes.Marshal(WithDeterministic())
+		d2, _ := o2.Nodes.Marshal(WithDeterministic())
+		if !bytes.Equal(d1, d2) {
+			t.Errorf("should be byte identical")
+		}
+
+		ns, err := UnmarshalNodes(d2)
+		if err != nil {
+			t.Fatalf("unmarshal error: %v", err)
+		}
+
+		if !reflect.DeepEqual(ns[0].Tags, o1.Nodes[0].Tags) {
+			t.Errorf("incorrect tags: %v", ns[0].Tags)
+		}
+	})
+
+	t.Run("changeset", func(t *testing.T) {
+		c1 := &Changeset{ID: 1, User: "user", Tags: o1.Nodes[0].Tags, Change: &Change{Create: o1}}
+		c2 := &Changeset{ID: 1, User: "user", Tags: o2.Nodes[0].Tags, Change: &Change{Create: o2}}
+
+		d1, _ := c1.Marshal(WithDeterministic())
+		d2, _ := c2.Marshal(WithDeterministic())
+		if !bytes.Equal(d1, d2) {
+			t.Errorf("should be byte identical")
+		}
+
+		d1, _ = c1.Change.Marshal(WithDeterministic())
+		d2, _ = c2.Change.Marshal(WithDeterministic())
+		if !bytes.Equal(d1, d2) {
+			t.Errorf("should be byte identical")
+		}
+	})
+}
@@ -0,0 +1,177 @@
+package osm
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// A Dictionary is a shared table of common strings, like tag keys and
+// values, used by the Marshal methods so small encodings, e.g. a single
+// node stored in a key value cache, do not each carry the full strings.
+// Only the dictionary version is saved with the data, so a dictionary
+// must never change once data has been written with it.
+type Dictionary struct {
+	Version uint32
+
+	// strings includes the reserved empty string at index 0
+	strings []string
+	index   map[string]uint32
+}
+
+// ErrDictionaryVersion is returned when registering a dictionary with the
+// same version as an existing one but different strings.
+var ErrDictionaryVersion = errors.New("osm: dictionary version already registered")
+
+// NewDictionary creates a dictionary with the strings. Duplicate and empty
+// strings are ignored. Versions 1 to 99 are reserved for the dictionaries
+// in this package, like DefaultDictionary.
+func NewDictionary(version uint32, strings []string) *Dictionary {
+	d := &Dictionary{
+		Version: version,
+		strings: make([]string, 1, len(strings)+1),
+		index:   make(map[string]uint32, len(strings)),
+	}
+
+	for _, s := range strings {
+		if _, ok := d.index[s]; ok || s == "" {
+			continue
+		}
+
+		d.index[s] = uint32(len(d.strings))
+		d.strings = append(d.strings, s)
+	}
+
+	return d
+}
+
+// Strings returns the strings in the dictionary.
+func (d *Dictionary) Strings() []string {
+	return d.strings[1:]
+}
+
+// WithDictionary will encode the strings in the dictionary by their index
+// into it, saving only the dictionary version with the data. The dictionary
+// must be registered, see RegisterDictionary, to be unmarshalled.
+func WithDictionary(d *Dictionary) MarshalOption {
+	return func(o *marshalOptions) {
+		o.dictionary = d
+	}
+}
+
+func (o *marshalOptions) dictionaryVersion() *uint32 {
+	if o.dictionary == nil {
+		return nil
+	}
+
+	v := o.dictionary.Version
+	return &v
+}
+
+var dictionaries = struct {
+	sync.RWMutex
+	versions map[uint32]*Dictionary
+}{
+	versions: map[uint32]*Dictionary{},
+}
+
+// RegisterDictionary makes the dictionary available to the Unmarshal
+// functions. It must be called, usually in an init function, before
+// unmarshalling data encoded using the dictionary. The DefaultDictionary
+// is always registered.
+func RegisterDictionary(d *Dictionary) error {
+	if d.Version == 0 {
+		return errors.New("osm: dictionary version must be positive")
+	}
+
+	dictionaries.Lock()
+	defer dictionaries.Unlock()
+
+	if existing := dictionaries.versions[d.Version]; existing != nil && existing != d {
+		if len(existing.strings) != len(d.strings) {
+			return ErrDictionaryVersion
+		}
+
+		for i := range existing.strings {
+			if existing.strings[i] != d.strings[i] {
+				return ErrDictionaryVersion
+			}
+		}
+	}
+
+	dictionaries.versions[d.Version] = d
+	return nil
+}
+
+// stringTable returns the strings of the encoded data, prefixed
+// with the dictionary strings if the data was encoded using one.
+func stringTable(version uint32, strings []string) ([]string, error) {
+	if version == 0 {
+		return strings, nil
+	}
+
+	dictionaries.RLock()
+	d := dictionaries.versions[version]
+	dictionaries.RUnlock()
+
+	if d == nil {
+		return nil, fmt.Errorf("osm: dictionary version %d is not registered", version)
+	}
+
+	result := make([]string, 0, len(d.strings)+len(strings))
+	result = append(result, d.strings...)
+	return append(result, strings...), nil
+}
+
+func init() {
+	if err := RegisterDictionary(DefaultDictionary); err != nil {
+		panic(err)
+	}
+}
+
+// DefaultDictionary contains the most common tag keys and values, roles
+// and other strings in the osm data. Its contents will never change,
+// new strings will be added to a dictionary with a new version.
+var DefaultDictionary = NewDictionary(1, []string{
+	// keys
+	"access", "addr:city", "addr:country", "addr:housename", "addr:housenumber",
+	"addr:place", "addr:postcode", "addr:state", "addr:street", "admin_level",
+	"aeroway", "amenity", "area", "barrier", "bicycle", "boundary", "brand",
+	"bridge", "building", "building:levels", "created_by", "crossing",
+	"cuisine", "cycleway", "denomination", "description", "ele", "emergency",
+	"foot", "ford", "highway", "historic", "horse", "int_name", "junction",
+	"landuse", "lanes", "layer", "leisure", "level", "man_made", "maxspeed",
+	"military", "motor_vehicle", "name", "name:en", "natural", "network",
+	"note", "oneway", "opening_hours", "operator", "parking", "place",
+	"population", "power", "public_transport", "railway", "ref", "religion",
+	"route", "service", "shop", "source", "sport", "surface", "tiger:cfcc",
+	"tiger:county", "tiger:name_base", "tiger:name_type", "tiger:reviewed",
+	"tourism", "tracktype", "tunnel", "type", "waterway", "website", "wheelchair",
+	"wikidata", "wikipedia", "width",
+
+	// values
+	"yes", "no", "residential", "house", "service", "track", "footway",
+	"unclassified", "tertiary", "secondary", "primary", "trunk", "motorway",
+	"path", "living_street", "pedestrian", "steps", "cycleway", "crossing",
+	"traffic_signals", "stop", "bus_stop", "platform", "stop_position",
+	"turning_circle", "street_lamp", "tree", "water", "stream", "river",
+	"ditch", "drain", "canal", "wood", "forest", "grass", "meadow", "farmland",
+	"farmyard", "scrub", "wetland", "parking", "parking_aisle", "driveway",
+	"restaurant", "cafe", "fast_food", "bench", "school", "place_of_worship",
+	"christian", "fuel", "bank", "toilets", "pharmacy", "hospital", "retail",
+	"commercial", "industrial", "apartments", "garage", "garages", "detached",
+	"roof", "shed", "hut", "fence", "wall", "gate", "hedge", "kerb", "bollard",
+	"pitch", "park", "playground", "swimming_pool", "tower", "pole", "line",
+	"minor_line", "substation", "village", "hamlet", "town", "city", "suburb",
+	"locality", "isolated_dwelling", "peak", "administrative", "asphalt",
+	"paved", "unpaved", "gravel", "ground", "dirt", "grade1", "grade2",
+	"grade3", "grade4", "grade5", "designated", "permissive", "private",
+	"destination", "customers", "bing", "survey", "multipolygon", "bus",
+	"road", "bicycle", "hiking", "foot", "rail", "abandoned", "disused",
+	"uncontrolled", "marked", "unmarked", "zebra", "roundabout", "-1", "1",
+	"2", "3", "4", "5", "6", "7", "8", "9", "10",
+
+	// relation roles
+	"outer", "inner", "forward", "backward", "stop", "platform", "from", "to",
+	"via", "admin_centre", "label", "subarea", "main_stream", "side_stream",
+})
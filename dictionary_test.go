@@ -0,0 +1,120 @@
+package osm
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWithDictionary(t *testing.T) {
+	ns := Nodes{
+		{ID: 1, Lat: 1, Lon: 2, User: "user", Tags: Tags{
+			{Key: "amenity", Value: "cafe"},
+			{Key: "name", Value: "Corner Cafe"},
+			{Key: "", Value: "empty key"},
+		}},
+		{ID: 2, Lat: 1, Lon: 2, Tags: Tags{{Key: "highway", Value: "bus_stop"}}},
+	}
+
+	data, err := ns.Marshal(WithDictionary(DefaultDictionary))
+	if err != nil {
+		t.Fatalf("marshal error: %v", err)
+	}
+
+	plain, err := ns.Marshal()
+	if err != nil {
+		t.Fatalf("marshal error: %v", err)
+	}
+
+	if len(data) >= len(plain) {
+		t.Errorf("should be smaller: %d >= %d", len(data), len(plain))
+	}
+
+	result, err := UnmarshalNodes(data)
+	if err != nil {
+		t.Fatalf("unmarshal error: %v", err)
+	}
+
+	if !reflect.DeepEqual(result, ns) {
+		t.Errorf("incorrect nodes: %v", result)
+	}
+
+	t.Run("osm", func(t *testing.T) {
+		o := &OSM{
+			Nodes: ns,
+			Ways:  Ways{{ID: 3, Tags: Tags{{Key: "highway", Value: "residential"}}}},
+			Relations: Relations{{ID: 4, Tags: Tags{{Key: "type", Value: "multipolygon"}},
+				Members: Members{{Type: TypeWay, Ref: 3, Role: "outer"}}}},
+		}
+
+		data, err := o.Marshal(WithDictionary(DefaultDictionary), WithDeterministic())
+		if err != nil {
+			t.Fatalf("marshal error: %v", err)
+		}
+
+		result, err := UnmarshalOSM(data)
+		if err != nil {
+			t.Fatalf("unmarshal error: %v", err)
+		}
+
+		if !reflect.DeepEqual(result.Relations, o.Relations) || !reflect.DeepEqual(result.Ways, o.Ways) {
+			t.Errorf("incorrect data: %v %v", result.Ways, result.Relations)
+		}
+	})
+
+	t.Run("changeset", func(t *testing.T) {
+		c := &Changeset{ID: 1, User: "user", Tags: Tags{{Key: "source", Value: "survey"}}}
+		data, err := c.Marshal(WithDictionary(DefaultDictionary))
+		if err != nil {
+			t.Fatalf("marshal error: %v", err)
+		}
+
+		result, err := UnmarshalChangeset(data)
+		if err != nil {
+			t.Fatalf("unmarshal error: %v", err)
+		}
+
+		if !reflect.DeepEqual(result.Tags, c.Tags) || result.User != "user" {
+			t.Errorf("incorrect changeset: %v", result)
+		}
+	})
+
+	t.Run("not registered", func(t *testing.T) {
+		d := NewDictionary(1000, []string{"amenity"})
+		data, err := ns.Marshal(WithDictionary(d))
+		if err != nil {
+			t.Fatalf("marshal error: %v", err)
+		}
+
+		_, err = UnmarshalNodes(data)
+		if err == nil {
+			t.Fatalf("should error if dictionary not registered")
+		}
+
+		if err := RegisterDictionary(d); err != nil {
+			t.Fatalf("register error: %v", err)
+		}
+
+		result, err := UnmarshalNodes(data)
+		if err != nil {
+			t.Fatalf("unmarshal error: %v", err)
+		}
+
+		if !reflect.DeepEqual(result, ns) {
+			t.Errorf("incorrect nodes: %v", result)
+		}
+	})
+}
+
+func TestRegisterDictionary(t *testing.T) {
+	if err := RegisterDictionary(NewDictionary(1, []string{"other"})); err != ErrDictionaryVersion {
+		t.Errorf("should not replace a dictionary version: %v", err)
+	}
+
+	if err := RegisterDictionary(NewDictionary(1, DefaultDictionary.Strings())); err != nil {
+		t.Errorf("should allow the same strings: %v", err)
+	}
+
+	if err := RegisterDictionary(NewDictionary(0, nil)); err == nil {
+		t.Errorf("should require a version")
+	}
+}
@@ -0,0 +1,158 @@
+package osm
+
+import "encoding/xml"
+
+// DiffResult is the response from the api after uploading an osmChange.
+// It maps the ids, including the negative placeholder ids of created
+// elements, to the new ids and versions.
+// See: https://wiki.openstreetmap.org/wiki/API_v0.6#Response_10
+type DiffResult struct {
+	XMLName   xml.Name `xml:"diffResult"`
+	Version   string   `xml:"version,attr,omitempty"`
+	Generator string   `xml:"generator,attr,omitempty"`
+
+	Nodes     []DiffResultEntry `xml:"node"`
+	Ways      []DiffResultEntry `xml:"way"`
+	Relations []DiffResultEntry `xml:"relation"`
+}
+
+// DiffResultEntry is the result for one element. The new id and
+// version are zero if the element was deleted.
+type DiffResultEntry struct {
+	OldID      int64 `xml:"old_id,attr"`
+	NewID      int64 `xml:"new_id,attr,omitempty"`
+	NewVersion int   `xml:"new_version,attr,omitempty"`
+}
+
+// UnmarshalDiffResult parses the diffResult xml returned by the api.
+func UnmarshalDiffResult(data []byte) (*DiffResult, error) {
+	dr := &DiffResult{}
+	if err := xml.Unmarshal(data, dr); err != nil {
+		return nil, err
+	}
+
+	return dr, nil
+}
+
+// NodeID returns the new id for the old node id. Returns false
+// if the node is not in the result or was deleted.
+func (dr *DiffResult) NodeID(old NodeID) (NodeID, bool) {
+	id, _, ok := findEntry(dr.Nodes, int64(old))
+	return NodeID(id), ok
+}
+
+// WayID returns the new id for the old way id. Returns false
+// if the way is not in the result or was deleted.
+func (dr *DiffResult) WayID(old WayID) (WayID, bool) {
+	id, _, ok := findEntry(dr.Ways, int64(old))
+	return WayID(id), ok
+}
+
+// RelationID returns the new id for the old relation id. Returns false
+// if the relation is not in the result or was deleted.
+func (dr *DiffResult) RelationID(old RelationID) (RelationID, bool) {
+	id, _, ok := findEntry(dr.Relations, int64(old))
+	return RelationID(id), ok
+}
+
+func findEntry(entries []DiffResultEntry, old int64) (int64, int, bool) {
+	for _, e := range entries {
+		if e.OldID == old && e.NewID != 0 {
+			return e.NewID, e.NewVersion, true
+		}
+	}
+
+	return 0, 0, false
+}
+
+// Apply updates the ids and versions of the created and modified
+// elements in the change, including the way node and member references,
+// to the values assigned by the server. This allows for further edits
+// of the same data.
+func (dr *DiffResult) Apply(c *Change) {
+	if c == nil {
+		return
+	}
+
+	m := dr.mapping()
+	m.apply(c.Create)
+	m.apply(c.Modify)
+	m.apply(c.Delete)
+}
+
+// ApplyOSM updates the ids and versions of the elements in the osm data,
+// including the way node and member references, to the values assigned
+// by the server.
+func (dr *DiffResult) ApplyOSM(o *OSM) {
+	dr.mapping().apply(o)
+}
+
+type diffEntry struct {
+	id      int64
+	version int
+}
+
+// diffKey is used instead of a FeatureID since the
+// negative placeholder ids do not pack into one.
+type diffKey struct {
+	t   Type
+	ref int64
+}
+
+type diffMapping map[diffKey]diffEntry
+
+func (dr *DiffResult) mapping() diffMapping {
+	m := make(diffMapping, len(dr.Nodes)+len(dr.Ways)+len(dr.Relations))
+	add := func(t Type, entries []DiffResultEntry) {
+		for _, e := range entries {
+			if e.NewID != 0 {
+				m[diffKey{t, e.OldID}] = diffEntry{id: e.NewID, version: e.NewVersion}
+			}
+		}
+	}
+
+	add(TypeNode, dr.Nodes)
+	add(TypeWay, dr.Ways)
+	add(TypeRelation, dr.Relations)
+
+	return m
+}
+
+func (m diffMapping) apply(o *OSM) {
+	if o == nil {
+		return
+	}
+
+	for _, n := range o.Nodes {
+		if e, ok := m[diffKey{TypeNode, int64(n.ID)}]; ok {
+			n.ID = NodeID(e.id)
+			n.Version = e.version
+		}
+	}
+
+	for _, w := range o.Ways {
+		if e, ok := m[diffKey{TypeWay, int64(w.ID)}]; ok {
+			w.ID = WayID(e.id)
+			w.Version = e.version
+		}
+
+		for i := range w.Nodes {
+			if e, ok := m[diffKey{TypeNode, int64(w.Nodes[i].ID)}]; ok {
+				w.Nodes[i].ID = NodeID(e.id)
+			}
+		}
+	}
+
+	for _, r := range o.Relations {
+		if e, ok := m[diffKey{TypeRelation, int64(r.ID)}]; ok {
+			r.ID = RelationID(e.id)
+			r.Version = e.version
+		}
+
+		for i := range r.Members {
+			if e, ok := m[diffKey{r.Members[i].Type, r.Members[i].Ref}]; ok {
+				r.Members[i].Ref = e.id
+			}
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package osm
+
+import (
+	"testing"
+)
+
+func TestDiffResult_Apply(t *testing.T) {
+	data := []byte(`<diffResult version="0.6" generator="OpenStreetMap server">
+		<node old_id="-1" new_id="100" new_version="1"/>
+		<node old_id="-2" new_id="101" new_version="1"/>
+		<node old_id="5" new_id="5" new_version="3"/>
+		<node old_id="6"/>
+		<way old_id="-3" new_id="200" new_version="1"/>
+		<relation old_id="-4" new_id="300" new_version="1"/>
+	</diffResult>`)
+
+	dr, err := UnmarshalDiffResult(data)
+	if err != nil {
+		t.Fatalf("unmarshal error: %v", err)
+	}
+
+	if dr.Version != "0.6" || len(dr.Nodes) != 4 || len(dr.Ways) != 1 || len(dr.Relations) != 1 {
+		t.Fatalf("incorrect diff result: %+v", dr)
+	}
+
+	if id, ok := dr.NodeID(-2); !ok || id != 101 {
+		t.Errorf("incorrect node id: %v %v", id, ok)
+	}
+
+	if _, ok := dr.NodeID(6); ok {
+		t.Errorf("deleted node should not have a new id")
+	}
+
+	if id, ok := dr.WayID(-3); !ok || id != 200 {
+		t.Errorf("incorrect way id: %v %v", id, ok)
+	}
+
+	if id, ok := dr.RelationID(-4); !ok || id != 300 {
+		t.Errorf("incorrect relation id: %v %v", id, ok)
+	}
+
+	a := NewIDAllocator(-1)
+	n1 := a.NewNode(1, 1, nil)
+	n2 := a.NewNode(2, 2, nil)
+	w := a.NewWay(nil, n1.ID, n2.ID, 5)
+	r := a.NewRelation(nil, Member{Type: TypeWay, Ref: int64(w.ID)}, Member{Type: TypeNode, Ref: -3})
+
+	c := &Change{}
+	c.AppendCreate(n1)
+	c.AppendCreate(n2)
+	c.AppendCreate(w)
+	c.AppendCreate(r)
+	c.AppendModify(&Node{ID: 5, Version: 2})
+
+	dr.Apply(c)
+
+	if n1.ID != 100 || n1.Version != 1 || n2.ID != 101 {
+		t.Errorf("incorrect nodes: %v %v", n1.ID, n2.ID)
+	}
+
+	if w.ID != 200 || w.Nodes[0].ID != 100 || w.Nodes[1].ID != 101 || w.Nodes[2].ID != 5 {
+		t.Errorf("incorrect way: %v %v", w.ID, w.Nodes)
+	}
+
+	if r.ID != 300 || r.Members[0].Ref != 200 {
+		t.Errorf("incorrect relation: %v %v", r.ID, r.Members)
+	}
+
+	// node -3 does not exist, only way -3
+	if r.Members[1].Ref != -3 {
+		t.Errorf("member type should be respected: %v", r.Members[1])
+	}
+
+	if v := c.Modify.Nodes[0].Version; v != 3 {
+		t.Errorf("incorrect modified version: %v", v)
+	}
+}
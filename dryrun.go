@@ -0,0 +1,274 @@
+package osm
+
+import (
+	"context"
+	"fmt"
+)
+
+// ViolationKind is the reason a change would be rejected by the api.
+type ViolationKind string
+
+// The kinds of integrity violations found by DryRun.
+const (
+	// ViolationVersionMismatch is a modified or deleted element that is
+	// not the latest version, the api returns 409 Conflict.
+	ViolationVersionMismatch ViolationKind = "version mismatch"
+
+	// ViolationNotFound is a modified or deleted element that does not exist.
+	ViolationNotFound ViolationKind = "not found"
+
+	// ViolationDeleted is a modified or deleted element that
+	// was already deleted, the api returns 410 Gone.
+	ViolationDeleted ViolationKind = "already deleted"
+
+	// ViolationMissingReference is a way node or relation member that does
+	// not exist after the change, the api returns 412 Precondition Failed.
+	ViolationMissingReference ViolationKind = "missing reference"
+
+	// ViolationStillUsed is a deleted element that is still a node of a way
+	// or member of a relation, the api returns 412 Precondition Failed.
+	ViolationStillUsed ViolationKind = "still used"
+)
+
+// A Violation is a problem with an element of a change.
+type Violation struct {
+	Kind   ViolationKind
+	Action ActionType
+
+	// Element is the element in the change.
+	Element Element
+
+	// RefType and Ref are the referenced element for a missing
+	// reference or the parent way or relation still using the element.
+	RefType Type
+	Ref     int64
+}
+
+// String returns a description of the violation.
+func (v Violation) String() string {
+	s := fmt.Sprintf("%s %s %d: %s", v.Action, elementType(v.Element), elementRef(v.Element), v.Kind)
+	if v.RefType != "" {
+		s += fmt.Sprintf(" %s %d", v.RefType, v.Ref)
+	}
+
+	return s
+}
+
+// DryRunResult is the outcome of applying a change to a snapshot.
+type DryRunResult struct {
+	// Elements are the states of the elements in the change after it is
+	// applied, with the new versions. Created elements keep their ids and
+	// deleted elements are not visible.
+	Elements Elements
+
+	Violations []Violation
+}
+
+// OK returns true if the change has no violations.
+func (r *DryRunResult) OK() bool {
+	return len(r.Violations) == 0
+}
+
+// DryRun applies the change to the snapshot, without modifying it, and
+// returns the resulting element states and the integrity violations the
+// api would reject the upload for. Run it before uploading to catch
+// problems without sending anything. The elements in the change are not
+// modified. The elements of a snapshot built from an extract, without the
+// visible flag, are only deleted if an applied change deleted them.
+func DryRun(ctx context.Context, s *Snapshot, c *Change) (*DryRunResult, error) {
+	result := &DryRunResult{}
+	if c == nil {
+		return result, nil
+	}
+
+	created := make(map[diffKey]bool)
+	deleted := make(map[diffKey]bool)
+	modified := make(map[diffKey]bool)
+	for _, e := range elementsOf(c.Create) {
+		created[diffKey{elementType(e), elementRef(e)}] = true
+	}
+	for _, e := range elementsOf(c.Modify) {
+		modified[diffKey{elementType(e), elementRef(e)}] = true
+	}
+	for _, e := range elementsOf(c.Delete) {
+		deleted[diffKey{elementType(e), elementRef(e)}] = true
+	}
+
+	violate := func(kind ViolationKind, action ActionType, e Element, t Type, ref int64) {
+		result.Violations = append(result.Violations, Violation{
+			Kind:    kind,
+			Action:  action,
+			Element: e,
+			RefType: t,
+			Ref:     ref,
+		})
+	}
+
+	// exists returns true if the element is visible after the change.
+	exists := func(t Type, ref int64) bool {
+		key := diffKey{t, ref}
+		if created[key] {
+			return true
+		}
+
+		if deleted[key] {
+			return false
+		}
+
+		return snapshotLatest(ctx, s, key) != nil && !snapshotDeleted(s, key)
+	}
+
+	checkRefs := func(action ActionType, e Element) {
+		switch e := e.(type) {
+		case *Way:
+			for _, wn := range e.Nodes {
+				if !exists(TypeNode, int64(wn.ID)) {
+					violate(ViolationMissingReference, action, e, TypeNode, int64(wn.ID))
+				}
+			}
+		case *Relation:
+			for _, m := range e.Members {
+				if !exists(m.Type, m.Ref) {
+					violate(ViolationMissingReference, action, e, m.Type, m.Ref)
+				}
+			}
+		}
+	}
+
+	// checkCurrent returns false if the element can not be modified or deleted.
+	checkCurrent := func(action ActionType, e Element) bool {
+		key := diffKey{elementType(e), elementRef(e)}
+		latest := snapshotLatest(ctx, s, key)
+		switch {
+		case latest == nil:
+			violate(ViolationNotFound, action, e, "", 0)
+			return false
+		case elementVersion(latest) != elementVersion(e):
+			violate(ViolationVersionMismatch, action, e, "", 0)
+			return false
+		case snapshotDeleted(s, key):
+			violate(ViolationDeleted, action, e, "", 0)
+			return false
+		}
+
+		return true
+	}
+
+	for _, e := range elementsOf(c.Create) {
+		checkRefs(ActionCreate, e)
+		result.Elements = append(result.Elements, dryRunState(e, 1, true))
+	}
+
+	for _, e := range elementsOf(c.Modify) {
+		checkCurrent(ActionModify, e)
+		checkRefs(ActionModify, e)
+		result.Elements = append(result.Elements, dryRunState(e, elementVersion(e)+1, true))
+	}
+
+	for _, e := range elementsOf(c.Delete) {
+		if checkCurrent(ActionDelete, e) {
+			// parents in the change are checked by their references
+			t, ref := elementType(e), elementRef(e)
+			for _, p := range snapshotParents(ctx, s, t, ref) {
+				key := diffKey{elementType(p), elementRef(p)}
+				if !deleted[key] && !modified[key] {
+					violate(ViolationStillUsed, ActionDelete, e, key.t, key.ref)
+				}
+			}
+		}
+
+		result.Elements = append(result.Elements, dryRunState(e, elementVersion(e)+1, false))
+	}
+
+	return result, nil
+}
+
+// DryRun applies all the edits added to the builder to the snapshot, see
+// the DryRun func, before they are built and uploaded.
+func (b *ChangeBuilder) DryRun(ctx context.Context, s *Snapshot) (*DryRunResult, error) {
+	return DryRun(ctx, s, &Change{Create: b.create, Modify: b.modify, Delete: b.delete})
+}
+
+func elementsOf(o *OSM) Elements {
+	if o == nil {
+		return nil
+	}
+
+	return o.Elements()
+}
+
+// snapshotLatest returns nil if the element is not in the snapshot.
+func snapshotLatest(ctx context.Context, s *Snapshot, key diffKey) Element {
+	switch key.t {
+	case TypeNode:
+		if n, err := s.Node(ctx, NodeID(key.ref)); err == nil {
+			return n
+		}
+	case TypeWay:
+		if w, err := s.Way(ctx, WayID(key.ref)); err == nil {
+			return w
+		}
+	case TypeRelation:
+		if r, err := s.Relation(ctx, RelationID(key.ref)); err == nil {
+			return r
+		}
+	}
+
+	return nil
+}
+
+// snapshotDeleted returns true if the latest version of the element is
+// deleted. Snapshots of extracts do not have the visible flag, their
+// elements are only deleted by applied changes.
+func snapshotDeleted(s *Snapshot, key diffKey) bool {
+	id, err := key.t.FeatureID(key.ref)
+	return err == nil && s.isDeleted(id)
+}
+
+// snapshotParents returns the visible ways and relations using the element.
+func snapshotParents(ctx context.Context, s *Snapshot, t Type, ref int64) Elements {
+	var parents Elements
+	if t == TypeNode {
+		ways, _ := s.WaysForNode(ctx, NodeID(ref))
+		for _, w := range ways {
+			parents = append(parents, w)
+		}
+	}
+
+	var id ElementID
+	switch t {
+	case TypeNode:
+		id = NodeID(ref).ElementID(0)
+	case TypeWay:
+		id = WayID(ref).ElementID(0)
+	case TypeRelation:
+		id = RelationID(ref).ElementID(0)
+	}
+
+	relations, _ := s.RelationsForElement(ctx, id)
+	for _, r := range relations {
+		parents = append(parents, r)
+	}
+
+	return parents
+}
+
+// dryRunState returns a copy of the element with the version and visibility.
+func dryRunState(e Element, version int, visible bool) Element {
+	switch e := e.(type) {
+	case *Node:
+		n := e.Copy()
+		n.Version, n.Visible = version, visible
+		return n
+	case *Way:
+		w := e.Copy()
+		w.Version, w.Visible = version, visible
+		return w
+	case *Relation:
+		r := e.Copy()
+		r.Version, r.Visible = version, visible
+		return r
+	}
+
+	return e
+}
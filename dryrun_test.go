@@ -0,0 +1,191 @@
+package osm
+
+import (
+	"context"
+	"testing"
+)
+
+func dryRunSnapshot() *Snapshot {
+	o := &OSM{
+		Nodes: Nodes{
+			{ID: 1, Version: 1, Visible: true},
+			{ID: 2, Version: 1, Visible: true},
+			{ID: 3, Version: 2, Visible: true},
+			{ID: 4, Version: 2, Visible: false},
+		},
+		Ways: Ways{
+			{ID: 10, Version: 1, Visible: true, Nodes: WayNodes{{ID: 1}, {ID: 2}}},
+		},
+		Relations: Relations{
+			{ID: 20, Version: 1, Visible: true, Members: Members{{Type: TypeWay, Ref: 10}}},
+		},
+	}
+
+	return NewSnapshotDatasource(o).Snapshot()
+}
+
+func TestDryRun(t *testing.T) {
+	ctx := context.Background()
+	s := dryRunSnapshot()
+
+	c := &Change{}
+	c.AppendCreate(&Node{ID: -1, Lat: 1})
+	c.AppendCreate(&Way{ID: -1, Nodes: WayNodes{{ID: -1}, {ID: 3}}})
+	c.AppendModify(&Node{ID: 2, Version: 1, Lat: 5})
+
+	result, err := DryRun(ctx, s, c)
+	if err != nil {
+		t.Fatalf("dry run error: %v", err)
+	}
+
+	if !result.OK() {
+		t.Errorf("expected no violations: %v", result.Violations)
+	}
+
+	if len(result.Elements) != 3 {
+		t.Fatalf("incorrect elements: %v", result.Elements)
+	}
+
+	n := result.Elements[2].(*Node)
+	if n.ID != 2 || n.Version != 2 || !n.Visible || n.Lat != 5 {
+		t.Errorf("incorrect modified node: %+v", n)
+	}
+
+	if c.Modify.Nodes[0].Version != 1 {
+		t.Errorf("change should not be modified")
+	}
+
+	if v, _ := s.Node(ctx, 2); v.Version != 1 {
+		t.Errorf("snapshot should not be modified")
+	}
+}
+
+func TestDryRun_violations(t *testing.T) {
+	ctx := context.Background()
+	s := dryRunSnapshot()
+
+	c := &Change{}
+	c.AppendCreate(&Way{ID: -1, Nodes: WayNodes{{ID: 4}, {ID: -5}}})
+	c.AppendModify(&Node{ID: 3, Version: 1})
+	c.AppendModify(&Node{ID: 4, Version: 2})
+	c.AppendModify(&Node{ID: 100, Version: 1})
+	c.AppendDelete(&Node{ID: 1, Version: 1})
+	c.AppendDelete(&Way{ID: 10, Version: 1})
+
+	result, err := DryRun(ctx, s, c)
+	if err != nil {
+		t.Fatalf("dry run error: %v", err)
+	}
+
+	expected := []string{
+		"create way -1: missing reference node 4",
+		"create way -1: missing reference node -5",
+		"modify node 3: version mismatch",
+		"modify node 4: already deleted",
+		"modify node 100: not found",
+		"delete way 10: still used relation 20",
+	}
+
+	if len(result.Violations) != len(expected) {
+		t.Fatalf("incorrect violations: %v", result.Violations)
+	}
+
+	for i, v := range result.Violations {
+		if v.String() != expected[i] {
+			t.Errorf("incorrect violation %d: %v != %v", i, v, expected[i])
+		}
+	}
+
+	w := result.Elements[len(result.Elements)-1].(*Way)
+	if w.Version != 2 || w.Visible {
+		t.Errorf("incorrect deleted way state: %+v", w)
+	}
+}
+
+func TestDryRun_extract(t *testing.T) {
+	ctx := context.Background()
+
+	// extracts do not set the visible flag
+	ds := NewSnapshotDatasource(&OSM{
+		Nodes: Nodes{
+			{ID: 1, Version: 1},
+			{ID: 2, Version: 1},
+			{ID: 3, Version: 1},
+		},
+		Ways: Ways{
+			{ID: 10, Version: 1, Nodes: WayNodes{{ID: 1}, {ID: 2}}},
+		},
+	})
+
+	c := &Change{}
+	c.AppendModify(&Way{ID: 10, Version: 1, Nodes: WayNodes{{ID: 1}, {ID: 2}, {ID: 3}}})
+
+	result, err := DryRun(ctx, ds.Snapshot(), c)
+	if err != nil {
+		t.Fatalf("dry run error: %v", err)
+	}
+
+	if !result.OK() {
+		t.Errorf("expected no violations: %v", result.Violations)
+	}
+
+	// elements deleted by an applied change are deleted
+	err = ds.Apply(ctx, &Change{Delete: &OSM{Nodes: Nodes{{ID: 3, Version: 2}}}})
+	if err != nil {
+		t.Fatalf("apply error: %v", err)
+	}
+
+	c.AppendDelete(&Node{ID: 3, Version: 2})
+	result, err = DryRun(ctx, ds.Snapshot(), c)
+	if err != nil {
+		t.Fatalf("dry run error: %v", err)
+	}
+
+	expected := []string{
+		"modify way 10: missing reference node 3",
+		"delete node 3: already deleted",
+	}
+
+	if len(result.Violations) != len(expected) {
+		t.Fatalf("incorrect violations: %v", result.Violations)
+	}
+
+	for i, v := range result.Violations {
+		if v.String() != expected[i] {
+			t.Errorf("incorrect violation %d: %v != %v", i, v, expected[i])
+		}
+	}
+}
+
+func TestChangeBuilder_DryRun(t *testing.T) {
+	ctx := context.Background()
+	s := dryRunSnapshot()
+
+	b := NewChangeBuilder()
+	if err := b.Delete(&Node{ID: 2, Version: 1}); err != nil {
+		t.Fatalf("delete error: %v", err)
+	}
+
+	result, err := b.DryRun(ctx, s)
+	if err != nil {
+		t.Fatalf("dry run error: %v", err)
+	}
+
+	if len(result.Violations) != 1 || result.Violations[0].String() != "delete node 2: still used way 10" {
+		t.Errorf("incorrect violations: %v", result.Violations)
+	}
+
+	// also modifying the way to not use the node
+	if err := b.Modify(&Way{ID: 10, Version: 1, Nodes: WayNodes{{ID: 1}, {ID: 3}}}); err != nil {
+		t.Fatalf("modify error: %v", err)
+	}
+
+	result, err = b.DryRun(ctx, s)
+	if err != nil {
+		t.Fatalf("dry run error: %v", err)
+	}
+
+	if !result.OK() {
+		t.Errorf("expected no violations: %v", result.Violations)
+	}
+}
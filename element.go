@@ -133,6 +133,7 @@ type Element interface {
 	ElementID() ElementID
 	FeatureID() FeatureID
 	TagMap() map[string]string
+	Hash(opts ...EqualOption) uint64
 
 	// TagMap keeps waynodes and members from matching the interface.
 	// This keeps the meaning of what an element is.
@@ -215,3 +216,78 @@ func (ids elementIDsSort) Swap(i, j int) { ids[i], ids[j] = ids[j], ids[i] }
 func (ids elementIDsSort) Less(i, j int) bool {
 	return ids[i] < ids[j]
 }
+
+// DiffMetadata is a report of what changed between two versions
+// of an element.
+type DiffMetadata struct {
+	FeatureID   FeatureID
+	FromVersion int
+	ToVersion   int
+
+	// Deleted is true if the element went from visible to not visible,
+	// Restored is the opposite.
+	Deleted  bool
+	Restored bool
+
+	// Moved is true if the location of a node changed.
+	Moved bool
+
+	// NodesChanged is true if the node ids of a way changed.
+	NodesChanged bool
+
+	// MembersChanged is true if the type, ref or role of the
+	// members of a relation changed.
+	MembersChanged bool
+
+	TagsAdded   Tags
+	TagsRemoved Tags
+	TagsChanged []TagChange
+}
+
+// NewDiffMetadata compares two versions of the same element,
+// eg. version N and N+1, and reports what changed.
+func NewDiffMetadata(from, to Element) (*DiffMetadata, error) {
+	if from.FeatureID() != to.FeatureID() {
+		return nil, fmt.Errorf("osm: can not diff different elements %v and %v", from.FeatureID(), to.FeatureID())
+	}
+
+	d := &DiffMetadata{
+		FeatureID:   from.FeatureID(),
+		FromVersion: from.ElementID().Version(),
+		ToVersion:   to.ElementID().Version(),
+	}
+
+	var fromTags, toTags Tags
+	var fromVisible, toVisible bool
+
+	switch f := from.(type) {
+	case *Node:
+		t := to.(*Node)
+		fromTags, toTags = f.Tags, t.Tags
+		fromVisible, toVisible = f.Visible, t.Visible
+		d.Moved = f.Lat != t.Lat || f.Lon != t.Lon
+	case *Way:
+		t := to.(*Way)
+		fromTags, toTags = f.Tags, t.Tags
+		fromVisible, toVisible = f.Visible, t.Visible
+		d.NodesChanged = !(&Way{Nodes: f.Nodes}).Equal(&Way{Nodes: t.Nodes}, IgnoreMetadata())
+	case *Relation:
+		t := to.(*Relation)
+		fromTags, toTags = f.Tags, t.Tags
+		fromVisible, toVisible = f.Visible, t.Visible
+		d.MembersChanged = !(&Relation{Members: f.Members}).Equal(&Relation{Members: t.Members}, IgnoreMetadata())
+	default:
+		return nil, fmt.Errorf("osm: unsupported element type %T", from)
+	}
+
+	d.Deleted = fromVisible && !toVisible
+	d.Restored = !fromVisible && toVisible
+	d.TagsAdded, d.TagsRemoved, d.TagsChanged = fromTags.Diff(toTags)
+
+	return d, nil
+}
+
+// TagsModified returns true if any tags were added, removed or changed.
+func (d *DiffMetadata) TagsModified() bool {
+	return len(d.TagsAdded) > 0 || len(d.TagsRemoved) > 0 || len(d.TagsChanged) > 0
+}
@@ -328,3 +328,78 @@ func BenchmarkElementID_Sort(b *testing.B) {
 		tests[n].Sort()
 	}
 }
+
+func TestNewDiffMetadata(t *testing.T) {
+	t.Run("node", func(t *testing.T) {
+		from := &Node{ID: 1, Version: 1, Visible: true, Lat: 1, Lon: 2,
+			Tags: Tags{{Key: "amenity", Value: "cafe"}}}
+		to := &Node{ID: 1, Version: 2, Visible: true, Lat: 1, Lon: 3,
+			Tags: Tags{{Key: "amenity", Value: "cafe"}, {Key: "name", Value: "Joe's"}}}
+
+		d, err := NewDiffMetadata(from, to)
+		if err != nil {
+			t.Fatalf("diff error: %v", err)
+		}
+
+		if d.FromVersion != 1 || d.ToVersion != 2 {
+			t.Errorf("incorrect versions: %v %v", d.FromVersion, d.ToVersion)
+		}
+
+		if !d.Moved {
+			t.Errorf("should be moved")
+		}
+
+		if !d.TagsModified() {
+			t.Errorf("tags should be modified")
+		}
+
+		if v := d.TagsAdded.Find("name"); v != "Joe's" {
+			t.Errorf("incorrect added tags: %v", d.TagsAdded)
+		}
+	})
+
+	t.Run("way deleted", func(t *testing.T) {
+		from := &Way{ID: 1, Version: 3, Visible: true, Nodes: WayNodes{{ID: 1}, {ID: 2}}}
+		to := &Way{ID: 1, Version: 4, Visible: false}
+
+		d, err := NewDiffMetadata(from, to)
+		if err != nil {
+			t.Fatalf("diff error: %v", err)
+		}
+
+		if !d.Deleted || d.Restored {
+			t.Errorf("should be deleted: %v %v", d.Deleted, d.Restored)
+		}
+
+		if !d.NodesChanged {
+			t.Errorf("nodes should have changed")
+		}
+
+		if d.TagsModified() {
+			t.Errorf("tags should not be modified")
+		}
+	})
+
+	t.Run("relation members", func(t *testing.T) {
+		from := &Relation{ID: 1, Version: 1, Visible: true,
+			Members: Members{{Type: TypeWay, Ref: 1, Role: "outer"}}}
+		to := &Relation{ID: 1, Version: 2, Visible: true,
+			Members: Members{{Type: TypeWay, Ref: 1, Role: "inner"}}}
+
+		d, err := NewDiffMetadata(from, to)
+		if err != nil {
+			t.Fatalf("diff error: %v", err)
+		}
+
+		if !d.MembersChanged {
+			t.Errorf("members should have changed")
+		}
+	})
+
+	t.Run("different features", func(t *testing.T) {
+		_, err := NewDiffMetadata(&Node{ID: 1}, &Way{ID: 1})
+		if err == nil {
+			t.Errorf("expected error")
+		}
+	})
+}
@@ -0,0 +1,137 @@
+// Package elevation enriches osm data with elevations sampled from a
+// digital elevation model, such as SRTM tiles, for 3D rendering and
+// hiking profiles.
+package elevation
+
+import (
+	"errors"
+	"math"
+	"strconv"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/osm"
+	"github.com/paulmach/osm/pipeline"
+)
+
+// ErrNoData is returned by samplers for points without elevation data,
+// for example outside the available tiles.
+var ErrNoData = errors.New("elevation: no data")
+
+// A Sampler returns the elevation in meters at a point.
+// It should return ErrNoData if there is no data for the point.
+type Sampler interface {
+	Elevation(p orb.Point) (float64, error)
+}
+
+// SamplerFunc is an adapter to allow the use of ordinary functions
+// as samplers.
+type SamplerFunc func(p orb.Point) (float64, error)
+
+// Elevation calls f(p).
+func (f SamplerFunc) Elevation(p orb.Point) (float64, error) {
+	return f(p)
+}
+
+// An Option is a setting for the transform.
+type Option func(*options)
+
+type options struct {
+	key       string
+	precision int
+	overwrite bool
+}
+
+// Key sets the tag key used to store the elevation, the default is "ele".
+func Key(k string) Option {
+	return func(o *options) {
+		o.key = k
+	}
+}
+
+// Precision sets the number of decimals of the elevation value,
+// the default is 0, whole meters.
+func Precision(decimals int) Option {
+	return func(o *options) {
+		o.precision = decimals
+	}
+}
+
+// Overwrite will replace existing elevation tags. By default nodes
+// that already have the tag, usually surveyed values, are not changed.
+func Overwrite(yes bool) Option {
+	return func(o *options) {
+		o.overwrite = yes
+	}
+}
+
+// Transform returns a pipeline transform that sets the ele tag of the
+// nodes to the elevation from the sampler. Nodes without data are passed
+// through unchanged, other sampler errors stop the pipeline.
+func Transform(s Sampler, opts ...Option) pipeline.TransformFunc {
+	o := &options{key: "ele"}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return func(obj osm.Object) (osm.Object, error) {
+		n, ok := obj.(*osm.Node)
+		if !ok {
+			return obj, nil
+		}
+
+		i := tagIndex(n.Tags, o.key)
+		if i >= 0 && !o.overwrite {
+			return n, nil
+		}
+
+		ele, err := s.Elevation(n.Point())
+		if err == ErrNoData {
+			return n, nil
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		v := strconv.FormatFloat(ele, 'f', o.precision, 64)
+		if i >= 0 {
+			n.Tags[i].Value = v
+		} else {
+			n.Tags = append(n.Tags, osm.Tag{Key: o.key, Value: v})
+		}
+
+		return n, nil
+	}
+}
+
+// WayProfile returns the elevations of the way nodes, a parallel array to
+// w.Nodes, e.g. for the elevation profile of a hiking route. The way nodes
+// must be annotated with their locations. Nodes without data are NaN.
+func WayProfile(s Sampler, w *osm.Way) ([]float64, error) {
+	result := make([]float64, len(w.Nodes))
+	for i, wn := range w.Nodes {
+		ele, err := s.Elevation(wn.Point())
+		if err == ErrNoData {
+			result[i] = math.NaN()
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		result[i] = ele
+	}
+
+	return result, nil
+}
+
+func tagIndex(tags osm.Tags, key string) int {
+	for i, t := range tags {
+		if t.Key == key {
+			return i
+		}
+	}
+
+	return -1
+}
@@ -0,0 +1,113 @@
+package elevation
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/osm"
+)
+
+var testSampler = SamplerFunc(func(p orb.Point) (float64, error) {
+	if p.Lat() < 0 {
+		return 0, ErrNoData
+	}
+
+	return p.Lat() * 100, nil
+})
+
+func TestTransform(t *testing.T) {
+	cases := []struct {
+		name     string
+		node     *osm.Node
+		opts     []Option
+		expected osm.Tags
+	}{
+		{
+			name:     "sets tag",
+			node:     &osm.Node{Lat: 1.2345},
+			expected: osm.Tags{{Key: "ele", Value: "123"}},
+		},
+		{
+			name:     "precision",
+			node:     &osm.Node{Lat: 1.2346},
+			opts:     []Option{Precision(1)},
+			expected: osm.Tags{{Key: "ele", Value: "123.5"}},
+		},
+		{
+			name:     "key",
+			node:     &osm.Node{Lat: 1},
+			opts:     []Option{Key("ele:srtm")},
+			expected: osm.Tags{{Key: "ele:srtm", Value: "100"}},
+		},
+		{
+			name:     "keep existing",
+			node:     &osm.Node{Lat: 1, Tags: osm.Tags{{Key: "ele", Value: "95"}}},
+			expected: osm.Tags{{Key: "ele", Value: "95"}},
+		},
+		{
+			name:     "overwrite",
+			node:     &osm.Node{Lat: 1, Tags: osm.Tags{{Key: "ele", Value: "95"}}},
+			opts:     []Option{Overwrite(true)},
+			expected: osm.Tags{{Key: "ele", Value: "100"}},
+		},
+		{
+			name: "no data",
+			node: &osm.Node{Lat: -1},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			o, err := Transform(testSampler, tc.opts...)(tc.node)
+			if err != nil {
+				t.Fatalf("transform error: %v", err)
+			}
+
+			n := o.(*osm.Node)
+			if len(n.Tags) != len(tc.expected) {
+				t.Fatalf("incorrect tags: %v", n.Tags)
+			}
+
+			for i := range n.Tags {
+				if n.Tags[i] != tc.expected[i] {
+					t.Errorf("incorrect tags: %v", n.Tags)
+				}
+			}
+		})
+	}
+
+	// ways are passed through
+	w := &osm.Way{ID: 1}
+	if o, err := Transform(testSampler)(w); err != nil || o != w {
+		t.Errorf("should pass through ways: %v %v", o, err)
+	}
+
+	// sampler errors are returned
+	failing := SamplerFunc(func(p orb.Point) (float64, error) {
+		return 0, errors.New("failed")
+	})
+	if _, err := Transform(failing)(&osm.Node{}); err == nil {
+		t.Errorf("should return sampler error")
+	}
+}
+
+func TestWayProfile(t *testing.T) {
+	w := &osm.Way{
+		Nodes: osm.WayNodes{
+			{ID: 1, Lat: 1},
+			{ID: 2, Lat: -1},
+			{ID: 3, Lat: 2},
+		},
+	}
+
+	profile, err := WayProfile(testSampler, w)
+	if err != nil {
+		t.Fatalf("profile error: %v", err)
+	}
+
+	if len(profile) != 3 || profile[0] != 100 || !math.IsNaN(profile[1]) || profile[2] != 200 {
+		t.Errorf("incorrect profile: %v", profile)
+	}
+}
@@ -0,0 +1,156 @@
+package elevation
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/paulmach/orb"
+)
+
+// hgtVoid is the value of missing data in hgt files.
+const hgtVoid = -32768
+
+// HGT is a SRTM elevation tile in the .hgt format, a square grid of
+// big endian 16 bit integers covering one degree. The common sizes are
+// 1201x1201 (3 arc seconds) and 3601x3601 (1 arc second).
+type HGT struct {
+	lat, lon int
+	size     int
+	data     []byte
+}
+
+// NewHGT creates a tile from the contents of a hgt file. Lat and lon are
+// the south west corner of the tile, as in the file name, e.g. N45E006.hgt
+// is lat 45 and lon 6.
+func NewHGT(lat, lon int, data []byte) (*HGT, error) {
+	size := int(math.Sqrt(float64(len(data) / 2)))
+	if size < 2 || 2*size*size != len(data) {
+		return nil, errors.New("elevation: invalid hgt data size")
+	}
+
+	return &HGT{lat: lat, lon: lon, size: size, data: data}, nil
+}
+
+// Elevation returns the elevation at the point in meters, bilinearly
+// interpolated between the grid values. Returns ErrNoData if the point is
+// outside the tile or any of the values it is interpolated from are void.
+func (h *HGT) Elevation(p orb.Point) (float64, error) {
+	y := (float64(h.lat+1) - p.Lat()) * float64(h.size-1)
+	x := (p.Lon() - float64(h.lon)) * float64(h.size-1)
+
+	max := float64(h.size - 1)
+	if x < 0 || y < 0 || x > max || y > max {
+		return 0, ErrNoData
+	}
+
+	col, row := int(x), int(y)
+	if col == h.size-1 {
+		col--
+	}
+	if row == h.size-1 {
+		row--
+	}
+
+	fx, fy := x-float64(col), y-float64(row)
+	weights := [4]float64{(1 - fx) * (1 - fy), fx * (1 - fy), (1 - fx) * fy, fx * fy}
+
+	var ele float64
+	for i, rc := range [4][2]int{{row, col}, {row, col + 1}, {row + 1, col}, {row + 1, col + 1}} {
+		if weights[i] == 0 {
+			continue
+		}
+
+		v := int16(binary.BigEndian.Uint16(h.data[2*(rc[0]*h.size+rc[1]):]))
+		if v == hgtVoid {
+			return 0, ErrNoData
+		}
+
+		ele += weights[i] * float64(v)
+	}
+
+	return ele, nil
+}
+
+// TileDir samples elevations from a directory of hgt files named by their
+// south west corner, e.g. N45E006.hgt. The tiles are loaded as needed and
+// kept in memory. Points in tiles without a file return ErrNoData.
+// It is safe for concurrent use.
+type TileDir struct {
+	path string
+
+	mu    sync.Mutex
+	tiles map[[2]int]*HGT
+}
+
+// NewTileDir creates a sampler for the hgt files in the directory.
+func NewTileDir(path string) *TileDir {
+	return &TileDir{
+		path:  path,
+		tiles: make(map[[2]int]*HGT),
+	}
+}
+
+// Elevation returns the elevation at the point in meters.
+func (d *TileDir) Elevation(p orb.Point) (float64, error) {
+	lat, lon := int(math.Floor(p.Lat())), int(math.Floor(p.Lon()))
+
+	tile, err := d.tile(lat, lon)
+	if err != nil {
+		return 0, err
+	}
+
+	if tile == nil {
+		return 0, ErrNoData
+	}
+
+	return tile.Elevation(p)
+}
+
+func (d *TileDir) tile(lat, lon int) (*HGT, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	key := [2]int{lat, lon}
+	if t, ok := d.tiles[key]; ok {
+		return t, nil
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(d.path, TileName(lat, lon)))
+	if os.IsNotExist(err) {
+		d.tiles[key] = nil
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	t, err := NewHGT(lat, lon, data)
+	if err != nil {
+		return nil, err
+	}
+
+	d.tiles[key] = t
+	return t, nil
+}
+
+// TileName returns the hgt file name of the tile with the south west
+// corner at lat, lon, e.g. N45E006.hgt.
+func TileName(lat, lon int) string {
+	ns, ew := 'N', 'E'
+	if lat < 0 {
+		ns, lat = 'S', -lat
+	}
+
+	if lon < 0 {
+		ew, lon = 'W', -lon
+	}
+
+	return fmt.Sprintf("%c%02d%c%03d.hgt", ns, lat, ew, lon)
+}
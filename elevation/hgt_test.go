@@ -0,0 +1,104 @@
+package elevation
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/paulmach/orb"
+)
+
+// testHGT returns a 3x3 tile, rows from north to south.
+func testHGT(values ...int16) []byte {
+	data := make([]byte, 2*len(values))
+	for i, v := range values {
+		binary.BigEndian.PutUint16(data[2*i:], uint16(v))
+	}
+
+	return data
+}
+
+func TestHGT_Elevation(t *testing.T) {
+	h, err := NewHGT(45, 6, testHGT(
+		100, 200, 300,
+		100, 200, 300,
+		0, 0, hgtVoid,
+	))
+	if err != nil {
+		t.Fatalf("create error: %v", err)
+	}
+
+	cases := []struct {
+		name     string
+		point    orb.Point
+		expected float64
+		err      error
+	}{
+		{name: "north west corner", point: orb.Point{6, 46}, expected: 100},
+		{name: "grid value", point: orb.Point{6.5, 45.5}, expected: 200},
+		{name: "interpolated", point: orb.Point{6.25, 46}, expected: 150},
+		{name: "interpolated rows", point: orb.Point{6, 45.25}, expected: 50},
+		{name: "east edge", point: orb.Point{7, 46}, expected: 300},
+		{name: "void", point: orb.Point{6.75, 45.25}, err: ErrNoData},
+		{name: "outside", point: orb.Point{7.5, 45.5}, err: ErrNoData},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			v, err := h.Elevation(tc.point)
+			if err != tc.err {
+				t.Fatalf("incorrect error: %v", err)
+			}
+
+			if v != tc.expected {
+				t.Errorf("incorrect elevation: %v", v)
+			}
+		})
+	}
+
+	if _, err := NewHGT(45, 6, make([]byte, 10)); err == nil {
+		t.Errorf("should error for invalid size")
+	}
+}
+
+func TestTileDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "elevation")
+	if err != nil {
+		t.Fatalf("temp dir error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	data := testHGT(10, 10, 10, 10, 10, 10, 10, 10, 10)
+	err = ioutil.WriteFile(filepath.Join(dir, "S01W002.hgt"), data, 0644)
+	if err != nil {
+		t.Fatalf("write error: %v", err)
+	}
+
+	d := NewTileDir(dir)
+	if v, err := d.Elevation(orb.Point{-1.5, -0.5}); err != nil || v != 10 {
+		t.Errorf("incorrect elevation: %v %v", v, err)
+	}
+
+	if _, err := d.Elevation(orb.Point{1.5, 0.5}); err != ErrNoData {
+		t.Errorf("incorrect error: %v", err)
+	}
+}
+
+func TestTileName(t *testing.T) {
+	cases := []struct {
+		lat, lon int
+		expected string
+	}{
+		{lat: 45, lon: 6, expected: "N45E006.hgt"},
+		{lat: -1, lon: -2, expected: "S01W002.hgt"},
+		{lat: 0, lon: -120, expected: "N00W120.hgt"},
+	}
+
+	for _, tc := range cases {
+		if v := TileName(tc.lat, tc.lon); v != tc.expected {
+			t.Errorf("incorrect name: %v != %v", v, tc.expected)
+		}
+	}
+}
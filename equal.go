@@ -0,0 +1,134 @@
+package osm
+
+import (
+	"sort"
+	"time"
+)
+
+// An EqualOption changes how elements are compared.
+type EqualOption func(*equalOptions)
+
+type equalOptions struct {
+	ignoreMetadata bool
+}
+
+// IgnoreMetadata will only compare the id, visibility, tags, and location,
+// way node ids or members. The user, version, changeset, timestamps,
+// annotations, updates and bounds are ignored.
+func IgnoreMetadata() EqualOption {
+	return func(o *equalOptions) {
+		o.ignoreMetadata = true
+	}
+}
+
+func newEqualOptions(opts []EqualOption) *equalOptions {
+	o := &equalOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+// equalTags compares the tags ignoring the order. Sorted copies are
+// compared so repeated keys are handled the same in both directions
+// and equal tags hash the same.
+func equalTags(a, b Tags) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	sa, sb := sortedTags(a), sortedTags(b)
+	for i := range sa {
+		if sa[i] != sb[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// sortedTags returns a copy of the tags sorted by key and value.
+func sortedTags(ts Tags) Tags {
+	sorted := make(Tags, len(ts))
+	copy(sorted, ts)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Key != sorted[j].Key {
+			return sorted[i].Key < sorted[j].Key
+		}
+
+		return sorted[i].Value < sorted[j].Value
+	})
+
+	return sorted
+}
+
+func equalTimePtr(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	return a.Equal(*b)
+}
+
+func equalBounds(a, b *Bounds) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	return *a == *b
+}
+
+func equalUpdates(a, b Updates) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		u1, u2 := a[i], b[i]
+		if !u1.Timestamp.Equal(u2.Timestamp) {
+			return false
+		}
+
+		u1.Timestamp, u2.Timestamp = time.Time{}, time.Time{}
+		if u1 != u2 {
+			return false
+		}
+	}
+
+	return true
+}
+
+func copyTimePtr(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+
+	c := *t
+	return &c
+}
+
+func copyBounds(b *Bounds) *Bounds {
+	if b == nil {
+		return nil
+	}
+
+	c := *b
+	return &c
+}
+
+func (ts Tags) copy() Tags {
+	if ts == nil {
+		return nil
+	}
+
+	return append(Tags(nil), ts...)
+}
+
+func (us Updates) copy() Updates {
+	if us == nil {
+		return nil
+	}
+
+	return append(Updates(nil), us...)
+}
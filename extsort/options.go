@@ -0,0 +1,52 @@
+package extsort
+
+import (
+	"errors"
+
+	"github.com/paulmach/osm"
+)
+
+// An Option is a setting for the sorter.
+type Option func(*Sorter) error
+
+// MemoryBudget sets the approximate number of bytes of elements kept in
+// memory before a sorted chunk is written to disk. The default is 256MB.
+func MemoryBudget(bytes int64) Option {
+	return func(s *Sorter) error {
+		if bytes <= 0 {
+			return errors.New("extsort: memory budget must be positive")
+		}
+
+		s.budget = bytes
+		return nil
+	}
+}
+
+// TempDir sets the directory for the chunk files.
+// The default is the system temporary directory.
+func TempDir(dir string) Option {
+	return func(s *Sorter) error {
+		s.dir = dir
+		return nil
+	}
+}
+
+// Less sets the order of the elements. The default is by type, node, way,
+// relation, then id and version, the order of osm.Elements.Sort.
+// Elements that are equal keep the order they were added.
+func Less(less func(a, b osm.Element) bool) Option {
+	return func(s *Sorter) error {
+		s.less = less
+		return nil
+	}
+}
+
+// Dedup drops an element if it has the same element id, type, id and
+// version, as the previous one, keeping the first added. The order must
+// put the same element ids next to each other, as the default does.
+func Dedup() Option {
+	return func(s *Sorter) error {
+		s.dedup = true
+		return nil
+	}
+}
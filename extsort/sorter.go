@@ -0,0 +1,543 @@
+// Package extsort sorts osm elements that do not fit in memory. Elements
+// are buffered up to a memory budget, then sorted and written to temporary
+// chunk files encoded with the osmpb protocol buffers. The sorted result
+// is a k-way merge of the chunks.
+package extsort
+
+import (
+	"bufio"
+	"container/heap"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+
+	"github.com/paulmach/osm"
+)
+
+const defaultBudget = 256 * 1024 * 1024
+
+// A Sorter collects elements and returns them in order. Use New to create
+// one, Add the elements and then call Sort. It is not safe for concurrent use.
+type Sorter struct {
+	budget int64
+	dir    string
+	less   func(a, b osm.Element) bool
+	dedup  bool
+
+	buffer osm.Elements
+	size   int64
+	chunks []string
+	sorted bool
+}
+
+// New creates a sorter with the options.
+func New(opts ...Option) (*Sorter, error) {
+	s := &Sorter{
+		budget: defaultBudget,
+		less:   defaultLess,
+	}
+
+	for _, opt := range opts {
+		if err := opt(s); err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+// Add adds a node, way or relation to be sorted. A sorted chunk is
+// written to disk if the memory budget is exceeded.
+func (s *Sorter) Add(e osm.Element) error {
+	if s.sorted {
+		return errors.New("extsort: add after sort")
+	}
+
+	switch e.(type) {
+	case *osm.Node, *osm.Way, *osm.Relation:
+	default:
+		return fmt.Errorf("extsort: unsupported type %T", e)
+	}
+
+	s.buffer = append(s.buffer, e)
+	s.size += approxSize(e)
+
+	if s.size >= s.budget {
+		return s.spill()
+	}
+
+	return nil
+}
+
+// AddScanner adds all the nodes, ways and relations from the scanner.
+// Other objects, like changesets, are skipped.
+func (s *Sorter) AddScanner(ctx context.Context, scanner osm.Scanner) error {
+	for scanner.Scan() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		e, ok := scanner.Object().(osm.Element)
+		if !ok {
+			continue
+		}
+
+		switch e.(type) {
+		case *osm.Node, *osm.Way, *osm.Relation:
+			if err := s.Add(e); err != nil {
+				return err
+			}
+		}
+	}
+
+	return scanner.Err()
+}
+
+// Sort returns a scanner over all the added elements in order. The chunk
+// files are removed when the scanner is closed. The sorter can not be
+// used after this call.
+func (s *Sorter) Sort(ctx context.Context) (*Scanner, error) {
+	if s.sorted {
+		return nil, errors.New("extsort: already sorted")
+	}
+	s.sorted = true
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	s.sortBuffer()
+
+	scanner := &Scanner{
+		ctx:   ctx,
+		less:  s.less,
+		dedup: s.dedup,
+		files: s.chunks,
+	}
+
+	for i, name := range s.chunks {
+		f, err := os.Open(name)
+		if err != nil {
+			scanner.Close()
+			return nil, err
+		}
+
+		c := &chunk{index: i, file: f, r: bufio.NewReader(f)}
+		scanner.open = append(scanner.open, c)
+
+		if err := scanner.push(c); err != nil {
+			scanner.Close()
+			return nil, err
+		}
+	}
+
+	if len(s.buffer) > 0 {
+		if err := scanner.push(&chunk{index: len(s.chunks), memory: s.buffer}); err != nil {
+			scanner.Close()
+			return nil, err
+		}
+	}
+
+	s.buffer = nil
+	return scanner, nil
+}
+
+// Close removes any chunk files if Sort has not been called.
+func (s *Sorter) Close() error {
+	if s.sorted {
+		return nil
+	}
+	s.sorted = true
+
+	s.buffer = nil
+	return removeAll(s.chunks)
+}
+
+func (s *Sorter) sortBuffer() {
+	sort.SliceStable(s.buffer, func(i, j int) bool {
+		return s.less(s.buffer[i], s.buffer[j])
+	})
+}
+
+// spill writes the sorted buffer to a new chunk file.
+func (s *Sorter) spill() error {
+	s.sortBuffer()
+
+	f, err := ioutil.TempFile(s.dir, "osm-extsort-")
+	if err != nil {
+		return err
+	}
+	s.chunks = append(s.chunks, f.Name())
+
+	w := bufio.NewWriter(f)
+	for _, e := range s.buffer {
+		if err := writeElement(w, e); err != nil {
+			f.Close()
+			return err
+		}
+	}
+
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	// release the elements but keep the capacity
+	for i := range s.buffer {
+		s.buffer[i] = nil
+	}
+	s.buffer = s.buffer[:0]
+	s.size = 0
+
+	return nil
+}
+
+func removeAll(names []string) error {
+	var result error
+	for _, name := range names {
+		if err := os.Remove(name); err != nil && !os.IsNotExist(err) && result == nil {
+			result = err
+		}
+	}
+
+	return result
+}
+
+// approxSize estimates the memory used by the element.
+func approxSize(e osm.Element) int64 {
+	const base = 160
+
+	var size int64 = base
+	switch e := e.(type) {
+	case *osm.Node:
+		size += tagsSize(e.Tags) + int64(len(e.User))
+	case *osm.Way:
+		size += tagsSize(e.Tags) + int64(len(e.User)) + 48*int64(len(e.Nodes))
+	case *osm.Relation:
+		size += tagsSize(e.Tags) + int64(len(e.User))
+		for _, m := range e.Members {
+			size += 96 + int64(len(m.Role))
+		}
+	}
+
+	return size
+}
+
+func tagsSize(tags osm.Tags) int64 {
+	var size int64
+	for _, t := range tags {
+		size += 32 + int64(len(t.Key)) + int64(len(t.Value))
+	}
+
+	return size
+}
+
+// writeElement writes the element as the type, the length of
+// the encoded data and the data encoded in the osmpb format.
+func writeElement(w *bufio.Writer, e osm.Element) error {
+	var (
+		data []byte
+		err  error
+	)
+
+	switch e := e.(type) {
+	case *osm.Node:
+		data, err = osm.Nodes{e}.Marshal()
+	case *osm.Way:
+		data, err = osm.Ways{e}.Marshal()
+	case *osm.Relation:
+		data, err = osm.Relations{e}.Marshal()
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if err := w.WriteByte(typeByte(elementKeyOf(e).t)); err != nil {
+		return err
+	}
+
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(buf[:], uint64(len(data)))
+	if _, err := w.Write(buf[:n]); err != nil {
+		return err
+	}
+
+	_, err = w.Write(data)
+	return err
+}
+
+// readElement reads the next element written by writeElement.
+func readElement(r *bufio.Reader) (osm.Element, error) {
+	t, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+
+	l, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, unexpected(err)
+	}
+
+	data := make([]byte, l)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, unexpected(err)
+	}
+
+	switch t {
+	case 'n':
+		ns, err := osm.UnmarshalNodes(data)
+		if err != nil || len(ns) != 1 {
+			return nil, corrupt(err)
+		}
+		return ns[0], nil
+	case 'w':
+		ws, err := osm.UnmarshalWays(data)
+		if err != nil || len(ws) != 1 {
+			return nil, corrupt(err)
+		}
+		return ws[0], nil
+	case 'r':
+		rs, err := osm.UnmarshalRelations(data)
+		if err != nil || len(rs) != 1 {
+			return nil, corrupt(err)
+		}
+		return rs[0], nil
+	}
+
+	return nil, corrupt(nil)
+}
+
+// elementKey is the type, id and version of an element. The packed
+// ElementID is not used since negative ids, used for new elements,
+// do not pack into it.
+type elementKey struct {
+	t       osm.Type
+	ref     int64
+	version int
+}
+
+func elementKeyOf(e osm.Element) elementKey {
+	switch e := e.(type) {
+	case *osm.Node:
+		return elementKey{osm.TypeNode, int64(e.ID), e.Version}
+	case *osm.Way:
+		return elementKey{osm.TypeWay, int64(e.ID), e.Version}
+	case *osm.Relation:
+		return elementKey{osm.TypeRelation, int64(e.ID), e.Version}
+	}
+
+	return elementKey{}
+}
+
+// defaultLess orders by type, nodes then ways then relations,
+// then by id and version.
+func defaultLess(a, b osm.Element) bool {
+	ka, kb := elementKeyOf(a), elementKeyOf(b)
+	if ka.t != kb.t {
+		return typeOrder[ka.t] < typeOrder[kb.t]
+	}
+
+	if ka.ref != kb.ref {
+		return ka.ref < kb.ref
+	}
+
+	return ka.version < kb.version
+}
+
+var typeOrder = map[osm.Type]int{
+	osm.TypeNode:     1,
+	osm.TypeWay:      2,
+	osm.TypeRelation: 3,
+}
+
+func typeByte(t osm.Type) byte {
+	switch t {
+	case osm.TypeNode:
+		return 'n'
+	case osm.TypeWay:
+		return 'w'
+	}
+
+	return 'r'
+}
+
+func unexpected(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+
+	return err
+}
+
+func corrupt(err error) error {
+	if err != nil {
+		return fmt.Errorf("extsort: corrupt chunk: %v", err)
+	}
+
+	return errors.New("extsort: corrupt chunk")
+}
+
+// a chunk is a sorted file, or the sorted in memory remainder.
+type chunk struct {
+	index int
+	file  *os.File
+	r     *bufio.Reader
+
+	memory osm.Elements
+	next   osm.Element
+}
+
+func (c *chunk) read() (osm.Element, error) {
+	if c.r == nil {
+		if len(c.memory) == 0 {
+			return nil, io.EOF
+		}
+
+		e := c.memory[0]
+		c.memory = c.memory[1:]
+		return e, nil
+	}
+
+	return readElement(c.r)
+}
+
+// chunkHeap orders the chunks by their next element,
+// the earlier chunk first if equal to keep the sort stable.
+type chunkHeap struct {
+	chunks []*chunk
+	less   func(a, b osm.Element) bool
+}
+
+func (h *chunkHeap) Len() int      { return len(h.chunks) }
+func (h *chunkHeap) Swap(i, j int) { h.chunks[i], h.chunks[j] = h.chunks[j], h.chunks[i] }
+func (h *chunkHeap) Less(i, j int) bool {
+	a, b := h.chunks[i], h.chunks[j]
+	if h.less(a.next, b.next) {
+		return true
+	}
+
+	if h.less(b.next, a.next) {
+		return false
+	}
+
+	return a.index < b.index
+}
+
+func (h *chunkHeap) Push(x interface{}) { h.chunks = append(h.chunks, x.(*chunk)) }
+func (h *chunkHeap) Pop() interface{} {
+	c := h.chunks[len(h.chunks)-1]
+	h.chunks = h.chunks[:len(h.chunks)-1]
+	return c
+}
+
+var _ osm.Scanner = &Scanner{}
+
+// Scanner merges the sorted chunks. It implements the osm.Scanner interface.
+type Scanner struct {
+	ctx   context.Context
+	less  func(a, b osm.Element) bool
+	dedup bool
+
+	heap   *chunkHeap
+	open   []*chunk
+	files  []string
+	closed bool
+
+	next osm.Element
+	err  error
+}
+
+// push reads the next element of the chunk and adds it to the heap.
+func (s *Scanner) push(c *chunk) error {
+	if s.heap == nil {
+		s.heap = &chunkHeap{less: s.less}
+	}
+
+	e, err := c.read()
+	if err == io.EOF {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	c.next = e
+	heap.Push(s.heap, c)
+	return nil
+}
+
+// Scan advances to the next element in order, which will then be available
+// through the Object and Element methods. It returns false when all the
+// elements have been returned, a chunk could not be read or the context is
+// canceled.
+func (s *Scanner) Scan() bool {
+	if s.err != nil || s.closed {
+		return false
+	}
+
+	for {
+		if err := s.ctx.Err(); err != nil {
+			s.err = err
+			return false
+		}
+
+		if s.heap == nil || s.heap.Len() == 0 {
+			return false
+		}
+
+		c := heap.Pop(s.heap).(*chunk)
+		e := c.next
+
+		if err := s.push(c); err != nil {
+			s.err = err
+			return false
+		}
+
+		if s.dedup && s.next != nil && elementKeyOf(s.next) == elementKeyOf(e) {
+			continue
+		}
+
+		s.next = e
+		return true
+	}
+}
+
+// Object returns the current element as an osm.Object.
+func (s *Scanner) Object() osm.Object {
+	return s.next
+}
+
+// Element returns the current element.
+func (s *Scanner) Element() osm.Element {
+	return s.next
+}
+
+// Err returns the first error that was encountered by the Scanner.
+func (s *Scanner) Err() error {
+	return s.err
+}
+
+// Close closes and removes the chunk files.
+func (s *Scanner) Close() error {
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+
+	for _, c := range s.open {
+		c.file.Close()
+	}
+
+	return removeAll(s.files)
+}
@@ -0,0 +1,257 @@
+package extsort
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/paulmach/osm"
+	"github.com/paulmach/osm/osmtest"
+)
+
+func TestSorter(t *testing.T) {
+	g := osmtest.NewGenerator(42)
+	o := g.OSM(300, 100, 30)
+
+	elements := o.Elements()
+	rand.New(rand.NewSource(1)).Shuffle(len(elements), func(i, j int) {
+		elements[i], elements[j] = elements[j], elements[i]
+	})
+
+	cases := []struct {
+		name   string
+		budget int64
+		chunks int
+	}{
+		{name: "in memory", budget: 1 << 30, chunks: 0},
+		{name: "spilled", budget: 10000, chunks: 1},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := tempDir(t)
+			defer os.RemoveAll(dir)
+
+			s, err := New(MemoryBudget(tc.budget), TempDir(dir))
+			if err != nil {
+				t.Fatalf("new error: %v", err)
+			}
+
+			for _, e := range elements {
+				if err := s.Add(e); err != nil {
+					t.Fatalf("add error: %v", err)
+				}
+			}
+
+			if len(s.chunks) < tc.chunks {
+				t.Errorf("incorrect number of chunks: %v", len(s.chunks))
+			}
+
+			scanner, err := s.Sort(context.Background())
+			if err != nil {
+				t.Fatalf("sort error: %v", err)
+			}
+
+			var result osm.Elements
+			for scanner.Scan() {
+				result = append(result, scanner.Element())
+			}
+
+			if err := scanner.Err(); err != nil {
+				t.Fatalf("scan error: %v", err)
+			}
+
+			expected := o.Elements()
+			expected.Sort()
+			if !reflect.DeepEqual(result, expected) {
+				t.Errorf("incorrect result, %d elements", len(result))
+			}
+
+			scanner.Close()
+			if files, _ := ioutil.ReadDir(dir); len(files) != 0 {
+				t.Errorf("chunk files should be removed: %v", len(files))
+			}
+		})
+	}
+}
+
+func TestSorter_Dedup(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	s, _ := New(MemoryBudget(1), TempDir(dir), Dedup())
+
+	input := osm.Elements{
+		&osm.Way{ID: 1, Version: 1, Tags: osm.Tags{{Key: "first", Value: "yes"}}},
+		&osm.Node{ID: 2, Version: 1},
+		&osm.Node{ID: 1, Version: 2},
+		&osm.Way{ID: 1, Version: 1},
+		&osm.Node{ID: 1, Version: 2},
+		&osm.Node{ID: 1, Version: 1},
+	}
+
+	for _, e := range input {
+		s.Add(e)
+	}
+
+	scanner, err := s.Sort(nil)
+	if err != nil {
+		t.Fatalf("sort error: %v", err)
+	}
+	defer scanner.Close()
+
+	var result osm.ElementIDs
+	var way *osm.Way
+	for scanner.Scan() {
+		result = append(result, scanner.Element().ElementID())
+		if w, ok := scanner.Object().(*osm.Way); ok {
+			way = w
+		}
+	}
+
+	expected := osm.ElementIDs{
+		osm.NodeID(1).ElementID(1),
+		osm.NodeID(1).ElementID(2),
+		osm.NodeID(2).ElementID(1),
+		osm.WayID(1).ElementID(1),
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("incorrect result: %v", result)
+	}
+
+	if way == nil || len(way.Tags) != 1 {
+		t.Errorf("should keep the first added: %v", way)
+	}
+}
+
+func TestSorter_negativeIDs(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	// chunks are written to disk for every element
+	s, _ := New(MemoryBudget(1), TempDir(dir), Dedup())
+
+	input := osm.Elements{
+		&osm.Relation{ID: -1},
+		&osm.Way{ID: -1},
+		&osm.Node{ID: 1},
+		&osm.Node{ID: -1},
+		&osm.Node{ID: -2},
+		&osm.Way{ID: -1},
+	}
+
+	for _, e := range input {
+		if err := s.Add(e); err != nil {
+			t.Fatalf("add error: %v", err)
+		}
+	}
+
+	scanner, err := s.Sort(context.Background())
+	if err != nil {
+		t.Fatalf("sort error: %v", err)
+	}
+	defer scanner.Close()
+
+	var result []string
+	for scanner.Scan() {
+		e := scanner.Element()
+		result = append(result, fmt.Sprintf("%s/%d", elementKeyOf(e).t, elementKeyOf(e).ref))
+	}
+
+	if err := scanner.Err(); err != nil {
+		t.Fatalf("scan error: %v", err)
+	}
+
+	expected := []string{"node/-2", "node/-1", "node/1", "way/-1", "relation/-1"}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("incorrect result: %v", result)
+	}
+}
+
+func TestSorter_Less(t *testing.T) {
+	// order by id descending, ignoring the type
+	s, _ := New(Less(func(a, b osm.Element) bool {
+		return a.ElementID().Ref() > b.ElementID().Ref()
+	}))
+
+	s.Add(&osm.Node{ID: 1})
+	s.Add(&osm.Way{ID: 2})
+	s.Add(&osm.Relation{ID: 3})
+	s.Add(&osm.Node{ID: 2})
+
+	scanner, _ := s.Sort(context.Background())
+	defer scanner.Close()
+
+	var result osm.ElementIDs
+	for scanner.Scan() {
+		result = append(result, scanner.Element().ElementID())
+	}
+
+	expected := osm.ElementIDs{
+		osm.RelationID(3).ElementID(0),
+		osm.WayID(2).ElementID(0),
+		osm.NodeID(2).ElementID(0),
+		osm.NodeID(1).ElementID(0),
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("incorrect result: %v", result)
+	}
+}
+
+func TestSorter_errors(t *testing.T) {
+	if _, err := New(MemoryBudget(0)); err == nil {
+		t.Errorf("should error on zero budget")
+	}
+
+	s, _ := New()
+	scanner, _ := s.Sort(context.Background())
+	defer scanner.Close()
+
+	if scanner.Scan() {
+		t.Errorf("should not scan anything")
+	}
+
+	if err := s.Add(&osm.Node{ID: 1}); err == nil {
+		t.Errorf("should error on add after sort")
+	}
+
+	if _, err := s.Sort(context.Background()); err == nil {
+		t.Errorf("should error on second sort")
+	}
+}
+
+func TestScanner_context(t *testing.T) {
+	s, _ := New()
+	s.Add(&osm.Node{ID: 1})
+	s.Add(&osm.Node{ID: 2})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	scanner, _ := s.Sort(ctx)
+	defer scanner.Close()
+
+	if !scanner.Scan() {
+		t.Fatalf("should scan first")
+	}
+
+	cancel()
+	if scanner.Scan() {
+		t.Errorf("should stop when the context is canceled")
+	}
+
+	if scanner.Err() != context.Canceled {
+		t.Errorf("incorrect error: %v", scanner.Err())
+	}
+}
+
+func tempDir(t testing.TB) string {
+	dir, err := ioutil.TempDir("", "extsort")
+	if err != nil {
+		t.Fatalf("temp dir error: %v", err)
+	}
+
+	return dir
+}
@@ -0,0 +1,157 @@
+package flatgeobuf
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+	"sort"
+
+	"github.com/paulmach/orb"
+)
+
+const (
+	nodeItemSize = 40
+	hilbertMax   = (1 << 16) - 1
+)
+
+// node is a packed R-tree node. For leaf nodes the offset is the byte offset
+// of the feature in the data section, for other nodes it's the index of the
+// first child node.
+type node struct {
+	bound  orb.Bound
+	offset uint64
+}
+
+// levelBounds returns the [start, end) node indexes of each tree level,
+// leaves first. The nodes are stored with the root first.
+func levelBounds(numItems, nodeSize int) [][2]int {
+	n := numItems
+	numNodes := n
+	levelNumNodes := []int{n}
+	for n != 1 {
+		n = (n + nodeSize - 1) / nodeSize
+		numNodes += n
+		levelNumNodes = append(levelNumNodes, n)
+	}
+
+	bounds := make([][2]int, len(levelNumNodes))
+	end := numNodes
+	for i, count := range levelNumNodes {
+		bounds[i] = [2]int{end - count, end}
+		end -= count
+	}
+
+	return bounds
+}
+
+// buildIndex creates all the nodes of the packed R-tree given the sorted leaves.
+func buildIndex(leaves []node, nodeSize int) []node {
+	levels := levelBounds(len(leaves), nodeSize)
+	nodes := make([]node, levels[0][1])
+	copy(nodes[levels[0][0]:], leaves)
+
+	for i := 0; i < len(levels)-1; i++ {
+		children := levels[i]
+		parent := levels[i+1][0]
+		for pos := children[0]; pos < children[1]; pos += nodeSize {
+			n := node{bound: nodes[pos].bound, offset: uint64(pos)}
+			for j := pos + 1; j < pos+nodeSize && j < children[1]; j++ {
+				n.bound = n.bound.Union(nodes[j].bound)
+			}
+
+			nodes[parent] = n
+			parent++
+		}
+	}
+
+	return nodes
+}
+
+func writeIndex(w io.Writer, nodes []node) error {
+	buf := make([]byte, nodeItemSize*len(nodes))
+	for i, n := range nodes {
+		b := buf[i*nodeItemSize:]
+		binary.LittleEndian.PutUint64(b[0:], math.Float64bits(n.bound.Min[0]))
+		binary.LittleEndian.PutUint64(b[8:], math.Float64bits(n.bound.Min[1]))
+		binary.LittleEndian.PutUint64(b[16:], math.Float64bits(n.bound.Max[0]))
+		binary.LittleEndian.PutUint64(b[24:], math.Float64bits(n.bound.Max[1]))
+		binary.LittleEndian.PutUint64(b[32:], n.offset)
+	}
+
+	_, err := w.Write(buf)
+	return err
+}
+
+// hilbertSort sorts the items by the hilbert value of their bound
+// centers within the extent.
+func hilbertSort(items []*item, extent orb.Bound) {
+	width := extent.Max[0] - extent.Min[0]
+	height := extent.Max[1] - extent.Min[1]
+
+	values := make(map[*item]uint32, len(items))
+	for _, it := range items {
+		c := it.bound.Center()
+
+		var x, y uint32
+		if width != 0 {
+			x = uint32(math.Floor(hilbertMax * (c[0] - extent.Min[0]) / width))
+		}
+
+		if height != 0 {
+			y = uint32(math.Floor(hilbertMax * (c[1] - extent.Min[1]) / height))
+		}
+
+		values[it] = hilbert(x, y)
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		return values[items[i]] > values[items[j]]
+	})
+}
+
+// hilbert computes the hilbert curve index of a 16 bit x, y position.
+// Based on https://github.com/rawrunprotected/hilbert_curves (public domain)
+// as used by the reference FlatGeobuf implementations.
+func hilbert(x, y uint32) uint32 {
+	a := x ^ y
+	b := 0xFFFF ^ a
+	c := 0xFFFF ^ (x | y)
+	d := x & (y ^ 0xFFFF)
+
+	A := a | (b >> 1)
+	B := (a >> 1) ^ a
+	C := ((c >> 1) ^ (b & (d >> 1))) ^ c
+	D := ((a & (c >> 1)) ^ (d >> 1)) ^ d
+
+	a, b, c, d = A, B, C, D
+	A = (a & (a >> 2)) ^ (b & (b >> 2))
+	B = (a & (b >> 2)) ^ (b & ((a ^ b) >> 2))
+	C ^= (a & (c >> 2)) ^ (b & (d >> 2))
+	D ^= (b & (c >> 2)) ^ ((a ^ b) & (d >> 2))
+
+	a, b, c, d = A, B, C, D
+	A = (a & (a >> 4)) ^ (b & (b >> 4))
+	B = (a & (b >> 4)) ^ (b & ((a ^ b) >> 4))
+	C ^= (a & (c >> 4)) ^ (b & (d >> 4))
+	D ^= (b & (c >> 4)) ^ ((a ^ b) & (d >> 4))
+
+	a, b, c, d = A, B, C, D
+	C ^= (a & (c >> 8)) ^ (b & (d >> 8))
+	D ^= (b & (c >> 8)) ^ ((a ^ b) & (d >> 8))
+
+	a = C ^ (C >> 1)
+	b = D ^ (D >> 1)
+
+	i0 := x ^ y
+	i1 := b | (0xFFFF ^ (i0 | a))
+
+	return (interleave(i1) << 1) | interleave(i0)
+}
+
+func interleave(x uint32) uint32 {
+	x = (x | (x << 8)) & 0x00FF00FF
+	x = (x | (x << 4)) & 0x0F0F0F0F
+	x = (x | (x << 2)) & 0x33333333
+	x = (x | (x << 1)) & 0x55555555
+	return x
+}
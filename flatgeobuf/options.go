@@ -0,0 +1,43 @@
+package flatgeobuf
+
+import "errors"
+
+// An Option is a setting for writing the FlatGeobuf file.
+type Option func(*Writer) error
+
+// Name sets the dataset name in the header.
+func Name(name string) Option {
+	return func(w *Writer) error {
+		w.name = name
+		return nil
+	}
+}
+
+// Keys sets the tag keys written as string columns of the same name.
+// By default all the tags are written as json in a `tags` column.
+func Keys(keys ...string) Option {
+	return func(w *Writer) error {
+		for _, k := range keys {
+			if k == "osm_type" || k == "osm_id" {
+				return errors.New("flatgeobuf: reserved column name " + k)
+			}
+		}
+
+		w.keys = keys
+		return nil
+	}
+}
+
+// IndexNodeSize sets the branching factor of the packed Hilbert R-tree index.
+// The default is 16. A size of 0 disables the index and the features are
+// written directly to the output as they're added, in a fully streaming way.
+func IndexNodeSize(n uint16) Option {
+	return func(w *Writer) error {
+		if n == 1 {
+			return errors.New("flatgeobuf: index node size must be 0 or at least 2")
+		}
+
+		w.nodeSize = n
+		return nil
+	}
+}
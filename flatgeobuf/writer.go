@@ -0,0 +1,401 @@
+// Package flatgeobuf writes converted osm geometries in the FlatGeobuf format.
+package flatgeobuf
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geojson"
+	"github.com/paulmach/osm"
+	"github.com/paulmach/osm/internal/flatbuf"
+	"github.com/paulmach/osm/osmgeojson"
+)
+
+var magicBytes = []byte{'f', 'g', 'b', 3, 'f', 'g', 'b', 0}
+
+// FlatGeobuf geometry types
+const (
+	geometryUnknown uint8 = iota
+	geometryPoint
+	geometryLineString
+	geometryPolygon
+	geometryMultiPoint
+	geometryMultiLineString
+	geometryMultiPolygon
+	geometryCollection
+)
+
+// FlatGeobuf column types
+const (
+	columnLong   uint8 = 7
+	columnString uint8 = 11
+	columnJSON   uint8 = 12
+)
+
+type column struct {
+	name string
+	typ  uint8
+}
+
+type item struct {
+	bound orb.Bound
+	data  []byte
+}
+
+// Writer writes features to an output stream in the FlatGeobuf format. By default
+// features are encoded as they're added and the file, with a packed Hilbert R-tree
+// index, is written on Close. Disabling the index using IndexNodeSize(0) writes
+// the features directly to the output.
+type Writer struct {
+	w        io.Writer
+	name     string
+	keys     []string
+	nodeSize uint16
+
+	columns []column
+	items   []*item
+	extent  orb.Bound
+
+	wroteHeader bool
+}
+
+// NewWriter creates a new writer writing to w.
+func NewWriter(w io.Writer, opts ...Option) (*Writer, error) {
+	fw := &Writer{
+		w:        w,
+		nodeSize: 16,
+	}
+
+	for _, opt := range opts {
+		if err := opt(fw); err != nil {
+			return nil, err
+		}
+	}
+
+	fw.columns = []column{{"osm_type", columnString}, {"osm_id", columnLong}}
+	if len(fw.keys) == 0 {
+		fw.columns = append(fw.columns, column{"tags", columnJSON})
+	}
+
+	for _, k := range fw.keys {
+		fw.columns = append(fw.columns, column{k, columnString})
+	}
+
+	return fw, nil
+}
+
+// Write converts the osm data to geometry, using the osmgeojson package,
+// and adds the features. Ways need their nodes, and relations their members,
+// to be in the same osm object so they can be built.
+func (w *Writer) Write(o *osm.OSM) error {
+	fc, err := osmgeojson.Convert(o,
+		osmgeojson.NoID(true),
+		osmgeojson.NoMeta(true),
+		osmgeojson.NoRelationMembership(true),
+	)
+	if err != nil {
+		return err
+	}
+
+	for _, f := range fc.Features {
+		if err := w.WriteFeature(f); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// WriteFeature adds a feature created by the osmgeojson package.
+// The osm type, id and tags are read from the feature properties.
+func (w *Writer) WriteFeature(f *geojson.Feature) error {
+	data, err := w.encodeFeature(f)
+	if err != nil {
+		return err
+	}
+
+	if w.nodeSize == 0 {
+		if err := w.writeHeader(0, nil); err != nil {
+			return err
+		}
+
+		_, err := w.w.Write(data)
+		return err
+	}
+
+	b := f.Geometry.Bound()
+	if len(w.items) == 0 {
+		w.extent = b
+	} else {
+		w.extent = w.extent.Union(b)
+	}
+
+	w.items = append(w.items, &item{bound: b, data: data})
+	return nil
+}
+
+// Close writes the header, index and features if the index is enabled.
+// It does not close the underlying writer.
+func (w *Writer) Close() error {
+	if w.nodeSize == 0 {
+		return w.writeHeader(0, nil)
+	}
+
+	var extent *orb.Bound
+	if len(w.items) > 0 {
+		extent = &w.extent
+	}
+
+	if err := w.writeHeader(uint64(len(w.items)), extent); err != nil {
+		return err
+	}
+
+	if len(w.items) == 0 {
+		return nil
+	}
+
+	hilbertSort(w.items, w.extent)
+
+	leaves := make([]node, len(w.items))
+	var offset uint64
+	for i, it := range w.items {
+		leaves[i] = node{bound: it.bound, offset: offset}
+		offset += uint64(len(it.data))
+	}
+
+	if err := writeIndex(w.w, buildIndex(leaves, int(w.nodeSize))); err != nil {
+		return err
+	}
+
+	for _, it := range w.items {
+		if _, err := w.w.Write(it.data); err != nil {
+			return err
+		}
+	}
+
+	w.items = nil
+	return nil
+}
+
+func (w *Writer) writeHeader(count uint64, extent *orb.Bound) error {
+	if w.wroteHeader {
+		return nil
+	}
+	w.wroteHeader = true
+
+	if _, err := w.w.Write(magicBytes); err != nil {
+		return err
+	}
+
+	_, err := w.w.Write(sizePrefixed(w.encodeHeader(count, extent)))
+	return err
+}
+
+func (w *Writer) encodeHeader(count uint64, extent *orb.Bound) []byte {
+	b := flatbuf.NewBuilder(1024)
+
+	cols := make([]int, len(w.columns))
+	for i, c := range w.columns {
+		name := b.CreateString(c.name)
+		b.StartTable(11)
+		b.AddOffset(0, name)
+		b.AddUint8(1, c.typ)
+		cols[i] = b.EndTable()
+	}
+	columns := b.CreateOffsets(cols)
+
+	var envelope, name int
+	if extent != nil {
+		envelope = b.CreateFloat64s([]float64{extent.Min[0], extent.Min[1], extent.Max[0], extent.Max[1]})
+	}
+
+	if w.name != "" {
+		name = b.CreateString(w.name)
+	}
+
+	org := b.CreateString("EPSG")
+	b.StartTable(6)
+	b.AddOffset(0, org)
+	b.AddInt32(1, 4326)
+	crs := b.EndTable()
+
+	b.StartTable(14)
+	b.AddUint64(8, count)
+	if name != 0 {
+		b.AddOffset(0, name)
+	}
+
+	if envelope != 0 {
+		b.AddOffset(1, envelope)
+	}
+
+	b.AddOffset(7, columns)
+	b.AddOffset(10, crs)
+	b.AddUint16(9, w.nodeSize)
+	b.AddUint8(2, geometryUnknown)
+
+	return b.Finish(b.EndTable())
+}
+
+func (w *Writer) encodeFeature(f *geojson.Feature) ([]byte, error) {
+	b := flatbuf.NewBuilder(256)
+
+	geom, err := encodeGeometry(b, f.Geometry)
+	if err != nil {
+		return nil, err
+	}
+
+	props, err := w.encodeProperties(f.Properties)
+	if err != nil {
+		return nil, err
+	}
+	properties := b.CreateBytes(props)
+
+	b.StartTable(3)
+	b.AddOffset(0, geom)
+	b.AddOffset(1, properties)
+
+	return sizePrefixed(b.Finish(b.EndTable())), nil
+}
+
+// encodeProperties encodes the values as a column index followed
+// by the value for each present column.
+func (w *Writer) encodeProperties(props geojson.Properties) ([]byte, error) {
+	var data []byte
+	tags, _ := props["tags"].(map[string]string)
+
+	for i, c := range w.columns {
+		var value []byte
+		switch c.name {
+		case "osm_type":
+			t, _ := props["type"].(string)
+			value = encodeString(t)
+		case "osm_id":
+			id, _ := props["id"].(int)
+			value = make([]byte, 8)
+			binary.LittleEndian.PutUint64(value, uint64(id))
+		default:
+			if c.typ == columnJSON {
+				j, err := json.Marshal(tags)
+				if err != nil {
+					return nil, err
+				}
+				value = encodeString(string(j))
+			} else if v, ok := tags[c.name]; ok {
+				value = encodeString(v)
+			} else {
+				continue
+			}
+		}
+
+		data = append(data, byte(i), byte(i>>8))
+		data = append(data, value...)
+	}
+
+	return data, nil
+}
+
+func encodeString(s string) []byte {
+	data := make([]byte, 4, 4+len(s))
+	binary.LittleEndian.PutUint32(data, uint32(len(s)))
+	return append(data, s...)
+}
+
+func encodeGeometry(b *flatbuf.Builder, g orb.Geometry) (int, error) {
+	var (
+		typ   uint8
+		xy    []float64
+		ends  []uint32
+		parts []int
+	)
+
+	addPoints := func(ps []orb.Point) {
+		for _, p := range ps {
+			xy = append(xy, p[0], p[1])
+		}
+		ends = append(ends, uint32(len(xy)/2))
+	}
+
+	switch g := g.(type) {
+	case orb.Point:
+		typ = geometryPoint
+		xy = []float64{g[0], g[1]}
+	case orb.MultiPoint:
+		typ = geometryMultiPoint
+		addPoints(g)
+	case orb.LineString:
+		typ = geometryLineString
+		addPoints(g)
+	case orb.MultiLineString:
+		typ = geometryMultiLineString
+		for _, ls := range g {
+			addPoints(ls)
+		}
+	case orb.Polygon:
+		typ = geometryPolygon
+		for _, r := range g {
+			addPoints(r)
+		}
+	case orb.MultiPolygon:
+		typ = geometryMultiPolygon
+		for _, p := range g {
+			off, err := encodeGeometry(b, p)
+			if err != nil {
+				return 0, err
+			}
+			parts = append(parts, off)
+		}
+	case orb.Collection:
+		typ = geometryCollection
+		for _, c := range g {
+			off, err := encodeGeometry(b, c)
+			if err != nil {
+				return 0, err
+			}
+			parts = append(parts, off)
+		}
+	default:
+		return 0, fmt.Errorf("flatgeobuf: unsupported geometry type %T", g)
+	}
+
+	var xyOff, endsOff, partsOff int
+	if len(xy) > 0 {
+		xyOff = b.CreateFloat64s(xy)
+	}
+
+	// ends are only needed if there is more than one part
+	if len(ends) > 1 {
+		endsOff = b.CreateUint32s(ends)
+	}
+
+	if len(parts) > 0 {
+		partsOff = b.CreateOffsets(parts)
+	}
+
+	b.StartTable(8)
+	if endsOff != 0 {
+		b.AddOffset(0, endsOff)
+	}
+
+	if xyOff != 0 {
+		b.AddOffset(1, xyOff)
+	}
+
+	if partsOff != 0 {
+		b.AddOffset(7, partsOff)
+	}
+	b.AddUint8(6, typ)
+
+	return b.EndTable(), nil
+}
+
+func sizePrefixed(data []byte) []byte {
+	result := make([]byte, 4+len(data))
+	binary.LittleEndian.PutUint32(result, uint32(len(data)))
+	copy(result[4:], data)
+
+	return result
+}
@@ -0,0 +1,348 @@
+package flatgeobuf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/osm"
+	"github.com/paulmach/osm/internal/flatbuf"
+)
+
+func testOSM() *osm.OSM {
+	return &osm.OSM{
+		Nodes: osm.Nodes{
+			{ID: 1, Lat: 1, Lon: 1, Version: 1, Tags: osm.Tags{{Key: "amenity", Value: "cafe"}}},
+			{ID: 2, Lat: 1, Lon: 2, Version: 1, Tags: osm.Tags{{Key: "amenity", Value: "bar"}}},
+			{ID: 3, Lat: 2, Lon: 2, Version: 1},
+			{ID: 4, Lat: 5, Lon: 5, Version: 1, Tags: osm.Tags{{Key: "shop", Value: "bakery"}}},
+		},
+		Ways: osm.Ways{
+			{ID: 10, Version: 1, Nodes: osm.WayNodes{{ID: 2}, {ID: 3}},
+				Tags: osm.Tags{{Key: "highway", Value: "primary"}}},
+			{ID: 11, Version: 1, Nodes: osm.WayNodes{{ID: 1}, {ID: 2}, {ID: 3}, {ID: 1}},
+				Tags: osm.Tags{{Key: "building", Value: "yes"}}},
+		},
+	}
+}
+
+func TestWriter(t *testing.T) {
+	buf := &bytes.Buffer{}
+	w, err := NewWriter(buf, Name("test"), IndexNodeSize(2))
+	if err != nil {
+		t.Fatalf("new error: %v", err)
+	}
+
+	if err := w.Write(testOSM()); err != nil {
+		t.Fatalf("write error: %v", err)
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatalf("close error: %v", err)
+	}
+
+	data := buf.Bytes()
+	if !bytes.Equal(data[:8], magicBytes) {
+		t.Fatalf("incorrect magic bytes: %v", data[:8])
+	}
+
+	size := int(binary.LittleEndian.Uint32(data[8:]))
+	header := table(data[12 : 12+size])
+
+	if v := header.str(0); v != "test" {
+		t.Errorf("incorrect name: %v", v)
+	}
+
+	count := int(header.uint64(8))
+	if count != 5 {
+		t.Errorf("incorrect feature count: %v", count)
+	}
+
+	if v := header.uint16(9); v != 2 {
+		t.Errorf("incorrect node size: %v", v)
+	}
+
+	if v := header.float64s(1); !reflect.DeepEqual(v, []float64{1, 1, 5, 5}) {
+		t.Errorf("incorrect envelope: %v", v)
+	}
+
+	if v := header.tables(7)[2].str(0); v != "tags" {
+		t.Errorf("incorrect columns: %v", v)
+	}
+
+	// 5 leaves, 3, 2 and a root
+	indexStart := 12 + size
+	numNodes := levelBounds(count, 2)[0][1]
+	if numNodes != 11 {
+		t.Errorf("incorrect number of nodes: %v", numNodes)
+	}
+
+	root := readNode(data[indexStart:])
+	if root.bound != (orb.Bound{Min: orb.Point{1, 1}, Max: orb.Point{5, 5}}) {
+		t.Errorf("incorrect root bound: %v", root.bound)
+	}
+
+	// check every leaf points to a feature with the same bound
+	featureStart := indexStart + nodeItemSize*numNodes
+	types := map[string]int{}
+	for i := numNodes - count; i < numNodes; i++ {
+		leaf := readNode(data[indexStart+i*nodeItemSize:])
+		pos := featureStart + int(leaf.offset)
+		size := int(binary.LittleEndian.Uint32(data[pos:]))
+		feature := table(data[pos+4 : pos+4+size])
+
+		geom := feature.table(0)
+		xy := geom.float64s(1)
+
+		var b orb.Bound
+		for j := 0; j < len(xy); j += 2 {
+			p := orb.Point{xy[j], xy[j+1]}
+			if j == 0 {
+				b = orb.Bound{Min: p, Max: p}
+			}
+			b = b.Extend(p)
+		}
+
+		if b != leaf.bound {
+			t.Errorf("incorrect leaf bound: %v != %v", b, leaf.bound)
+		}
+
+		props := feature.bytes(1)
+		l := binary.LittleEndian.Uint32(props[2:])
+		types[string(props[6:6+l])]++
+	}
+
+	if !reflect.DeepEqual(types, map[string]int{"node": 3, "way": 2}) {
+		t.Errorf("incorrect feature types: %v", types)
+	}
+}
+
+func TestWriter_noIndex(t *testing.T) {
+	buf := &bytes.Buffer{}
+	w, err := NewWriter(buf, IndexNodeSize(0), Keys("amenity"))
+	if err != nil {
+		t.Fatalf("new error: %v", err)
+	}
+
+	if err := w.Write(testOSM()); err != nil {
+		t.Fatalf("write error: %v", err)
+	}
+
+	// written before close
+	written := buf.Len()
+	if err := w.Close(); err != nil {
+		t.Fatalf("close error: %v", err)
+	}
+
+	if buf.Len() != written {
+		t.Errorf("should write features as they're added")
+	}
+
+	data := buf.Bytes()
+	size := int(binary.LittleEndian.Uint32(data[8:]))
+	header := table(data[12 : 12+size])
+
+	if v := header.uint64(8); v != 0 {
+		t.Errorf("count should be unknown: %v", v)
+	}
+
+	if v := header.tables(7)[2].str(0); v != "amenity" {
+		t.Errorf("incorrect columns: %v", v)
+	}
+
+	// first feature directly after the header
+	pos := 12 + size
+	size = int(binary.LittleEndian.Uint32(data[pos:]))
+	feature := table(data[pos+4 : pos+4+size])
+
+	if v := feature.table(0).uint8(6); v != geometryLineString {
+		t.Errorf("incorrect geometry type: %v", v)
+	}
+}
+
+func TestEncodeGeometry(t *testing.T) {
+	b := flatbuf.NewBuilder(0)
+	off, err := encodeGeometry(b, orb.MultiPolygon{
+		{{{0, 0}, {1, 0}, {1, 1}, {0, 0}}, {{0.1, 0.1}, {0.2, 0.1}, {0.2, 0.2}, {0.1, 0.1}}},
+		{{{2, 2}, {3, 2}, {3, 3}, {2, 2}}},
+	})
+	if err != nil {
+		t.Fatalf("encode error: %v", err)
+	}
+
+	geom := table(b.Finish(off))
+	if v := geom.uint8(6); v != geometryMultiPolygon {
+		t.Errorf("incorrect type: %v", v)
+	}
+
+	parts := geom.tables(7)
+	if len(parts) != 2 {
+		t.Fatalf("incorrect number of parts: %v", len(parts))
+	}
+
+	if v := parts[0].uint32s(0); !reflect.DeepEqual(v, []uint32{4, 8}) {
+		t.Errorf("incorrect ends: %v", v)
+	}
+
+	if v := parts[1].float64s(1); len(v) != 8 || v[0] != 2 {
+		t.Errorf("incorrect xy: %v", v)
+	}
+
+	if _, err := encodeGeometry(b, orb.Bound{}); err == nil {
+		t.Errorf("should error on unsupported type")
+	}
+}
+
+func TestHilbert(t *testing.T) {
+	if v := hilbert(0, 0); v != 0 {
+		t.Errorf("incorrect origin: %v", v)
+	}
+
+	// the curve visits every cell once
+	seen := map[uint32]bool{}
+	for x := uint32(0); x < 4; x++ {
+		for y := uint32(0); y < 4; y++ {
+			seen[hilbert(x<<14, y<<14)] = true
+		}
+	}
+
+	if len(seen) != 16 {
+		t.Errorf("values should be unique: %v", len(seen))
+	}
+}
+
+// fbTable is a minimal FlatBuffers table reader for testing.
+type fbTable struct {
+	buf []byte
+	pos int
+}
+
+func table(buf []byte) fbTable {
+	return fbTable{buf: buf, pos: int(binary.LittleEndian.Uint32(buf))}
+}
+
+func (t fbTable) field(i int) int {
+	vtable := t.pos - int(int32(binary.LittleEndian.Uint32(t.buf[t.pos:])))
+	if 4+2*i >= int(binary.LittleEndian.Uint16(t.buf[vtable:])) {
+		return 0
+	}
+
+	off := int(binary.LittleEndian.Uint16(t.buf[vtable+4+2*i:]))
+	if off == 0 {
+		return 0
+	}
+
+	return t.pos + off
+}
+
+func (t fbTable) indirect(i int) int {
+	pos := t.field(i)
+	if pos == 0 {
+		return 0
+	}
+
+	return pos + int(binary.LittleEndian.Uint32(t.buf[pos:]))
+}
+
+func (t fbTable) uint8(i int) uint8 {
+	if pos := t.field(i); pos != 0 {
+		return t.buf[pos]
+	}
+	return 0
+}
+
+func (t fbTable) uint16(i int) uint16 {
+	if pos := t.field(i); pos != 0 {
+		return binary.LittleEndian.Uint16(t.buf[pos:])
+	}
+	return 0
+}
+
+func (t fbTable) uint64(i int) uint64 {
+	if pos := t.field(i); pos != 0 {
+		return binary.LittleEndian.Uint64(t.buf[pos:])
+	}
+	return 0
+}
+
+func (t fbTable) bytes(i int) []byte {
+	pos := t.indirect(i)
+	if pos == 0 {
+		return nil
+	}
+
+	l := int(binary.LittleEndian.Uint32(t.buf[pos:]))
+	return t.buf[pos+4 : pos+4+l]
+}
+
+func (t fbTable) str(i int) string {
+	return string(t.bytes(i))
+}
+
+func (t fbTable) float64s(i int) []float64 {
+	pos := t.indirect(i)
+	if pos == 0 {
+		return nil
+	}
+
+	if (pos+4)%8 != 0 {
+		panic("doubles not aligned")
+	}
+
+	l := int(binary.LittleEndian.Uint32(t.buf[pos:]))
+	result := make([]float64, l)
+	for j := range result {
+		result[j] = math.Float64frombits(binary.LittleEndian.Uint64(t.buf[pos+4+8*j:]))
+	}
+
+	return result
+}
+
+func (t fbTable) uint32s(i int) []uint32 {
+	pos := t.indirect(i)
+	if pos == 0 {
+		return nil
+	}
+
+	l := int(binary.LittleEndian.Uint32(t.buf[pos:]))
+	result := make([]uint32, l)
+	for j := range result {
+		result[j] = binary.LittleEndian.Uint32(t.buf[pos+4+4*j:])
+	}
+
+	return result
+}
+
+func (t fbTable) table(i int) fbTable {
+	return fbTable{buf: t.buf, pos: t.indirect(i)}
+}
+
+func (t fbTable) tables(i int) []fbTable {
+	pos := t.indirect(i)
+	if pos == 0 {
+		return nil
+	}
+
+	l := int(binary.LittleEndian.Uint32(t.buf[pos:]))
+	result := make([]fbTable, l)
+	for j := range result {
+		p := pos + 4 + 4*j
+		result[j] = fbTable{buf: t.buf, pos: p + int(binary.LittleEndian.Uint32(t.buf[p:]))}
+	}
+
+	return result
+}
+
+func readNode(data []byte) node {
+	f := func(i int) float64 {
+		return math.Float64frombits(binary.LittleEndian.Uint64(data[i*8:]))
+	}
+
+	return node{
+		bound:  orb.Bound{Min: orb.Point{f(0), f(1)}, Max: orb.Point{f(2), f(3)}},
+		offset: binary.LittleEndian.Uint64(data[32:]),
+	}
+}
@@ -0,0 +1,193 @@
+// Package geoindex provides in memory spatial indexes over osm data
+// so "elements near here" lookups don't require a full scan or an
+// external database.
+package geoindex
+
+import (
+	"errors"
+	"math"
+	"sort"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geo"
+	"github.com/paulmach/osm"
+)
+
+type nodeEntry struct {
+	id    osm.NodeID
+	point orb.Point
+}
+
+// NodeIndex buckets node locations into geohash cells of a fixed
+// precision and supports bound and radius queries.
+type NodeIndex struct {
+	latBits, lonBits uint
+	cellWidth        float64
+	cellHeight       float64
+
+	buckets map[uint64][]nodeEntry
+	count   int
+}
+
+// NewNodeIndex creates an index using geohash cells of the given precision,
+// the number of characters in the geohash string, between 1 and 12.
+// A precision of 6, cells about 1.2km by 0.6km, works well for city
+// scale queries.
+func NewNodeIndex(precision int) (*NodeIndex, error) {
+	if precision < 1 || precision > 12 {
+		return nil, errors.New("geoindex: precision must be between 1 and 12")
+	}
+
+	bits := uint(5 * precision)
+	idx := &NodeIndex{
+		latBits: bits / 2,
+		lonBits: bits - bits/2,
+		buckets: make(map[uint64][]nodeEntry),
+	}
+
+	idx.cellWidth = 360 / float64(uint64(1)<<idx.lonBits)
+	idx.cellHeight = 180 / float64(uint64(1)<<idx.latBits)
+
+	return idx, nil
+}
+
+// Build adds all the nodes in the scanner to the index.
+// The scanner is not closed.
+func (idx *NodeIndex) Build(s osm.Scanner) error {
+	for s.Scan() {
+		if n, ok := s.Object().(*osm.Node); ok {
+			idx.Add(n)
+		}
+	}
+
+	return s.Err()
+}
+
+// Add adds the node location to the index. Nodes should only be added once.
+func (idx *NodeIndex) Add(n *osm.Node) {
+	p := n.Point()
+	x, y := idx.cellXY(p)
+	h := idx.hash(x, y)
+
+	idx.buckets[h] = append(idx.buckets[h], nodeEntry{id: n.ID, point: p})
+	idx.count++
+}
+
+// Len returns the number of nodes in the index.
+func (idx *NodeIndex) Len() int {
+	return idx.count
+}
+
+// Bound returns the ids of the nodes within the bound.
+// The result is sorted by id.
+func (idx *NodeIndex) Bound(b orb.Bound) []osm.NodeID {
+	var result []osm.NodeID
+	idx.search(b, func(e nodeEntry) {
+		result = append(result, e.id)
+	})
+
+	sort.Slice(result, func(i, j int) bool { return result[i] < result[j] })
+	return result
+}
+
+// Radius returns the ids of the nodes within the distance, in meters,
+// of the center point. The result is sorted by distance.
+func (idx *NodeIndex) Radius(center orb.Point, meters float64) []osm.NodeID {
+	type match struct {
+		id       osm.NodeID
+		distance float64
+	}
+
+	var matches []match
+	idx.search(geo.NewBoundAroundPoint(center, meters), func(e nodeEntry) {
+		if d := geo.Distance(center, e.point); d <= meters {
+			matches = append(matches, match{id: e.id, distance: d})
+		}
+	})
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].distance != matches[j].distance {
+			return matches[i].distance < matches[j].distance
+		}
+		return matches[i].id < matches[j].id
+	})
+
+	result := make([]osm.NodeID, len(matches))
+	for i, m := range matches {
+		result[i] = m.id
+	}
+
+	return result
+}
+
+// search calls f for every node in the bound. The cells covering the bound
+// are checked unless there are more cells than buckets in the index.
+func (idx *NodeIndex) search(b orb.Bound, f func(nodeEntry)) {
+	if idx.count == 0 {
+		return
+	}
+
+	minX, minY := idx.cellXY(b.Min)
+	maxX, maxY := idx.cellXY(b.Max)
+
+	check := func(entries []nodeEntry) {
+		for _, e := range entries {
+			if b.Contains(e.point) {
+				f(e)
+			}
+		}
+	}
+
+	cells := float64(maxX-minX+1) * float64(maxY-minY+1)
+	if cells > float64(len(idx.buckets)) {
+		for _, entries := range idx.buckets {
+			check(entries)
+		}
+		return
+	}
+
+	for x := minX; x <= maxX; x++ {
+		for y := minY; y <= maxY; y++ {
+			check(idx.buckets[idx.hash(x, y)])
+		}
+	}
+}
+
+// cellXY returns the column and row of the cell containing the point.
+func (idx *NodeIndex) cellXY(p orb.Point) (uint64, uint64) {
+	x := clamp(math.Floor((p[0]+180)/idx.cellWidth), 1<<idx.lonBits)
+	y := clamp(math.Floor((p[1]+90)/idx.cellHeight), 1<<idx.latBits)
+
+	return x, y
+}
+
+// hash interleaves the cell column and row bits, starting with the
+// longitude, to get the geohash value.
+func (idx *NodeIndex) hash(x, y uint64) uint64 {
+	var h uint64
+	lon, lat := idx.lonBits, idx.latBits
+	for i := uint(0); i < idx.lonBits+idx.latBits; i++ {
+		h <<= 1
+		if i%2 == 0 {
+			lon--
+			h |= (x >> lon) & 1
+		} else {
+			lat--
+			h |= (y >> lat) & 1
+		}
+	}
+
+	return h
+}
+
+func clamp(v float64, n uint64) uint64 {
+	if v < 0 {
+		return 0
+	}
+
+	if v >= float64(n) {
+		return n - 1
+	}
+
+	return uint64(v)
+}
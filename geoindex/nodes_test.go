@@ -0,0 +1,133 @@
+package geoindex
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/osm"
+	"github.com/paulmach/osm/osmtest"
+)
+
+func testNodeIndex(t *testing.T) *NodeIndex {
+	idx, err := NewNodeIndex(6)
+	if err != nil {
+		t.Fatalf("new error: %v", err)
+	}
+
+	err = idx.Build(osmtest.NewScanner(osm.Objects{
+		&osm.Node{ID: 1, Lat: 40.0, Lon: -75.0},
+		&osm.Node{ID: 2, Lat: 40.001, Lon: -75.0},
+		&osm.Node{ID: 3, Lat: 40.01, Lon: -75.0},
+		&osm.Node{ID: 4, Lat: -40.0, Lon: 75.0},
+		&osm.Way{ID: 10},
+	}))
+	if err != nil {
+		t.Fatalf("build error: %v", err)
+	}
+
+	return idx
+}
+
+func TestNodeIndex_Bound(t *testing.T) {
+	idx := testNodeIndex(t)
+	if v := idx.Len(); v != 4 {
+		t.Errorf("incorrect length: %v", v)
+	}
+
+	cases := []struct {
+		name     string
+		bound    orb.Bound
+		expected []osm.NodeID
+	}{
+		{
+			name:     "small",
+			bound:    orb.Bound{Min: orb.Point{-75.1, 39.9}, Max: orb.Point{-74.9, 40.005}},
+			expected: []osm.NodeID{1, 2},
+		},
+		{
+			name:     "exact point",
+			bound:    orb.Bound{Min: orb.Point{75, -40}, Max: orb.Point{75, -40}},
+			expected: []osm.NodeID{4},
+		},
+		{
+			name:     "world",
+			bound:    orb.Bound{Min: orb.Point{-180, -90}, Max: orb.Point{180, 90}},
+			expected: []osm.NodeID{1, 2, 3, 4},
+		},
+		{
+			name:  "empty",
+			bound: orb.Bound{Min: orb.Point{0, 0}, Max: orb.Point{1, 1}},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			v := idx.Bound(tc.bound)
+			if !reflect.DeepEqual(v, tc.expected) {
+				t.Errorf("incorrect nodes: %v != %v", v, tc.expected)
+			}
+		})
+	}
+}
+
+func TestNodeIndex_Radius(t *testing.T) {
+	idx := testNodeIndex(t)
+
+	// about 111m between node 1 and 2, 1.1km to node 3
+	v := idx.Radius(orb.Point{-75.0, 40.0009}, 200)
+	if !reflect.DeepEqual(v, []osm.NodeID{2, 1}) {
+		t.Errorf("incorrect nodes: %v", v)
+	}
+
+	v = idx.Radius(orb.Point{-75.0, 40.0}, 2000)
+	if !reflect.DeepEqual(v, []osm.NodeID{1, 2, 3}) {
+		t.Errorf("incorrect nodes: %v", v)
+	}
+
+	v = idx.Radius(orb.Point{0, 0}, 2000)
+	if len(v) != 0 {
+		t.Errorf("should not find nodes: %v", v)
+	}
+}
+
+func TestNodeIndex_hash(t *testing.T) {
+	idx, _ := NewNodeIndex(5)
+
+	// https://en.wikipedia.org/wiki/Geohash example
+	x, y := idx.cellXY(orb.Point{-5.6, 42.6})
+	if v := geohash(idx.hash(x, y), 5); v != "ezs42" {
+		t.Errorf("incorrect geohash: %v", v)
+	}
+}
+
+func TestNewNodeIndex(t *testing.T) {
+	if _, err := NewNodeIndex(0); err == nil {
+		t.Errorf("should error on invalid precision")
+	}
+
+	if _, err := NewNodeIndex(13); err == nil {
+		t.Errorf("should error on invalid precision")
+	}
+
+	idx, _ := NewNodeIndex(6)
+	s := osmtest.NewScanner(nil)
+	s.ScanError = errors.New("some error")
+
+	if err := idx.Build(s); err == nil {
+		t.Errorf("should return scanner error")
+	}
+}
+
+func geohash(h uint64, precision int) string {
+	const base32 = "0123456789bcdefghjkmnpqrstuvwxyz"
+
+	result := make([]byte, precision)
+	for i := precision - 1; i >= 0; i-- {
+		result[i] = base32[h&0x1f]
+		h >>= 5
+	}
+
+	return string(result)
+}
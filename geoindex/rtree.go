@@ -0,0 +1,175 @@
+package geoindex
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"sync"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/osm"
+)
+
+const rtreeNodeSize = 16
+
+type rtreeNode struct {
+	bound    orb.Bound
+	children []*rtreeNode
+	item     int // index of the item for leaf entries, -1 otherwise
+}
+
+type rtreeItem struct {
+	id    osm.FeatureID
+	bound orb.Bound
+}
+
+// RTree indexes the bounds of ways, relations or any other features for
+// bound intersection queries. It is bulk loaded using the Sort-Tile-Recursive
+// algorithm, the tree is built on the first search after any inserts.
+// Searches are safe to call concurrently, inserts are not.
+type RTree struct {
+	items []rtreeItem
+
+	lock  sync.RWMutex
+	root  *rtreeNode
+	dirty bool
+}
+
+// NewRTree creates an empty tree.
+func NewRTree() *RTree {
+	return &RTree{}
+}
+
+// Insert adds the feature with the given bound to the tree. For relations
+// this can be the bound of the assembled polygon, eg. from the osmgeojson
+// package.
+func (t *RTree) Insert(id osm.FeatureID, b orb.Bound) {
+	t.items = append(t.items, rtreeItem{id: id, bound: b})
+	t.dirty = true
+}
+
+// InsertWay adds the way using the bound of its nodes.
+// The way nodes must be annotated with their locations.
+func (t *RTree) InsertWay(w *osm.Way) error {
+	ls := w.LineString()
+	if len(ls) == 0 {
+		return errors.New("geoindex: way nodes are not annotated")
+	}
+
+	t.Insert(w.FeatureID(), ls.Bound())
+	return nil
+}
+
+// Len returns the number of items in the tree.
+func (t *RTree) Len() int {
+	return len(t.items)
+}
+
+// Search returns the ids of the features whose bound intersects the
+// given bound. The result is sorted.
+func (t *RTree) Search(b orb.Bound) osm.FeatureIDs {
+	t.lock.RLock()
+	if t.dirty {
+		t.lock.RUnlock()
+		t.build()
+		t.lock.RLock()
+	}
+	defer t.lock.RUnlock()
+
+	if t.root == nil {
+		return nil
+	}
+
+	var result osm.FeatureIDs
+	var search func(n *rtreeNode)
+	search = func(n *rtreeNode) {
+		for _, c := range n.children {
+			if !c.bound.Intersects(b) {
+				continue
+			}
+
+			if c.item >= 0 {
+				result = append(result, t.items[c.item].id)
+			} else {
+				search(c)
+			}
+		}
+	}
+	search(t.root)
+
+	result.Sort()
+	return result
+}
+
+func (t *RTree) build() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if !t.dirty {
+		return
+	}
+	t.dirty = false
+
+	level := make([]*rtreeNode, len(t.items))
+	for i, it := range t.items {
+		level[i] = &rtreeNode{bound: it.bound, item: i}
+	}
+
+	// always have a root internal node, even with few items.
+	for {
+		level = packLevel(level)
+		if len(level) == 1 {
+			break
+		}
+	}
+
+	t.root = level[0]
+	if len(t.items) == 0 {
+		t.root = nil
+	}
+}
+
+// packLevel groups the nodes into parents using sort-tile-recursive,
+// nodes are sorted into vertical slices by x and then grouped by y.
+func packLevel(nodes []*rtreeNode) []*rtreeNode {
+	count := int(math.Ceil(float64(len(nodes)) / rtreeNodeSize))
+	slices := int(math.Ceil(math.Sqrt(float64(count))))
+	sliceSize := slices * rtreeNodeSize
+
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].bound.Center()[0] < nodes[j].bound.Center()[0]
+	})
+
+	var parents []*rtreeNode
+	for s := 0; s < len(nodes); s += sliceSize {
+		slice := nodes[s:min(s+sliceSize, len(nodes))]
+		sort.Slice(slice, func(i, j int) bool {
+			return slice[i].bound.Center()[1] < slice[j].bound.Center()[1]
+		})
+
+		for i := 0; i < len(slice); i += rtreeNodeSize {
+			children := slice[i:min(i+rtreeNodeSize, len(slice))]
+
+			parent := &rtreeNode{bound: children[0].bound, children: children, item: -1}
+			for _, c := range children[1:] {
+				parent.bound = parent.bound.Union(c.bound)
+			}
+
+			parents = append(parents, parent)
+		}
+	}
+
+	if len(parents) == 0 {
+		parents = append(parents, &rtreeNode{item: -1})
+	}
+
+	return parents
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+
+	return b
+}
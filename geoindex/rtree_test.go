@@ -0,0 +1,103 @@
+package geoindex
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/osm"
+)
+
+func TestRTree(t *testing.T) {
+	tree := NewRTree()
+	if v := tree.Search(orb.Bound{Max: orb.Point{1, 1}}); len(v) != 0 {
+		t.Errorf("empty tree should not find anything: %v", v)
+	}
+
+	err := tree.InsertWay(&osm.Way{ID: 1, Nodes: osm.WayNodes{
+		{ID: 1, Lat: 1, Lon: 1},
+		{ID: 2, Lat: 2, Lon: 3},
+	}})
+	if err != nil {
+		t.Fatalf("insert error: %v", err)
+	}
+
+	if err := tree.InsertWay(&osm.Way{ID: 2, Nodes: osm.WayNodes{{ID: 1}}}); err == nil {
+		t.Errorf("should error for not annotated way")
+	}
+
+	tree.Insert(osm.RelationID(5).FeatureID(), orb.Bound{Min: orb.Point{2, 0}, Max: orb.Point{4, 4}})
+
+	cases := []struct {
+		name     string
+		bound    orb.Bound
+		expected osm.FeatureIDs
+	}{
+		{
+			name:     "both",
+			bound:    orb.Bound{Min: orb.Point{2.5, 1.5}, Max: orb.Point{2.5, 1.5}},
+			expected: osm.FeatureIDs{osm.WayID(1).FeatureID(), osm.RelationID(5).FeatureID()},
+		},
+		{
+			name:     "way",
+			bound:    orb.Bound{Min: orb.Point{0, 0}, Max: orb.Point{1, 1}},
+			expected: osm.FeatureIDs{osm.WayID(1).FeatureID()},
+		},
+		{
+			name:  "none",
+			bound: orb.Bound{Min: orb.Point{5, 5}, Max: orb.Point{6, 6}},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			v := tree.Search(tc.bound)
+			if !reflect.DeepEqual(v, tc.expected) {
+				t.Errorf("incorrect result: %v != %v", v, tc.expected)
+			}
+		})
+	}
+
+	// insert after search rebuilds the tree
+	tree.Insert(osm.WayID(3).FeatureID(), orb.Bound{Min: orb.Point{5, 5}, Max: orb.Point{5, 5}})
+	if v := tree.Search(orb.Bound{Min: orb.Point{5, 5}, Max: orb.Point{6, 6}}); len(v) != 1 {
+		t.Errorf("should find new item: %v", v)
+	}
+}
+
+func TestRTree_bruteForce(t *testing.T) {
+	r := rand.New(rand.NewSource(42))
+	randomBound := func(size float64) orb.Bound {
+		p := orb.Point{r.Float64()*360 - 180, r.Float64()*180 - 90}
+		return orb.Bound{Min: p, Max: orb.Point{p[0] + r.Float64()*size, p[1] + r.Float64()*size}}
+	}
+
+	tree := NewRTree()
+	var bounds []orb.Bound
+	for i := 0; i < 5000; i++ {
+		b := randomBound(2)
+		bounds = append(bounds, b)
+		tree.Insert(osm.WayID(i).FeatureID(), b)
+	}
+
+	if v := tree.Len(); v != 5000 {
+		t.Errorf("incorrect length: %v", v)
+	}
+
+	for i := 0; i < 100; i++ {
+		query := randomBound(20)
+
+		var expected osm.FeatureIDs
+		for j, b := range bounds {
+			if b.Intersects(query) {
+				expected = append(expected, osm.WayID(j).FeatureID())
+			}
+		}
+		expected.Sort()
+
+		if v := tree.Search(query); !reflect.DeepEqual(v, expected) {
+			t.Fatalf("incorrect result for %v: %d != %d", query, len(v), len(expected))
+		}
+	}
+}
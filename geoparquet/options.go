@@ -0,0 +1,38 @@
+package geoparquet
+
+import "errors"
+
+// An Option is a setting for writing the parquet file.
+type Option func(*Writer) error
+
+// RowGroupSize sets the number of rows buffered before writing a row group.
+// The default is 100,000.
+func RowGroupSize(n int) Option {
+	return func(w *Writer) error {
+		if n <= 0 {
+			return errors.New("geoparquet: row group size must be positive")
+		}
+
+		w.rowGroupSize = n
+		return nil
+	}
+}
+
+// Gzip will compress the data pages using gzip.
+func Gzip(yes bool) Option {
+	return func(w *Writer) error {
+		w.gzip = yes
+		return nil
+	}
+}
+
+// WayGeometry will build a linestring, or polygon for closed area ways, geometry
+// for every way. If the way nodes are not annotated with their locations the
+// node locations are kept in memory as they are written, so nodes must come
+// before the ways. Without this option only nodes have a geometry.
+func WayGeometry(yes bool) Option {
+	return func(w *Writer) error {
+		w.wayGeometry = yes
+		return nil
+	}
+}
@@ -0,0 +1,112 @@
+package geoparquet
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// thrift compact protocol types
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// thriftWriter is a minimal encoder for the thrift compact protocol
+// used by the parquet file and page metadata.
+type thriftWriter struct {
+	buf  bytes.Buffer
+	last []int16
+}
+
+func newThriftWriter() *thriftWriter {
+	return &thriftWriter{last: []int16{0}}
+}
+
+func (t *thriftWriter) varint(v uint64) {
+	var b [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(b[:], v)
+	t.buf.Write(b[:n])
+}
+
+func (t *thriftWriter) zigzag(v int64) {
+	t.varint(uint64((v << 1) ^ (v >> 63)))
+}
+
+func (t *thriftWriter) field(id int16, typ byte) {
+	last := &t.last[len(t.last)-1]
+	if delta := id - *last; delta > 0 && delta <= 15 {
+		t.buf.WriteByte(byte(delta)<<4 | typ)
+	} else {
+		t.buf.WriteByte(typ)
+		t.zigzag(int64(id))
+	}
+
+	*last = id
+}
+
+func (t *thriftWriter) i32(id int16, v int32) {
+	t.field(id, thriftI32)
+	t.zigzag(int64(v))
+}
+
+func (t *thriftWriter) i64(id int16, v int64) {
+	t.field(id, thriftI64)
+	t.zigzag(v)
+}
+
+func (t *thriftWriter) str(id int16, s string) {
+	t.field(id, thriftBinary)
+	t.varint(uint64(len(s)))
+	t.buf.WriteString(s)
+}
+
+// list writes the list header, the elements must follow.
+func (t *thriftWriter) list(id int16, elemType byte, size int) {
+	t.field(id, thriftList)
+	if size < 15 {
+		t.buf.WriteByte(byte(size)<<4 | elemType)
+	} else {
+		t.buf.WriteByte(0xf0 | elemType)
+		t.varint(uint64(size))
+	}
+}
+
+// structField starts a struct valued field, it must be closed with end.
+func (t *thriftWriter) structField(id int16) {
+	t.field(id, thriftStruct)
+	t.begin()
+}
+
+// begin starts a struct without a field header, eg. a list element.
+func (t *thriftWriter) begin() {
+	t.last = append(t.last, 0)
+}
+
+func (t *thriftWriter) end() {
+	t.buf.WriteByte(0)
+	t.last = t.last[:len(t.last)-1]
+}
+
+func (t *thriftWriter) listStrings(id int16, vals []string) {
+	t.list(id, thriftBinary, len(vals))
+	for _, v := range vals {
+		t.varint(uint64(len(v)))
+		t.buf.WriteString(v)
+	}
+}
+
+func (t *thriftWriter) listI32(id int16, vals []int32) {
+	t.list(id, thriftI32, len(vals))
+	for _, v := range vals {
+		t.zigzag(int64(v))
+	}
+}
+
+// bytes returns the encoded top level struct including the stop byte.
+func (t *thriftWriter) bytes() []byte {
+	t.buf.WriteByte(0)
+	return t.buf.Bytes()
+}
@@ -0,0 +1,535 @@
+// Package geoparquet writes osm elements and their tags to GeoParquet files
+// for analytics in tools like DuckDB and Spark.
+package geoparquet
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/bits"
+	"time"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/encoding/wkb"
+	"github.com/paulmach/osm"
+)
+
+var magic = []byte("PAR1")
+
+// parquet physical types
+const (
+	typeInt32     = 1
+	typeInt64     = 2
+	typeByteArray = 6
+)
+
+// parquet converted types
+const (
+	convertedUTF8            = 0
+	convertedMap             = 1
+	convertedMapKeyValue     = 2
+	convertedTimestampMillis = 9
+)
+
+// parquet repetition types
+const (
+	required = 0
+	optional = 1
+	repeated = 2
+)
+
+// parquet encodings and codecs
+const (
+	encodingPlain = 0
+	encodingRLE   = 3
+
+	codecUncompressed = 0
+	codecGzip         = 2
+)
+
+type schemaElement struct {
+	name       string
+	typ        int32 // -1 for groups
+	repetition int32
+	children   int32
+	converted  int32 // -1 if not set
+	maxDef     int
+	maxRep     int
+}
+
+// schema is the flattened, depth first, parquet schema. Tags are a map column.
+var schema = []schemaElement{
+	{name: "schema", typ: -1, repetition: -1, children: 6, converted: -1},
+	{name: "id", typ: typeInt64, repetition: required, converted: -1},
+	{name: "type", typ: typeByteArray, repetition: required, converted: convertedUTF8},
+	{name: "version", typ: typeInt32, repetition: required, converted: -1},
+	{name: "timestamp", typ: typeInt64, repetition: required, converted: convertedTimestampMillis},
+	{name: "tags", typ: -1, repetition: required, children: 1, converted: convertedMap},
+	{name: "key_value", typ: -1, repetition: repeated, children: 2, converted: convertedMapKeyValue},
+	{name: "key", typ: typeByteArray, repetition: required, converted: convertedUTF8, maxDef: 1, maxRep: 1},
+	{name: "value", typ: typeByteArray, repetition: required, converted: convertedUTF8, maxDef: 1, maxRep: 1},
+	{name: "geometry", typ: typeByteArray, repetition: optional, converted: -1, maxDef: 1},
+}
+
+// the leaf columns and their path in the schema
+var columnPaths = [][]string{
+	{"id"},
+	{"type"},
+	{"version"},
+	{"timestamp"},
+	{"tags", "key_value", "key"},
+	{"tags", "key_value", "value"},
+	{"geometry"},
+}
+
+var columnSchema = []int{1, 2, 3, 4, 7, 8, 9}
+
+type column struct {
+	values bytes.Buffer
+	defs   []int
+	reps   []int
+	count  int
+}
+
+type chunkMeta struct {
+	offset           int64
+	uncompressedSize int64
+	compressedSize   int64
+	numValues        int64
+}
+
+type rowGroup struct {
+	columns   []chunkMeta
+	totalSize int64
+	numRows   int64
+}
+
+// Writer writes osm elements as rows to a parquet file with GeoParquet
+// metadata. Rows are buffered and written in row groups, Close must be
+// called to write the final row group and the file footer.
+type Writer struct {
+	w      io.Writer
+	offset int64
+
+	rowGroupSize int
+	gzip         bool
+	wayGeometry  bool
+
+	locations map[osm.NodeID]orb.Point
+	columns   []*column
+	rows      int
+
+	rowGroups []rowGroup
+	numRows   int64
+	bound     orb.Bound
+	hasBound  bool
+	geomTypes map[string]bool
+}
+
+// NewWriter creates a writer and writes the file header to w.
+func NewWriter(w io.Writer, opts ...Option) (*Writer, error) {
+	pw := &Writer{
+		w:            w,
+		rowGroupSize: 100000,
+		geomTypes:    make(map[string]bool),
+	}
+
+	for _, opt := range opts {
+		if err := opt(pw); err != nil {
+			return nil, err
+		}
+	}
+
+	if pw.wayGeometry {
+		pw.locations = make(map[osm.NodeID]orb.Point)
+	}
+
+	pw.resetColumns()
+	if err := pw.write(magic); err != nil {
+		return nil, err
+	}
+
+	return pw, nil
+}
+
+func (w *Writer) resetColumns() {
+	w.columns = make([]*column, len(columnPaths))
+	for i := range w.columns {
+		w.columns[i] = &column{}
+	}
+	w.rows = 0
+}
+
+func (w *Writer) write(data []byte) error {
+	n, err := w.w.Write(data)
+	w.offset += int64(n)
+	return err
+}
+
+// Write adds the node, way or relation as a row. Other types are ignored.
+func (w *Writer) Write(o osm.Object) error {
+	var (
+		id        int64
+		typ       osm.Type
+		version   int
+		timestamp int64
+		tags      osm.Tags
+		geom      orb.Geometry
+	)
+
+	switch o := o.(type) {
+	case *osm.Node:
+		id, typ, version, tags = int64(o.ID), osm.TypeNode, o.Version, o.Tags
+		timestamp = millis(o.Timestamp)
+		geom = o.Point()
+
+		if w.locations != nil {
+			w.locations[o.ID] = o.Point()
+		}
+	case *osm.Way:
+		id, typ, version, tags = int64(o.ID), osm.TypeWay, o.Version, o.Tags
+		timestamp = millis(o.Timestamp)
+		if w.wayGeometry {
+			geom = w.wayGeom(o)
+		}
+	case *osm.Relation:
+		id, typ, version, tags = int64(o.ID), osm.TypeRelation, o.Version, o.Tags
+		timestamp = millis(o.Timestamp)
+	default:
+		return nil
+	}
+
+	c := w.columns
+	writeInt64(c[0], id)
+	writeBytes(c[1], []byte(typ))
+	writeInt32(c[2], int32(version))
+	writeInt64(c[3], timestamp)
+
+	if len(tags) == 0 {
+		c[4].defs, c[4].reps = append(c[4].defs, 0), append(c[4].reps, 0)
+		c[5].defs, c[5].reps = append(c[5].defs, 0), append(c[5].reps, 0)
+		c[4].count++
+		c[5].count++
+	}
+
+	for i, t := range tags {
+		rep := 1
+		if i == 0 {
+			rep = 0
+		}
+
+		for j, v := range []string{t.Key, t.Value} {
+			col := c[4+j]
+			col.defs = append(col.defs, 1)
+			col.reps = append(col.reps, rep)
+			writeBytes(col, []byte(v))
+		}
+	}
+
+	if geom == nil {
+		c[6].defs = append(c[6].defs, 0)
+		c[6].count++
+	} else {
+		data, err := wkb.Marshal(geom)
+		if err != nil {
+			return err
+		}
+
+		c[6].defs = append(c[6].defs, 1)
+		writeBytes(c[6], data)
+		w.addGeometry(geom)
+	}
+
+	w.rows++
+	if w.rows >= w.rowGroupSize {
+		return w.flush()
+	}
+
+	return nil
+}
+
+func (w *Writer) wayGeom(way *osm.Way) orb.Geometry {
+	ls := make(orb.LineString, 0, len(way.Nodes))
+	for _, wn := range way.Nodes {
+		if wn.Lat != 0 || wn.Lon != 0 {
+			ls = append(ls, wn.Point())
+		} else if p, ok := w.locations[wn.ID]; ok {
+			ls = append(ls, p)
+		}
+	}
+
+	if len(ls) < 2 {
+		return nil
+	}
+
+	if way.Polygon() && len(ls) > 3 && ls[0] == ls[len(ls)-1] {
+		return orb.Polygon{orb.Ring(ls)}
+	}
+
+	return ls
+}
+
+func (w *Writer) addGeometry(g orb.Geometry) {
+	w.geomTypes[g.GeoJSONType()] = true
+
+	if !w.hasBound {
+		w.bound = g.Bound()
+		w.hasBound = true
+	} else {
+		w.bound = w.bound.Union(g.Bound())
+	}
+}
+
+// flush writes the buffered rows as a row group.
+func (w *Writer) flush() error {
+	if w.rows == 0 {
+		return nil
+	}
+
+	rg := rowGroup{numRows: int64(w.rows)}
+	for i, c := range w.columns {
+		meta, err := w.writeColumn(c, schema[columnSchema[i]])
+		if err != nil {
+			return err
+		}
+
+		rg.columns = append(rg.columns, meta)
+		rg.totalSize += meta.uncompressedSize
+	}
+
+	w.rowGroups = append(w.rowGroups, rg)
+	w.numRows += int64(w.rows)
+	w.resetColumns()
+
+	return nil
+}
+
+// writeColumn writes the column chunk as a single data page.
+func (w *Writer) writeColumn(c *column, s schemaElement) (chunkMeta, error) {
+	page := &bytes.Buffer{}
+	if s.maxRep > 0 {
+		writeLevels(page, c.reps, s.maxRep)
+	}
+
+	if s.maxDef > 0 {
+		writeLevels(page, c.defs, s.maxDef)
+	}
+	page.Write(c.values.Bytes())
+
+	data := page.Bytes()
+	if w.gzip {
+		buf := &bytes.Buffer{}
+		zw := gzip.NewWriter(buf)
+		if _, err := zw.Write(data); err != nil {
+			return chunkMeta{}, err
+		}
+
+		if err := zw.Close(); err != nil {
+			return chunkMeta{}, err
+		}
+		data = buf.Bytes()
+	}
+
+	t := newThriftWriter()
+	t.i32(1, 0) // DATA_PAGE
+	t.i32(2, int32(page.Len()))
+	t.i32(3, int32(len(data)))
+	t.structField(5)
+	t.i32(1, int32(c.count))
+	t.i32(2, encodingPlain)
+	t.i32(3, encodingRLE)
+	t.i32(4, encodingRLE)
+	t.end()
+	header := t.bytes()
+
+	meta := chunkMeta{
+		offset:           w.offset,
+		uncompressedSize: int64(len(header) + page.Len()),
+		compressedSize:   int64(len(header) + len(data)),
+		numValues:        int64(c.count),
+	}
+
+	if err := w.write(header); err != nil {
+		return meta, err
+	}
+
+	return meta, w.write(data)
+}
+
+// Close writes any buffered rows and the file footer.
+// It does not close the underlying writer.
+func (w *Writer) Close() error {
+	if err := w.flush(); err != nil {
+		return err
+	}
+
+	geo, err := w.geoMetadata()
+	if err != nil {
+		return err
+	}
+
+	footer := w.fileMetadata(geo)
+	if err := w.write(footer); err != nil {
+		return err
+	}
+
+	size := make([]byte, 4)
+	binary.LittleEndian.PutUint32(size, uint32(len(footer)))
+	if err := w.write(size); err != nil {
+		return err
+	}
+
+	return w.write(magic)
+}
+
+func (w *Writer) fileMetadata(geo string) []byte {
+	codec := int32(codecUncompressed)
+	if w.gzip {
+		codec = codecGzip
+	}
+
+	t := newThriftWriter()
+	t.i32(1, 1)
+
+	t.list(2, thriftStruct, len(schema))
+	for _, s := range schema {
+		t.begin()
+		if s.typ >= 0 {
+			t.i32(1, s.typ)
+		}
+
+		if s.repetition >= 0 {
+			t.i32(3, s.repetition)
+		}
+		t.str(4, s.name)
+
+		if s.children > 0 {
+			t.i32(5, s.children)
+		}
+
+		if s.converted >= 0 {
+			t.i32(6, s.converted)
+		}
+		t.end()
+	}
+
+	t.i64(3, w.numRows)
+
+	t.list(4, thriftStruct, len(w.rowGroups))
+	for _, rg := range w.rowGroups {
+		t.begin()
+		t.list(1, thriftStruct, len(rg.columns))
+		for i, c := range rg.columns {
+			t.begin()
+			t.i64(2, c.offset)
+			t.structField(3)
+			t.i32(1, schema[columnSchema[i]].typ)
+			t.listI32(2, []int32{encodingPlain, encodingRLE})
+			t.listStrings(3, columnPaths[i])
+			t.i32(4, codec)
+			t.i64(5, c.numValues)
+			t.i64(6, c.uncompressedSize)
+			t.i64(7, c.compressedSize)
+			t.i64(9, c.offset)
+			t.end()
+			t.end()
+		}
+		t.i64(2, rg.totalSize)
+		t.i64(3, rg.numRows)
+		t.end()
+	}
+
+	t.list(5, thriftStruct, 1)
+	t.begin()
+	t.str(1, "geo")
+	t.str(2, geo)
+	t.end()
+
+	t.str(6, "github.com/paulmach/osm")
+
+	return t.bytes()
+}
+
+// geoMetadata returns the GeoParquet metadata json stored in the `geo` key.
+func (w *Writer) geoMetadata() (string, error) {
+	types := []string{}
+	for _, t := range []string{"Point", "LineString", "Polygon"} {
+		if w.geomTypes[t] {
+			types = append(types, t)
+		}
+	}
+
+	column := map[string]interface{}{
+		"encoding":       "WKB",
+		"geometry_types": types,
+	}
+
+	if w.hasBound {
+		b := w.bound
+		column["bbox"] = []float64{b.Min[0], b.Min[1], b.Max[0], b.Max[1]}
+	}
+
+	data, err := json.Marshal(map[string]interface{}{
+		"version":        "1.0.0",
+		"primary_column": "geometry",
+		"columns":        map[string]interface{}{"geometry": column},
+	})
+	if err != nil {
+		return "", fmt.Errorf("geoparquet: %v", err)
+	}
+
+	return string(data), nil
+}
+
+// writeLevels writes the levels using the RLE/bit-packing hybrid encoding,
+// prefixed with the length. Only RLE runs are used.
+func writeLevels(buf *bytes.Buffer, levels []int, max int) {
+	width := (bits.Len(uint(max)) + 7) / 8
+
+	data := &bytes.Buffer{}
+	var tmp [binary.MaxVarintLen64]byte
+	for i := 0; i < len(levels); {
+		j := i
+		for j < len(levels) && levels[j] == levels[i] {
+			j++
+		}
+
+		n := binary.PutUvarint(tmp[:], uint64(j-i)<<1)
+		data.Write(tmp[:n])
+		for k := 0; k < width; k++ {
+			data.WriteByte(byte(levels[i] >> uint(8*k)))
+		}
+
+		i = j
+	}
+
+	binary.Write(buf, binary.LittleEndian, uint32(data.Len()))
+	buf.Write(data.Bytes())
+}
+
+func writeInt32(c *column, v int32) {
+	binary.Write(&c.values, binary.LittleEndian, v)
+	c.count++
+}
+
+func writeInt64(c *column, v int64) {
+	binary.Write(&c.values, binary.LittleEndian, v)
+	c.count++
+}
+
+func writeBytes(c *column, v []byte) {
+	binary.Write(&c.values, binary.LittleEndian, uint32(len(v)))
+	c.values.Write(v)
+	c.count++
+}
+
+func millis(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+
+	return t.UnixNano() / int64(time.Millisecond)
+}
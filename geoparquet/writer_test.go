@@ -0,0 +1,356 @@
+package geoparquet
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/json"
+	"io/ioutil"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/encoding/wkb"
+	"github.com/paulmach/osm"
+)
+
+func testObjects() osm.Objects {
+	ts := time.Date(2018, 1, 2, 3, 4, 5, 0, time.UTC)
+	return osm.Objects{
+		&osm.Node{ID: 1, Lat: 1, Lon: 2, Version: 1, Timestamp: ts,
+			Tags: osm.Tags{{Key: "amenity", Value: "cafe"}, {Key: "name", Value: "Joe's"}}},
+		&osm.Node{ID: 2, Lat: 1, Lon: 3, Version: 2, Timestamp: ts},
+		&osm.Way{ID: 5, Version: 3, Timestamp: ts, Nodes: osm.WayNodes{{ID: 1}, {ID: 2}},
+			Tags: osm.Tags{{Key: "highway", Value: "primary"}}},
+		&osm.Relation{ID: 6, Version: 4, Timestamp: ts},
+	}
+}
+
+func TestWriter(t *testing.T) {
+	for _, gz := range []bool{false, true} {
+		buf := &bytes.Buffer{}
+		w, err := NewWriter(buf, RowGroupSize(3), WayGeometry(true), Gzip(gz))
+		if err != nil {
+			t.Fatalf("new error: %v", err)
+		}
+
+		for _, o := range testObjects() {
+			if err := w.Write(o); err != nil {
+				t.Fatalf("write error: %v", err)
+			}
+		}
+
+		if err := w.Close(); err != nil {
+			t.Fatalf("close error: %v", err)
+		}
+
+		f := readFile(t, buf.Bytes())
+		if f.numRows != 4 || len(f.rowGroups) != 2 {
+			t.Fatalf("incorrect rows: %d %d", f.numRows, len(f.rowGroups))
+		}
+
+		ids := append(f.column(t, 0, 0), f.column(t, 1, 0)...)
+		if !reflect.DeepEqual(ids, []interface{}{int64(1), int64(2), int64(5), int64(6)}) {
+			t.Errorf("incorrect ids: %v", ids)
+		}
+
+		types := f.column(t, 0, 1)
+		if !reflect.DeepEqual(types, []interface{}{"node", "node", "way"}) {
+			t.Errorf("incorrect types: %v", types)
+		}
+
+		versions := f.column(t, 1, 2)
+		if !reflect.DeepEqual(versions, []interface{}{int32(4)}) {
+			t.Errorf("incorrect versions: %v", versions)
+		}
+
+		timestamps := f.column(t, 0, 3)
+		if timestamps[0] != int64(1514862245000) {
+			t.Errorf("incorrect timestamp: %v", timestamps[0])
+		}
+
+		// tags are a map column, nil is an empty map
+		keys := f.column(t, 0, 4)
+		if !reflect.DeepEqual(keys, []interface{}{[]string{"amenity", "name"}, nil, []string{"highway"}}) {
+			t.Errorf("incorrect keys: %v", keys)
+		}
+
+		values := f.column(t, 0, 5)
+		if !reflect.DeepEqual(values, []interface{}{[]string{"cafe", "Joe's"}, nil, []string{"primary"}}) {
+			t.Errorf("incorrect values: %v", values)
+		}
+
+		geoms := f.column(t, 0, 6)
+		way, err := wkb.Unmarshal([]byte(geoms[2].(string)))
+		if err != nil {
+			t.Fatalf("wkb error: %v", err)
+		}
+
+		if !orb.Equal(way, orb.LineString{{2, 1}, {3, 1}}) {
+			t.Errorf("incorrect way geometry: %v", way)
+		}
+
+		if v := f.column(t, 1, 6); v[0] != nil {
+			t.Errorf("relation should not have geometry: %v", v)
+		}
+
+		var geo map[string]interface{}
+		if err := json.Unmarshal([]byte(f.metadata["geo"]), &geo); err != nil {
+			t.Fatalf("geo metadata error: %v", err)
+		}
+
+		column := geo["columns"].(map[string]interface{})["geometry"].(map[string]interface{})
+		if !reflect.DeepEqual(column["bbox"], []interface{}{2.0, 1.0, 3.0, 1.0}) {
+			t.Errorf("incorrect bbox: %v", column["bbox"])
+		}
+
+		if !reflect.DeepEqual(column["geometry_types"], []interface{}{"Point", "LineString"}) {
+			t.Errorf("incorrect geometry types: %v", column["geometry_types"])
+		}
+	}
+}
+
+func TestWriter_negativeIDs(t *testing.T) {
+	buf := &bytes.Buffer{}
+	w, err := NewWriter(buf)
+	if err != nil {
+		t.Fatalf("new error: %v", err)
+	}
+
+	objects := osm.Objects{
+		&osm.Node{ID: -1, Lat: 1, Lon: 2},
+		&osm.Way{ID: -2},
+		&osm.Relation{ID: -3},
+	}
+
+	for _, o := range objects {
+		if err := w.Write(o); err != nil {
+			t.Fatalf("write error: %v", err)
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatalf("close error: %v", err)
+	}
+
+	f := readFile(t, buf.Bytes())
+	if ids := f.column(t, 0, 0); !reflect.DeepEqual(ids, []interface{}{int64(-1), int64(-2), int64(-3)}) {
+		t.Errorf("incorrect ids: %v", ids)
+	}
+
+	if types := f.column(t, 0, 1); !reflect.DeepEqual(types, []interface{}{"node", "way", "relation"}) {
+		t.Errorf("incorrect types: %v", types)
+	}
+}
+
+func TestRowGroupSize(t *testing.T) {
+	if _, err := NewWriter(&bytes.Buffer{}, RowGroupSize(0)); err == nil {
+		t.Errorf("should error on zero row group size")
+	}
+}
+
+// parquetFile is the subset of the parquet file needed to verify the output.
+type parquetFile struct {
+	data      []byte
+	numRows   int64
+	rowGroups []tStruct
+	metadata  map[string]string
+	gzip      bool
+}
+
+type tStruct map[int16]interface{}
+
+func readFile(t *testing.T, data []byte) *parquetFile {
+	if !bytes.Equal(data[:4], magic) || !bytes.Equal(data[len(data)-4:], magic) {
+		t.Fatalf("incorrect magic bytes")
+	}
+
+	size := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	r := &thriftReader{data: data[len(data)-8-size : len(data)-8]}
+	meta := r.readStruct()
+
+	f := &parquetFile{
+		data:     data,
+		numRows:  meta[3].(int64),
+		metadata: map[string]string{},
+	}
+
+	for _, rg := range meta[4].([]interface{}) {
+		f.rowGroups = append(f.rowGroups, rg.(tStruct))
+	}
+
+	for _, kv := range meta[5].([]interface{}) {
+		kv := kv.(tStruct)
+		f.metadata[string(kv[1].([]byte))] = string(kv[2].([]byte))
+	}
+
+	return f
+}
+
+// column decodes the values of the column chunk, rows with repeated
+// values are returned as a slice of strings.
+func (f *parquetFile) column(t *testing.T, rowGroup, col int) []interface{} {
+	chunk := f.rowGroups[rowGroup][1].([]interface{})[col].(tStruct)
+	meta := chunk[3].(tStruct)
+	offset := meta[9].(int64)
+
+	r := &thriftReader{data: f.data[offset:]}
+	header := r.readStruct()
+	page := f.data[int(offset)+r.pos : int(offset)+r.pos+int(header[3].(int32))]
+
+	if meta[4].(int32) == codecGzip {
+		zr, err := gzip.NewReader(bytes.NewReader(page))
+		if err != nil {
+			t.Fatalf("gzip error: %v", err)
+		}
+
+		page, err = ioutil.ReadAll(zr)
+		if err != nil {
+			t.Fatalf("gzip error: %v", err)
+		}
+	}
+
+	count := int(header[5].(tStruct)[1].(int32))
+	s := schema[columnSchema[col]]
+
+	var reps, defs []int
+	if s.maxRep > 0 {
+		reps, page = readLevels(page, count)
+	}
+
+	if s.maxDef > 0 {
+		defs, page = readLevels(page, count)
+	}
+
+	var result []interface{}
+	for i := 0; i < count; i++ {
+		var v interface{}
+		if defs == nil || defs[i] == s.maxDef {
+			switch s.typ {
+			case typeInt32:
+				v = int32(binary.LittleEndian.Uint32(page))
+				page = page[4:]
+			case typeInt64:
+				v = int64(binary.LittleEndian.Uint64(page))
+				page = page[8:]
+			case typeByteArray:
+				l := binary.LittleEndian.Uint32(page)
+				v = string(page[4 : 4+l])
+				page = page[4+l:]
+			}
+		}
+
+		if reps == nil {
+			result = append(result, v)
+			continue
+		}
+
+		if reps[i] == 0 {
+			if v == nil {
+				result = append(result, nil)
+				continue
+			}
+			result = append(result, []string{})
+		}
+
+		last := result[len(result)-1].([]string)
+		result[len(result)-1] = append(last, v.(string))
+	}
+
+	return result
+}
+
+func readLevels(data []byte, count int) ([]int, []byte) {
+	size := binary.LittleEndian.Uint32(data)
+	levels := data[4 : 4+size]
+
+	var result []int
+	for len(levels) > 0 {
+		header, n := binary.Uvarint(levels)
+		levels = levels[n:]
+		if header&1 != 0 {
+			panic("bit-packed runs not supported")
+		}
+
+		for i := 0; i < int(header>>1); i++ {
+			result = append(result, int(levels[0]))
+		}
+		levels = levels[1:]
+	}
+
+	if len(result) != count {
+		panic("incorrect number of levels")
+	}
+
+	return result, data[4+size:]
+}
+
+// thriftReader is a minimal thrift compact protocol decoder.
+type thriftReader struct {
+	data []byte
+	pos  int
+}
+
+func (r *thriftReader) varint() uint64 {
+	v, n := binary.Uvarint(r.data[r.pos:])
+	r.pos += n
+	return v
+}
+
+func (r *thriftReader) zigzag() int64 {
+	v := r.varint()
+	return int64(v>>1) ^ -int64(v&1)
+}
+
+func (r *thriftReader) readStruct() tStruct {
+	s := tStruct{}
+	var last int16
+	for {
+		b := r.data[r.pos]
+		r.pos++
+		if b == 0 {
+			return s
+		}
+
+		id := last + int16(b>>4)
+		if b>>4 == 0 {
+			id = int16(r.zigzag())
+		}
+		last = id
+
+		s[id] = r.readValue(b & 0x0f)
+	}
+}
+
+func (r *thriftReader) readValue(typ byte) interface{} {
+	switch typ {
+	case thriftI32:
+		return int32(r.zigzag())
+	case thriftI64:
+		return r.zigzag()
+	case thriftBinary:
+		l := int(r.varint())
+		v := r.data[r.pos : r.pos+l]
+		r.pos += l
+		return v
+	case thriftStruct:
+		return r.readStruct()
+	case thriftList:
+		b := r.data[r.pos]
+		r.pos++
+
+		size := int(b >> 4)
+		if size == 15 {
+			size = int(r.varint())
+		}
+
+		list := make([]interface{}, size)
+		for i := range list {
+			list[i] = r.readValue(b & 0x0f)
+		}
+		return list
+	}
+
+	panic("unsupported thrift type")
+}
@@ -0,0 +1,89 @@
+package gpkg
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/paulmach/orb"
+)
+
+// GeometryType is the type of geometry stored in a layer.
+type GeometryType string
+
+// The geometry types supported by layers. Lines and polygons are always
+// stored as multi geometries so ways and relations can share a layer.
+const (
+	Points   GeometryType = "POINT"
+	Lines    GeometryType = "MULTILINESTRING"
+	Polygons GeometryType = "MULTIPOLYGON"
+)
+
+var validName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// A Layer defines which converted features are written
+// to a GeoPackage feature table and which tags are kept.
+type Layer struct {
+	// Name is the name of the table, it must be a valid sql identifier.
+	Name string
+	Type GeometryType
+
+	// Filter returns true if a feature with the given tags should be
+	// included in the layer, e.g. osm.HasTag. If nil, all features of the
+	// type are included.
+	Filter func(tags map[string]string) bool
+
+	// Keys are the tag keys stored as text columns of the same name.
+	// If empty, all the tags are stored as json in a `tags` column.
+	Keys []string
+}
+
+func (l *Layer) validate() error {
+	if !validName.MatchString(l.Name) {
+		return fmt.Errorf("gpkg: invalid layer name %q", l.Name)
+	}
+
+	switch l.Type {
+	case Points, Lines, Polygons:
+	default:
+		return fmt.Errorf("gpkg: layer %s: invalid geometry type %q", l.Name, l.Type)
+	}
+
+	for _, k := range l.Keys {
+		switch k {
+		case "fid", "geom", "osm_type", "osm_id":
+			return fmt.Errorf("gpkg: layer %s: reserved column name %q", l.Name, k)
+		}
+	}
+
+	return nil
+}
+
+// geometry returns the geometry to store in the layer, promoted to the
+// multi geometry type. It returns nil if the geometry does not match the layer.
+func (l *Layer) geometry(g orb.Geometry) orb.Geometry {
+	switch l.Type {
+	case Points:
+		if p, ok := g.(orb.Point); ok {
+			return p
+		}
+	case Lines:
+		switch g := g.(type) {
+		case orb.LineString:
+			return orb.MultiLineString{g}
+		case orb.MultiLineString:
+			return g
+		}
+	case Polygons:
+		switch g := g.(type) {
+		case orb.Polygon:
+			return orb.MultiPolygon{g}
+		case orb.MultiPolygon:
+			return g
+		}
+	}
+
+	return nil
+}
+
+var errNoLayers = errors.New("gpkg: at least one layer is required")
@@ -0,0 +1,315 @@
+// Package gpkg writes converted osm geometries into GeoPackage (.gpkg) files.
+package gpkg
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/encoding/wkb"
+	"github.com/paulmach/osm"
+	"github.com/paulmach/osm/osmgeojson"
+)
+
+const (
+	applicationID = 0x47504B47 // "GPKG"
+	userVersion   = 10200      // version 1.2
+	srsID         = 4326
+)
+
+var metadataTables = []string{
+	fmt.Sprintf("PRAGMA application_id = %d", applicationID),
+	fmt.Sprintf("PRAGMA user_version = %d", userVersion),
+	`CREATE TABLE IF NOT EXISTS gpkg_spatial_ref_sys (
+	srs_name TEXT NOT NULL,
+	srs_id INTEGER NOT NULL PRIMARY KEY,
+	organization TEXT NOT NULL,
+	organization_coordsys_id INTEGER NOT NULL,
+	definition TEXT NOT NULL,
+	description TEXT
+)`,
+	`INSERT OR IGNORE INTO gpkg_spatial_ref_sys VALUES
+	('Undefined cartesian SRS', -1, 'NONE', -1, 'undefined', 'undefined cartesian coordinate reference system'),
+	('Undefined geographic SRS', 0, 'NONE', 0, 'undefined', 'undefined geographic coordinate reference system'),
+	('WGS 84 geodetic', 4326, 'EPSG', 4326, 'GEOGCS["WGS 84",DATUM["WGS_1984",SPHEROID["WGS 84",6378137,298.257223563,AUTHORITY["EPSG","7030"]],AUTHORITY["EPSG","6326"]],PRIMEM["Greenwich",0,AUTHORITY["EPSG","8901"]],UNIT["degree",0.0174532925199433,AUTHORITY["EPSG","9122"]],AUTHORITY["EPSG","4326"]]', 'longitude/latitude coordinates in decimal degrees on the WGS 84 spheroid')`,
+	`CREATE TABLE IF NOT EXISTS gpkg_contents (
+	table_name TEXT NOT NULL PRIMARY KEY,
+	data_type TEXT NOT NULL,
+	identifier TEXT UNIQUE,
+	description TEXT DEFAULT '',
+	last_change DATETIME NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ','now')),
+	min_x DOUBLE,
+	min_y DOUBLE,
+	max_x DOUBLE,
+	max_y DOUBLE,
+	srs_id INTEGER,
+	CONSTRAINT fk_gc_r_srs_id FOREIGN KEY (srs_id) REFERENCES gpkg_spatial_ref_sys(srs_id)
+)`,
+	`CREATE TABLE IF NOT EXISTS gpkg_geometry_columns (
+	table_name TEXT NOT NULL,
+	column_name TEXT NOT NULL,
+	geometry_type_name TEXT NOT NULL,
+	srs_id INTEGER NOT NULL,
+	z TINYINT NOT NULL,
+	m TINYINT NOT NULL,
+	CONSTRAINT pk_geom_cols PRIMARY KEY (table_name, column_name),
+	CONSTRAINT fk_gc_tn FOREIGN KEY (table_name) REFERENCES gpkg_contents(table_name),
+	CONSTRAINT fk_gc_srs FOREIGN KEY (srs_id) REFERENCES gpkg_spatial_ref_sys (srs_id)
+)`,
+}
+
+// Writer converts osm data to geometry and writes the features into
+// the GeoPackage layer tables. The database/sql driver, eg. github.com/mattn/go-sqlite3,
+// must be imported by the caller.
+type Writer struct {
+	db      *sql.DB
+	layers  []*Layer
+	inserts []string
+	bounds  []orb.Bound
+}
+
+// New creates a writer for the layers. A feature can be written to more than one layer.
+func New(db *sql.DB, layers []*Layer) (*Writer, error) {
+	if len(layers) == 0 {
+		return nil, errNoLayers
+	}
+
+	w := &Writer{
+		db:      db,
+		layers:  layers,
+		inserts: make([]string, len(layers)),
+		bounds:  make([]orb.Bound, len(layers)),
+	}
+
+	for i, l := range layers {
+		if err := l.validate(); err != nil {
+			return nil, err
+		}
+
+		cols := []string{"geom", "osm_type", "osm_id"}
+		cols = append(cols, l.columns()...)
+		w.inserts[i] = fmt.Sprintf("INSERT INTO %s (%s) VALUES (?%s)",
+			quoteIdent(l.Name), quoteIdents(cols), strings.Repeat(", ?", len(cols)-1))
+	}
+
+	return w, nil
+}
+
+func (l *Layer) columns() []string {
+	if len(l.Keys) == 0 {
+		return []string{"tags"}
+	}
+
+	return l.Keys
+}
+
+// Init creates the GeoPackage metadata tables and registers the feature tables.
+func (w *Writer) Init(ctx context.Context) error {
+	stmts := metadataTables
+	for _, l := range w.layers {
+		cols := make([]string, 0, len(l.columns()))
+		for _, c := range l.columns() {
+			cols = append(cols, ",\n\t"+quoteIdent(c)+" TEXT")
+		}
+
+		stmts = append(stmts[:len(stmts):len(stmts)],
+			fmt.Sprintf(`CREATE TABLE %s (
+	fid INTEGER PRIMARY KEY AUTOINCREMENT,
+	geom %s,
+	osm_type TEXT NOT NULL,
+	osm_id INTEGER NOT NULL%s
+)`, quoteIdent(l.Name), l.Type, strings.Join(cols, "")),
+			fmt.Sprintf("INSERT INTO gpkg_contents (table_name, data_type, identifier, srs_id) VALUES ('%s', 'features', '%s', %d)",
+				l.Name, l.Name, srsID),
+			fmt.Sprintf("INSERT INTO gpkg_geometry_columns VALUES ('%s', 'geom', '%s', %d, 0, 0)",
+				l.Name, l.Type, srsID),
+		)
+	}
+
+	for _, s := range stmts {
+		if _, err := w.db.ExecContext(ctx, s); err != nil {
+			return fmt.Errorf("gpkg: %v: %s", err, s)
+		}
+	}
+
+	return nil
+}
+
+// Write converts the osm data and inserts the features into the matching layers
+// in a single transaction. Ways need their nodes, and relations their members,
+// to be in the same osm object so they can be built. The extents in the
+// gpkg_contents table are updated to include the new features.
+func (w *Writer) Write(ctx context.Context, o *osm.OSM) error {
+	fc, err := osmgeojson.Convert(o,
+		osmgeojson.NoID(true),
+		osmgeojson.NoMeta(true),
+		osmgeojson.NoRelationMembership(true),
+	)
+	if err != nil {
+		return err
+	}
+
+	tx, err := w.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	stmts := make([]*sql.Stmt, len(w.layers))
+	defer func() {
+		for _, s := range stmts {
+			if s != nil {
+				s.Close()
+			}
+		}
+	}()
+
+	bounds := append([]orb.Bound(nil), w.bounds...)
+	for _, f := range fc.Features {
+		tags, _ := f.Properties["tags"].(map[string]string)
+		for i, l := range w.layers {
+			geom := l.geometry(f.Geometry)
+			if geom == nil || (l.Filter != nil && !l.Filter(tags)) {
+				continue
+			}
+
+			if stmts[i] == nil {
+				stmts[i], err = tx.PrepareContext(ctx, w.inserts[i])
+				if err != nil {
+					tx.Rollback()
+					return err
+				}
+			}
+
+			data, err := encodeGeometry(geom)
+			if err != nil {
+				tx.Rollback()
+				return err
+			}
+
+			args := []interface{}{data, f.Properties["type"], f.Properties["id"]}
+			args, err = appendTags(args, l, tags)
+			if err != nil {
+				tx.Rollback()
+				return err
+			}
+
+			if _, err := stmts[i].ExecContext(ctx, args...); err != nil {
+				tx.Rollback()
+				return err
+			}
+
+			if bounds[i].IsZero() {
+				bounds[i] = geom.Bound()
+			} else {
+				bounds[i] = bounds[i].Union(geom.Bound())
+			}
+		}
+	}
+
+	for i, l := range w.layers {
+		if bounds[i] == w.bounds[i] {
+			continue
+		}
+
+		b := bounds[i]
+		_, err := tx.ExecContext(ctx,
+			"UPDATE gpkg_contents SET min_x = ?, min_y = ?, max_x = ?, max_y = ?, last_change = strftime('%Y-%m-%dT%H:%M:%fZ','now') WHERE table_name = ?",
+			b.Min[0], b.Min[1], b.Max[0], b.Max[1], l.Name)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	w.bounds = bounds
+	return nil
+}
+
+// WriteScanner reads all the nodes, ways and relations from the scanner
+// and writes them to the layers.
+func (w *Writer) WriteScanner(ctx context.Context, s osm.Scanner) error {
+	o := &osm.OSM{}
+	for s.Scan() {
+		switch obj := s.Object().(type) {
+		case *osm.Node, *osm.Way, *osm.Relation:
+			o.Append(obj)
+		}
+	}
+
+	if err := s.Err(); err != nil {
+		return err
+	}
+
+	return w.Write(ctx, o)
+}
+
+func appendTags(args []interface{}, l *Layer, tags map[string]string) ([]interface{}, error) {
+	if len(l.Keys) == 0 {
+		data, err := json.Marshal(tags)
+		if err != nil {
+			return nil, err
+		}
+
+		return append(args, string(data)), nil
+	}
+
+	for _, k := range l.Keys {
+		if v, ok := tags[k]; ok {
+			args = append(args, v)
+		} else {
+			args = append(args, nil)
+		}
+	}
+
+	return args, nil
+}
+
+// encodeGeometry encodes the geometry in the GeoPackage binary format,
+// a header with the srs and envelope followed by the wkb.
+func encodeGeometry(g orb.Geometry) ([]byte, error) {
+	data, err := wkb.Marshal(g, binary.LittleEndian)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := bytes.NewBuffer(make([]byte, 0, 8+32+len(data)))
+	buf.Write([]byte{'G', 'P', 0})
+
+	if _, ok := g.(orb.Point); ok {
+		// flags: little endian, no envelope
+		buf.WriteByte(0x01)
+		binary.Write(buf, binary.LittleEndian, int32(srsID))
+	} else {
+		// flags: little endian, envelope [minx, maxx, miny, maxy]
+		buf.WriteByte(0x03)
+		binary.Write(buf, binary.LittleEndian, int32(srsID))
+
+		b := g.Bound()
+		binary.Write(buf, binary.LittleEndian, []float64{b.Min[0], b.Max[0], b.Min[1], b.Max[1]})
+	}
+
+	buf.Write(data)
+	return buf.Bytes(), nil
+}
+
+func quoteIdent(name string) string {
+	return `"` + strings.Replace(name, `"`, `""`, -1) + `"`
+}
+
+func quoteIdents(names []string) string {
+	quoted := make([]string, len(names))
+	for i, n := range names {
+		quoted[i] = quoteIdent(n)
+	}
+
+	return strings.Join(quoted, ", ")
+}
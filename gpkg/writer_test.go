@@ -0,0 +1,167 @@
+package gpkg
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"strings"
+	"testing"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/encoding/wkb"
+	"github.com/paulmach/osm"
+	"github.com/paulmach/osm/internal/sqltest"
+)
+
+func testOSM() *osm.OSM {
+	return &osm.OSM{
+		Nodes: osm.Nodes{
+			{ID: 1, Lat: 1, Lon: 1, Version: 1,
+				Tags: osm.Tags{{Key: "amenity", Value: "cafe"}, {Key: "name", Value: "Joe's"}}},
+			{ID: 2, Lat: 1, Lon: 2, Version: 1},
+			{ID: 3, Lat: 2, Lon: 2, Version: 1},
+		},
+		Ways: osm.Ways{
+			{ID: 10, Version: 1, Nodes: osm.WayNodes{{ID: 2}, {ID: 3}},
+				Tags: osm.Tags{{Key: "highway", Value: "primary"}}},
+			{ID: 11, Version: 1, Nodes: osm.WayNodes{{ID: 1}, {ID: 2}, {ID: 3}, {ID: 1}},
+				Tags: osm.Tags{{Key: "building", Value: "yes"}}},
+		},
+	}
+}
+
+func TestWriter(t *testing.T) {
+	db, rec, err := sqltest.Open()
+	if err != nil {
+		t.Fatalf("open error: %v", err)
+	}
+	defer db.Close()
+
+	w, err := New(db, []*Layer{
+		{Name: "pois", Type: Points, Filter: osm.HasTag("amenity"), Keys: []string{"amenity", "cuisine"}},
+		{Name: "roads", Type: Lines, Filter: osm.HasTag("highway")},
+		{Name: "buildings", Type: Polygons},
+	})
+	if err != nil {
+		t.Fatalf("new error: %v", err)
+	}
+
+	if err := w.Init(context.Background()); err != nil {
+		t.Fatalf("init error: %v", err)
+	}
+
+	found := false
+	for _, s := range rec.Execs {
+		if strings.Contains(s, `CREATE TABLE "roads"`) && strings.Contains(s, "geom MULTILINESTRING") {
+			found = true
+		}
+	}
+
+	if !found {
+		t.Errorf("should create roads table: %v", rec.Execs)
+	}
+
+	if err := w.Write(context.Background(), testOSM()); err != nil {
+		t.Fatalf("write error: %v", err)
+	}
+
+	pois := rec.Rows[w.inserts[0]]
+	if len(pois) != 1 {
+		t.Fatalf("incorrect pois: %v", pois)
+	}
+
+	if pois[0][1] != "node" || pois[0][2] != int64(1) || pois[0][3] != "cafe" || pois[0][4] != nil {
+		t.Errorf("incorrect poi: %v", pois[0])
+	}
+
+	roads := rec.Rows[w.inserts[1]]
+	if len(roads) != 1 {
+		t.Fatalf("incorrect roads: %v", roads)
+	}
+
+	if roads[0][3] != `{"highway":"primary"}` {
+		t.Errorf("incorrect road tags: %v", roads[0][3])
+	}
+
+	geom, err := decodeGeometry(roads[0][0].([]byte))
+	if err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+
+	if !orb.Equal(geom, orb.MultiLineString{{{2, 1}, {2, 2}}}) {
+		t.Errorf("incorrect geometry: %v", geom)
+	}
+
+	buildings := rec.Rows[w.inserts[2]]
+	if len(buildings) != 1 {
+		t.Fatalf("incorrect buildings: %v", buildings)
+	}
+
+	if w.bounds[2] != (orb.Bound{Min: orb.Point{1, 1}, Max: orb.Point{2, 2}}) {
+		t.Errorf("incorrect bound: %v", w.bounds[2])
+	}
+}
+
+func TestNew_errors(t *testing.T) {
+	cases := []struct {
+		name   string
+		layers []*Layer
+	}{
+		{
+			name: "no layers",
+		},
+		{
+			name:   "invalid name",
+			layers: []*Layer{{Name: "a b", Type: Points}},
+		},
+		{
+			name:   "invalid type",
+			layers: []*Layer{{Name: "a", Type: "CURVE"}},
+		},
+		{
+			name:   "reserved key",
+			layers: []*Layer{{Name: "a", Type: Points, Keys: []string{"geom"}}},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := New(nil, tc.layers); err == nil {
+				t.Errorf("should return error")
+			}
+		})
+	}
+}
+
+func TestEncodeGeometry(t *testing.T) {
+	data, err := encodeGeometry(orb.Point{1, 2})
+	if err != nil {
+		t.Fatalf("encode error: %v", err)
+	}
+
+	if !bytes.Equal(data[:4], []byte{'G', 'P', 0, 1}) {
+		t.Errorf("incorrect header: %v", data[:4])
+	}
+
+	if srs := binary.LittleEndian.Uint32(data[4:8]); srs != srsID {
+		t.Errorf("incorrect srs: %v", srs)
+	}
+
+	g, err := decodeGeometry(data)
+	if err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+
+	if !orb.Equal(g, orb.Point{1, 2}) {
+		t.Errorf("incorrect point: %v", g)
+	}
+}
+
+func decodeGeometry(data []byte) (orb.Geometry, error) {
+	offset := 8
+	if data[3]&0x0e == 0x02 {
+		offset += 32
+	}
+
+	return wkb.Unmarshal(data[offset:])
+}
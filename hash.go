@@ -0,0 +1,195 @@
+package osm
+
+import (
+	"encoding/binary"
+	"hash"
+	"hash/fnv"
+	"math"
+	"time"
+)
+
+// Hash returns a stable 64-bit hash of the node's content. Nodes that are
+// Equal, with the same options, will have the same hash. The value is stable
+// across processes and versions of this package so it can be used as a cache
+// key or for cheap change detection. It is not a cryptographic hash, elements
+// with the same hash should be compared with Equal if that matters.
+// Use the IgnoreMetadata option to only hash the data.
+func (n *Node) Hash(opts ...EqualOption) uint64 {
+	h := newHasher(TypeNode)
+	h.int(int64(n.ID))
+	h.bool(n.Visible)
+	h.float(n.Lat)
+	h.float(n.Lon)
+	h.tags(n.Tags)
+
+	if !newEqualOptions(opts).ignoreMetadata {
+		h.metadata(n.User, n.UserID, n.Version, n.ChangesetID, n.Timestamp, n.Committed)
+	}
+
+	return h.Sum64()
+}
+
+// Hash returns a stable 64-bit hash of the way's content. Ways that are
+// Equal, with the same options, will have the same hash.
+// Use the IgnoreMetadata option to only hash the tags and node ids.
+func (w *Way) Hash(opts ...EqualOption) uint64 {
+	h := newHasher(TypeWay)
+	h.int(int64(w.ID))
+	h.bool(w.Visible)
+	h.tags(w.Tags)
+
+	ignore := newEqualOptions(opts).ignoreMetadata
+
+	h.int(int64(len(w.Nodes)))
+	for _, n := range w.Nodes {
+		h.int(int64(n.ID))
+		if !ignore {
+			h.int(int64(n.Version))
+			h.int(int64(n.ChangesetID))
+			h.float(n.Lat)
+			h.float(n.Lon)
+		}
+	}
+
+	if !ignore {
+		h.metadata(w.User, w.UserID, w.Version, w.ChangesetID, w.Timestamp, w.Committed)
+		h.updates(w.Updates)
+		h.bounds(w.Bounds)
+	}
+
+	return h.Sum64()
+}
+
+// Hash returns a stable 64-bit hash of the relation's content. Relations
+// that are Equal, with the same options, will have the same hash.
+// Use the IgnoreMetadata option to only hash the tags and the member
+// type, ref and role.
+func (r *Relation) Hash(opts ...EqualOption) uint64 {
+	h := newHasher(TypeRelation)
+	h.int(int64(r.ID))
+	h.bool(r.Visible)
+	h.tags(r.Tags)
+
+	ignore := newEqualOptions(opts).ignoreMetadata
+
+	h.int(int64(len(r.Members)))
+	for _, m := range r.Members {
+		h.string(string(m.Type))
+		h.int(m.Ref)
+		h.string(m.Role)
+		if !ignore {
+			h.int(int64(m.Version))
+			h.int(int64(m.ChangesetID))
+			h.float(m.Lat)
+			h.float(m.Lon)
+			h.int(int64(m.Orientation))
+		}
+	}
+
+	if !ignore {
+		h.metadata(r.User, r.UserID, r.Version, r.ChangesetID, r.Timestamp, r.Committed)
+		h.updates(r.Updates)
+		h.bounds(r.Bounds)
+	}
+
+	return h.Sum64()
+}
+
+// hasher writes values in a fixed, unambiguous, binary format.
+// Strings are prefixed by their length.
+type hasher struct {
+	hash.Hash64
+	buf [binary.MaxVarintLen64]byte
+}
+
+func newHasher(t Type) *hasher {
+	h := &hasher{Hash64: fnv.New64a()}
+	h.string(string(t))
+
+	return h
+}
+
+func (h *hasher) int(v int64) {
+	n := binary.PutVarint(h.buf[:], v)
+	h.Write(h.buf[:n])
+}
+
+func (h *hasher) float(v float64) {
+	if v == 0 {
+		// -0 == 0
+		v = 0
+	}
+
+	binary.LittleEndian.PutUint64(h.buf[:8], math.Float64bits(v))
+	h.Write(h.buf[:8])
+}
+
+func (h *hasher) bool(v bool) {
+	if v {
+		h.int(1)
+	} else {
+		h.int(0)
+	}
+}
+
+func (h *hasher) string(s string) {
+	h.int(int64(len(s)))
+	h.Write([]byte(s))
+}
+
+func (h *hasher) time(t time.Time) {
+	if t.IsZero() {
+		h.bool(false)
+		return
+	}
+
+	h.bool(true)
+	h.int(t.Unix())
+	h.int(int64(t.Nanosecond()))
+}
+
+// tags hashes the tags ignoring their order, like Equal.
+func (h *hasher) tags(ts Tags) {
+	sorted := sortedTags(ts)
+	h.int(int64(len(sorted)))
+	for _, t := range sorted {
+		h.string(t.Key)
+		h.string(t.Value)
+	}
+}
+
+func (h *hasher) metadata(user string, uid UserID, version int, cid ChangesetID, ts time.Time, committed *time.Time) {
+	h.string(user)
+	h.int(int64(uid))
+	h.int(int64(version))
+	h.int(int64(cid))
+	h.time(ts)
+
+	h.bool(committed != nil)
+	if committed != nil {
+		h.time(*committed)
+	}
+}
+
+func (h *hasher) updates(us Updates) {
+	h.int(int64(len(us)))
+	for _, u := range us {
+		h.int(int64(u.Index))
+		h.int(int64(u.Version))
+		h.time(u.Timestamp)
+		h.int(int64(u.ChangesetID))
+		h.float(u.Lat)
+		h.float(u.Lon)
+		h.bool(u.Reverse)
+	}
+}
+
+func (h *hasher) bounds(b *Bounds) {
+	h.bool(b != nil)
+	if b != nil {
+		h.float(b.MinLat)
+		h.float(b.MaxLat)
+		h.float(b.MinLon)
+		h.float(b.MaxLon)
+	}
+}
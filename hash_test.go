@@ -0,0 +1,99 @@
+package osm
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNode_Hash(t *testing.T) {
+	ts := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+	n := &Node{ID: 1, Lat: 1, Lon: 2, Version: 3, User: "u", Timestamp: ts,
+		Tags: Tags{{Key: "a", Value: "b"}, {Key: "c", Value: "d"}}}
+
+	// the hash must not change between versions of the package
+	if h := n.Hash(); h != 12499424284704414107 {
+		t.Errorf("hash changed: %d", h)
+	}
+
+	c := n.Copy()
+	c.Tags[0], c.Tags[1] = c.Tags[1], c.Tags[0]
+	c.Timestamp = ts.In(time.FixedZone("other", 3600))
+	if n.Hash() != c.Hash() {
+		t.Errorf("tag order and time zone should not matter")
+	}
+
+	c.Version = 4
+	if n.Hash() == c.Hash() {
+		t.Errorf("should hash metadata")
+	}
+
+	if n.Hash(IgnoreMetadata()) != c.Hash(IgnoreMetadata()) {
+		t.Errorf("should ignore metadata")
+	}
+
+	c.Lat = 5
+	if n.Hash(IgnoreMetadata()) == c.Hash(IgnoreMetadata()) {
+		t.Errorf("should hash location")
+	}
+
+	c = n.Copy()
+	c.Tags = Tags{{Key: "ab", Value: ""}, {Key: "c", Value: "d"}}
+	n.Tags = Tags{{Key: "a", Value: "b"}, {Key: "c", Value: "d"}}
+	if n.Hash() == c.Hash() {
+		t.Errorf("tag boundaries should be hashed")
+	}
+}
+
+func TestWay_Hash(t *testing.T) {
+	w := &Way{ID: 1, Version: 2, Nodes: WayNodes{{ID: 1}, {ID: 2}},
+		Tags: Tags{{Key: "highway", Value: "residential"}}}
+
+	c := w.Copy()
+	if w.Hash() != c.Hash() {
+		t.Errorf("copy should have the same hash")
+	}
+
+	c.Nodes[0].Lat = 1
+	c.Updates = Updates{{Index: 0, Version: 2}}
+	if w.Hash() == c.Hash() {
+		t.Errorf("should hash way node locations and updates")
+	}
+
+	if w.Hash(IgnoreMetadata()) != c.Hash(IgnoreMetadata()) {
+		t.Errorf("should ignore metadata")
+	}
+
+	c.Nodes[0].ID = 3
+	if w.Hash(IgnoreMetadata()) == c.Hash(IgnoreMetadata()) {
+		t.Errorf("should hash node ids")
+	}
+
+	n := &Node{ID: 1, Version: 2, Tags: w.Tags}
+	if n.Hash() == (&Way{ID: 1, Version: 2, Tags: w.Tags}).Hash() {
+		t.Errorf("should hash type")
+	}
+}
+
+func TestRelation_Hash(t *testing.T) {
+	r := &Relation{ID: 1, Version: 2,
+		Members: Members{{Type: TypeWay, Ref: 1, Role: "outer"}}}
+
+	c := r.Copy()
+	if r.Hash() != c.Hash() {
+		t.Errorf("copy should have the same hash")
+	}
+
+	c.Members[0].Version = 3
+	if r.Hash() == c.Hash() {
+		t.Errorf("should hash member versions")
+	}
+
+	if r.Hash(IgnoreMetadata()) != c.Hash(IgnoreMetadata()) {
+		t.Errorf("should ignore metadata")
+	}
+
+	c.Members[0].Role = "inner"
+	if r.Hash(IgnoreMetadata()) == c.Hash(IgnoreMetadata()) {
+		t.Errorf("should hash roles")
+	}
+}
@@ -0,0 +1,262 @@
+package idset
+
+import (
+	"math/bits"
+	"sort"
+)
+
+const (
+	// containers with more ids than this are stored as bitsets,
+	// at this size the array and bitset use the same memory, 8KB.
+	maxArray = 4096
+
+	bitsetWords = 65536 / 64
+)
+
+// Bitmap is an exact set of ids modeled on roaring bitmaps. The ids are
+// grouped by their high 48 bits into containers holding the low 16 bits,
+// as a sorted array when sparse or as a bitset when dense. The zero value
+// is an empty set. It is not safe for concurrent writes.
+type Bitmap struct {
+	containers map[int64]*container
+	n          int
+}
+
+// NewBitmap creates an empty bitmap.
+func NewBitmap() *Bitmap {
+	return &Bitmap{containers: make(map[int64]*container)}
+}
+
+type container struct {
+	array []uint16 // sorted, nil once converted to a bitset
+	bits  []uint64
+}
+
+// Add adds the id to the set.
+func (b *Bitmap) Add(id int64) {
+	if b.containers == nil {
+		b.containers = make(map[int64]*container)
+	}
+
+	key, low := split(id)
+	c := b.containers[key]
+	if c == nil {
+		c = &container{}
+		b.containers[key] = c
+	}
+
+	if c.add(low) {
+		b.n++
+	}
+}
+
+// Contains returns true if the id is in the set.
+func (b *Bitmap) Contains(id int64) bool {
+	key, low := split(id)
+	c := b.containers[key]
+
+	return c != nil && c.contains(low)
+}
+
+// Len returns the number of ids in the set.
+func (b *Bitmap) Len() int {
+	return b.n
+}
+
+// Each calls the function for every id, in ascending order,
+// until it returns false.
+func (b *Bitmap) Each(f func(id int64) bool) {
+	keys := make([]int64, 0, len(b.containers))
+	for k := range b.containers {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+
+	for _, k := range keys {
+		if !b.containers[k].each(func(v uint16) bool {
+			return f(k<<16 | int64(v))
+		}) {
+			return
+		}
+	}
+}
+
+// Union returns a new bitmap with the ids in either bitmap.
+func (b *Bitmap) Union(other *Bitmap) *Bitmap {
+	result := NewBitmap()
+	for k, c := range b.containers {
+		result.containers[k] = c.clone()
+	}
+
+	for k, c := range other.containers {
+		if r := result.containers[k]; r != nil {
+			result.containers[k] = r.union(c)
+		} else {
+			result.containers[k] = c.clone()
+		}
+	}
+
+	for _, c := range result.containers {
+		result.n += c.len()
+	}
+
+	return result
+}
+
+// Intersect returns a new bitmap with the ids in both bitmaps.
+func (b *Bitmap) Intersect(other *Bitmap) *Bitmap {
+	result := NewBitmap()
+	for k, c := range b.containers {
+		o := other.containers[k]
+		if o == nil {
+			continue
+		}
+
+		if r := c.intersect(o); r.len() > 0 {
+			result.containers[k] = r
+			result.n += r.len()
+		}
+	}
+
+	return result
+}
+
+func split(id int64) (int64, uint16) {
+	return id >> 16, uint16(id)
+}
+
+func (c *container) add(v uint16) bool {
+	if c.bits != nil {
+		w, m := v/64, uint64(1)<<(v%64)
+		if c.bits[w]&m != 0 {
+			return false
+		}
+
+		c.bits[w] |= m
+		return true
+	}
+
+	i := sort.Search(len(c.array), func(i int) bool { return c.array[i] >= v })
+	if i < len(c.array) && c.array[i] == v {
+		return false
+	}
+
+	if len(c.array) == maxArray {
+		c.bits = make([]uint64, bitsetWords)
+		for _, a := range c.array {
+			c.bits[a/64] |= 1 << (a % 64)
+		}
+		c.array = nil
+
+		c.bits[v/64] |= 1 << (v % 64)
+		return true
+	}
+
+	c.array = append(c.array, 0)
+	copy(c.array[i+1:], c.array[i:])
+	c.array[i] = v
+
+	return true
+}
+
+func (c *container) contains(v uint16) bool {
+	if c.bits != nil {
+		return c.bits[v/64]&(1<<(v%64)) != 0
+	}
+
+	i := sort.Search(len(c.array), func(i int) bool { return c.array[i] >= v })
+	return i < len(c.array) && c.array[i] == v
+}
+
+func (c *container) len() int {
+	if c.bits == nil {
+		return len(c.array)
+	}
+
+	n := 0
+	for _, w := range c.bits {
+		n += bits.OnesCount64(w)
+	}
+
+	return n
+}
+
+func (c *container) each(f func(uint16) bool) bool {
+	if c.bits == nil {
+		for _, v := range c.array {
+			if !f(v) {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	for i, w := range c.bits {
+		for w != 0 {
+			t := bits.TrailingZeros64(w)
+			if !f(uint16(i*64 + t)) {
+				return false
+			}
+			w &= w - 1
+		}
+	}
+
+	return true
+}
+
+func (c *container) clone() *container {
+	r := &container{}
+	if c.bits != nil {
+		r.bits = append([]uint64(nil), c.bits...)
+	} else {
+		r.array = append([]uint16(nil), c.array...)
+	}
+
+	return r
+}
+
+// union adds the values of the other container, modifying c.
+func (c *container) union(other *container) *container {
+	if c.bits != nil && other.bits != nil {
+		for i, w := range other.bits {
+			c.bits[i] |= w
+		}
+
+		return c
+	}
+
+	other.each(func(v uint16) bool {
+		c.add(v)
+		return true
+	})
+
+	return c
+}
+
+// intersect returns a new container with the values in both.
+func (c *container) intersect(other *container) *container {
+	if c.bits != nil && other.bits != nil {
+		r := &container{bits: make([]uint64, bitsetWords)}
+		for i, w := range c.bits {
+			r.bits[i] = w & other.bits[i]
+		}
+
+		return r
+	}
+
+	a, b := c, other
+	if b.bits == nil && (a.bits != nil || len(b.array) < len(a.array)) {
+		a, b = b, a
+	}
+
+	r := &container{}
+	a.each(func(v uint16) bool {
+		if b.contains(v) {
+			r.array = append(r.array, v)
+		}
+		return true
+	})
+
+	return r
+}
@@ -0,0 +1,181 @@
+package idset
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestBitmap(t *testing.T) {
+	cases := []struct {
+		name string
+		ids  []int64
+	}{
+		{
+			name: "sparse",
+			ids:  []int64{1, 5, 1 << 20, 1<<40 + 3},
+		},
+		{
+			name: "negative",
+			ids:  []int64{-1, -65536, -65537, 0},
+		},
+		{
+			name: "dense",
+			ids:  sequence(1000, 10000),
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			b := &Bitmap{}
+			for _, id := range tc.ids {
+				b.Add(id)
+				b.Add(id)
+			}
+
+			if b.Len() != len(tc.ids) {
+				t.Errorf("incorrect length: %v != %v", b.Len(), len(tc.ids))
+			}
+
+			set := make(map[int64]bool)
+			for _, id := range tc.ids {
+				set[id] = true
+				if !b.Contains(id) {
+					t.Errorf("should contain %v", id)
+				}
+			}
+
+			for _, id := range []int64{2, 3, -2, 1 << 30, 1<<40 + 4, 20000} {
+				if !set[id] && b.Contains(id) {
+					t.Errorf("should not contain %v", id)
+				}
+			}
+		})
+	}
+}
+
+func TestBitmap_random(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+
+	b := NewBitmap()
+	set := make(map[int64]bool)
+	for i := 0; i < 50000; i++ {
+		id := r.Int63n(200000)
+		b.Add(id)
+		set[id] = true
+	}
+
+	if b.Len() != len(set) {
+		t.Errorf("incorrect length: %v != %v", b.Len(), len(set))
+	}
+
+	for id := int64(0); id < 200000; id++ {
+		if b.Contains(id) != set[id] {
+			t.Fatalf("incorrect contains for %v", id)
+		}
+	}
+}
+
+func sequence(start, n int64) []int64 {
+	ids := make([]int64, n)
+	for i := range ids {
+		ids[i] = start + int64(i)
+	}
+
+	return ids
+}
+
+func TestBitmap_Each(t *testing.T) {
+	b := NewBitmap()
+	input := append([]int64{1<<20 + 1, -5, 3}, sequence(70000, 5000)...)
+	for _, id := range input {
+		b.Add(id)
+	}
+
+	var result []int64
+	b.Each(func(id int64) bool {
+		result = append(result, id)
+		return true
+	})
+
+	if len(result) != len(input) {
+		t.Fatalf("incorrect number of ids: %v", len(result))
+	}
+
+	if result[0] != -5 || result[1] != 3 || result[2] != 70000 || result[len(result)-1] != 1<<20+1 {
+		t.Errorf("should be in ascending order: %v", result[:3])
+	}
+
+	count := 0
+	b.Each(func(id int64) bool {
+		count++
+		return count < 2
+	})
+
+	if count != 2 {
+		t.Errorf("should stop early: %v", count)
+	}
+}
+
+func TestBitmap_UnionIntersect(t *testing.T) {
+	cases := []struct {
+		name string
+		a, b []int64
+	}{
+		{
+			name: "arrays",
+			a:    []int64{1, 2, 3, 1 << 20},
+			b:    []int64{3, 4, 1 << 20, 1 << 30},
+		},
+		{
+			name: "bitsets",
+			a:    sequence(0, 6000),
+			b:    sequence(3000, 6000),
+		},
+		{
+			name: "array and bitset",
+			a:    []int64{5, 5000, 9000, 70000},
+			b:    sequence(0, 6000),
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			a, b := NewBitmap(), NewBitmap()
+			ha, hb := NewHash(), NewHash()
+			for _, id := range tc.a {
+				a.Add(id)
+				ha.Add(id)
+			}
+			for _, id := range tc.b {
+				b.Add(id)
+				hb.Add(id)
+			}
+
+			checkSame(t, "union", a.Union(b), ha.Union(hb))
+			checkSame(t, "reverse union", b.Union(a), ha.Union(hb))
+			checkSame(t, "intersect", a.Intersect(b), ha.Intersect(hb))
+			checkSame(t, "reverse intersect", b.Intersect(a), ha.Intersect(hb))
+
+			// inputs are not modified
+			if a.Len() != len(tc.a) || b.Len() != len(tc.b) {
+				t.Errorf("inputs modified: %v %v", a.Len(), b.Len())
+			}
+		})
+	}
+}
+
+func checkSame(t testing.TB, name string, b *Bitmap, h Hash) {
+	t.Helper()
+
+	if b.Len() != h.Len() {
+		t.Errorf("%s: incorrect length: %v != %v", name, b.Len(), h.Len())
+	}
+
+	b.Each(func(id int64) bool {
+		if !h.Contains(id) {
+			t.Errorf("%s: should not contain %v", name, id)
+			return false
+		}
+		return true
+	})
+}
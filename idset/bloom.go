@@ -0,0 +1,121 @@
+package idset
+
+import (
+	"errors"
+	"math"
+)
+
+// Bloom is an approximate set of ids using a fixed amount of memory.
+// Contains is always true for added ids but can also be true, with the
+// configured probability, for ids that were not added. It is not safe
+// for concurrent writes.
+type Bloom struct {
+	bits []uint64
+	m    uint64
+	k    uint64
+}
+
+// NewBloom creates a bloom filter sized for n ids with a false
+// positive rate p, e.g. 0.01. It uses about -n*ln(p)/ln(2)^2 bits,
+// 1.2 bytes per id for 1%.
+func NewBloom(n int, p float64) *Bloom {
+	if n < 1 {
+		n = 1
+	}
+
+	if p <= 0 || p >= 1 {
+		p = 0.01
+	}
+
+	m := uint64(math.Ceil(-float64(n) * math.Log(p) / (math.Ln2 * math.Ln2)))
+	if m < 64 {
+		m = 64
+	}
+
+	k := uint64(math.Round(float64(m) / float64(n) * math.Ln2))
+	if k < 1 {
+		k = 1
+	}
+
+	return &Bloom{
+		bits: make([]uint64, (m+63)/64),
+		m:    m,
+		k:    k,
+	}
+}
+
+// Add adds the id to the set.
+func (b *Bloom) Add(id int64) {
+	h1, h2 := hashes(id)
+	for i := uint64(0); i < b.k; i++ {
+		bit := (h1 + i*h2) % b.m
+		b.bits[bit/64] |= 1 << (bit % 64)
+	}
+}
+
+// Contains returns true if the id was probably added
+// and false if it was definitely not.
+func (b *Bloom) Contains(id int64) bool {
+	h1, h2 := hashes(id)
+	for i := uint64(0); i < b.k; i++ {
+		bit := (h1 + i*h2) % b.m
+		if b.bits[bit/64]&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ErrBloomSize is returned when combining bloom filters
+// that were not created with the same size and rate.
+var ErrBloomSize = errors.New("idset: bloom filters must have the same size")
+
+// Union returns a new filter containing the ids of both filters.
+// The false positive rate is that of a filter with all the ids added.
+func (b *Bloom) Union(other *Bloom) (*Bloom, error) {
+	if b.m != other.m || b.k != other.k {
+		return nil, ErrBloomSize
+	}
+
+	result := &Bloom{bits: make([]uint64, len(b.bits)), m: b.m, k: b.k}
+	for i := range b.bits {
+		result.bits[i] = b.bits[i] | other.bits[i]
+	}
+
+	return result, nil
+}
+
+// Intersect returns a new filter containing the ids in both filters.
+// The false positive rate is at most that of the input filters.
+func (b *Bloom) Intersect(other *Bloom) (*Bloom, error) {
+	if b.m != other.m || b.k != other.k {
+		return nil, ErrBloomSize
+	}
+
+	result := &Bloom{bits: make([]uint64, len(b.bits)), m: b.m, k: b.k}
+	for i := range b.bits {
+		result.bits[i] = b.bits[i] & other.bits[i]
+	}
+
+	return result, nil
+}
+
+// hashes returns the two hashes used to derive the k bit positions.
+func hashes(id int64) (uint64, uint64) {
+	h1 := mix(uint64(id))
+	h2 := mix(h1) | 1
+
+	return h1, h2
+}
+
+// mix is the splitmix64 finalizer.
+func mix(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+
+	return x
+}
@@ -0,0 +1,89 @@
+package idset
+
+import "testing"
+
+func TestBloom(t *testing.T) {
+	n := 10000
+	b := NewBloom(n, 0.01)
+
+	for i := 0; i < n; i++ {
+		b.Add(int64(i) * 7)
+	}
+
+	for i := 0; i < n; i++ {
+		if !b.Contains(int64(i) * 7) {
+			t.Fatalf("should contain %v", i*7)
+		}
+	}
+
+	falsePositives := 0
+	for i := 0; i < n; i++ {
+		if b.Contains(int64(i)*7 + 1) {
+			falsePositives++
+		}
+	}
+
+	if rate := float64(falsePositives) / float64(n); rate > 0.02 {
+		t.Errorf("false positive rate too high: %v", rate)
+	}
+}
+
+func TestNewBloom(t *testing.T) {
+	b := NewBloom(0, 0)
+	if b.m != 64 || b.k < 1 {
+		t.Errorf("should use minimum size: %v %v", b.m, b.k)
+	}
+
+	b = NewBloom(1000000, 0.01)
+	if bytes := len(b.bits) * 8; bytes > 1300000 {
+		t.Errorf("too much memory: %v", bytes)
+	}
+}
+
+func TestBloom_UnionIntersect(t *testing.T) {
+	a, b := NewBloom(1000, 0.001), NewBloom(1000, 0.001)
+	for i := int64(0); i < 500; i++ {
+		a.Add(i)
+		b.Add(i + 250)
+	}
+
+	u, err := a.Union(b)
+	if err != nil {
+		t.Fatalf("union error: %v", err)
+	}
+
+	for i := int64(0); i < 750; i++ {
+		if !u.Contains(i) {
+			t.Errorf("union should contain %v", i)
+		}
+	}
+
+	in, err := a.Intersect(b)
+	if err != nil {
+		t.Fatalf("intersect error: %v", err)
+	}
+
+	falsePositives := 0
+	for i := int64(0); i < 750; i++ {
+		c := in.Contains(i)
+		if i >= 250 && i < 500 && !c {
+			t.Errorf("intersection should contain %v", i)
+		}
+
+		if (i < 250 || i >= 500) && c {
+			falsePositives++
+		}
+	}
+
+	if falsePositives > 10 {
+		t.Errorf("too many false positives: %v", falsePositives)
+	}
+
+	if _, err := a.Union(NewBloom(10, 0.1)); err != ErrBloomSize {
+		t.Errorf("should error for different sizes: %v", err)
+	}
+
+	if _, err := a.Intersect(NewBloom(10, 0.1)); err != ErrBloomSize {
+		t.Errorf("should error for different sizes: %v", err)
+	}
+}
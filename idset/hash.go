@@ -0,0 +1,67 @@
+package idset
+
+// Hash is an exact set of ids backed by a map. It is the fastest for
+// small sets but uses the most memory, around 40 bytes per id.
+type Hash map[int64]struct{}
+
+// NewHash creates an empty hash set.
+func NewHash() Hash {
+	return make(Hash)
+}
+
+// Add adds the id to the set.
+func (h Hash) Add(id int64) {
+	h[id] = struct{}{}
+}
+
+// Contains returns true if the id is in the set.
+func (h Hash) Contains(id int64) bool {
+	_, ok := h[id]
+	return ok
+}
+
+// Len returns the number of ids in the set.
+func (h Hash) Len() int {
+	return len(h)
+}
+
+// Each calls the function for every id, in no particular order,
+// until it returns false.
+func (h Hash) Each(f func(id int64) bool) {
+	for id := range h {
+		if !f(id) {
+			return
+		}
+	}
+}
+
+// Union returns a new set with the ids in either set.
+func (h Hash) Union(other Hash) Hash {
+	result := make(Hash, len(h)+len(other))
+	for id := range h {
+		result[id] = struct{}{}
+	}
+
+	for id := range other {
+		result[id] = struct{}{}
+	}
+
+	return result
+}
+
+// Intersect returns a new set with the ids in both sets.
+func (h Hash) Intersect(other Hash) Hash {
+	a, b := h, other
+	if len(b) < len(a) {
+		a, b = b, a
+	}
+
+	result := make(Hash)
+	for id := range a {
+		if _, ok := b[id]; ok {
+			result[id] = struct{}{}
+		}
+	}
+
+	return result
+}
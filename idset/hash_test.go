@@ -0,0 +1,41 @@
+package idset
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestHash(t *testing.T) {
+	a := NewHash()
+	a.Add(1)
+	a.Add(2)
+	a.Add(2)
+
+	if a.Len() != 2 || !a.Contains(1) || a.Contains(3) {
+		t.Errorf("incorrect set: %v", a)
+	}
+
+	b := NewHash()
+	b.Add(2)
+	b.Add(3)
+
+	if v := ids(a.Union(b)); !reflect.DeepEqual(v, []int64{1, 2, 3}) {
+		t.Errorf("incorrect union: %v", v)
+	}
+
+	if v := ids(a.Intersect(b)); !reflect.DeepEqual(v, []int64{2}) {
+		t.Errorf("incorrect intersection: %v", v)
+	}
+}
+
+func ids(h Hash) []int64 {
+	var result []int64
+	h.Each(func(id int64) bool {
+		result = append(result, id)
+		return true
+	})
+	sort.Slice(result, func(i, j int) bool { return result[i] < result[j] })
+
+	return result
+}
@@ -0,0 +1,20 @@
+// Package idset provides memory efficient sets of element ids for tools
+// that track hundreds of millions of them, e.g. the nodes needed by an
+// extract. A map[int64]bool uses around 40 bytes per id, the Bitmap uses
+// as little as one bit for dense ids and the Bloom filter a fixed amount
+// of memory with some false positives. Hash is a plain map based set for
+// small or very sparse sets.
+package idset
+
+// An IDSet is a set of ids. The ids are int64 so the node, way and
+// relation ids can be used, e.g. int64(node.ID).
+type IDSet interface {
+	Add(id int64)
+	Contains(id int64) bool
+}
+
+var (
+	_ IDSet = &Bitmap{}
+	_ IDSet = &Bloom{}
+	_ IDSet = Hash{}
+)
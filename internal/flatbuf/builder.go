@@ -0,0 +1,241 @@
+// Package flatbuf is a minimal FlatBuffers builder shared by the
+// packages writing FlatBuffers based formats.
+package flatbuf
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// Builder is a minimal FlatBuffers builder supporting the tables, vectors
+// and scalars needed to write FlatGeobuf and Arrow IPC metadata. Like the
+// official implementation the buffer is built back to front.
+type Builder struct {
+	buf      []byte
+	head     int
+	minalign int
+
+	vtable    []int
+	objectEnd int
+}
+
+// NewBuilder creates a builder with an initial buffer of the given size.
+func NewBuilder(size int) *Builder {
+	return &Builder{
+		buf:      make([]byte, size),
+		head:     size,
+		minalign: 1,
+	}
+}
+
+// offset returns the offset of the current head from the end of the buffer.
+func (b *Builder) offset() int {
+	return len(b.buf) - b.head
+}
+
+func (b *Builder) grow() {
+	size := len(b.buf) * 2
+	if size == 0 {
+		size = 64
+	}
+
+	buf := make([]byte, size)
+	copy(buf[size-len(b.buf):], b.buf)
+	b.head += size - len(b.buf)
+	b.buf = buf
+}
+
+// prep aligns the head so a value of the given size can be written
+// after additional bytes have been written.
+func (b *Builder) prep(size, additional int) {
+	if size > b.minalign {
+		b.minalign = size
+	}
+
+	align := (^(b.offset() + additional) + 1) & (size - 1)
+	for b.head < align+size+additional {
+		b.grow()
+	}
+
+	for i := 0; i < align; i++ {
+		b.head--
+		b.buf[b.head] = 0
+	}
+}
+
+func (b *Builder) placeUint8(v uint8) {
+	b.head--
+	b.buf[b.head] = v
+}
+
+func (b *Builder) placeUint16(v uint16) {
+	b.head -= 2
+	binary.LittleEndian.PutUint16(b.buf[b.head:], v)
+}
+
+func (b *Builder) placeUint32(v uint32) {
+	b.head -= 4
+	binary.LittleEndian.PutUint32(b.buf[b.head:], v)
+}
+
+func (b *Builder) placeUint64(v uint64) {
+	b.head -= 8
+	binary.LittleEndian.PutUint64(b.buf[b.head:], v)
+}
+
+func (b *Builder) prependUOffset(off int) {
+	b.prep(4, 0)
+	b.placeUint32(uint32(b.offset() - off + 4))
+}
+
+// CreateString writes a null terminated string and returns its offset.
+func (b *Builder) CreateString(s string) int {
+	b.prep(4, len(s)+1)
+	b.placeUint8(0)
+	b.head -= len(s)
+	copy(b.buf[b.head:], s)
+	b.placeUint32(uint32(len(s)))
+
+	return b.offset()
+}
+
+// CreateBytes writes a byte vector and returns its offset.
+func (b *Builder) CreateBytes(data []byte) int {
+	b.prep(4, len(data))
+	b.head -= len(data)
+	copy(b.buf[b.head:], data)
+	b.placeUint32(uint32(len(data)))
+
+	return b.offset()
+}
+
+// CreateFloat64s writes a vector of doubles and returns its offset.
+func (b *Builder) CreateFloat64s(vals []float64) int {
+	b.prep(4, 8*len(vals))
+	b.prep(8, 8*len(vals))
+	for i := len(vals) - 1; i >= 0; i-- {
+		b.placeUint64(math.Float64bits(vals[i]))
+	}
+	b.placeUint32(uint32(len(vals)))
+
+	return b.offset()
+}
+
+// CreateUint32s writes a vector of uint32s and returns its offset.
+func (b *Builder) CreateUint32s(vals []uint32) int {
+	b.prep(4, 4*len(vals))
+	for i := len(vals) - 1; i >= 0; i-- {
+		b.placeUint32(vals[i])
+	}
+	b.placeUint32(uint32(len(vals)))
+
+	return b.offset()
+}
+
+// CreateOffsets writes a vector of tables, or strings, and returns its offset.
+func (b *Builder) CreateOffsets(offsets []int) int {
+	b.prep(4, 4*len(offsets))
+	for i := len(offsets) - 1; i >= 0; i-- {
+		b.prependUOffset(offsets[i])
+	}
+	b.placeUint32(uint32(len(offsets)))
+
+	return b.offset()
+}
+
+// CreateInt64Structs writes a vector of structs where every struct is made up
+// of the given number of int64 fields. The values are the flattened fields.
+func (b *Builder) CreateInt64Structs(vals []int64, fields int) int {
+	b.prep(4, 8*len(vals))
+	b.prep(8, 8*len(vals))
+	for i := len(vals) - 1; i >= 0; i-- {
+		b.placeUint64(uint64(vals[i]))
+	}
+	b.placeUint32(uint32(len(vals) / fields))
+
+	return b.offset()
+}
+
+// StartTable starts a table with the given number of fields. The table
+// fields must be added, after any child objects, before calling EndTable.
+func (b *Builder) StartTable(fields int) {
+	b.vtable = make([]int, fields)
+	b.objectEnd = b.offset()
+}
+
+// AddUint8 adds a ubyte, or bool, field to the current table.
+func (b *Builder) AddUint8(field int, v uint8) {
+	b.prep(1, 0)
+	b.placeUint8(v)
+	b.vtable[field] = b.offset()
+}
+
+// AddUint16 adds a ushort, or short, field to the current table.
+func (b *Builder) AddUint16(field int, v uint16) {
+	b.prep(2, 0)
+	b.placeUint16(v)
+	b.vtable[field] = b.offset()
+}
+
+// AddInt32 adds an int field to the current table.
+func (b *Builder) AddInt32(field int, v int32) {
+	b.prep(4, 0)
+	b.placeUint32(uint32(v))
+	b.vtable[field] = b.offset()
+}
+
+// AddUint64 adds a ulong, or long, field to the current table.
+func (b *Builder) AddUint64(field int, v uint64) {
+	b.prep(8, 0)
+	b.placeUint64(v)
+	b.vtable[field] = b.offset()
+}
+
+// AddOffset adds a reference to a table, string or vector to the current table.
+func (b *Builder) AddOffset(field int, off int) {
+	b.prependUOffset(off)
+	b.vtable[field] = b.offset()
+}
+
+// EndTable writes the vtable for the current table and returns its offset.
+func (b *Builder) EndTable() int {
+	// placeholder for the offset to the vtable
+	b.prep(4, 0)
+	b.placeUint32(0)
+	object := b.offset()
+
+	last := len(b.vtable) - 1
+	for last >= 0 && b.vtable[last] == 0 {
+		last--
+	}
+
+	for i := last; i >= 0; i-- {
+		off := 0
+		if b.vtable[i] != 0 {
+			off = object - b.vtable[i]
+		}
+
+		b.prep(2, 0)
+		b.placeUint16(uint16(off))
+	}
+
+	b.prep(2, 0)
+	b.placeUint16(uint16(object - b.objectEnd))
+	b.prep(2, 0)
+	b.placeUint16(uint16((last + 3) * 2))
+
+	// the vtable is written before the table, so the soffset is positive
+	pos := len(b.buf) - object
+	binary.LittleEndian.PutUint32(b.buf[pos:], uint32(b.offset()-object))
+
+	b.vtable = nil
+	return object
+}
+
+// Finish writes the root table offset and returns the finished buffer.
+func (b *Builder) Finish(root int) []byte {
+	b.prep(b.minalign, 4)
+	b.prependUOffset(root)
+
+	return b.buf[b.head:]
+}
@@ -91,9 +91,19 @@ type Changeset struct {
 	Open      *bool    `protobuf:"varint,9,opt,name=open" json:"open,omitempty"`
 	Bounds    *Bounds  `protobuf:"bytes,10,opt,name=bounds" json:"bounds,omitempty"`
 	Change    *Change  `protobuf:"bytes,11,opt,name=change" json:"change,omitempty"`
+	// the discussion and counts, not part of the change data.
+	ChangesCount  *int32              `protobuf:"varint,12,opt,name=changes_count,json=changesCount" json:"changes_count,omitempty"`
+	CommentsCount *int32              `protobuf:"varint,13,opt,name=comments_count,json=commentsCount" json:"comments_count,omitempty"`
+	Comments      []*ChangesetComment `protobuf:"bytes,14,rep,name=comments" json:"comments,omitempty"`
 	// contains the tag strings for everything
 	// in this entire changeset.
 	Strings []string `protobuf:"bytes,20,rep,name=strings" json:"strings,omitempty"`
+	// the version of the encoding, only set on the root message.
+	SchemaVersion *uint32 `protobuf:"varint,16,opt,name=schema_version,json=schemaVersion" json:"schema_version,omitempty"`
+	// crc32c of the preceding bytes, only set on the root message.
+	Checksum *uint32 `protobuf:"fixed32,17,opt,name=checksum" json:"checksum,omitempty"`
+	// the version of the shared string dictionary, if used.
+	Dictionary *uint32 `protobuf:"varint,19,opt,name=dictionary" json:"dictionary,omitempty"`
 }
 
 func (m *Changeset) Reset()                    { *m = Changeset{} }
@@ -171,6 +181,27 @@ func (m *Changeset) GetChange() *Change {
 	return nil
 }
 
+func (m *Changeset) GetChangesCount() int32 {
+	if m != nil && m.ChangesCount != nil {
+		return *m.ChangesCount
+	}
+	return 0
+}
+
+func (m *Changeset) GetCommentsCount() int32 {
+	if m != nil && m.CommentsCount != nil {
+		return *m.CommentsCount
+	}
+	return 0
+}
+
+func (m *Changeset) GetComments() []*ChangesetComment {
+	if m != nil {
+		return m.Comments
+	}
+	return nil
+}
+
 func (m *Changeset) GetStrings() []string {
 	if m != nil {
 		return m.Strings
@@ -178,6 +209,66 @@ func (m *Changeset) GetStrings() []string {
 	return nil
 }
 
+func (m *Changeset) GetSchemaVersion() uint32 {
+	if m != nil && m.SchemaVersion != nil {
+		return *m.SchemaVersion
+	}
+	return 0
+}
+
+func (m *Changeset) GetChecksum() uint32 {
+	if m != nil && m.Checksum != nil {
+		return *m.Checksum
+	}
+	return 0
+}
+
+func (m *Changeset) GetDictionary() uint32 {
+	if m != nil && m.Dictionary != nil {
+		return *m.Dictionary
+	}
+	return 0
+}
+
+type ChangesetComment struct {
+	UserId    *int32  `protobuf:"varint,1,opt,name=user_id,json=userId" json:"user_id,omitempty"`
+	UserSid   *uint32 `protobuf:"varint,2,opt,name=user_sid,json=userSid" json:"user_sid,omitempty"`
+	Timestamp *int64  `protobuf:"varint,3,opt,name=timestamp" json:"timestamp,omitempty"`
+	Text      *string `protobuf:"bytes,4,opt,name=text" json:"text,omitempty"`
+}
+
+func (m *ChangesetComment) Reset()         { *m = ChangesetComment{} }
+func (m *ChangesetComment) String() string { return proto.CompactTextString(m) }
+func (*ChangesetComment) ProtoMessage()    {}
+
+func (m *ChangesetComment) GetUserId() int32 {
+	if m != nil && m.UserId != nil {
+		return *m.UserId
+	}
+	return 0
+}
+
+func (m *ChangesetComment) GetUserSid() uint32 {
+	if m != nil && m.UserSid != nil {
+		return *m.UserSid
+	}
+	return 0
+}
+
+func (m *ChangesetComment) GetTimestamp() int64 {
+	if m != nil && m.Timestamp != nil {
+		return *m.Timestamp
+	}
+	return 0
+}
+
+func (m *ChangesetComment) GetText() string {
+	if m != nil && m.Text != nil {
+		return *m.Text
+	}
+	return ""
+}
+
 type Bounds struct {
 	MinLon int64 `protobuf:"zigzag64,1,req,name=min_lon,json=minLon" json:"min_lon"`
 	MaxLon int64 `protobuf:"zigzag64,2,req,name=max_lon,json=maxLon" json:"max_lon"`
@@ -227,6 +318,12 @@ type Change struct {
 	Context *OSM `protobuf:"bytes,4,opt,name=context" json:"context,omitempty"`
 	// contains the tag strings if this is the root of the data.
 	Strings []string `protobuf:"bytes,20,rep,name=strings" json:"strings,omitempty"`
+	// the version of the encoding, only set on the root message.
+	SchemaVersion *uint32 `protobuf:"varint,16,opt,name=schema_version,json=schemaVersion" json:"schema_version,omitempty"`
+	// crc32c of the preceding bytes, only set on the root message.
+	Checksum *uint32 `protobuf:"fixed32,17,opt,name=checksum" json:"checksum,omitempty"`
+	// the version of the shared string dictionary, if used.
+	Dictionary *uint32 `protobuf:"varint,19,opt,name=dictionary" json:"dictionary,omitempty"`
 }
 
 func (m *Change) Reset()                    { *m = Change{} }
@@ -269,6 +366,27 @@ func (m *Change) GetStrings() []string {
 	return nil
 }
 
+func (m *Change) GetSchemaVersion() uint32 {
+	if m != nil && m.SchemaVersion != nil {
+		return *m.SchemaVersion
+	}
+	return 0
+}
+
+func (m *Change) GetChecksum() uint32 {
+	if m != nil && m.Checksum != nil {
+		return *m.Checksum
+	}
+	return 0
+}
+
+func (m *Change) GetDictionary() uint32 {
+	if m != nil && m.Dictionary != nil {
+		return *m.Dictionary
+	}
+	return 0
+}
+
 type Tags struct {
 	// encoded as [key1, val1, key2, val2, etc.]
 	KeysVals []string `protobuf:"bytes,1,rep,name=keys_vals,json=keysVals" json:"keys_vals,omitempty"`
@@ -295,6 +413,12 @@ type OSM struct {
 	Relations  []*Relation `protobuf:"bytes,5,rep,name=relations" json:"relations,omitempty"`
 	// contains the tag strings if this is the root of the data.
 	Strings []string `protobuf:"bytes,15,rep,name=strings" json:"strings,omitempty"`
+	// the version of the encoding, only set on the root message.
+	SchemaVersion *uint32 `protobuf:"varint,16,opt,name=schema_version,json=schemaVersion" json:"schema_version,omitempty"`
+	// crc32c of the preceding bytes, only set on the root message.
+	Checksum *uint32 `protobuf:"fixed32,17,opt,name=checksum" json:"checksum,omitempty"`
+	// the version of the shared string dictionary, if used.
+	Dictionary *uint32 `protobuf:"varint,19,opt,name=dictionary" json:"dictionary,omitempty"`
 }
 
 func (m *OSM) Reset()                    { *m = OSM{} }
@@ -344,6 +468,27 @@ func (m *OSM) GetStrings() []string {
 	return nil
 }
 
+func (m *OSM) GetSchemaVersion() uint32 {
+	if m != nil && m.SchemaVersion != nil {
+		return *m.SchemaVersion
+	}
+	return 0
+}
+
+func (m *OSM) GetChecksum() uint32 {
+	if m != nil && m.Checksum != nil {
+		return *m.Checksum
+	}
+	return 0
+}
+
+func (m *OSM) GetDictionary() uint32 {
+	if m != nil && m.Dictionary != nil {
+		return *m.Dictionary
+	}
+	return 0
+}
+
 type Node struct {
 	Id int64 `protobuf:"varint,1,req,name=id" json:"id"`
 	// Parallel arrays.
@@ -418,6 +563,8 @@ type Info struct {
 	// the time this element was committed into the db. Could be much later than
 	// timestamp for large uploads.
 	Committed *int64 `protobuf:"varint,7,opt,name=committed" json:"committed,omitempty"`
+	// the sub-second part of the timestamp in milliseconds.
+	TimestampMs *int32 `protobuf:"varint,8,opt,name=timestamp_ms,json=timestampMs" json:"timestamp_ms,omitempty"`
 }
 
 func (m *Info) Reset()                    { *m = Info{} }
@@ -474,6 +621,13 @@ func (m *Info) GetCommitted() int64 {
 	return 0
 }
 
+func (m *Info) GetTimestampMs() int32 {
+	if m != nil && m.TimestampMs != nil {
+		return *m.TimestampMs
+	}
+	return 0
+}
+
 type DenseNodes struct {
 	Ids       []int64    `protobuf:"zigzag64,1,rep,packed,name=ids" json:"ids,omitempty"`
 	DenseInfo *DenseInfo `protobuf:"bytes,5,opt,name=dense_info,json=denseInfo" json:"dense_info,omitempty"`
@@ -486,6 +640,12 @@ type DenseNodes struct {
 	// not contain any delimiters, but is simply empty.
 	KeysVals []uint32 `protobuf:"varint,10,rep,packed,name=keys_vals,json=keysVals" json:"keys_vals,omitempty"`
 	Strings  []string `protobuf:"bytes,15,rep,name=strings" json:"strings,omitempty"`
+	// the version of the encoding, only set on the root message.
+	SchemaVersion *uint32 `protobuf:"varint,16,opt,name=schema_version,json=schemaVersion" json:"schema_version,omitempty"`
+	// crc32c of the preceding bytes, only set on the root message.
+	Checksum *uint32 `protobuf:"fixed32,17,opt,name=checksum" json:"checksum,omitempty"`
+	// the version of the shared string dictionary, if used.
+	Dictionary *uint32 `protobuf:"varint,19,opt,name=dictionary" json:"dictionary,omitempty"`
 }
 
 func (m *DenseNodes) Reset()                    { *m = DenseNodes{} }
@@ -535,6 +695,27 @@ func (m *DenseNodes) GetStrings() []string {
 	return nil
 }
 
+func (m *DenseNodes) GetSchemaVersion() uint32 {
+	if m != nil && m.SchemaVersion != nil {
+		return *m.SchemaVersion
+	}
+	return 0
+}
+
+func (m *DenseNodes) GetChecksum() uint32 {
+	if m != nil && m.Checksum != nil {
+		return *m.Checksum
+	}
+	return 0
+}
+
+func (m *DenseNodes) GetDictionary() uint32 {
+	if m != nil && m.Dictionary != nil {
+		return *m.Dictionary
+	}
+	return 0
+}
+
 type DenseInfo struct {
 	Versions   []int32 `protobuf:"varint,1,rep,packed,name=versions" json:"versions,omitempty"`
 	Timestamps []int64 `protobuf:"zigzag64,2,rep,packed,name=timestamps" json:"timestamps,omitempty"`
@@ -551,6 +732,9 @@ type DenseInfo struct {
 	// the time this element was committed into the db. Could be much later than
 	// timestamp for large uploads.
 	Committeds []int64 `protobuf:"zigzag64,7,rep,packed,name=committeds" json:"committeds,omitempty"`
+	// the sub-second part of the timestamps in milliseconds.
+	// Omitted if all the timestamps are whole seconds.
+	TimestampMs []int32 `protobuf:"varint,8,rep,packed,name=timestamp_ms,json=timestampMs" json:"timestamp_ms,omitempty"`
 }
 
 func (m *DenseInfo) Reset()                    { *m = DenseInfo{} }
@@ -607,6 +791,13 @@ func (m *DenseInfo) GetCommitteds() []int64 {
 	return nil
 }
 
+func (m *DenseInfo) GetTimestampMs() []int32 {
+	if m != nil {
+		return m.TimestampMs
+	}
+	return nil
+}
+
 type Way struct {
 	Id int64 `protobuf:"varint,1,req,name=id" json:"id"`
 	// Parallel arrays.
@@ -771,6 +962,8 @@ type DenseMembers struct {
 	Timestamps   []int64 `protobuf:"zigzag64,3,rep,packed,name=timestamps" json:"timestamps,omitempty"`
 	ChangesetIds []int64 `protobuf:"zigzag64,4,rep,packed,name=changeset_ids,json=changesetIds" json:"changeset_ids,omitempty"`
 	Orientation  []int32 `protobuf:"zigzag32,5,rep,packed,name=orientation" json:"orientation,omitempty"`
+	// used instead of versions by the updates
+	VersionDeltas []int32 `protobuf:"zigzag32,6,rep,packed,name=version_deltas,json=versionDeltas" json:"version_deltas,omitempty"`
 	// included if some of the members are nodes
 	Lats []int64 `protobuf:"zigzag64,8,rep,packed,name=lats" json:"lats,omitempty"`
 	Lons []int64 `protobuf:"zigzag64,9,rep,packed,name=lons" json:"lons,omitempty"`
@@ -816,6 +1009,13 @@ func (m *DenseMembers) GetOrientation() []int32 {
 	return nil
 }
 
+func (m *DenseMembers) GetVersionDeltas() []int32 {
+	if m != nil {
+		return m.VersionDeltas
+	}
+	return nil
+}
+
 func (m *DenseMembers) GetLats() []int64 {
 	if m != nil {
 		return m.Lats
@@ -832,6 +1032,7 @@ func (m *DenseMembers) GetLons() []int64 {
 
 func init() {
 	proto.RegisterType((*Changeset)(nil), "osm.Changeset")
+	proto.RegisterType((*ChangesetComment)(nil), "osm.ChangesetComment")
 	proto.RegisterType((*Bounds)(nil), "osm.Bounds")
 	proto.RegisterType((*Change)(nil), "osm.Change")
 	proto.RegisterType((*Tags)(nil), "osm.Tags")
@@ -949,6 +1150,28 @@ func (m *Changeset) MarshalTo(dAtA []byte) (int, error) {
 		}
 		i += n6
 	}
+	if m.ChangesCount != nil {
+		dAtA[i] = 0x60
+		i++
+		i = encodeVarintOsm(dAtA, i, uint64(*m.ChangesCount))
+	}
+	if m.CommentsCount != nil {
+		dAtA[i] = 0x68
+		i++
+		i = encodeVarintOsm(dAtA, i, uint64(*m.CommentsCount))
+	}
+	if len(m.Comments) > 0 {
+		for _, msg := range m.Comments {
+			dAtA[i] = 0x72
+			i++
+			i = encodeVarintOsm(dAtA, i, uint64(msg.Size()))
+			n, err := msg.MarshalTo(dAtA[i:])
+			if err != nil {
+				return 0, err
+			}
+			i += n
+		}
+	}
 	if len(m.Strings) > 0 {
 		for _, s := range m.Strings {
 			dAtA[i] = 0xa2
@@ -966,6 +1189,66 @@ func (m *Changeset) MarshalTo(dAtA []byte) (int, error) {
 			i += copy(dAtA[i:], s)
 		}
 	}
+	if m.SchemaVersion != nil {
+		dAtA[i] = 0x80
+		i++
+		dAtA[i] = 0x1
+		i++
+		i = encodeVarintOsm(dAtA, i, uint64(*m.SchemaVersion))
+	}
+	if m.Checksum != nil {
+		dAtA[i] = 0x8d
+		i++
+		dAtA[i] = 0x1
+		i++
+		i = encodeFixed32Osm(dAtA, i, uint32(*m.Checksum))
+	}
+	if m.Dictionary != nil {
+		dAtA[i] = 0x98
+		i++
+		dAtA[i] = 0x1
+		i++
+		i = encodeVarintOsm(dAtA, i, uint64(*m.Dictionary))
+	}
+	return i, nil
+}
+
+func (m *ChangesetComment) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *ChangesetComment) MarshalTo(dAtA []byte) (int, error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	if m.UserId != nil {
+		dAtA[i] = 0x8
+		i++
+		i = encodeVarintOsm(dAtA, i, uint64(*m.UserId))
+	}
+	if m.UserSid != nil {
+		dAtA[i] = 0x10
+		i++
+		i = encodeVarintOsm(dAtA, i, uint64(*m.UserSid))
+	}
+	if m.Timestamp != nil {
+		dAtA[i] = 0x18
+		i++
+		i = encodeVarintOsm(dAtA, i, uint64(*m.Timestamp))
+	}
+	if m.Text != nil {
+		dAtA[i] = 0x22
+		i++
+		i = encodeVarintOsm(dAtA, i, uint64(len(*m.Text)))
+		i += copy(dAtA[i:], *m.Text)
+	}
 	return i, nil
 }
 
@@ -1071,6 +1354,27 @@ func (m *Change) MarshalTo(dAtA []byte) (int, error) {
 			i += copy(dAtA[i:], s)
 		}
 	}
+	if m.SchemaVersion != nil {
+		dAtA[i] = 0x80
+		i++
+		dAtA[i] = 0x1
+		i++
+		i = encodeVarintOsm(dAtA, i, uint64(*m.SchemaVersion))
+	}
+	if m.Checksum != nil {
+		dAtA[i] = 0x8d
+		i++
+		dAtA[i] = 0x1
+		i++
+		i = encodeFixed32Osm(dAtA, i, uint32(*m.Checksum))
+	}
+	if m.Dictionary != nil {
+		dAtA[i] = 0x98
+		i++
+		dAtA[i] = 0x1
+		i++
+		i = encodeVarintOsm(dAtA, i, uint64(*m.Dictionary))
+	}
 	return i, nil
 }
 
@@ -1193,6 +1497,27 @@ func (m *OSM) MarshalTo(dAtA []byte) (int, error) {
 			i += copy(dAtA[i:], s)
 		}
 	}
+	if m.SchemaVersion != nil {
+		dAtA[i] = 0x80
+		i++
+		dAtA[i] = 0x1
+		i++
+		i = encodeVarintOsm(dAtA, i, uint64(*m.SchemaVersion))
+	}
+	if m.Checksum != nil {
+		dAtA[i] = 0x8d
+		i++
+		dAtA[i] = 0x1
+		i++
+		i = encodeFixed32Osm(dAtA, i, uint32(*m.Checksum))
+	}
+	if m.Dictionary != nil {
+		dAtA[i] = 0x98
+		i++
+		dAtA[i] = 0x1
+		i++
+		i = encodeVarintOsm(dAtA, i, uint64(*m.Dictionary))
+	}
 	return i, nil
 }
 
@@ -1312,6 +1637,11 @@ func (m *Info) MarshalTo(dAtA []byte) (int, error) {
 		i++
 		i = encodeVarintOsm(dAtA, i, uint64(*m.Committed))
 	}
+	if m.TimestampMs != nil {
+		dAtA[i] = 0x40
+		i++
+		i = encodeVarintOsm(dAtA, i, uint64(*m.TimestampMs))
+	}
 	return i, nil
 }
 
@@ -1426,6 +1756,27 @@ func (m *DenseNodes) MarshalTo(dAtA []byte) (int, error) {
 			i += copy(dAtA[i:], s)
 		}
 	}
+	if m.SchemaVersion != nil {
+		dAtA[i] = 0x80
+		i++
+		dAtA[i] = 0x1
+		i++
+		i = encodeVarintOsm(dAtA, i, uint64(*m.SchemaVersion))
+	}
+	if m.Checksum != nil {
+		dAtA[i] = 0x8d
+		i++
+		dAtA[i] = 0x1
+		i++
+		i = encodeFixed32Osm(dAtA, i, uint32(*m.Checksum))
+	}
+	if m.Dictionary != nil {
+		dAtA[i] = 0x98
+		i++
+		dAtA[i] = 0x1
+		i++
+		i = encodeVarintOsm(dAtA, i, uint64(*m.Dictionary))
+	}
 	return i, nil
 }
 
@@ -1565,6 +1916,24 @@ func (m *DenseInfo) MarshalTo(dAtA []byte) (int, error) {
 		i = encodeVarintOsm(dAtA, i, uint64(j44))
 		i += copy(dAtA[i:], dAtA46[:j44])
 	}
+	if len(m.TimestampMs) > 0 {
+		dAtA48 := make([]byte, len(m.TimestampMs)*10)
+		var j47 int
+		for _, num1 := range m.TimestampMs {
+			num := uint64(num1)
+			for num >= 1<<7 {
+				dAtA48[j47] = uint8(uint64(num)&0x7f | 0x80)
+				num >>= 7
+				j47++
+			}
+			dAtA48[j47] = uint8(num)
+			j47++
+		}
+		dAtA[i] = 0x42
+		i++
+		i = encodeVarintOsm(dAtA, i, uint64(j47))
+		i += copy(dAtA[i:], dAtA48[:j47])
+	}
 	return i, nil
 }
 
@@ -1913,6 +2282,24 @@ func (m *DenseMembers) MarshalTo(dAtA []byte) (int, error) {
 		i = encodeVarintOsm(dAtA, i, uint64(j83))
 		i += copy(dAtA[i:], dAtA82[:j83])
 	}
+	if len(m.VersionDeltas) > 0 {
+		dAtA83 := make([]byte, len(m.VersionDeltas)*5)
+		var j84 int
+		for _, num := range m.VersionDeltas {
+			x85 := (uint32(num) << 1) ^ uint32((num >> 31))
+			for x85 >= 1<<7 {
+				dAtA83[j84] = uint8(uint64(x85)&0x7f | 0x80)
+				j84++
+				x85 >>= 7
+			}
+			dAtA83[j84] = uint8(x85)
+			j84++
+		}
+		dAtA[i] = 0x32
+		i++
+		i = encodeVarintOsm(dAtA, i, uint64(j84))
+		i += copy(dAtA[i:], dAtA83[:j84])
+	}
 	if len(m.Lats) > 0 {
 		var j85 int
 		dAtA87 := make([]byte, len(m.Lats)*10)
@@ -2022,12 +2409,52 @@ func (m *Changeset) Size() (n int) {
 		l = m.Change.Size()
 		n += 1 + l + sovOsm(uint64(l))
 	}
+	if m.ChangesCount != nil {
+		n += 1 + sovOsm(uint64(*m.ChangesCount))
+	}
+	if m.CommentsCount != nil {
+		n += 1 + sovOsm(uint64(*m.CommentsCount))
+	}
+	if len(m.Comments) > 0 {
+		for _, e := range m.Comments {
+			l = e.Size()
+			n += 1 + l + sovOsm(uint64(l))
+		}
+	}
 	if len(m.Strings) > 0 {
 		for _, s := range m.Strings {
 			l = len(s)
 			n += 2 + l + sovOsm(uint64(l))
 		}
 	}
+	if m.SchemaVersion != nil {
+		n += 2 + sovOsm(uint64(*m.SchemaVersion))
+	}
+	if m.Checksum != nil {
+		n += 6
+	}
+	if m.Dictionary != nil {
+		n += 2 + sovOsm(uint64(*m.Dictionary))
+	}
+	return n
+}
+
+func (m *ChangesetComment) Size() (n int) {
+	var l int
+	_ = l
+	if m.UserId != nil {
+		n += 1 + sovOsm(uint64(*m.UserId))
+	}
+	if m.UserSid != nil {
+		n += 1 + sovOsm(uint64(*m.UserSid))
+	}
+	if m.Timestamp != nil {
+		n += 1 + sovOsm(uint64(*m.Timestamp))
+	}
+	if m.Text != nil {
+		l = len(*m.Text)
+		n += 1 + l + sovOsm(uint64(l))
+	}
 	return n
 }
 
@@ -2066,6 +2493,15 @@ func (m *Change) Size() (n int) {
 			n += 2 + l + sovOsm(uint64(l))
 		}
 	}
+	if m.SchemaVersion != nil {
+		n += 2 + sovOsm(uint64(*m.SchemaVersion))
+	}
+	if m.Checksum != nil {
+		n += 6
+	}
+	if m.Dictionary != nil {
+		n += 2 + sovOsm(uint64(*m.Dictionary))
+	}
 	return n
 }
 
@@ -2116,6 +2552,15 @@ func (m *OSM) Size() (n int) {
 			n += 1 + l + sovOsm(uint64(l))
 		}
 	}
+	if m.SchemaVersion != nil {
+		n += 2 + sovOsm(uint64(*m.SchemaVersion))
+	}
+	if m.Checksum != nil {
+		n += 6
+	}
+	if m.Dictionary != nil {
+		n += 2 + sovOsm(uint64(*m.Dictionary))
+	}
 	return n
 }
 
@@ -2160,6 +2605,9 @@ func (m *Info) Size() (n int) {
 	if m.Committed != nil {
 		n += 1 + sovOsm(uint64(*m.Committed))
 	}
+	if m.TimestampMs != nil {
+		n += 1 + sovOsm(uint64(*m.TimestampMs))
+	}
 	return n
 }
 
@@ -2204,6 +2652,15 @@ func (m *DenseNodes) Size() (n int) {
 			n += 1 + l + sovOsm(uint64(l))
 		}
 	}
+	if m.SchemaVersion != nil {
+		n += 2 + sovOsm(uint64(*m.SchemaVersion))
+	}
+	if m.Checksum != nil {
+		n += 6
+	}
+	if m.Dictionary != nil {
+		n += 2 + sovOsm(uint64(*m.Dictionary))
+	}
 	return n
 }
 
@@ -2255,6 +2712,13 @@ func (m *DenseInfo) Size() (n int) {
 		}
 		n += 1 + sovOsm(uint64(l)) + l
 	}
+	if len(m.TimestampMs) > 0 {
+		l = 0
+		for _, e := range m.TimestampMs {
+			l += sovOsm(uint64(e))
+		}
+		n += 1 + sovOsm(uint64(l)) + l
+	}
 	return n
 }
 
@@ -2390,6 +2854,13 @@ func (m *DenseMembers) Size() (n int) {
 		}
 		n += 1 + sovOsm(uint64(l)) + l
 	}
+	if len(m.VersionDeltas) > 0 {
+		l = 0
+		for _, e := range m.VersionDeltas {
+			l += sozOsm(uint64(e))
+		}
+		n += 1 + sovOsm(uint64(l)) + l
+	}
 	if len(m.Lats) > 0 {
 		l = 0
 		for _, e := range m.Lats {
@@ -2760,6 +3231,77 @@ func (m *Changeset) Unmarshal(dAtA []byte) error {
 				return err
 			}
 			iNdEx = postIndex
+		case 12:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ChangesCount", wireType)
+			}
+			var v int32
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowOsm
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= (int32(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.ChangesCount = &v
+		case 13:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field CommentsCount", wireType)
+			}
+			var v int32
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowOsm
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= (int32(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.CommentsCount = &v
+		case 14:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Comments", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowOsm
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthOsm
+			}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Comments = append(m.Comments, &ChangesetComment{})
+			if err := m.Comments[len(m.Comments)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
 		case 20:
 			if wireType != 2 {
 				return fmt.Errorf("proto: wrong wireType = %d for field Strings", wireType)
@@ -2787,7 +3329,201 @@ func (m *Changeset) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Strings = append(m.Strings, string(dAtA[iNdEx:postIndex]))
+			m.Strings = append(m.Strings, string(dAtA[iNdEx:postIndex]))
+			iNdEx = postIndex
+		case 16:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field SchemaVersion", wireType)
+			}
+			var v uint32
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowOsm
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= (uint32(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.SchemaVersion = &v
+		case 19:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Dictionary", wireType)
+			}
+			var v uint32
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowOsm
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= (uint32(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Dictionary = &v
+		case 17:
+			if wireType != 5 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Checksum", wireType)
+			}
+			var v uint32
+			if (iNdEx + 4) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += 4
+			v = uint32(dAtA[iNdEx-4])
+			v |= uint32(dAtA[iNdEx-3]) << 8
+			v |= uint32(dAtA[iNdEx-2]) << 16
+			v |= uint32(dAtA[iNdEx-1]) << 24
+			m.Checksum = &v
+		default:
+			iNdEx = preIndex
+			skippy, err := skipOsm(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthOsm
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *ChangesetComment) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowOsm
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: ChangesetComment: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: ChangesetComment: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field UserId", wireType)
+			}
+			var v int32
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowOsm
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= (int32(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.UserId = &v
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field UserSid", wireType)
+			}
+			var v uint32
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowOsm
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= (uint32(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.UserSid = &v
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Timestamp", wireType)
+			}
+			var v int64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowOsm
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= (int64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Timestamp = &v
+		case 4:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Text", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowOsm
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthOsm
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			v := string(dAtA[iNdEx:postIndex])
+			m.Text = &v
 			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
@@ -3151,6 +3887,60 @@ func (m *Change) Unmarshal(dAtA []byte) error {
 			}
 			m.Strings = append(m.Strings, string(dAtA[iNdEx:postIndex]))
 			iNdEx = postIndex
+		case 16:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field SchemaVersion", wireType)
+			}
+			var v uint32
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowOsm
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= (uint32(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.SchemaVersion = &v
+		case 19:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Dictionary", wireType)
+			}
+			var v uint32
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowOsm
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= (uint32(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Dictionary = &v
+		case 17:
+			if wireType != 5 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Checksum", wireType)
+			}
+			var v uint32
+			if (iNdEx + 4) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += 4
+			v = uint32(dAtA[iNdEx-4])
+			v |= uint32(dAtA[iNdEx-3]) << 8
+			v |= uint32(dAtA[iNdEx-2]) << 16
+			v |= uint32(dAtA[iNdEx-1]) << 24
+			m.Checksum = &v
 		default:
 			iNdEx = preIndex
 			skippy, err := skipOsm(dAtA[iNdEx:])
@@ -3468,6 +4258,60 @@ func (m *OSM) Unmarshal(dAtA []byte) error {
 			}
 			m.Strings = append(m.Strings, string(dAtA[iNdEx:postIndex]))
 			iNdEx = postIndex
+		case 16:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field SchemaVersion", wireType)
+			}
+			var v uint32
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowOsm
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= (uint32(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.SchemaVersion = &v
+		case 19:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Dictionary", wireType)
+			}
+			var v uint32
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowOsm
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= (uint32(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Dictionary = &v
+		case 17:
+			if wireType != 5 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Checksum", wireType)
+			}
+			var v uint32
+			if (iNdEx + 4) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += 4
+			v = uint32(dAtA[iNdEx-4])
+			v |= uint32(dAtA[iNdEx-3]) << 8
+			v |= uint32(dAtA[iNdEx-2]) << 16
+			v |= uint32(dAtA[iNdEx-1]) << 24
+			m.Checksum = &v
 		default:
 			iNdEx = preIndex
 			skippy, err := skipOsm(dAtA[iNdEx:])
@@ -3935,6 +4779,26 @@ func (m *Info) Unmarshal(dAtA []byte) error {
 				}
 			}
 			m.Committed = &v
+		case 8:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field TimestampMs", wireType)
+			}
+			var v int32
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowOsm
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= (int32(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.TimestampMs = &v
 		default:
 			iNdEx = preIndex
 			skippy, err := skipOsm(dAtA[iNdEx:])
@@ -4301,6 +5165,60 @@ func (m *DenseNodes) Unmarshal(dAtA []byte) error {
 			}
 			m.Strings = append(m.Strings, string(dAtA[iNdEx:postIndex]))
 			iNdEx = postIndex
+		case 16:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field SchemaVersion", wireType)
+			}
+			var v uint32
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowOsm
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= (uint32(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.SchemaVersion = &v
+		case 19:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Dictionary", wireType)
+			}
+			var v uint32
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowOsm
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= (uint32(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Dictionary = &v
+		case 17:
+			if wireType != 5 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Checksum", wireType)
+			}
+			var v uint32
+			if (iNdEx + 4) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += 4
+			v = uint32(dAtA[iNdEx-4])
+			v |= uint32(dAtA[iNdEx-3]) << 8
+			v |= uint32(dAtA[iNdEx-2]) << 16
+			v |= uint32(dAtA[iNdEx-1]) << 24
+			m.Checksum = &v
 		default:
 			iNdEx = preIndex
 			skippy, err := skipOsm(dAtA[iNdEx:])
@@ -4795,6 +5713,68 @@ func (m *DenseInfo) Unmarshal(dAtA []byte) error {
 			} else {
 				return fmt.Errorf("proto: wrong wireType = %d for field Committeds", wireType)
 			}
+		case 8:
+			if wireType == 0 {
+				var v int32
+				for shift := uint(0); ; shift += 7 {
+					if shift >= 64 {
+						return ErrIntOverflowOsm
+					}
+					if iNdEx >= l {
+						return io.ErrUnexpectedEOF
+					}
+					b := dAtA[iNdEx]
+					iNdEx++
+					v |= (int32(b) & 0x7F) << shift
+					if b < 0x80 {
+						break
+					}
+				}
+				m.TimestampMs = append(m.TimestampMs, v)
+			} else if wireType == 2 {
+				var packedLen int
+				for shift := uint(0); ; shift += 7 {
+					if shift >= 64 {
+						return ErrIntOverflowOsm
+					}
+					if iNdEx >= l {
+						return io.ErrUnexpectedEOF
+					}
+					b := dAtA[iNdEx]
+					iNdEx++
+					packedLen |= (int(b) & 0x7F) << shift
+					if b < 0x80 {
+						break
+					}
+				}
+				if packedLen < 0 {
+					return ErrInvalidLengthOsm
+				}
+				postIndex := iNdEx + packedLen
+				if postIndex > l {
+					return io.ErrUnexpectedEOF
+				}
+				for iNdEx < postIndex {
+					var v int32
+					for shift := uint(0); ; shift += 7 {
+						if shift >= 64 {
+							return ErrIntOverflowOsm
+						}
+						if iNdEx >= l {
+							return io.ErrUnexpectedEOF
+						}
+						b := dAtA[iNdEx]
+						iNdEx++
+						v |= (int32(b) & 0x7F) << shift
+						if b < 0x80 {
+							break
+						}
+					}
+					m.TimestampMs = append(m.TimestampMs, v)
+				}
+			} else {
+				return fmt.Errorf("proto: wrong wireType = %d for field TimestampMs", wireType)
+			}
 		default:
 			iNdEx = preIndex
 			skippy, err := skipOsm(dAtA[iNdEx:])
@@ -6009,6 +6989,70 @@ func (m *DenseMembers) Unmarshal(dAtA []byte) error {
 			} else {
 				return fmt.Errorf("proto: wrong wireType = %d for field Orientation", wireType)
 			}
+		case 6:
+			if wireType == 0 {
+				var v int32
+				for shift := uint(0); ; shift += 7 {
+					if shift >= 64 {
+						return ErrIntOverflowOsm
+					}
+					if iNdEx >= l {
+						return io.ErrUnexpectedEOF
+					}
+					b := dAtA[iNdEx]
+					iNdEx++
+					v |= (int32(b) & 0x7F) << shift
+					if b < 0x80 {
+						break
+					}
+				}
+				v = int32((uint32(v) >> 1) ^ uint32(((v&1)<<31)>>31))
+				m.VersionDeltas = append(m.VersionDeltas, v)
+			} else if wireType == 2 {
+				var packedLen int
+				for shift := uint(0); ; shift += 7 {
+					if shift >= 64 {
+						return ErrIntOverflowOsm
+					}
+					if iNdEx >= l {
+						return io.ErrUnexpectedEOF
+					}
+					b := dAtA[iNdEx]
+					iNdEx++
+					packedLen |= (int(b) & 0x7F) << shift
+					if b < 0x80 {
+						break
+					}
+				}
+				if packedLen < 0 {
+					return ErrInvalidLengthOsm
+				}
+				postIndex := iNdEx + packedLen
+				if postIndex > l {
+					return io.ErrUnexpectedEOF
+				}
+				for iNdEx < postIndex {
+					var v int32
+					for shift := uint(0); ; shift += 7 {
+						if shift >= 64 {
+							return ErrIntOverflowOsm
+						}
+						if iNdEx >= l {
+							return io.ErrUnexpectedEOF
+						}
+						b := dAtA[iNdEx]
+						iNdEx++
+						v |= (int32(b) & 0x7F) << shift
+						if b < 0x80 {
+							break
+						}
+					}
+					v = int32((uint32(v) >> 1) ^ uint32(((v&1)<<31)>>31))
+					m.VersionDeltas = append(m.VersionDeltas, v)
+				}
+			} else {
+				return fmt.Errorf("proto: wrong wireType = %d for field VersionDeltas", wireType)
+			}
 		case 8:
 			if wireType == 0 {
 				var v uint64
@@ -0,0 +1,128 @@
+// Package sqltest provides a minimal database/sql driver that records
+// the statements executed, for testing the database loaders.
+package sqltest
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"strconv"
+	"sync"
+)
+
+// Recorder holds the statements executed on a test database.
+type Recorder struct {
+	sync.Mutex
+
+	// Rows are the arguments passed to each prepared statement
+	// keyed by the query.
+	Rows map[string][][]driver.Value
+
+	// Execs are the queries executed directly on the database.
+	Execs []string
+
+	Commits   int
+	Rollbacks int
+
+	// FailCommit is the commit, counting from 1, that returns an error.
+	// Zero for none.
+	FailCommit int
+}
+
+var (
+	registerOnce sync.Once
+	testDriver   = &testDriverImpl{recorders: map[string]*Recorder{}}
+)
+
+type testDriverImpl struct {
+	sync.Mutex
+	count     int
+	recorders map[string]*Recorder
+}
+
+// Open returns a new database that records everything executed on it.
+func Open() (*sql.DB, *Recorder, error) {
+	registerOnce.Do(func() {
+		sql.Register("sqltest", testDriver)
+	})
+
+	rec := &Recorder{Rows: map[string][][]driver.Value{}}
+
+	testDriver.Lock()
+	testDriver.count++
+	name := strconv.Itoa(testDriver.count)
+	testDriver.recorders[name] = rec
+	testDriver.Unlock()
+
+	db, err := sql.Open("sqltest", name)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return db, rec, nil
+}
+
+func (d *testDriverImpl) Open(name string) (driver.Conn, error) {
+	d.Lock()
+	defer d.Unlock()
+	return &testConn{rec: d.recorders[name]}, nil
+}
+
+type testConn struct{ rec *Recorder }
+
+func (c *testConn) Prepare(query string) (driver.Stmt, error) {
+	return &testStmt{rec: c.rec, query: query}, nil
+}
+
+func (c *testConn) Exec(query string, args []driver.Value) (driver.Result, error) {
+	c.rec.Lock()
+	defer c.rec.Unlock()
+	c.rec.Execs = append(c.rec.Execs, query)
+	return driver.RowsAffected(0), nil
+}
+
+func (c *testConn) Close() error              { return nil }
+func (c *testConn) Begin() (driver.Tx, error) { return &testTx{rec: c.rec}, nil }
+
+type testTx struct{ rec *Recorder }
+
+func (tx *testTx) Commit() error {
+	tx.rec.Lock()
+	defer tx.rec.Unlock()
+	tx.rec.Commits++
+	if tx.rec.Commits == tx.rec.FailCommit {
+		return errors.New("sqltest: commit failed")
+	}
+
+	return nil
+}
+
+func (tx *testTx) Rollback() error {
+	tx.rec.Lock()
+	defer tx.rec.Unlock()
+	tx.rec.Rollbacks++
+	return nil
+}
+
+type testStmt struct {
+	rec   *Recorder
+	query string
+}
+
+func (s *testStmt) Close() error  { return nil }
+func (s *testStmt) NumInput() int { return -1 }
+
+func (s *testStmt) Exec(args []driver.Value) (driver.Result, error) {
+	if len(args) == 0 {
+		return driver.RowsAffected(0), nil
+	}
+
+	s.rec.Lock()
+	defer s.rec.Unlock()
+	s.rec.Rows[s.query] = append(s.rec.Rows[s.query], args)
+	return driver.RowsAffected(1), nil
+}
+
+func (s *testStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return nil, errors.New("sqltest: query not supported")
+}
@@ -0,0 +1,294 @@
+// Package josm reads and writes the .osm files saved by the JOSM editor.
+// These are regular osm xml files with an action attribute on the edited
+// elements, negative ids for new elements and an upload attribute on the
+// root that can prevent the data from being uploaded.
+// See: https://wiki.openstreetmap.org/wiki/JOSM_file_format
+package josm
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/paulmach/osm"
+)
+
+// Action is the edit made to an element in the editor session.
+type Action string
+
+// The actions written by JOSM. Unmodified and new elements have no
+// action, new elements are recognized by their negative id.
+const (
+	ActionNone   Action = ""
+	ActionModify Action = "modify"
+	ActionDelete Action = "delete"
+)
+
+// Upload values for the root element. An empty value means true.
+const (
+	UploadTrue  = "true"
+	UploadFalse = "false"
+	UploadNever = "never"
+)
+
+// File is the contents of a JOSM .osm file.
+type File struct {
+	Version   string
+	Generator string
+	Upload    string
+
+	// JOSM writes a bounds element for every downloaded area.
+	Bounds []*osm.Bounds
+
+	Elements []Element
+}
+
+// Element is a node, way or relation with the action applied to it.
+type Element struct {
+	osm.Element
+	Action Action
+}
+
+// IsNew returns true if the element was created in the editor
+// and has a negative placeholder id.
+func (e Element) IsNew() bool {
+	return elementRef(e.Element) < 0
+}
+
+// Uploadable returns false if the upload attribute is false or never.
+func (f *File) Uploadable() bool {
+	return f.Upload != UploadFalse && f.Upload != UploadNever
+}
+
+// Change returns the edits as an osm change for upload. New elements
+// are created, modified elements are modified and deleted elements are
+// deleted. New elements that were also deleted are skipped.
+func (f *File) Change() *osm.Change {
+	c := &osm.Change{
+		Version:   0.6,
+		Generator: f.Generator,
+	}
+
+	for _, e := range f.Elements {
+		switch {
+		case e.IsNew() && e.Action == ActionDelete:
+		case e.IsNew():
+			c.AppendCreate(e.Element)
+		case e.Action == ActionModify:
+			c.AppendModify(e.Element)
+		case e.Action == ActionDelete:
+			c.AppendDelete(e.Element)
+		}
+	}
+
+	return c
+}
+
+// OSM returns the current state of the data, all the elements that
+// have not been deleted.
+func (f *File) OSM() *osm.OSM {
+	o := &osm.OSM{
+		Version:   0.6,
+		Generator: f.Generator,
+	}
+
+	if len(f.Bounds) > 0 {
+		o.Bounds = f.Bounds[0]
+	}
+
+	for _, e := range f.Elements {
+		if e.Action != ActionDelete {
+			o.Append(e.Element)
+		}
+	}
+
+	return o
+}
+
+// Unmarshal parses a JOSM .osm file.
+func Unmarshal(data []byte) (*File, error) {
+	return Decode(bytes.NewReader(data))
+}
+
+// Decode reads a JOSM .osm file from the reader.
+func Decode(r io.Reader) (*File, error) {
+	f := &File{}
+	d := xml.NewDecoder(r)
+
+	for {
+		t, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		start, ok := t.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		switch start.Name.Local {
+		case "osm":
+			for _, a := range start.Attr {
+				switch a.Name.Local {
+				case "version":
+					f.Version = a.Value
+				case "generator":
+					f.Generator = a.Value
+				case "upload":
+					f.Upload = a.Value
+				}
+			}
+		case "bounds":
+			b := &osm.Bounds{}
+			if err := d.DecodeElement(b, &start); err != nil {
+				return nil, err
+			}
+			f.Bounds = append(f.Bounds, b)
+		case "node":
+			n := &osm.Node{}
+			if err := d.DecodeElement(n, &start); err != nil {
+				return nil, err
+			}
+			n.Visible = visible(start)
+			f.Elements = append(f.Elements, Element{Element: n, Action: action(start)})
+		case "way":
+			w := &osm.Way{}
+			if err := d.DecodeElement(w, &start); err != nil {
+				return nil, err
+			}
+			w.Visible = visible(start)
+			f.Elements = append(f.Elements, Element{Element: w, Action: action(start)})
+		case "relation":
+			r := &osm.Relation{}
+			if err := d.DecodeElement(r, &start); err != nil {
+				return nil, err
+			}
+			r.Visible = visible(start)
+			f.Elements = append(f.Elements, Element{Element: r, Action: action(start)})
+		}
+	}
+
+	return f, nil
+}
+
+func action(start xml.StartElement) Action {
+	for _, a := range start.Attr {
+		if a.Name.Local == "action" {
+			return Action(a.Value)
+		}
+	}
+
+	return ActionNone
+}
+
+// visible returns true if the attribute is missing, as it is for new
+// elements, since the osm package would default to false.
+func visible(start xml.StartElement) bool {
+	for _, a := range start.Attr {
+		if a.Name.Local == "visible" {
+			return a.Value != "false"
+		}
+	}
+
+	return true
+}
+
+// Marshal encodes the file as JOSM xml.
+func (f *File) Marshal() ([]byte, error) {
+	buf := &bytes.Buffer{}
+	if err := f.Encode(buf); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Encode writes the file as JOSM xml, including the xml header.
+func (f *File) Encode(w io.Writer) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	e := xml.NewEncoder(w)
+	e.Indent("", "  ")
+	if err := e.Encode(f); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// MarshalXML implements the xml.Marshaller interface.
+func (f File) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	start.Name.Local = "osm"
+
+	version := f.Version
+	if version == "" {
+		version = "0.6"
+	}
+
+	start.Attr = []xml.Attr{{Name: xml.Name{Local: "version"}, Value: version}}
+	if f.Upload != "" {
+		start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: "upload"}, Value: f.Upload})
+	}
+
+	if f.Generator != "" {
+		start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: "generator"}, Value: f.Generator})
+	}
+
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+
+	for _, b := range f.Bounds {
+		if err := e.EncodeElement(b, xml.StartElement{Name: xml.Name{Local: "bounds"}}); err != nil {
+			return err
+		}
+	}
+
+	for _, el := range f.Elements {
+		// the elements keep the attributes of the start element
+		es := xml.StartElement{}
+		switch o := el.Element.(type) {
+		case *osm.Node:
+			es.Name.Local = "node"
+		case *osm.Way:
+			es.Name.Local = "way"
+		case *osm.Relation:
+			es.Name.Local = "relation"
+		case nil:
+			return errors.New("josm: element is nil")
+		default:
+			return fmt.Errorf("josm: unsupported element type %T", o)
+		}
+
+		if el.Action != ActionNone {
+			es.Attr = []xml.Attr{{Name: xml.Name{Local: "action"}, Value: string(el.Action)}}
+		}
+
+		if err := e.EncodeElement(el.Element, es); err != nil {
+			return err
+		}
+	}
+
+	return e.EncodeToken(start.End())
+}
+
+func elementRef(e osm.Element) int64 {
+	switch e := e.(type) {
+	case *osm.Node:
+		return int64(e.ID)
+	case *osm.Way:
+		return int64(e.ID)
+	case *osm.Relation:
+		return int64(e.ID)
+	}
+
+	return 0
+}
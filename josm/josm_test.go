@@ -0,0 +1,136 @@
+package josm
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/paulmach/osm"
+)
+
+var data = []byte(`<?xml version='1.0' encoding='UTF-8'?>
+<osm version='0.6' upload='false' generator='JOSM'>
+  <bounds minlat='1' minlon='2' maxlat='3' maxlon='4' origin='OpenStreetMap server' />
+  <node id='-1' lat='1.5' lon='2.5'>
+    <tag k='amenity' v='cafe' />
+  </node>
+  <node id='-2' action='delete' lat='1.5' lon='2.5' />
+  <node id='10' action='modify' timestamp='2018-01-01T00:00:00Z' uid='1' user='u' visible='true' version='3' changeset='5' lat='1.1' lon='2.1' />
+  <node id='11' timestamp='2018-01-01T00:00:00Z' uid='1' user='u' visible='true' version='1' changeset='5' lat='1.2' lon='2.2' />
+  <way id='20' action='delete' timestamp='2018-01-01T00:00:00Z' uid='1' user='u' visible='true' version='2' changeset='5'>
+    <nd ref='11' />
+    <nd ref='10' />
+  </way>
+  <relation id='-3'>
+    <member type='node' ref='-1' role='' />
+  </relation>
+</osm>`)
+
+func TestDecode(t *testing.T) {
+	f, err := Unmarshal(data)
+	if err != nil {
+		t.Fatalf("unmarshal error: %v", err)
+	}
+
+	if f.Version != "0.6" || f.Generator != "JOSM" || f.Upload != UploadFalse {
+		t.Errorf("incorrect header: %+v", f)
+	}
+
+	if f.Uploadable() {
+		t.Errorf("should not be uploadable")
+	}
+
+	if len(f.Bounds) != 1 || f.Bounds[0].MaxLon != 4 {
+		t.Errorf("incorrect bounds: %v", f.Bounds)
+	}
+
+	if len(f.Elements) != 6 {
+		t.Fatalf("incorrect elements: %v", len(f.Elements))
+	}
+
+	n := f.Elements[0]
+	if !n.IsNew() || n.Action != ActionNone || !n.Element.(*osm.Node).Visible {
+		t.Errorf("new node should be visible: %+v", n)
+	}
+
+	if e := f.Elements[2]; e.Action != ActionModify || e.IsNew() {
+		t.Errorf("incorrect modified node: %+v", e)
+	}
+
+	if e := f.Elements[4]; e.Action != ActionDelete || len(e.Element.(*osm.Way).Nodes) != 2 {
+		t.Errorf("incorrect deleted way: %+v", e)
+	}
+
+	c := f.Change()
+	if len(c.Create.Nodes) != 1 || len(c.Create.Relations) != 1 {
+		t.Errorf("incorrect creates: %+v", c.Create)
+	}
+
+	if len(c.Modify.Nodes) != 1 || c.Modify.Nodes[0].ID != 10 {
+		t.Errorf("incorrect modifies: %+v", c.Modify)
+	}
+
+	if c.Delete.Nodes != nil || len(c.Delete.Ways) != 1 {
+		t.Errorf("incorrect deletes: %+v", c.Delete)
+	}
+
+	o := f.OSM()
+	if len(o.Nodes) != 3 || len(o.Ways) != 0 || len(o.Relations) != 1 {
+		t.Errorf("incorrect osm: %v %v %v", len(o.Nodes), len(o.Ways), len(o.Relations))
+	}
+}
+
+func TestFile_roundTrip(t *testing.T) {
+	f, err := Unmarshal(data)
+	if err != nil {
+		t.Fatalf("unmarshal error: %v", err)
+	}
+
+	out, err := f.Marshal()
+	if err != nil {
+		t.Fatalf("marshal error: %v", err)
+	}
+
+	for _, s := range []string{`upload="false"`, `<bounds`, `action="modify"`, `action="delete"`, `id="-1"`} {
+		if !bytes.Contains(out, []byte(s)) {
+			t.Errorf("output missing %s", s)
+		}
+	}
+
+	if c := bytes.Count(out, []byte(`action="modify"`)); c != 1 {
+		t.Errorf("action should be written once: %d", c)
+	}
+
+	f2, err := Unmarshal(out)
+	if err != nil {
+		t.Fatalf("unmarshal error: %v", err)
+	}
+
+	if len(f2.Elements) != len(f.Elements) {
+		t.Fatalf("incorrect elements: %v", len(f2.Elements))
+	}
+
+	for i := range f.Elements {
+		if f.Elements[i].Action != f2.Elements[i].Action {
+			t.Errorf("%d: incorrect action: %v != %v", i, f.Elements[i].Action, f2.Elements[i].Action)
+		}
+
+		if elementRef(f.Elements[i].Element) != elementRef(f2.Elements[i].Element) {
+			t.Errorf("%d: incorrect id", i)
+		}
+	}
+
+	if len(f2.Bounds) != 1 {
+		t.Errorf("bounds should be preserved: %v", f2.Bounds)
+	}
+
+	if !f2.Elements[0].Element.(*osm.Node).Visible {
+		t.Errorf("visible should be preserved")
+	}
+}
+
+func TestFile_MarshalXML_errors(t *testing.T) {
+	f := &File{Elements: []Element{{}}}
+	if _, err := f.Marshal(); err == nil {
+		t.Errorf("expected error for nil element")
+	}
+}
@@ -1,6 +1,10 @@
 package osm
 
-import "encoding/xml"
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+)
 
 // xmlNameJSONTypeNode is kind of a hack to encode the proper json
 // object type attribute for this struct type.
@@ -10,6 +14,10 @@ func (x xmlNameJSONTypeNode) MarshalJSON() ([]byte, error) {
 	return []byte(`"node"`), nil
 }
 
+func (x *xmlNameJSONTypeNode) UnmarshalJSON(data []byte) error {
+	return unmarshalJSONType(data, "node")
+}
+
 // xmlNameJSONTypeWay is kind of a hack to encode the proper json
 // object type attribute for this struct type.
 type xmlNameJSONTypeWay xml.Name
@@ -18,6 +26,10 @@ func (x xmlNameJSONTypeWay) MarshalJSON() ([]byte, error) {
 	return []byte(`"way"`), nil
 }
 
+func (x *xmlNameJSONTypeWay) UnmarshalJSON(data []byte) error {
+	return unmarshalJSONType(data, "way")
+}
+
 // xmlNameJSONTypeRel is kind of a hack to encode the proper json
 // object type attribute for this struct type.
 type xmlNameJSONTypeRel xml.Name
@@ -26,6 +38,10 @@ func (x xmlNameJSONTypeRel) MarshalJSON() ([]byte, error) {
 	return []byte(`"relation"`), nil
 }
 
+func (x *xmlNameJSONTypeRel) UnmarshalJSON(data []byte) error {
+	return unmarshalJSONType(data, "relation")
+}
+
 // xmlNameJSONTypeCS is kind of a hack to encode the proper json
 // object type attribute for this struct type.
 type xmlNameJSONTypeCS xml.Name
@@ -34,6 +50,10 @@ func (x xmlNameJSONTypeCS) MarshalJSON() ([]byte, error) {
 	return []byte(`"changeset"`), nil
 }
 
+func (x *xmlNameJSONTypeCS) UnmarshalJSON(data []byte) error {
+	return unmarshalJSONType(data, "changeset")
+}
+
 // xmlNameJSONTypeUser is kind of a hack to encode the proper json
 // object type attribute for this struct type.
 type xmlNameJSONTypeUser xml.Name
@@ -42,6 +62,10 @@ func (x xmlNameJSONTypeUser) MarshalJSON() ([]byte, error) {
 	return []byte(`"user"`), nil
 }
 
+func (x *xmlNameJSONTypeUser) UnmarshalJSON(data []byte) error {
+	return unmarshalJSONType(data, "user")
+}
+
 // xmlNameJSONTypeNote is kind of a hack to encode the proper json
 // object type attribute for this struct type.
 type xmlNameJSONTypeNote xml.Name
@@ -49,3 +73,22 @@ type xmlNameJSONTypeNote xml.Name
 func (x xmlNameJSONTypeNote) MarshalJSON() ([]byte, error) {
 	return []byte(`"note"`), nil
 }
+
+func (x *xmlNameJSONTypeNote) UnmarshalJSON(data []byte) error {
+	return unmarshalJSONType(data, "note")
+}
+
+// unmarshalJSONType validates the json type attribute. The type is
+// implied by the struct so nothing needs to be saved.
+func unmarshalJSONType(data []byte, expected string) error {
+	var t string
+	if err := json.Unmarshal(data, &t); err != nil {
+		return err
+	}
+
+	if t != expected {
+		return fmt.Errorf("osm: expected type %s, got %s", expected, t)
+	}
+
+	return nil
+}
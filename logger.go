@@ -0,0 +1,61 @@
+package osm
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+// A Logger receives the warnings of the readers, writers, api client and
+// replication loop, such as skipped elements, retried requests and truncated
+// blocks. Nothing is logged if no logger is set. The method matches the
+// log/slog Logger so a *slog.Logger can be used directly. The key value
+// pairs are alternating string keys and values.
+type Logger interface {
+	Warn(msg string, keyvals ...interface{})
+}
+
+// StdLogger returns a Logger that writes the warnings, with the key value
+// pairs formatted as key=value, to the standard library logger. If l is nil
+// the default logger of the log package is used.
+func StdLogger(l *log.Logger) Logger {
+	return &stdLogger{l: l}
+}
+
+type stdLogger struct {
+	l *log.Logger
+}
+
+func (s *stdLogger) Warn(msg string, keyvals ...interface{}) {
+	line := formatWarning(msg, keyvals)
+	if s.l == nil {
+		log.Print(line)
+		return
+	}
+
+	s.l.Print(line)
+}
+
+func formatWarning(msg string, keyvals []interface{}) string {
+	b := &strings.Builder{}
+	b.WriteString("WARN ")
+	b.WriteString(msg)
+
+	for i := 0; i < len(keyvals); i += 2 {
+		b.WriteByte(' ')
+		if i+1 == len(keyvals) {
+			fmt.Fprintf(b, "!BADKEY=%v", keyvals[i])
+			break
+		}
+
+		fmt.Fprintf(b, "%v=", keyvals[i])
+
+		v := fmt.Sprint(keyvals[i+1])
+		if v == "" || strings.ContainsAny(v, " \t\n\"=") {
+			v = fmt.Sprintf("%q", v)
+		}
+		b.WriteString(v)
+	}
+
+	return b.String()
+}
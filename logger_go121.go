@@ -0,0 +1,11 @@
+//go:build go1.21
+// +build go1.21
+
+package osm
+
+import "log/slog"
+
+// a *slog.Logger is a Logger, e.g.
+//
+//	scanner := osmxml.New(ctx, r, osmxml.WithLogger(slog.Default()))
+var _ Logger = (*slog.Logger)(nil)
@@ -0,0 +1,22 @@
+//go:build go1.21
+// +build go1.21
+
+package osm
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestLogger_slog(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	var l Logger = slog.New(slog.NewTextHandler(buf, nil))
+	l.Warn("skipped element", "name", "bounds")
+
+	if v := buf.String(); !strings.Contains(v, `level=WARN msg="skipped element" name=bounds`) {
+		t.Errorf("incorrect output: %q", v)
+	}
+}
@@ -0,0 +1,51 @@
+package osm
+
+import (
+	"bytes"
+	"log"
+	"testing"
+)
+
+func TestStdLogger(t *testing.T) {
+	cases := []struct {
+		name     string
+		msg      string
+		keyvals  []interface{}
+		expected string
+	}{
+		{
+			name:     "no keyvals",
+			msg:      "skipped element",
+			expected: "WARN skipped element\n",
+		},
+		{
+			name:     "keyvals",
+			msg:      "skipped element",
+			keyvals:  []interface{}{"name", "bounds", "count", 2},
+			expected: "WARN skipped element name=bounds count=2\n",
+		},
+		{
+			name:     "quoted values",
+			msg:      "retrying",
+			keyvals:  []interface{}{"err", "connection reset", "empty", ""},
+			expected: `WARN retrying err="connection reset" empty=""` + "\n",
+		},
+		{
+			name:     "missing value",
+			msg:      "odd",
+			keyvals:  []interface{}{"a", 1, "b"},
+			expected: "WARN odd a=1 !BADKEY=b\n",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			StdLogger(log.New(buf, "", 0)).Warn(tc.msg, tc.keyvals...)
+
+			if v := buf.String(); v != tc.expected {
+				t.Errorf("incorrect output: %q", v)
+			}
+		})
+	}
+}
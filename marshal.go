@@ -1,6 +1,7 @@
 package osm
 
 import (
+	"errors"
 	"time"
 
 	"github.com/gogo/protobuf/proto"
@@ -22,6 +23,35 @@ var memberTypeMapRev = map[osmpb.Relation_MemberType]Type{
 	osmpb.Relation_RELATION: TypeRelation,
 }
 
+func marshalNode(node *Node, ss *stringSet, includeChangeset bool, mode RoundingMode) *osmpb.Node {
+	keys, vals := node.Tags.keyValues(ss)
+	encoded := &osmpb.Node{
+		Id:   int64(node.ID),
+		Keys: keys,
+		Vals: vals,
+		Info: &osmpb.Info{
+			Version:     int32(node.Version),
+			Timestamp:   timeToUnix(node.Timestamp),
+			TimestampMs: timeToMillis(node.Timestamp),
+			Visible:     proto.Bool(node.Visible),
+		},
+		Lat: GeoToInt64(node.Lat, mode),
+		Lon: GeoToInt64(node.Lon, mode),
+	}
+
+	if node.Committed != nil {
+		encoded.Info.Committed = timeToUnixPointer(*node.Committed)
+	}
+
+	if includeChangeset {
+		encoded.Info.ChangesetId = int64(node.ChangesetID)
+		encoded.Info.UserId = int32(node.UserID)
+		encoded.Info.UserSid = ss.Add(node.User)
+	}
+
+	return encoded
+}
+
 func unmarshalNode(encoded *osmpb.Node, ss []string, cs *Changeset) (*Node, error) {
 	tags, err := tagsFromStrings(ss, encoded.GetKeys(), encoded.GetVals())
 	if err != nil {
@@ -29,14 +59,19 @@ func unmarshalNode(encoded *osmpb.Node, ss []string, cs *Changeset) (*Node, erro
 	}
 
 	info := encoded.GetInfo()
+	user, err := stringAt(ss, info.GetUserSid())
+	if err != nil {
+		return nil, err
+	}
+
 	n := &Node{
 		ID:          NodeID(encoded.GetId()),
-		User:        ss[info.GetUserSid()],
+		User:        user,
 		UserID:      UserID(info.GetUserId()),
 		Visible:     info.GetVisible(),
 		Version:     int(info.GetVersion()),
 		ChangesetID: ChangesetID(info.GetChangesetId()),
-		Timestamp:   unixToTime(info.GetTimestamp()),
+		Timestamp:   addMillis(unixToTime(info.GetTimestamp()), info.GetTimestampMs()),
 		Tags:        tags,
 		Lat:         float64(encoded.GetLat()) / locMultiple,
 		Lon:         float64(encoded.GetLon()) / locMultiple,
@@ -53,15 +88,16 @@ func unmarshalNode(encoded *osmpb.Node, ss []string, cs *Changeset) (*Node, erro
 	return n, nil
 }
 
-func marshalNodes(nodes Nodes, ss *stringSet, includeChangeset bool) *osmpb.DenseNodes {
-	dense := denseNodesValues(nodes)
+func marshalNodes(nodes Nodes, ss *stringSet, includeChangeset bool, mode RoundingMode) *osmpb.DenseNodes {
+	dense := denseNodesValues(nodes, mode)
 	encoded := &osmpb.DenseNodes{
 		Ids: encodeInt64(dense.IDs),
 		DenseInfo: &osmpb.DenseInfo{
-			Versions:   dense.Versions,
-			Timestamps: encodeInt64(dense.Timestamps),
-			Committeds: encodeInt64(dense.Committeds),
-			Visibles:   dense.Visibles,
+			Versions:    dense.Versions,
+			Timestamps:  encodeInt64(dense.Timestamps),
+			Committeds:  encodeInt64(dense.Committeds),
+			Visibles:    dense.Visibles,
+			TimestampMs: dense.TimestampMs,
 		},
 		Lats: encodeInt64(dense.Lats),
 		Lons: encodeInt64(dense.Lons),
@@ -82,29 +118,38 @@ func marshalNodes(nodes Nodes, ss *stringSet, includeChangeset bool) *osmpb.Dens
 }
 
 func unmarshalNodes(encoded *osmpb.DenseNodes, ss []string, cs *Changeset) (Nodes, error) {
-	encoded.Ids = decodeInt64(encoded.Ids)
-	encoded.Lats = decodeInt64(encoded.Lats)
-	encoded.Lons = decodeInt64(encoded.Lons)
-	encoded.DenseInfo.Timestamps = decodeInt64(encoded.DenseInfo.Timestamps)
-	encoded.DenseInfo.ChangesetIds = decodeInt64(encoded.DenseInfo.ChangesetIds)
-	encoded.DenseInfo.Committeds = decodeInt64(encoded.DenseInfo.Committeds)
-	encoded.DenseInfo.UserIds = decodeInt32(encoded.DenseInfo.UserIds)
-	encoded.DenseInfo.UserSids = decodeInt32(encoded.DenseInfo.UserSids)
+	if err := checkDenseNodes(encoded); err != nil {
+		return nil, err
+	}
+
+	info := encoded.DenseInfo
+	ids := decodeInt64(encoded.Ids)
+	lats := decodeInt64(encoded.Lats)
+	lons := decodeInt64(encoded.Lons)
+	timestamps := decodeInt64(info.GetTimestamps())
+	changesetIDs := decodeInt64(info.GetChangesetIds())
+	committeds := decodeInt64(info.GetCommitteds())
+	userIDs := decodeInt32(info.GetUserIds())
+	userSids := decodeInt32(info.GetUserSids())
 
 	tagLoc := 0
-	nodes := make(Nodes, len(encoded.Ids))
-	for i := range encoded.Ids {
+	nodes := make(Nodes, len(ids))
+	for i := range ids {
 		n := &Node{
-			ID:        NodeID(encoded.Ids[i]),
-			Lat:       float64(encoded.Lats[i]) / locMultiple,
-			Lon:       float64(encoded.Lons[i]) / locMultiple,
-			Visible:   encoded.DenseInfo.Visibles[i],
-			Version:   int(encoded.DenseInfo.Versions[i]),
-			Timestamp: unixToTime(encoded.DenseInfo.Timestamps[i]),
+			ID:        NodeID(ids[i]),
+			Lat:       float64(lats[i]) / locMultiple,
+			Lon:       float64(lons[i]) / locMultiple,
+			Visible:   info.Visibles[i],
+			Version:   int(info.Versions[i]),
+			Timestamp: unixToTime(timestamps[i]),
 		}
 
-		if i < len(encoded.DenseInfo.Committeds) {
-			n.Committed = unixToTimePointer(encoded.DenseInfo.Committeds[i])
+		if i < len(info.TimestampMs) {
+			n.Timestamp = addMillis(n.Timestamp, info.TimestampMs[i])
+		}
+
+		if i < len(committeds) {
+			n.Committed = unixToTimePointer(committeds[i])
 		}
 
 		if cs != nil {
@@ -112,32 +157,28 @@ func unmarshalNodes(encoded *osmpb.DenseNodes, ss []string, cs *Changeset) (Node
 			n.UserID = cs.UserID
 			n.User = cs.User
 		} else {
-			if len(encoded.DenseInfo.ChangesetIds) > 0 {
-				n.ChangesetID = ChangesetID(encoded.DenseInfo.ChangesetIds[i])
+			if len(changesetIDs) > 0 {
+				n.ChangesetID = ChangesetID(changesetIDs[i])
 			}
 
-			if len(encoded.DenseInfo.UserIds) > 0 {
-				n.UserID = UserID(encoded.DenseInfo.UserIds[i])
+			if len(userIDs) > 0 {
+				n.UserID = UserID(userIDs[i])
 			}
 
-			if len(encoded.DenseInfo.UserSids) > 0 {
-				n.User = ss[encoded.DenseInfo.UserSids[i]]
+			if len(userSids) > 0 {
+				user, err := stringAt(ss, uint32(userSids[i]))
+				if err != nil {
+					return nil, err
+				}
+				n.User = user
 			}
 		}
 
 		if encoded.KeysVals != nil {
-			if encoded.KeysVals[tagLoc] == 0 {
-				tagLoc++
-			} else {
-				for encoded.KeysVals[tagLoc] != 0 {
-					n.Tags = append(n.Tags, Tag{
-						Key:   ss[encoded.KeysVals[tagLoc]],
-						Value: ss[encoded.KeysVals[tagLoc+1]],
-					})
-
-					tagLoc += 2
-				}
-				tagLoc++
+			var err error
+			n.Tags, tagLoc, err = denseNodeTags(ss, encoded.KeysVals, tagLoc)
+			if err != nil {
+				return nil, err
 			}
 		}
 
@@ -147,18 +188,19 @@ func unmarshalNodes(encoded *osmpb.DenseNodes, ss []string, cs *Changeset) (Node
 	return nodes, nil
 }
 
-func marshalWay(way *Way, ss *stringSet, includeChangeset bool) *osmpb.Way {
+func marshalWay(way *Way, ss *stringSet, includeChangeset bool, mode RoundingMode) *osmpb.Way {
 	keys, vals := way.Tags.keyValues(ss)
 	encoded := &osmpb.Way{
 		Id:   int64(way.ID),
 		Keys: keys,
 		Vals: vals,
 		Info: &osmpb.Info{
-			Version:   int32(way.Version),
-			Timestamp: timeToUnix(way.Timestamp),
-			Visible:   proto.Bool(way.Visible),
+			Version:     int32(way.Version),
+			Timestamp:   timeToUnix(way.Timestamp),
+			TimestampMs: timeToMillis(way.Timestamp),
+			Visible:     proto.Bool(way.Visible),
 		},
-		Updates: marshalUpdates(way.Updates),
+		Updates: marshalUpdates(way.Updates, mode),
 	}
 
 	if way.Committed != nil {
@@ -169,7 +211,7 @@ func marshalWay(way *Way, ss *stringSet, includeChangeset bool) *osmpb.Way {
 		encoded.Refs = encodeWayNodeIDs(way.Nodes)
 
 		if way.Nodes[0].Version != 0 {
-			encoded.DenseMembers = encodeDenseWayNodes(way.Nodes)
+			encoded.DenseMembers = encodeDenseWayNodes(way.Nodes, mode)
 		}
 	}
 
@@ -189,22 +231,32 @@ func unmarshalWay(encoded *osmpb.Way, ss []string, cs *Changeset) (*Way, error)
 	}
 
 	info := encoded.GetInfo()
+	user, err := stringAt(ss, info.GetUserSid())
+	if err != nil {
+		return nil, err
+	}
+
 	w := &Way{
 		ID:          WayID(encoded.GetId()),
-		User:        ss[info.GetUserSid()],
+		User:        user,
 		UserID:      UserID(info.GetUserId()),
 		Visible:     info.GetVisible(),
 		Version:     int(info.GetVersion()),
 		ChangesetID: ChangesetID(info.GetChangesetId()),
-		Timestamp:   unixToTime(info.GetTimestamp()),
+		Timestamp:   addMillis(unixToTime(info.GetTimestamp()), info.GetTimestampMs()),
 		Committed:   unixToTimePointer(info.GetCommitted()),
 		Tags:        tags,
 	}
 
 	w.Nodes = decodeWayNodeIDs(encoded.GetRefs())
-	decodeDenseWayNodes(w.Nodes, encoded.GetDenseMembers())
+	if err := decodeDenseWayNodes(w.Nodes, encoded.GetDenseMembers()); err != nil {
+		return nil, err
+	}
 
-	w.Updates = unmarshalUpdates(encoded.GetUpdates())
+	w.Updates, err = unmarshalUpdates(encoded.GetUpdates())
+	if err != nil {
+		return nil, err
+	}
 
 	if cs != nil {
 		w.ChangesetID = cs.ID
@@ -215,7 +267,7 @@ func unmarshalWay(encoded *osmpb.Way, ss []string, cs *Changeset) (*Way, error)
 	return w, nil
 }
 
-func marshalRelation(relation *Relation, ss *stringSet, includeChangeset bool) *osmpb.Relation {
+func marshalRelation(relation *Relation, ss *stringSet, includeChangeset bool, mode RoundingMode) *osmpb.Relation {
 	l := len(relation.Members)
 	roles := make([]uint32, l)
 	refs := make([]int64, l)
@@ -238,14 +290,15 @@ func marshalRelation(relation *Relation, ss *stringSet, includeChangeset bool) *
 		Keys: keys,
 		Vals: vals,
 		Info: &osmpb.Info{
-			Version:   int32(relation.Version),
-			Timestamp: timeToUnix(relation.Timestamp),
-			Visible:   proto.Bool(relation.Visible),
+			Version:     int32(relation.Version),
+			Timestamp:   timeToUnix(relation.Timestamp),
+			TimestampMs: timeToMillis(relation.Timestamp),
+			Visible:     proto.Bool(relation.Visible),
 		},
 		Roles:   roles,
 		Refs:    encodeInt64(refs),
 		Types:   types,
-		Updates: marshalUpdates(relation.Updates),
+		Updates: marshalUpdates(relation.Updates, mode),
 	}
 
 	if relation.Committed != nil {
@@ -255,7 +308,7 @@ func marshalRelation(relation *Relation, ss *stringSet, includeChangeset bool) *
 	if interestingMember {
 		// relations can be partial annotated, in that case we still
 		// want to save the annotation data.
-		encoded.DenseMembers = encodeDenseMembers(relation.Members)
+		encoded.DenseMembers = encodeDenseMembers(relation.Members, mode)
 	}
 
 	if includeChangeset {
@@ -274,21 +327,37 @@ func unmarshalRelation(encoded *osmpb.Relation, ss []string, cs *Changeset) (*Re
 	}
 
 	info := encoded.GetInfo()
+	user, err := stringAt(ss, info.GetUserSid())
+	if err != nil {
+		return nil, err
+	}
+
+	members, err := decodeMembers(ss, encoded.GetRoles(), encoded.GetRefs(), encoded.GetTypes())
+	if err != nil {
+		return nil, err
+	}
+
 	r := &Relation{
 		ID:          RelationID(encoded.GetId()),
-		User:        ss[info.GetUserSid()],
+		User:        user,
 		UserID:      UserID(info.GetUserId()),
 		Visible:     info.GetVisible(),
 		Version:     int(info.GetVersion()),
 		ChangesetID: ChangesetID(info.GetChangesetId()),
-		Timestamp:   unixToTime(info.GetTimestamp()),
+		Timestamp:   addMillis(unixToTime(info.GetTimestamp()), info.GetTimestampMs()),
 		Committed:   unixToTimePointer(info.GetCommitted()),
-		Members:     decodeMembers(ss, encoded.GetRoles(), encoded.GetRefs(), encoded.GetTypes()),
+		Members:     members,
 		Tags:        tags,
 	}
 
-	decodeDenseMembers(r.Members, encoded.GetDenseMembers())
-	r.Updates = unmarshalUpdates(encoded.GetUpdates())
+	if err := decodeDenseMembers(r.Members, encoded.GetDenseMembers()); err != nil {
+		return nil, err
+	}
+
+	r.Updates, err = unmarshalUpdates(encoded.GetUpdates())
+	if err != nil {
+		return nil, err
+	}
 
 	if cs != nil {
 		r.ChangesetID = cs.ID
@@ -300,17 +369,18 @@ func unmarshalRelation(encoded *osmpb.Relation, ss []string, cs *Changeset) (*Re
 }
 
 type denseNodesResult struct {
-	IDs        []int64
-	Lats       []int64
-	Lons       []int64
-	Timestamps []int64
-	Committeds []int64
-	Versions   []int32
-	Visibles   []bool
-	TagCount   int
+	IDs         []int64
+	Lats        []int64
+	Lons        []int64
+	Timestamps  []int64
+	TimestampMs []int32
+	Committeds  []int64
+	Versions    []int32
+	Visibles    []bool
+	TagCount    int
 }
 
-func denseNodesValues(ns Nodes) denseNodesResult {
+func denseNodesValues(ns Nodes, mode RoundingMode) denseNodesResult {
 	l := len(ns)
 	ds := denseNodesResult{
 		IDs:        make([]int64, l),
@@ -325,9 +395,15 @@ func denseNodesValues(ns Nodes) denseNodesResult {
 	cc := 0
 	for i, n := range ns {
 		ds.IDs[i] = int64(n.ID)
-		ds.Lats[i] = geoToInt64(n.Lat)
-		ds.Lons[i] = geoToInt64(n.Lon)
-		ds.Timestamps[i] = n.Timestamp.Unix()
+		ds.Lats[i] = GeoToInt64(n.Lat, mode)
+		ds.Lons[i] = GeoToInt64(n.Lon, mode)
+		ds.Timestamps[i] = timeToUnix(n.Timestamp)
+		if ms := n.Timestamp.Nanosecond() / 1e6; ms != 0 {
+			if ds.TimestampMs == nil {
+				ds.TimestampMs = make([]int32, l)
+			}
+			ds.TimestampMs[i] = int32(ms)
+		}
 		ds.Versions[i] = int32(n.Version)
 		ds.Visibles[i] = n.Visible
 		ds.TagCount += len(n.Tags)
@@ -348,7 +424,7 @@ func denseNodesValues(ns Nodes) denseNodesResult {
 func encodeNodesTags(ns Nodes, ss *stringSet, count int) []uint32 {
 	r := make([]uint32, 0, 2*count+len(ns))
 	for _, n := range ns {
-		for _, t := range n.Tags {
+		for _, t := range ss.tags(n.Tags) {
 			r = append(r, ss.Add(t.Key))
 			r = append(r, ss.Add(t.Value))
 		}
@@ -399,16 +475,14 @@ func decodeWayNodeIDs(diff []int64) WayNodes {
 	}
 
 	result := make(WayNodes, len(diff))
-	decodeInt64(diff)
-
-	for i, d := range diff {
+	for i, d := range decodeInt64(diff) {
 		result[i] = WayNode{ID: NodeID(d)}
 	}
 
 	return result
 }
 
-func encodeDenseWayNodes(waynodes WayNodes) *osmpb.DenseMembers {
+func encodeDenseWayNodes(waynodes WayNodes, mode RoundingMode) *osmpb.DenseMembers {
 	l := len(waynodes)
 
 	versions := make([]int32, l)
@@ -417,8 +491,8 @@ func encodeDenseWayNodes(waynodes WayNodes) *osmpb.DenseMembers {
 	lons := make([]int64, l)
 
 	for i, n := range waynodes {
-		lats[i] = geoToInt64(n.Lat)
-		lons[i] = geoToInt64(n.Lon)
+		lats[i] = GeoToInt64(n.Lat, mode)
+		lons[i] = GeoToInt64(n.Lon, mode)
 		versions[i] = int32(n.Version)
 		changesetIDs[i] = int64(n.ChangesetID)
 	}
@@ -431,21 +505,29 @@ func encodeDenseWayNodes(waynodes WayNodes) *osmpb.DenseMembers {
 	}
 }
 
-func decodeDenseWayNodes(waynodes WayNodes, encoded *osmpb.DenseMembers) {
+func decodeDenseWayNodes(waynodes WayNodes, encoded *osmpb.DenseMembers) error {
 	if encoded == nil {
-		return
+		return nil
+	}
+
+	l := len(encoded.Versions)
+	if l > len(waynodes) || len(encoded.ChangesetIds) != l ||
+		len(encoded.Lats) != l || len(encoded.Lons) != l {
+		return errDenseLength
 	}
 
-	decodeInt64(encoded.ChangesetIds)
-	decodeInt64(encoded.Lats)
-	decodeInt64(encoded.Lons)
+	changesetIDs := decodeInt64(encoded.ChangesetIds)
+	lats := decodeInt64(encoded.Lats)
+	lons := decodeInt64(encoded.Lons)
 
 	for i := range encoded.Versions {
 		waynodes[i].Version = int(encoded.Versions[i])
-		waynodes[i].ChangesetID = ChangesetID(encoded.ChangesetIds[i])
-		waynodes[i].Lat = float64(encoded.Lats[i]) / locMultiple
-		waynodes[i].Lon = float64(encoded.Lons[i]) / locMultiple
+		waynodes[i].ChangesetID = ChangesetID(changesetIDs[i])
+		waynodes[i].Lat = float64(lats[i]) / locMultiple
+		waynodes[i].Lon = float64(lons[i]) / locMultiple
 	}
+
+	return nil
 }
 
 func decodeMembers(
@@ -453,25 +535,34 @@ func decodeMembers(
 	roles []uint32,
 	refs []int64,
 	types []osmpb.Relation_MemberType,
-) Members {
+) (Members, error) {
 	if len(roles) == 0 {
-		return nil
+		return nil, nil
+	}
+
+	if len(refs) != len(roles) || len(types) != len(roles) {
+		return nil, errDenseLength
 	}
 
 	result := make(Members, len(roles))
-	decodeInt64(refs)
+	refs = decodeInt64(refs)
 	for i := range roles {
+		role, err := stringAt(ss, roles[i])
+		if err != nil {
+			return nil, err
+		}
+
 		result[i] = Member{
-			Role: ss[roles[i]],
+			Role: role,
 			Ref:  refs[i],
 			Type: memberTypeMapRev[types[i]],
 		}
 	}
 
-	return result
+	return result, nil
 }
 
-func encodeDenseMembers(members Members) *osmpb.DenseMembers {
+func encodeDenseMembers(members Members, mode RoundingMode) *osmpb.DenseMembers {
 	l := len(members)
 	versions := make([]int32, l)
 	changesetIDs := make([]int64, l)
@@ -486,8 +577,8 @@ func encodeDenseMembers(members Members) *osmpb.DenseMembers {
 			locCount++
 		}
 
-		lats[i] = geoToInt64(m.Lat)
-		lons[i] = geoToInt64(m.Lon)
+		lats[i] = GeoToInt64(m.Lat, mode)
+		lons[i] = GeoToInt64(m.Lon, mode)
 
 		versions[i] = int32(m.Version)
 		changesetIDs[i] = int64(m.ChangesetID)
@@ -515,121 +606,244 @@ func encodeDenseMembers(members Members) *osmpb.DenseMembers {
 	return result
 }
 
-func decodeDenseMembers(members Members, encoded *osmpb.DenseMembers) {
+func decodeDenseMembers(members Members, encoded *osmpb.DenseMembers) error {
 	if encoded == nil || len(encoded.Versions) == 0 {
-		return
+		return nil
+	}
+
+	l := len(encoded.Versions)
+	if l > len(members) || len(encoded.ChangesetIds) != l ||
+		(encoded.Orientation != nil && len(encoded.Orientation) != l) ||
+		(encoded.Lats != nil && len(encoded.Lats) != l) || len(encoded.Lats) != len(encoded.Lons) {
+		return errDenseLength
 	}
 
-	decodeInt64(encoded.ChangesetIds)
-	decodeInt64(encoded.Lats)
-	decodeInt64(encoded.Lons)
+	changesetIDs := decodeInt64(encoded.ChangesetIds)
+	lats := decodeInt64(encoded.Lats)
+	lons := decodeInt64(encoded.Lons)
 
 	for i := range encoded.Versions {
 		members[i].Version = int(encoded.Versions[i])
-		members[i].ChangesetID = ChangesetID(encoded.ChangesetIds[i])
+		members[i].ChangesetID = ChangesetID(changesetIDs[i])
 
 		if encoded.Orientation != nil {
 			members[i].Orientation = orb.Orientation(encoded.Orientation[i])
 		}
 
-		if encoded.Lats != nil {
-			members[i].Lat = float64(encoded.Lats[i]) / locMultiple
-			members[i].Lon = float64(encoded.Lons[i]) / locMultiple
+		if lats != nil {
+			members[i].Lat = float64(lats[i]) / locMultiple
+			members[i].Lon = float64(lons[i]) / locMultiple
+		}
+	}
+
+	return nil
+}
+
+var (
+	errStringIndex = errors.New("osm: string index out of range")
+	errDenseLength = errors.New("osm: dense arrays have different lengths")
+	errDenseTags   = errors.New("osm: dense node tags are truncated")
+)
+
+// stringAt returns the string from the string table,
+// or an error if the index is invalid, ie. corrupted data.
+func stringAt(ss []string, i uint32) (string, error) {
+	if uint64(i) >= uint64(len(ss)) {
+		return "", errStringIndex
+	}
+
+	return ss[i], nil
+}
+
+// checkDenseNodes validates the lengths of the parallel arrays
+// so truncated data returns an error instead of panicking.
+func checkDenseNodes(encoded *osmpb.DenseNodes) error {
+	l := len(encoded.Ids)
+	if len(encoded.Lats) != l || len(encoded.Lons) != l {
+		return errDenseLength
+	}
+
+	info := encoded.DenseInfo
+	if info == nil {
+		if l == 0 {
+			return nil
+		}
+		return errors.New("osm: dense nodes missing info")
+	}
+
+	if len(info.Visibles) != l || len(info.Versions) != l || len(info.Timestamps) != l {
+		return errDenseLength
+	}
+
+	for _, n := range []int{len(info.ChangesetIds), len(info.UserIds), len(info.UserSids)} {
+		if n != 0 && n != l {
+			return errDenseLength
+		}
+	}
+
+	return nil
+}
+
+// denseNodeTags reads the tags of one node from the keys_vals
+// array starting at loc. It returns the location of the next node.
+func denseNodeTags(ss []string, keysVals []uint32, loc int) (Tags, int, error) {
+	var tags Tags
+	for {
+		if loc >= len(keysVals) {
+			return nil, loc, errDenseTags
+		}
+
+		if keysVals[loc] == 0 {
+			return tags, loc + 1, nil
+		}
+
+		if loc+1 >= len(keysVals) {
+			return nil, loc, errDenseTags
+		}
+
+		k, err := stringAt(ss, keysVals[loc])
+		if err != nil {
+			return nil, loc, err
 		}
+
+		v, err := stringAt(ss, keysVals[loc+1])
+		if err != nil {
+			return nil, loc, err
+		}
+
+		tags = append(tags, Tag{Key: k, Value: v})
+		loc += 2
 	}
 }
 
+// The delta encode and decode functions return a new slice and do not
+// modify the input. This keeps the source objects, and the decoded
+// protobuf messages, unchanged so marshalling is idempotent and safe
+// to do concurrently.
+
 func encodeInt32(vals []int32) []int32 {
+	if vals == nil {
+		return nil
+	}
+
+	result := make([]int32, len(vals))
 	var prev int32
 	for i, v := range vals {
-		vals[i] = v - prev
+		result[i] = v - prev
 		prev = v
 	}
 
-	return vals
+	return result
 }
 
 func encodeInt64(vals []int64) []int64 {
+	if vals == nil {
+		return nil
+	}
+
+	result := make([]int64, len(vals))
 	var prev int64
 	for i, v := range vals {
-		vals[i] = v - prev
+		result[i] = v - prev
 		prev = v
 	}
 
-	return vals
+	return result
 }
 
 func decodeInt32(vals []int32) []int32 {
+	if vals == nil {
+		return nil
+	}
+
+	result := make([]int32, len(vals))
 	var prev int32
 	for i, v := range vals {
 		prev += v
-		vals[i] = prev
+		result[i] = prev
 	}
 
-	return vals
+	return result
 }
 
 func decodeInt64(vals []int64) []int64 {
+	if vals == nil {
+		return nil
+	}
+
+	result := make([]int64, len(vals))
 	var prev int64
 	for i, v := range vals {
 		prev += v
-		vals[i] = prev
+		result[i] = prev
 	}
 
-	return vals
+	return result
 }
 
-func geoToInt64(l float64) int64 {
-	// on rounding errors
-	//
-	// It is the case that 32.850314 * 10e6 = 32850313.999999996
-	// Simpily casting this as an int will truncate towards zero
-	// and result in an off by one. The true solution is to round
-	// the scaled result, like so:
-	//
-	// int64(math.Floor(stream.BaseData[i][0]*factor + 0.5))
-	//
-	// However, the code below does the same thing in this context,
-	// and is twice as fast:
-	sign := 0.5
-	if l < 0 {
-		sign = -0.5
+// Times are encoded as unix seconds with zero reserved for "unset", the zero
+// time.Time. To keep epoch and pre-epoch times representable, non-positive
+// values are shifted down by one, so 1970-01-01T00:00:00Z is encoded as -1.
+func timeToUnix(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
 	}
 
-	return int64(l*locMultiple + sign)
-}
-
-func timeToUnix(t time.Time) int64 {
 	u := t.Unix()
 	if u <= 0 {
-		return 0
+		return u - 1
 	}
 
 	return u
 }
 
+// timeToMillis returns the sub-second part of the time in milliseconds,
+// or nil if the time is a whole second.
+func timeToMillis(t time.Time) *int32 {
+	ms := int32(t.Nanosecond() / 1e6)
+	if ms == 0 {
+		return nil
+	}
+
+	return &ms
+}
+
+// addMillis adds the sub-second part of the time back
+// to a time decoded from unix seconds.
+func addMillis(t time.Time, ms int32) time.Time {
+	if ms == 0 || t.IsZero() {
+		return t
+	}
+
+	return t.Add(time.Duration(ms) * time.Millisecond)
+}
+
 func timeToUnixPointer(t time.Time) *int64 {
-	u := t.Unix()
-	if u <= 0 {
+	if t.IsZero() {
 		return nil
 	}
 
+	u := timeToUnix(t)
 	return &u
 }
 
 func unixToTime(u int64) time.Time {
-	if u <= 0 {
+	if u == 0 {
 		return time.Time{}
 	}
 
+	if u < 0 {
+		u++
+	}
+
 	return time.Unix(u, 0).UTC()
 }
 
 func unixToTimePointer(u int64) *time.Time {
-	if u <= 0 {
+	if u == 0 {
 		return nil
 	}
 
-	t := time.Unix(u, 0).UTC()
+	t := unixToTime(u)
 	return &t
 }
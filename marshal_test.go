@@ -57,6 +57,295 @@ func TestNodes_Marshal(t *testing.T) {
 	checkMarshal(t, o)
 }
 
+func TestMarshal_singleElement(t *testing.T) {
+	c := loadChange(t, "testdata/changeset_38162210.osc")
+
+	n := c.Create.Nodes[12]
+	data, err := n.Marshal()
+	if err != nil {
+		t.Fatalf("marshal error: %v", err)
+	}
+
+	list, _ := Nodes{n}.Marshal()
+	if len(data) >= len(list) {
+		t.Errorf("should be smaller than a list: %d >= %d", len(data), len(list))
+	}
+
+	n2, err := UnmarshalNode(data)
+	if err != nil {
+		t.Fatalf("unmarshal error: %v", err)
+	}
+
+	if !reflect.DeepEqual(n, n2) {
+		t.Errorf("incorrect node: %+v", n2)
+	}
+
+	w := c.Create.Ways[5]
+	data, err = w.Marshal(WithDictionary(DefaultDictionary))
+	if err != nil {
+		t.Fatalf("marshal error: %v", err)
+	}
+
+	w2, err := UnmarshalWay(data)
+	if err != nil {
+		t.Fatalf("unmarshal error: %v", err)
+	}
+
+	if !reflect.DeepEqual(w, w2) {
+		t.Errorf("incorrect way: %+v", w2)
+	}
+
+	if _, err := UnmarshalRelation(data); err == nil {
+		t.Errorf("should error for a different type")
+	}
+
+	r := loadChange(t, "testdata/changeset_38162206.osc").Create.Relations[0]
+	data, err = r.Marshal(WithChecksum())
+	if err != nil {
+		t.Fatalf("marshal error: %v", err)
+	}
+
+	r2, err := UnmarshalRelation(data)
+	if err != nil {
+		t.Fatalf("unmarshal error: %v", err)
+	}
+
+	if !reflect.DeepEqual(r, r2) {
+		t.Errorf("incorrect relation: %+v", r2)
+	}
+
+	data, _ = Nodes{n, n}.Marshal()
+	if _, err := UnmarshalNode(data); err == nil {
+		t.Errorf("should error for a list of nodes")
+	}
+}
+
+func TestMarshal_subSecondTimestamps(t *testing.T) {
+	ts := time.Date(2017, 1, 1, 10, 20, 30, 123456789, time.UTC)
+	o := &OSM{
+		Nodes: Nodes{
+			{ID: 1, Version: 1, Visible: true, Timestamp: ts},
+			{ID: 2, Version: 1, Visible: true, Timestamp: ts.Truncate(time.Second)},
+		},
+		Ways:      Ways{{ID: 1, Version: 1, Visible: true, Timestamp: ts}},
+		Relations: Relations{{ID: 1, Version: 1, Visible: true, Timestamp: ts}},
+	}
+
+	data, err := o.Marshal()
+	if err != nil {
+		t.Fatalf("marshal error: %v", err)
+	}
+
+	o2, err := UnmarshalOSM(data)
+	if err != nil {
+		t.Fatalf("unmarshal error: %v", err)
+	}
+
+	expected := ts.Truncate(time.Millisecond)
+	if v := o2.Nodes[0].Timestamp; !v.Equal(expected) {
+		t.Errorf("incorrect node timestamp: %v", v)
+	}
+
+	if v := o2.Nodes[1].Timestamp; !v.Equal(ts.Truncate(time.Second)) {
+		t.Errorf("incorrect node timestamp: %v", v)
+	}
+
+	if v := o2.Ways[0].Timestamp; !v.Equal(expected) {
+		t.Errorf("incorrect way timestamp: %v", v)
+	}
+
+	if v := o2.Relations[0].Timestamp; !v.Equal(expected) {
+		t.Errorf("incorrect relation timestamp: %v", v)
+	}
+}
+
+func TestTimeToUnix(t *testing.T) {
+	cases := []struct {
+		name string
+		time time.Time
+	}{
+		{
+			name: "zero",
+			time: time.Time{},
+		},
+		{
+			name: "epoch",
+			time: time.Unix(0, 0).UTC(),
+		},
+		{
+			name: "one second after epoch",
+			time: time.Unix(1, 0).UTC(),
+		},
+		{
+			name: "one second before epoch",
+			time: time.Unix(-1, 0).UTC(),
+		},
+		{
+			name: "pre epoch",
+			time: time.Date(1950, 6, 1, 12, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "recent",
+			time: time.Date(2017, 6, 1, 12, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			u := timeToUnix(tc.time)
+			if u == 0 && !tc.time.IsZero() {
+				t.Errorf("non zero time encoded as unset")
+			}
+
+			if v := unixToTime(u); !v.Equal(tc.time) {
+				t.Errorf("incorrect round trip: %v != %v", v, tc.time)
+			}
+
+			p := timeToUnixPointer(tc.time)
+			if tc.time.IsZero() {
+				if p != nil {
+					t.Errorf("zero time should be nil")
+				}
+				return
+			}
+
+			if v := unixToTimePointer(*p); !v.Equal(tc.time) {
+				t.Errorf("incorrect pointer round trip: %v != %v", v, tc.time)
+			}
+		})
+	}
+}
+
+func TestMarshal_epochTimestamps(t *testing.T) {
+	epoch := time.Unix(0, 0).UTC()
+	before := time.Date(1969, 12, 31, 23, 59, 59, 500000000, time.UTC)
+
+	o := &OSM{
+		Nodes: Nodes{
+			{ID: 1, Version: 1, Timestamp: epoch},
+			{ID: 2, Version: 1, Timestamp: before},
+			{ID: 3, Version: 1},
+		},
+		Ways: Ways{
+			{ID: 1, Version: 1, Timestamp: epoch, Committed: &before},
+			{ID: 2, Version: 1},
+		},
+	}
+
+	data, err := o.Marshal()
+	if err != nil {
+		t.Fatalf("marshal error: %v", err)
+	}
+
+	o2, err := UnmarshalOSM(data)
+	if err != nil {
+		t.Fatalf("unmarshal error: %v", err)
+	}
+
+	if v := o2.Nodes[0].Timestamp; !v.Equal(epoch) {
+		t.Errorf("incorrect epoch: %v", v)
+	}
+
+	if v := o2.Nodes[1].Timestamp; !v.Equal(before) {
+		t.Errorf("incorrect pre epoch: %v", v)
+	}
+
+	if v := o2.Nodes[2].Timestamp; !v.IsZero() {
+		t.Errorf("unset should be zero: %v", v)
+	}
+
+	if v := o2.Ways[0].Timestamp; !v.Equal(epoch) {
+		t.Errorf("incorrect way epoch: %v", v)
+	}
+
+	if v := o2.Ways[0].Committed; v == nil || !v.Truncate(time.Second).Equal(before.Truncate(time.Second)) {
+		t.Errorf("incorrect way committed: %v", v)
+	}
+
+	if v := o2.Ways[1].Timestamp; !v.IsZero() {
+		t.Errorf("unset should be zero: %v", v)
+	}
+}
+
+func TestUnmarshalOSM_corrupted(t *testing.T) {
+	files, err := ioutil.ReadDir("testdata/corrupt")
+	if err != nil {
+		t.Fatalf("unable to read corpus: %v", err)
+	}
+
+	if len(files) == 0 {
+		t.Fatalf("no corrupted files found")
+	}
+
+	for _, f := range files {
+		t.Run(f.Name(), func(t *testing.T) {
+			data, err := ioutil.ReadFile("testdata/corrupt/" + f.Name())
+			if err != nil {
+				t.Fatalf("unable to read file: %v", err)
+			}
+
+			_, err = UnmarshalOSM(data)
+			if err == nil {
+				t.Errorf("expected error")
+			}
+		})
+	}
+}
+
+func TestUnmarshalOSM_truncated(t *testing.T) {
+	c := loadChange(t, "testdata/changeset_38162210.osc")
+	data, err := c.Create.Marshal()
+	if err != nil {
+		t.Fatalf("marshal error: %v", err)
+	}
+
+	// should error or decode, but never panic
+	for i := 0; i < len(data); i += 7 {
+		UnmarshalOSM(data[:i])
+	}
+}
+
+func TestMarshal_idempotent(t *testing.T) {
+	c := loadChange(t, "testdata/changeset_38162210.osc")
+	o := c.Create
+
+	data1, err := o.Marshal()
+	if err != nil {
+		t.Fatalf("marshal error: %v", err)
+	}
+
+	data2, err := o.Marshal()
+	if err != nil {
+		t.Fatalf("marshal error: %v", err)
+	}
+
+	if !bytes.Equal(data1, data2) {
+		t.Errorf("marshalling twice should produce the same data")
+	}
+
+	// decoding the same message twice should give the same result
+	ss := &stringSet{}
+	encoded := marshalOSM(o, ss, true, RoundHalfAwayFromZero)
+
+	o1, err := unmarshalOSM(encoded, ss.Strings(), nil)
+	if err != nil {
+		t.Fatalf("unmarshal error: %v", err)
+	}
+
+	o2, err := unmarshalOSM(encoded, ss.Strings(), nil)
+	if err != nil {
+		t.Fatalf("unmarshal error: %v", err)
+	}
+
+	if !reflect.DeepEqual(o1, o2) {
+		t.Errorf("unmarshalling twice should produce the same data")
+	}
+
+	if o1.Nodes[0].ID != o.Nodes[0].ID || o1.Ways[0].Nodes[1].ID != o.Ways[0].Nodes[1].ID {
+		t.Errorf("incorrect unmarshal")
+	}
+}
+
 func TestWay_Marshal(t *testing.T) {
 	c := loadChange(t, "testdata/changeset_38162210.osc")
 	w := c.Create.Ways[5]
@@ -413,3 +702,30 @@ func checkMarshal(t testing.TB, o1 *OSM) *OSM {
 
 	return o2
 }
+
+func TestDeltaEncoding_doesNotModifyInput(t *testing.T) {
+	vals := []int64{5, 7, 10, 3}
+	encoded := encodeInt64(vals)
+	if !reflect.DeepEqual(vals, []int64{5, 7, 10, 3}) {
+		t.Errorf("encode modified input: %v", vals)
+	}
+
+	if !reflect.DeepEqual(encoded, []int64{5, 2, 3, -7}) {
+		t.Errorf("incorrect encoding: %v", encoded)
+	}
+
+	decoded := decodeInt64(encoded)
+	if !reflect.DeepEqual(encoded, []int64{5, 2, 3, -7}) {
+		t.Errorf("decode modified input: %v", encoded)
+	}
+
+	if !reflect.DeepEqual(decoded, vals) {
+		t.Errorf("incorrect decoding: %v", decoded)
+	}
+
+	vals32 := []int32{5, 7, 10, 3}
+	decoded32 := decodeInt32(encodeInt32(vals32))
+	if !reflect.DeepEqual(decoded32, []int32{5, 7, 10, 3}) || !reflect.DeepEqual(vals32, decoded32) {
+		t.Errorf("incorrect int32 round trip: %v", decoded32)
+	}
+}
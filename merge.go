@@ -0,0 +1,193 @@
+package osm
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// A TagConflict is a key with different values on the merged nodes.
+// The kept value is used in the result.
+type TagConflict struct {
+	Key       string
+	Kept      string
+	Discarded string
+}
+
+// A MergeResult is the outcome of merging two nodes.
+type MergeResult struct {
+	// Node is the kept node with the combined tags.
+	Node *Node
+
+	// Discarded is the node to be deleted.
+	Discarded *Node
+
+	// Ways and Relations are copies of the elements that referenced
+	// the discarded node, updated to reference the kept node.
+	Ways      Ways
+	Relations Relations
+
+	// Conflicts are the tag keys with different values,
+	// sorted by key.
+	Conflicts []TagConflict
+
+	tagsChanged bool
+}
+
+// MergeNodes merges the discarded node into the kept node. The ways and
+// relations are the parents of the discarded node, elements that do not
+// reference it are ignored. References to the discarded node are replaced,
+// repeated consecutive way nodes are removed as are duplicate relation
+// members. Tags of the discarded node are added to the kept node, keys
+// with different values keep the kept value and are reported as conflicts.
+// The kept node keeps its location. The inputs are not modified and the
+// updates of the copied ways and relations are cleared.
+func MergeNodes(keep, discard *Node, ways Ways, relations Relations) (*MergeResult, error) {
+	if keep == nil || discard == nil {
+		return nil, errors.New("osm: nodes to merge must not be nil")
+	}
+
+	if keep.ID == discard.ID {
+		return nil, fmt.Errorf("osm: can not merge node %d with itself", keep.ID)
+	}
+
+	result := &MergeResult{
+		Node:      keep.Copy(),
+		Discarded: discard,
+	}
+
+	kept := keep.Tags.Map()
+	for _, t := range discard.Tags {
+		v, ok := kept[t.Key]
+		if !ok {
+			result.Node.Tags = append(result.Node.Tags, t)
+			result.tagsChanged = true
+		} else if v != t.Value {
+			result.Conflicts = append(result.Conflicts, TagConflict{Key: t.Key, Kept: v, Discarded: t.Value})
+		}
+	}
+
+	sort.Slice(result.Conflicts, func(i, j int) bool {
+		return result.Conflicts[i].Key < result.Conflicts[j].Key
+	})
+
+	for _, w := range ways {
+		if !wayReferences(w, discard.ID) {
+			continue
+		}
+
+		w = w.Copy()
+		for i := range w.Nodes {
+			if w.Nodes[i].ID == discard.ID {
+				w.Nodes[i] = WayNode{ID: keep.ID, Lat: keep.Lat, Lon: keep.Lon}
+			}
+		}
+
+		nodes := w.Nodes[:0]
+		for i, wn := range w.Nodes {
+			if i > 0 && wn.ID == w.Nodes[i-1].ID {
+				continue
+			}
+			nodes = append(nodes, wn)
+		}
+		w.Nodes = nodes
+		w.Updates = nil
+
+		if len(w.Nodes) < 2 || (len(w.Nodes) < 4 && w.Nodes[0].ID == w.Nodes[len(w.Nodes)-1].ID) {
+			return nil, fmt.Errorf("osm: merging nodes %d and %d would leave way %d with too few nodes", keep.ID, discard.ID, w.ID)
+		}
+
+		result.Ways = append(result.Ways, w)
+	}
+
+	for _, r := range relations {
+		if !relationReferences(r, discard.ID) {
+			continue
+		}
+
+		r = r.Copy()
+
+		seen := make(map[Member]bool, len(r.Members))
+		members := r.Members[:0]
+		for _, m := range r.Members {
+			if m.Type == TypeNode && m.Ref == int64(discard.ID) {
+				m.Ref = int64(keep.ID)
+				m.Lat, m.Lon = keep.Lat, keep.Lon
+			}
+
+			if m.Type == TypeNode {
+				key := Member{Type: m.Type, Ref: m.Ref, Role: m.Role}
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+			}
+
+			members = append(members, m)
+		}
+		r.Members = members
+		r.Updates = nil
+
+		result.Relations = append(result.Relations, r)
+	}
+
+	return result, nil
+}
+
+// Build adds the edits of the merge to the change builder. The kept node
+// is modified if its tags changed, the parent ways and relations are
+// modified and the discarded node is deleted. Elements without a version,
+// not yet uploaded, are created and the discarded node is then not deleted.
+func (r *MergeResult) Build(b *ChangeBuilder) error {
+	if r.tagsChanged {
+		if err := createOrModify(b, r.Node); err != nil {
+			return err
+		}
+	}
+
+	for _, w := range r.Ways {
+		if err := createOrModify(b, w); err != nil {
+			return err
+		}
+	}
+
+	for _, rel := range r.Relations {
+		if err := createOrModify(b, rel); err != nil {
+			return err
+		}
+	}
+
+	if r.Discarded.Version == 0 {
+		return nil
+	}
+
+	return b.Delete(r.Discarded)
+}
+
+func createOrModify(b *ChangeBuilder, e Element) error {
+	if elementVersion(e) == 0 {
+		return b.Create(e)
+	}
+
+	return b.Modify(e)
+}
+
+func wayReferences(w *Way, id NodeID) bool {
+	for _, wn := range w.Nodes {
+		if wn.ID == id {
+			return true
+		}
+	}
+
+	return false
+}
+
+func relationReferences(r *Relation, id NodeID) bool {
+	for _, m := range r.Members {
+		if m.Type == TypeNode && m.Ref == int64(id) {
+			return true
+		}
+	}
+
+	return false
+}
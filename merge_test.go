@@ -0,0 +1,100 @@
+package osm
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMergeNodes(t *testing.T) {
+	keep := &Node{ID: 1, Version: 2, Lat: 1, Lon: 2, Tags: Tags{
+		{Key: "amenity", Value: "cafe"},
+		{Key: "name", Value: "Central"},
+	}}
+	discard := &Node{ID: 2, Version: 1, Lat: 1.1, Lon: 2.1, Tags: Tags{
+		{Key: "name", Value: "Central Cafe"},
+		{Key: "opening_hours", Value: "24/7"},
+	}}
+
+	ways := Ways{
+		{ID: 1, Version: 1, Nodes: WayNodes{{ID: 3}, {ID: 2}, {ID: 1}, {ID: 4}}},
+		{ID: 2, Version: 1, Nodes: WayNodes{{ID: 3}, {ID: 4}}},
+	}
+	relations := Relations{
+		{ID: 1, Version: 1, Members: Members{
+			{Type: TypeNode, Ref: 1, Role: "stop"},
+			{Type: TypeNode, Ref: 2, Role: "stop"},
+			{Type: TypeWay, Ref: 1},
+		}},
+	}
+
+	result, err := MergeNodes(keep, discard, ways, relations)
+	if err != nil {
+		t.Fatalf("merge error: %v", err)
+	}
+
+	if result.Node.Tags.Find("opening_hours") != "24/7" || result.Node.Tags.Find("name") != "Central" {
+		t.Errorf("incorrect tags: %v", result.Node.Tags)
+	}
+
+	if len(keep.Tags) != 2 {
+		t.Errorf("should not modify the input node: %v", keep.Tags)
+	}
+
+	expected := []TagConflict{{Key: "name", Kept: "Central", Discarded: "Central Cafe"}}
+	if !reflect.DeepEqual(result.Conflicts, expected) {
+		t.Errorf("incorrect conflicts: %v", result.Conflicts)
+	}
+
+	if len(result.Ways) != 1 {
+		t.Fatalf("should only return referencing ways: %v", result.Ways)
+	}
+
+	if ids := result.Ways[0].Nodes.NodeIDs(); !reflect.DeepEqual(ids, []NodeID{3, 1, 4}) {
+		t.Errorf("incorrect way nodes: %v", ids)
+	}
+
+	if ways[0].Nodes[1].ID != 2 {
+		t.Errorf("should not modify the input way")
+	}
+
+	if len(result.Relations) != 1 || len(result.Relations[0].Members) != 2 {
+		t.Errorf("should remove duplicate member: %v", result.Relations)
+	}
+
+	b := NewChangeBuilder()
+	if err := result.Build(b); err != nil {
+		t.Fatalf("build error: %v", err)
+	}
+
+	changes, err := b.Build()
+	if err != nil {
+		t.Fatalf("build error: %v", err)
+	}
+
+	c := changes[0]
+	if len(c.Modify.Nodes) != 1 || len(c.Modify.Ways) != 1 || len(c.Modify.Relations) != 1 {
+		t.Errorf("incorrect modifies: %v", c.Modify)
+	}
+
+	if len(c.Delete.Nodes) != 1 || c.Delete.Nodes[0].ID != 2 {
+		t.Errorf("incorrect deletes: %v", c.Delete)
+	}
+}
+
+func TestMergeNodes_errors(t *testing.T) {
+	n1 := &Node{ID: 1}
+	n2 := &Node{ID: 2}
+
+	if _, err := MergeNodes(n1, n1, nil, nil); err == nil {
+		t.Errorf("should error merging node with itself")
+	}
+
+	if _, err := MergeNodes(n1, nil, nil, nil); err == nil {
+		t.Errorf("should error on nil node")
+	}
+
+	ways := Ways{{ID: 1, Nodes: WayNodes{{ID: 1}, {ID: 2}}}}
+	if _, err := MergeNodes(n1, n2, ways, nil); err == nil {
+		t.Errorf("should error on degenerate way")
+	}
+}
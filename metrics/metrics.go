@@ -0,0 +1,66 @@
+// Package metrics defines the optional hooks used to instrument decoding,
+// api calls and replication, and a Prometheus adapter to expose them.
+// The instrumented packages do nothing if no Recorder is set.
+package metrics
+
+// A Recorder receives the measurements. Implementations must be safe
+// for concurrent use since the pbf decoder records from multiple goroutines.
+type Recorder interface {
+	// Add increments the counter by the value.
+	Add(name string, value float64, labels ...Label)
+
+	// Set sets the gauge to the value.
+	Set(name string, value float64, labels ...Label)
+
+	// Observe adds the value, e.g. a duration in seconds, to the histogram.
+	Observe(name string, value float64, labels ...Label)
+}
+
+// A Label is a dimension of a measurement, e.g. the element type.
+type Label struct {
+	Name  string
+	Value string
+}
+
+// The names of the metrics recorded by the packages.
+const (
+	// PBFBlocksDecoded counts the osmpbf data blocks decoded.
+	PBFBlocksDecoded = "osm_pbf_blocks_decoded_total"
+
+	// PBFElementsDecoded counts the elements decoded by type. Its rate
+	// is the elements decoded per second.
+	PBFElementsDecoded = "osm_pbf_elements_decoded_total"
+
+	// PBFBlockDecodeSeconds is the histogram of the time to decompress
+	// and decode a block.
+	PBFBlockDecodeSeconds = "osm_pbf_block_decode_seconds"
+
+	// APIRequestSeconds is the histogram of osmapi call latencies,
+	// labeled by the call, e.g. node or map, and the status code.
+	APIRequestSeconds = "osm_api_request_duration_seconds"
+
+	// ReplicationLagSeconds is the age of the newest element in the last
+	// processed replication change, labeled by the interval.
+	ReplicationLagSeconds = "osm_replication_lag_seconds"
+
+	// ReplicationSeqNum is the last processed sequence number.
+	ReplicationSeqNum = "osm_replication_sequence_number"
+
+	// ReplicationPending is the number of changes between the
+	// last processed and the current sequence number.
+	ReplicationPending = "osm_replication_pending_changes"
+
+	// ReplicationChanges counts the processed replication changes.
+	ReplicationChanges = "osm_replication_changes_total"
+)
+
+var help = map[string]string{
+	PBFBlocksDecoded:      "Number of osm pbf data blocks decoded.",
+	PBFElementsDecoded:    "Number of osm pbf elements decoded.",
+	PBFBlockDecodeSeconds: "Time to decompress and decode an osm pbf block.",
+	APIRequestSeconds:     "Latency of osm api calls.",
+	ReplicationLagSeconds: "Age of the newest element in the last processed replication change.",
+	ReplicationSeqNum:     "Last processed replication sequence number.",
+	ReplicationPending:    "Number of replication changes not yet processed.",
+	ReplicationChanges:    "Number of replication changes processed.",
+}
@@ -0,0 +1,212 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultBuckets are the histogram upper bounds in seconds,
+// the same as the Prometheus client defaults.
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Prometheus is a Recorder that keeps the measurements in memory and
+// serves them in the Prometheus text format. It has no dependencies, mount
+// it on the /metrics path of the service. It is safe for concurrent use.
+type Prometheus struct {
+	// Buckets are the histogram upper bounds,
+	// DefaultBuckets is used if empty.
+	Buckets []float64
+
+	mu      sync.Mutex
+	metrics map[string]*family
+}
+
+var _ Recorder = &Prometheus{}
+var _ http.Handler = &Prometheus{}
+
+// NewPrometheus creates an empty Prometheus recorder.
+func NewPrometheus() *Prometheus {
+	return &Prometheus{metrics: make(map[string]*family)}
+}
+
+type family struct {
+	kind   string
+	series map[string]*series
+}
+
+type series struct {
+	labels  string
+	value   float64
+	buckets []uint64
+	count   uint64
+}
+
+// Add increments the counter by the value.
+func (p *Prometheus) Add(name string, value float64, labels ...Label) {
+	p.mu.Lock()
+	p.series(name, "counter", labels).value += value
+	p.mu.Unlock()
+}
+
+// Set sets the gauge to the value.
+func (p *Prometheus) Set(name string, value float64, labels ...Label) {
+	p.mu.Lock()
+	p.series(name, "gauge", labels).value = value
+	p.mu.Unlock()
+}
+
+// Observe adds the value to the histogram.
+func (p *Prometheus) Observe(name string, value float64, labels ...Label) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	s := p.series(name, "histogram", labels)
+	if s.buckets == nil {
+		s.buckets = make([]uint64, len(p.buckets()))
+	}
+
+	for i, b := range p.buckets() {
+		if value <= b {
+			s.buckets[i]++
+		}
+	}
+
+	s.value += value
+	s.count++
+}
+
+func (p *Prometheus) buckets() []float64 {
+	if len(p.Buckets) > 0 {
+		return p.Buckets
+	}
+
+	return DefaultBuckets
+}
+
+func (p *Prometheus) series(name, kind string, labels []Label) *series {
+	if p.metrics == nil {
+		p.metrics = make(map[string]*family)
+	}
+
+	f := p.metrics[name]
+	if f == nil {
+		f = &family{kind: kind, series: make(map[string]*series)}
+		p.metrics[name] = f
+	}
+
+	key := formatLabels(labels)
+	s := f.series[key]
+	if s == nil {
+		s = &series{labels: key}
+		f.series[key] = s
+	}
+
+	return s
+}
+
+// ServeHTTP writes the metrics in the Prometheus text exposition format.
+func (p *Prometheus) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+
+	p.Write(w)
+}
+
+// Write writes the metrics in the Prometheus text exposition format,
+// ordered by name and labels.
+func (p *Prometheus) Write(out io.Writer) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	w := bufio.NewWriter(out)
+
+	names := make([]string, 0, len(p.metrics))
+	for name := range p.metrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		f := p.metrics[name]
+		if h, ok := help[name]; ok {
+			fmt.Fprintf(w, "# HELP %s %s\n", name, h)
+		}
+		fmt.Fprintf(w, "# TYPE %s %s\n", name, f.kind)
+
+		keys := make([]string, 0, len(f.series))
+		for k := range f.series {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			s := f.series[k]
+			if f.kind != "histogram" {
+				fmt.Fprintf(w, "%s%s %s\n", name, braces(s.labels), formatFloat(s.value))
+				continue
+			}
+
+			for i, b := range p.buckets() {
+				le := `le="` + formatFloat(b) + `"`
+				fmt.Fprintf(w, "%s_bucket%s %d\n", name, braces(join(s.labels, le)), s.buckets[i])
+			}
+			fmt.Fprintf(w, "%s_bucket%s %d\n", name, braces(join(s.labels, `le="+Inf"`)), s.count)
+			fmt.Fprintf(w, "%s_sum%s %s\n", name, braces(s.labels), formatFloat(s.value))
+			fmt.Fprintf(w, "%s_count%s %d\n", name, braces(s.labels), s.count)
+		}
+	}
+
+	return w.Flush()
+}
+
+func formatLabels(labels []Label) string {
+	if len(labels) == 0 {
+		return ""
+	}
+
+	sorted := make([]Label, len(labels))
+	copy(sorted, labels)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+
+	parts := make([]string, len(sorted))
+	for i, l := range sorted {
+		parts[i] = l.Name + `="` + labelEscaper.Replace(l.Value) + `"`
+	}
+
+	return strings.Join(parts, ",")
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+
+func join(a, b string) string {
+	if a == "" {
+		return b
+	}
+
+	return a + "," + b
+}
+
+func braces(s string) string {
+	if s == "" {
+		return ""
+	}
+
+	return "{" + s + "}"
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
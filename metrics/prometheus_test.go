@@ -0,0 +1,71 @@
+package metrics
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestPrometheus(t *testing.T) {
+	p := NewPrometheus()
+	p.Buckets = []float64{0.1, 1}
+
+	p.Add(PBFBlocksDecoded, 1)
+	p.Add(PBFBlocksDecoded, 2)
+	p.Set(ReplicationSeqNum, 123, Label{Name: "interval", Value: "minute"})
+	p.Add("custom_total", 1, Label{Name: "b", Value: "2"}, Label{Name: "a", Value: `x"y`})
+	p.Observe(APIRequestSeconds, 0.05, Label{Name: "call", Value: "node"})
+	p.Observe(APIRequestSeconds, 0.5, Label{Name: "call", Value: "node"})
+
+	buf := &bytes.Buffer{}
+	if err := p.Write(buf); err != nil {
+		t.Fatalf("write error: %v", err)
+	}
+
+	expected := `# TYPE custom_total counter
+custom_total{a="x\"y",b="2"} 1
+# HELP osm_api_request_duration_seconds Latency of osm api calls.
+# TYPE osm_api_request_duration_seconds histogram
+osm_api_request_duration_seconds_bucket{call="node",le="0.1"} 1
+osm_api_request_duration_seconds_bucket{call="node",le="1"} 2
+osm_api_request_duration_seconds_bucket{call="node",le="+Inf"} 2
+osm_api_request_duration_seconds_sum{call="node"} 0.55
+osm_api_request_duration_seconds_count{call="node"} 2
+# HELP osm_pbf_blocks_decoded_total Number of osm pbf data blocks decoded.
+# TYPE osm_pbf_blocks_decoded_total counter
+osm_pbf_blocks_decoded_total 3
+# HELP osm_replication_sequence_number Last processed replication sequence number.
+# TYPE osm_replication_sequence_number gauge
+osm_replication_sequence_number{interval="minute"} 123
+`
+
+	if buf.String() != expected {
+		t.Errorf("incorrect output:\n%s", buf.String())
+	}
+}
+
+func TestPrometheus_ServeHTTP(t *testing.T) {
+	p := &Prometheus{}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.Add(PBFBlocksDecoded, 1)
+		}()
+	}
+	wg.Wait()
+
+	w := httptest.NewRecorder()
+	p.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+
+	if ct := w.Header().Get("Content-Type"); ct != "text/plain; version=0.0.4; charset=utf-8" {
+		t.Errorf("incorrect content type: %v", ct)
+	}
+
+	if !bytes.Contains(w.Body.Bytes(), []byte("osm_pbf_blocks_decoded_total 10\n")) {
+		t.Errorf("incorrect body: %s", w.Body.String())
+	}
+}
@@ -0,0 +1,167 @@
+package network
+
+import (
+	"strings"
+
+	"github.com/paulmach/osm"
+)
+
+// highways are the highway values usable by each mode by default.
+var highways = map[Mode]map[string]bool{
+	Car: set(
+		"motorway", "motorway_link", "trunk", "trunk_link",
+		"primary", "primary_link", "secondary", "secondary_link",
+		"tertiary", "tertiary_link", "unclassified", "residential",
+		"living_street", "service", "road", "track",
+	),
+	Bicycle: set(
+		"primary", "primary_link", "secondary", "secondary_link",
+		"tertiary", "tertiary_link", "unclassified", "residential",
+		"living_street", "service", "road", "track", "cycleway", "path",
+	),
+	Foot: set(
+		"trunk", "trunk_link", "primary", "primary_link",
+		"secondary", "secondary_link", "tertiary", "tertiary_link",
+		"unclassified", "residential", "living_street", "service", "road",
+		"track", "cycleway", "path", "footway", "pedestrian", "steps",
+		"bridleway", "corridor",
+	),
+}
+
+// accessKeys are the access keys for each mode, from least to most specific.
+var accessKeys = map[Mode][]string{
+	Car:     {"access", "vehicle", "motor_vehicle", "motorcar"},
+	Bicycle: {"access", "vehicle", "bicycle"},
+	Foot:    {"access", "foot"},
+}
+
+// onewayKeys are the oneway keys for each mode, from least to most specific.
+// Oneway does not apply to pedestrians unless tagged with oneway:foot.
+var onewayKeys = map[Mode][]string{
+	Car:     {"oneway", "oneway:vehicle", "oneway:motor_vehicle", "oneway:motorcar"},
+	Bicycle: {"oneway", "oneway:vehicle", "oneway:bicycle"},
+	Foot:    {"oneway:foot"},
+}
+
+// denied are the access values that don't allow general use.
+var denied = set("no", "private", "agricultural", "forestry", "use_sidepath")
+
+// Access is the permission for a mode to travel along a way, in the
+// direction of the way nodes (forward) and against it (backward).
+type Access struct {
+	Forward  bool
+	Backward bool
+}
+
+// Allowed returns true if the way can be used in at least one direction.
+func (a Access) Allowed() bool {
+	return a.Forward || a.Backward
+}
+
+// Oneway returns true if the way can be used in only one direction.
+func (a Access) Oneway() bool {
+	return a.Forward != a.Backward
+}
+
+// Evaluate returns the access of the way for the mode. The highway type
+// sets the default, e.g. no bicycles on motorways, which is overridden by
+// the access tags from least to most specific, e.g. access=no + bicycle=yes
+// allows bicycles. Directional tags, e.g. bicycle:backward=no, apply to one
+// direction. Oneways, explicit or implied by junction=roundabout and
+// motorways, limit the direction unless overridden for the mode,
+// e.g. oneway:bicycle=no or cycleway=opposite_lane. Reversible oneways are
+// not usable since the direction changes over time.
+func Evaluate(w *osm.Way, mode Mode) Access {
+	highway := w.Tags.Find("highway")
+	if highway == "" || w.Tags.Find("area") == "yes" {
+		return Access{}
+	}
+
+	allowed := highways[mode][highway]
+	a := Access{Forward: allowed, Backward: allowed}
+
+	for _, k := range accessKeys[mode] {
+		if v := w.Tags.Find(k); v != "" {
+			a.Forward = !denied[v]
+			a.Backward = !denied[v]
+		}
+
+		if v := w.Tags.Find(k + ":forward"); v != "" {
+			a.Forward = !denied[v]
+		}
+
+		if v := w.Tags.Find(k + ":backward"); v != "" {
+			a.Backward = !denied[v]
+		}
+	}
+
+	switch oneway(w.Tags, highway, mode) {
+	case 1:
+		a.Backward = false
+	case -1:
+		a.Forward = false
+	case 2:
+		return Access{}
+	}
+
+	return a
+}
+
+// Permissions returns the access of the way for all the modes.
+func Permissions(w *osm.Way) map[Mode]Access {
+	return map[Mode]Access{
+		Car:     Evaluate(w, Car),
+		Bicycle: Evaluate(w, Bicycle),
+		Foot:    Evaluate(w, Foot),
+	}
+}
+
+// Routable returns true if the way can be used by the mode in
+// at least one direction, see Evaluate.
+func Routable(w *osm.Way, mode Mode) bool {
+	return Evaluate(w, mode).Allowed()
+}
+
+// oneway returns 0 for both directions, 1 for forward only,
+// -1 for backward only and 2 for reversible.
+func oneway(tags osm.Tags, highway string, mode Mode) int {
+	result := 0
+	if mode != Foot {
+		switch {
+		case highway == "motorway", highway == "motorway_link":
+			result = 1
+		case tags.Find("junction") == "roundabout", tags.Find("junction") == "circular":
+			result = 1
+		}
+	}
+
+	for _, k := range onewayKeys[mode] {
+		switch tags.Find(k) {
+		case "yes", "true", "1":
+			result = 1
+		case "-1", "reverse":
+			result = -1
+		case "no", "false", "0":
+			result = 0
+		case "reversible", "alternating":
+			result = 2
+		}
+	}
+
+	if mode == Bicycle && result != 0 && tags.Find("oneway:bicycle") == "" {
+		if strings.HasPrefix(tags.Find("cycleway"), "opposite") {
+			result = 0
+		}
+	}
+
+	return result
+}
+
+func set(values ...string) map[string]bool {
+	m := make(map[string]bool, len(values))
+	for _, v := range values {
+		m[v] = true
+	}
+
+	return m
+}
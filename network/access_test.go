@@ -0,0 +1,127 @@
+package network
+
+import (
+	"testing"
+
+	"github.com/paulmach/osm"
+)
+
+func TestEvaluate(t *testing.T) {
+	tag := func(k, v string) osm.Tag { return osm.Tag{Key: k, Value: v} }
+
+	cases := []struct {
+		name     string
+		tags     osm.Tags
+		mode     Mode
+		expected Access
+	}{
+		{name: "both", tags: highway("residential"), mode: Car, expected: Access{true, true}},
+		{name: "oneway", tags: highway("residential", tag("oneway", "yes")), mode: Car, expected: Access{true, false}},
+		{name: "oneway reverse", tags: highway("residential", tag("oneway", "-1")), mode: Car, expected: Access{false, true}},
+		{name: "oneway foot", tags: highway("residential", tag("oneway", "yes")), mode: Foot, expected: Access{true, true}},
+		{name: "oneway:foot", tags: highway("footway", tag("oneway:foot", "yes")), mode: Foot, expected: Access{true, false}},
+		{name: "roundabout", tags: highway("primary", tag("junction", "roundabout")), mode: Bicycle, expected: Access{true, false}},
+		{name: "motorway", tags: highway("motorway"), mode: Car, expected: Access{true, false}},
+		{name: "motorway not oneway", tags: highway("motorway", tag("oneway", "no")), mode: Car, expected: Access{true, true}},
+		{
+			name:     "oneway:bicycle no",
+			tags:     highway("residential", tag("oneway", "yes"), tag("oneway:bicycle", "no")),
+			mode:     Bicycle,
+			expected: Access{true, true},
+		},
+		{
+			name:     "oneway:bicycle no, car",
+			tags:     highway("residential", tag("oneway", "yes"), tag("oneway:bicycle", "no")),
+			mode:     Car,
+			expected: Access{true, false},
+		},
+		{
+			name:     "cycleway opposite",
+			tags:     highway("residential", tag("oneway", "yes"), tag("cycleway", "opposite_lane")),
+			mode:     Bicycle,
+			expected: Access{true, true},
+		},
+		{name: "reversible", tags: highway("primary", tag("oneway", "reversible")), mode: Car, expected: Access{}},
+		{
+			name:     "backward access",
+			tags:     highway("residential", tag("vehicle:backward", "no")),
+			mode:     Bicycle,
+			expected: Access{true, false},
+		},
+		{
+			name:     "backward access overridden",
+			tags:     highway("residential", tag("vehicle:backward", "no"), tag("bicycle", "yes")),
+			mode:     Bicycle,
+			expected: Access{true, true},
+		},
+		{name: "agricultural", tags: highway("track", tag("motor_vehicle", "agricultural")), mode: Car, expected: Access{}},
+		{name: "destination", tags: highway("residential", tag("access", "destination")), mode: Car, expected: Access{true, true}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := &osm.Way{Tags: tc.tags}
+			if v := Evaluate(w, tc.mode); v != tc.expected {
+				t.Errorf("incorrect access: %+v", v)
+			}
+		})
+	}
+}
+
+func TestPermissions(t *testing.T) {
+	w := &osm.Way{Tags: highway("cycleway")}
+
+	p := Permissions(w)
+	if p[Car].Allowed() || !p[Bicycle].Allowed() || !p[Foot].Allowed() {
+		t.Errorf("incorrect permissions: %v", p)
+	}
+
+	w.Tags = append(w.Tags, osm.Tag{Key: "oneway", Value: "yes"})
+	if p := Permissions(w); !p[Bicycle].Oneway() || p[Foot].Oneway() {
+		t.Errorf("incorrect permissions: %v", p)
+	}
+}
+
+func TestRoutable(t *testing.T) {
+	cases := []struct {
+		name     string
+		tags     osm.Tags
+		mode     Mode
+		expected bool
+	}{
+		{name: "residential car", tags: highway("residential"), mode: Car, expected: true},
+		{name: "footway car", tags: highway("footway"), mode: Car, expected: false},
+		{name: "footway foot", tags: highway("footway"), mode: Foot, expected: true},
+		{name: "motorway bicycle", tags: highway("motorway"), mode: Bicycle, expected: false},
+		{name: "access no", tags: highway("residential", osm.Tag{Key: "access", Value: "no"}), mode: Foot, expected: false},
+		{
+			name:     "access no, bicycle yes",
+			tags:     highway("residential", osm.Tag{Key: "access", Value: "no"}, osm.Tag{Key: "bicycle", Value: "yes"}),
+			mode:     Bicycle,
+			expected: true,
+		},
+		{
+			name:     "footway bicycle designated",
+			tags:     highway("footway", osm.Tag{Key: "bicycle", Value: "designated"}),
+			mode:     Bicycle,
+			expected: true,
+		},
+		{
+			name:     "motor_vehicle no",
+			tags:     highway("residential", osm.Tag{Key: "motor_vehicle", Value: "no"}),
+			mode:     Car,
+			expected: false,
+		},
+		{name: "pedestrian area", tags: highway("pedestrian", osm.Tag{Key: "area", Value: "yes"}), mode: Foot, expected: false},
+		{name: "not a highway", tags: osm.Tags{{Key: "railway", Value: "rail"}}, mode: Car, expected: false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := &osm.Way{Tags: tc.tags}
+			if v := Routable(w, tc.mode); v != tc.expected {
+				t.Errorf("incorrect routable: %v", v)
+			}
+		})
+	}
+}
@@ -0,0 +1,183 @@
+// Package network evaluates way access per transport mode and builds a
+// graph of the street network for a mode to analyze its connectivity,
+// for quality assurance of routable extracts.
+package network
+
+import (
+	"sort"
+
+	"github.com/paulmach/osm"
+)
+
+// Mode is a transport mode.
+type Mode string
+
+// The supported transport modes.
+const (
+	Car     Mode = "car"
+	Bicycle Mode = "bicycle"
+	Foot    Mode = "foot"
+)
+
+// Graph is the street network for a mode. The vertices are the way nodes
+// and consecutive way nodes are connected. Direction, e.g. oneway, is not
+// considered, the graph is undirected.
+type Graph struct {
+	Mode Mode
+
+	edges map[osm.NodeID][]osm.NodeID
+	ways  map[osm.NodeID][]osm.WayID
+}
+
+// Build creates the graph from the ways that can be used by the mode,
+// based on the highway and access tags.
+func Build(ways osm.Ways, mode Mode) *Graph {
+	g := &Graph{
+		Mode:  mode,
+		edges: make(map[osm.NodeID][]osm.NodeID),
+		ways:  make(map[osm.NodeID][]osm.WayID),
+	}
+
+	for _, w := range ways {
+		if Routable(w, mode) {
+			g.addWay(w)
+		}
+	}
+
+	return g
+}
+
+func (g *Graph) addWay(w *osm.Way) {
+	for i, wn := range w.Nodes {
+		g.ways[wn.ID] = addWayID(g.ways[wn.ID], w.ID)
+		if i == 0 {
+			continue
+		}
+
+		prev := w.Nodes[i-1].ID
+		if prev == wn.ID {
+			continue
+		}
+
+		g.edges[prev] = addNodeID(g.edges[prev], wn.ID)
+		g.edges[wn.ID] = addNodeID(g.edges[wn.ID], prev)
+	}
+}
+
+// Len returns the number of nodes in the graph.
+func (g *Graph) Len() int {
+	return len(g.ways)
+}
+
+// A Component is a set of connected nodes and the ways that connect them.
+// The ids are sorted.
+type Component struct {
+	Nodes []osm.NodeID
+	Ways  []osm.WayID
+}
+
+// Components returns the connected components of the graph,
+// the largest first.
+func (g *Graph) Components() []Component {
+	nodes := make([]osm.NodeID, 0, len(g.ways))
+	for id := range g.ways {
+		nodes = append(nodes, id)
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i] < nodes[j] })
+
+	visited := make(map[osm.NodeID]bool, len(nodes))
+
+	var result []Component
+	for _, start := range nodes {
+		if visited[start] {
+			continue
+		}
+
+		var (
+			c     Component
+			ways  = make(map[osm.WayID]struct{})
+			stack = []osm.NodeID{start}
+		)
+
+		visited[start] = true
+		for len(stack) > 0 {
+			n := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+
+			c.Nodes = append(c.Nodes, n)
+			for _, w := range g.ways[n] {
+				ways[w] = struct{}{}
+			}
+
+			for _, next := range g.edges[n] {
+				if !visited[next] {
+					visited[next] = true
+					stack = append(stack, next)
+				}
+			}
+		}
+
+		sort.Slice(c.Nodes, func(i, j int) bool { return c.Nodes[i] < c.Nodes[j] })
+
+		c.Ways = make([]osm.WayID, 0, len(ways))
+		for w := range ways {
+			c.Ways = append(c.Ways, w)
+		}
+		sort.Slice(c.Ways, func(i, j int) bool { return c.Ways[i] < c.Ways[j] })
+
+		result = append(result, c)
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return len(result[i].Nodes) > len(result[j].Nodes)
+	})
+
+	return result
+}
+
+// Islands returns the components not connected to the largest component,
+// the main network. These parts of the network are unreachable by the mode
+// from most of the network and are often data errors.
+func (g *Graph) Islands() []Component {
+	c := g.Components()
+	if len(c) <= 1 {
+		return nil
+	}
+
+	return c[1:]
+}
+
+// DeadEnds returns the nodes connected to only one other node,
+// sorted by id. These are the ends of cul-de-sacs but can also be
+// ways that should be connected to the network but are not.
+func (g *Graph) DeadEnds() []osm.NodeID {
+	var result []osm.NodeID
+	for id, e := range g.edges {
+		if len(e) == 1 {
+			result = append(result, id)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i] < result[j] })
+	return result
+}
+
+func addNodeID(ids []osm.NodeID, id osm.NodeID) []osm.NodeID {
+	for _, i := range ids {
+		if i == id {
+			return ids
+		}
+	}
+
+	return append(ids, id)
+}
+
+func addWayID(ids []osm.WayID, id osm.WayID) []osm.WayID {
+	for _, i := range ids {
+		if i == id {
+			return ids
+		}
+	}
+
+	return append(ids, id)
+}
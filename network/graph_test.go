@@ -0,0 +1,81 @@
+package network
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/paulmach/osm"
+)
+
+func way(id osm.WayID, tags osm.Tags, nodes ...osm.NodeID) *osm.Way {
+	w := &osm.Way{ID: id, Tags: tags}
+	for _, n := range nodes {
+		w.Nodes = append(w.Nodes, osm.WayNode{ID: n})
+	}
+
+	return w
+}
+
+func highway(v string, more ...osm.Tag) osm.Tags {
+	return append(osm.Tags{{Key: "highway", Value: v}}, more...)
+}
+
+func testWays() osm.Ways {
+	return osm.Ways{
+		// main network
+		way(1, highway("residential"), 1, 2, 3),
+		way(2, highway("residential"), 3, 4),
+		way(3, highway("service"), 2, 5),
+		// island, connected by a footway
+		way(4, highway("residential"), 10, 11),
+		way(5, highway("footway"), 4, 10),
+		// private road
+		way(6, highway("residential", osm.Tag{Key: "access", Value: "private"}), 20, 21),
+		// not a road
+		way(7, osm.Tags{{Key: "building", Value: "yes"}}, 30, 31, 32, 30),
+	}
+}
+
+func TestGraph_Components(t *testing.T) {
+	g := Build(testWays(), Car)
+
+	components := g.Components()
+	expected := []Component{
+		{Nodes: []osm.NodeID{1, 2, 3, 4, 5}, Ways: []osm.WayID{1, 2, 3}},
+		{Nodes: []osm.NodeID{10, 11}, Ways: []osm.WayID{4}},
+	}
+	if !reflect.DeepEqual(components, expected) {
+		t.Errorf("incorrect components: %v", components)
+	}
+
+	islands := g.Islands()
+	if !reflect.DeepEqual(islands, expected[1:]) {
+		t.Errorf("incorrect islands: %v", islands)
+	}
+
+	// connected by foot
+	g = Build(testWays(), Foot)
+	if c := g.Components(); len(c) != 1 || len(c[0].Nodes) != 7 {
+		t.Errorf("incorrect components: %v", c)
+	}
+
+	if islands := g.Islands(); islands != nil {
+		t.Errorf("should not have islands: %v", islands)
+	}
+}
+
+func TestGraph_DeadEnds(t *testing.T) {
+	g := Build(testWays(), Car)
+
+	deadEnds := g.DeadEnds()
+	expected := []osm.NodeID{1, 4, 5, 10, 11}
+	if !reflect.DeepEqual(deadEnds, expected) {
+		t.Errorf("incorrect dead ends: %v", deadEnds)
+	}
+
+	// loops are not dead ends
+	g = Build(osm.Ways{way(1, highway("residential"), 1, 2, 3, 1)}, Car)
+	if d := g.DeadEnds(); len(d) != 0 {
+		t.Errorf("loop should not have dead ends: %v", d)
+	}
+}
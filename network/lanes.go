@@ -0,0 +1,254 @@
+package network
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/paulmach/osm"
+)
+
+// turns are the valid turn:lanes values.
+var turns = set(
+	"left", "slight_left", "sharp_left", "through",
+	"right", "slight_right", "sharp_right", "reverse",
+	"merge_to_left", "merge_to_right", "none",
+)
+
+// A Lane is a lane in one direction of the way.
+type Lane struct {
+	// Turns are the turn:lanes indications of the lane, e.g. left or
+	// through. Empty if none or unknown.
+	Turns []string
+}
+
+// Lanes is the lane model of a way. The lanes in each direction are
+// ordered left to right in the direction of travel, where forward is
+// in the direction of the way nodes.
+type Lanes struct {
+	// Total is the number of lanes, zero if unknown.
+	Total int
+
+	// BothWays is the number of center turn lanes usable in both
+	// directions, lanes:both_ways.
+	BothWays int
+
+	// Forward and Backward are the lanes in each direction,
+	// empty if none or the count is unknown.
+	Forward  []Lane
+	Backward []Lane
+}
+
+// LaneProblemReason is why the lane tags are inconsistent.
+type LaneProblemReason string
+
+// The lane problems.
+const (
+	// InvalidLaneCount is a lane count that is not a non-negative integer.
+	InvalidLaneCount LaneProblemReason = "invalid_lane_count"
+
+	// LaneCountMismatch is a lanes value that is not the sum of the
+	// forward, backward and both ways lanes.
+	LaneCountMismatch LaneProblemReason = "lane_count_mismatch"
+
+	// TurnLaneCountMismatch is a turn:lanes value with a different
+	// number of lanes than the lane count for the direction.
+	TurnLaneCountMismatch LaneProblemReason = "turn_lane_count_mismatch"
+
+	// InvalidTurn is an unknown turn:lanes value.
+	InvalidTurn LaneProblemReason = "invalid_turn"
+
+	// UndirectedTurnLanes is turn:lanes on a two way road, it must be
+	// turn:lanes:forward or turn:lanes:backward.
+	UndirectedTurnLanes LaneProblemReason = "undirected_turn_lanes"
+)
+
+// A LaneProblem is an issue with the lane tags.
+type LaneProblem struct {
+	Reason LaneProblemReason
+
+	// Key is the tag with the problem.
+	Key string
+}
+
+// ParseLanes builds the lane model from the lanes, lanes:forward,
+// lanes:backward, lanes:both_ways and turn:lanes tags of the way,
+// and validates they are consistent. Missing counts are derived from the
+// others where possible, e.g. a two way road with lanes=4 has two lanes
+// in each direction. The oneway tags are evaluated as for cars.
+func ParseLanes(w *osm.Way) (*Lanes, []LaneProblem) {
+	var problems []LaneProblem
+	count := func(k string) int {
+		v := w.Tags.Find(k)
+		if v == "" {
+			return -1
+		}
+
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			problems = append(problems, LaneProblem{Reason: InvalidLaneCount, Key: k})
+			return -1
+		}
+
+		return n
+	}
+
+	var (
+		total    = count("lanes")
+		forward  = count("lanes:forward")
+		backward = count("lanes:backward")
+		bothWays = count("lanes:both_ways")
+	)
+
+	if bothWays < 0 {
+		bothWays = 0
+	}
+
+	var (
+		forwardKey  = "turn:lanes:forward"
+		backwardKey = "turn:lanes:backward"
+	)
+
+	switch oneway(w.Tags, w.Tags.Find("highway"), Car) {
+	case 1:
+		forwardKey = "turn:lanes"
+		if forward < 0 {
+			forward = total
+		}
+		if backward < 0 {
+			backward = 0
+		}
+	case -1:
+		backwardKey = "turn:lanes"
+		if backward < 0 {
+			backward = total
+		}
+		if forward < 0 {
+			forward = 0
+		}
+	default:
+		if w.Tags.Find("turn:lanes") != "" {
+			problems = append(problems, LaneProblem{Reason: UndirectedTurnLanes, Key: "turn:lanes"})
+		}
+
+		switch {
+		case total < 0:
+		case forward < 0 && backward >= 0:
+			forward = derived(total-bothWays-backward, &problems)
+		case backward < 0 && forward >= 0:
+			backward = derived(total-bothWays-forward, &problems)
+		case forward < 0 && backward < 0 && (total-bothWays)%2 == 0:
+			forward = (total - bothWays) / 2
+			backward = forward
+		}
+	}
+
+	forwardTurns, p := parseTurnLanes(w.Tags, forwardKey)
+	problems = append(problems, p...)
+
+	backwardTurns, p := parseTurnLanes(w.Tags, backwardKey)
+	problems = append(problems, p...)
+
+	// the turn lanes give the count if nothing else does
+	if forward < 0 && forwardTurns != nil {
+		forward = len(forwardTurns)
+	}
+
+	if backward < 0 && backwardTurns != nil {
+		backward = len(backwardTurns)
+	}
+
+	if total < 0 && forward >= 0 && backward >= 0 {
+		total = forward + backward + bothWays
+	}
+
+	if total >= 0 && forward >= 0 && backward >= 0 && forward+backward+bothWays != total {
+		problems = append(problems, LaneProblem{Reason: LaneCountMismatch, Key: "lanes"})
+	}
+
+	if forwardTurns != nil && forward >= 0 && len(forwardTurns) != forward {
+		problems = append(problems, LaneProblem{Reason: TurnLaneCountMismatch, Key: forwardKey})
+	}
+
+	if backwardTurns != nil && backward >= 0 && len(backwardTurns) != backward {
+		problems = append(problems, LaneProblem{Reason: TurnLaneCountMismatch, Key: backwardKey})
+	}
+
+	l := &Lanes{
+		Total:    maxInt(total, 0),
+		BothWays: bothWays,
+		Forward:  buildLanes(forward, forwardTurns),
+		Backward: buildLanes(backward, backwardTurns),
+	}
+
+	return l, problems
+}
+
+// parseTurnLanes splits a turn:lanes value into the turns of each lane,
+// nil if the tag is not present.
+func parseTurnLanes(tags osm.Tags, key string) ([][]string, []LaneProblem) {
+	v := tags.Find(key)
+	if v == "" {
+		return nil, nil
+	}
+
+	var (
+		result   [][]string
+		problems []LaneProblem
+		invalid  bool
+	)
+
+	for _, lane := range strings.Split(v, "|") {
+		var lt []string
+		for _, t := range strings.Split(lane, ";") {
+			t = strings.TrimSpace(t)
+			if t == "" || t == "none" {
+				continue
+			}
+
+			if !turns[t] && !invalid {
+				invalid = true
+				problems = append(problems, LaneProblem{Reason: InvalidTurn, Key: key})
+			}
+
+			lt = append(lt, t)
+		}
+
+		result = append(result, lt)
+	}
+
+	return result, problems
+}
+
+// derived returns the derived count, or -1 and a mismatch problem
+// if the counts don't add up.
+func derived(n int, problems *[]LaneProblem) int {
+	if n < 0 {
+		*problems = append(*problems, LaneProblem{Reason: LaneCountMismatch, Key: "lanes"})
+		return -1
+	}
+
+	return n
+}
+
+func buildLanes(count int, laneTurns [][]string) []Lane {
+	if count <= 0 {
+		return nil
+	}
+
+	lanes := make([]Lane, count)
+	if len(laneTurns) == count {
+		for i := range lanes {
+			lanes[i].Turns = laneTurns[i]
+		}
+	}
+
+	return lanes
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+
+	return b
+}
@@ -0,0 +1,139 @@
+package network
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/paulmach/osm"
+)
+
+func TestParseLanes(t *testing.T) {
+	tag := func(k, v string) osm.Tag { return osm.Tag{Key: k, Value: v} }
+
+	cases := []struct {
+		name     string
+		tags     osm.Tags
+		total    int
+		forward  int
+		backward int
+		turns    [][]string // forward
+		problems []LaneProblem
+	}{
+		{
+			name:     "two way split",
+			tags:     highway("primary", tag("lanes", "4")),
+			total:    4,
+			forward:  2,
+			backward: 2,
+		},
+		{
+			name:  "two way odd",
+			tags:  highway("primary", tag("lanes", "3")),
+			total: 3,
+		},
+		{
+			name:     "two way derived",
+			tags:     highway("primary", tag("lanes", "3"), tag("lanes:forward", "2")),
+			total:    3,
+			forward:  2,
+			backward: 1,
+		},
+		{
+			name:     "both ways",
+			tags:     highway("primary", tag("lanes", "5"), tag("lanes:both_ways", "1")),
+			total:    5,
+			forward:  2,
+			backward: 2,
+		},
+		{
+			name:    "oneway with turns",
+			tags:    highway("primary", tag("oneway", "yes"), tag("lanes", "3"), tag("turn:lanes", "left|through|through;right")),
+			total:   3,
+			forward: 3,
+			turns:   [][]string{{"left"}, {"through"}, {"through", "right"}},
+		},
+		{
+			name:     "count from turn lanes",
+			tags:     highway("primary", tag("turn:lanes:forward", "left|none"), tag("turn:lanes:backward", "through")),
+			total:    3,
+			forward:  2,
+			backward: 1,
+			turns:    [][]string{{"left"}, nil},
+		},
+		{
+			name:     "reverse oneway",
+			tags:     highway("primary", tag("oneway", "-1"), tag("lanes", "2")),
+			total:    2,
+			backward: 2,
+		},
+		{
+			name:     "invalid count",
+			tags:     highway("primary", tag("lanes", "two")),
+			problems: []LaneProblem{{Reason: InvalidLaneCount, Key: "lanes"}},
+		},
+		{
+			name:     "count mismatch",
+			tags:     highway("primary", tag("lanes", "3"), tag("lanes:forward", "2"), tag("lanes:backward", "2")),
+			total:    3,
+			forward:  2,
+			backward: 2,
+			problems: []LaneProblem{{Reason: LaneCountMismatch, Key: "lanes"}},
+		},
+		{
+			name:     "derived negative",
+			tags:     highway("primary", tag("lanes", "1"), tag("lanes:forward", "2")),
+			total:    1,
+			forward:  2,
+			problems: []LaneProblem{{Reason: LaneCountMismatch, Key: "lanes"}},
+		},
+		{
+			name:    "turn lane count mismatch",
+			tags:    highway("primary", tag("oneway", "yes"), tag("lanes", "2"), tag("turn:lanes", "left|through|right")),
+			total:   2,
+			forward: 2,
+			problems: []LaneProblem{
+				{Reason: TurnLaneCountMismatch, Key: "turn:lanes"},
+			},
+		},
+		{
+			name:     "undirected and invalid turns",
+			tags:     highway("primary", tag("lanes", "2"), tag("turn:lanes", "left|through"), tag("turn:lanes:forward", "up")),
+			total:    2,
+			forward:  1,
+			backward: 1,
+			turns:    [][]string{{"up"}},
+			problems: []LaneProblem{
+				{Reason: UndirectedTurnLanes, Key: "turn:lanes"},
+				{Reason: InvalidTurn, Key: "turn:lanes:forward"},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			lanes, problems := ParseLanes(&osm.Way{Tags: tc.tags})
+			if lanes.Total != tc.total {
+				t.Errorf("incorrect total: %v", lanes.Total)
+			}
+
+			if len(lanes.Forward) != tc.forward || len(lanes.Backward) != tc.backward {
+				t.Errorf("incorrect lanes: %d forward, %d backward", len(lanes.Forward), len(lanes.Backward))
+			}
+
+			if tc.turns != nil {
+				var turns [][]string
+				for _, l := range lanes.Forward {
+					turns = append(turns, l.Turns)
+				}
+
+				if !reflect.DeepEqual(turns, tc.turns) {
+					t.Errorf("incorrect turns: %v", turns)
+				}
+			}
+
+			if !reflect.DeepEqual(problems, tc.problems) {
+				t.Errorf("incorrect problems: %v", problems)
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package osm
 
 import (
+	"encoding/xml"
 	"sort"
 	"time"
 
@@ -40,11 +41,16 @@ type Node struct {
 	Version     int                 `xml:"version,attr" json:"version,omitempty"`
 	ChangesetID ChangesetID         `xml:"changeset,attr" json:"changeset,omitempty"`
 	Timestamp   time.Time           `xml:"timestamp,attr" json:"timestamp"`
+	Redacted    RedactionID         `xml:"redacted,attr,omitempty" json:"redacted,omitempty"`
 	Tags        Tags                `xml:"tag" json:"tags,omitempty"`
 
 	// Committed, is the estimated time this object was committed
 	// and made visible in the central OSM database.
 	Committed *time.Time `xml:"committed,attr,omitempty" json:"committed,omitempty"`
+	// XMLExtra are the xml attributes and child elements that are not
+	// part of the osm format. They are only set by the osmxml scanner
+	// with the PreserveUnknown option and written out again by MarshalXML.
+	XMLExtra *XMLExtra `xml:"-" json:"-"`
 }
 
 // ObjectID returns the object id of the node.
@@ -77,12 +83,71 @@ func (n *Node) TagMap() map[string]string {
 	return n.Tags.Map()
 }
 
+// IsInteresting returns true if the node has at least one tag
+// that is not in the uninteresting set. Nodes without any interesting
+// tags are usually only there to define the shape of a way.
+func (n *Node) IsInteresting() bool {
+	return n.Tags.AnyInteresting()
+}
+
+// MarshalXML implements the xml.Marshaler interface to write out the
+// XMLExtra attributes and elements with the node. The attributes of
+// the start element are kept so wrappers can add their own.
+func (n Node) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	start.Name = xml.Name{Local: "node"}
+
+	type plain Node
+	if n.XMLExtra == nil {
+		return e.EncodeElement(plain(n), start)
+	}
+
+	return e.EncodeElement(struct {
+		plain
+		*XMLExtra
+	}{plain(n), n.XMLExtra}, start)
+}
+
 // Point returns the orb.Point location for the node.
 // Will be (0, 0) for "deleted" nodes.
 func (n *Node) Point() orb.Point {
 	return orb.Point{n.Lon, n.Lat}
 }
 
+// Copy returns a deep copy of the node, the tags are not shared.
+func (n *Node) Copy() *Node {
+	c := *n
+	c.Tags = n.Tags.copy()
+	c.Committed = copyTimePtr(n.Committed)
+	c.XMLExtra = n.XMLExtra.copy()
+
+	return &c
+}
+
+// Equal compares the nodes. The tags are compared ignoring their order.
+// Use the IgnoreMetadata option to only compare the data.
+func (n *Node) Equal(o *Node, opts ...EqualOption) bool {
+	if n == nil || o == nil {
+		return n == o
+	}
+
+	if n.ID != o.ID || n.Visible != o.Visible ||
+		n.Lat != o.Lat || n.Lon != o.Lon || !equalTags(n.Tags, o.Tags) {
+		return false
+	}
+
+	if newEqualOptions(opts).ignoreMetadata {
+		return true
+	}
+
+	return n.User == o.User &&
+		n.UserID == o.UserID &&
+		n.Version == o.Version &&
+		n.ChangesetID == o.ChangesetID &&
+		n.Timestamp.Equal(o.Timestamp) &&
+		n.Redacted == o.Redacted &&
+		equalTimePtr(n.Committed, o.Committed)
+}
+
 // Nodes is a list of nodes with helper functions on top.
 type Nodes []*Node
 
@@ -116,17 +181,38 @@ func (ns Nodes) ElementIDs() ElementIDs {
 	return r
 }
 
+// Marshal encodes the node using protocol buffers. The encoding is smaller
+// than a list of one node so it is useful for values in key value stores.
+// It can be decoded using UnmarshalNode.
+func (n *Node) Marshal(opts ...MarshalOption) ([]byte, error) {
+	return marshalElement(n, opts)
+}
+
+// UnmarshalNode will unmarshal the data, encoded by Node.Marshal,
+// into a single node.
+func UnmarshalNode(data []byte) (*Node, error) {
+	o, err := unmarshalElement(data, TypeNode)
+	if err != nil {
+		return nil, err
+	}
+
+	return o.Nodes[0], nil
+}
+
 // Marshal encodes the nodes using protocol buffers.
-func (ns Nodes) Marshal() ([]byte, error) {
+func (ns Nodes) Marshal(opts ...MarshalOption) ([]byte, error) {
 	if len(ns) == 0 {
 		return nil, nil
 	}
 
-	ss := &stringSet{}
-	encoded := marshalNodes(ns, ss, true)
-	encoded.Strings = ss.Strings()
+	mo := newMarshalOptions(opts)
 
-	return proto.Marshal(encoded)
+	chunks, err := encodeNodes(ns, mo)
+	if err != nil {
+		return nil, err
+	}
+
+	return joinChunks(chunks), nil
 }
 
 // UnmarshalNodes will unmarshal the data into a list of nodes.
@@ -135,13 +221,35 @@ func UnmarshalNodes(data []byte) (Nodes, error) {
 		return nil, nil
 	}
 
+	if isChunked(data) {
+		return unmarshalNodeChunks(data)
+	}
+
 	pbf := &osmpb.DenseNodes{}
 	err := proto.Unmarshal(data, pbf)
 	if err != nil {
 		return nil, err
 	}
 
-	return unmarshalNodes(pbf, pbf.GetStrings(), nil)
+	if err := verifyChecksum(data, pbf.Checksum); err != nil {
+		return nil, err
+	}
+
+	legacy, err := checkSchemaVersion(pbf.GetSchemaVersion())
+	if err != nil {
+		return nil, err
+	}
+
+	if legacy {
+		upgradeDenseNodesV1(pbf)
+	}
+
+	ss, err := stringTable(pbf.GetDictionary(), pbf.GetStrings())
+	if err != nil {
+		return nil, err
+	}
+
+	return unmarshalNodes(pbf, ss, nil)
 }
 
 type nodesSort Nodes
@@ -86,6 +86,53 @@ func TestNode_MarshalXML(t *testing.T) {
 	}
 }
 
+func TestNode_MarshalXML_extra(t *testing.T) {
+	n := &Node{
+		ID: 123,
+		XMLExtra: &XMLExtra{
+			Attrs: []xml.Attr{{Name: xml.Name{Local: "vendor"}, Value: "x"}},
+			Elements: []XMLElement{
+				{XMLName: xml.Name{Local: "center"}, InnerXML: "1"},
+			},
+		},
+	}
+
+	data, err := xml.Marshal(n)
+	if err != nil {
+		t.Fatalf("xml marshal error: %v", err)
+	}
+
+	expected := `<node id="123" lat="0" lon="0" user="" uid="0" visible="false" version="0" changeset="0" timestamp="0001-01-01T00:00:00Z" vendor="x"><center>1</center></node>`
+	if !bytes.Equal(data, []byte(expected)) {
+		t.Errorf("incorrect marshal, got: %s", string(data))
+	}
+
+	// the extra data is not decoded by default
+	n2 := &Node{}
+	if err := xml.Unmarshal(data, n2); err != nil {
+		t.Fatalf("unmarshal error: %v", err)
+	}
+
+	if n2.XMLExtra != nil {
+		t.Errorf("should not decode extra data: %v", n2.XMLExtra)
+	}
+
+	// the start element attributes are kept
+	buf := &bytes.Buffer{}
+	start := xml.StartElement{
+		Name: xml.Name{Local: "node"},
+		Attr: []xml.Attr{{Name: xml.Name{Local: "action"}, Value: "modify"}},
+	}
+	if err := xml.NewEncoder(buf).EncodeElement(n2, start); err != nil {
+		t.Fatalf("encode error: %v", err)
+	}
+
+	expected = `<node action="modify" id="123" lat="0" lon="0" user="" uid="0" visible="false" version="0" changeset="0" timestamp="0001-01-01T00:00:00Z"></node>`
+	if buf.String() != expected {
+		t.Errorf("incorrect encode, got: %s", buf.String())
+	}
+}
+
 func TestUnmarshalNodes(t *testing.T) {
 	ns := Nodes{
 		{ID: 123},
@@ -181,3 +228,83 @@ func TestNodes_SortByIDVersion(t *testing.T) {
 		t.Errorf("incorrect sort: %v", eids)
 	}
 }
+
+func TestNode_Copy(t *testing.T) {
+	now := time.Now()
+	n := &Node{
+		ID:        1,
+		Tags:      Tags{{Key: "a", Value: "b"}},
+		Committed: &now,
+		XMLExtra:  &XMLExtra{Attrs: []xml.Attr{{Name: xml.Name{Local: "vendor"}, Value: "x"}}},
+	}
+
+	c := n.Copy()
+	if !reflect.DeepEqual(c, n) {
+		t.Errorf("copy should be equal")
+	}
+
+	c.Tags[0].Value = "c"
+	*c.Committed = now.Add(time.Hour)
+	c.XMLExtra.Attrs[0].Value = "y"
+	if n.Tags[0].Value != "b" || !n.Committed.Equal(now) || n.XMLExtra.Attrs[0].Value != "x" {
+		t.Errorf("should not share data with the copy")
+	}
+}
+
+func TestNode_Equal(t *testing.T) {
+	ts := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+	n := &Node{ID: 1, Lat: 1, Lon: 2, Version: 3, User: "u", Timestamp: ts,
+		Tags: Tags{{Key: "a", Value: "b"}, {Key: "c", Value: "d"}}}
+
+	if !n.Equal(n.Copy()) {
+		t.Errorf("copy should be equal")
+	}
+
+	c := n.Copy()
+	c.Tags[0], c.Tags[1] = c.Tags[1], c.Tags[0]
+	c.Timestamp = ts.In(time.FixedZone("other", 3600))
+	if !n.Equal(c) {
+		t.Errorf("tag order and time zone should not matter")
+	}
+
+	c.Version = 4
+	if n.Equal(c) {
+		t.Errorf("should compare metadata")
+	}
+
+	if !n.Equal(c, IgnoreMetadata()) {
+		t.Errorf("should ignore metadata")
+	}
+
+	c.Lat = 5
+	if n.Equal(c, IgnoreMetadata()) {
+		t.Errorf("should compare location")
+	}
+
+	c = n.Copy()
+	c.Tags[0].Value = "z"
+	if n.Equal(c, IgnoreMetadata()) {
+		t.Errorf("should compare tags")
+	}
+
+	// repeated keys
+	a := &Node{ID: 1, Tags: Tags{{Key: "x", Value: "1"}, {Key: "y", Value: "2"}}}
+	b := &Node{ID: 1, Tags: Tags{{Key: "x", Value: "1"}, {Key: "x", Value: "1"}}}
+	if a.Equal(b) || b.Equal(a) {
+		t.Errorf("should compare repeated keys in both directions")
+	}
+
+	if a.Hash() == b.Hash() {
+		t.Errorf("should not hash the same")
+	}
+
+	c = &Node{ID: 1, Tags: Tags{{Key: "x", Value: "2"}, {Key: "x", Value: "1"}}}
+	d := &Node{ID: 1, Tags: Tags{{Key: "x", Value: "1"}, {Key: "x", Value: "2"}}}
+	if !c.Equal(d) || !d.Equal(c) || c.Hash() != d.Hash() {
+		t.Errorf("repeated keys should ignore order")
+	}
+
+	if n.Equal(nil) || !(*Node)(nil).Equal(nil) {
+		t.Errorf("incorrect nil comparison")
+	}
+}
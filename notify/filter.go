@@ -0,0 +1,336 @@
+package notify
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/paulmach/osm"
+)
+
+// A TagFilter is a parsed tag expression, see ParseTagFilter.
+type TagFilter struct {
+	expr string
+	root tagExpr
+}
+
+// ParseTagFilter parses a tag expression. The expression is made of terms
+// combined with and, or, not and parentheses, e.g.
+//
+//	amenity=cafe and not name
+//	highway~"^(primary|secondary)$" or (building and building!=no)
+//
+// The terms are:
+//
+//	key           the tag is present
+//	key=value     the tag has the value
+//	key!=value    the tag does not have the value, or is not present
+//	key~regexp    the tag value matches the regular expression
+//
+// Keys and values containing spaces or operator characters must be quoted.
+// An empty expression matches all tags.
+func ParseTagFilter(expr string) (*TagFilter, error) {
+	p := &parser{tokens: tokenize(expr)}
+
+	f := &TagFilter{expr: expr}
+	if len(p.tokens) == 0 {
+		return f, nil
+	}
+
+	root, err := p.or()
+	if err != nil {
+		return nil, fmt.Errorf("notify: invalid tag filter %q: %v", expr, err)
+	}
+
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("notify: invalid tag filter %q: unexpected %q", expr, p.tokens[p.pos].value)
+	}
+
+	f.root = root
+	return f, nil
+}
+
+// Match returns true if the tags match the expression.
+func (f *TagFilter) Match(tags osm.Tags) bool {
+	if f.root == nil {
+		return true
+	}
+
+	return f.root.match(tags)
+}
+
+// String returns the expression the filter was parsed from.
+func (f *TagFilter) String() string {
+	return f.expr
+}
+
+type tagExpr interface {
+	match(osm.Tags) bool
+}
+
+type andExpr []tagExpr
+
+func (e andExpr) match(tags osm.Tags) bool {
+	for _, c := range e {
+		if !c.match(tags) {
+			return false
+		}
+	}
+
+	return true
+}
+
+type orExpr []tagExpr
+
+func (e orExpr) match(tags osm.Tags) bool {
+	for _, c := range e {
+		if c.match(tags) {
+			return true
+		}
+	}
+
+	return false
+}
+
+type notExpr struct {
+	expr tagExpr
+}
+
+func (e notExpr) match(tags osm.Tags) bool {
+	return !e.expr.match(tags)
+}
+
+type termExpr struct {
+	key   string
+	op    string
+	value string
+	re    *regexp.Regexp
+}
+
+func (e termExpr) match(tags osm.Tags) bool {
+	v, has := find(tags, e.key)
+
+	switch e.op {
+	case "":
+		return has
+	case "=":
+		return has && v == e.value
+	case "!=":
+		return !has || v != e.value
+	case "~":
+		return has && e.re.MatchString(v)
+	}
+
+	return false
+}
+
+func find(tags osm.Tags, key string) (string, bool) {
+	for _, t := range tags {
+		if t.Key == key {
+			return t.Value, true
+		}
+	}
+
+	return "", false
+}
+
+type tokenKind int
+
+const (
+	tokenWord tokenKind = iota
+	tokenString
+	tokenOp
+)
+
+type token struct {
+	kind  tokenKind
+	value string
+}
+
+func tokenize(s string) []token {
+	var tokens []token
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(' || c == ')' || c == '=' || c == '~':
+			tokens = append(tokens, token{kind: tokenOp, value: string(c)})
+			i++
+		case c == '!':
+			if i+1 < len(s) && s[i+1] == '=' {
+				tokens = append(tokens, token{kind: tokenOp, value: "!="})
+				i += 2
+			} else {
+				tokens = append(tokens, token{kind: tokenOp, value: "!"})
+				i++
+			}
+		case c == '"':
+			var b strings.Builder
+			i++
+			for i < len(s) && s[i] != '"' {
+				if s[i] == '\\' && i+1 < len(s) {
+					i++
+				}
+				b.WriteByte(s[i])
+				i++
+			}
+			i++ // closing quote
+			tokens = append(tokens, token{kind: tokenString, value: b.String()})
+		default:
+			start := i
+			for i < len(s) && !strings.ContainsRune(" \t\n\r()=~!\"", rune(s[i])) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenWord, value: s[start:i]})
+		}
+	}
+
+	return tokens
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() (token, bool) {
+	if p.pos >= len(p.tokens) {
+		return token{}, false
+	}
+
+	return p.tokens[p.pos], true
+}
+
+func (p *parser) keyword(k string) bool {
+	t, ok := p.peek()
+	if ok && t.kind == tokenWord && strings.EqualFold(t.value, k) {
+		p.pos++
+		return true
+	}
+
+	return false
+}
+
+func (p *parser) op(o string) bool {
+	t, ok := p.peek()
+	if ok && t.kind == tokenOp && t.value == o {
+		p.pos++
+		return true
+	}
+
+	return false
+}
+
+func (p *parser) or() (tagExpr, error) {
+	e, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+
+	result := orExpr{e}
+	for p.keyword("or") {
+		e, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, e)
+	}
+
+	if len(result) == 1 {
+		return result[0], nil
+	}
+
+	return result, nil
+}
+
+func (p *parser) and() (tagExpr, error) {
+	e, err := p.not()
+	if err != nil {
+		return nil, err
+	}
+
+	result := andExpr{e}
+	for p.keyword("and") {
+		e, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, e)
+	}
+
+	if len(result) == 1 {
+		return result[0], nil
+	}
+
+	return result, nil
+}
+
+func (p *parser) not() (tagExpr, error) {
+	if p.keyword("not") || p.op("!") {
+		e, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+
+		return notExpr{expr: e}, nil
+	}
+
+	return p.primary()
+}
+
+func (p *parser) primary() (tagExpr, error) {
+	if p.op("(") {
+		e, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+
+		if !p.op(")") {
+			return nil, errors.New("missing )")
+		}
+
+		return e, nil
+	}
+
+	key, err := p.text("key")
+	if err != nil {
+		return nil, err
+	}
+
+	t, ok := p.peek()
+	if !ok || t.kind != tokenOp || (t.value != "=" && t.value != "!=" && t.value != "~") {
+		return termExpr{key: key}, nil
+	}
+	p.pos++
+
+	value, err := p.text("value")
+	if err != nil {
+		return nil, err
+	}
+
+	term := termExpr{key: key, op: t.value, value: value}
+	if t.value == "~" {
+		term.re, err = regexp.Compile(value)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return term, nil
+}
+
+func (p *parser) text(what string) (string, error) {
+	t, ok := p.peek()
+	if !ok {
+		return "", fmt.Errorf("missing %s", what)
+	}
+
+	if t.kind == tokenOp {
+		return "", fmt.Errorf("expected %s, got %q", what, t.value)
+	}
+
+	p.pos++
+	return t.value, nil
+}
@@ -0,0 +1,75 @@
+package notify
+
+import (
+	"testing"
+
+	"github.com/paulmach/osm"
+)
+
+func TestParseTagFilter(t *testing.T) {
+	tags := osm.Tags{
+		{Key: "amenity", Value: "cafe"},
+		{Key: "name", Value: "Blue Bottle"},
+		{Key: "building", Value: "no"},
+	}
+
+	cases := []struct {
+		name  string
+		expr  string
+		match bool
+	}{
+		{name: "empty", expr: "", match: true},
+		{name: "key", expr: "amenity", match: true},
+		{name: "missing key", expr: "shop", match: false},
+		{name: "equal", expr: "amenity=cafe", match: true},
+		{name: "not equal", expr: "amenity!=cafe", match: false},
+		{name: "not equal missing", expr: "shop!=bakery", match: true},
+		{name: "regexp", expr: `name~"^Blue "`, match: true},
+		{name: "quoted value", expr: `name="Blue Bottle"`, match: true},
+		{name: "and", expr: "amenity=cafe and name", match: true},
+		{name: "not", expr: "amenity and not name", match: false},
+		{name: "bang", expr: "!shop", match: true},
+		{name: "or", expr: "shop or amenity=cafe", match: true},
+		{name: "precedence", expr: "shop and name or amenity", match: true},
+		{name: "parens", expr: "shop and (name or amenity)", match: false},
+		{name: "building", expr: "building and building!=no", match: false},
+		{name: "case insensitive keywords", expr: "amenity AND NOT shop", match: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			f, err := ParseTagFilter(tc.expr)
+			if err != nil {
+				t.Fatalf("parse error: %v", err)
+			}
+
+			if v := f.Match(tags); v != tc.match {
+				t.Errorf("incorrect match: %v != %v", v, tc.match)
+			}
+
+			if f.String() != tc.expr {
+				t.Errorf("incorrect string: %v", f.String())
+			}
+		})
+	}
+}
+
+func TestParseTagFilter_errors(t *testing.T) {
+	cases := []string{
+		"amenity=",
+		"=cafe",
+		"(amenity",
+		"amenity)",
+		"amenity and",
+		`name~"("`,
+	}
+
+	for _, expr := range cases {
+		t.Run(expr, func(t *testing.T) {
+			_, err := ParseTagFilter(expr)
+			if err == nil {
+				t.Errorf("expected error")
+			}
+		})
+	}
+}
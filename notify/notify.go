@@ -0,0 +1,487 @@
+// Package notify matches incoming diffs against user registered
+// subscriptions, tag filters and geofences, and calls their webhooks with
+// the matched elements. It is the core of a change alerting service.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/planar"
+	"github.com/paulmach/osm"
+	"github.com/paulmach/osm/replication"
+)
+
+// A Subscription is a webhook called with the elements matching
+// the tag filter within the geofence.
+type Subscription struct {
+	ID  string
+	URL string
+
+	// Filter is matched against the tags of the old and new versions.
+	// If nil all elements match.
+	Filter *TagFilter
+
+	// Geofence is an orb.Bound, orb.Ring, orb.Polygon or orb.MultiPolygon.
+	// An element matches if a location of the old or new version is inside.
+	// Ways and relations must be annotated, see annotate.AugmentedDiff.
+	// If nil all elements match, including ones without locations.
+	Geofence orb.Geometry
+
+	// Secret, if set, is used to sign the request body. The hex encoded
+	// HMAC-SHA256 is sent in the X-OSM-Signature header as sha256=<hex>.
+	Secret string
+}
+
+// A Match is a changed element that matched a subscription.
+// Old is nil for creates, New is nil if not available for deletes.
+type Match struct {
+	Action osm.ActionType `json:"action"`
+	Old    osm.Element    `json:"old,omitempty"`
+	New    osm.Element    `json:"new,omitempty"`
+}
+
+// A Notification is the request body sent to a webhook.
+type Notification struct {
+	Subscription string  `json:"subscription"`
+	Matches      []Match `json:"matches"`
+}
+
+// A Notifier holds the subscriptions and delivers notifications.
+// It is safe for concurrent use.
+type Notifier struct {
+	client     *http.Client
+	maxRetries int
+	minBackoff time.Duration
+	maxBackoff time.Duration
+	logger     osm.Logger
+
+	mu   sync.RWMutex
+	subs map[string]Subscription
+}
+
+// NewNotifier creates a notifier without subscriptions.
+func NewNotifier(opts ...Option) *Notifier {
+	n := &Notifier{
+		client:     http.DefaultClient,
+		maxRetries: 3,
+		minBackoff: time.Second,
+		maxBackoff: 30 * time.Second,
+		subs:       make(map[string]Subscription),
+	}
+
+	for _, opt := range opts {
+		opt(n)
+	}
+
+	return n
+}
+
+// Subscribe adds the subscription, replacing one with the same id.
+func (n *Notifier) Subscribe(s Subscription) error {
+	if s.ID == "" {
+		return errors.New("notify: subscription id required")
+	}
+
+	if s.URL == "" {
+		return errors.New("notify: subscription url required")
+	}
+
+	switch s.Geofence.(type) {
+	case nil, orb.Bound, orb.Ring, orb.Polygon, orb.MultiPolygon:
+	default:
+		return fmt.Errorf("notify: unsupported geofence type %T", s.Geofence)
+	}
+
+	n.mu.Lock()
+	n.subs[s.ID] = s
+	n.mu.Unlock()
+
+	return nil
+}
+
+// Unsubscribe removes the subscription with the id.
+func (n *Notifier) Unsubscribe(id string) {
+	n.mu.Lock()
+	delete(n.subs, id)
+	n.mu.Unlock()
+}
+
+// Subscriptions returns the current subscriptions ordered by id.
+func (n *Notifier) Subscriptions() []Subscription {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	result := make([]Subscription, 0, len(n.subs))
+	for _, s := range n.subs {
+		result = append(result, s)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ID < result[j].ID })
+
+	return result
+}
+
+// Match returns the notification for each subscription with matched
+// elements, ordered by subscription id.
+func (n *Notifier) Match(d *osm.Diff) []Notification {
+	var result []Notification
+	for _, m := range match(n.Subscriptions(), d) {
+		result = append(result, m.notification)
+	}
+
+	return result
+}
+
+// Notify matches the diff and calls the webhooks. Failed requests, network
+// errors and 5xx responses, are retried with backoff, see the Retries
+// option. All webhooks are called, a *DeliveryError is returned with the
+// subscriptions that failed.
+func (n *Notifier) Notify(ctx context.Context, d *osm.Diff) error {
+	failed := make(map[string]error)
+	for _, m := range match(n.Subscriptions(), d) {
+		if err := n.deliver(ctx, m.subscription, m.notification); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+
+			failed[m.subscription.ID] = err
+		}
+	}
+
+	if len(failed) > 0 {
+		return &DeliveryError{Failed: failed}
+	}
+
+	return nil
+}
+
+// Handler returns a replication.Catchup handler that notifies the
+// subscriptions of the changes. Replication changes only have the new
+// versions and no way node locations, so only creates and modifies match
+// geofences on their node locations. Use annotate.AugmentedDiff and
+// Notify directly to match on the old versions and way geometry.
+//
+// Webhooks that could not be called are reported to the logger, see the
+// WithLogger option, and do not stop the catch up, so one failing webhook
+// does not hold back or duplicate the notifications of the others.
+// Only context errors are returned.
+func (n *Notifier) Handler(ctx context.Context) func(replication.SeqNum, *osm.Change) error {
+	return func(seq replication.SeqNum, c *osm.Change) error {
+		err := n.Notify(ctx, changeDiff(c))
+		if e, ok := err.(*DeliveryError); ok {
+			n.warnDelivery(seq, e)
+			return nil
+		}
+
+		return err
+	}
+}
+
+func (n *Notifier) warnDelivery(seq replication.SeqNum, e *DeliveryError) {
+	if n.logger == nil {
+		return
+	}
+
+	ids := make([]string, 0, len(e.Failed))
+	for id := range e.Failed {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		n.logger.Warn("notify: delivery failed",
+			"seq", seq, "subscription", id, "error", e.Failed[id].Error())
+	}
+}
+
+// DeliveryError is returned by Notify if webhooks could not be called.
+type DeliveryError struct {
+	Failed map[string]error
+}
+
+// Error returns the failed subscriptions and their errors.
+func (e *DeliveryError) Error() string {
+	ids := make([]string, 0, len(e.Failed))
+	for id := range e.Failed {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	parts := make([]string, 0, len(ids))
+	for _, id := range ids {
+		parts = append(parts, fmt.Sprintf("%s: %v", id, e.Failed[id]))
+	}
+
+	return "notify: delivery failed: " + strings.Join(parts, "; ")
+}
+
+// UnexpectedStatusCodeError is returned if a webhook responds
+// with a non 2xx status code.
+type UnexpectedStatusCodeError struct {
+	Code int
+	URL  string
+}
+
+// Error returns an error message with some information.
+func (e *UnexpectedStatusCodeError) Error() string {
+	return fmt.Sprintf("notify: unexpected status code of %d for url %s", e.Code, e.URL)
+}
+
+func (n *Notifier) deliver(ctx context.Context, s Subscription, notif Notification) error {
+	body, err := json.Marshal(notif)
+	if err != nil {
+		return err
+	}
+
+	backoff := n.minBackoff
+	for i := 0; ; i++ {
+		err = n.post(ctx, s, body)
+		if err == nil {
+			return nil
+		}
+
+		if e, ok := err.(*UnexpectedStatusCodeError); ok && e.Code < 500 {
+			return err
+		}
+
+		if i >= n.maxRetries {
+			return err
+		}
+
+		if err := sleep(ctx, backoff); err != nil {
+			return err
+		}
+
+		backoff *= 2
+		if backoff > n.maxBackoff {
+			backoff = n.maxBackoff
+		}
+	}
+}
+
+func (n *Notifier) post(ctx context.Context, s Subscription, body []byte) error {
+	req, err := http.NewRequest("POST", s.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-OSM-Subscription", s.ID)
+	if s.Secret != "" {
+		req.Header.Set("X-OSM-Signature", Sign(s.Secret, body))
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return &UnexpectedStatusCodeError{Code: resp.StatusCode, URL: s.URL}
+	}
+
+	return nil
+}
+
+// Sign returns the X-OSM-Signature header value for the body.
+// Webhook receivers can use it to verify requests.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func (s Subscription) match(m Match) bool {
+	tags := s.Filter == nil ||
+		(m.Old != nil && s.Filter.Match(elementTags(m.Old))) ||
+		(m.New != nil && s.Filter.Match(elementTags(m.New)))
+	if !tags {
+		return false
+	}
+
+	if s.Geofence == nil {
+		return true
+	}
+
+	return (m.Old != nil && inside(s.Geofence, m.Old)) ||
+		(m.New != nil && inside(s.Geofence, m.New))
+}
+
+type matched struct {
+	subscription Subscription
+	notification Notification
+}
+
+func match(subs []Subscription, d *osm.Diff) []matched {
+	changes := elementChanges(d)
+
+	var result []matched
+	for _, s := range subs {
+		var matches []Match
+		for _, m := range changes {
+			if s.match(m) {
+				matches = append(matches, m)
+			}
+		}
+
+		if len(matches) > 0 {
+			result = append(result, matched{
+				subscription: s,
+				notification: Notification{Subscription: s.ID, Matches: matches},
+			})
+		}
+	}
+
+	return result
+}
+
+// elementChanges returns the element changes in the diff.
+func elementChanges(d *osm.Diff) []Match {
+	var result []Match
+	for _, a := range d.Actions {
+		if a.Type == osm.ActionCreate {
+			if a.OSM == nil {
+				continue
+			}
+
+			for _, e := range a.OSM.Elements() {
+				result = append(result, Match{Action: a.Type, New: e})
+			}
+
+			continue
+		}
+
+		m := Match{Action: a.Type, Old: first(a.Old), New: first(a.New)}
+		if m.Old != nil || m.New != nil {
+			result = append(result, m)
+		}
+	}
+
+	return result
+}
+
+func first(o *osm.OSM) osm.Element {
+	if o == nil {
+		return nil
+	}
+
+	es := o.Elements()
+	if len(es) == 0 {
+		return nil
+	}
+
+	return es[0]
+}
+
+// changeDiff converts a change into a diff with only the new versions.
+func changeDiff(c *osm.Change) *osm.Diff {
+	d := &osm.Diff{}
+	add := func(t osm.ActionType, o *osm.OSM) {
+		if o == nil {
+			return
+		}
+
+		for _, e := range o.Elements() {
+			a := osm.Action{Type: t}
+			single := &osm.OSM{}
+			single.Append(e)
+
+			if t == osm.ActionCreate {
+				a.OSM = single
+			} else {
+				a.New = single
+			}
+
+			d.Actions = append(d.Actions, a)
+		}
+	}
+
+	add(osm.ActionCreate, c.Create)
+	add(osm.ActionModify, c.Modify)
+	add(osm.ActionDelete, c.Delete)
+
+	return d
+}
+
+func elementTags(e osm.Element) osm.Tags {
+	switch e := e.(type) {
+	case *osm.Node:
+		return e.Tags
+	case *osm.Way:
+		return e.Tags
+	case *osm.Relation:
+		return e.Tags
+	}
+
+	return nil
+}
+
+// inside returns true if any location of the element is in the geofence.
+func inside(g orb.Geometry, e osm.Element) bool {
+	switch e := e.(type) {
+	case *osm.Node:
+		return contains(g, e.Point())
+	case *osm.Way:
+		for _, wn := range e.Nodes {
+			if (wn.Lat != 0 || wn.Lon != 0) && contains(g, wn.Point()) {
+				return true
+			}
+		}
+	case *osm.Relation:
+		for _, m := range e.Members {
+			if (m.Lat != 0 || m.Lon != 0) && contains(g, m.Point()) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func contains(g orb.Geometry, p orb.Point) bool {
+	switch g := g.(type) {
+	case orb.Bound:
+		return g.Contains(p)
+	case orb.Ring:
+		return planar.RingContains(g, p)
+	case orb.Polygon:
+		return planar.PolygonContains(g, p)
+	case orb.MultiPolygon:
+		for _, poly := range g {
+			if planar.PolygonContains(poly, p) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
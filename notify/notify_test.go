@@ -0,0 +1,313 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/osm"
+	"github.com/paulmach/osm/replication"
+)
+
+func testDiff() *osm.Diff {
+	return &osm.Diff{
+		Actions: osm.Actions{
+			{
+				Type: osm.ActionCreate,
+				OSM: &osm.OSM{Nodes: osm.Nodes{
+					{ID: 1, Version: 1, Lat: 1, Lon: 1, Tags: osm.Tags{{Key: "amenity", Value: "cafe"}}},
+				}},
+			},
+			{
+				Type: osm.ActionModify,
+				Old: &osm.OSM{Ways: osm.Ways{
+					{ID: 2, Version: 1, Nodes: osm.WayNodes{{ID: 3, Lat: 5, Lon: 5}}, Tags: osm.Tags{{Key: "highway", Value: "primary"}}},
+				}},
+				New: &osm.OSM{Ways: osm.Ways{
+					{ID: 2, Version: 2, Nodes: osm.WayNodes{{ID: 3, Lat: 5, Lon: 5}}, Tags: osm.Tags{{Key: "highway", Value: "secondary"}}},
+				}},
+			},
+			{
+				Type: osm.ActionDelete,
+				Old: &osm.OSM{Nodes: osm.Nodes{
+					{ID: 4, Version: 3, Lat: 1.5, Lon: 1.5, Tags: osm.Tags{{Key: "amenity", Value: "cafe"}}},
+				}},
+				New: &osm.OSM{Nodes: osm.Nodes{
+					{ID: 4, Version: 4, Visible: false},
+				}},
+			},
+		},
+	}
+}
+
+func mustFilter(t testing.TB, expr string) *TagFilter {
+	t.Helper()
+
+	f, err := ParseTagFilter(expr)
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+
+	return f
+}
+
+func TestNotifier_Match(t *testing.T) {
+	cases := []struct {
+		name     string
+		sub      Subscription
+		expected []osm.FeatureID
+	}{
+		{
+			name:     "all",
+			sub:      Subscription{},
+			expected: []osm.FeatureID{osm.NodeID(1).FeatureID(), osm.WayID(2).FeatureID(), osm.NodeID(4).FeatureID()},
+		},
+		{
+			name:     "tags",
+			sub:      Subscription{Filter: mustFilter(t, "amenity=cafe")},
+			expected: []osm.FeatureID{osm.NodeID(1).FeatureID(), osm.NodeID(4).FeatureID()},
+		},
+		{
+			name:     "old tags",
+			sub:      Subscription{Filter: mustFilter(t, "highway=primary")},
+			expected: []osm.FeatureID{osm.WayID(2).FeatureID()},
+		},
+		{
+			name:     "bound",
+			sub:      Subscription{Geofence: orb.Bound{Min: orb.Point{0, 0}, Max: orb.Point{2, 2}}},
+			expected: []osm.FeatureID{osm.NodeID(1).FeatureID(), osm.NodeID(4).FeatureID()},
+		},
+		{
+			name: "polygon with way",
+			sub: Subscription{
+				Filter:   mustFilter(t, "highway"),
+				Geofence: orb.Polygon{{{4, 4}, {6, 4}, {6, 6}, {4, 6}, {4, 4}}},
+			},
+			expected: []osm.FeatureID{osm.WayID(2).FeatureID()},
+		},
+		{
+			name: "no match",
+			sub:  Subscription{Filter: mustFilter(t, "shop")},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			n := NewNotifier()
+			tc.sub.ID = "sub"
+			tc.sub.URL = "http://example.com"
+			if err := n.Subscribe(tc.sub); err != nil {
+				t.Fatalf("subscribe error: %v", err)
+			}
+
+			var ids []osm.FeatureID
+			for _, notif := range n.Match(testDiff()) {
+				for _, m := range notif.Matches {
+					e := m.New
+					if m.Old != nil {
+						e = m.Old
+					}
+					ids = append(ids, e.ElementID().FeatureID())
+				}
+			}
+
+			if !reflect.DeepEqual(ids, tc.expected) {
+				t.Errorf("incorrect matches")
+				t.Logf("%v", ids)
+				t.Logf("%v", tc.expected)
+			}
+		})
+	}
+}
+
+func TestNotifier_Subscribe(t *testing.T) {
+	n := NewNotifier()
+
+	if err := n.Subscribe(Subscription{URL: "http://example.com"}); err == nil {
+		t.Errorf("expected error for missing id")
+	}
+
+	if err := n.Subscribe(Subscription{ID: "a"}); err == nil {
+		t.Errorf("expected error for missing url")
+	}
+
+	err := n.Subscribe(Subscription{ID: "a", URL: "http://example.com", Geofence: orb.Point{1, 1}})
+	if err == nil {
+		t.Errorf("expected error for point geofence")
+	}
+
+	n.Subscribe(Subscription{ID: "b", URL: "http://example.com"})
+	n.Subscribe(Subscription{ID: "a", URL: "http://example.com"})
+	if subs := n.Subscriptions(); len(subs) != 2 || subs[0].ID != "a" {
+		t.Errorf("incorrect subscriptions: %v", subs)
+	}
+
+	n.Unsubscribe("a")
+	if subs := n.Subscriptions(); len(subs) != 1 || subs[0].ID != "b" {
+		t.Errorf("incorrect subscriptions: %v", subs)
+	}
+}
+
+func TestNotifier_Notify(t *testing.T) {
+	var calls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// fail the first request to check the retry
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		body, _ := ioutil.ReadAll(r.Body)
+		if s := r.Header.Get("X-OSM-Signature"); s != Sign("secret", body) {
+			t.Errorf("incorrect signature: %v", s)
+		}
+
+		if s := r.Header.Get("X-OSM-Subscription"); s != "cafes" {
+			t.Errorf("incorrect subscription header: %v", s)
+		}
+
+		notif := struct {
+			Subscription string `json:"subscription"`
+			Matches      []struct {
+				Action string          `json:"action"`
+				Old    json.RawMessage `json:"old"`
+				New    json.RawMessage `json:"new"`
+			} `json:"matches"`
+		}{}
+		if err := json.Unmarshal(body, &notif); err != nil {
+			t.Fatalf("unmarshal error: %v", err)
+		}
+
+		if notif.Subscription != "cafes" || len(notif.Matches) != 2 {
+			t.Errorf("incorrect notification: %s", body)
+		}
+	}))
+	defer ts.Close()
+
+	n := NewNotifier(Retries(2, time.Millisecond, time.Millisecond))
+	n.Subscribe(Subscription{
+		ID:     "cafes",
+		URL:    ts.URL,
+		Filter: mustFilter(t, "amenity=cafe"),
+		Secret: "secret",
+	})
+
+	if err := n.Notify(context.Background(), testDiff()); err != nil {
+		t.Fatalf("notify error: %v", err)
+	}
+
+	if calls != 2 {
+		t.Errorf("incorrect number of calls: %v", calls)
+	}
+}
+
+func TestNotifier_Notify_error(t *testing.T) {
+	var calls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusGone)
+	}))
+	defer ts.Close()
+
+	n := NewNotifier(Retries(2, time.Millisecond, time.Millisecond))
+	n.Subscribe(Subscription{ID: "gone", URL: ts.URL})
+
+	err := n.Notify(context.Background(), testDiff())
+	e, ok := err.(*DeliveryError)
+	if !ok {
+		t.Fatalf("incorrect error: %v", err)
+	}
+
+	if sc, ok := e.Failed["gone"].(*UnexpectedStatusCodeError); !ok || sc.Code != http.StatusGone {
+		t.Errorf("incorrect failure: %v", e.Failed["gone"])
+	}
+
+	if calls != 1 {
+		t.Errorf("4xx errors should not be retried: %v", calls)
+	}
+}
+
+func TestNotifier_Handler(t *testing.T) {
+	var received Notification
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		json.Unmarshal(body, &struct {
+			Subscription *string `json:"subscription"`
+		}{&received.Subscription})
+	}))
+	defer ts.Close()
+
+	n := NewNotifier()
+	n.Subscribe(Subscription{
+		ID:       "area",
+		URL:      ts.URL,
+		Geofence: orb.Bound{Min: orb.Point{0, 0}, Max: orb.Point{2, 2}},
+	})
+
+	c := &osm.Change{
+		Modify: &osm.OSM{Nodes: osm.Nodes{{ID: 1, Version: 2, Lat: 1, Lon: 1}}},
+	}
+
+	if err := n.Handler(context.Background())(nil, c); err != nil {
+		t.Fatalf("handler error: %v", err)
+	}
+
+	if received.Subscription != "area" {
+		t.Errorf("webhook not called")
+	}
+}
+
+func TestNotifier_Handler_deliveryError(t *testing.T) {
+	var calls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		if r.Header.Get("X-OSM-Subscription") == "broken" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	logger := &testLogger{}
+	n := NewNotifier(WithLogger(logger))
+	n.Subscribe(Subscription{ID: "broken", URL: ts.URL})
+	n.Subscribe(Subscription{ID: "working", URL: ts.URL})
+
+	c := &osm.Change{
+		Modify: &osm.OSM{Nodes: osm.Nodes{{ID: 1, Version: 2, Lat: 1, Lon: 1}}},
+	}
+
+	if err := n.Handler(context.Background())(replication.MinuteSeqNum(5), c); err != nil {
+		t.Fatalf("should not stop on delivery errors: %v", err)
+	}
+
+	if v := atomic.LoadInt32(&calls); v != 2 {
+		t.Errorf("should call all the webhooks: %v", v)
+	}
+
+	if len(logger.warnings) != 1 {
+		t.Fatalf("should report the failed webhook: %v", logger.warnings)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := n.Handler(ctx)(replication.MinuteSeqNum(6), c); err != context.Canceled {
+		t.Errorf("should return context errors: %v", err)
+	}
+}
+
+type testLogger struct {
+	warnings []string
+}
+
+func (l *testLogger) Warn(msg string, keyvals ...interface{}) {
+	l.warnings = append(l.warnings, fmt.Sprintf("%s %v", msg, keyvals))
+}
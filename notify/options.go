@@ -0,0 +1,38 @@
+package notify
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/paulmach/osm"
+)
+
+// An Option is a setting for the notifier.
+type Option func(*Notifier)
+
+// WithHTTPClient sets the client used to call the webhooks.
+// The default is http.DefaultClient.
+func WithHTTPClient(c *http.Client) Option {
+	return func(n *Notifier) {
+		n.client = c
+	}
+}
+
+// Retries sets the number of times a failed webhook request is retried
+// with exponential backoff, starting at min and doubling up to max.
+// The default is 3 retries from 1 second up to 30 seconds.
+func Retries(count int, min, max time.Duration) Option {
+	return func(n *Notifier) {
+		n.maxRetries = count
+		n.minBackoff = min
+		n.maxBackoff = max
+	}
+}
+
+// WithLogger sets the logger that receives warnings for the webhooks
+// the Handler could not call.
+func WithLogger(l osm.Logger) Option {
+	return func(n *Notifier) {
+		n.logger = l
+	}
+}
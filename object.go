@@ -127,7 +127,7 @@ type ObjectIDs []ObjectID
 //	s := scanner.New(r)
 //	defer s.Close()
 //
-//	for s.Next() {
+//	for s.Scan() {
 //		o := s.Object()
 //		// do something
 //	}
@@ -0,0 +1,316 @@
+package osm
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/paulmach/orb"
+)
+
+// DefaultOrthogonalizeThreshold is the maximum number of degrees a corner
+// can be off a right angle, or a straight line, to be orthogonalized.
+const DefaultOrthogonalizeThreshold = 13.0
+
+// A GeometryEdit is the result of a geometry editing helper.
+type GeometryEdit struct {
+	// Way is a copy of the way with updated way node locations,
+	// or the new way for the creation helpers.
+	Way *Way
+
+	// Nodes are copies of the moved nodes, or the new nodes for the
+	// creation helpers.
+	Nodes Nodes
+}
+
+// Build adds the edits to the change builder. Moved nodes are modified,
+// new nodes and ways, those without a version, are created. The way is
+// not modified since its node list does not change.
+func (e *GeometryEdit) Build(b *ChangeBuilder) error {
+	for _, n := range e.Nodes {
+		if err := createOrModify(b, n); err != nil {
+			return err
+		}
+	}
+
+	if e.Way.Version == 0 {
+		return b.Create(e.Way)
+	}
+
+	return nil
+}
+
+// Orthogonalize squares the corners of a closed way, usually a building
+// footprint, to right angles. The nodes are the current versions of the
+// way nodes. Corners within threshold degrees of straight are kept on the
+// line between their neighbours. An error is returned if a corner is more
+// than threshold degrees from a right angle or straight line, the shape is
+// then not squarish. Zero uses the DefaultOrthogonalizeThreshold.
+func Orthogonalize(w *Way, nodes Nodes, threshold float64) (*GeometryEdit, error) {
+	if threshold == 0 {
+		threshold = DefaultOrthogonalizeThreshold
+	}
+
+	if len(w.Nodes) < 5 || w.Nodes[0].ID != w.Nodes[len(w.Nodes)-1].ID {
+		return nil, fmt.Errorf("osm: way %d must be closed with at least 4 nodes", w.ID)
+	}
+
+	byID := make(map[NodeID]*Node, len(nodes))
+	for _, n := range nodes {
+		byID[n.ID] = n
+	}
+
+	ring := w.Nodes[:len(w.Nodes)-1]
+	for _, wn := range ring {
+		if byID[wn.ID] == nil {
+			return nil, fmt.Errorf("osm: node %d of way %d not found", wn.ID, w.ID)
+		}
+	}
+
+	proj := newLocalProjection(ring, byID)
+	points := make([]orb.Point, len(ring))
+	for i, wn := range ring {
+		points[i] = proj.To(byID[wn.ID].Point())
+	}
+
+	lower := math.Cos((90 - threshold) * math.Pi / 180)
+	upper := math.Cos(threshold * math.Pi / 180)
+
+	var corners []int
+	for i := range points {
+		d := math.Abs(cornerDot(points, i))
+		if d > lower && d < upper {
+			return nil, fmt.Errorf("osm: way %d is not squarish, corner at node %d", w.ID, ring[i].ID)
+		}
+
+		if d <= lower {
+			corners = append(corners, i)
+		}
+	}
+
+	if len(corners) < 4 {
+		return nil, fmt.Errorf("osm: way %d must have at least 4 corners", w.ID)
+	}
+
+	squared := make([]orb.Point, len(corners))
+	for i, c := range corners {
+		squared[i] = points[c]
+	}
+	squared = squareCorners(squared)
+
+	// straight nodes are projected onto the line between the corners.
+	result := append([]orb.Point(nil), points...)
+	for i, c := range corners {
+		result[c] = squared[i]
+
+		next := corners[(i+1)%len(corners)]
+		nextPoint := squared[(i+1)%len(corners)]
+		for j := (c + 1) % len(points); j != next; j = (j + 1) % len(points) {
+			result[j] = projectToSegment(points[j], squared[i], nextPoint)
+		}
+	}
+
+	edit := &GeometryEdit{Way: w.Copy()}
+	edit.Way.Updates = nil
+
+	for i, wn := range ring {
+		p := proj.From(result[i])
+		n := byID[wn.ID]
+		if math.Abs(p.Lon()-n.Lon) < 1e-9 && math.Abs(p.Lat()-n.Lat) < 1e-9 {
+			continue
+		}
+
+		moved := n.Copy()
+		moved.Lon, moved.Lat = p.Lon(), p.Lat()
+		edit.Nodes = append(edit.Nodes, moved)
+	}
+
+	for i := range edit.Way.Nodes {
+		p := proj.From(result[i%len(ring)])
+		edit.Way.Nodes[i].Lat, edit.Way.Nodes[i].Lon = p.Lat(), p.Lon()
+	}
+
+	return edit, nil
+}
+
+// NewCircle creates a closed way with the number of nodes, at least 3,
+// on a circle around the center with the radius in meters.
+func NewCircle(a *IDAllocator, center orb.Point, radius float64, count int, tags Tags) (*GeometryEdit, error) {
+	if count < 3 {
+		return nil, errors.New("osm: circle must have at least 3 nodes")
+	}
+
+	if radius <= 0 {
+		return nil, errors.New("osm: circle radius must be positive")
+	}
+
+	points := make([]orb.Point, count)
+	for i := range points {
+		angle := 2 * math.Pi * float64(i) / float64(count)
+		points[i] = orb.Point{radius * math.Cos(angle), radius * math.Sin(angle)}
+	}
+
+	return newShape(a, center, points, tags), nil
+}
+
+// NewSquare creates a closed way with 4 nodes, a square around the center
+// with sides of size meters, aligned north-south.
+func NewSquare(a *IDAllocator, center orb.Point, size float64, tags Tags) (*GeometryEdit, error) {
+	if size <= 0 {
+		return nil, errors.New("osm: square size must be positive")
+	}
+
+	h := size / 2
+	points := []orb.Point{{-h, -h}, {h, -h}, {h, h}, {-h, h}}
+
+	return newShape(a, center, points, tags), nil
+}
+
+// newShape creates the nodes and way for the points,
+// counter clockwise in meters around the center.
+func newShape(a *IDAllocator, center orb.Point, points []orb.Point, tags Tags) *GeometryEdit {
+	proj := localProjection{
+		center: center,
+		scale:  math.Cos(center.Lat() * math.Pi / 180),
+	}
+
+	edit := &GeometryEdit{}
+	ids := make([]NodeID, 0, len(points)+1)
+	for _, p := range points {
+		// meters to degrees
+		p = proj.From(orb.Point{p[0] / orb.EarthRadius * 180 / math.Pi, p[1] / orb.EarthRadius * 180 / math.Pi})
+		n := a.NewNode(p.Lat(), p.Lon(), nil)
+		edit.Nodes = append(edit.Nodes, n)
+		ids = append(ids, n.ID)
+	}
+	ids = append(ids, ids[0])
+
+	edit.Way = a.NewWay(tags, ids...)
+	for i := range edit.Way.Nodes {
+		n := edit.Nodes[i%len(edit.Nodes)]
+		edit.Way.Nodes[i].Lat, edit.Way.Nodes[i].Lon = n.Lat, n.Lon
+	}
+
+	return edit
+}
+
+// squareCorners iteratively moves the corners until all the angles are right
+// angles, based on the iD editor orthogonalize action.
+func squareCorners(points []orb.Point) []orb.Point {
+	const epsilon = 1e-6
+
+	best := append([]orb.Point(nil), points...)
+	bestScore := orthoScore(points)
+
+	motions := make([]orb.Point, len(points))
+	for iter := 0; iter < 1000 && bestScore > epsilon; iter++ {
+		for i := range points {
+			motions[i] = cornerMotion(points, i)
+		}
+
+		for i := range points {
+			points[i] = orb.Point{points[i][0] + motions[i][0], points[i][1] + motions[i][1]}
+		}
+
+		if s := orthoScore(points); s < bestScore {
+			bestScore = s
+			copy(best, points)
+		}
+	}
+
+	return best
+}
+
+func cornerMotion(points []orb.Point, i int) orb.Point {
+	a := points[(i-1+len(points))%len(points)]
+	c := points[(i+1)%len(points)]
+	p := points[i]
+
+	u := orb.Point{a[0] - p[0], a[1] - p[1]}
+	v := orb.Point{c[0] - p[0], c[1] - p[1]}
+
+	scale := 2 * math.Min(vectorLength(u), vectorLength(v))
+	u, v = normalize(u), normalize(v)
+
+	dot := u[0]*v[0] + u[1]*v[1]
+	move := normalize(orb.Point{u[0] + v[0], u[1] + v[1]})
+
+	return orb.Point{0.1 * dot * scale * move[0], 0.1 * dot * scale * move[1]}
+}
+
+func orthoScore(points []orb.Point) float64 {
+	score := 0.0
+	for i := range points {
+		score += math.Abs(cornerDot(points, i))
+	}
+
+	return score
+}
+
+// cornerDot returns the cosine of the angle at the point,
+// 0 for a right angle and -1 for a straight line.
+func cornerDot(points []orb.Point, i int) float64 {
+	a := points[(i-1+len(points))%len(points)]
+	c := points[(i+1)%len(points)]
+	p := points[i]
+
+	u := normalize(orb.Point{a[0] - p[0], a[1] - p[1]})
+	v := normalize(orb.Point{c[0] - p[0], c[1] - p[1]})
+
+	return u[0]*v[0] + u[1]*v[1]
+}
+
+func projectToSegment(p, a, b orb.Point) orb.Point {
+	dx, dy := b[0]-a[0], b[1]-a[1]
+	l := dx*dx + dy*dy
+	if l == 0 {
+		return a
+	}
+
+	t := ((p[0]-a[0])*dx + (p[1]-a[1])*dy) / l
+	t = math.Max(0, math.Min(1, t))
+
+	return orb.Point{a[0] + t*dx, a[1] + t*dy}
+}
+
+func vectorLength(p orb.Point) float64 {
+	return math.Sqrt(p[0]*p[0] + p[1]*p[1])
+}
+
+func normalize(p orb.Point) orb.Point {
+	l := vectorLength(p)
+	if l == 0 {
+		return p
+	}
+
+	return orb.Point{p[0] / l, p[1] / l}
+}
+
+// localProjection is an equirectangular projection around a center,
+// good enough for the angles of small shapes like buildings.
+type localProjection struct {
+	center orb.Point
+	scale  float64
+}
+
+func newLocalProjection(ring WayNodes, nodes map[NodeID]*Node) localProjection {
+	var lon, lat float64
+	for _, wn := range ring {
+		lon += nodes[wn.ID].Lon
+		lat += nodes[wn.ID].Lat
+	}
+
+	center := orb.Point{lon / float64(len(ring)), lat / float64(len(ring))}
+	return localProjection{
+		center: center,
+		scale:  math.Cos(center.Lat() * math.Pi / 180),
+	}
+}
+
+func (p localProjection) To(q orb.Point) orb.Point {
+	return orb.Point{(q.Lon() - p.center.Lon()) * p.scale, q.Lat() - p.center.Lat()}
+}
+
+func (p localProjection) From(q orb.Point) orb.Point {
+	return orb.Point{q[0]/p.scale + p.center.Lon(), q[1] + p.center.Lat()}
+}
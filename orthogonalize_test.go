@@ -0,0 +1,149 @@
+package osm
+
+import (
+	"math"
+	"testing"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geo"
+)
+
+func TestOrthogonalize(t *testing.T) {
+	// a slightly skewed building with a straight node on one side
+	nodes := Nodes{
+		{ID: 1, Version: 1, Lon: 0, Lat: 0},
+		{ID: 2, Version: 1, Lon: 0.0010, Lat: 0.00003},
+		{ID: 3, Version: 1, Lon: 0.00102, Lat: 0.0010},
+		{ID: 4, Version: 1, Lon: 0.0005, Lat: 0.00101},
+		{ID: 5, Version: 1, Lon: 0.00002, Lat: 0.00098},
+	}
+
+	w := &Way{ID: 1, Version: 1, Nodes: WayNodes{{ID: 1}, {ID: 2}, {ID: 3}, {ID: 4}, {ID: 5}, {ID: 1}}}
+
+	edit, err := Orthogonalize(w, nodes, 0)
+	if err != nil {
+		t.Fatalf("orthogonalize error: %v", err)
+	}
+
+	moved := make(map[NodeID]*Node)
+	for _, n := range nodes {
+		moved[n.ID] = n
+	}
+	for _, n := range edit.Nodes {
+		moved[n.ID] = n
+	}
+
+	proj := newLocalProjection(w.Nodes[:5], moved)
+	points := []orb.Point{}
+	for _, id := range []NodeID{1, 2, 3, 5} {
+		points = append(points, proj.To(moved[id].Point()))
+	}
+
+	for i := range points {
+		if d := cornerDot(points, i); math.Abs(d) > 1e-3 {
+			t.Errorf("corner %d not square: %v", i, d)
+		}
+	}
+
+	// the straight node should be on the line
+	line := []orb.Point{points[2], proj.To(moved[4].Point()), points[3]}
+	if d := cornerDot(line, 1); d > -0.999 {
+		t.Errorf("straight node not on line: %v", d)
+	}
+
+	if edit.Way.Nodes[0].Lat != moved[1].Lat || edit.Way.Nodes[5].Lat != moved[1].Lat {
+		t.Errorf("way node locations not updated")
+	}
+
+	if nodes[1].Lat != 0.00003 {
+		t.Errorf("should not modify input nodes")
+	}
+
+	b := NewChangeBuilder()
+	if err := edit.Build(b); err != nil {
+		t.Fatalf("build error: %v", err)
+	}
+
+	if b.Len() != len(edit.Nodes) {
+		t.Errorf("should only modify the nodes: %v", b.Len())
+	}
+}
+
+func TestOrthogonalize_errors(t *testing.T) {
+	nodes := Nodes{
+		{ID: 1, Lon: 0, Lat: 0},
+		{ID: 2, Lon: 1, Lat: 0},
+		{ID: 3, Lon: 0.5, Lat: 1},
+		{ID: 4, Lon: 0, Lat: 0.5},
+	}
+
+	if _, err := Orthogonalize(&Way{Nodes: WayNodes{{ID: 1}, {ID: 2}, {ID: 3}, {ID: 4}}}, nodes, 0); err == nil {
+		t.Errorf("should error for unclosed way")
+	}
+
+	if _, err := Orthogonalize(&Way{Nodes: WayNodes{{ID: 1}, {ID: 2}, {ID: 3}, {ID: 4}, {ID: 1}}}, nodes, 0); err == nil {
+		t.Errorf("should error for not squarish way")
+	}
+
+	if _, err := Orthogonalize(&Way{Nodes: WayNodes{{ID: 1}, {ID: 2}, {ID: 3}, {ID: 5}, {ID: 1}}}, nodes, 0); err == nil {
+		t.Errorf("should error for missing node")
+	}
+}
+
+func TestNewCircle(t *testing.T) {
+	center := orb.Point{-122.4, 37.8}
+	edit, err := NewCircle(&IDAllocator{}, center, 100, 12, Tags{{Key: "building", Value: "yes"}})
+	if err != nil {
+		t.Fatalf("circle error: %v", err)
+	}
+
+	if len(edit.Nodes) != 12 || len(edit.Way.Nodes) != 13 {
+		t.Fatalf("incorrect nodes: %v %v", len(edit.Nodes), len(edit.Way.Nodes))
+	}
+
+	if edit.Way.Nodes[0].ID != edit.Way.Nodes[12].ID {
+		t.Errorf("way should be closed")
+	}
+
+	for _, n := range edit.Nodes {
+		if d := geo.Distance(center, n.Point()); math.Abs(d-100) > 0.5 {
+			t.Errorf("incorrect radius: %v", d)
+		}
+	}
+
+	b := NewChangeBuilder()
+	if err := edit.Build(b); err != nil {
+		t.Fatalf("build error: %v", err)
+	}
+
+	if b.Len() != 13 {
+		t.Errorf("should create nodes and way: %v", b.Len())
+	}
+
+	if _, err := NewCircle(&IDAllocator{}, center, 100, 2, nil); err == nil {
+		t.Errorf("should error with too few nodes")
+	}
+}
+
+func TestNewSquare(t *testing.T) {
+	center := orb.Point{5, 50}
+	edit, err := NewSquare(&IDAllocator{}, center, 20, nil)
+	if err != nil {
+		t.Fatalf("square error: %v", err)
+	}
+
+	if len(edit.Nodes) != 4 {
+		t.Fatalf("incorrect nodes: %v", len(edit.Nodes))
+	}
+
+	for i, n := range edit.Nodes {
+		next := edit.Nodes[(i+1)%4]
+		if d := geo.Distance(n.Point(), next.Point()); math.Abs(d-20) > 0.1 {
+			t.Errorf("incorrect side length: %v", d)
+		}
+	}
+
+	if _, err := NewSquare(&IDAllocator{}, center, 0, nil); err == nil {
+		t.Errorf("should error with zero size")
+	}
+}
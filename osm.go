@@ -46,29 +46,34 @@ type OSM struct {
 
 // Marshal encodes the osm data using protocol buffers.
 // Will only save the elements: nodes, ways and relations.
-func (o *OSM) Marshal() ([]byte, error) {
-	ss := &stringSet{}
-	encoded := marshalOSM(o, ss, true)
-	encoded.Strings = ss.Strings()
+func (o *OSM) Marshal(opts ...MarshalOption) ([]byte, error) {
+	mo := newMarshalOptions(opts)
+
+	chunks, err := encodeOSM(o, mo)
+	if err != nil {
+		return nil, err
+	}
 
-	return proto.Marshal(encoded)
+	return joinChunks(chunks), nil
 }
 
 // Append will add the given object to the OSM object.
 func (o *OSM) Append(obj Object) {
-	switch obj.ObjectID().Type() {
-	case TypeNode:
-		o.Nodes = append(o.Nodes, obj.(*Node))
-	case TypeWay:
-		o.Ways = append(o.Ways, obj.(*Way))
-	case TypeRelation:
-		o.Relations = append(o.Relations, obj.(*Relation))
-	case TypeChangeset:
-		o.Changesets = append(o.Changesets, obj.(*Changeset))
-	case TypeNote:
-		o.Notes = append(o.Notes, obj.(*Note))
-	case TypeUser:
-		o.Users = append(o.Users, obj.(*User))
+	// switch on the concrete type since negative ids,
+	// used for new elements, do not pack into an ObjectID.
+	switch obj := obj.(type) {
+	case *Node:
+		o.Nodes = append(o.Nodes, obj)
+	case *Way:
+		o.Ways = append(o.Ways, obj)
+	case *Relation:
+		o.Relations = append(o.Relations, obj)
+	case *Changeset:
+		o.Changesets = append(o.Changesets, obj)
+	case *Note:
+		o.Notes = append(o.Notes, obj)
+	case *User:
+		o.Users = append(o.Users, obj)
 	default:
 		panic(fmt.Sprintf("unsupported type: %[1]T: %[1]v", obj))
 	}
@@ -189,6 +194,9 @@ func (o *OSM) HistoryDatasource() *HistoryDatasource {
 
 // UnmarshalOSM will unmarshal the data into a OSM object.
 func UnmarshalOSM(data []byte) (*OSM, error) {
+	if isChunked(data) {
+		return unmarshalOSMChunks(data)
+	}
 
 	pbf := &osmpb.OSM{}
 	err := proto.Unmarshal(data, pbf)
@@ -196,41 +204,111 @@ func UnmarshalOSM(data []byte) (*OSM, error) {
 		return nil, err
 	}
 
-	return unmarshalOSM(pbf, pbf.GetStrings(), nil)
+	if err := verifyChecksum(data, pbf.Checksum); err != nil {
+		return nil, err
+	}
+
+	legacy, err := checkSchemaVersion(pbf.GetSchemaVersion())
+	if err != nil {
+		return nil, err
+	}
+
+	if legacy {
+		upgradeOSMV1(pbf)
+	}
+
+	ss, err := stringTable(pbf.GetDictionary(), pbf.GetStrings())
+	if err != nil {
+		return nil, err
+	}
+
+	return unmarshalOSM(pbf, ss, nil)
+}
+
+// marshalElement encodes a single element as the only element of the osm
+// data. Nodes are not dense encoded, which only saves space for many nodes.
+func marshalElement(e Element, opts []MarshalOption) ([]byte, error) {
+	mo := newMarshalOptions(opts)
+
+	encode := func(ss *stringSet) *osmpb.OSM {
+		encoded := &osmpb.OSM{}
+		switch e := e.(type) {
+		case *Node:
+			encoded.Nodes = []*osmpb.Node{marshalNode(e, ss, true, mo.rounding)}
+		case *Way:
+			encoded.Ways = []*osmpb.Way{marshalWay(e, ss, true, mo.rounding)}
+		case *Relation:
+			encoded.Relations = []*osmpb.Relation{marshalRelation(e, ss, true, mo.rounding)}
+		}
+
+		return encoded
+	}
+
+	ss := mo.newStringSet(func(ss *stringSet) { encode(ss) })
+	encoded := encode(ss)
+	encoded.Strings = ss.Strings()
+	encoded.SchemaVersion = schemaVersion()
+	encoded.Dictionary = mo.dictionaryVersion()
+
+	return marshalProto(encoded, mo)
+}
+
+// unmarshalElement decodes the data and checks it is a single element.
+func unmarshalElement(data []byte, t Type) (*OSM, error) {
+	o, err := UnmarshalOSM(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var l int
+	switch t {
+	case TypeNode:
+		l = len(o.Nodes)
+	case TypeWay:
+		l = len(o.Ways)
+	case TypeRelation:
+		l = len(o.Relations)
+	}
+
+	if l != 1 || len(o.Nodes)+len(o.Ways)+len(o.Relations) != 1 {
+		return nil, fmt.Errorf("osm: data is not a single %s", t)
+	}
+
+	return o, nil
 }
 
 // includeChangeset can be set to false to not repeat the changeset
 // info for every item, if this comes from osm change data.
-func marshalOSM(o *OSM, ss *stringSet, includeChangeset bool) *osmpb.OSM {
+func marshalOSM(o *OSM, ss *stringSet, includeChangeset bool, mode RoundingMode) *osmpb.OSM {
 	encoded := &osmpb.OSM{}
 	if o == nil {
 		return nil
 	}
 
 	if len(o.Nodes) > 0 {
-		encoded.DenseNodes = marshalNodes(o.Nodes, ss, includeChangeset)
+		encoded.DenseNodes = marshalNodes(o.Nodes, ss, includeChangeset, mode)
 	}
 
 	if len(o.Ways) > 0 {
 		encoded.Ways = make([]*osmpb.Way, len(o.Ways))
 		for i, w := range o.Ways {
-			encoded.Ways[i] = marshalWay(w, ss, includeChangeset)
+			encoded.Ways[i] = marshalWay(w, ss, includeChangeset, mode)
 		}
 	}
 
 	if len(o.Relations) > 0 {
 		encoded.Relations = make([]*osmpb.Relation, len(o.Relations))
 		for i, r := range o.Relations {
-			encoded.Relations[i] = marshalRelation(r, ss, includeChangeset)
+			encoded.Relations[i] = marshalRelation(r, ss, includeChangeset, mode)
 		}
 	}
 
 	if o.Bounds != nil {
 		encoded.Bounds = &osmpb.Bounds{
-			MinLat: geoToInt64(o.Bounds.MinLat),
-			MaxLat: geoToInt64(o.Bounds.MaxLat),
-			MinLon: geoToInt64(o.Bounds.MinLon),
-			MaxLon: geoToInt64(o.Bounds.MaxLon),
+			MinLat: GeoToInt64(o.Bounds.MinLat, mode),
+			MaxLat: GeoToInt64(o.Bounds.MaxLat, mode),
+			MinLon: GeoToInt64(o.Bounds.MinLon, mode),
+			MaxLon: GeoToInt64(o.Bounds.MaxLon, mode),
 		}
 	}
 
@@ -320,6 +398,67 @@ func (o OSM) MarshalJSON() ([]byte, error) {
 	return json.Marshal(s)
 }
 
+// UnmarshalJSON will unmarshal the osmjson format, the inverse of MarshalJSON.
+// Elements of an unknown type, eg. overpass areas, are skipped.
+func (o *OSM) UnmarshalJSON(data []byte) error {
+	s := struct {
+		Version     float64           `json:"version"`
+		Generator   string            `json:"generator"`
+		Copyright   string            `json:"copyright"`
+		Attribution string            `json:"attribution"`
+		License     string            `json:"license"`
+		Elements    []json.RawMessage `json:"elements"`
+	}{}
+
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+
+	result := OSM{
+		Version:     s.Version,
+		Generator:   s.Generator,
+		Copyright:   s.Copyright,
+		Attribution: s.Attribution,
+		License:     s.License,
+	}
+
+	for _, raw := range s.Elements {
+		t := struct {
+			Type string `json:"type"`
+		}{}
+		if err := json.Unmarshal(raw, &t); err != nil {
+			return err
+		}
+
+		var obj Object
+		switch t.Type {
+		case "node":
+			obj = &Node{}
+		case "way":
+			obj = &Way{}
+		case "relation":
+			obj = &Relation{}
+		case "changeset":
+			obj = &Changeset{}
+		case "note":
+			obj = &Note{}
+		case "user":
+			obj = &User{}
+		default:
+			continue
+		}
+
+		if err := json.Unmarshal(raw, obj); err != nil {
+			return err
+		}
+
+		result.Append(obj)
+	}
+
+	*o = result
+	return nil
+}
+
 // MarshalXML implements the xml.Marshaller method to allow for the
 // correct wrapper/start element case and attr data.
 func (o OSM) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
@@ -403,3 +542,31 @@ func (o *OSM) marshalInnerElementsXML(e *xml.Encoder) error {
 
 	return e.Encode(o.Relations)
 }
+
+func (o *OSM) marshalInnerDeleteXML(e *xml.Encoder) error {
+	if err := e.Encode(o.Bounds); err != nil {
+		return err
+	}
+
+	if err := e.Encode(o.Relations); err != nil {
+		return err
+	}
+
+	if err := e.Encode(o.Ways); err != nil {
+		return err
+	}
+
+	if err := e.Encode(o.Nodes); err != nil {
+		return err
+	}
+
+	if err := e.Encode(o.Changesets); err != nil {
+		return err
+	}
+
+	if err := e.Encode(o.Notes); err != nil {
+		return err
+	}
+
+	return e.Encode(o.Users)
+}
@@ -209,6 +209,69 @@ func TestOSM_MarshalJSON(t *testing.T) {
 	}
 }
 
+func TestOSM_UnmarshalJSON(t *testing.T) {
+	data := []byte(`{"version":0.6,"generator":"osm-go","elements":[
+		{"type":"node","id":123,"lat":1.5,"lon":2,"visible":true,"timestamp":"2018-01-02T03:04:05Z","tags":{"amenity":"cafe"}},
+		{"type":"way","id":456,"visible":true,"timestamp":"0001-01-01T00:00:00Z","nodes":[123,124]},
+		{"type":"area","id":3600000789},
+		{"type":"relation","id":789,"visible":false,"timestamp":"0001-01-01T00:00:00Z","members":[{"type":"way","ref":456,"role":"outer"}]},
+		{"type":"changeset","id":10,"created_at":"0001-01-01T00:00:00Z","closed_at":"0001-01-01T00:00:00Z","open":true}]}`)
+
+	o := &OSM{}
+	err := json.Unmarshal(data, o)
+	if err != nil {
+		t.Fatalf("unmarshal error: %v", err)
+	}
+
+	if o.Version != 0.6 || o.Generator != "osm-go" {
+		t.Errorf("incorrect attributes: %v %v", o.Version, o.Generator)
+	}
+
+	if len(o.Nodes) != 1 || len(o.Ways) != 1 || len(o.Relations) != 1 || len(o.Changesets) != 1 {
+		t.Fatalf("incorrect elements: %+v", o)
+	}
+
+	n := o.Nodes[0]
+	if n.ID != 123 || n.Lat != 1.5 || n.Tags.Find("amenity") != "cafe" || !n.Visible {
+		t.Errorf("incorrect node: %+v", n)
+	}
+
+	if ids := o.Ways[0].Nodes.NodeIDs(); len(ids) != 2 || ids[1] != 124 {
+		t.Errorf("incorrect way nodes: %v", ids)
+	}
+
+	if m := o.Relations[0].Members[0]; m.Type != TypeWay || m.Ref != 456 || m.Role != "outer" {
+		t.Errorf("incorrect member: %+v", m)
+	}
+
+	if cs := o.Changesets[0]; cs.ID != 10 || !cs.Open {
+		t.Errorf("incorrect changeset: %+v", cs)
+	}
+
+	// round trip
+	data, err = json.Marshal(o)
+	if err != nil {
+		t.Fatalf("marshal error: %v", err)
+	}
+
+	o2 := &OSM{}
+	if err := json.Unmarshal(data, o2); err != nil {
+		t.Fatalf("unmarshal error: %v", err)
+	}
+
+	if !reflect.DeepEqual(o, o2) {
+		t.Errorf("incorrect round trip")
+		t.Logf("%+v", o)
+		t.Logf("%+v", o2)
+	}
+
+	// type attribute must match the struct
+	err = json.Unmarshal([]byte(`{"type":"way","id":1}`), &Node{})
+	if err == nil {
+		t.Errorf("should return error for incorrect type")
+	}
+}
+
 func TestOSM_MarshalXML(t *testing.T) {
 	o := &OSM{
 		Version:     0.7,
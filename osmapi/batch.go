@@ -0,0 +1,146 @@
+package osmapi
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/paulmach/osm"
+)
+
+// maxURLLength is the url length above which the multi id requests,
+// i.e. Nodes, Ways and Relations, are split into batches.
+// The api servers reject urls longer than about 8k.
+const maxURLLength = 8000
+
+// PartialError is returned by the multi id requests, i.e. Nodes, Ways and
+// Relations, when some of the elements could not be fetched. The other
+// elements are still returned.
+type PartialError struct {
+	// Missing are the elements that do not exist, 404 from the api.
+	Missing []osm.FeatureID
+
+	// Deleted are the elements that have been deleted, 410 from the api.
+	Deleted []osm.FeatureID
+}
+
+// Error returns an error message with the number of missing elements.
+func (e *PartialError) Error() string {
+	return fmt.Sprintf("osmapi: %d missing and %d deleted elements", len(e.Missing), len(e.Deleted))
+}
+
+// getMany fetches the elements of the type using the multi id endpoint,
+// splitting the ids into batches to stay below the url length limit.
+// Missing and deleted elements are found by splitting the failed batches
+// and are returned in a *PartialError with the other elements.
+func (ds *Datasource) getMany(ctx context.Context, t osm.Type, ids []int64, opts []FeatureOption) (*osm.OSM, error) {
+	params, err := featureOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &osm.OSM{}
+	partial := &PartialError{}
+
+	for len(ids) > 0 {
+		n := ds.batchSize(t, ids, params)
+		if err := ds.getBatch(ctx, t, ids[:n], params, result, partial); err != nil {
+			return nil, err
+		}
+		ids = ids[n:]
+	}
+
+	if len(partial.Missing) > 0 || len(partial.Deleted) > 0 {
+		return result, partial
+	}
+
+	return result, nil
+}
+
+// batchSize returns the number of ids that fit in the url, at least one.
+func (ds *Datasource) batchSize(t osm.Type, ids []int64, params string) int {
+	l := len(multiURL(ds.baseURL(), t, nil, params))
+	for i, id := range ids {
+		l += len(strconv.FormatInt(id, 10)) + 1
+		if l > maxURLLength && i > 0 {
+			return i
+		}
+	}
+
+	return len(ids)
+}
+
+func (ds *Datasource) getBatch(
+	ctx context.Context,
+	t osm.Type,
+	ids []int64,
+	params string,
+	result *osm.OSM,
+	partial *PartialError,
+) error {
+	o := &osm.OSM{}
+	err := ds.getFromAPI(ctx, multiURL(ds.baseURL(), t, ids, params), &o)
+
+	switch err.(type) {
+	case nil:
+		result.Nodes = append(result.Nodes, o.Nodes...)
+		result.Ways = append(result.Ways, o.Ways...)
+		result.Relations = append(result.Relations, o.Relations...)
+		return nil
+	case *NotFoundError, *GoneError, *RequestURITooLongError:
+		// find the problem ids by splitting the batch
+	default:
+		return err
+	}
+
+	if len(ids) == 1 {
+		fid, ferr := t.FeatureID(ids[0])
+		if ferr != nil {
+			return ferr
+		}
+
+		switch err.(type) {
+		case *NotFoundError:
+			partial.Missing = append(partial.Missing, fid)
+		case *GoneError:
+			partial.Deleted = append(partial.Deleted, fid)
+		default:
+			return err
+		}
+
+		return nil
+	}
+
+	h := len(ids) / 2
+	if err := ds.getBatch(ctx, t, ids[:h], params, result, partial); err != nil {
+		return err
+	}
+
+	return ds.getBatch(ctx, t, ids[h:], params, result, partial)
+}
+
+// multiURL returns the url for the multi id request,
+// e.g. /nodes?nodes=1,2,3
+func multiURL(base string, t osm.Type, ids []int64, params string) string {
+	data := make([]byte, 0, len(base)+11*len(ids)+len(params)+30)
+	data = append(data, base...)
+	data = append(data, '/')
+	data = append(data, t...)
+	data = append(data, "s?"...)
+	data = append(data, t...)
+	data = append(data, "s="...)
+
+	for i, id := range ids {
+		if i != 0 {
+			data = append(data, ',')
+		}
+		data = strconv.AppendInt(data, id, 10)
+	}
+
+	if len(params) > 0 {
+		data = append(data, '&')
+		data = append(data, params...)
+	}
+
+	return string(data)
+}
@@ -0,0 +1,124 @@
+package osmapi
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/paulmach/osm"
+)
+
+// multiServer mimics the multi id endpoints, failing the whole request
+// if any of the ids are missing or deleted.
+func multiServer(t *testing.T, missing, deleted map[int64]bool) (*httptest.Server, *[]string) {
+	var (
+		lk   sync.Mutex
+		urls []string
+	)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lk.Lock()
+		urls = append(urls, r.URL.String())
+		lk.Unlock()
+
+		if len(r.URL.String()) > maxURLLength+len("/nodes") {
+			w.WriteHeader(http.StatusRequestURITooLong)
+			return
+		}
+
+		body := "<osm>"
+		for _, v := range strings.Split(r.URL.Query().Get("nodes"), ",") {
+			id, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				t.Fatalf("invalid id: %v", err)
+			}
+
+			if missing[id] {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+
+			if deleted[id] {
+				w.WriteHeader(http.StatusGone)
+				return
+			}
+
+			body += fmt.Sprintf(`<node id="%d" visible="true"></node>`, id)
+		}
+
+		w.Write([]byte(body + "</osm>"))
+	}))
+
+	return ts, &urls
+}
+
+func TestDatasource_Nodes_batches(t *testing.T) {
+	ts, urls := multiServer(t, nil, nil)
+	defer ts.Close()
+
+	var ids []osm.NodeID
+	for i := 0; i < 2000; i++ {
+		ids = append(ids, osm.NodeID(5000000000+i))
+	}
+
+	ds := &Datasource{BaseURL: ts.URL, Client: ts.Client()}
+	nodes, err := ds.Nodes(context.Background(), ids)
+	if err != nil {
+		t.Fatalf("request error: %v", err)
+	}
+
+	if len(*urls) != 3 {
+		t.Errorf("incorrect number of requests: %v", len(*urls))
+	}
+
+	if !reflect.DeepEqual(nodes.IDs(), ids) {
+		t.Errorf("incorrect nodes: %v", len(nodes))
+	}
+}
+
+func TestDatasource_Nodes_partial(t *testing.T) {
+	ts, _ := multiServer(t,
+		map[int64]bool{3: true, 7: true},
+		map[int64]bool{5: true},
+	)
+	defer ts.Close()
+
+	ds := &Datasource{BaseURL: ts.URL, Client: ts.Client()}
+	nodes, err := ds.Nodes(context.Background(), []osm.NodeID{1, 2, 3, 4, 5, 6, 7, 8})
+
+	pe, ok := err.(*PartialError)
+	if !ok {
+		t.Fatalf("incorrect error: %v", err)
+	}
+
+	if !reflect.DeepEqual(pe.Missing, []osm.FeatureID{osm.NodeID(3).FeatureID(), osm.NodeID(7).FeatureID()}) {
+		t.Errorf("incorrect missing: %v", pe.Missing)
+	}
+
+	if !reflect.DeepEqual(pe.Deleted, []osm.FeatureID{osm.NodeID(5).FeatureID()}) {
+		t.Errorf("incorrect deleted: %v", pe.Deleted)
+	}
+
+	if !reflect.DeepEqual(nodes.IDs(), []osm.NodeID{1, 2, 4, 6, 8}) {
+		t.Errorf("incorrect nodes: %v", nodes.IDs())
+	}
+}
+
+func TestDatasource_Nodes_error(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer ts.Close()
+
+	ds := &Datasource{BaseURL: ts.URL, Client: ts.Client()}
+	_, err := ds.Nodes(context.Background(), []osm.NodeID{1, 2})
+	if _, ok := err.(*UnexpectedStatusCodeError); !ok {
+		t.Errorf("incorrect error: %v", err)
+	}
+}
@@ -0,0 +1,185 @@
+package osmapi
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/paulmach/osm"
+)
+
+// Capabilities are the limits and status of the api server,
+// from the /capabilities call.
+type Capabilities struct {
+	MinVersion string
+	MaxVersion string
+
+	// MaxArea is the largest bounding box, in square degrees,
+	// of the Map call.
+	MaxArea float64
+
+	// MaxNoteArea is the largest bounding box, in square degrees,
+	// of the Notes call.
+	MaxNoteArea float64
+
+	TracepointsPerPage   int
+	MaxWayNodes          int
+	MaxRelationMembers   int
+	MaxChangesetElements int
+
+	ChangesetsDefaultQueryLimit int
+	ChangesetsMaxQueryLimit     int
+	NotesDefaultQueryLimit      int
+	NotesMaxQueryLimit          int
+
+	// Timeout is how long the server processes a request.
+	Timeout time.Duration
+
+	// DatabaseStatus, APIStatus and GPXStatus are online,
+	// readonly or offline.
+	DatabaseStatus string
+	APIStatus      string
+	GPXStatus      string
+
+	Policy Policy
+}
+
+// Policy is the usage policy of the server, also part of
+// the /capabilities call.
+type Policy struct {
+	// ImageryBlacklist are the regular expressions matching the
+	// urls of imagery that may not be used for mapping.
+	ImageryBlacklist []*regexp.Regexp
+}
+
+// ImageryBlacklisted returns true if the imagery url matches
+// any of the blacklist expressions.
+func (p *Policy) ImageryBlacklisted(url string) bool {
+	for _, re := range p.ImageryBlacklist {
+		if re.MatchString(url) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// capabilitiesXML mirrors the xml of the /capabilities call.
+type capabilitiesXML struct {
+	API struct {
+		Version struct {
+			Minimum string `xml:"minimum,attr"`
+			Maximum string `xml:"maximum,attr"`
+		} `xml:"version"`
+		Area struct {
+			Maximum float64 `xml:"maximum,attr"`
+		} `xml:"area"`
+		NoteArea struct {
+			Maximum float64 `xml:"maximum,attr"`
+		} `xml:"note_area"`
+		Tracepoints struct {
+			PerPage int `xml:"per_page,attr"`
+		} `xml:"tracepoints"`
+		WayNodes struct {
+			Maximum int `xml:"maximum,attr"`
+		} `xml:"waynodes"`
+		RelationMembers struct {
+			Maximum int `xml:"maximum,attr"`
+		} `xml:"relationmembers"`
+		Changesets struct {
+			MaximumElements   int `xml:"maximum_elements,attr"`
+			DefaultQueryLimit int `xml:"default_query_limit,attr"`
+			MaximumQueryLimit int `xml:"maximum_query_limit,attr"`
+		} `xml:"changesets"`
+		Notes struct {
+			DefaultQueryLimit int `xml:"default_query_limit,attr"`
+			MaximumQueryLimit int `xml:"maximum_query_limit,attr"`
+		} `xml:"notes"`
+		Timeout struct {
+			Seconds int `xml:"seconds,attr"`
+		} `xml:"timeout"`
+		Status struct {
+			Database string `xml:"database,attr"`
+			API      string `xml:"api,attr"`
+			GPX      string `xml:"gpx,attr"`
+		} `xml:"status"`
+	} `xml:"api"`
+	Policy struct {
+		Blacklist []struct {
+			Regex string `xml:"regex,attr"`
+		} `xml:"imagery>blacklist"`
+	} `xml:"policy"`
+}
+
+// GetCapabilities returns the limits and policy of the api server.
+// Delegates to the DefaultDatasource and uses its http.Client to make the request.
+func GetCapabilities(ctx context.Context) (*Capabilities, error) {
+	return DefaultDatasource.GetCapabilities(ctx)
+}
+
+// GetCapabilities returns the limits and policy of the api server.
+// Set the result as the Limits of the datasource to check the
+// requests against them before they are made.
+func (ds *Datasource) GetCapabilities(ctx context.Context) (*Capabilities, error) {
+	url := fmt.Sprintf("%s/capabilities", ds.baseURL())
+
+	c := &capabilitiesXML{}
+	if err := ds.getFromAPI(ctx, url, &c); err != nil {
+		return nil, err
+	}
+
+	result := &Capabilities{
+		MinVersion:                  c.API.Version.Minimum,
+		MaxVersion:                  c.API.Version.Maximum,
+		MaxArea:                     c.API.Area.Maximum,
+		MaxNoteArea:                 c.API.NoteArea.Maximum,
+		TracepointsPerPage:          c.API.Tracepoints.PerPage,
+		MaxWayNodes:                 c.API.WayNodes.Maximum,
+		MaxRelationMembers:          c.API.RelationMembers.Maximum,
+		MaxChangesetElements:        c.API.Changesets.MaximumElements,
+		ChangesetsDefaultQueryLimit: c.API.Changesets.DefaultQueryLimit,
+		ChangesetsMaxQueryLimit:     c.API.Changesets.MaximumQueryLimit,
+		NotesDefaultQueryLimit:      c.API.Notes.DefaultQueryLimit,
+		NotesMaxQueryLimit:          c.API.Notes.MaximumQueryLimit,
+		Timeout:                     time.Duration(c.API.Timeout.Seconds) * time.Second,
+		DatabaseStatus:              c.API.Status.Database,
+		APIStatus:                   c.API.Status.API,
+		GPXStatus:                   c.API.Status.GPX,
+	}
+
+	for _, b := range c.Policy.Blacklist {
+		re, err := regexp.Compile(b.Regex)
+		if err != nil {
+			return nil, fmt.Errorf("osmapi: invalid imagery blacklist regex %q: %v", b.Regex, err)
+		}
+
+		result.Policy.ImageryBlacklist = append(result.Policy.ImageryBlacklist, re)
+	}
+
+	return result, nil
+}
+
+// LimitError is returned when a request exceeds the capabilities
+// set as the Limits of the datasource. The request is not made.
+type LimitError struct {
+	Limit string
+	Value float64
+	Max   float64
+}
+
+// Error returns an error message with the exceeded limit.
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("osmapi: %s of %v exceeds the maximum of %v", e.Limit, e.Value, e.Max)
+}
+
+// checkArea returns a *LimitError if the bounds area is larger than the max.
+// A zero max has no limit.
+func checkArea(limit string, b *osm.Bounds, max float64) error {
+	area := (b.MaxLon - b.MinLon) * (b.MaxLat - b.MinLat)
+	if max > 0 && area > max {
+		return &LimitError{Limit: limit, Value: area, Max: max}
+	}
+
+	return nil
+}
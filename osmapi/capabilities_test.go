@@ -0,0 +1,136 @@
+package osmapi
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/paulmach/osm"
+)
+
+const capabilitiesResponse = `<?xml version="1.0" encoding="UTF-8"?>
+<osm version="0.6" generator="OpenStreetMap server">
+  <api>
+    <version minimum="0.6" maximum="0.6"/>
+    <area maximum="0.25"/>
+    <note_area maximum="25"/>
+    <tracepoints per_page="5000"/>
+    <waynodes maximum="2000"/>
+    <relationmembers maximum="32000"/>
+    <changesets maximum_elements="10000" default_query_limit="100" maximum_query_limit="100"/>
+    <notes default_query_limit="100" maximum_query_limit="10000"/>
+    <timeout seconds="300"/>
+    <status database="online" api="online" gpx="online"/>
+  </api>
+  <policy>
+    <imagery>
+      <blacklist regex=".*\.google(apis)?\..*/.*"/>
+      <blacklist regex="http://xdworld\.vworld\.kr:8080/.*"/>
+    </imagery>
+  </policy>
+</osm>`
+
+func TestDatasource_GetCapabilities(t *testing.T) {
+	url := ""
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		url = r.URL.String()
+		w.Write([]byte(capabilitiesResponse))
+	}))
+	defer ts.Close()
+
+	ds := &Datasource{BaseURL: ts.URL, Client: ts.Client()}
+	c, err := ds.GetCapabilities(context.Background())
+	if err != nil {
+		t.Fatalf("request error: %v", err)
+	}
+
+	if url != "/capabilities" {
+		t.Errorf("incorrect url: %v", url)
+	}
+
+	if c.MaxVersion != "0.6" || c.MaxArea != 0.25 || c.MaxNoteArea != 25 {
+		t.Errorf("incorrect limits: %+v", c)
+	}
+
+	if c.MaxWayNodes != 2000 || c.MaxRelationMembers != 32000 || c.MaxChangesetElements != 10000 {
+		t.Errorf("incorrect element limits: %+v", c)
+	}
+
+	if c.ChangesetsMaxQueryLimit != 100 || c.NotesMaxQueryLimit != 10000 {
+		t.Errorf("incorrect query limits: %+v", c)
+	}
+
+	if c.Timeout != 300*time.Second {
+		t.Errorf("incorrect timeout: %v", c.Timeout)
+	}
+
+	if c.APIStatus != "online" {
+		t.Errorf("incorrect status: %v", c.APIStatus)
+	}
+
+	if len(c.Policy.ImageryBlacklist) != 2 {
+		t.Fatalf("incorrect blacklist: %v", c.Policy.ImageryBlacklist)
+	}
+
+	if !c.Policy.ImageryBlacklisted("https://mt0.google.com/vt/lyrs=s") {
+		t.Errorf("should be blacklisted")
+	}
+
+	if c.Policy.ImageryBlacklisted("https://tile.openstreetmap.org/1/1/1.png") {
+		t.Errorf("should not be blacklisted")
+	}
+}
+
+func TestDatasource_Limits(t *testing.T) {
+	var calls int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Write([]byte(`<osm></osm>`))
+	}))
+	defer ts.Close()
+
+	ds := &Datasource{
+		BaseURL: ts.URL,
+		Client:  ts.Client(),
+		Limits: &Capabilities{
+			MaxArea:                 0.25,
+			MaxNoteArea:             1,
+			NotesMaxQueryLimit:      50,
+			ChangesetsMaxQueryLimit: 10,
+		},
+	}
+
+	ctx := context.Background()
+	_, err := ds.Map(ctx, &osm.Bounds{MinLat: 0, MaxLat: 1, MinLon: 0, MaxLon: 1})
+	if _, ok := err.(*LimitError); !ok {
+		t.Errorf("incorrect error: %v", err)
+	}
+
+	_, err = ds.NotesSearch(ctx, "q", Limit(100))
+	if _, ok := err.(*LimitError); !ok {
+		t.Errorf("incorrect error: %v", err)
+	}
+
+	if calls != 0 {
+		t.Errorf("should not make requests: %v", calls)
+	}
+
+	// tiled using the max note area
+	ds.Notes(ctx, &osm.Bounds{MinLat: 0, MaxLat: 2, MinLon: 0, MaxLon: 2})
+	if calls != 4 {
+		t.Errorf("incorrect number of tiles: %v", calls)
+	}
+
+	var url string
+	ts.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		url = r.URL.String()
+		w.Write([]byte(`<osm></osm>`))
+	})
+
+	ds.Changesets(ctx)
+	if url != "/changesets?limit=10" {
+		t.Errorf("should use the max query limit as the page size: %v", url)
+	}
+}
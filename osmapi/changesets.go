@@ -0,0 +1,223 @@
+package osmapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/paulmach/osm"
+)
+
+// changesetsPageSize is the maximum number of changesets
+// returned by one changesets query.
+const changesetsPageSize = 100
+
+// ChangesetsOption defines a valid option for the osmapi.Changesets query.
+type ChangesetsOption interface {
+	applyChangesets(*changesetsQuery) error
+}
+
+type changesetsQuery struct {
+	params     url.Values
+	start, end time.Time
+	limit      int
+}
+
+type changesetsOption func(*changesetsQuery) error
+
+func (o changesetsOption) applyChangesets(q *changesetsQuery) error {
+	return o(q)
+}
+
+// ChangesetsBounds finds the changesets that intersect the bounds.
+func ChangesetsBounds(b *osm.Bounds) ChangesetsOption {
+	return changesetsOption(func(q *changesetsQuery) error {
+		q.params.Set("bbox", fmt.Sprintf("%f,%f,%f,%f", b.MinLon, b.MinLat, b.MaxLon, b.MaxLat))
+		return nil
+	})
+}
+
+// ChangesetsUser finds the changesets of the user.
+func ChangesetsUser(id osm.UserID) ChangesetsOption {
+	return changesetsOption(func(q *changesetsQuery) error {
+		q.params.Set("user", strconv.FormatInt(int64(id), 10))
+		return nil
+	})
+}
+
+// ChangesetsDisplayName finds the changesets of the user with the display name.
+func ChangesetsDisplayName(name string) ChangesetsOption {
+	return changesetsOption(func(q *changesetsQuery) error {
+		q.params.Set("display_name", name)
+		return nil
+	})
+}
+
+// ChangesetsTime finds the changesets closed after the start and created
+// before the end. A zero end has no upper limit.
+func ChangesetsTime(start, end time.Time) ChangesetsOption {
+	return changesetsOption(func(q *changesetsQuery) error {
+		if !end.IsZero() && end.Before(start) {
+			return errors.New("osmapi: changesets end time must be after the start")
+		}
+
+		q.start, q.end = start, end
+		return nil
+	})
+}
+
+// ChangesetsOpen finds only the changesets that are still open.
+func ChangesetsOpen() ChangesetsOption {
+	return changesetsOption(func(q *changesetsQuery) error {
+		q.params.Set("open", "true")
+		return nil
+	})
+}
+
+// ChangesetsClosed finds only the changesets that are closed.
+func ChangesetsClosed() ChangesetsOption {
+	return changesetsOption(func(q *changesetsQuery) error {
+		q.params.Set("closed", "true")
+		return nil
+	})
+}
+
+// ChangesetsIDs finds the changesets with the ids.
+func ChangesetsIDs(ids ...osm.ChangesetID) ChangesetsOption {
+	return changesetsOption(func(q *changesetsQuery) error {
+		s := make([]string, len(ids))
+		for i, id := range ids {
+			s[i] = strconv.FormatInt(int64(id), 10)
+		}
+
+		q.params.Set("changesets", strings.Join(s, ","))
+		return nil
+	})
+}
+
+// ChangesetsLimit sets the maximum number of changesets to return.
+// By default all the matching changesets are returned.
+func ChangesetsLimit(n int) ChangesetsOption {
+	return changesetsOption(func(q *changesetsQuery) error {
+		if n < 1 {
+			return errors.New("osmapi: changesets limit must be positive")
+		}
+
+		q.limit = n
+		return nil
+	})
+}
+
+// Changesets returns the changesets matching the query options,
+// newest first. See the options or osm api v0.6 docs for details.
+// Delegates to the DefaultDatasource and uses its http.Client to make the request.
+func Changesets(ctx context.Context, opts ...ChangesetsOption) (osm.Changesets, error) {
+	return DefaultDatasource.Changesets(ctx, opts...)
+}
+
+// Changesets returns the changesets matching the query options,
+// newest first. See the options or osm api v0.6 docs for details.
+//
+// The api returns at most 100 changesets per request, or the
+// ChangesetsMaxQueryLimit of the datasource Limits. The next page is
+// requested with the end time set to the creation time of the oldest
+// changeset so far, until all the changesets, or the limit, are found.
+// More than 100 changesets created in the same second can not be paged
+// past, the query stops there.
+func (ds *Datasource) Changesets(ctx context.Context, opts ...ChangesetsOption) (osm.Changesets, error) {
+	q := &changesetsQuery{params: url.Values{}}
+	for _, o := range opts {
+		if err := o.applyChangesets(q); err != nil {
+			return nil, err
+		}
+	}
+
+	pageSize := changesetsPageSize
+	if ds.Limits != nil && ds.Limits.ChangesetsMaxQueryLimit > 0 {
+		pageSize = ds.Limits.ChangesetsMaxQueryLimit
+	}
+
+	var (
+		result osm.Changesets
+		seen   = make(map[osm.ChangesetID]bool)
+		end    = q.end
+
+		// the end time is inclusive so the changesets created at the end
+		// are returned again, the page must be large enough to skip them.
+		atEnd = 0
+	)
+
+	for {
+		size := pageSize
+		if q.limit > 0 && q.limit-len(result)+atEnd < size {
+			size = q.limit - len(result) + atEnd
+		}
+
+		o := &osm.OSM{}
+		if err := ds.getFromAPI(ctx, ds.changesetsURL(q, end, size), &o); err != nil {
+			return nil, err
+		}
+
+		added := 0
+		for _, cs := range o.Changesets {
+			if seen[cs.ID] {
+				continue
+			}
+
+			seen[cs.ID] = true
+			result = append(result, cs)
+			added++
+
+			switch {
+			case end.IsZero() || cs.CreatedAt.Before(end):
+				end = cs.CreatedAt
+				atEnd = 1
+			case cs.CreatedAt.Equal(end):
+				atEnd++
+			}
+		}
+
+		// a full page of changesets created in the same second
+		// can not be paged past.
+		if len(o.Changesets) < size || added == 0 ||
+			(q.limit > 0 && len(result) >= q.limit) {
+			break
+		}
+	}
+
+	if q.limit > 0 && len(result) > q.limit {
+		result = result[:q.limit]
+	}
+
+	return result, nil
+}
+
+func (ds *Datasource) changesetsURL(q *changesetsQuery, end time.Time, size int) string {
+	params := url.Values{}
+	for k, v := range q.params {
+		params[k] = v
+	}
+
+	if !q.start.IsZero() || !end.IsZero() {
+		t := formatTime(q.start)
+		if !end.IsZero() {
+			t += "," + formatTime(end)
+		}
+		params.Set("time", t)
+	}
+
+	// the api default is the max
+	if size != changesetsPageSize {
+		params.Set("limit", strconv.Itoa(size))
+	}
+
+	return fmt.Sprintf("%s/changesets?%s", ds.baseURL(), params.Encode())
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02T15:04:05Z")
+}
@@ -0,0 +1,145 @@
+package osmapi
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/paulmach/osm"
+)
+
+// changesetsServer mimics the changesets query returning the newest
+// changesets created before the end of the time parameter.
+func changesetsServer(t *testing.T, count int) (*httptest.Server, *[]string) {
+	start := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	var urls []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		urls = append(urls, r.URL.String())
+
+		end := start.Add(time.Hour)
+		if v := r.URL.Query().Get("time"); v != "" {
+			parts := strings.Split(v, ",")
+			if len(parts) == 2 {
+				end, _ = time.Parse(time.RFC3339, parts[1])
+			}
+		}
+
+		limit := 100
+		if v := r.URL.Query().Get("limit"); v != "" {
+			limit, _ = strconv.Atoi(v)
+		}
+
+		body := "<osm>"
+		n := 0
+		for id := count; id > 0 && n < limit; id-- {
+			// two changesets created every second
+			created := start.Add(time.Duration(id/2) * time.Second)
+			if created.After(end) {
+				continue
+			}
+
+			body += fmt.Sprintf(`<changeset id="%d" created_at="%s"></changeset>`,
+				id, created.Format(time.RFC3339))
+			n++
+		}
+
+		w.Write([]byte(body + "</osm>"))
+	}))
+
+	return ts, &urls
+}
+
+func TestDatasource_Changesets(t *testing.T) {
+	ts, urls := changesetsServer(t, 250)
+	defer ts.Close()
+
+	ds := &Datasource{BaseURL: ts.URL, Client: ts.Client()}
+	css, err := ds.Changesets(context.Background(), ChangesetsUser(1), ChangesetsClosed())
+	if err != nil {
+		t.Fatalf("request error: %v", err)
+	}
+
+	if len(css) != 250 {
+		t.Errorf("incorrect number of changesets: %v", len(css))
+	}
+
+	for i, cs := range css {
+		if cs.ID != osm.ChangesetID(250-i) {
+			t.Fatalf("incorrect changeset %d: %v", i, cs.ID)
+		}
+	}
+
+	if len(*urls) != 3 {
+		t.Errorf("incorrect number of requests: %v", len(*urls))
+	}
+
+	if u := (*urls)[0]; u != "/changesets?closed=true&user=1" {
+		t.Errorf("incorrect first url: %v", u)
+	}
+
+	if u := (*urls)[1]; u != "/changesets?closed=true&time=0001-01-01T00%3A00%3A00Z%2C2018-01-01T00%3A01%3A15Z&user=1" {
+		t.Errorf("incorrect second url: %v", u)
+	}
+}
+
+func TestDatasource_Changesets_limit(t *testing.T) {
+	ts, urls := changesetsServer(t, 250)
+	defer ts.Close()
+
+	ds := &Datasource{BaseURL: ts.URL, Client: ts.Client()}
+	css, err := ds.Changesets(context.Background(), ChangesetsLimit(150))
+	if err != nil {
+		t.Fatalf("request error: %v", err)
+	}
+
+	if len(css) != 150 {
+		t.Errorf("incorrect number of changesets: %v", len(css))
+	}
+
+	if u := (*urls)[1]; !strings.Contains(u, "limit=51") {
+		t.Errorf("should limit the last page: %v", u)
+	}
+
+	if _, err := ds.Changesets(context.Background(), ChangesetsLimit(0)); err == nil {
+		t.Errorf("should error for invalid limit")
+	}
+}
+
+func TestChangesetsOptions(t *testing.T) {
+	start := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	q := &changesetsQuery{params: map[string][]string{}}
+	opts := []ChangesetsOption{
+		ChangesetsBounds(&osm.Bounds{MinLat: 1, MaxLat: 2, MinLon: 3, MaxLon: 4}),
+		ChangesetsDisplayName("name"),
+		ChangesetsTime(start, time.Time{}),
+		ChangesetsOpen(),
+		ChangesetsIDs(1, 2),
+	}
+
+	for _, o := range opts {
+		if err := o.applyChangesets(q); err != nil {
+			t.Fatalf("option error: %v", err)
+		}
+	}
+
+	ds := &Datasource{BaseURL: "http://api"}
+	u := ds.changesetsURL(q, time.Time{}, 100)
+
+	expected := "http://api/changesets?bbox=3.000000%2C1.000000%2C4.000000%2C2.000000&" +
+		"changesets=1%2C2&display_name=name&open=true&time=2018-01-01T00%3A00%3A00Z"
+	if u != expected {
+		t.Errorf("incorrect url: %v", u)
+	}
+
+	err := ChangesetsTime(start, start.Add(-time.Hour)).applyChangesets(q)
+	if err == nil {
+		t.Errorf("should error if end is before start")
+	}
+}
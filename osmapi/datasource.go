@@ -6,9 +6,12 @@ import (
 	"encoding/xml"
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/paulmach/osm"
+	"github.com/paulmach/osm/metrics"
 )
 
 // BaseURL defines the api host. This can be change to hit
@@ -34,9 +37,29 @@ type Datasource struct {
 
 	BaseURL string
 	Client  *http.Client
+
+	// WebsiteURL is the host of the osm website used for the endpoints,
+	// like the changesets feed, that are not part of the api.
+	// Defaults to the WebsiteURL constant.
+	WebsiteURL string
+
+	// Metrics, if set, records the latency of the api calls,
+	// see the metrics package.
+	Metrics metrics.Recorder
+
+	// Logger, if set, receives warnings for failed requests with the
+	// status code and the reason from the api's Error header.
+	Logger osm.Logger
+
+	// Limits, if set, are checked before making requests, e.g. the area of
+	// the Map bounds, returning a *LimitError instead. The notes are tiled
+	// and the changesets paged using these limits. See GetCapabilities.
+	Limits *Capabilities
 }
 
 // DefaultDatasource is the Datasource used by package level convenience functions.
+// Its client does not set a user agent or wait when rate limited, set the Client
+// to one from osmhttp.NewClient for that.
 var DefaultDatasource = &Datasource{
 	BaseURL: BaseURL,
 	Client: &http.Client{
@@ -45,6 +68,7 @@ var DefaultDatasource = &Datasource{
 }
 
 var _ osm.HistoryDatasourcer = &Datasource{}
+var _ osm.ChangesetDatasourcer = &Datasource{}
 
 // NewDatasource creates a Datasource using the given client.
 func NewDatasource(client *http.Client) *Datasource {
@@ -53,16 +77,21 @@ func NewDatasource(client *http.Client) *Datasource {
 	}
 }
 
-func (ds *Datasource) getFromAPI(ctx context.Context, url string, item interface{}) error {
-	client := ds.Client
-	if client == nil {
-		client = DefaultDatasource.Client
+func (ds *Datasource) client() *http.Client {
+	if ds.Client != nil {
+		return ds.Client
 	}
 
-	if client == nil {
-		client = http.DefaultClient
+	if DefaultDatasource.Client != nil {
+		return DefaultDatasource.Client
 	}
 
+	return http.DefaultClient
+}
+
+func (ds *Datasource) getFromAPI(ctx context.Context, url string, item interface{}) error {
+	client := ds.client()
+
 	if ds.Limiter != nil {
 		err := ds.Limiter.Wait(ctx)
 		if err != nil {
@@ -75,11 +104,22 @@ func (ds *Datasource) getFromAPI(ctx context.Context, url string, item interface
 		return err
 	}
 
+	code := "error"
+	if ds.Metrics != nil {
+		defer func(start time.Time) {
+			ds.Metrics.Observe(metrics.APIRequestSeconds, time.Since(start).Seconds(),
+				metrics.Label{Name: "call", Value: apiCall(req.URL.Path)},
+				metrics.Label{Name: "code", Value: code},
+			)
+		}(time.Now())
+	}
+
 	resp, err := client.Do(req.WithContext(ctx))
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
+	code = strconv.Itoa(resp.StatusCode)
 
 	if resp.StatusCode == http.StatusNotFound {
 		return &NotFoundError{URL: url}
@@ -98,6 +138,11 @@ func (ds *Datasource) getFromAPI(ctx context.Context, url string, item interface
 	}
 
 	if resp.StatusCode != http.StatusOK {
+		if ds.Logger != nil {
+			ds.Logger.Warn("osmapi: unexpected status code",
+				"url", url, "code", resp.StatusCode, "error", resp.Header.Get("Error"))
+		}
+
 		return &UnexpectedStatusCodeError{
 			Code: resp.StatusCode,
 			URL:  url,
@@ -107,6 +152,21 @@ func (ds *Datasource) getFromAPI(ctx context.Context, url string, item interface
 	return xml.NewDecoder(resp.Body).Decode(item)
 }
 
+// apiCall returns the name of the call for the metrics, the first part
+// of the path after the version, e.g. node for /api/0.6/node/1/history.
+func apiCall(path string) string {
+	if i := strings.Index(path, "/0.6/"); i != -1 {
+		path = path[i+len("/0.6/"):]
+	}
+
+	path = strings.TrimPrefix(path, "/")
+	if i := strings.IndexAny(path, "/."); i != -1 {
+		path = path[:i]
+	}
+
+	return path
+}
+
 func (ds *Datasource) baseURL() string {
 	if ds.BaseURL != "" {
 		return ds.BaseURL
@@ -1,7 +1,11 @@
 package osmapi
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 )
 
@@ -24,3 +28,57 @@ func TestDatasourceNotFound(t *testing.T) {
 		t.Errorf("should be true for not found error")
 	}
 }
+
+func TestAPICall(t *testing.T) {
+	cases := []struct {
+		path string
+		call string
+	}{
+		{path: "/api/0.6/node/1", call: "node"},
+		{path: "/api/0.6/way/1/full", call: "way"},
+		{path: "/api/0.6/map", call: "map"},
+		{path: "/api/0.6/notes/search.json", call: "notes"},
+		{path: "/changesets", call: "changesets"},
+	}
+
+	for _, tc := range cases {
+		if v := apiCall(tc.path); v != tc.call {
+			t.Errorf("%s: incorrect call: %v != %v", tc.path, v, tc.call)
+		}
+	}
+}
+
+func TestDatasource_Logger(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Error", "Database offline for maintenance")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	logger := &testLogger{}
+	ds := &Datasource{BaseURL: ts.URL, Client: ts.Client(), Logger: logger}
+
+	_, err := ds.Node(context.Background(), 1)
+	if _, ok := err.(*UnexpectedStatusCodeError); !ok {
+		t.Fatalf("incorrect error: %v", err)
+	}
+
+	expected := fmt.Sprintf("osmapi: unexpected status code [url %s/node/1? code 503 error Database offline for maintenance]", ts.URL)
+	if len(logger.warnings) != 1 || logger.warnings[0] != expected {
+		t.Errorf("incorrect warnings: %v", logger.warnings)
+	}
+}
+
+type testLogger struct {
+	warnings []string
+}
+
+func (l *testLogger) Warn(msg string, keyvals ...interface{}) {
+	l.warnings = append(l.warnings, fmt.Sprintf("%s %v", msg, keyvals))
+}
+
+func TestDefaultDatasource(t *testing.T) {
+	if tr := DefaultDatasource.Client.Transport; tr != nil {
+		t.Errorf("should use the default transport: %T", tr)
+	}
+}
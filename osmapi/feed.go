@@ -0,0 +1,309 @@
+package osmapi
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/paulmach/osm"
+)
+
+// WebsiteURL is the host of the osm website. The changesets feed is served
+// by the website and not the api. This can be changed using the WebsiteURL
+// field on the Datasource.
+const WebsiteURL = "https://www.openstreetmap.org"
+
+// NoteFeedAction is the kind of note event in the notes feed.
+type NoteFeedAction string
+
+// The note feed actions, based on the item title.
+const (
+	NoteFeedOpened    NoteFeedAction = "opened"
+	NoteFeedCommented NoteFeedAction = "commented"
+	NoteFeedClosed    NoteFeedAction = "closed"
+	NoteFeedReopened  NoteFeedAction = "reopened"
+	NoteFeedHidden    NoteFeedAction = "hidden"
+	NoteFeedUnknown   NoteFeedAction = ""
+)
+
+// NoteFeedItem is an event from the notes rss feed.
+type NoteFeedItem struct {
+	NoteID      osm.NoteID
+	Action      NoteFeedAction
+	Title       string
+	Link        string
+	Description string // html
+	User        string
+	Published   time.Time
+	Lat         float64
+	Lon         float64
+}
+
+// NotesFeed returns the recent note events in the bounding box from the
+// notes rss feed. It is a lightweight alternative to polling the notes api.
+// Delegates to the DefaultDatasource and uses its http.Client to make the request.
+func NotesFeed(ctx context.Context, bounds *osm.Bounds) ([]*NoteFeedItem, error) {
+	return DefaultDatasource.NotesFeed(ctx, bounds)
+}
+
+// NotesFeed returns the recent note events in the bounding box from the
+// notes rss feed. It is a lightweight alternative to polling the notes api.
+func (ds *Datasource) NotesFeed(ctx context.Context, bounds *osm.Bounds) ([]*NoteFeedItem, error) {
+	url := fmt.Sprintf("%s/notes/feed?bbox=%f,%f,%f,%f", ds.baseURL(),
+		bounds.MinLon, bounds.MinLat,
+		bounds.MaxLon, bounds.MaxLat)
+
+	feed := &rssFeed{}
+	if err := ds.getFromAPI(ctx, url, feed); err != nil {
+		return nil, err
+	}
+
+	result := make([]*NoteFeedItem, 0, len(feed.Items))
+	for _, item := range feed.Items {
+		i, err := item.noteFeedItem()
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, i)
+	}
+
+	return result, nil
+}
+
+// ChangesetFeedEntry is a changeset from the changesets atom feed.
+type ChangesetFeedEntry struct {
+	ChangesetID osm.ChangesetID
+	Title       string
+	Comment     string
+	Link        string
+	Content     string // html
+	User        string
+	Published   time.Time
+	Updated     time.Time
+
+	// Bounds will be nil for changesets without any changes.
+	Bounds *osm.Bounds
+}
+
+// ChangesetsFeed returns the recent changesets in the bounding box from
+// the website changesets (history) atom feed.
+// Delegates to the DefaultDatasource and uses its http.Client to make the request.
+func ChangesetsFeed(ctx context.Context, bounds *osm.Bounds) ([]*ChangesetFeedEntry, error) {
+	return DefaultDatasource.ChangesetsFeed(ctx, bounds)
+}
+
+// ChangesetsFeed returns the recent changesets in the bounding box from
+// the website changesets (history) atom feed.
+func (ds *Datasource) ChangesetsFeed(ctx context.Context, bounds *osm.Bounds) ([]*ChangesetFeedEntry, error) {
+	url := fmt.Sprintf("%s/history/feed?bbox=%f,%f,%f,%f", ds.websiteURL(),
+		bounds.MinLon, bounds.MinLat,
+		bounds.MaxLon, bounds.MaxLat)
+
+	feed := &atomFeed{}
+	if err := ds.getFromAPI(ctx, url, feed); err != nil {
+		return nil, err
+	}
+
+	result := make([]*ChangesetFeedEntry, 0, len(feed.Entries))
+	for _, entry := range feed.Entries {
+		e, err := entry.changesetFeedEntry()
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, e)
+	}
+
+	return result, nil
+}
+
+func (ds *Datasource) websiteURL() string {
+	if ds.WebsiteURL != "" {
+		return ds.WebsiteURL
+	}
+
+	return WebsiteURL
+}
+
+type rssFeed struct {
+	Items []*rssItem `xml:"channel>item"`
+}
+
+type rssItem struct {
+	Title       string `xml:"title"`
+	Link        string `xml:"link"`
+	Description string `xml:"description"`
+	Creator     string `xml:"http://purl.org/dc/elements/1.1/ creator"`
+	PubDate     string `xml:"pubDate"`
+	Lat         string `xml:"http://www.w3.org/2003/01/geo/wgs84_pos# lat"`
+	Lon         string `xml:"http://www.w3.org/2003/01/geo/wgs84_pos# long"`
+}
+
+func (item *rssItem) noteFeedItem() (*NoteFeedItem, error) {
+	i := &NoteFeedItem{
+		Title:       item.Title,
+		Link:        item.Link,
+		Description: item.Description,
+		User:        item.Creator,
+		Action:      noteFeedAction(item.Title),
+	}
+
+	id, err := idFromLink(item.Link, "/note/")
+	if err != nil {
+		return nil, err
+	}
+	i.NoteID = osm.NoteID(id)
+
+	if item.PubDate != "" {
+		i.Published, err = time.Parse(time.RFC1123Z, item.PubDate)
+		if err != nil {
+			return nil, fmt.Errorf("osmapi: invalid feed date: %v", err)
+		}
+		i.Published = i.Published.UTC()
+	}
+
+	if item.Lat != "" || item.Lon != "" {
+		i.Lat, err = strconv.ParseFloat(item.Lat, 64)
+		if err != nil {
+			return nil, fmt.Errorf("osmapi: invalid feed lat: %v", err)
+		}
+
+		i.Lon, err = strconv.ParseFloat(item.Lon, 64)
+		if err != nil {
+			return nil, fmt.Errorf("osmapi: invalid feed lon: %v", err)
+		}
+	}
+
+	return i, nil
+}
+
+func noteFeedAction(title string) NoteFeedAction {
+	title = strings.ToLower(title)
+	switch {
+	case strings.HasPrefix(title, "new note"):
+		return NoteFeedOpened
+	case strings.HasPrefix(title, "new comment"):
+		return NoteFeedCommented
+	case strings.HasPrefix(title, "closed note"):
+		return NoteFeedClosed
+	case strings.HasPrefix(title, "reactivated note"),
+		strings.HasPrefix(title, "reopened note"):
+		return NoteFeedReopened
+	case strings.HasPrefix(title, "hidden note"):
+		return NoteFeedHidden
+	}
+
+	return NoteFeedUnknown
+}
+
+type atomFeed struct {
+	Entries []*atomEntry `xml:"entry"`
+}
+
+type atomEntry struct {
+	ID        string `xml:"id"`
+	Title     string `xml:"title"`
+	Published string `xml:"published"`
+	Updated   string `xml:"updated"`
+	Links     []struct {
+		Rel  string `xml:"rel,attr"`
+		Type string `xml:"type,attr"`
+		Href string `xml:"href,attr"`
+	} `xml:"link"`
+	Content string `xml:"content"`
+	Author  string `xml:"author>name"`
+	Box     string `xml:"http://www.georss.org/georss box"`
+}
+
+func (entry *atomEntry) changesetFeedEntry() (*ChangesetFeedEntry, error) {
+	e := &ChangesetFeedEntry{
+		Title:   entry.Title,
+		Content: entry.Content,
+		User:    entry.Author,
+	}
+
+	for _, l := range entry.Links {
+		if l.Type == "text/html" || (e.Link == "" && l.Type == "") {
+			e.Link = l.Href
+		}
+	}
+
+	link := e.Link
+	if link == "" {
+		link = entry.ID
+	}
+
+	id, err := idFromLink(link, "/changeset/")
+	if err != nil {
+		return nil, err
+	}
+	e.ChangesetID = osm.ChangesetID(id)
+
+	// titles look like "Changeset 123 - the comment"
+	if i := strings.Index(entry.Title, " - "); i >= 0 {
+		e.Comment = entry.Title[i+3:]
+	}
+
+	if entry.Published != "" {
+		e.Published, err = time.Parse(time.RFC3339, entry.Published)
+		if err != nil {
+			return nil, fmt.Errorf("osmapi: invalid feed date: %v", err)
+		}
+		e.Published = e.Published.UTC()
+	}
+
+	if entry.Updated != "" {
+		e.Updated, err = time.Parse(time.RFC3339, entry.Updated)
+		if err != nil {
+			return nil, fmt.Errorf("osmapi: invalid feed date: %v", err)
+		}
+		e.Updated = e.Updated.UTC()
+	}
+
+	if entry.Box != "" {
+		// georss box is "minlat minlon maxlat maxlon"
+		parts := strings.Fields(entry.Box)
+		if len(parts) != 4 {
+			return nil, fmt.Errorf("osmapi: invalid feed box: %s", entry.Box)
+		}
+
+		var vals [4]float64
+		for i, p := range parts {
+			vals[i], err = strconv.ParseFloat(p, 64)
+			if err != nil {
+				return nil, fmt.Errorf("osmapi: invalid feed box: %v", err)
+			}
+		}
+
+		e.Bounds = &osm.Bounds{
+			MinLat: vals[0], MinLon: vals[1],
+			MaxLat: vals[2], MaxLon: vals[3],
+		}
+	}
+
+	return e, nil
+}
+
+// idFromLink returns the id after the prefix in links like
+// https://www.openstreetmap.org/note/123#c456.
+func idFromLink(link, prefix string) (int64, error) {
+	i := strings.LastIndex(link, prefix)
+	if i == -1 {
+		return 0, fmt.Errorf("osmapi: no id in feed link: %s", link)
+	}
+
+	s := link[i+len(prefix):]
+	if j := strings.IndexAny(s, "/#?"); j >= 0 {
+		s = s[:j]
+	}
+
+	id, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("osmapi: no id in feed link: %s", link)
+	}
+
+	return id, nil
+}
@@ -0,0 +1,199 @@
+package osmapi
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/paulmach/osm"
+)
+
+const notesFeed = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0" xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:geo="http://www.w3.org/2003/01/geo/wgs84_pos#" xmlns:georss="http://www.georss.org/georss">
+  <channel>
+    <title>OpenStreetMap Notes</title>
+    <item>
+      <title>new note (near Greenwich, London)</title>
+      <link>https://www.openstreetmap.org/note/4012345</link>
+      <guid>https://api.openstreetmap.org/api/0.6/notes/4012345</guid>
+      <description>&lt;p&gt;Missing cafe&lt;/p&gt;</description>
+      <dc:creator>mapper</dc:creator>
+      <pubDate>Wed, 10 Jan 2024 10:11:12 +0000</pubDate>
+      <geo:lat>51.4778</geo:lat>
+      <geo:long>-0.0015</geo:long>
+      <georss:point>51.4778 -0.0015</georss:point>
+    </item>
+    <item>
+      <title>new comment (near Greenwich, London)</title>
+      <link>https://www.openstreetmap.org/note/4012346#c7012345</link>
+      <description>&lt;p&gt;Added&lt;/p&gt;</description>
+      <pubDate>Wed, 10 Jan 2024 11:00:00 +0100</pubDate>
+      <geo:lat>51.4</geo:lat>
+      <geo:long>-0.1</geo:long>
+    </item>
+    <item>
+      <title>closed note (near Greenwich, London)</title>
+      <link>https://www.openstreetmap.org/note/4012347</link>
+    </item>
+  </channel>
+</rss>`
+
+const changesetsFeed = `<?xml version="1.0" encoding="UTF-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom" xmlns:georss="http://www.georss.org/georss">
+  <title>OpenStreetMap changesets</title>
+  <entry>
+    <id>https://www.openstreetmap.org/changeset/145000001</id>
+    <published>2024-01-10T10:00:00Z</published>
+    <updated>2024-01-10T10:05:00Z</updated>
+    <link rel="alternate" type="text/html" href="https://www.openstreetmap.org/changeset/145000001"/>
+    <link rel="alternate" type="application/osm+xml" href="https://www.openstreetmap.org/api/0.6/changeset/145000001"/>
+    <title>Changeset 145000001 - Add cafe - with details</title>
+    <content type="html">&lt;table&gt;&lt;/table&gt;</content>
+    <author><name>mapper</name></author>
+    <georss:box>51.1 -0.2 51.2 -0.1</georss:box>
+  </entry>
+  <entry>
+    <id>https://www.openstreetmap.org/changeset/145000002</id>
+    <published>2024-01-10T11:00:00Z</published>
+    <updated>2024-01-10T11:00:00Z</updated>
+    <title>Changeset 145000002</title>
+    <author><name>other</name></author>
+  </entry>
+</feed>`
+
+func TestNotesFeed(t *testing.T) {
+	ctx := context.Background()
+
+	url := ""
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		url = r.URL.String()
+		w.Write([]byte(notesFeed))
+	}))
+	defer ts.Close()
+
+	ds := &Datasource{BaseURL: ts.URL}
+	items, err := ds.NotesFeed(ctx, &osm.Bounds{MinLon: 1, MinLat: 2, MaxLon: 3, MaxLat: 4})
+	if err != nil {
+		t.Fatalf("feed error: %v", err)
+	}
+
+	if !strings.Contains(url, "notes/feed?bbox=1.000000,2.000000,3.000000,4.000000") {
+		t.Errorf("incorrect path: %v", url)
+	}
+
+	if l := len(items); l != 3 {
+		t.Fatalf("incorrect number of items: %v", l)
+	}
+
+	i := items[0]
+	if i.NoteID != 4012345 || i.Action != NoteFeedOpened || i.User != "mapper" {
+		t.Errorf("incorrect item: %+v", i)
+	}
+
+	if i.Lat != 51.4778 || i.Lon != -0.0015 || i.Description != "<p>Missing cafe</p>" {
+		t.Errorf("incorrect item: %+v", i)
+	}
+
+	if !i.Published.Equal(time.Date(2024, 1, 10, 10, 11, 12, 0, time.UTC)) {
+		t.Errorf("incorrect published: %v", i.Published)
+	}
+
+	i = items[1]
+	if i.NoteID != 4012346 || i.Action != NoteFeedCommented {
+		t.Errorf("incorrect item: %+v", i)
+	}
+
+	if !i.Published.Equal(time.Date(2024, 1, 10, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("incorrect published: %v", i.Published)
+	}
+
+	i = items[2]
+	if i.NoteID != 4012347 || i.Action != NoteFeedClosed || !i.Published.IsZero() {
+		t.Errorf("incorrect item: %+v", i)
+	}
+}
+
+func TestChangesetsFeed(t *testing.T) {
+	ctx := context.Background()
+
+	url := ""
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		url = r.URL.String()
+		w.Write([]byte(changesetsFeed))
+	}))
+	defer ts.Close()
+
+	ds := &Datasource{WebsiteURL: ts.URL}
+	entries, err := ds.ChangesetsFeed(ctx, &osm.Bounds{MinLon: 1, MinLat: 2, MaxLon: 3, MaxLat: 4})
+	if err != nil {
+		t.Fatalf("feed error: %v", err)
+	}
+
+	if !strings.Contains(url, "history/feed?bbox=1.000000,2.000000,3.000000,4.000000") {
+		t.Errorf("incorrect path: %v", url)
+	}
+
+	if l := len(entries); l != 2 {
+		t.Fatalf("incorrect number of entries: %v", l)
+	}
+
+	e := entries[0]
+	if e.ChangesetID != 145000001 || e.User != "mapper" || e.Comment != "Add cafe - with details" {
+		t.Errorf("incorrect entry: %+v", e)
+	}
+
+	if e.Link != "https://www.openstreetmap.org/changeset/145000001" {
+		t.Errorf("incorrect link: %v", e.Link)
+	}
+
+	expected := &osm.Bounds{MinLat: 51.1, MinLon: -0.2, MaxLat: 51.2, MaxLon: -0.1}
+	if *e.Bounds != *expected {
+		t.Errorf("incorrect bounds: %+v", e.Bounds)
+	}
+
+	if !e.Updated.Equal(time.Date(2024, 1, 10, 10, 5, 0, 0, time.UTC)) {
+		t.Errorf("incorrect updated: %v", e.Updated)
+	}
+
+	e = entries[1]
+	if e.ChangesetID != 145000002 || e.Comment != "" || e.Bounds != nil {
+		t.Errorf("incorrect entry: %+v", e)
+	}
+}
+
+func TestIDFromLink(t *testing.T) {
+	cases := []struct {
+		link string
+		id   int64
+		err  bool
+	}{
+		{link: "https://www.openstreetmap.org/note/123", id: 123},
+		{link: "https://www.openstreetmap.org/note/123#c456", id: 123},
+		{link: "https://www.openstreetmap.org/note/123/comment", id: 123},
+		{link: "https://www.openstreetmap.org/way/123", err: true},
+		{link: "https://www.openstreetmap.org/note/abc", err: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.link, func(t *testing.T) {
+			id, err := idFromLink(tc.link, "/note/")
+			if tc.err {
+				if err == nil {
+					t.Errorf("expected error")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if id != tc.id {
+				t.Errorf("incorrect id: %v", id)
+			}
+		})
+	}
+}
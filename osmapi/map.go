@@ -20,6 +20,12 @@ func (ds *Datasource) Map(ctx context.Context, bounds *osm.Bounds, opts ...Featu
 		return nil, err
 	}
 
+	if ds.Limits != nil {
+		if err := checkArea("map area", bounds, ds.Limits.MaxArea); err != nil {
+			return nil, err
+		}
+	}
+
 	url := fmt.Sprintf("%s/map?bbox=%f,%f,%f,%f&%s", ds.baseURL(),
 		bounds.MinLon, bounds.MinLat,
 		bounds.MaxLon, bounds.MaxLat,
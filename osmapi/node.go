@@ -3,7 +3,6 @@ package osmapi
 import (
 	"context"
 	"fmt"
-	"strconv"
 
 	"github.com/paulmach/osm"
 )
@@ -41,31 +40,21 @@ func Nodes(ctx context.Context, ids []osm.NodeID, opts ...FeatureOption) (osm.No
 }
 
 // Nodes returns the latest version of the nodes from the osm rest api.
-// Will return 404 if any node is missing.
+// The ids are split into multiple requests if they do not fit in one url.
+// If some nodes are missing or deleted the others are returned
+// with a *PartialError listing them.
 func (ds *Datasource) Nodes(ctx context.Context, ids []osm.NodeID, opts ...FeatureOption) (osm.Nodes, error) {
-	params, err := featureOptions(opts)
-	if err != nil {
-		return nil, err
-	}
-
-	data := make([]byte, 0, 11*len(ids))
+	refs := make([]int64, len(ids))
 	for i, id := range ids {
-		if i != 0 {
-			data = append(data, byte(','))
-		}
-		data = strconv.AppendInt(data, int64(id), 10)
-	}
-	url := ds.baseURL() + "/nodes?nodes=" + string(data)
-	if len(params) > 0 {
-		url += "&" + params
+		refs[i] = int64(id)
 	}
 
-	o := &osm.OSM{}
-	if err := ds.getFromAPI(ctx, url, &o); err != nil {
+	o, err := ds.getMany(ctx, osm.TypeNode, refs, opts)
+	if o == nil {
 		return nil, err
 	}
 
-	return o.Nodes, nil
+	return o.Nodes, err
 }
 
 // NodeVersion returns the specific version of the node from the osm rest api.
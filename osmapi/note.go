@@ -3,8 +3,10 @@ package osmapi
 import (
 	"context"
 	"fmt"
+	"math"
 	"net/url"
 	"strings"
+	"time"
 
 	"github.com/paulmach/osm"
 )
@@ -41,30 +43,89 @@ func Notes(ctx context.Context, bounds *osm.Bounds, opts ...NotesOption) (osm.No
 var _ NotesOption = Limit(1)
 var _ NotesOption = MaxDaysClosed(1)
 
+// maxNotesArea is the largest bounding box, in square degrees,
+// the api allows for the notes by bounding box request.
+const maxNotesArea = 25
+
 // Notes returns the notes in a bounding box. Can provide options to limit the results
 // or change what it means to be "closed". See the options or osm api v0.6 docs for details.
+// Bounds larger than the api limit of 25 square degrees, or the MaxNoteArea
+// of the datasource Limits, are split into tiles,
+// the limit applies to each tile, and notes in more than one tile are only
+// returned once.
 func (ds *Datasource) Notes(ctx context.Context, bounds *osm.Bounds, opts ...NotesOption) (osm.Notes, error) {
-	params := make([]string, 0, 1+len(opts))
-	params = append(params, fmt.Sprintf("bbox=%f,%f,%f,%f",
-		bounds.MinLon, bounds.MinLat,
-		bounds.MaxLon, bounds.MaxLat))
+	var params []string
 
 	var err error
 	for _, o := range opts {
+		if err := ds.checkNotesLimit(o); err != nil {
+			return nil, err
+		}
+
 		params, err = o.applyNotes(params)
 		if err != nil {
 			return nil, err
 		}
 	}
 
-	url := fmt.Sprintf("%s/notes?%s", ds.baseURL(), strings.Join(params, "&"))
+	maxArea := float64(maxNotesArea)
+	if ds.Limits != nil && ds.Limits.MaxNoteArea > 0 {
+		maxArea = ds.Limits.MaxNoteArea
+	}
 
-	o := &osm.OSM{}
-	if err := ds.getFromAPI(ctx, url, &o); err != nil {
-		return nil, err
+	var (
+		result osm.Notes
+		seen   = make(map[osm.NoteID]bool)
+	)
+
+	for _, b := range notesTiles(bounds, maxArea) {
+		p := append([]string{fmt.Sprintf("bbox=%f,%f,%f,%f",
+			b.MinLon, b.MinLat,
+			b.MaxLon, b.MaxLat)}, params...)
+
+		url := fmt.Sprintf("%s/notes?%s", ds.baseURL(), strings.Join(p, "&"))
+
+		o := &osm.OSM{}
+		if err := ds.getFromAPI(ctx, url, &o); err != nil {
+			return nil, err
+		}
+
+		for _, n := range o.Notes {
+			if !seen[n.ID] {
+				seen[n.ID] = true
+				result = append(result, n)
+			}
+		}
 	}
 
-	return o.Notes, nil
+	return result, nil
+}
+
+// notesTiles splits the bounds into tiles smaller than the max area.
+func notesTiles(b *osm.Bounds, maxArea float64) []*osm.Bounds {
+	width := b.MaxLon - b.MinLon
+	height := b.MaxLat - b.MinLat
+	if width*height <= maxArea {
+		return []*osm.Bounds{b}
+	}
+
+	size := math.Sqrt(maxArea)
+	rows := int(math.Ceil(height / size))
+	cols := int(math.Ceil(width / size))
+
+	tiles := make([]*osm.Bounds, 0, rows*cols)
+	for r := 0; r < rows; r++ {
+		for c := 0; c < cols; c++ {
+			tiles = append(tiles, &osm.Bounds{
+				MinLat: b.MinLat + float64(r)*size,
+				MaxLat: math.Min(b.MinLat+float64(r+1)*size, b.MaxLat),
+				MinLon: b.MinLon + float64(c)*size,
+				MaxLon: math.Min(b.MinLon+float64(c+1)*size, b.MaxLon),
+			})
+		}
+	}
+
+	return tiles
 }
 
 // NotesSearch returns the notes in a bounding box whose text matches the query.
@@ -77,19 +138,92 @@ func NotesSearch(ctx context.Context, query string, opts ...NotesOption) (osm.No
 
 // NotesSearch returns the notes whose text matches the query.
 // Can provide options to limit the results or change what it means to be "closed".
-// See the options or osm api v0.6 docs for details.
+// See the options or osm api v0.6 docs for details. With the AllNotes option
+// all the matching notes are returned, requested page by page.
 func (ds *Datasource) NotesSearch(ctx context.Context, query string, opts ...NotesOption) (osm.Notes, error) {
 	params := make([]string, 0, 1+len(opts))
 	params = append(params, fmt.Sprintf("q=%s", url.QueryEscape(query)))
 
-	var err error
+	var (
+		all      bool
+		pageSize = 100
+		to       time.Time
+		err      error
+	)
+
 	for _, o := range opts {
+		if err := ds.checkNotesLimit(o); err != nil {
+			return nil, err
+		}
+
+		switch o := o.(type) {
+		case *allNotes:
+			all = true
+		case *limit:
+			pageSize = o.n
+		case *notesTo:
+			to = o.t
+		}
+
 		params, err = o.applyNotes(params)
 		if err != nil {
 			return nil, err
 		}
 	}
 
+	if !all {
+		return ds.notesSearch(ctx, params)
+	}
+
+	// page by the creation time, the to time is replaced for each page.
+	var base []string
+	for _, p := range params {
+		if !strings.HasPrefix(p, "to=") {
+			base = append(base, p)
+		}
+	}
+	base = append(base, "sort=created_at", "order=newest")
+
+	var (
+		result osm.Notes
+		seen   = make(map[osm.NoteID]bool)
+	)
+
+	for {
+		params := base
+		if !to.IsZero() {
+			params = append(params[:len(params):len(params)], "to="+url.QueryEscape(formatTime(to)))
+		}
+
+		notes, err := ds.notesSearch(ctx, params)
+		if err != nil {
+			return nil, err
+		}
+
+		// the to time is inclusive so the oldest notes
+		// of the last page are returned again.
+		added := 0
+		for _, n := range notes {
+			if seen[n.ID] {
+				continue
+			}
+
+			seen[n.ID] = true
+			result = append(result, n)
+			added++
+
+			if to.IsZero() || n.DateCreated.Before(to) {
+				to = n.DateCreated.Time
+			}
+		}
+
+		if len(notes) < pageSize || added == 0 {
+			return result, nil
+		}
+	}
+}
+
+func (ds *Datasource) notesSearch(ctx context.Context, params []string) (osm.Notes, error) {
 	url := fmt.Sprintf("%s/notes/search?%s", ds.baseURL(), strings.Join(params, "&"))
 
 	o := &osm.OSM{}
@@ -99,3 +233,21 @@ func (ds *Datasource) NotesSearch(ctx context.Context, query string, opts ...Not
 
 	return o.Notes, nil
 }
+
+// checkNotesLimit checks the limit option against the datasource Limits.
+func (ds *Datasource) checkNotesLimit(o NotesOption) error {
+	l, ok := o.(*limit)
+	if !ok || ds.Limits == nil || ds.Limits.NotesMaxQueryLimit == 0 {
+		return nil
+	}
+
+	if l.n > ds.Limits.NotesMaxQueryLimit {
+		return &LimitError{
+			Limit: "notes limit",
+			Value: float64(l.n),
+			Max:   float64(ds.Limits.NotesMaxQueryLimit),
+		}
+	}
+
+	return nil
+}
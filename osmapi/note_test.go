@@ -2,10 +2,13 @@ package osmapi
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/paulmach/osm"
 )
@@ -61,3 +64,119 @@ func TestNote_urls(t *testing.T) {
 		}
 	})
 }
+
+func TestDatasource_Notes_tiles(t *testing.T) {
+	var urls []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		urls = append(urls, r.URL.String())
+
+		// every tile has note 1 and its own note
+		fmt.Fprintf(w, `<osm><note><id>1</id></note><note><id>%d</id></note></osm>`, len(urls)+1)
+	}))
+	defer ts.Close()
+
+	ds := &Datasource{BaseURL: ts.URL, Client: ts.Client()}
+	notes, err := ds.Notes(context.Background(),
+		&osm.Bounds{MinLon: 0, MinLat: 0, MaxLon: 10, MaxLat: 6}, Limit(10))
+	if err != nil {
+		t.Fatalf("request error: %v", err)
+	}
+
+	if len(urls) != 4 {
+		t.Errorf("incorrect number of requests: %v", urls)
+	}
+
+	if urls[3] != "/notes?bbox=5.000000,5.000000,10.000000,6.000000&limit=10" {
+		t.Errorf("incorrect url: %v", urls[3])
+	}
+
+	var ids []osm.NoteID
+	for _, n := range notes {
+		ids = append(ids, n.ID)
+	}
+
+	if !reflect.DeepEqual(ids, []osm.NoteID{1, 2, 3, 4, 5}) {
+		t.Errorf("incorrect notes: %v", ids)
+	}
+}
+
+func TestDatasource_NotesSearch_all(t *testing.T) {
+	start := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	var urls []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		urls = append(urls, r.URL.String())
+
+		to := start.Add(time.Hour)
+		if v := r.URL.Query().Get("to"); v != "" {
+			to, _ = time.Parse(time.RFC3339, v)
+		}
+
+		body := "<osm>"
+		n := 0
+		for id := 25; id > 0 && n < 10; id-- {
+			// two notes created every second
+			created := start.Add(time.Duration(id/2) * time.Second)
+			if created.After(to) {
+				continue
+			}
+
+			body += fmt.Sprintf(`<note><id>%d</id><date_created>%s</date_created></note>`,
+				id, created.Format("2006-01-02 15:04:05 MST"))
+			n++
+		}
+
+		w.Write([]byte(body + "</osm>"))
+	}))
+	defer ts.Close()
+
+	ds := &Datasource{BaseURL: ts.URL, Client: ts.Client()}
+	notes, err := ds.NotesSearch(context.Background(), "q", Limit(10), AllNotes())
+	if err != nil {
+		t.Fatalf("request error: %v", err)
+	}
+
+	if len(notes) != 25 {
+		t.Errorf("incorrect number of notes: %v", len(notes))
+	}
+
+	if urls[0] != "/notes/search?q=q&limit=10&sort=created_at&order=newest" {
+		t.Errorf("incorrect first url: %v", urls[0])
+	}
+
+	if urls[1] != "/notes/search?q=q&limit=10&sort=created_at&order=newest&to=2018-01-01T00%3A00%3A08Z" {
+		t.Errorf("incorrect second url: %v", urls[1])
+	}
+}
+
+func TestNotesOptions(t *testing.T) {
+	start := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	var params []string
+	opts := []NotesOption{
+		NotesBounds(&osm.Bounds{MinLon: 1, MinLat: 2, MaxLon: 3, MaxLat: 4}),
+		NotesUser(5),
+		NotesDisplayName("a b"),
+		NotesFrom(start),
+		NotesTo(start.Add(time.Hour)),
+	}
+
+	for _, o := range opts {
+		var err error
+		params, err = o.applyNotes(params)
+		if err != nil {
+			t.Fatalf("option error: %v", err)
+		}
+	}
+
+	expected := []string{
+		"bbox=1.000000,2.000000,3.000000,4.000000",
+		"user=5",
+		"display_name=a+b",
+		"from=2018-01-01T00%3A00%3A00Z",
+		"to=2018-01-01T01%3A00%3A00Z",
+	}
+	if !reflect.DeepEqual(params, expected) {
+		t.Errorf("incorrect params: %v", params)
+	}
+}
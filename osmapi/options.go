@@ -3,8 +3,11 @@ package osmapi
 import (
 	"errors"
 	"fmt"
+	"net/url"
 	"strings"
 	"time"
+
+	"github.com/paulmach/osm"
 )
 
 // FeatureOption can be used when fetching a feature or a set of different features.
@@ -44,6 +47,41 @@ func MaxDaysClosed(num int) NotesOption {
 	return &maxDaysClosed{num}
 }
 
+// NotesBounds limits the notes search to the bounding box.
+func NotesBounds(b *osm.Bounds) NotesOption {
+	return &notesParam{fmt.Sprintf("bbox=%f,%f,%f,%f", b.MinLon, b.MinLat, b.MaxLon, b.MaxLat)}
+}
+
+// NotesUser limits the notes search to the notes the user interacted with.
+func NotesUser(id osm.UserID) NotesOption {
+	return &notesParam{fmt.Sprintf("user=%d", id)}
+}
+
+// NotesDisplayName limits the notes search to the notes the user with
+// the display name interacted with.
+func NotesDisplayName(name string) NotesOption {
+	return &notesParam{"display_name=" + url.QueryEscape(name)}
+}
+
+// NotesFrom limits the notes search to the notes created at or after the time.
+func NotesFrom(t time.Time) NotesOption {
+	return &notesParam{"from=" + url.QueryEscape(formatTime(t))}
+}
+
+// NotesTo limits the notes search to the notes created at or before the time.
+func NotesTo(t time.Time) NotesOption {
+	return &notesTo{t}
+}
+
+// AllNotes returns all the matching notes of the notes search, the limit
+// is the size of each page. The pages are requested newest first, moving
+// the end time back to the creation time of the oldest note so far.
+// More notes created in the same second than fit in a page can not be
+// paged past, the search stops there.
+func AllNotes() NotesOption {
+	return &allNotes{}
+}
+
 type limit struct{ n int }
 
 func (o *limit) applyNotes(p []string) ([]string, error) {
@@ -59,6 +97,24 @@ func (o *maxDaysClosed) applyNotes(p []string) ([]string, error) {
 	return append(p, fmt.Sprintf("closed=%d", o.n)), nil
 }
 
+type notesParam struct{ p string }
+
+func (o *notesParam) applyNotes(p []string) ([]string, error) {
+	return append(p, o.p), nil
+}
+
+type notesTo struct{ t time.Time }
+
+func (o *notesTo) applyNotes(p []string) ([]string, error) {
+	return append(p, "to="+url.QueryEscape(formatTime(o.t))), nil
+}
+
+type allNotes struct{}
+
+func (o *allNotes) applyNotes(p []string) ([]string, error) {
+	return p, nil
+}
+
 func featureOptions(opts []FeatureOption) (string, error) {
 	if len(opts) == 0 {
 		return "", nil
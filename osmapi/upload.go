@@ -0,0 +1,317 @@
+package osmapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/paulmach/osm"
+	"github.com/paulmach/osm/metrics"
+)
+
+// maxUploadRetries is the number of times an upload is sent again
+// after it was found to not have been applied.
+const maxUploadRetries = 3
+
+// ConflictError is returned for 409 from the api, e.g. a version
+// mismatch when uploading a change or the changeset is closed.
+type ConflictError struct {
+	URL     string
+	Message string
+}
+
+// Error returns an error message with the reason from the api.
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("osmapi: conflict at %s: %s", e.URL, e.Message)
+}
+
+// UploadUncertainError is returned when an upload failed without a
+// response, e.g. it timed out, and checking the changeset could not
+// determine whether it was applied. The upload should not be sent
+// again without checking the changeset.
+type UploadUncertainError struct {
+	ChangesetID osm.ChangesetID
+	Err         error
+}
+
+// Error returns an error message with the upload error.
+func (e *UploadUncertainError) Error() string {
+	return fmt.Sprintf("osmapi: unknown if upload to changeset %d was applied: %v", e.ChangesetID, e.Err)
+}
+
+// Upload uploads the change to the open changeset and returns the
+// new ids and versions. The client must be authorized by the api.
+// Delegates to the DefaultDatasource and uses its http.Client to make the request.
+func Upload(ctx context.Context, id osm.ChangesetID, change *osm.Change) (*osm.DiffResult, error) {
+	return DefaultDatasource.Upload(ctx, id, change)
+}
+
+// Upload uploads the change to the open changeset and returns the
+// new ids and versions. The client must be authorized by the api.
+//
+// Uploads that fail without a response, e.g. a timeout, or with a server
+// error may still have been applied. Sending them again would create
+// duplicate elements, so the changeset is downloaded to check. If the change
+// was applied the diff result is rebuilt from the changeset, otherwise the
+// upload is retried. If it can not be determined an *UploadUncertainError
+// is returned.
+func (ds *Datasource) Upload(ctx context.Context, id osm.ChangesetID, change *osm.Change) (*osm.DiffResult, error) {
+	data, err := xml.Marshal(change)
+	if err != nil {
+		return nil, err
+	}
+
+	// the elements created by earlier uploads to the changeset,
+	// so they are not mistaken for the elements of this change.
+	var before *osm.Change
+	if change.Create != nil && len(change.Create.Elements()) > 0 {
+		before, err = ds.ChangesetDownload(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for i := 0; ; i++ {
+		dr, err := ds.upload(ctx, id, data)
+		if err == nil || !uploadUncertain(ctx, err) {
+			return dr, err
+		}
+
+		after, derr := ds.ChangesetDownload(ctx, id)
+		if derr != nil {
+			return nil, &UploadUncertainError{ChangesetID: id, Err: err}
+		}
+
+		dr, applied, ok := uploadResult(change, before, after)
+		if !ok {
+			return nil, &UploadUncertainError{ChangesetID: id, Err: err}
+		}
+
+		if applied {
+			return dr, nil
+		}
+
+		if i >= maxUploadRetries {
+			return nil, err
+		}
+
+		if ds.Logger != nil {
+			ds.Logger.Warn("osmapi: upload was not applied, retrying",
+				"changeset", int64(id), "error", err.Error())
+		}
+	}
+}
+
+func (ds *Datasource) upload(ctx context.Context, id osm.ChangesetID, data []byte) (*osm.DiffResult, error) {
+	if ds.Limiter != nil {
+		if err := ds.Limiter.Wait(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	url := fmt.Sprintf("%s/changeset/%d/upload", ds.baseURL(), id)
+	req, err := http.NewRequest("POST", url, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "text/xml")
+
+	code := "error"
+	if ds.Metrics != nil {
+		defer func(start time.Time) {
+			ds.Metrics.Observe(metrics.APIRequestSeconds, time.Since(start).Seconds(),
+				metrics.Label{Name: "call", Value: "upload"},
+				metrics.Label{Name: "code", Value: code},
+			)
+		}(time.Now())
+	}
+
+	resp, err := ds.client().Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	code = strconv.Itoa(resp.StatusCode)
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusConflict:
+		return nil, &ConflictError{URL: url, Message: resp.Header.Get("Error")}
+	case http.StatusNotFound:
+		return nil, &NotFoundError{URL: url}
+	default:
+		if ds.Logger != nil {
+			ds.Logger.Warn("osmapi: unexpected status code",
+				"url", url, "code", resp.StatusCode, "error", resp.Header.Get("Error"))
+		}
+
+		return nil, &UnexpectedStatusCodeError{Code: resp.StatusCode, URL: url}
+	}
+
+	dr := &osm.DiffResult{}
+	if err := xml.NewDecoder(resp.Body).Decode(dr); err != nil {
+		return nil, err
+	}
+
+	return dr, nil
+}
+
+// uploadUncertain returns true if the upload may have been applied
+// even though it failed. A cancelled context is not retried.
+func uploadUncertain(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	if e, ok := err.(*UnexpectedStatusCodeError); ok {
+		return e.Code >= 500
+	}
+
+	switch err.(type) {
+	case *ConflictError, *NotFoundError:
+		return false
+	}
+
+	// no response, e.g. a timeout or closed connection
+	return true
+}
+
+// uploadResult checks if the change is in the changeset contents, before
+// and after the upload, and rebuilds the diff result. Uploads are applied
+// all or nothing, ok is false if only some of the change is found.
+func uploadResult(change, before, after *osm.Change) (dr *osm.DiffResult, applied bool, ok bool) {
+	versions := make(map[osm.ElementID]bool)
+	for _, o := range []*osm.OSM{after.Modify, after.Delete} {
+		if o != nil {
+			for _, id := range o.ElementIDs() {
+				versions[id] = true
+			}
+		}
+	}
+
+	dr = &osm.DiffResult{}
+	found, total := 0, 0
+
+	for _, o := range []*osm.OSM{change.Modify, change.Delete} {
+		if o == nil {
+			continue
+		}
+
+		for _, e := range o.Elements() {
+			total++
+
+			id := e.ElementID()
+			if versions[id.FeatureID().ElementID(id.Version()+1)] {
+				found++
+			}
+
+			entry := osm.DiffResultEntry{OldID: id.Ref()}
+			if o == change.Modify {
+				entry.NewID = id.Ref()
+				entry.NewVersion = id.Version() + 1
+			}
+			appendEntry(dr, id.Type(), entry)
+		}
+	}
+
+	if change.Create != nil {
+		created := newlyCreated(before, after)
+		for _, t := range []osm.Type{osm.TypeNode, osm.TypeWay, osm.TypeRelation} {
+			old := createdRefs(change.Create, t)
+			total += len(old)
+
+			ids := created[t]
+			if len(ids) == 0 {
+				continue
+			}
+
+			// the api assigns the new ids in the order of the upload.
+			if len(ids) != len(old) {
+				return nil, false, false
+			}
+			found += len(old)
+
+			for i, ref := range old {
+				appendEntry(dr, t, osm.DiffResultEntry{OldID: ref, NewID: ids[i], NewVersion: 1})
+			}
+		}
+	}
+
+	switch found {
+	case total:
+		return dr, true, true
+	case 0:
+		return nil, false, true
+	}
+
+	return nil, false, false
+}
+
+func appendEntry(dr *osm.DiffResult, t osm.Type, e osm.DiffResultEntry) {
+	switch t {
+	case osm.TypeNode:
+		dr.Nodes = append(dr.Nodes, e)
+	case osm.TypeWay:
+		dr.Ways = append(dr.Ways, e)
+	case osm.TypeRelation:
+		dr.Relations = append(dr.Relations, e)
+	}
+}
+
+// newlyCreated returns the sorted ids, by type, of the elements created
+// in the after changeset contents that are not in the before contents.
+func newlyCreated(before, after *osm.Change) map[osm.Type][]int64 {
+	existing := make(map[osm.Type]map[int64]bool)
+	if before != nil && before.Create != nil {
+		for _, t := range []osm.Type{osm.TypeNode, osm.TypeWay, osm.TypeRelation} {
+			existing[t] = make(map[int64]bool)
+			for _, ref := range createdRefs(before.Create, t) {
+				existing[t][ref] = true
+			}
+		}
+	}
+
+	result := make(map[osm.Type][]int64)
+	if after.Create == nil {
+		return result
+	}
+
+	for _, t := range []osm.Type{osm.TypeNode, osm.TypeWay, osm.TypeRelation} {
+		for _, ref := range createdRefs(after.Create, t) {
+			if !existing[t][ref] {
+				result[t] = append(result[t], ref)
+			}
+		}
+
+		ids := result[t]
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	}
+
+	return result
+}
+
+// createdRefs returns the ids of the elements of the type in order.
+func createdRefs(o *osm.OSM, t osm.Type) []int64 {
+	var refs []int64
+	switch t {
+	case osm.TypeNode:
+		for _, n := range o.Nodes {
+			refs = append(refs, int64(n.ID))
+		}
+	case osm.TypeWay:
+		for _, w := range o.Ways {
+			refs = append(refs, int64(w.ID))
+		}
+	case osm.TypeRelation:
+		for _, r := range o.Relations {
+			refs = append(refs, int64(r.ID))
+		}
+	}
+
+	return refs
+}
@@ -0,0 +1,174 @@
+package osmapi
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/paulmach/osm"
+)
+
+// uploadServer mimics the upload and download calls of a changeset that
+// already has node 1 created. The fail function decides, for each upload,
+// if it is applied and the status code to respond with.
+func uploadServer(t *testing.T, fail func(upload int) (applied bool, code int)) (*httptest.Server, *int) {
+	uploads := 0
+	applied := false
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "POST" && r.URL.Path == "/changeset/10/upload":
+			uploads++
+			body, _ := ioutil.ReadAll(r.Body)
+			if !strings.Contains(string(body), `<node id="-1"`) {
+				t.Errorf("incorrect upload: %s", body)
+			}
+
+			ok, code := fail(uploads)
+			applied = applied || ok
+			w.WriteHeader(code)
+			if code == http.StatusOK {
+				w.Write([]byte(`<diffResult>
+					<node old_id="-1" new_id="6" new_version="1"/>
+					<node old_id="-2" new_id="7" new_version="1"/>
+					<way old_id="2" new_id="2" new_version="4"/>
+				</diffResult>`))
+			}
+		case r.Method == "GET" && r.URL.Path == "/changeset/10/download":
+			body := `<osmChange><create><node id="1" version="1"/></create>`
+			if applied {
+				body += `<create><node id="7" version="1"/><node id="6" version="1"/></create>
+					<modify><way id="2" version="4"/></modify>`
+			}
+			w.Write([]byte(body + `</osmChange>`))
+		default:
+			t.Errorf("unexpected request: %v %v", r.Method, r.URL)
+		}
+	}))
+
+	return ts, &uploads
+}
+
+func uploadChange() *osm.Change {
+	c := &osm.Change{}
+	c.AppendCreate(&osm.Node{ID: -1})
+	c.AppendCreate(&osm.Node{ID: -2})
+	c.AppendModify(&osm.Way{ID: 2, Version: 3})
+	return c
+}
+
+var expectedDiffResult = &osm.DiffResult{
+	Nodes: []osm.DiffResultEntry{
+		{OldID: -1, NewID: 6, NewVersion: 1},
+		{OldID: -2, NewID: 7, NewVersion: 1},
+	},
+	Ways: []osm.DiffResultEntry{
+		{OldID: 2, NewID: 2, NewVersion: 4},
+	},
+}
+
+func TestDatasource_Upload(t *testing.T) {
+	cases := []struct {
+		name    string
+		fail    func(int) (bool, int)
+		uploads int
+	}{
+		{
+			name: "success",
+			fail: func(int) (bool, int) {
+				return true, http.StatusOK
+			},
+			uploads: 1,
+		},
+		{
+			name: "applied but failed",
+			fail: func(int) (bool, int) {
+				return true, http.StatusGatewayTimeout
+			},
+			uploads: 1,
+		},
+		{
+			name: "not applied",
+			fail: func(i int) (bool, int) {
+				if i == 1 {
+					return false, http.StatusBadGateway
+				}
+				return true, http.StatusOK
+			},
+			uploads: 2,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ts, uploads := uploadServer(t, tc.fail)
+			defer ts.Close()
+
+			ds := &Datasource{BaseURL: ts.URL, Client: ts.Client()}
+			dr, err := ds.Upload(context.Background(), 10, uploadChange())
+			if err != nil {
+				t.Fatalf("upload error: %v", err)
+			}
+
+			if *uploads != tc.uploads {
+				t.Errorf("incorrect number of uploads: %v", *uploads)
+			}
+
+			dr.XMLName.Local = ""
+			if !reflect.DeepEqual(dr, expectedDiffResult) {
+				t.Errorf("incorrect diff result: %+v", dr)
+			}
+		})
+	}
+}
+
+func TestDatasource_Upload_errors(t *testing.T) {
+	t.Run("conflict", func(t *testing.T) {
+		ts, uploads := uploadServer(t, func(int) (bool, int) {
+			return false, http.StatusConflict
+		})
+		defer ts.Close()
+
+		ds := &Datasource{BaseURL: ts.URL, Client: ts.Client()}
+		_, err := ds.Upload(context.Background(), 10, uploadChange())
+		if _, ok := err.(*ConflictError); !ok {
+			t.Errorf("incorrect error: %v", err)
+		}
+
+		if *uploads != 1 {
+			t.Errorf("should not retry conflicts: %v", *uploads)
+		}
+	})
+
+	t.Run("retries", func(t *testing.T) {
+		ts, uploads := uploadServer(t, func(int) (bool, int) {
+			return false, http.StatusInternalServerError
+		})
+		defer ts.Close()
+
+		ds := &Datasource{BaseURL: ts.URL, Client: ts.Client()}
+		_, err := ds.Upload(context.Background(), 10, uploadChange())
+		if _, ok := err.(*UnexpectedStatusCodeError); !ok {
+			t.Errorf("incorrect error: %v", err)
+		}
+
+		if *uploads != maxUploadRetries+1 {
+			t.Errorf("incorrect number of uploads: %v", *uploads)
+		}
+	})
+}
+
+func TestUploadResult_partial(t *testing.T) {
+	after := &osm.Change{
+		Create: &osm.OSM{Nodes: osm.Nodes{{ID: 6, Version: 1}}},
+	}
+
+	_, _, ok := uploadResult(uploadChange(), nil, after)
+	if ok {
+		t.Errorf("should not be sure about a partial result")
+	}
+}
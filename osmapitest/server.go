@@ -0,0 +1,278 @@
+// Package osmapitest provides a fake osm api server, backed by an in memory
+// datasource, for hermetic integration tests of applications using the
+// osmapi package.
+package osmapitest
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/paulmach/osm"
+	"github.com/paulmach/osm/osmapi"
+	"github.com/paulmach/osm/osmserver"
+)
+
+// A Server is a fake osm api. The read calls are served by the osmserver
+// handler, in addition it supports creating, closing, downloading and
+// uploading to changesets:
+//
+//	PUT  /api/0.6/changeset/create
+//	PUT  /api/0.6/changeset/#id/close
+//	GET  /api/0.6/changeset/#id
+//	GET  /api/0.6/changeset/#id/download
+//	POST /api/0.6/changeset/#id/upload
+//
+// Uploads are checked like the api, returning 409 Conflict for version
+// mismatches and 412 Precondition Failed for missing references or
+// deleting elements that are still used. They are applied all or nothing.
+type Server struct {
+	*httptest.Server
+
+	// User and UserID are set on the changesets and uploaded elements.
+	User   string
+	UserID osm.UserID
+
+	lk         sync.RWMutex
+	store      *osmserver.Store
+	reads      *osmserver.Handler
+	changesets map[osm.ChangesetID]*changeset
+	lastID     map[osm.Type]int64
+	now        func() time.Time
+}
+
+type changeset struct {
+	cs     *osm.Changeset
+	change *osm.Change
+}
+
+// NewServer starts a server with the objects, nodes, ways and relations,
+// in the datasource. The server must be closed when done.
+func NewServer(objects ...osm.Object) *Server {
+	s := &Server{
+		User:       "osmapitest",
+		UserID:     1,
+		store:      &osmserver.Store{},
+		changesets: make(map[osm.ChangesetID]*changeset),
+		lastID:     make(map[osm.Type]int64),
+		now:        time.Now,
+	}
+	s.reads = osmserver.NewHandler(s.store)
+
+	s.Add(objects...)
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+
+	return s
+}
+
+// Add adds the nodes, ways and relations, all versions or just the
+// latest, to the datasource. New elements are given larger ids.
+func (s *Server) Add(objects ...osm.Object) {
+	s.lk.Lock()
+	defer s.lk.Unlock()
+
+	for _, o := range objects {
+		e, ok := o.(osm.Element)
+		if !ok {
+			continue
+		}
+
+		fid := e.FeatureID()
+		if fid.Ref() > s.lastID[fid.Type()] {
+			s.lastID[fid.Type()] = fid.Ref()
+		}
+	}
+
+	s.store.Add(context.Background(), objects...)
+}
+
+// Datasource returns an osmapi datasource for the server.
+func (s *Server) Datasource() *osmapi.Datasource {
+	ds := osmapi.NewDatasource(s.Client())
+	ds.BaseURL = s.URL + "/api/0.6"
+
+	return ds
+}
+
+// CreateChangeset opens a changeset with the tags, like the
+// changeset/create call, and returns its id.
+func (s *Server) CreateChangeset(tags osm.Tags) osm.ChangesetID {
+	s.lk.Lock()
+	defer s.lk.Unlock()
+
+	return s.createChangeset(tags)
+}
+
+func (s *Server) createChangeset(tags osm.Tags) osm.ChangesetID {
+	id := osm.ChangesetID(len(s.changesets) + 1)
+	s.changesets[id] = &changeset{
+		cs: &osm.Changeset{
+			ID:        id,
+			User:      s.User,
+			UserID:    s.UserID,
+			CreatedAt: s.now().UTC().Truncate(time.Second),
+			Open:      true,
+			Tags:      tags,
+		},
+		change: &osm.Change{},
+	}
+
+	return id
+}
+
+// Changeset returns the changeset and its contents, nil if it does
+// not exist. They must not be modified.
+func (s *Server) Changeset(id osm.ChangesetID) (*osm.Changeset, *osm.Change) {
+	s.lk.RLock()
+	defer s.lk.RUnlock()
+
+	c, ok := s.changesets[id]
+	if !ok {
+		return nil, nil
+	}
+
+	return c.cs, c.change
+}
+
+// httpError is an error response with the reason in the Error header.
+type httpError struct {
+	code    int
+	message string
+}
+
+func (e *httpError) Error() string {
+	return e.message
+}
+
+func errorf(code int, format string, args ...interface{}) error {
+	return &httpError{code: code, message: fmt.Sprintf(format, args...)}
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	i := strings.LastIndex(r.URL.Path, "/api/0.6/changeset/")
+	if i == -1 {
+		s.lk.RLock()
+		defer s.lk.RUnlock()
+
+		s.reads.ServeHTTP(w, r)
+		return
+	}
+
+	parts := strings.Split(r.URL.Path[i+len("/api/0.6/changeset/"):], "/")
+
+	var (
+		result interface{}
+		err    error
+	)
+
+	switch {
+	case r.Method == "PUT" && len(parts) == 1 && parts[0] == "create":
+		result, err = s.handleCreate(r)
+	case len(parts) > 0:
+		var id int64
+		id, err = strconv.ParseInt(parts[0], 10, 64)
+		if err != nil {
+			err = errorf(http.StatusBadRequest, "invalid changeset id %q", parts[0])
+			break
+		}
+
+		result, err = s.handleChangeset(r, osm.ChangesetID(id), parts[1:])
+	}
+
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	if text, ok := result.(string); ok {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Write([]byte(text))
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+	w.Write([]byte(xml.Header))
+	xml.NewEncoder(w).Encode(result)
+}
+
+func (s *Server) handleCreate(r *http.Request) (interface{}, error) {
+	o := &osm.OSM{}
+	if err := xml.NewDecoder(r.Body).Decode(o); err != nil || len(o.Changesets) != 1 {
+		return nil, errorf(http.StatusBadRequest, "invalid changeset")
+	}
+
+	s.lk.Lock()
+	defer s.lk.Unlock()
+
+	return strconv.FormatInt(int64(s.createChangeset(o.Changesets[0].Tags)), 10), nil
+}
+
+func (s *Server) handleChangeset(r *http.Request, id osm.ChangesetID, parts []string) (interface{}, error) {
+	if r.Method == "GET" {
+		s.lk.RLock()
+		defer s.lk.RUnlock()
+	} else {
+		s.lk.Lock()
+		defer s.lk.Unlock()
+	}
+
+	c, ok := s.changesets[id]
+	if !ok {
+		return nil, errorf(http.StatusNotFound, "Changeset %d not found", id)
+	}
+
+	call := ""
+	if len(parts) > 0 {
+		call = parts[0]
+	}
+
+	switch {
+	case r.Method == "GET" && call == "":
+		return &osm.OSM{Changesets: osm.Changesets{c.cs}}, nil
+	case r.Method == "GET" && call == "download":
+		return c.change, nil
+	case r.Method == "PUT" && call == "close":
+		if !c.cs.Open {
+			return nil, errorf(http.StatusConflict, "The changeset %d was closed at %s", id, c.cs.ClosedAt.Format(time.RFC3339))
+		}
+
+		c.cs.Open = false
+		c.cs.ClosedAt = s.now().UTC().Truncate(time.Second)
+		return "", nil
+	case r.Method == "POST" && call == "upload":
+		data, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			return nil, err
+		}
+
+		change := &osm.Change{}
+		if err := xml.NewDecoder(bytes.NewReader(data)).Decode(change); err != nil {
+			return nil, errorf(http.StatusBadRequest, "invalid osmChange: %v", err)
+		}
+
+		return s.upload(c, change)
+	}
+
+	return nil, errorf(http.StatusNotFound, "not found")
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	e, ok := err.(*httpError)
+	if !ok {
+		e = &httpError{code: http.StatusInternalServerError, message: err.Error()}
+	}
+
+	// the osm api reports the error in the Error header
+	w.Header().Set("Error", e.message)
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(e.code)
+	w.Write([]byte(e.message))
+}
@@ -0,0 +1,222 @@
+package osmapitest
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/paulmach/osm"
+	"github.com/paulmach/osm/osmapi"
+)
+
+func testServer() *Server {
+	return NewServer(
+		&osm.Node{ID: 1, Version: 1, Visible: true, Lat: 1, Lon: 1},
+		&osm.Node{ID: 2, Version: 1, Visible: true, Lat: 2, Lon: 2},
+		&osm.Node{ID: 3, Version: 2, Visible: true, Lat: 3, Lon: 3},
+		&osm.Way{ID: 10, Version: 1, Visible: true, Nodes: osm.WayNodes{{ID: 1}, {ID: 2}}},
+	)
+}
+
+func TestServer_reads(t *testing.T) {
+	s := testServer()
+	defer s.Close()
+
+	ctx := context.Background()
+	ds := s.Datasource()
+
+	n, err := ds.Node(ctx, 3)
+	if err != nil {
+		t.Fatalf("node error: %v", err)
+	}
+
+	if n.Version != 2 || n.Lat != 3 {
+		t.Errorf("incorrect node: %+v", n)
+	}
+
+	_, err = ds.Node(ctx, 100)
+	if _, ok := err.(*osmapi.NotFoundError); !ok {
+		t.Errorf("expected not found error, got %v", err)
+	}
+}
+
+func TestServer_upload(t *testing.T) {
+	s := testServer()
+	defer s.Close()
+
+	ctx := context.Background()
+	ds := s.Datasource()
+	id := s.CreateChangeset(osm.Tags{{Key: "comment", Value: "test"}})
+
+	change := &osm.Change{}
+	change.AppendCreate(&osm.Node{ID: -1, ChangesetID: id, Lat: 4, Lon: 4})
+	change.AppendCreate(&osm.Way{ID: -1, ChangesetID: id, Nodes: osm.WayNodes{{ID: -1}, {ID: 3}}})
+	change.AppendModify(&osm.Node{ID: 2, Version: 1, ChangesetID: id, Lat: 5, Lon: 5})
+
+	dr, err := ds.Upload(ctx, id, change)
+	if err != nil {
+		t.Fatalf("upload error: %v", err)
+	}
+
+	if len(dr.Nodes) != 2 || dr.Nodes[0].NewID != 4 || dr.Nodes[1].NewVersion != 2 {
+		t.Errorf("incorrect node results: %+v", dr.Nodes)
+	}
+
+	if len(dr.Ways) != 1 || dr.Ways[0].OldID != -1 || dr.Ways[0].NewID != 11 {
+		t.Errorf("incorrect way results: %+v", dr.Ways)
+	}
+
+	w, err := ds.Way(ctx, 11)
+	if err != nil {
+		t.Fatalf("way error: %v", err)
+	}
+
+	if w.Nodes[0].ID != 4 || w.ChangesetID != id || w.User != "osmapitest" {
+		t.Errorf("incorrect way: %+v", w)
+	}
+
+	n, err := ds.Node(ctx, 2)
+	if err != nil {
+		t.Fatalf("node error: %v", err)
+	}
+
+	if n.Version != 2 || n.Lat != 5 {
+		t.Errorf("incorrect node: %+v", n)
+	}
+
+	contents, err := ds.ChangesetDownload(ctx, id)
+	if err != nil {
+		t.Fatalf("download error: %v", err)
+	}
+
+	if len(contents.Create.Nodes) != 1 || len(contents.Modify.Nodes) != 1 {
+		t.Errorf("incorrect changeset contents: %+v", contents)
+	}
+
+	cs, _ := s.Changeset(id)
+	if cs.ChangesCount != 3 {
+		t.Errorf("incorrect changes count: %v", cs.ChangesCount)
+	}
+}
+
+func TestServer_uploadConflict(t *testing.T) {
+	s := testServer()
+	defer s.Close()
+
+	ctx := context.Background()
+	ds := s.Datasource()
+	id := s.CreateChangeset(nil)
+
+	change := &osm.Change{}
+	change.AppendCreate(&osm.Node{ID: -1, ChangesetID: id})
+	change.AppendModify(&osm.Node{ID: 3, Version: 1, ChangesetID: id})
+
+	_, err := ds.Upload(ctx, id, change)
+	e, ok := err.(*osmapi.ConflictError)
+	if !ok {
+		t.Fatalf("expected conflict error, got %v", err)
+	}
+
+	if e.Message != "Version mismatch: Provided 1, server had: 2 of Node 3" {
+		t.Errorf("incorrect message: %v", e.Message)
+	}
+
+	// nothing should be applied
+	if _, err := ds.Node(ctx, 4); err == nil {
+		t.Errorf("created node should not exist")
+	}
+
+	if _, change := s.Changeset(id); change.Create != nil {
+		t.Errorf("changeset should be empty: %+v", change)
+	}
+}
+
+func TestServer_uploadPrecondition(t *testing.T) {
+	s := testServer()
+	defer s.Close()
+
+	ctx := context.Background()
+	ds := s.Datasource()
+	id := s.CreateChangeset(nil)
+
+	// node 1 is used by way 10
+	change := &osm.Change{}
+	change.AppendDelete(&osm.Node{ID: 1, Version: 1, ChangesetID: id})
+
+	_, err := ds.Upload(ctx, id, change)
+	if e, ok := err.(*osmapi.UnexpectedStatusCodeError); !ok || e.Code != http.StatusPreconditionFailed {
+		t.Errorf("expected precondition failed, got %v", err)
+	}
+
+	// deleting both is fine
+	change.AppendDelete(&osm.Way{ID: 10, Version: 1, ChangesetID: id})
+	dr, err := ds.Upload(ctx, id, change)
+	if err != nil {
+		t.Fatalf("upload error: %v", err)
+	}
+
+	if len(dr.Nodes) != 1 || dr.Nodes[0].NewID != 0 {
+		t.Errorf("incorrect result: %+v", dr.Nodes)
+	}
+
+	_, err = ds.Way(ctx, 10)
+	if _, ok := err.(*osmapi.GoneError); !ok {
+		t.Errorf("expected gone error, got %v", err)
+	}
+
+	// way with a missing node
+	change = &osm.Change{}
+	change.AppendCreate(&osm.Way{ID: -1, ChangesetID: id, Nodes: osm.WayNodes{{ID: 2}, {ID: 1}}})
+
+	_, err = ds.Upload(ctx, id, change)
+	if e, ok := err.(*osmapi.UnexpectedStatusCodeError); !ok || e.Code != http.StatusPreconditionFailed {
+		t.Errorf("expected precondition failed, got %v", err)
+	}
+}
+
+func TestServer_changesets(t *testing.T) {
+	s := testServer()
+	defer s.Close()
+
+	ctx := context.Background()
+	client := s.Client()
+
+	body := `<osm><changeset><tag k="comment" v="new"/></changeset></osm>`
+	req, _ := http.NewRequest("PUT", s.URL+"/api/0.6/changeset/create", strings.NewReader(body))
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("request error: %v", err)
+	}
+	resp.Body.Close()
+
+	cs, _ := s.Changeset(1)
+	if cs == nil || !cs.Open || cs.Tags.Find("comment") != "new" {
+		t.Fatalf("incorrect changeset: %+v", cs)
+	}
+
+	req, _ = http.NewRequest("PUT", s.URL+"/api/0.6/changeset/1/close", nil)
+	resp, err = client.Do(req)
+	if err != nil {
+		t.Fatalf("request error: %v", err)
+	}
+	resp.Body.Close()
+
+	ds := s.Datasource()
+	c, err := ds.Changeset(ctx, 1)
+	if err != nil {
+		t.Fatalf("changeset error: %v", err)
+	}
+
+	if c.Open || c.ClosedAt.IsZero() {
+		t.Errorf("changeset should be closed: %+v", c)
+	}
+
+	change := &osm.Change{}
+	change.AppendCreate(&osm.Node{ID: -1, ChangesetID: 1})
+
+	_, err = ds.Upload(ctx, 1, change)
+	if _, ok := err.(*osmapi.ConflictError); !ok {
+		t.Errorf("expected conflict error, got %v", err)
+	}
+}
@@ -0,0 +1,390 @@
+package osmapitest
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/paulmach/osm"
+)
+
+// upload checks and applies the change to the datasource, all or nothing,
+// and returns the new ids and versions like the api.
+func (s *Server) upload(c *changeset, change *osm.Change) (*osm.DiffResult, error) {
+	if !c.cs.Open {
+		return nil, errorf(http.StatusConflict, "The changeset %d was closed at %s",
+			c.cs.ID, c.cs.ClosedAt.Format(time.RFC3339))
+	}
+
+	create, modify, remove := elements(change.Create), elements(change.Modify), elements(change.Delete)
+	dr := &osm.DiffResult{}
+
+	// the elements deleted by this upload, they may still be referenced
+	// by other elements deleted in the same upload.
+	deleted := make(map[osm.FeatureID]bool)
+	for _, e := range remove {
+		deleted[e.FeatureID()] = true
+	}
+
+	lastID := make(map[osm.Type]int64)
+	for t, id := range s.lastID {
+		lastID[t] = id
+	}
+
+	// created elements have negative placeholder ids, that can not be
+	// used as feature ids, so the ids are read by type.
+	for _, e := range create {
+		if err := s.checkChangeset(c, e); err != nil {
+			return nil, err
+		}
+	}
+
+	if change.Create != nil {
+		for _, t := range []osm.Type{osm.TypeNode, osm.TypeWay, osm.TypeRelation} {
+			for _, ref := range createdRefs(change.Create, t) {
+				lastID[t]++
+				appendEntry(dr, t, osm.DiffResultEntry{
+					OldID:      ref,
+					NewID:      lastID[t],
+					NewVersion: 1,
+				})
+			}
+		}
+	}
+
+	for _, e := range modify {
+		if err := s.checkChangeset(c, e); err != nil {
+			return nil, err
+		}
+
+		current, err := s.checkVersion(e)
+		if err != nil {
+			return nil, err
+		}
+
+		fid := e.FeatureID()
+		if !visible(current) {
+			return nil, errorf(http.StatusGone, "The %s with the id %d has already been deleted", fid.Type(), fid.Ref())
+		}
+
+		appendEntry(dr, fid.Type(), osm.DiffResultEntry{
+			OldID:      fid.Ref(),
+			NewID:      fid.Ref(),
+			NewVersion: e.ElementID().Version() + 1,
+		})
+	}
+
+	for _, e := range remove {
+		if err := s.checkChangeset(c, e); err != nil {
+			return nil, err
+		}
+
+		current, err := s.checkVersion(e)
+		if err != nil {
+			return nil, err
+		}
+
+		fid := e.FeatureID()
+		if !visible(current) {
+			return nil, errorf(http.StatusGone, "The %s with the id %d has already been deleted", fid.Type(), fid.Ref())
+		}
+
+		if err := s.checkUnused(fid, deleted); err != nil {
+			return nil, err
+		}
+
+		appendEntry(dr, fid.Type(), osm.DiffResultEntry{OldID: fid.Ref()})
+	}
+
+	// update the placeholder ids, the references to them and the versions.
+	dr.Apply(change)
+
+	if err := s.checkReferences(change, deleted); err != nil {
+		return nil, err
+	}
+
+	// everything checks out, apply the change
+	s.lastID = lastID
+	now := s.now().UTC().Truncate(time.Second)
+
+	for _, e := range elements(change.Create) {
+		s.stamp(e, c.cs.ID, now, true, 0)
+	}
+
+	for _, e := range elements(change.Modify) {
+		s.stamp(e, c.cs.ID, now, true, 0)
+	}
+
+	for _, e := range elements(change.Delete) {
+		s.stamp(e, c.cs.ID, now, false, e.ElementID().Version()+1)
+	}
+
+	var objects []osm.Object
+	for _, o := range []*osm.OSM{change.Create, change.Modify, change.Delete} {
+		if o != nil {
+			objects = append(objects, o.Objects()...)
+		}
+	}
+
+	s.store.Add(context.Background(), objects...)
+	appendChange(c.change, change)
+	c.cs.ChangesCount += len(objects)
+
+	return dr, nil
+}
+
+func (s *Server) checkChangeset(c *changeset, e osm.Element) error {
+	id := changesetID(e)
+	if id != c.cs.ID {
+		return errorf(http.StatusConflict, "Changeset mismatch: Provided %d but only %d is allowed", id, c.cs.ID)
+	}
+
+	return nil
+}
+
+// checkVersion returns the current version of the element, 409 Conflict
+// if it is not the same as the version of the element.
+func (s *Server) checkVersion(e osm.Element) (osm.Element, error) {
+	fid := e.FeatureID()
+	current := s.latest(fid)
+	if current == nil {
+		return nil, errorf(http.StatusNotFound, "The %s with the id %d was not found", fid.Type(), fid.Ref())
+	}
+
+	provided, server := e.ElementID().Version(), current.ElementID().Version()
+	if provided != server {
+		return nil, errorf(http.StatusConflict, "Version mismatch: Provided %d, server had: %d of %s %d",
+			provided, server, typeName(fid.Type()), fid.Ref())
+	}
+
+	return current, nil
+}
+
+// checkUnused returns 412 Precondition Failed if the element is still
+// used by a way or relation that is not deleted in the same upload.
+func (s *Server) checkUnused(fid osm.FeatureID, deleted map[osm.FeatureID]bool) error {
+	if fid.Type() == osm.TypeNode {
+		for id := range s.store.Ways {
+			w := s.latest(id.FeatureID())
+			if w == nil || !visible(w) || deleted[w.FeatureID()] {
+				continue
+			}
+
+			for _, n := range w.(*osm.Way).Nodes {
+				if n.ID == fid.NodeID() {
+					return errorf(http.StatusPreconditionFailed, "Node %d is still used by ways %d.", fid.Ref(), id)
+				}
+			}
+		}
+	}
+
+	for id := range s.store.Relations {
+		r := s.latest(id.FeatureID())
+		if r == nil || !visible(r) || deleted[r.FeatureID()] {
+			continue
+		}
+
+		for _, m := range r.(*osm.Relation).Members {
+			if m.FeatureID() == fid {
+				return errorf(http.StatusPreconditionFailed, "The %s %d is used in relations %d.", fid.Type(), fid.Ref(), id)
+			}
+		}
+	}
+
+	return nil
+}
+
+// checkReferences returns 412 Precondition Failed if the created or
+// modified ways and relations reference elements that do not exist,
+// after the placeholder ids have been replaced.
+func (s *Server) checkReferences(change *osm.Change, deleted map[osm.FeatureID]bool) error {
+	created := make(map[osm.FeatureID]bool)
+	if change.Create != nil {
+		for _, fid := range change.Create.FeatureIDs() {
+			created[fid] = true
+		}
+	}
+
+	exists := func(fid osm.FeatureID) bool {
+		if created[fid] {
+			return true
+		}
+
+		if deleted[fid] {
+			return false
+		}
+
+		e := s.latest(fid)
+		return e != nil && visible(e)
+	}
+
+	for _, o := range []*osm.OSM{change.Create, change.Modify} {
+		if o == nil {
+			continue
+		}
+
+		for _, w := range o.Ways {
+			for _, n := range w.Nodes {
+				if !exists(n.ID.FeatureID()) {
+					return errorf(http.StatusPreconditionFailed,
+						"Way %d requires the nodes with id in %d, which either do not exist, or are not visible.", w.ID, n.ID)
+				}
+			}
+		}
+
+		for _, r := range o.Relations {
+			for _, m := range r.Members {
+				if !exists(m.FeatureID()) {
+					return errorf(http.StatusPreconditionFailed,
+						"Relation with id %d cannot be saved due to %s with id %d", r.ID, typeName(m.Type), m.Ref)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// latest returns the latest version of the element, including deleted
+// versions, nil if it does not exist.
+func (s *Server) latest(fid osm.FeatureID) osm.Element {
+	var result osm.Element
+	switch fid.Type() {
+	case osm.TypeNode:
+		for _, n := range s.store.Nodes[fid.NodeID()] {
+			if result == nil || n.Version > result.(*osm.Node).Version {
+				result = n
+			}
+		}
+	case osm.TypeWay:
+		for _, w := range s.store.Ways[fid.WayID()] {
+			if result == nil || w.Version > result.(*osm.Way).Version {
+				result = w
+			}
+		}
+	case osm.TypeRelation:
+		for _, r := range s.store.Relations[fid.RelationID()] {
+			if result == nil || r.Version > result.(*osm.Relation).Version {
+				result = r
+			}
+		}
+	}
+
+	return result
+}
+
+// stamp sets the changeset, user, timestamp and visibility of
+// the uploaded element. A non zero version is also set.
+func (s *Server) stamp(e osm.Element, id osm.ChangesetID, now time.Time, visible bool, version int) {
+	switch e := e.(type) {
+	case *osm.Node:
+		e.ChangesetID, e.User, e.UserID, e.Timestamp, e.Visible = id, s.User, s.UserID, now, visible
+		if version != 0 {
+			e.Version = version
+		}
+	case *osm.Way:
+		e.ChangesetID, e.User, e.UserID, e.Timestamp, e.Visible = id, s.User, s.UserID, now, visible
+		if version != 0 {
+			e.Version = version
+		}
+	case *osm.Relation:
+		e.ChangesetID, e.User, e.UserID, e.Timestamp, e.Visible = id, s.User, s.UserID, now, visible
+		if version != 0 {
+			e.Version = version
+		}
+	}
+}
+
+func elements(o *osm.OSM) osm.Elements {
+	if o == nil {
+		return nil
+	}
+
+	return o.Elements()
+}
+
+func appendEntry(dr *osm.DiffResult, t osm.Type, e osm.DiffResultEntry) {
+	switch t {
+	case osm.TypeNode:
+		dr.Nodes = append(dr.Nodes, e)
+	case osm.TypeWay:
+		dr.Ways = append(dr.Ways, e)
+	case osm.TypeRelation:
+		dr.Relations = append(dr.Relations, e)
+	}
+}
+
+// createdRefs returns the ids of the elements of the type in order.
+func createdRefs(o *osm.OSM, t osm.Type) []int64 {
+	var refs []int64
+	switch t {
+	case osm.TypeNode:
+		for _, n := range o.Nodes {
+			refs = append(refs, int64(n.ID))
+		}
+	case osm.TypeWay:
+		for _, w := range o.Ways {
+			refs = append(refs, int64(w.ID))
+		}
+	case osm.TypeRelation:
+		for _, r := range o.Relations {
+			refs = append(refs, int64(r.ID))
+		}
+	}
+
+	return refs
+}
+
+func appendChange(c, add *osm.Change) {
+	for _, o := range add.Create.Objects() {
+		c.AppendCreate(o)
+	}
+
+	for _, o := range add.Modify.Objects() {
+		c.AppendModify(o)
+	}
+
+	for _, o := range add.Delete.Objects() {
+		c.AppendDelete(o)
+	}
+}
+
+func changesetID(e osm.Element) osm.ChangesetID {
+	switch e := e.(type) {
+	case *osm.Node:
+		return e.ChangesetID
+	case *osm.Way:
+		return e.ChangesetID
+	case *osm.Relation:
+		return e.ChangesetID
+	}
+
+	return 0
+}
+
+func visible(e osm.Element) bool {
+	switch e := e.(type) {
+	case *osm.Node:
+		return e.Visible
+	case *osm.Way:
+		return e.Visible
+	case *osm.Relation:
+		return e.Visible
+	}
+
+	return false
+}
+
+// typeName returns the capitalized type name used in the api error messages.
+func typeName(t osm.Type) string {
+	switch t {
+	case osm.TypeNode:
+		return "Node"
+	case osm.TypeWay:
+		return "Way"
+	case osm.TypeRelation:
+		return "Relation"
+	}
+
+	return string(t)
+}
@@ -0,0 +1,19 @@
+package osmarrow
+
+import "errors"
+
+// An Option is a setting for writing the arrow stream.
+type Option func(*Writer) error
+
+// BatchSize sets the number of elements buffered before writing a record batch.
+// The default is 10,000.
+func BatchSize(n int) Option {
+	return func(w *Writer) error {
+		if n <= 0 {
+			return errors.New("osmarrow: batch size must be positive")
+		}
+
+		w.batchSize = n
+		return nil
+	}
+}
@@ -0,0 +1,129 @@
+package osmarrow
+
+import "github.com/paulmach/osm/internal/flatbuf"
+
+// arrow metadata version V5
+const metadataVersion = 4
+
+// arrow message header types
+const (
+	headerSchema      = 1
+	headerRecordBatch = 3
+)
+
+// arrow type union values
+const (
+	typeInt       = 2
+	typeFloat     = 3
+	typeUtf8      = 5
+	typeBool      = 6
+	typeTimestamp = 10
+	typeList      = 12
+	typeStruct    = 13
+	typeMap       = 17
+)
+
+type field struct {
+	name     string
+	typ      uint8
+	bitWidth int32
+	nullable bool
+	children []field
+}
+
+// schema is the record batch schema. Lat and lon are null for ways and
+// relations, nodes is null for nodes and relations and members is null
+// for nodes and ways.
+var schema = []field{
+	{name: "type", typ: typeUtf8},
+	{name: "id", typ: typeInt, bitWidth: 64},
+	{name: "version", typ: typeInt, bitWidth: 32},
+	{name: "timestamp", typ: typeTimestamp},
+	{name: "changeset", typ: typeInt, bitWidth: 64},
+	{name: "user_id", typ: typeInt, bitWidth: 32},
+	{name: "user", typ: typeUtf8},
+	{name: "visible", typ: typeBool},
+	{name: "lat", typ: typeFloat, nullable: true},
+	{name: "lon", typ: typeFloat, nullable: true},
+	{name: "tags", typ: typeMap, children: []field{
+		{name: "entries", typ: typeStruct, children: []field{
+			{name: "key", typ: typeUtf8},
+			{name: "value", typ: typeUtf8},
+		}},
+	}},
+	{name: "nodes", typ: typeList, nullable: true, children: []field{
+		{name: "item", typ: typeInt, bitWidth: 64},
+	}},
+	{name: "members", typ: typeList, nullable: true, children: []field{
+		{name: "item", typ: typeStruct, children: []field{
+			{name: "type", typ: typeUtf8},
+			{name: "ref", typ: typeInt, bitWidth: 64},
+			{name: "role", typ: typeUtf8},
+		}},
+	}},
+}
+
+func encodeSchema() []byte {
+	b := flatbuf.NewBuilder(1024)
+
+	fields := make([]int, len(schema))
+	for i, f := range schema {
+		fields[i] = encodeField(b, f)
+	}
+	fieldsOff := b.CreateOffsets(fields)
+
+	b.StartTable(4)
+	b.AddOffset(1, fieldsOff)
+	header := b.EndTable()
+
+	return encodeMessage(b, headerSchema, header, 0)
+}
+
+func encodeField(b *flatbuf.Builder, f field) int {
+	var children []int
+	for _, c := range f.children {
+		children = append(children, encodeField(b, c))
+	}
+	childrenOff := b.CreateOffsets(children)
+
+	var timezone int
+	if f.typ == typeTimestamp {
+		timezone = b.CreateString("UTC")
+	}
+
+	b.StartTable(2)
+	switch f.typ {
+	case typeInt:
+		b.AddInt32(0, f.bitWidth)
+		b.AddUint8(1, 1) // signed
+	case typeFloat:
+		b.AddUint16(0, 2) // double precision
+	case typeTimestamp:
+		b.AddOffset(1, timezone)
+		b.AddUint16(0, 1) // milliseconds
+	}
+	typ := b.EndTable()
+
+	name := b.CreateString(f.name)
+
+	b.StartTable(7)
+	b.AddOffset(0, name)
+	b.AddOffset(3, typ)
+	b.AddOffset(5, childrenOff)
+	if f.nullable {
+		b.AddUint8(1, 1)
+	}
+	b.AddUint8(2, f.typ)
+
+	return b.EndTable()
+}
+
+func encodeMessage(b *flatbuf.Builder, headerType uint8, header int, bodyLength int64) []byte {
+	b.StartTable(5)
+	b.AddUint64(3, uint64(bodyLength))
+	b.AddOffset(2, header)
+	b.AddUint16(0, metadataVersion)
+	b.AddUint8(1, headerType)
+
+	return b.Finish(b.EndTable())
+}
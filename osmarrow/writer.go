@@ -0,0 +1,350 @@
+// Package osmarrow writes osm elements as Apache Arrow record batches
+// using the IPC streaming format, for use with pyarrow and other
+// arrow based analytics tools.
+package osmarrow
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"time"
+
+	"github.com/paulmach/osm"
+	"github.com/paulmach/osm/internal/flatbuf"
+)
+
+// batch is the struct-of-arrays layout of the buffered elements,
+// one value per element in every slice.
+type batch struct {
+	Types      []osm.Type
+	IDs        []int64
+	Versions   []int32
+	Timestamps []int64
+	Changesets []int64
+	UserIDs    []int32
+	Users      []string
+	Visibles   []bool
+	Lats       []float64
+	Lons       []float64
+	Tags       []osm.Tags
+	Nodes      []osm.WayNodes
+	Members    []osm.Members
+}
+
+type element struct {
+	typ       osm.Type
+	id        int64
+	version   int
+	timestamp time.Time
+	changeset osm.ChangesetID
+	userID    osm.UserID
+	user      string
+	visible   bool
+	tags      osm.Tags
+	lat, lon  float64
+	nodes     osm.WayNodes
+	members   osm.Members
+}
+
+// Writer writes osm elements to an arrow IPC stream. The schema is written
+// when the writer is created and the elements are buffered and written as
+// record batches. Close must be called to write the final batch and the
+// end of stream marker.
+type Writer struct {
+	w         io.Writer
+	batchSize int
+	batch     batch
+}
+
+// NewWriter creates a writer and writes the stream schema to w.
+func NewWriter(w io.Writer, opts ...Option) (*Writer, error) {
+	aw := &Writer{
+		w:         w,
+		batchSize: 10000,
+	}
+
+	for _, opt := range opts {
+		if err := opt(aw); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := aw.writeMessage(encodeSchema(), nil); err != nil {
+		return nil, err
+	}
+
+	return aw, nil
+}
+
+// Write adds the node, way or relation to the current batch.
+// Other types are ignored.
+func (w *Writer) Write(o osm.Object) error {
+	var e element
+	switch o := o.(type) {
+	case *osm.Node:
+		e = element{
+			typ: osm.TypeNode, id: int64(o.ID), version: o.Version, timestamp: o.Timestamp,
+			changeset: o.ChangesetID, userID: o.UserID, user: o.User, visible: o.Visible,
+			tags: o.Tags, lat: o.Lat, lon: o.Lon,
+		}
+	case *osm.Way:
+		e = element{
+			typ: osm.TypeWay, id: int64(o.ID), version: o.Version, timestamp: o.Timestamp,
+			changeset: o.ChangesetID, userID: o.UserID, user: o.User, visible: o.Visible,
+			tags: o.Tags, nodes: o.Nodes,
+		}
+	case *osm.Relation:
+		e = element{
+			typ: osm.TypeRelation, id: int64(o.ID), version: o.Version, timestamp: o.Timestamp,
+			changeset: o.ChangesetID, userID: o.UserID, user: o.User, visible: o.Visible,
+			tags: o.Tags, members: o.Members,
+		}
+	default:
+		return nil
+	}
+
+	b := &w.batch
+	b.Types = append(b.Types, e.typ)
+	b.IDs = append(b.IDs, e.id)
+	b.Versions = append(b.Versions, int32(e.version))
+	b.Timestamps = append(b.Timestamps, millis(e.timestamp))
+	b.Changesets = append(b.Changesets, int64(e.changeset))
+	b.UserIDs = append(b.UserIDs, int32(e.userID))
+	b.Users = append(b.Users, e.user)
+	b.Visibles = append(b.Visibles, e.visible)
+	b.Lats = append(b.Lats, e.lat)
+	b.Lons = append(b.Lons, e.lon)
+	b.Tags = append(b.Tags, e.tags)
+	b.Nodes = append(b.Nodes, e.nodes)
+	b.Members = append(b.Members, e.members)
+
+	if len(b.IDs) >= w.batchSize {
+		return w.Flush()
+	}
+
+	return nil
+}
+
+// Flush writes the buffered elements as a record batch.
+func (w *Writer) Flush() error {
+	n := len(w.batch.IDs)
+	if n == 0 {
+		return nil
+	}
+
+	b := w.batch
+	w.batch = batch{}
+
+	types := make([]string, n)
+	isNode := make([]bool, n)
+	isWay := make([]bool, n)
+	isRelation := make([]bool, n)
+	for i, t := range b.Types {
+		types[i] = string(t)
+		isNode[i] = t == osm.TypeNode
+		isWay[i] = t == osm.TypeWay
+		isRelation[i] = t == osm.TypeRelation
+	}
+
+	body := &body{}
+	body.strings(types)
+	body.fixed(n, nil, int64s(b.IDs))
+	body.fixed(n, nil, int32s(b.Versions))
+	body.fixed(n, nil, int64s(b.Timestamps))
+	body.fixed(n, nil, int64s(b.Changesets))
+	body.fixed(n, nil, int32s(b.UserIDs))
+	body.strings(b.Users)
+	body.fixed(n, nil, bitmap(b.Visibles))
+	body.fixed(n, isNode, float64s(b.Lats))
+	body.fixed(n, isNode, float64s(b.Lons))
+
+	// tags are a list of key value structs
+	var keys, values []string
+	tagOffsets := make([]int32, 1, n+1)
+	for _, tags := range b.Tags {
+		for _, t := range tags {
+			keys = append(keys, t.Key)
+			values = append(values, t.Value)
+		}
+		tagOffsets = append(tagOffsets, int32(len(keys)))
+	}
+
+	body.node(n, nil)
+	body.buffer(int32s(tagOffsets))
+	body.node(len(keys), nil)
+	body.strings(keys)
+	body.strings(values)
+
+	var refs []int64
+	nodeOffsets := make([]int32, 1, n+1)
+	for _, wn := range b.Nodes {
+		for _, n := range wn {
+			refs = append(refs, int64(n.ID))
+		}
+		nodeOffsets = append(nodeOffsets, int32(len(refs)))
+	}
+
+	body.node(n, isWay)
+	body.buffer(int32s(nodeOffsets))
+	body.fixed(len(refs), nil, int64s(refs))
+
+	// members are a list of type, ref and role structs
+	var memberTypes, roles []string
+	var memberRefs []int64
+	memberOffsets := make([]int32, 1, n+1)
+	for _, ms := range b.Members {
+		for _, m := range ms {
+			memberTypes = append(memberTypes, string(m.Type))
+			memberRefs = append(memberRefs, m.Ref)
+			roles = append(roles, m.Role)
+		}
+		memberOffsets = append(memberOffsets, int32(len(memberRefs)))
+	}
+
+	body.node(n, isRelation)
+	body.buffer(int32s(memberOffsets))
+	body.node(len(memberRefs), nil)
+	body.strings(memberTypes)
+	body.fixed(len(memberRefs), nil, int64s(memberRefs))
+	body.strings(roles)
+
+	fb := flatbuf.NewBuilder(1024)
+	buffers := fb.CreateInt64Structs(body.buffers, 2)
+	nodes := fb.CreateInt64Structs(body.nodes, 2)
+
+	fb.StartTable(5)
+	fb.AddUint64(0, uint64(n))
+	fb.AddOffset(1, nodes)
+	fb.AddOffset(2, buffers)
+	header := fb.EndTable()
+
+	meta := encodeMessage(fb, headerRecordBatch, header, int64(body.buf.Len()))
+	return w.writeMessage(meta, body.buf.Bytes())
+}
+
+// Close writes the buffered elements and the end of stream marker.
+// It does not close the underlying writer.
+func (w *Writer) Close() error {
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	_, err := w.w.Write([]byte{0xff, 0xff, 0xff, 0xff, 0, 0, 0, 0})
+	return err
+}
+
+// writeMessage writes an encapsulated message. The metadata is prefixed
+// with the continuation marker and its size and padded to 8 bytes.
+func (w *Writer) writeMessage(meta, body []byte) error {
+	size := len(meta) + padding(len(meta)+8)
+
+	data := make([]byte, 8+size, 8+size+len(body))
+	binary.LittleEndian.PutUint32(data, 0xffffffff)
+	binary.LittleEndian.PutUint32(data[4:], uint32(size))
+	copy(data[8:], meta)
+	data = append(data, body...)
+
+	_, err := w.w.Write(data)
+	return err
+}
+
+// body builds the record batch body along with the field nodes and
+// buffer locations, both as flattened int64 pairs, for the metadata.
+type body struct {
+	buf     bytes.Buffer
+	nodes   []int64
+	buffers []int64
+}
+
+// node adds a field node and its validity bitmap. The bitmap is
+// empty if all the values are valid.
+func (b *body) node(length int, valid []bool) {
+	nulls := 0
+	for _, v := range valid {
+		if !v {
+			nulls++
+		}
+	}
+
+	b.nodes = append(b.nodes, int64(length), int64(nulls))
+	if nulls == 0 {
+		b.buffer(nil)
+	} else {
+		b.buffer(bitmap(valid))
+	}
+}
+
+func (b *body) buffer(data []byte) {
+	b.buffers = append(b.buffers, int64(b.buf.Len()), int64(len(data)))
+	b.buf.Write(data)
+	b.buf.Write(make([]byte, padding(len(data))))
+}
+
+func (b *body) fixed(length int, valid []bool, data []byte) {
+	b.node(length, valid)
+	b.buffer(data)
+}
+
+func (b *body) strings(vals []string) {
+	offsets := make([]int32, len(vals)+1)
+	var data []byte
+	for i, v := range vals {
+		data = append(data, v...)
+		offsets[i+1] = int32(len(data))
+	}
+
+	b.node(len(vals), nil)
+	b.buffer(int32s(offsets))
+	b.buffer(data)
+}
+
+func padding(n int) int {
+	return (8 - n%8) % 8
+}
+
+func bitmap(vals []bool) []byte {
+	data := make([]byte, (len(vals)+7)/8)
+	for i, v := range vals {
+		if v {
+			data[i/8] |= 1 << uint(i%8)
+		}
+	}
+
+	return data
+}
+
+func int32s(vals []int32) []byte {
+	data := make([]byte, 4*len(vals))
+	for i, v := range vals {
+		binary.LittleEndian.PutUint32(data[4*i:], uint32(v))
+	}
+
+	return data
+}
+
+func int64s(vals []int64) []byte {
+	data := make([]byte, 8*len(vals))
+	for i, v := range vals {
+		binary.LittleEndian.PutUint64(data[8*i:], uint64(v))
+	}
+
+	return data
+}
+
+func float64s(vals []float64) []byte {
+	data := make([]byte, 8*len(vals))
+	for i, v := range vals {
+		binary.LittleEndian.PutUint64(data[8*i:], math.Float64bits(v))
+	}
+
+	return data
+}
+
+func millis(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+
+	return t.UnixNano() / int64(time.Millisecond)
+}
@@ -0,0 +1,355 @@
+package osmarrow
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/paulmach/osm"
+)
+
+func testObjects() osm.Objects {
+	ts := time.Date(2018, 1, 2, 3, 4, 5, 0, time.UTC)
+	return osm.Objects{
+		&osm.Node{ID: 1, Lat: 1.5, Lon: 2, Version: 1, Timestamp: ts, User: "alice", Visible: true,
+			Tags: osm.Tags{{Key: "amenity", Value: "cafe"}, {Key: "name", Value: "Joe's"}}},
+		&osm.Node{ID: 2, Lat: 1, Lon: 3, Version: 2, Timestamp: ts, User: "bob", Visible: true},
+		&osm.Way{ID: 5, Version: 3, Timestamp: ts, Nodes: osm.WayNodes{{ID: 1}, {ID: 2}},
+			Tags: osm.Tags{{Key: "highway", Value: "primary"}}},
+		&osm.Relation{ID: 6, Version: 4, Timestamp: ts, Members: osm.Members{
+			{Type: osm.TypeWay, Ref: 5, Role: "outer"},
+			{Type: osm.TypeNode, Ref: 1, Role: "label"},
+		}},
+	}
+}
+
+func TestWriter(t *testing.T) {
+	buf := &bytes.Buffer{}
+	w, err := NewWriter(buf, BatchSize(3))
+	if err != nil {
+		t.Fatalf("new error: %v", err)
+	}
+
+	for _, o := range testObjects() {
+		if err := w.Write(o); err != nil {
+			t.Fatalf("write error: %v", err)
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatalf("close error: %v", err)
+	}
+
+	messages := readMessages(t, buf.Bytes())
+	if len(messages) != 3 {
+		t.Fatalf("incorrect number of messages: %v", len(messages))
+	}
+
+	// schema
+	if v := messages[0].meta.uint8(1); v != headerSchema {
+		t.Errorf("incorrect header type: %v", v)
+	}
+
+	fields := messages[0].meta.table(2).tables(1)
+	if len(fields) != len(schema) {
+		t.Fatalf("incorrect number of fields: %v", len(fields))
+	}
+
+	for i, f := range fields {
+		if v := f.str(0); v != schema[i].name {
+			t.Errorf("incorrect field name: %v != %v", v, schema[i].name)
+		}
+
+		if v := f.uint8(2); v != schema[i].typ {
+			t.Errorf("incorrect field type: %v != %v", v, schema[i].typ)
+		}
+	}
+
+	if v := fields[8].uint8(1); v != 1 {
+		t.Errorf("lat should be nullable")
+	}
+
+	if v := fields[10].tables(5)[0].tables(5)[1].str(0); v != "value" {
+		t.Errorf("incorrect map value name: %v", v)
+	}
+
+	// first batch
+	m := messages[1]
+	if v := m.meta.uint8(1); v != headerRecordBatch {
+		t.Errorf("incorrect header type: %v", v)
+	}
+
+	rb := m.meta.table(2)
+	if v := rb.uint64(0); v != 3 {
+		t.Errorf("incorrect length: %v", v)
+	}
+
+	nodes := rb.int64s(1)
+	if len(nodes) != 2*21 {
+		t.Fatalf("incorrect number of field nodes: %v", len(nodes)/2)
+	}
+
+	if nodes[2*8] != 3 || nodes[2*8+1] != 1 {
+		t.Errorf("lat should have one null: %v", nodes[2*8:2*8+2])
+	}
+
+	if nodes[2*11] != 3 {
+		t.Errorf("incorrect number of tags: %v", nodes[2*11])
+	}
+
+	buffers := rb.int64s(2)
+	if len(buffers) != 2*46 {
+		t.Fatalf("incorrect number of buffers: %v", len(buffers)/2)
+	}
+
+	if v := int64(len(m.body)); v != int64(m.meta.uint64(3)) {
+		t.Errorf("incorrect body length: %v", v)
+	}
+
+	for i := 0; i < len(buffers); i += 2 {
+		if buffers[i]%8 != 0 {
+			t.Errorf("buffer %d not aligned: %v", i/2, buffers[i])
+		}
+	}
+
+	buffer := func(i int) []byte {
+		return m.body[buffers[2*i] : buffers[2*i]+buffers[2*i+1]]
+	}
+
+	if v := readInt64s(buffer(4)); !reflect.DeepEqual(v, []int64{1, 2, 5}) {
+		t.Errorf("incorrect ids: %v", v)
+	}
+
+	if v := readStrings(buffer(1), buffer(2)); !reflect.DeepEqual(v, []string{"node", "node", "way"}) {
+		t.Errorf("incorrect types: %v", v)
+	}
+
+	if v := readInt64s(buffer(8)); v[0] != 1514862245000 {
+		t.Errorf("incorrect timestamp: %v", v[0])
+	}
+
+	if v := buffer(18); !reflect.DeepEqual(v, []byte{3}) {
+		t.Errorf("incorrect lat validity: %v", v)
+	}
+
+	if v := math.Float64frombits(binary.LittleEndian.Uint64(buffer(19))); v != 1.5 {
+		t.Errorf("incorrect lat: %v", v)
+	}
+
+	if v := readInt32s(buffer(23)); !reflect.DeepEqual(v, []int32{0, 2, 2, 3}) {
+		t.Errorf("incorrect tag offsets: %v", v)
+	}
+
+	if v := readStrings(buffer(26), buffer(27)); !reflect.DeepEqual(v, []string{"amenity", "name", "highway"}) {
+		t.Errorf("incorrect keys: %v", v)
+	}
+
+	if v := readInt32s(buffer(32)); !reflect.DeepEqual(v, []int32{0, 0, 0, 2}) {
+		t.Errorf("incorrect node offsets: %v", v)
+	}
+
+	if v := readInt64s(buffer(34)); !reflect.DeepEqual(v, []int64{1, 2}) {
+		t.Errorf("incorrect nodes: %v", v)
+	}
+
+	if nodes[2*16] != 3 || nodes[2*16+1] != 3 {
+		t.Errorf("members should be null: %v", nodes[2*16:2*16+2])
+	}
+
+	if v := readInt32s(buffer(36)); !reflect.DeepEqual(v, []int32{0, 0, 0, 0}) {
+		t.Errorf("incorrect member offsets: %v", v)
+	}
+
+	// second batch
+	m = messages[2]
+	rb = m.meta.table(2)
+	if v := rb.uint64(0); v != 1 {
+		t.Errorf("incorrect length: %v", v)
+	}
+
+	nodes = rb.int64s(1)
+	if nodes[2*16] != 1 || nodes[2*16+1] != 0 {
+		t.Errorf("members should not be null: %v", nodes[2*16:2*16+2])
+	}
+
+	buffers = rb.int64s(2)
+	if v := readInt32s(buffer(36)); !reflect.DeepEqual(v, []int32{0, 2}) {
+		t.Errorf("incorrect member offsets: %v", v)
+	}
+
+	if v := readStrings(buffer(39), buffer(40)); !reflect.DeepEqual(v, []string{"way", "node"}) {
+		t.Errorf("incorrect member types: %v", v)
+	}
+
+	if v := readInt64s(buffer(42)); !reflect.DeepEqual(v, []int64{5, 1}) {
+		t.Errorf("incorrect member refs: %v", v)
+	}
+
+	if v := readStrings(buffer(44), buffer(45)); !reflect.DeepEqual(v, []string{"outer", "label"}) {
+		t.Errorf("incorrect member roles: %v", v)
+	}
+}
+
+func TestBatchSize(t *testing.T) {
+	if _, err := NewWriter(&bytes.Buffer{}, BatchSize(0)); err == nil {
+		t.Errorf("should error on zero batch size")
+	}
+}
+
+type message struct {
+	meta fbTable
+	body []byte
+}
+
+// readMessages reads the encapsulated messages until the end of stream marker.
+func readMessages(t *testing.T, data []byte) []message {
+	var result []message
+	for {
+		if binary.LittleEndian.Uint32(data) != 0xffffffff {
+			t.Fatalf("missing continuation marker")
+		}
+
+		size := int(binary.LittleEndian.Uint32(data[4:]))
+		if size == 0 {
+			if len(data) != 8 {
+				t.Fatalf("data after end of stream")
+			}
+			return result
+		}
+
+		if (8+size)%8 != 0 {
+			t.Fatalf("metadata not padded: %v", size)
+		}
+
+		meta := table(data[8 : 8+size])
+		bodyLength := int(meta.uint64(3))
+		data = data[8+size:]
+
+		result = append(result, message{meta: meta, body: data[:bodyLength]})
+		data = data[bodyLength:]
+	}
+}
+
+func readInt32s(data []byte) []int32 {
+	result := make([]int32, len(data)/4)
+	for i := range result {
+		result[i] = int32(binary.LittleEndian.Uint32(data[4*i:]))
+	}
+
+	return result
+}
+
+func readInt64s(data []byte) []int64 {
+	result := make([]int64, len(data)/8)
+	for i := range result {
+		result[i] = int64(binary.LittleEndian.Uint64(data[8*i:]))
+	}
+
+	return result
+}
+
+func readStrings(offsets, data []byte) []string {
+	o := readInt32s(offsets)
+	result := make([]string, len(o)-1)
+	for i := range result {
+		result[i] = string(data[o[i]:o[i+1]])
+	}
+
+	return result
+}
+
+// fbTable is a minimal FlatBuffers table reader for testing.
+type fbTable struct {
+	buf []byte
+	pos int
+}
+
+func table(buf []byte) fbTable {
+	return fbTable{buf: buf, pos: int(binary.LittleEndian.Uint32(buf))}
+}
+
+func (t fbTable) field(i int) int {
+	vtable := t.pos - int(int32(binary.LittleEndian.Uint32(t.buf[t.pos:])))
+	if 4+2*i >= int(binary.LittleEndian.Uint16(t.buf[vtable:])) {
+		return 0
+	}
+
+	off := int(binary.LittleEndian.Uint16(t.buf[vtable+4+2*i:]))
+	if off == 0 {
+		return 0
+	}
+
+	return t.pos + off
+}
+
+func (t fbTable) indirect(i int) int {
+	pos := t.field(i)
+	if pos == 0 {
+		return 0
+	}
+
+	return pos + int(binary.LittleEndian.Uint32(t.buf[pos:]))
+}
+
+func (t fbTable) uint8(i int) uint8 {
+	if pos := t.field(i); pos != 0 {
+		return t.buf[pos]
+	}
+	return 0
+}
+
+func (t fbTable) uint64(i int) uint64 {
+	if pos := t.field(i); pos != 0 {
+		return binary.LittleEndian.Uint64(t.buf[pos:])
+	}
+	return 0
+}
+
+func (t fbTable) str(i int) string {
+	pos := t.indirect(i)
+	if pos == 0 {
+		return ""
+	}
+
+	l := int(binary.LittleEndian.Uint32(t.buf[pos:]))
+	return string(t.buf[pos+4 : pos+4+l])
+}
+
+// int64s reads a vector of structs of int64 fields as a flat slice.
+func (t fbTable) int64s(i int) []int64 {
+	pos := t.indirect(i)
+	if pos == 0 {
+		return nil
+	}
+
+	if (pos+4)%8 != 0 {
+		panic("structs not aligned")
+	}
+
+	// assumes the structs have two fields
+	l := 2 * int(binary.LittleEndian.Uint32(t.buf[pos:]))
+	return readInt64s(t.buf[pos+4 : pos+4+8*l])
+}
+
+func (t fbTable) table(i int) fbTable {
+	return fbTable{buf: t.buf, pos: t.indirect(i)}
+}
+
+func (t fbTable) tables(i int) []fbTable {
+	pos := t.indirect(i)
+	if pos == 0 {
+		return nil
+	}
+
+	l := int(binary.LittleEndian.Uint32(t.buf[pos:]))
+	result := make([]fbTable, l)
+	for j := range result {
+		p := pos + 4 + 4*j
+		result[j] = fbTable{buf: t.buf, pos: p + int(binary.LittleEndian.Uint32(t.buf[p:]))}
+	}
+
+	return result
+}
@@ -0,0 +1,75 @@
+package osmcsv
+
+import (
+	"errors"
+
+	"github.com/paulmach/osm"
+)
+
+// An Option is a setting for writing the csv output.
+type Option func(*Writer) error
+
+// Keys sets the tag keys written as columns of the same name.
+// By default all the tags are written as json in a `tags` column.
+func Keys(keys ...string) Option {
+	return func(w *Writer) error {
+		for _, k := range keys {
+			for _, c := range baseColumns {
+				if k == c {
+					return errors.New("osmcsv: reserved column name " + k)
+				}
+			}
+		}
+
+		w.keys = keys
+		return nil
+	}
+}
+
+// Types sets the element types to include. By default nodes, ways
+// and relations are written.
+func Types(types ...osm.Type) Option {
+	return func(w *Writer) error {
+		w.types = make(map[osm.Type]bool, len(types))
+		for _, t := range types {
+			if t != osm.TypeNode && t != osm.TypeWay && t != osm.TypeRelation {
+				return errors.New("osmcsv: unsupported type " + string(t))
+			}
+
+			w.types[t] = true
+		}
+
+		return nil
+	}
+}
+
+// Comma sets the field delimiter, use '\t' for tsv output.
+// The default is ','.
+func Comma(r rune) Option {
+	return func(w *Writer) error {
+		w.csv.Comma = r
+		return nil
+	}
+}
+
+// Precision sets the number of decimal places used for the lat and lon
+// columns. The default, -1, uses the smallest number necessary to
+// represent the value exactly.
+func Precision(n int) Option {
+	return func(w *Writer) error {
+		if n < -1 {
+			return errors.New("osmcsv: invalid precision")
+		}
+
+		w.precision = n
+		return nil
+	}
+}
+
+// NoHeader will skip writing the header row with the column names.
+func NoHeader(yes bool) Option {
+	return func(w *Writer) error {
+		w.wroteHeader = yes
+		return nil
+	}
+}
@@ -0,0 +1,151 @@
+// Package osmcsv writes osm elements as rows of csv, or tsv, output
+// for use in spreadsheets and business intelligence tools.
+package osmcsv
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/paulmach/osm"
+)
+
+// baseColumns are written for every element, followed by the tag columns.
+var baseColumns = []string{"type", "id", "version", "timestamp", "user", "lat", "lon"}
+
+// Writer writes osm elements as csv rows. Nodes have a lat and lon,
+// for ways and relations these columns are empty.
+type Writer struct {
+	csv       *csv.Writer
+	keys      []string
+	types     map[osm.Type]bool
+	precision int
+
+	wroteHeader bool
+	row         []string
+}
+
+// NewWriter creates a new writer writing to w.
+func NewWriter(w io.Writer, opts ...Option) (*Writer, error) {
+	cw := &Writer{
+		csv:       csv.NewWriter(w),
+		precision: -1,
+	}
+
+	for _, opt := range opts {
+		if err := opt(cw); err != nil {
+			return nil, err
+		}
+	}
+
+	return cw, nil
+}
+
+func (w *Writer) header() []string {
+	header := append([]string{}, baseColumns...)
+	if len(w.keys) == 0 {
+		return append(header, "tags")
+	}
+
+	return append(header, w.keys...)
+}
+
+// Write writes the node, way or relation as a row. Other types, and
+// types excluded with the Types option, are ignored.
+func (w *Writer) Write(o osm.Object) error {
+	var (
+		version   int
+		typ       osm.Type
+		ref       int64
+		timestamp time.Time
+		user      string
+		lat, lon  string
+		tags      osm.Tags
+	)
+
+	// switch on the concrete type since negative ids,
+	// used for new elements, do not pack into an ObjectID.
+	switch o := o.(type) {
+	case *osm.Node:
+		typ, ref = osm.TypeNode, int64(o.ID)
+		version, timestamp, user, tags = o.Version, o.Timestamp, o.User, o.Tags
+		lat, lon = w.formatCoord(o.Lat), w.formatCoord(o.Lon)
+	case *osm.Way:
+		typ, ref = osm.TypeWay, int64(o.ID)
+		version, timestamp, user, tags = o.Version, o.Timestamp, o.User, o.Tags
+	case *osm.Relation:
+		typ, ref = osm.TypeRelation, int64(o.ID)
+		version, timestamp, user, tags = o.Version, o.Timestamp, o.User, o.Tags
+	default:
+		return nil
+	}
+
+	if w.types != nil && !w.types[typ] {
+		return nil
+	}
+
+	if err := w.writeHeader(); err != nil {
+		return err
+	}
+
+	var ts string
+	if !timestamp.IsZero() {
+		ts = timestamp.UTC().Format(time.RFC3339)
+	}
+
+	w.row = append(w.row[:0],
+		string(typ),
+		strconv.FormatInt(ref, 10),
+		strconv.Itoa(version),
+		ts,
+		user,
+		lat,
+		lon,
+	)
+
+	if len(w.keys) == 0 {
+		data, err := json.Marshal(tags.Map())
+		if err != nil {
+			return err
+		}
+
+		w.row = append(w.row, string(data))
+	}
+
+	for _, k := range w.keys {
+		w.row = append(w.row, tags.Find(k))
+	}
+
+	return w.csv.Write(w.row)
+}
+
+func (w *Writer) formatCoord(v float64) string {
+	return strconv.FormatFloat(v, 'f', w.precision, 64)
+}
+
+func (w *Writer) writeHeader() error {
+	if w.wroteHeader {
+		return nil
+	}
+	w.wroteHeader = true
+
+	return w.csv.Write(w.header())
+}
+
+// Flush writes any buffered data to the underlying writer.
+func (w *Writer) Flush() error {
+	w.csv.Flush()
+	return w.csv.Error()
+}
+
+// Close writes the header, if no elements were written, and flushes
+// the buffered data. It does not close the underlying writer.
+func (w *Writer) Close() error {
+	if err := w.writeHeader(); err != nil {
+		return err
+	}
+
+	return w.Flush()
+}
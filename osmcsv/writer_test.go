@@ -0,0 +1,141 @@
+package osmcsv
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/paulmach/osm"
+)
+
+func testObjects() osm.Objects {
+	ts := time.Date(2018, 1, 2, 3, 4, 5, 0, time.UTC)
+	return osm.Objects{
+		&osm.Node{ID: 1, Lat: 1.5, Lon: 2.123456789, Version: 1, Timestamp: ts, User: "alice",
+			Tags: osm.Tags{{Key: "amenity", Value: "cafe"}, {Key: "name", Value: "Joe's, Bar"}}},
+		&osm.Way{ID: 5, Version: 3, Timestamp: ts, Nodes: osm.WayNodes{{ID: 1}, {ID: 2}},
+			Tags: osm.Tags{{Key: "highway", Value: "primary"}}},
+		&osm.Relation{ID: 6, Version: 4},
+		&osm.Changeset{ID: 7},
+	}
+}
+
+func TestWriter(t *testing.T) {
+	cases := []struct {
+		name     string
+		options  []Option
+		expected string
+	}{
+		{
+			name: "default",
+			expected: `type,id,version,timestamp,user,lat,lon,tags
+node,1,1,2018-01-02T03:04:05Z,alice,1.5,2.123456789,"{""amenity"":""cafe"",""name"":""Joe's, Bar""}"
+way,5,3,2018-01-02T03:04:05Z,,,,"{""highway"":""primary""}"
+relation,6,4,,,,,{}
+`,
+		},
+		{
+			name:    "keys",
+			options: []Option{Keys("name", "highway")},
+			expected: `type,id,version,timestamp,user,lat,lon,name,highway
+node,1,1,2018-01-02T03:04:05Z,alice,1.5,2.123456789,"Joe's, Bar",
+way,5,3,2018-01-02T03:04:05Z,,,,,primary
+relation,6,4,,,,,,
+`,
+		},
+		{
+			name:    "tsv with precision",
+			options: []Option{Comma('\t'), Precision(3), Keys("name"), Types(osm.TypeNode)},
+			expected: "type\tid\tversion\ttimestamp\tuser\tlat\tlon\tname\n" +
+				"node\t1\t1\t2018-01-02T03:04:05Z\talice\t1.500\t2.123\tJoe's, Bar\n",
+		},
+		{
+			name:     "no header",
+			options:  []Option{NoHeader(true), Keys("highway"), Types(osm.TypeWay)},
+			expected: "way,5,3,2018-01-02T03:04:05Z,,,,primary\n",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			w, err := NewWriter(buf, tc.options...)
+			if err != nil {
+				t.Fatalf("new error: %v", err)
+			}
+
+			for _, o := range testObjects() {
+				if err := w.Write(o); err != nil {
+					t.Fatalf("write error: %v", err)
+				}
+			}
+
+			if err := w.Close(); err != nil {
+				t.Fatalf("close error: %v", err)
+			}
+
+			if v := buf.String(); v != tc.expected {
+				t.Errorf("incorrect output:\n%s\nexpected:\n%s", v, tc.expected)
+			}
+		})
+	}
+}
+
+func TestWriter_negativeIDs(t *testing.T) {
+	buf := &bytes.Buffer{}
+	w, err := NewWriter(buf, NoHeader(true), Keys("name"), Types(osm.TypeNode, osm.TypeWay, osm.TypeRelation))
+	if err != nil {
+		t.Fatalf("new error: %v", err)
+	}
+
+	objects := osm.Objects{
+		&osm.Node{ID: -1, Lat: 1, Lon: 2, Tags: osm.Tags{{Key: "name", Value: "new"}}},
+		&osm.Way{ID: -2},
+		&osm.Relation{ID: -3},
+	}
+
+	for _, o := range objects {
+		if err := w.Write(o); err != nil {
+			t.Fatalf("write error: %v", err)
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatalf("close error: %v", err)
+	}
+
+	expected := "node,-1,0,,,1,2,new\nway,-2,0,,,,,\nrelation,-3,0,,,,,\n"
+	if v := buf.String(); v != expected {
+		t.Errorf("incorrect output:\n%s\nexpected:\n%s", v, expected)
+	}
+}
+
+func TestWriter_empty(t *testing.T) {
+	buf := &bytes.Buffer{}
+	w, err := NewWriter(buf, Keys("name"))
+	if err != nil {
+		t.Fatalf("new error: %v", err)
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatalf("close error: %v", err)
+	}
+
+	if v := buf.String(); v != "type,id,version,timestamp,user,lat,lon,name\n" {
+		t.Errorf("should write header: %v", v)
+	}
+}
+
+func TestOptions(t *testing.T) {
+	if _, err := NewWriter(nil, Keys("name", "id")); err == nil {
+		t.Errorf("should error on reserved column")
+	}
+
+	if _, err := NewWriter(nil, Types(osm.TypeChangeset)); err == nil {
+		t.Errorf("should error on unsupported type")
+	}
+
+	if _, err := NewWriter(nil, Precision(-2)); err == nil {
+		t.Errorf("should error on invalid precision")
+	}
+}
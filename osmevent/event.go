@@ -0,0 +1,243 @@
+// Package osmevent converts replication changes into per element events
+// and publishes them to a message broker, like Kafka or NATS, for event
+// driven downstream services.
+package osmevent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/paulmach/orb/maptile"
+	"github.com/paulmach/osm"
+	"github.com/paulmach/osm/replication"
+)
+
+// An Event is the create, modify or delete of one element.
+type Event struct {
+	Action  osm.ActionType
+	Element osm.Element
+}
+
+// Events returns the events for the elements in the change, in the order
+// creates, modifies and deletes, each ordered nodes, ways and relations.
+func Events(c *osm.Change) []Event {
+	var result []Event
+	add := func(action osm.ActionType, o *osm.OSM) {
+		if o == nil {
+			return
+		}
+
+		for _, e := range o.Elements() {
+			result = append(result, Event{Action: action, Element: e})
+		}
+	}
+
+	add(osm.ActionCreate, c.Create)
+	add(osm.ActionModify, c.Modify)
+	add(osm.ActionDelete, c.Delete)
+
+	return result
+}
+
+// Type returns the type of the element, node, way or relation.
+func (e Event) Type() osm.Type {
+	switch e.Element.(type) {
+	case *osm.Node:
+		return osm.TypeNode
+	case *osm.Way:
+		return osm.TypeWay
+	case *osm.Relation:
+		return osm.TypeRelation
+	}
+
+	return ""
+}
+
+// Key is the type and id of the element, e.g. node/123. Using it as
+// the message key keeps the events of an element in order in Kafka.
+func (e Event) Key() string {
+	return fmt.Sprintf("%s/%d", e.Type(), e.Element.ElementID().Ref())
+}
+
+// Tile returns the tile at the zoom containing the element. Ways and
+// relations must be annotated, ok is false if there is no location.
+func (e Event) Tile(z maptile.Zoom) (maptile.Tile, bool) {
+	p, ok := osm.EditCentroid(e.Element)
+	if !ok {
+		return maptile.Tile{}, false
+	}
+
+	return maptile.At(p, z), true
+}
+
+// A Message is an encoded event to be published.
+type Message struct {
+	Topic string
+	Key   string
+	Value []byte
+
+	// Headers are the event metadata, the action, type, id and version,
+	// so consumers can filter without decoding the value.
+	Headers map[string]string
+}
+
+// A Publisher sends messages to a broker. It is implemented by
+// the NATS publisher and can be implemented for a Kafka client.
+type Publisher interface {
+	Publish(ctx context.Context, msgs []Message) error
+}
+
+// A Bridge converts changes to events and publishes them.
+type Bridge struct {
+	publisher Publisher
+	encoding  Encoding
+	topic     func(Event) string
+}
+
+// NewBridge creates a bridge to the publisher. By default the events are
+// JSON encoded and published to per type topics, e.g. osm.node.
+func NewBridge(p Publisher, opts ...Option) (*Bridge, error) {
+	b := &Bridge{
+		publisher: p,
+		encoding:  JSON,
+		topic:     TypeTopic("osm"),
+	}
+
+	for _, opt := range opts {
+		if err := opt(b); err != nil {
+			return nil, err
+		}
+	}
+
+	return b, nil
+}
+
+// Publish publishes the events of the change as one batch.
+func (b *Bridge) Publish(ctx context.Context, c *osm.Change) error {
+	events := Events(c)
+	msgs := make([]Message, 0, len(events))
+	for _, e := range events {
+		m, err := b.Message(e)
+		if err != nil {
+			return err
+		}
+
+		msgs = append(msgs, m)
+	}
+
+	if len(msgs) == 0 {
+		return nil
+	}
+
+	return b.publisher.Publish(ctx, msgs)
+}
+
+// Message encodes the event as a message.
+func (b *Bridge) Message(e Event) (Message, error) {
+	value, err := b.encoding.encode(e)
+	if err != nil {
+		return Message{}, err
+	}
+
+	id := e.Element.ElementID()
+	return Message{
+		Topic: b.topic(e),
+		Key:   e.Key(),
+		Value: value,
+		Headers: map[string]string{
+			"action":  string(e.Action),
+			"type":    string(e.Type()),
+			"id":      fmt.Sprintf("%d", id.Ref()),
+			"version": fmt.Sprintf("%d", id.Version()),
+		},
+	}, nil
+}
+
+// Handler returns a replication.Catchup handler that publishes the changes.
+func (b *Bridge) Handler(ctx context.Context) func(replication.SeqNum, *osm.Change) error {
+	return func(n replication.SeqNum, c *osm.Change) error {
+		return b.Publish(ctx, c)
+	}
+}
+
+// TypeTopic publishes the events to a topic per element type,
+// e.g. osm.node, osm.way and osm.relation for the prefix osm.
+func TypeTopic(prefix string) func(Event) string {
+	return func(e Event) string {
+		return prefix + "." + string(e.Type())
+	}
+}
+
+// TileTopic publishes the events to a topic per tile at the zoom, e.g.
+// osm.12.654.1583. NATS subscribers can then use wildcards to select areas.
+// Events without a location go to the prefix.unknown topic.
+func TileTopic(prefix string, z maptile.Zoom) func(Event) string {
+	return func(e Event) string {
+		t, ok := e.Tile(z)
+		if !ok {
+			return prefix + ".unknown"
+		}
+
+		return fmt.Sprintf("%s.%d.%d.%d", prefix, t.Z, t.X, t.Y)
+	}
+}
+
+// Encoding is the format of the message values.
+type Encoding int
+
+// The supported encodings.
+const (
+	// JSON encodes an envelope with the action and the element.
+	JSON Encoding = iota
+
+	// Protobuf encodes the element using the osmpb format, see
+	// osm.Nodes.Marshal. The action is only in the headers.
+	Protobuf
+)
+
+type jsonEvent struct {
+	Action    osm.ActionType `json:"action"`
+	Type      osm.Type       `json:"type"`
+	Timestamp time.Time      `json:"timestamp"`
+	Element   osm.Element    `json:"element"`
+}
+
+func (enc Encoding) encode(e Event) ([]byte, error) {
+	switch enc {
+	case JSON:
+		return json.Marshal(jsonEvent{
+			Action:    e.Action,
+			Type:      e.Type(),
+			Timestamp: timestamp(e.Element),
+			Element:   e.Element,
+		})
+	case Protobuf:
+		switch el := e.Element.(type) {
+		case *osm.Node:
+			return osm.Nodes{el}.Marshal()
+		case *osm.Way:
+			return osm.Ways{el}.Marshal()
+		case *osm.Relation:
+			return osm.Relations{el}.Marshal()
+		}
+
+		return nil, fmt.Errorf("osmevent: unsupported element type %T", e.Element)
+	}
+
+	return nil, fmt.Errorf("osmevent: unsupported encoding %d", enc)
+}
+
+func timestamp(e osm.Element) time.Time {
+	switch e := e.(type) {
+	case *osm.Node:
+		return e.Timestamp
+	case *osm.Way:
+		return e.Timestamp
+	case *osm.Relation:
+		return e.Timestamp
+	}
+
+	return time.Time{}
+}
@@ -0,0 +1,131 @@
+package osmevent
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/paulmach/osm"
+)
+
+type publisherFunc func(context.Context, []Message) error
+
+func (f publisherFunc) Publish(ctx context.Context, msgs []Message) error {
+	return f(ctx, msgs)
+}
+
+func testChange() *osm.Change {
+	return &osm.Change{
+		Create: &osm.OSM{
+			Nodes: osm.Nodes{{ID: 1, Version: 1, Lat: 37.8, Lon: -122.4}},
+		},
+		Modify: &osm.OSM{
+			Ways: osm.Ways{{ID: 2, Version: 3, Nodes: osm.WayNodes{{ID: 1}}}},
+		},
+		Delete: &osm.OSM{
+			Relations: osm.Relations{{ID: 3, Version: 2}},
+		},
+	}
+}
+
+func TestEvents(t *testing.T) {
+	events := Events(testChange())
+	if len(events) != 3 {
+		t.Fatalf("incorrect number of events: %v", len(events))
+	}
+
+	var keys []string
+	for _, e := range events {
+		keys = append(keys, string(e.Action)+" "+e.Key())
+	}
+
+	expected := []string{"create node/1", "modify way/2", "delete relation/3"}
+	if !reflect.DeepEqual(keys, expected) {
+		t.Errorf("incorrect events: %v", keys)
+	}
+}
+
+func TestBridge(t *testing.T) {
+	ctx := context.Background()
+
+	var published []Message
+	p := publisherFunc(func(ctx context.Context, msgs []Message) error {
+		published = append(published, msgs...)
+		return nil
+	})
+
+	b, err := NewBridge(p)
+	if err != nil {
+		t.Fatalf("bridge error: %v", err)
+	}
+
+	if err := b.Publish(ctx, testChange()); err != nil {
+		t.Fatalf("publish error: %v", err)
+	}
+
+	if len(published) != 3 {
+		t.Fatalf("incorrect number of messages: %v", len(published))
+	}
+
+	m := published[1]
+	if m.Topic != "osm.way" || m.Key != "way/2" || m.Headers["action"] != "modify" || m.Headers["version"] != "3" {
+		t.Errorf("incorrect message: %+v", m)
+	}
+
+	var event struct {
+		Action  string          `json:"action"`
+		Type    string          `json:"type"`
+		Element json.RawMessage `json:"element"`
+	}
+	if err := json.Unmarshal(m.Value, &event); err != nil {
+		t.Fatalf("unmarshal error: %v", err)
+	}
+
+	w := &osm.Way{}
+	if err := json.Unmarshal(event.Element, w); err != nil {
+		t.Fatalf("unmarshal way error: %v", err)
+	}
+
+	if event.Action != "modify" || event.Type != "way" || w.ID != 2 {
+		t.Errorf("incorrect event: %v %v %v", event.Action, event.Type, w.ID)
+	}
+}
+
+func TestBridge_protobuf(t *testing.T) {
+	var published []Message
+	p := publisherFunc(func(ctx context.Context, msgs []Message) error {
+		published = msgs
+		return nil
+	})
+
+	b, err := NewBridge(p, WithEncoding(Protobuf), Topic(TileTopic("osm", 12)))
+	if err != nil {
+		t.Fatalf("bridge error: %v", err)
+	}
+
+	if err := b.Publish(context.Background(), testChange()); err != nil {
+		t.Fatalf("publish error: %v", err)
+	}
+
+	if published[0].Topic != "osm.12.655.1582" {
+		t.Errorf("incorrect tile topic: %v", published[0].Topic)
+	}
+
+	if published[2].Topic != "osm.unknown" {
+		t.Errorf("should be unknown without location: %v", published[2].Topic)
+	}
+
+	nodes, err := osm.UnmarshalNodes(published[0].Value)
+	if err != nil {
+		t.Fatalf("unmarshal error: %v", err)
+	}
+
+	if len(nodes) != 1 || nodes[0].ID != 1 {
+		t.Errorf("incorrect nodes: %v", nodes)
+	}
+
+	if _, err := NewBridge(p, WithEncoding(Encoding(5))); err == nil {
+		t.Errorf("should error on unsupported encoding")
+	}
+}
@@ -0,0 +1,171 @@
+package osmevent
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// NATS publishes messages to a NATS server. It implements the small subset
+// of the NATS client protocol needed to publish, so has no dependencies.
+// Message keys are not used, NATS messages are routed by subject, the topic.
+// Headers are sent if the server supports them. It is safe for concurrent use.
+type NATS struct {
+	mu      sync.Mutex
+	c       net.Conn
+	r       *bufio.Reader
+	w       *bufio.Writer
+	headers bool
+}
+
+var _ Publisher = &NATS{}
+
+// DialNATS connects to the NATS server at the address, e.g. localhost:4222.
+func DialNATS(ctx context.Context, addr string) (*NATS, error) {
+	c, err := (&net.Dialer{}).DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	n, err := newNATS(ctx, c)
+	if err != nil {
+		c.Close()
+		return nil, err
+	}
+
+	return n, nil
+}
+
+func newNATS(ctx context.Context, c net.Conn) (*NATS, error) {
+	n := &NATS{
+		c: c,
+		r: bufio.NewReader(c),
+		w: bufio.NewWriter(c),
+	}
+
+	if err := n.setDeadline(ctx); err != nil {
+		return nil, err
+	}
+
+	line, err := n.r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+
+	if !strings.HasPrefix(line, "INFO ") {
+		return nil, fmt.Errorf("osmevent: unexpected nats greeting: %q", strings.TrimSpace(line))
+	}
+	n.headers = strings.Contains(line, `"headers":true`)
+
+	fmt.Fprintf(n.w, `CONNECT {"verbose":false,"pedantic":false,"headers":%t,"name":"osmevent"}`+"\r\n", n.headers)
+	if err := n.flush(); err != nil {
+		return nil, err
+	}
+
+	return n, nil
+}
+
+// Publish sends the messages and waits for the server
+// to acknowledge them, or return an error.
+func (n *NATS) Publish(ctx context.Context, msgs []Message) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if err := n.setDeadline(ctx); err != nil {
+		return err
+	}
+
+	for _, m := range msgs {
+		if m.Topic == "" || strings.ContainsAny(m.Topic, " \t\r\n") {
+			return fmt.Errorf("osmevent: invalid nats subject %q", m.Topic)
+		}
+
+		if n.headers && len(m.Headers) > 0 {
+			hdr := encodeNATSHeaders(m.Headers)
+			fmt.Fprintf(n.w, "HPUB %s %d %d\r\n", m.Topic, len(hdr), len(hdr)+len(m.Value))
+			n.w.Write(hdr)
+		} else {
+			fmt.Fprintf(n.w, "PUB %s %d\r\n", m.Topic, len(m.Value))
+		}
+
+		n.w.Write(m.Value)
+		n.w.WriteString("\r\n")
+	}
+
+	return n.flush()
+}
+
+// Close closes the connection to the server.
+func (n *NATS) Close() error {
+	return n.c.Close()
+}
+
+// flush writes the buffered commands followed by a ping. The pong confirms
+// the server processed everything before it, an error is sent otherwise.
+func (n *NATS) flush() error {
+	n.w.WriteString("PING\r\n")
+	if err := n.w.Flush(); err != nil {
+		return err
+	}
+
+	// read up to the pong so the connection stays in sync
+	// even if the server reported an error.
+	var result error
+	for {
+		line, err := n.r.ReadString('\n')
+		if err != nil {
+			if result != nil {
+				return result
+			}
+			return err
+		}
+
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "PONG":
+			return result
+		case line == "PING":
+			n.w.WriteString("PONG\r\n")
+			if err := n.w.Flush(); err != nil {
+				return err
+			}
+		case strings.HasPrefix(line, "-ERR"):
+			if result == nil {
+				result = errors.New("osmevent: nats: " + strings.Trim(strings.TrimSpace(line[4:]), "'"))
+			}
+		}
+	}
+}
+
+func (n *NATS) setDeadline(ctx context.Context) error {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Time{}
+	}
+
+	return n.c.SetDeadline(deadline)
+}
+
+func encodeNATSHeaders(h map[string]string) []byte {
+	keys := make([]string, 0, len(h))
+	for k := range h {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	buf := &bytes.Buffer{}
+	buf.WriteString("NATS/1.0\r\n")
+	for _, k := range keys {
+		buf.WriteString(k + ": " + h[k] + "\r\n")
+	}
+	buf.WriteString("\r\n")
+
+	return buf.Bytes()
+}
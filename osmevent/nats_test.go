@@ -0,0 +1,84 @@
+package osmevent
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestNATS(t *testing.T) {
+	ctx := context.Background()
+
+	client, server := net.Pipe()
+	defer client.Close()
+
+	received := make(chan []string, 1)
+	go func() {
+		defer server.Close()
+		r := bufio.NewReader(server)
+
+		server.Write([]byte(`INFO {"server_id":"test","headers":false}` + "\r\n"))
+
+		var lines []string
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			line = strings.TrimSpace(line)
+
+			switch {
+			case line == "PING":
+				if len(lines) > 1 {
+					received <- lines
+				}
+				server.Write([]byte("PONG\r\n"))
+			case strings.HasPrefix(line, "PUB osm.bad"):
+				r.ReadString('\n')
+				server.Write([]byte("-ERR 'Permissions Violation'\r\n"))
+			default:
+				lines = append(lines, line)
+			}
+		}
+	}()
+
+	n, err := newNATS(ctx, client)
+	if err != nil {
+		t.Fatalf("connect error: %v", err)
+	}
+
+	err = n.Publish(ctx, []Message{
+		{Topic: "osm.node", Value: []byte("abc"), Headers: map[string]string{"action": "create"}},
+	})
+	if err != nil {
+		t.Fatalf("publish error: %v", err)
+	}
+
+	lines := <-received
+	expected := []string{
+		`CONNECT {"verbose":false,"pedantic":false,"headers":false,"name":"osmevent"}`,
+		"PUB osm.node 3",
+		"abc",
+	}
+	if strings.Join(lines, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("incorrect protocol: %q", lines)
+	}
+
+	err = n.Publish(ctx, []Message{{Topic: "osm.bad", Value: []byte("x")}})
+	if err == nil || !strings.Contains(err.Error(), "Permissions Violation") {
+		t.Errorf("should return server error: %v", err)
+	}
+
+	if err := n.Publish(ctx, []Message{{Topic: "has space"}}); err == nil {
+		t.Errorf("should error on invalid subject")
+	}
+}
+
+func TestEncodeNATSHeaders(t *testing.T) {
+	h := encodeNATSHeaders(map[string]string{"type": "node", "action": "create"})
+	if string(h) != "NATS/1.0\r\naction: create\r\ntype: node\r\n\r\n" {
+		t.Errorf("incorrect headers: %q", h)
+	}
+}
@@ -0,0 +1,31 @@
+package osmevent
+
+import "errors"
+
+// An Option is a setting for the bridge.
+type Option func(*Bridge) error
+
+// WithEncoding sets the encoding of the message values. The default is JSON.
+func WithEncoding(enc Encoding) Option {
+	return func(b *Bridge) error {
+		if enc != JSON && enc != Protobuf {
+			return errors.New("osmevent: unsupported encoding")
+		}
+
+		b.encoding = enc
+		return nil
+	}
+}
+
+// Topic sets the function that picks the topic, or NATS subject, for each
+// event, see TypeTopic and TileTopic. The default is TypeTopic("osm").
+func Topic(f func(Event) string) Option {
+	return func(b *Bridge) error {
+		if f == nil {
+			return errors.New("osmevent: topic function must not be nil")
+		}
+
+		b.topic = f
+		return nil
+	}
+}
@@ -152,6 +152,17 @@ func (ctx *context) buildPolygon(relation *osm.Relation) *geojson.Feature {
 		}
 	}
 
+	if ctx.rightHandRule {
+		switch g := geometry.(type) {
+		case orb.Polygon:
+			reorient(g)
+		case orb.MultiPolygon:
+			for _, p := range g {
+				reorient(p)
+			}
+		}
+	}
+
 	featureID := tagObject.FeatureID()
 	f := geojson.NewFeature(geometry)
 
@@ -236,7 +247,7 @@ func polygonContains(outer orb.Ring, r orb.Ring) bool {
 }
 
 func reorient(p orb.Polygon) {
-	if p[0].Orientation() != orb.CCW {
+	if len(p[0]) != 0 && p[0].Orientation() != orb.CCW {
 		p[0].Reverse()
 	}
 
@@ -14,6 +14,7 @@ type context struct {
 	noMeta                 bool
 	noRelationMembership   bool
 	includeInvalidPolygons bool
+	rightHandRule          bool
 
 	osm       *osm.OSM
 	skippable map[osm.WayID]struct{}
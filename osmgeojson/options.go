@@ -38,3 +38,14 @@ func IncludeInvalidPolygons(yes bool) Option {
 		return nil
 	}
 }
+
+// RightHandRule will enforce the right-hand rule winding order, outer rings
+// counter-clockwise and inner rings clockwise, on the polygons built from
+// relations. By default the orientation annotated on the members is trusted
+// and may be out of date. Many renderers misbehave on reversed rings.
+func RightHandRule(yes bool) Option {
+	return func(ctx *context) error {
+		ctx.rightHandRule = yes
+		return nil
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/xml"
 	"testing"
 
+	"github.com/paulmach/orb"
 	"github.com/paulmach/orb/geojson"
 	"github.com/paulmach/osm"
 )
@@ -148,6 +149,64 @@ func TestOptionNoRelationMembership(t *testing.T) {
 	})
 }
 
+func TestOptionRightHandRule(t *testing.T) {
+	data := `
+<osm>
+	<relation id="1">
+		<tag k="type" v="multipolygon" />
+		<tag k="building" v="yes" />
+		<member type="way" ref="1" role="outer" />
+	</relation>
+	<way id="1">
+		<nd ref="1" />
+		<nd ref="2" />
+		<nd ref="3" />
+		<nd ref="4" />
+		<nd ref="1" />
+	</way>
+	<node id="1" lat="0" lon="0" />
+	<node id="2" lat="0" lon="1" />
+	<node id="3" lat="1" lon="1" />
+	<node id="4" lat="1" lon="0" />
+</osm>`
+
+	o := &osm.OSM{}
+	if err := xml.Unmarshal([]byte(data), &o); err != nil {
+		t.Fatalf("failed to unmarshal xml: %v", err)
+	}
+
+	// the way is counter-clockwise
+	fc, err := Convert(o)
+	if err != nil {
+		t.Fatalf("failed to convert: %v", err)
+	}
+
+	if v := fc.Features[0].Geometry.(orb.Polygon)[0].Orientation(); v != orb.CCW {
+		t.Errorf("outer ring should be counter-clockwise: %v", v)
+	}
+
+	// the member orientation annotation is wrong
+	o.Relations[0].Members[0].Orientation = orb.CW
+
+	fc, err = Convert(o)
+	if err != nil {
+		t.Fatalf("failed to convert: %v", err)
+	}
+
+	if v := fc.Features[0].Geometry.(orb.Polygon)[0].Orientation(); v != orb.CW {
+		t.Errorf("should use the member orientation: %v", v)
+	}
+
+	fc, err = Convert(o, RightHandRule(true))
+	if err != nil {
+		t.Fatalf("failed to convert: %v", err)
+	}
+
+	if v := fc.Features[0].Geometry.(orb.Polygon)[0].Orientation(); v != orb.CCW {
+		t.Errorf("outer ring should be counter-clockwise: %v", v)
+	}
+}
+
 func convertXML(t *testing.T, data string, opts ...Option) *geojson.FeatureCollection {
 	o := &osm.OSM{}
 	err := xml.Unmarshal([]byte(data), &o)
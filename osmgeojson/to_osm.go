@@ -0,0 +1,244 @@
+package osmgeojson
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geojson"
+	"github.com/paulmach/osm"
+)
+
+// ToOSM converts geojson features into new osm elements, the reverse of
+// Convert, for example to import external data. The elements get negative
+// ids from the allocator, a zero allocator is used if nil, and can be added
+// to an osm.ChangeBuilder as creates.
+//
+// Points become tagged nodes. Line strings and single ring polygons become
+// tagged ways. Polygons with holes and multi polygons become multipolygon
+// relations with untagged member ways. Vertices at the same location are
+// shared between all the ways.
+//
+// The tags are taken from the "tags" property if present, as output by
+// Convert, otherwise from all the string, number and boolean properties.
+func ToOSM(fc *geojson.FeatureCollection, a *osm.IDAllocator) (*osm.OSM, error) {
+	if a == nil {
+		a = &osm.IDAllocator{}
+	}
+
+	ctx := &importContext{
+		alloc:    a,
+		vertices: make(map[orb.Point]osm.NodeID),
+		result:   &osm.OSM{},
+	}
+
+	for i, f := range fc.Features {
+		if f.Geometry == nil {
+			return nil, fmt.Errorf("osmgeojson: feature %d has no geometry", i)
+		}
+
+		if err := ctx.add(f.Geometry, propertiesToTags(f.Properties)); err != nil {
+			return nil, fmt.Errorf("osmgeojson: feature %d: %v", i, err)
+		}
+	}
+
+	return ctx.result, nil
+}
+
+type importContext struct {
+	alloc    *osm.IDAllocator
+	vertices map[orb.Point]osm.NodeID
+	result   *osm.OSM
+}
+
+func (ctx *importContext) add(g orb.Geometry, tags osm.Tags) error {
+	switch g := g.(type) {
+	case orb.Point:
+		ctx.result.Append(ctx.alloc.NewNode(g.Lat(), g.Lon(), tags))
+	case orb.MultiPoint:
+		for _, p := range g {
+			ctx.result.Append(ctx.alloc.NewNode(p.Lat(), p.Lon(), copyTags(tags)))
+		}
+	case orb.LineString:
+		return ctx.addWay(g, tags)
+	case orb.MultiLineString:
+		for _, ls := range g {
+			if err := ctx.addWay(ls, copyTags(tags)); err != nil {
+				return err
+			}
+		}
+	case orb.Ring:
+		return ctx.addPolygon(orb.Polygon{g}, tags)
+	case orb.Polygon:
+		return ctx.addPolygon(g, tags)
+	case orb.Bound:
+		return ctx.addPolygon(g.ToPolygon(), tags)
+	case orb.MultiPolygon:
+		return ctx.addMultiPolygon(g, tags)
+	case orb.Collection:
+		for _, c := range g {
+			if err := ctx.add(c, copyTags(tags)); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("unsupported geometry type %T", g)
+	}
+
+	return nil
+}
+
+func (ctx *importContext) addWay(ls orb.LineString, tags osm.Tags) error {
+	w := ctx.newWay(ls, tags)
+	if len(w.Nodes) < 2 {
+		return fmt.Errorf("line string must have at least 2 distinct points")
+	}
+
+	ctx.result.Append(w)
+	return nil
+}
+
+func (ctx *importContext) addPolygon(p orb.Polygon, tags osm.Tags) error {
+	if len(p) == 1 {
+		w, err := ctx.newRing(p[0], tags)
+		if err != nil {
+			return err
+		}
+
+		ctx.result.Append(w)
+		return nil
+	}
+
+	return ctx.addMultiPolygon(orb.MultiPolygon{p}, tags)
+}
+
+func (ctx *importContext) addMultiPolygon(mp orb.MultiPolygon, tags osm.Tags) error {
+	var members []osm.Member
+	for _, p := range mp {
+		for i, r := range p {
+			w, err := ctx.newRing(r, nil)
+			if err != nil {
+				return err
+			}
+			ctx.result.Append(w)
+
+			role := "inner"
+			if i == 0 {
+				role = "outer"
+			}
+
+			members = append(members, osm.Member{Type: osm.TypeWay, Ref: int64(w.ID), Role: role})
+		}
+	}
+
+	if len(members) == 0 {
+		return fmt.Errorf("multipolygon must have at least one ring")
+	}
+
+	tags = append(osm.Tags{{Key: "type", Value: "multipolygon"}}, tags...)
+	ctx.result.Append(ctx.alloc.NewRelation(tags, members...))
+
+	return nil
+}
+
+func (ctx *importContext) newRing(r orb.Ring, tags osm.Tags) (*osm.Way, error) {
+	if len(r) != 0 && r[0] != r[len(r)-1] {
+		r = append(r[:len(r):len(r)], r[0])
+	}
+
+	w := ctx.newWay(orb.LineString(r), tags)
+	if len(w.Nodes) < 4 {
+		return nil, fmt.Errorf("ring must have at least 3 distinct points")
+	}
+
+	return w, nil
+}
+
+// newWay creates a way reusing the nodes at the same location,
+// consecutive duplicate points are removed.
+func (ctx *importContext) newWay(ls orb.LineString, tags osm.Tags) *osm.Way {
+	w := ctx.alloc.NewWay(tags)
+	for _, p := range ls {
+		id := ctx.vertex(p)
+		if l := len(w.Nodes); l > 0 && w.Nodes[l-1].ID == id {
+			continue
+		}
+
+		w.Nodes = append(w.Nodes, osm.WayNode{ID: id, Lat: p.Lat(), Lon: p.Lon()})
+	}
+
+	return w
+}
+
+func (ctx *importContext) vertex(p orb.Point) osm.NodeID {
+	if id, ok := ctx.vertices[p]; ok {
+		return id
+	}
+
+	n := ctx.alloc.NewNode(p.Lat(), p.Lon(), nil)
+	ctx.vertices[p] = n.ID
+	ctx.result.Append(n)
+
+	return n.ID
+}
+
+func propertiesToTags(props geojson.Properties) osm.Tags {
+	switch t := props["tags"].(type) {
+	case map[string]string:
+		tags := make(osm.Tags, 0, len(t))
+		for k, v := range t {
+			tags = append(tags, osm.Tag{Key: k, Value: v})
+		}
+		return sortTags(tags)
+	case map[string]interface{}:
+		return valuesToTags(t)
+	}
+
+	return valuesToTags(props)
+}
+
+func valuesToTags(values map[string]interface{}) osm.Tags {
+	tags := make(osm.Tags, 0, len(values))
+	for k, v := range values {
+		var s string
+		switch v := v.(type) {
+		case string:
+			s = v
+		case float64:
+			s = strconv.FormatFloat(v, 'f', -1, 64)
+		case int:
+			s = strconv.Itoa(v)
+		case bool:
+			s = "no"
+			if v {
+				s = "yes"
+			}
+		default:
+			continue
+		}
+
+		if s != "" {
+			tags = append(tags, osm.Tag{Key: k, Value: s})
+		}
+	}
+
+	return sortTags(tags)
+}
+
+func sortTags(tags osm.Tags) osm.Tags {
+	sort.Slice(tags, func(i, j int) bool { return tags[i].Key < tags[j].Key })
+	if len(tags) == 0 {
+		return nil
+	}
+
+	return tags
+}
+
+func copyTags(tags osm.Tags) osm.Tags {
+	if tags == nil {
+		return nil
+	}
+
+	return append(osm.Tags(nil), tags...)
+}
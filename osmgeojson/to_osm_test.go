@@ -0,0 +1,167 @@
+package osmgeojson
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geojson"
+	"github.com/paulmach/osm"
+)
+
+func TestToOSM(t *testing.T) {
+	fc := geojson.NewFeatureCollection()
+
+	point := geojson.NewFeature(orb.Point{1, 2})
+	point.Properties["name"] = "shop"
+	point.Properties["levels"] = 2.0
+	point.Properties["wheelchair"] = true
+	point.Properties["ignored"] = []interface{}{1}
+	fc.Append(point)
+
+	line := geojson.NewFeature(orb.LineString{{0, 0}, {1, 0}, {1, 0}, {1, 1}})
+	line.Properties["tags"] = map[string]interface{}{"highway": "residential"}
+	fc.Append(line)
+
+	// shares the vertex at 1, 1
+	square := geojson.NewFeature(orb.Polygon{{{1, 1}, {2, 1}, {2, 2}, {1, 2}, {1, 1}}})
+	square.Properties["building"] = "yes"
+	fc.Append(square)
+
+	o, err := ToOSM(fc, nil)
+	if err != nil {
+		t.Fatalf("convert error: %v", err)
+	}
+
+	if len(o.Nodes) != 1+3+3 || len(o.Ways) != 2 || len(o.Relations) != 0 {
+		t.Fatalf("incorrect elements: %d nodes, %d ways, %d relations", len(o.Nodes), len(o.Ways), len(o.Relations))
+	}
+
+	n := o.Nodes[0]
+	expectedTags := osm.Tags{
+		{Key: "levels", Value: "2"},
+		{Key: "name", Value: "shop"},
+		{Key: "wheelchair", Value: "yes"},
+	}
+	if n.ID != -1 || n.Lon != 1 || n.Lat != 2 || !reflect.DeepEqual(n.Tags, expectedTags) {
+		t.Errorf("incorrect node: %+v", n)
+	}
+
+	w := o.Ways[0]
+	if v := w.Tags.Find("highway"); v != "residential" {
+		t.Errorf("incorrect way tags: %v", w.Tags)
+	}
+
+	if ids := w.Nodes.NodeIDs(); !reflect.DeepEqual(ids, []osm.NodeID{-3, -4, -5}) {
+		t.Errorf("should remove duplicate points: %v", ids)
+	}
+
+	w = o.Ways[1]
+	if ids := w.Nodes.NodeIDs(); !reflect.DeepEqual(ids, []osm.NodeID{-5, -7, -8, -9, -5}) {
+		t.Errorf("should share vertices and be closed: %v", ids)
+	}
+
+	for _, n := range o.Nodes[1:] {
+		if len(n.Tags) != 0 {
+			t.Errorf("vertices should not have tags: %v", n.Tags)
+		}
+	}
+}
+
+func TestToOSM_multiPolygon(t *testing.T) {
+	fc := geojson.NewFeatureCollection()
+
+	// rings are closed if needed
+	f := geojson.NewFeature(orb.MultiPolygon{
+		{
+			{{0, 0}, {10, 0}, {10, 10}, {0, 10}},
+			{{1, 1}, {1, 2}, {2, 2}, {1, 1}},
+		},
+		{
+			{{20, 20}, {30, 20}, {30, 30}, {20, 20}},
+		},
+	})
+	f.Properties["landuse"] = "forest"
+	fc.Append(f)
+
+	o, err := ToOSM(fc, osm.NewIDAllocator(-100))
+	if err != nil {
+		t.Fatalf("convert error: %v", err)
+	}
+
+	if len(o.Ways) != 3 || len(o.Relations) != 1 {
+		t.Fatalf("incorrect elements: %d ways, %d relations", len(o.Ways), len(o.Relations))
+	}
+
+	for _, w := range o.Ways {
+		if w.Nodes[0].ID != w.Nodes[len(w.Nodes)-1].ID {
+			t.Errorf("way should be closed: %v", w.Nodes.NodeIDs())
+		}
+
+		if len(w.Tags) != 0 {
+			t.Errorf("member ways should not have tags: %v", w.Tags)
+		}
+	}
+
+	r := o.Relations[0]
+	expectedTags := osm.Tags{{Key: "type", Value: "multipolygon"}, {Key: "landuse", Value: "forest"}}
+	if !reflect.DeepEqual(r.Tags, expectedTags) {
+		t.Errorf("incorrect relation tags: %v", r.Tags)
+	}
+
+	roles := []string{}
+	for _, m := range r.Members {
+		if m.Type != osm.TypeWay || m.Ref > -100 {
+			t.Errorf("incorrect member: %+v", m)
+		}
+		roles = append(roles, m.Role)
+	}
+
+	if !reflect.DeepEqual(roles, []string{"outer", "inner", "outer"}) {
+		t.Errorf("incorrect roles: %v", roles)
+	}
+}
+
+func TestToOSM_errors(t *testing.T) {
+	cases := []struct {
+		name     string
+		geometry orb.Geometry
+	}{
+		{name: "no geometry"},
+		{name: "short line", geometry: orb.LineString{{1, 1}, {1, 1}}},
+		{name: "short ring", geometry: orb.Polygon{{{1, 1}, {2, 2}, {1, 1}}}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			fc := geojson.NewFeatureCollection()
+			fc.Append(geojson.NewFeature(tc.geometry))
+
+			if _, err := ToOSM(fc, nil); err == nil {
+				t.Errorf("should return error")
+			}
+		})
+	}
+}
+
+func TestToOSM_roundTrip(t *testing.T) {
+	o := &osm.OSM{
+		Nodes: osm.Nodes{
+			{ID: 1, Lat: 1, Lon: 1, Version: 1, Tags: osm.Tags{{Key: "amenity", Value: "cafe"}}},
+		},
+	}
+
+	fc, err := Convert(o)
+	if err != nil {
+		t.Fatalf("convert error: %v", err)
+	}
+
+	result, err := ToOSM(fc, nil)
+	if err != nil {
+		t.Fatalf("to osm error: %v", err)
+	}
+
+	if len(result.Nodes) != 1 || !reflect.DeepEqual(result.Nodes[0].Tags, o.Nodes[0].Tags) {
+		t.Errorf("incorrect round trip: %v", result.Nodes)
+	}
+}
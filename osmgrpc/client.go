@@ -0,0 +1,229 @@
+package osmgrpc
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/paulmach/osm"
+)
+
+// A Client calls a gRPC datasource server. It implements the
+// osm.HistoryDatasourcer interface so a remote store can be used, for
+// example, to annotate changes. The http.Client must support HTTP/2.
+type Client struct {
+	BaseURL string
+	Client  *http.Client
+}
+
+var _ osm.HistoryDatasourcer = &Client{}
+var _ Mapper = &Client{}
+
+// NewClient creates a client for the server at the base url, e.g.
+// https://localhost:8443. It uses the http.DefaultClient if nil.
+func NewClient(baseURL string, c *http.Client) *Client {
+	if c == nil {
+		c = http.DefaultClient
+	}
+
+	return &Client{
+		BaseURL: strings.TrimSuffix(baseURL, "/"),
+		Client:  c,
+	}
+}
+
+// Element returns the version of the element, or the latest
+// visible version if the version of the id is zero.
+func (c *Client) Element(ctx context.Context, id osm.ElementID) (osm.Element, error) {
+	o, err := c.call(ctx, "Element", elementRequest(id.Type(), id.Ref(), id.Version()))
+	if err != nil {
+		return nil, err
+	}
+
+	es := o.Elements()
+	if len(es) != 1 {
+		return nil, statusf(Internal, "expected one element, got %d", len(es))
+	}
+
+	return es[0], nil
+}
+
+// NodeHistory returns all the versions of the node.
+func (c *Client) NodeHistory(ctx context.Context, id osm.NodeID) (osm.Nodes, error) {
+	o, err := c.call(ctx, "History", elementRequest(osm.TypeNode, int64(id), 0))
+	if err != nil {
+		return nil, err
+	}
+
+	return o.Nodes, nil
+}
+
+// WayHistory returns all the versions of the way.
+func (c *Client) WayHistory(ctx context.Context, id osm.WayID) (osm.Ways, error) {
+	o, err := c.call(ctx, "History", elementRequest(osm.TypeWay, int64(id), 0))
+	if err != nil {
+		return nil, err
+	}
+
+	return o.Ways, nil
+}
+
+// RelationHistory returns all the versions of the relation.
+func (c *Client) RelationHistory(ctx context.Context, id osm.RelationID) (osm.Relations, error) {
+	o, err := c.call(ctx, "History", elementRequest(osm.TypeRelation, int64(id), 0))
+	if err != nil {
+		return nil, err
+	}
+
+	return o.Relations, nil
+}
+
+// Map returns the elements within the bounds.
+func (c *Client) Map(ctx context.Context, b *osm.Bounds) (*osm.OSM, error) {
+	var req []byte
+	req = appendDouble(req, 1, b.MinLat)
+	req = appendDouble(req, 2, b.MaxLat)
+	req = appendDouble(req, 3, b.MinLon)
+	req = appendDouble(req, 4, b.MaxLon)
+
+	return c.call(ctx, "Map", req)
+}
+
+// Changes calls the function for every change published by the server
+// until the context is canceled, the function returns an error or
+// the stream is closed.
+func (c *Client) Changes(ctx context.Context, f func(*osm.Change) error) error {
+	resp, err := c.do(ctx, "Changes", nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	for {
+		data, err := readMessage(resp.Body)
+		if err == io.EOF {
+			return trailerStatus(resp)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		change, err := osm.UnmarshalChange(data)
+		if err != nil {
+			return err
+		}
+
+		if err := f(change); err != nil {
+			return err
+		}
+	}
+}
+
+// NotFound returns true if the error is a NOT_FOUND status.
+func (c *Client) NotFound(err error) bool {
+	s, ok := err.(*Status)
+	return ok && s.Code == NotFound
+}
+
+func (c *Client) call(ctx context.Context, method string, req []byte) (*osm.OSM, error) {
+	resp, err := c.do(ctx, method, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := readMessage(resp.Body)
+	if err == io.EOF {
+		// no message, the trailers have the error
+		if err := trailerStatus(resp); err != nil {
+			return nil, err
+		}
+
+		return nil, statusf(Internal, "missing response message")
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	// read to the end for the trailers
+	io.Copy(ioutil.Discard, resp.Body)
+	if err := trailerStatus(resp); err != nil {
+		return nil, err
+	}
+
+	return osm.UnmarshalOSM(data)
+}
+
+func (c *Client) do(ctx context.Context, method string, req []byte) (*http.Response, error) {
+	body := &bytes.Buffer{}
+	writeMessage(body, req)
+
+	r, err := http.NewRequest("POST", c.BaseURL+"/"+ServiceName+"/"+method, body)
+	if err != nil {
+		return nil, err
+	}
+	r = r.WithContext(ctx)
+
+	r.Header.Set("Content-Type", "application/grpc+proto")
+	r.Header.Set("TE", "trailers")
+
+	resp, err := c.Client.Do(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, statusf(Unavailable, "unexpected http status code %d", resp.StatusCode)
+	}
+
+	return resp, nil
+}
+
+// trailerStatus returns the error in the trailers, or the headers for
+// trailers only responses. Must be called after the body is read.
+func trailerStatus(resp *http.Response) error {
+	code := resp.Trailer.Get("Grpc-Status")
+	msg := resp.Trailer.Get("Grpc-Message")
+	if code == "" {
+		code = resp.Header.Get("Grpc-Status")
+		msg = resp.Header.Get("Grpc-Message")
+	}
+
+	if code == "" {
+		return statusf(Internal, "missing grpc-status")
+	}
+
+	c, err := strconv.Atoi(code)
+	if err != nil {
+		return statusf(Internal, "invalid grpc-status %q", code)
+	}
+
+	if c == int(OK) {
+		return nil
+	}
+
+	if m, err := url.PathUnescape(msg); err == nil {
+		msg = m
+	}
+
+	return &Status{Code: Code(c), Message: msg}
+}
+
+func elementRequest(t osm.Type, id int64, version int) []byte {
+	var req []byte
+	req = appendBytes(req, 1, []byte(t))
+	req = appendVarint(req, 2, uint64(id))
+	if version != 0 {
+		req = appendVarint(req, 3, uint64(version))
+	}
+
+	return req
+}
@@ -0,0 +1,362 @@
+// Package osmgrpc serves a datasource over gRPC, so services not written in
+// go can consume an osm store managed by a go service. The messages are the
+// osm protobuf encoding, see datasource.proto for the service definition.
+package osmgrpc
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/paulmach/osm"
+	"github.com/paulmach/osm/replication"
+)
+
+// ServiceName is the full name of the gRPC service.
+const ServiceName = "osm.grpc.Datasource"
+
+// A Datasource is the element store served by the server.
+type Datasource interface {
+	osm.HistoryDatasourcer
+}
+
+// A Mapper is a datasource that supports querying the elements within
+// bounds, like osmapi.Datasource. The Map rpc is unimplemented otherwise.
+type Mapper interface {
+	Map(context.Context, *osm.Bounds) (*osm.OSM, error)
+}
+
+// A Server implements the gRPC protocol on top of net/http. gRPC requires
+// HTTP/2, so the http.Server must use TLS, or an h2c handler like
+// golang.org/x/net/http2/h2c for unencrypted connections.
+type Server struct {
+	ds Datasource
+
+	mu      sync.Mutex
+	streams map[chan []byte]struct{}
+}
+
+var _ http.Handler = &Server{}
+
+// NewServer creates a server for the datasource.
+func NewServer(ds Datasource) *Server {
+	return &Server{
+		ds:      ds,
+		streams: make(map[chan []byte]struct{}),
+	}
+}
+
+// streamBuffer is the number of changes buffered for each Changes stream.
+// Streams that fall further behind are closed with RESOURCE_EXHAUSTED.
+const streamBuffer = 16
+
+// Publish sends the change to all open Changes streams.
+func (s *Server) Publish(c *osm.Change) error {
+	data, err := c.Marshal()
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for ch := range s.streams {
+		select {
+		case ch <- data:
+		default:
+			// too slow, close it so the client can reconnect
+			delete(s.streams, ch)
+			close(ch)
+		}
+	}
+
+	return nil
+}
+
+// Handler returns a replication.Catchup handler that
+// publishes the changes to the Changes streams.
+func (s *Server) Handler() func(replication.SeqNum, *osm.Change) error {
+	return func(n replication.SeqNum, c *osm.Change) error {
+		return s.Publish(c)
+	}
+}
+
+// ServeHTTP handles the gRPC requests.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" || !strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+		http.Error(w, "osmgrpc: gRPC requests only", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/grpc+proto")
+	w.Header().Set("Trailer", "Grpc-Status, Grpc-Message")
+
+	ctx := r.Context()
+	if t, ok := parseTimeout(r.Header.Get("Grpc-Timeout")); ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, t)
+		defer cancel()
+	}
+
+	err := s.serve(ctx, w, r)
+	if err == nil {
+		w.Header().Set("Grpc-Status", "0")
+		return
+	}
+
+	st, ok := err.(*Status)
+	if !ok {
+		st = s.status(ctx, err)
+	}
+
+	w.Header().Set("Grpc-Status", strconv.Itoa(int(st.Code)))
+	w.Header().Set("Grpc-Message", encodeMessage(st.Message))
+}
+
+func (s *Server) serve(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	prefix := "/" + ServiceName + "/"
+	if !strings.HasPrefix(r.URL.Path, prefix) {
+		return statusf(Unimplemented, "unknown service %s", strings.TrimPrefix(r.URL.Path, "/"))
+	}
+
+	data, err := readMessage(r.Body)
+	if err != nil {
+		return statusf(InvalidArgument, "%v", err)
+	}
+
+	fields, err := decodeFields(data)
+	if err != nil {
+		return statusf(InvalidArgument, "%v", err)
+	}
+
+	var result *osm.OSM
+	switch method := r.URL.Path[len(prefix):]; method {
+	case "Element":
+		result, err = s.element(ctx, fields)
+	case "History":
+		result, err = s.history(ctx, fields)
+	case "Map":
+		result, err = s.mapBounds(ctx, fields)
+	case "Changes":
+		return s.changes(ctx, w)
+	default:
+		return statusf(Unimplemented, "unknown method %s", method)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	data, err = result.Marshal()
+	if err != nil {
+		return err
+	}
+
+	return writeMessage(w, data)
+}
+
+func (s *Server) element(ctx context.Context, fields []field) (*osm.OSM, error) {
+	history, version, err := s.elementHistory(ctx, fields)
+	if err != nil {
+		return nil, err
+	}
+
+	var result osm.Element
+	for _, e := range history {
+		v := e.ElementID().Version()
+		if version != 0 && v == version {
+			result = e
+			break
+		}
+
+		if version == 0 && (result == nil || v > result.ElementID().Version()) {
+			result = e
+		}
+	}
+
+	if result == nil {
+		return nil, statusf(NotFound, "version %d not found", version)
+	}
+
+	if version == 0 && !visible(result) {
+		return nil, statusf(NotFound, "element deleted")
+	}
+
+	o := &osm.OSM{}
+	o.Append(result)
+	return o, nil
+}
+
+func (s *Server) history(ctx context.Context, fields []field) (*osm.OSM, error) {
+	history, _, err := s.elementHistory(ctx, fields)
+	if err != nil {
+		return nil, err
+	}
+
+	o := &osm.OSM{}
+	for _, e := range history {
+		o.Append(e)
+	}
+
+	return o, nil
+}
+
+// elementHistory loads the history of the element in the ElementRequest.
+func (s *Server) elementHistory(ctx context.Context, fields []field) (osm.Elements, int, error) {
+	var (
+		typ     osm.Type
+		id      int64
+		version int
+	)
+
+	for _, f := range fields {
+		switch f.num {
+		case 1:
+			typ = osm.Type(f.bytes)
+		case 2:
+			id = int64(f.varint)
+		case 3:
+			version = int(int32(f.varint))
+		}
+	}
+
+	var result osm.Elements
+	switch typ {
+	case osm.TypeNode:
+		nodes, err := s.ds.NodeHistory(ctx, osm.NodeID(id))
+		if err != nil {
+			return nil, 0, err
+		}
+
+		for _, n := range nodes {
+			result = append(result, n)
+		}
+	case osm.TypeWay:
+		ways, err := s.ds.WayHistory(ctx, osm.WayID(id))
+		if err != nil {
+			return nil, 0, err
+		}
+
+		for _, w := range ways {
+			result = append(result, w)
+		}
+	case osm.TypeRelation:
+		relations, err := s.ds.RelationHistory(ctx, osm.RelationID(id))
+		if err != nil {
+			return nil, 0, err
+		}
+
+		for _, r := range relations {
+			result = append(result, r)
+		}
+	default:
+		return nil, 0, statusf(InvalidArgument, "invalid type %q", typ)
+	}
+
+	if len(result) == 0 {
+		return nil, 0, statusf(NotFound, "%s %d not found", typ, id)
+	}
+
+	return result, version, nil
+}
+
+func (s *Server) mapBounds(ctx context.Context, fields []field) (*osm.OSM, error) {
+	m, ok := s.ds.(Mapper)
+	if !ok {
+		return nil, statusf(Unimplemented, "datasource does not support map queries")
+	}
+
+	b := &osm.Bounds{}
+	for _, f := range fields {
+		v := math.Float64frombits(f.fixed64)
+		switch f.num {
+		case 1:
+			b.MinLat = v
+		case 2:
+			b.MaxLat = v
+		case 3:
+			b.MinLon = v
+		case 4:
+			b.MaxLon = v
+		}
+	}
+
+	if b.MinLat > b.MaxLat || b.MinLon > b.MaxLon {
+		return nil, statusf(InvalidArgument, "invalid bounds")
+	}
+
+	return m.Map(ctx, b)
+}
+
+func (s *Server) changes(ctx context.Context, w http.ResponseWriter) error {
+	ch := make(chan []byte, streamBuffer)
+
+	s.mu.Lock()
+	s.streams[ch] = struct{}{}
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		if _, ok := s.streams[ch]; ok {
+			delete(s.streams, ch)
+			close(ch)
+		}
+		s.mu.Unlock()
+	}()
+
+	// send the headers so the client knows the stream is open.
+	w.WriteHeader(http.StatusOK)
+	flush(w)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case data, ok := <-ch:
+			if !ok {
+				return statusf(ResourceExhausted, "stream fell behind")
+			}
+
+			if err := writeMessage(w, data); err != nil {
+				return err
+			}
+			flush(w)
+		}
+	}
+}
+
+// status converts datasource and context errors to a gRPC status.
+func (s *Server) status(ctx context.Context, err error) *Status {
+	switch {
+	case s.ds.NotFound(err):
+		return &Status{Code: NotFound, Message: err.Error()}
+	case err == context.Canceled:
+		return &Status{Code: Canceled, Message: err.Error()}
+	case err == context.DeadlineExceeded:
+		return &Status{Code: DeadlineExceeded, Message: err.Error()}
+	}
+
+	return &Status{Code: Internal, Message: err.Error()}
+}
+
+func flush(w http.ResponseWriter) {
+	if f, ok := w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func visible(e osm.Element) bool {
+	switch e := e.(type) {
+	case *osm.Node:
+		return e.Visible
+	case *osm.Way:
+		return e.Visible
+	case *osm.Relation:
+		return e.Visible
+	}
+
+	return false
+}
@@ -0,0 +1,239 @@
+package osmgrpc
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/paulmach/osm"
+)
+
+type mapDatasource struct {
+	*osm.HistoryDatasource
+}
+
+func (ds *mapDatasource) Map(ctx context.Context, b *osm.Bounds) (*osm.OSM, error) {
+	o := &osm.OSM{}
+	for _, nodes := range ds.Nodes {
+		n := nodes[len(nodes)-1]
+		if n.Visible && b.ContainsNode(n) {
+			o.Append(n)
+		}
+	}
+
+	return o, nil
+}
+
+// testServer starts a test server. It uses HTTP/1.1 with chunked encoding,
+// which also supports trailers, so the tests work without TLS.
+func testServer(ds Datasource) (*Server, *Client, func()) {
+	s := NewServer(ds)
+	ts := httptest.NewServer(s)
+
+	return s, NewClient(ts.URL, ts.Client()), ts.Close
+}
+
+func testDatasource() *osm.HistoryDatasource {
+	ds := &osm.HistoryDatasource{}
+	ds.Add(context.Background(),
+		&osm.Node{ID: 1, Version: 1, Visible: true, Lat: 1, Lon: 1},
+		&osm.Node{ID: 1, Version: 2, Visible: true, Lat: 1.5, Lon: 1.5},
+		&osm.Node{ID: 2, Version: 1, Visible: true, Lat: 5, Lon: 5},
+		&osm.Node{ID: 2, Version: 2, Visible: false},
+		&osm.Way{ID: 3, Version: 1, Visible: true, Nodes: osm.WayNodes{{ID: 1}, {ID: 2}}},
+	)
+
+	return ds
+}
+
+func TestServer_Element(t *testing.T) {
+	_, c, close := testServer(testDatasource())
+	defer close()
+	ctx := context.Background()
+
+	cases := []struct {
+		name    string
+		id      osm.ElementID
+		version int
+		code    Code
+	}{
+		{name: "latest", id: osm.NodeID(1).ElementID(0), version: 2},
+		{name: "version", id: osm.NodeID(1).ElementID(1), version: 1},
+		{name: "way", id: osm.WayID(3).ElementID(0), version: 1},
+		{name: "deleted", id: osm.NodeID(2).ElementID(0), code: NotFound},
+		{name: "deleted version", id: osm.NodeID(2).ElementID(1), version: 1},
+		{name: "missing version", id: osm.NodeID(1).ElementID(5), code: NotFound},
+		{name: "missing", id: osm.RelationID(4).ElementID(0), code: NotFound},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			e, err := c.Element(ctx, tc.id)
+			if tc.code != OK {
+				if s, ok := err.(*Status); !ok || s.Code != tc.code {
+					t.Fatalf("incorrect error: %v", err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("element error: %v", err)
+			}
+
+			id := e.ElementID()
+			if id.FeatureID() != tc.id.FeatureID() || id.Version() != tc.version {
+				t.Errorf("incorrect element: %v", id)
+			}
+		})
+	}
+}
+
+func TestServer_History(t *testing.T) {
+	_, c, close := testServer(testDatasource())
+	defer close()
+	ctx := context.Background()
+
+	nodes, err := c.NodeHistory(ctx, 2)
+	if err != nil {
+		t.Fatalf("history error: %v", err)
+	}
+
+	if len(nodes) != 2 || nodes[1].Visible {
+		t.Errorf("incorrect history: %v", nodes)
+	}
+
+	_, err = c.WayHistory(ctx, 10)
+	if !c.NotFound(err) {
+		t.Errorf("expected not found: %v", err)
+	}
+}
+
+func TestServer_Map(t *testing.T) {
+	ctx := context.Background()
+	b := &osm.Bounds{MinLat: 0, MaxLat: 2, MinLon: 0, MaxLon: 2}
+
+	_, c, close := testServer(testDatasource())
+	defer close()
+
+	_, err := c.Map(ctx, b)
+	if s, ok := err.(*Status); !ok || s.Code != Unimplemented {
+		t.Errorf("expected unimplemented: %v", err)
+	}
+
+	_, c, close2 := testServer(&mapDatasource{testDatasource()})
+	defer close2()
+
+	o, err := c.Map(ctx, b)
+	if err != nil {
+		t.Fatalf("map error: %v", err)
+	}
+
+	if len(o.Nodes) != 1 || o.Nodes[0].ID != 1 {
+		t.Errorf("incorrect nodes: %v", o.Nodes)
+	}
+
+	_, err = c.Map(ctx, &osm.Bounds{MinLat: 2, MaxLat: 0})
+	if s, ok := err.(*Status); !ok || s.Code != InvalidArgument {
+		t.Errorf("expected invalid argument: %v", err)
+	}
+}
+
+func TestServer_Changes(t *testing.T) {
+	s, c, close := testServer(testDatasource())
+	defer close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	received := make(chan *osm.Change)
+	done := make(chan error)
+	go func() {
+		done <- c.Changes(ctx, func(change *osm.Change) error {
+			received <- change
+			return nil
+		})
+	}()
+
+	// wait for the stream to be registered
+	for {
+		s.mu.Lock()
+		l := len(s.streams)
+		s.mu.Unlock()
+		if l == 1 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	change := &osm.Change{
+		Create: &osm.OSM{Nodes: osm.Nodes{{ID: 5, Version: 1, Visible: true}}},
+	}
+	if err := s.Handler()(nil, change); err != nil {
+		t.Fatalf("publish error: %v", err)
+	}
+
+	select {
+	case c := <-received:
+		if len(c.Create.Nodes) != 1 || c.Create.Nodes[0].ID != 5 {
+			t.Errorf("incorrect change: %v", c.Create)
+		}
+	case <-ctx.Done():
+		t.Fatalf("change not received")
+	}
+
+	cancel()
+	<-done
+}
+
+func TestServer_ServeHTTP(t *testing.T) {
+	s, c, close := testServer(testDatasource())
+	defer close()
+
+	resp, err := c.do(context.Background(), "Unknown", nil)
+	if err != nil {
+		t.Fatalf("request error: %v", err)
+	}
+	ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	err = trailerStatus(resp)
+	if st, ok := err.(*Status); !ok || st.Code != Unimplemented {
+		t.Errorf("expected unimplemented: %v", err)
+	}
+
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if w.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("incorrect status code: %v", w.Code)
+	}
+}
+
+func TestParseTimeout(t *testing.T) {
+	cases := []struct {
+		value    string
+		duration time.Duration
+		ok       bool
+	}{
+		{value: "100m", duration: 100 * time.Millisecond, ok: true},
+		{value: "2S", duration: 2 * time.Second, ok: true},
+		{value: "1H", duration: time.Hour, ok: true},
+		{value: "5x", ok: false},
+		{value: "m", ok: false},
+	}
+
+	for _, tc := range cases {
+		d, ok := parseTimeout(tc.value)
+		if d != tc.duration || ok != tc.ok {
+			t.Errorf("%s: incorrect result: %v %v", tc.value, d, ok)
+		}
+	}
+}
+
+func TestEncodeMessage(t *testing.T) {
+	if v := encodeMessage("not found: 100%\n"); v != "not found: 100%25%0A" {
+		t.Errorf("incorrect encoding: %v", v)
+	}
+}
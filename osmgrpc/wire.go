@@ -0,0 +1,212 @@
+package osmgrpc
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A Code is a gRPC status code.
+type Code int
+
+// The gRPC status codes returned by the server.
+const (
+	OK                Code = 0
+	Canceled          Code = 1
+	Unknown           Code = 2
+	InvalidArgument   Code = 3
+	DeadlineExceeded  Code = 4
+	NotFound          Code = 5
+	ResourceExhausted Code = 8
+	Unimplemented     Code = 12
+	Internal          Code = 13
+	Unavailable       Code = 14
+)
+
+// A Status is a gRPC error status, sent in the grpc-status
+// and grpc-message trailers.
+type Status struct {
+	Code    Code
+	Message string
+}
+
+// Error returns the status code and message.
+func (s *Status) Error() string {
+	return fmt.Sprintf("osmgrpc: code %d: %s", s.Code, s.Message)
+}
+
+func statusf(c Code, format string, args ...interface{}) *Status {
+	return &Status{Code: c, Message: fmt.Sprintf(format, args...)}
+}
+
+// maxMessageSize is the largest request message accepted,
+// requests are small so this is plenty.
+const maxMessageSize = 1 << 20
+
+var errCompressed = errors.New("osmgrpc: compressed messages not supported")
+
+// readMessage reads one length prefixed message. A gRPC message is a one
+// byte compressed flag, the big endian 4 byte length and the message.
+func readMessage(r io.Reader) ([]byte, error) {
+	var header [5]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, err
+	}
+
+	if header[0] != 0 {
+		return nil, errCompressed
+	}
+
+	l := binary.BigEndian.Uint32(header[1:])
+	if l > maxMessageSize {
+		return nil, fmt.Errorf("osmgrpc: message of %d bytes too large", l)
+	}
+
+	data := make([]byte, l)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+func writeMessage(w io.Writer, data []byte) error {
+	var header [5]byte
+	binary.BigEndian.PutUint32(header[1:], uint32(len(data)))
+	if _, err := w.Write(header[:]); err != nil {
+		return err
+	}
+
+	_, err := w.Write(data)
+	return err
+}
+
+// field is a decoded protobuf field. Only the wire types
+// used by the request messages are supported.
+type field struct {
+	num     int
+	varint  uint64
+	fixed64 uint64
+	bytes   []byte
+}
+
+// decodeFields decodes a protobuf message into its fields.
+func decodeFields(data []byte) ([]field, error) {
+	var fields []field
+	for len(data) > 0 {
+		key, n := binary.Uvarint(data)
+		if n <= 0 {
+			return nil, errors.New("invalid field key")
+		}
+		data = data[n:]
+
+		f := field{num: int(key >> 3)}
+		switch key & 7 {
+		case 0: // varint
+			v, n := binary.Uvarint(data)
+			if n <= 0 {
+				return nil, errors.New("invalid varint")
+			}
+			f.varint = v
+			data = data[n:]
+		case 1: // 64 bit
+			if len(data) < 8 {
+				return nil, errors.New("invalid fixed64")
+			}
+			f.fixed64 = binary.LittleEndian.Uint64(data)
+			data = data[8:]
+		case 2: // length delimited
+			l, n := binary.Uvarint(data)
+			if n <= 0 || uint64(len(data)-n) < l {
+				return nil, errors.New("invalid length")
+			}
+			f.bytes = data[n : n+int(l)]
+			data = data[n+int(l):]
+		case 5: // 32 bit
+			if len(data) < 4 {
+				return nil, errors.New("invalid fixed32")
+			}
+			data = data[4:]
+		default:
+			return nil, fmt.Errorf("unsupported wire type %d", key&7)
+		}
+
+		fields = append(fields, f)
+	}
+
+	return fields, nil
+}
+
+// appendVarint, appendDouble and appendBytes encode the request fields.
+func appendVarint(b []byte, num int, v uint64) []byte {
+	b = appendUvarint(b, uint64(num)<<3)
+	return appendUvarint(b, v)
+}
+
+func appendDouble(b []byte, num int, v float64) []byte {
+	b = appendUvarint(b, uint64(num)<<3|1)
+
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], math.Float64bits(v))
+	return append(b, buf[:]...)
+}
+
+func appendBytes(b []byte, num int, v []byte) []byte {
+	b = appendUvarint(b, uint64(num)<<3|2)
+	b = appendUvarint(b, uint64(len(v)))
+	return append(b, v...)
+}
+
+func appendUvarint(b []byte, v uint64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(buf[:], v)
+	return append(b, buf[:n]...)
+}
+
+// parseTimeout parses the grpc-timeout header, e.g. 100m for 100 milliseconds.
+func parseTimeout(s string) (time.Duration, bool) {
+	if len(s) < 2 {
+		return 0, false
+	}
+
+	v, err := strconv.ParseInt(s[:len(s)-1], 10, 64)
+	if err != nil || v < 0 {
+		return 0, false
+	}
+
+	units := map[byte]time.Duration{
+		'H': time.Hour,
+		'M': time.Minute,
+		'S': time.Second,
+		'm': time.Millisecond,
+		'u': time.Microsecond,
+		'n': time.Nanosecond,
+	}
+
+	unit, ok := units[s[len(s)-1]]
+	if !ok {
+		return 0, false
+	}
+
+	return time.Duration(v) * unit, true
+}
+
+// encodeMessage percent encodes the grpc-message trailer value.
+func encodeMessage(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c < 0x20 || c > 0x7e || c == '%' {
+			fmt.Fprintf(&b, "%%%02X", c)
+		} else {
+			b.WriteByte(c)
+		}
+	}
+
+	return b.String()
+}
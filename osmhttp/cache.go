@@ -0,0 +1,264 @@
+package osmhttp
+
+import (
+	"bufio"
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httputil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A Cache stores the responses of GET requests and Overpass queries,
+// see the WithCache option.
+// Implementations must be safe for concurrent use.
+type Cache interface {
+	Get(key string) ([]byte, bool)
+
+	// Set saves the data, expiring after the ttl. A zero ttl never expires.
+	Set(key string, data []byte, ttl time.Duration)
+}
+
+// WithCache caches the successful responses to GET requests for the ttl,
+// so repeated jobs don't hammer the servers. The responses for specific
+// versions of elements, e.g. /node/1/2, never change and do not expire.
+// Overpass queries, POSTs to an interpreter url, are cached by the query.
+// Requests with an Authorization header are never cached since the
+// response depends on the user. Cached responses do not wait for the
+// rate limiter.
+func WithCache(c Cache, ttl time.Duration) Option {
+	return func(t *Transport) error {
+		t.cache = c
+		t.cacheTTL = ttl
+		return nil
+	}
+}
+
+// versionPath matches the api paths of specific versions of elements.
+var versionPath = regexp.MustCompile(`/(node|way|relation)/\d+/\d+$`)
+
+// cacheKey returns the key of the request in the cache, or an empty
+// string if the request should not be cached.
+func (t *Transport) cacheKey(req *http.Request) string {
+	if t.cache == nil || req.Header.Get("Authorization") != "" {
+		return ""
+	}
+
+	if req.Method == http.MethodGet {
+		return req.URL.String()
+	}
+
+	// the body can only be read if it can be read again for the request
+	if req.Method != http.MethodPost || !strings.HasSuffix(req.URL.Path, "/interpreter") || req.GetBody == nil {
+		return ""
+	}
+
+	body, err := req.GetBody()
+	if err != nil {
+		return ""
+	}
+	defer body.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, body); err != nil {
+		return ""
+	}
+
+	return req.URL.String() + " " + hex.EncodeToString(h.Sum(nil))
+}
+
+// cached returns the response from the cache, if present.
+func (t *Transport) cached(req *http.Request, key string) *http.Response {
+	if key == "" {
+		return nil
+	}
+
+	data, ok := t.cache.Get(key)
+	if !ok {
+		return nil
+	}
+
+	resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(data)), req)
+	if err != nil {
+		return nil
+	}
+
+	return resp
+}
+
+// store saves the successful response in the cache.
+func (t *Transport) store(req *http.Request, key string, resp *http.Response) error {
+	if key == "" || resp.StatusCode != http.StatusOK {
+		return nil
+	}
+
+	// reads the body and replaces it with one that can be read again
+	data, err := httputil.DumpResponse(resp, true)
+	if err != nil {
+		return err
+	}
+
+	ttl := t.cacheTTL
+	if versionPath.MatchString(req.URL.Path) {
+		ttl = 0
+	}
+
+	t.cache.Set(key, data, ttl)
+	return nil
+}
+
+// MemoryCache is a Cache that keeps the most recently used
+// responses in memory.
+type MemoryCache struct {
+	lk         sync.Mutex
+	maxEntries int
+	entries    map[string]*list.Element
+	order      *list.List
+
+	now func() time.Time
+}
+
+type memoryEntry struct {
+	key     string
+	data    []byte
+	expires time.Time
+}
+
+var _ Cache = &MemoryCache{}
+
+// NewMemoryCache creates a cache of up to max entries, removing the least
+// recently used entries when full. A max of zero has no limit.
+func NewMemoryCache(max int) *MemoryCache {
+	return &MemoryCache{
+		maxEntries: max,
+		entries:    make(map[string]*list.Element),
+		order:      list.New(),
+		now:        time.Now,
+	}
+}
+
+// Get returns the data for the key if present and not expired.
+func (c *MemoryCache) Get(key string) ([]byte, bool) {
+	c.lk.Lock()
+	defer c.lk.Unlock()
+
+	el, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	e := el.Value.(*memoryEntry)
+	if !e.expires.IsZero() && c.now().After(e.expires) {
+		c.order.Remove(el)
+		delete(c.entries, key)
+		return nil, false
+	}
+
+	c.order.MoveToFront(el)
+	return e.data, true
+}
+
+// Set saves the data, expiring after the ttl. A zero ttl never expires.
+func (c *MemoryCache) Set(key string, data []byte, ttl time.Duration) {
+	c.lk.Lock()
+	defer c.lk.Unlock()
+
+	e := &memoryEntry{key: key, data: data}
+	if ttl > 0 {
+		e.expires = c.now().Add(ttl)
+	}
+
+	if el, ok := c.entries[key]; ok {
+		el.Value = e
+		c.order.MoveToFront(el)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(e)
+	if c.maxEntries > 0 && c.order.Len() > c.maxEntries {
+		el := c.order.Back()
+		c.order.Remove(el)
+		delete(c.entries, el.Value.(*memoryEntry).key)
+	}
+}
+
+// DiskCache is a Cache that saves the responses as files in a directory,
+// so they are kept between runs. Expired files are removed when read.
+type DiskCache struct {
+	dir string
+	now func() time.Time
+}
+
+var _ Cache = &DiskCache{}
+
+// NewDiskCache creates a cache in the directory, creating it if needed.
+func NewDiskCache(dir string) (*DiskCache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	return &DiskCache{dir: dir, now: time.Now}, nil
+}
+
+// Get returns the data for the key if present and not expired.
+func (c *DiskCache) Get(key string) ([]byte, bool) {
+	filename := c.filename(key)
+	data, err := ioutil.ReadFile(filename)
+	if err != nil || len(data) < 8 {
+		return nil, false
+	}
+
+	expires := int64(binary.BigEndian.Uint64(data))
+	if expires != 0 && c.now().UnixNano() > expires {
+		os.Remove(filename)
+		return nil, false
+	}
+
+	return data[8:], true
+}
+
+// Set saves the data, expiring after the ttl. A zero ttl never expires.
+// Errors writing the file are ignored, the response will not be cached.
+func (c *DiskCache) Set(key string, data []byte, ttl time.Duration) {
+	var expires int64
+	if ttl > 0 {
+		expires = c.now().Add(ttl).UnixNano()
+	}
+
+	f, err := ioutil.TempFile(c.dir, "tmp")
+	if err != nil {
+		return
+	}
+
+	var header [8]byte
+	binary.BigEndian.PutUint64(header[:], uint64(expires))
+
+	_, err = f.Write(append(header[:], data...))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+
+	if err == nil {
+		// rename so readers never see a partial file
+		err = os.Rename(f.Name(), c.filename(key))
+	}
+
+	if err != nil {
+		os.Remove(f.Name())
+	}
+}
+
+func (c *DiskCache) filename(key string) string {
+	h := sha256.Sum256([]byte(key))
+	return filepath.Join(c.dir, hex.EncodeToString(h[:]))
+}
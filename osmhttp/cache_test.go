@@ -0,0 +1,186 @@
+package osmhttp
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWithCache(t *testing.T) {
+	var calls int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if strings.HasPrefix(r.URL.Path, "/missing") {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/xml")
+		w.Write([]byte("<osm></osm>"))
+	}))
+	defer ts.Close()
+
+	cache := NewMemoryCache(10)
+	now := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+	cache.now = func() time.Time { return now }
+
+	client, err := NewClient("test/1.0", WithCache(cache, time.Hour))
+	if err != nil {
+		t.Fatalf("new client error: %v", err)
+	}
+
+	get := func(path string) string {
+		t.Helper()
+
+		resp, err := client.Get(ts.URL + path)
+		if err != nil {
+			t.Fatalf("request error: %v", err)
+		}
+		defer resp.Body.Close()
+
+		data, _ := ioutil.ReadAll(resp.Body)
+		return resp.Header.Get("Content-Type") + " " + string(data)
+	}
+
+	for i := 0; i < 2; i++ {
+		if v := get("/node/1"); v != "text/xml <osm></osm>" {
+			t.Errorf("incorrect response: %v", v)
+		}
+	}
+
+	if calls != 1 {
+		t.Errorf("should use the cached response: %v", calls)
+	}
+
+	// expired
+	now = now.Add(2 * time.Hour)
+	get("/node/1")
+	if calls != 2 {
+		t.Errorf("should request again after the ttl: %v", calls)
+	}
+
+	// specific versions never expire
+	get("/node/1/2")
+	now = now.Add(1000 * time.Hour)
+	get("/node/1/2")
+	if calls != 3 {
+		t.Errorf("should cache versions forever: %v", calls)
+	}
+
+	// errors are not cached
+	get("/missing")
+	get("/missing")
+	if calls != 5 {
+		t.Errorf("should not cache errors: %v", calls)
+	}
+
+	// only get requests
+	client.Post(ts.URL+"/node/1", "text/plain", strings.NewReader("data"))
+	client.Post(ts.URL+"/node/1", "text/plain", strings.NewReader("data"))
+	if calls != 7 {
+		t.Errorf("should not cache post requests: %v", calls)
+	}
+
+	// authenticated requests
+	for i := 0; i < 2; i++ {
+		req, _ := http.NewRequest("GET", ts.URL+"/user/details", nil)
+		req.Header.Set("Authorization", "Bearer token")
+
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("request error: %v", err)
+		}
+		resp.Body.Close()
+	}
+
+	if calls != 9 {
+		t.Errorf("should not cache authenticated requests: %v", calls)
+	}
+
+	// overpass queries are cached by the query
+	query := func(q string) {
+		t.Helper()
+
+		resp, err := client.Post(ts.URL+"/api/interpreter", "application/x-www-form-urlencoded", strings.NewReader(q))
+		if err != nil {
+			t.Fatalf("request error: %v", err)
+		}
+		resp.Body.Close()
+	}
+
+	query("data=node(1);out;")
+	query("data=node(1);out;")
+	if calls != 10 {
+		t.Errorf("should cache overpass queries: %v", calls)
+	}
+
+	query("data=node(2);out;")
+	if calls != 11 {
+		t.Errorf("should cache by the query: %v", calls)
+	}
+}
+
+func TestMemoryCache(t *testing.T) {
+	c := NewMemoryCache(2)
+	c.Set("a", []byte("1"), 0)
+	c.Set("b", []byte("2"), 0)
+
+	// a is now the most recently used
+	if v, ok := c.Get("a"); !ok || string(v) != "1" {
+		t.Errorf("incorrect value: %v %v", v, ok)
+	}
+
+	c.Set("c", []byte("3"), 0)
+	if _, ok := c.Get("b"); ok {
+		t.Errorf("should remove the least recently used")
+	}
+
+	if _, ok := c.Get("a"); !ok {
+		t.Errorf("should keep the recently used")
+	}
+}
+
+func TestDiskCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "osmhttp")
+	if err != nil {
+		t.Fatalf("temp dir error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	c, err := NewDiskCache(dir)
+	if err != nil {
+		t.Fatalf("new cache error: %v", err)
+	}
+
+	now := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+	c.now = func() time.Time { return now }
+
+	c.Set("https://example.com/node/1", []byte("data"), time.Hour)
+	c.Set("https://example.com/node/1/2", []byte("version"), 0)
+
+	if v, ok := c.Get("https://example.com/node/1"); !ok || string(v) != "data" {
+		t.Errorf("incorrect value: %v %v", v, ok)
+	}
+
+	if _, ok := c.Get("https://example.com/node/2"); ok {
+		t.Errorf("should not find missing key")
+	}
+
+	now = now.Add(2 * time.Hour)
+	if _, ok := c.Get("https://example.com/node/1"); ok {
+		t.Errorf("should expire")
+	}
+
+	if v, ok := c.Get("https://example.com/node/1/2"); !ok || string(v) != "version" {
+		t.Errorf("incorrect value: %v %v", v, ok)
+	}
+
+	files, _ := ioutil.ReadDir(dir)
+	if len(files) != 1 {
+		t.Errorf("should remove expired files: %v", len(files))
+	}
+}
@@ -0,0 +1,77 @@
+package osmhttp
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// A RateLimiter is something that can wait until its next allowed request.
+// It is met by the Limiter in this package, `golang.org/x/time/rate.Limiter`
+// and the osmapi.RateLimiter interface.
+type RateLimiter interface {
+	Wait(context.Context) error
+}
+
+// A Limiter allows one request every interval, with bursts of up to burst
+// requests. It is safe for concurrent use.
+type Limiter struct {
+	interval time.Duration
+	burst    int
+
+	mu  sync.Mutex
+	tat time.Time // theoretical arrival time of the next request
+}
+
+var _ RateLimiter = &Limiter{}
+
+// NewLimiter creates a limiter allowing a request every interval
+// and bursts of up to burst requests, at least one.
+func NewLimiter(interval time.Duration, burst int) *Limiter {
+	if burst < 1 {
+		burst = 1
+	}
+
+	return &Limiter{
+		interval: interval,
+		burst:    burst,
+	}
+}
+
+// Wait blocks until the next request is allowed or
+// the context is canceled.
+func (l *Limiter) Wait(ctx context.Context) error {
+	d := l.reserve(time.Now())
+	if d == 0 {
+		return ctx.Err()
+	}
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// reserve claims the next slot and returns how long to wait for it.
+func (l *Limiter) reserve(now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.tat.Before(now) {
+		l.tat = now
+	}
+
+	allowed := l.tat.Add(-time.Duration(l.burst-1) * l.interval)
+	l.tat = l.tat.Add(l.interval)
+
+	if d := allowed.Sub(now); d > 0 {
+		return d
+	}
+
+	return 0
+}
@@ -0,0 +1,44 @@
+package osmhttp
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestLimiter_reserve(t *testing.T) {
+	now := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := NewLimiter(time.Second, 2)
+
+	cases := []struct {
+		name     string
+		at       time.Duration
+		expected time.Duration
+	}{
+		{name: "first", at: 0, expected: 0},
+		{name: "burst", at: 0, expected: 0},
+		{name: "wait", at: 0, expected: time.Second},
+		{name: "queued", at: 500 * time.Millisecond, expected: 1500 * time.Millisecond},
+		{name: "idle", at: 10 * time.Second, expected: 0},
+	}
+
+	for _, tc := range cases {
+		if d := l.reserve(now.Add(tc.at)); d != tc.expected {
+			t.Errorf("%s: incorrect wait: %v != %v", tc.name, d, tc.expected)
+		}
+	}
+}
+
+func TestLimiter_Wait(t *testing.T) {
+	l := NewLimiter(time.Hour, 1)
+	if err := l.Wait(context.Background()); err != nil {
+		t.Fatalf("first wait error: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if err := l.Wait(ctx); err != context.DeadlineExceeded {
+		t.Errorf("should return context error: %v", err)
+	}
+}
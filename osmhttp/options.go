@@ -0,0 +1,70 @@
+package osmhttp
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/paulmach/osm"
+)
+
+// An Option is a setting for the transport.
+type Option func(*Transport) error
+
+// WithBase sets the round tripper that makes the requests.
+// The default is http.DefaultTransport.
+func WithBase(rt http.RoundTripper) Option {
+	return func(t *Transport) error {
+		t.base = rt
+		return nil
+	}
+}
+
+// WithLimiter sets the rate limiter waited on before every request,
+// including retries.
+func WithLimiter(l RateLimiter) Option {
+	return func(t *Transport) error {
+		t.limiter = l
+		return nil
+	}
+}
+
+// Rate limits the requests to one every interval, with bursts of up to
+// burst requests. The osm api, Overpass and Nominatim ask for at most one
+// request per second from heavy users.
+func Rate(interval time.Duration, burst int) Option {
+	return func(t *Transport) error {
+		if interval <= 0 {
+			return errors.New("osmhttp: rate interval must be positive")
+		}
+
+		t.limiter = NewLimiter(interval, burst)
+		return nil
+	}
+}
+
+// RetryAfter sets the number of times a request is retried when the server
+// responds with 429 Too Many Requests or 503 Service Unavailable, and the
+// maximum wait. The Retry-After header is respected, if missing the wait
+// is a second doubling up to max. If the server asks to wait longer than
+// max the response is returned as is. The default is 3 retries waiting
+// up to a minute.
+func RetryAfter(retries int, max time.Duration) Option {
+	return func(t *Transport) error {
+		if retries < 0 {
+			return errors.New("osmhttp: retries must not be negative")
+		}
+
+		t.maxRetries = retries
+		t.maxWait = max
+		return nil
+	}
+}
+
+// WithLogger sets the logger that receives warnings for retried requests.
+func WithLogger(l osm.Logger) Option {
+	return func(t *Transport) error {
+		t.logger = l
+		return nil
+	}
+}
@@ -0,0 +1,209 @@
+// Package osmhttp provides an http.RoundTripper that makes clients follow
+// the usage policies of the osm api, Overpass and Nominatim: it sets an
+// identifying User-Agent, limits the request rate and respects the
+// Retry-After header of rate limited responses.
+package osmhttp
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/paulmach/osm"
+)
+
+// A Transport is an http.RoundTripper that adds polite usage on top of
+// another round tripper. It is safe for concurrent use.
+type Transport struct {
+	userAgent  string
+	base       http.RoundTripper
+	limiter    RateLimiter
+	maxRetries int
+	maxWait    time.Duration
+	logger     osm.Logger
+
+	cache    Cache
+	cacheTTL time.Duration
+}
+
+var _ http.RoundTripper = &Transport{}
+
+// NewTransport creates a transport that sets the user agent on requests
+// that do not have one. The usage policies require a user agent that
+// identifies the application, e.g. "myapp/1.0 (contact@example.com)",
+// so it can not be empty or the Go default.
+func NewTransport(userAgent string, opts ...Option) (*Transport, error) {
+	if !validUserAgent(userAgent) {
+		return nil, errors.New("osmhttp: a user agent identifying the application is required")
+	}
+
+	t := &Transport{
+		userAgent:  userAgent,
+		maxRetries: 3,
+		maxWait:    time.Minute,
+	}
+
+	for _, opt := range opts {
+		if err := opt(t); err != nil {
+			return nil, err
+		}
+	}
+
+	return t, nil
+}
+
+// NewClient returns an http client using a new transport.
+func NewClient(userAgent string, opts ...Option) (*http.Client, error) {
+	t, err := NewTransport(userAgent, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &http.Client{Transport: t}, nil
+}
+
+func validUserAgent(ua string) bool {
+	ua = strings.TrimSpace(ua)
+	return ua != "" && !strings.HasPrefix(ua, "Go-http-client")
+}
+
+// RoundTrip waits for the rate limiter, sets the user agent and makes the
+// request, or returns the cached response. Rate limited responses are retried after the time requested by
+// the server, if the request body can be read again.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	key := t.cacheKey(req)
+	if resp := t.cached(req, key); resp != nil {
+		return resp, nil
+	}
+
+	ctx := req.Context()
+
+	backoff := time.Second
+	for i := 0; ; i++ {
+		if t.limiter != nil {
+			if err := t.limiter.Wait(ctx); err != nil {
+				return nil, err
+			}
+		}
+
+		r, err := t.request(req, i > 0)
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := t.roundTripper().RoundTrip(r)
+		if err == nil && !retryable(resp.StatusCode) {
+			if err := t.store(req, key, resp); err != nil {
+				resp.Body.Close()
+				return nil, err
+			}
+		}
+
+		if err != nil || !retryable(resp.StatusCode) || i >= t.maxRetries {
+			return resp, err
+		}
+
+		if req.Body != nil && req.GetBody == nil {
+			return resp, nil
+		}
+
+		wait, ok := retryAfter(resp.Header.Get("Retry-After"), time.Now())
+		if !ok {
+			wait = backoff
+			backoff *= 2
+		}
+
+		if wait > t.maxWait {
+			if ok {
+				return resp, nil
+			}
+			wait = t.maxWait
+		}
+
+		io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 4096))
+		resp.Body.Close()
+
+		if t.logger != nil {
+			t.logger.Warn("osmhttp: retrying request",
+				"url", req.URL.String(), "code", resp.StatusCode, "attempt", i+1, "wait", wait.String())
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// request returns a copy of the request with the user agent set,
+// as a round tripper should not modify the request.
+func (t *Transport) request(req *http.Request, retry bool) (*http.Request, error) {
+	r := new(http.Request)
+	*r = *req
+
+	r.Header = make(http.Header, len(req.Header)+1)
+	for k, v := range req.Header {
+		r.Header[k] = v
+	}
+
+	if r.Header.Get("User-Agent") == "" {
+		r.Header.Set("User-Agent", t.userAgent)
+	}
+
+	if retry && req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		r.Body = body
+	}
+
+	return r, nil
+}
+
+func (t *Transport) roundTripper() http.RoundTripper {
+	if t.base != nil {
+		return t.base
+	}
+
+	return http.DefaultTransport
+}
+
+func retryable(code int) bool {
+	return code == http.StatusTooManyRequests || code == http.StatusServiceUnavailable
+}
+
+// retryAfter parses the Retry-After header, either a number
+// of seconds or an http date.
+func retryAfter(v string, now time.Time) (time.Duration, bool) {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return 0, false
+	}
+
+	if s, err := strconv.Atoi(v); err == nil {
+		if s < 0 {
+			return 0, false
+		}
+		return time.Duration(s) * time.Second, true
+	}
+
+	t, err := http.ParseTime(v)
+	if err != nil {
+		return 0, false
+	}
+
+	d := t.Sub(now)
+	if d < 0 {
+		d = 0
+	}
+
+	return d, true
+}
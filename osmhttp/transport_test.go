@@ -0,0 +1,224 @@
+package osmhttp
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNewTransport(t *testing.T) {
+	for _, ua := range []string{"", " ", "Go-http-client/1.1"} {
+		if _, err := NewTransport(ua); err == nil {
+			t.Errorf("should error for user agent %q", ua)
+		}
+	}
+
+	if _, err := NewTransport("test/1.0", Rate(0, 1)); err == nil {
+		t.Errorf("should error for zero rate")
+	}
+
+	if _, err := NewTransport("test/1.0", RetryAfter(-1, 0)); err == nil {
+		t.Errorf("should error for negative retries")
+	}
+}
+
+func TestTransport_userAgent(t *testing.T) {
+	var ua string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ua = r.Header.Get("User-Agent")
+	}))
+	defer ts.Close()
+
+	client, err := NewClient("test/1.0 (test@example.com)")
+	if err != nil {
+		t.Fatalf("new client error: %v", err)
+	}
+
+	if _, err := client.Get(ts.URL); err != nil {
+		t.Fatalf("request error: %v", err)
+	}
+
+	if ua != "test/1.0 (test@example.com)" {
+		t.Errorf("incorrect user agent: %v", ua)
+	}
+
+	// does not override one set on the request
+	req, _ := http.NewRequest("GET", ts.URL, nil)
+	req.Header.Set("User-Agent", "other/2.0")
+	if _, err := client.Do(req); err != nil {
+		t.Fatalf("request error: %v", err)
+	}
+
+	if ua != "other/2.0" {
+		t.Errorf("incorrect user agent: %v", ua)
+	}
+}
+
+func TestTransport_retryAfter(t *testing.T) {
+	cases := []struct {
+		name     string
+		header   string
+		failures int
+		options  []Option
+		code     int
+		requests int
+	}{
+		{
+			name:     "retry after seconds",
+			header:   "0",
+			failures: 2,
+			code:     http.StatusOK,
+			requests: 3,
+		},
+		{
+			name:     "retry after date",
+			header:   time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat),
+			failures: 1,
+			code:     http.StatusOK,
+			requests: 2,
+		},
+		{
+			name:     "backoff without header",
+			failures: 1,
+			options:  []Option{RetryAfter(3, time.Millisecond)},
+			code:     http.StatusOK,
+			requests: 2,
+		},
+		{
+			name:     "too many failures",
+			header:   "0",
+			failures: 5,
+			options:  []Option{RetryAfter(2, time.Minute)},
+			code:     http.StatusTooManyRequests,
+			requests: 3,
+		},
+		{
+			name:     "wait too long",
+			header:   "3600",
+			failures: 1,
+			code:     http.StatusTooManyRequests,
+			requests: 1,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			requests := 0
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				if requests <= tc.failures {
+					if tc.header != "" {
+						w.Header().Set("Retry-After", tc.header)
+					}
+					w.WriteHeader(http.StatusTooManyRequests)
+				}
+			}))
+			defer ts.Close()
+
+			client, err := NewClient("test/1.0", tc.options...)
+			if err != nil {
+				t.Fatalf("new client error: %v", err)
+			}
+
+			resp, err := client.Get(ts.URL)
+			if err != nil {
+				t.Fatalf("request error: %v", err)
+			}
+			resp.Body.Close()
+
+			if resp.StatusCode != tc.code {
+				t.Errorf("incorrect status code: %v", resp.StatusCode)
+			}
+
+			if requests != tc.requests {
+				t.Errorf("incorrect number of requests: %v", requests)
+			}
+		})
+	}
+}
+
+func TestTransport_retryBody(t *testing.T) {
+	var bodies []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := ioutil.ReadAll(r.Body)
+		bodies = append(bodies, string(data))
+		if len(bodies) == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer ts.Close()
+
+	logger := &testLogger{}
+	client, _ := NewClient("test/1.0", WithLogger(logger))
+
+	resp, err := client.Post(ts.URL, "text/plain", bytes.NewBufferString("data"))
+	if err != nil {
+		t.Fatalf("request error: %v", err)
+	}
+	resp.Body.Close()
+
+	if len(bodies) != 2 || bodies[0] != "data" || bodies[1] != "data" {
+		t.Errorf("incorrect bodies: %v", bodies)
+	}
+
+	expected := fmt.Sprintf("osmhttp: retrying request [url %s code 503 attempt 1 wait 0s]", ts.URL)
+	if len(logger.warnings) != 1 || logger.warnings[0] != expected {
+		t.Errorf("incorrect warnings: %v", logger.warnings)
+	}
+}
+
+func TestTransport_limiter(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+
+	client, _ := NewClient("test/1.0", Rate(time.Hour, 1))
+	if _, err := client.Get(ts.URL); err != nil {
+		t.Fatalf("request error: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	req, _ := http.NewRequest("GET", ts.URL, nil)
+	if _, err := client.Do(req.WithContext(ctx)); err == nil {
+		t.Errorf("should wait for the limiter until the context is done")
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		header   string
+		duration time.Duration
+		ok       bool
+	}{
+		{header: "", ok: false},
+		{header: "120", duration: 2 * time.Minute, ok: true},
+		{header: "-1", ok: false},
+		{header: "Mon, 01 Jan 2018 00:00:30 GMT", duration: 30 * time.Second, ok: true},
+		{header: "Sun, 31 Dec 2017 00:00:00 GMT", duration: 0, ok: true},
+		{header: "soon", ok: false},
+	}
+
+	for _, tc := range cases {
+		d, ok := retryAfter(tc.header, now)
+		if d != tc.duration || ok != tc.ok {
+			t.Errorf("%q: incorrect result: %v %v", tc.header, d, ok)
+		}
+	}
+}
+
+type testLogger struct {
+	warnings []string
+}
+
+func (l *testLogger) Warn(msg string, keyvals ...interface{}) {
+	l.warnings = append(l.warnings, fmt.Sprintf("%s %v", msg, keyvals))
+}
@@ -0,0 +1,37 @@
+package osmmvt
+
+import (
+	"errors"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/encoding/mvt"
+)
+
+// An Option is a setting for rendering the vector tiles.
+type Option func(*Renderer) error
+
+// Gzip will compress the output tiles using gzip.
+// This is what most tile servers expect to serve.
+func Gzip(yes bool) Option {
+	return func(r *Renderer) error {
+		r.gzip = yes
+		return nil
+	}
+}
+
+// Buffer sets the number of pixels, in tile extent units, to include
+// outside of the tile when clipping geometry. The default is 256,
+// matching the Mapbox GL default.
+func Buffer(pixels float64) Option {
+	return func(r *Renderer) error {
+		if pixels < 0 {
+			return errors.New("osmmvt: buffer must be non-negative")
+		}
+
+		r.clip = orb.Bound{
+			Min: orb.Point{-pixels, -pixels},
+			Max: orb.Point{mvt.DefaultExtent + pixels, mvt.DefaultExtent + pixels},
+		}
+		return nil
+	}
+}
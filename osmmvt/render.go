@@ -0,0 +1,278 @@
+// Package osmmvt renders osm data into Mapbox Vector Tiles.
+package osmmvt
+
+import (
+	"errors"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/encoding/mvt"
+	"github.com/paulmach/orb/geojson"
+	"github.com/paulmach/orb/maptile"
+	"github.com/paulmach/osm"
+	"github.com/paulmach/osm/osmgeojson"
+)
+
+// A Layer defines which osm features are rendered into a named vector
+// tile layer and which of their tags are kept.
+type Layer struct {
+	Name string
+
+	// Filter returns true if a feature with the given tags should be
+	// included in the layer, e.g. osm.HasTag. If nil, all features are
+	// included.
+	Filter func(tags map[string]string) bool
+
+	// Keys are the tag keys copied into the vector tile feature properties.
+	// If empty, all the tags are copied.
+	Keys []string
+
+	// MinZoom and MaxZoom limit the zoom levels the layer is rendered at.
+	// A MaxZoom of zero means there is no upper limit.
+	MinZoom maptile.Zoom
+	MaxZoom maptile.Zoom
+}
+
+func (l *Layer) atZoom(z maptile.Zoom) bool {
+	if z < l.MinZoom {
+		return false
+	}
+
+	return l.MaxZoom == 0 || z <= l.MaxZoom
+}
+
+type feature struct {
+	*geojson.Feature
+	bound orb.Bound
+}
+
+// Renderer holds the features assigned to each layer and renders them
+// into individual tiles. It is not safe for concurrent use while adding data.
+type Renderer struct {
+	layers   []*Layer
+	features [][]feature
+
+	gzip bool
+	clip orb.Bound
+}
+
+// New creates a renderer for the given layers.
+func New(layers []*Layer, opts ...Option) (*Renderer, error) {
+	if len(layers) == 0 {
+		return nil, errors.New("osmmvt: at least one layer is required")
+	}
+
+	r := &Renderer{
+		layers:   layers,
+		features: make([][]feature, len(layers)),
+		clip:     mvt.MapboxGLDefaultExtentBound,
+	}
+
+	for _, opt := range opts {
+		if err := opt(r); err != nil {
+			return nil, err
+		}
+	}
+
+	return r, nil
+}
+
+// Add converts the osm data to geometry and assigns the features to the
+// matching layers. Ways need their nodes, and relations their members, to be
+// in the same osm object so they can be built.
+func (r *Renderer) Add(o *osm.OSM) error {
+	fc, err := osmgeojson.Convert(o,
+		osmgeojson.NoID(true),
+		osmgeojson.NoMeta(true),
+		osmgeojson.NoRelationMembership(true),
+		osmgeojson.RightHandRule(true),
+	)
+	if err != nil {
+		return err
+	}
+
+	for _, f := range fc.Features {
+		tags, _ := f.Properties["tags"].(map[string]string)
+		for i, l := range r.layers {
+			if l.Filter != nil && !l.Filter(tags) {
+				continue
+			}
+
+			r.features[i] = append(r.features[i], feature{
+				Feature: layerFeature(f, l, tags),
+				bound:   f.Geometry.Bound(),
+			})
+		}
+	}
+
+	return nil
+}
+
+// AddScanner reads all the nodes, ways and relations from the scanner
+// and adds them to the renderer.
+func (r *Renderer) AddScanner(s osm.Scanner) error {
+	o := &osm.OSM{}
+	for s.Scan() {
+		switch obj := s.Object().(type) {
+		case *osm.Node, *osm.Way, *osm.Relation:
+			o.Append(obj)
+		}
+	}
+
+	if err := s.Err(); err != nil {
+		return err
+	}
+
+	return r.Add(o)
+}
+
+// Tile renders the given tile. Returns nil data if there are no features
+// within the tile.
+func (r *Renderer) Tile(t maptile.Tile) ([]byte, error) {
+	bound := t.Bound()
+
+	var layers mvt.Layers
+	for i, l := range r.layers {
+		if !l.atZoom(t.Z) {
+			continue
+		}
+
+		var features []*geojson.Feature
+		for _, f := range r.features[i] {
+			if !f.bound.Intersects(bound) {
+				continue
+			}
+
+			// projection happens in place so each tile needs its own copy.
+			c := geojson.NewFeature(orb.Clone(f.Geometry))
+			c.ID = f.ID
+			c.Properties = f.Properties
+			features = append(features, c)
+		}
+
+		if len(features) == 0 {
+			continue
+		}
+
+		layers = append(layers, &mvt.Layer{
+			Name:     l.Name,
+			Version:  2,
+			Extent:   mvt.DefaultExtent,
+			Features: features,
+		})
+	}
+
+	if len(layers) == 0 {
+		return nil, nil
+	}
+
+	layers.ProjectToTile(t)
+	layers.Clip(r.clip)
+	layers.RemoveEmpty(1.0, 1.0)
+
+	empty := true
+	for _, l := range layers {
+		if len(l.Features) > 0 {
+			empty = false
+			break
+		}
+	}
+
+	if empty {
+		return nil, nil
+	}
+
+	if r.gzip {
+		return mvt.MarshalGzipped(layers)
+	}
+
+	return mvt.Marshal(layers)
+}
+
+// Range renders all the tiles covering the bound for the zoom range,
+// inclusive. The function is called for every tile that contains data.
+// Rendering stops at the first error returned by the function.
+func (r *Renderer) Range(
+	b orb.Bound,
+	minZoom, maxZoom maptile.Zoom,
+	fn func(maptile.Tile, []byte) error,
+) error {
+	if minZoom > maxZoom {
+		return errors.New("osmmvt: min zoom greater than max zoom")
+	}
+
+	for z := minZoom; z <= maxZoom; z++ {
+		min := maptile.At(orb.Point{b.Min[0], b.Max[1]}, z)
+		max := maptile.At(orb.Point{b.Max[0], b.Min[1]}, z)
+
+		for x := min.X; x <= max.X; x++ {
+			for y := min.Y; y <= max.Y; y++ {
+				t := maptile.New(x, y, z)
+				data, err := r.Tile(t)
+				if err != nil {
+					return err
+				}
+
+				if data == nil {
+					continue
+				}
+
+				if err := fn(t, data); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// layerFeature creates the feature with the flat properties expected
+// by vector tiles, i.e. only the selected tags and the osm type.
+func layerFeature(f *geojson.Feature, l *Layer, tags map[string]string) *geojson.Feature {
+	lf := geojson.NewFeature(f.Geometry)
+	if id, ok := featureID(f); ok {
+		lf.ID = id
+	}
+
+	lf.Properties["osm_type"] = f.Properties["type"]
+	if len(l.Keys) == 0 {
+		for k, v := range tags {
+			lf.Properties[k] = v
+		}
+
+		return lf
+	}
+
+	for _, k := range l.Keys {
+		if v, ok := tags[k]; ok {
+			lf.Properties[k] = v
+		}
+	}
+
+	return lf
+}
+
+// typeDigits are the last digit of the feature ids.
+var typeDigits = map[string]uint64{
+	string(osm.TypeNode):     1,
+	string(osm.TypeWay):      2,
+	string(osm.TypeRelation): 3,
+}
+
+// featureID returns the vector tile feature id of the osm element. The
+// type is encoded as the last digit, id*10 plus 1 for nodes, 2 for ways
+// and 3 for relations, so elements of different types with the same id
+// do not share a feature id. New elements with negative ids have none.
+func featureID(f *geojson.Feature) (uint64, bool) {
+	id, ok := f.Properties["id"].(int)
+	if !ok || id <= 0 {
+		return 0, false
+	}
+
+	t, ok := f.Properties["type"].(string)
+	if !ok || typeDigits[t] == 0 {
+		return 0, false
+	}
+
+	return uint64(id)*10 + typeDigits[t], true
+}
@@ -0,0 +1,169 @@
+package osmmvt
+
+import (
+	"testing"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/encoding/mvt"
+	"github.com/paulmach/orb/geojson"
+	"github.com/paulmach/orb/maptile"
+	"github.com/paulmach/osm"
+)
+
+func testOSM() *osm.OSM {
+	return &osm.OSM{
+		Nodes: osm.Nodes{
+			{ID: 1, Lat: 1, Lon: 1, Visible: true, Version: 1,
+				Tags: osm.Tags{{Key: "amenity", Value: "cafe"}, {Key: "name", Value: "Joe's"}}},
+			{ID: 2, Lat: 1.01, Lon: 1.01, Visible: true, Version: 1},
+			{ID: 3, Lat: 1.02, Lon: 1.02, Visible: true, Version: 1},
+		},
+		Ways: osm.Ways{
+			{ID: 10, Visible: true, Version: 1,
+				Nodes: osm.WayNodes{{ID: 2}, {ID: 3}},
+				Tags:  osm.Tags{{Key: "highway", Value: "primary"}, {Key: "ref", Value: "A1"}}},
+		},
+	}
+}
+
+func TestRenderer_Tile(t *testing.T) {
+	r, err := New([]*Layer{
+		{Name: "pois", Filter: osm.HasTag("amenity"), Keys: []string{"amenity"}},
+		{Name: "roads", Filter: osm.HasTag("highway", "primary", "secondary")},
+	})
+	if err != nil {
+		t.Fatalf("new error: %v", err)
+	}
+
+	if err := r.Add(testOSM()); err != nil {
+		t.Fatalf("add error: %v", err)
+	}
+
+	data, err := r.Tile(maptile.At(orb.Point{1, 1}, 10))
+	if err != nil {
+		t.Fatalf("tile error: %v", err)
+	}
+
+	layers, err := mvt.Unmarshal(data)
+	if err != nil {
+		t.Fatalf("unmarshal error: %v", err)
+	}
+
+	if len(layers) != 2 {
+		t.Fatalf("incorrect number of layers: %d", len(layers))
+	}
+
+	pois := layers[0]
+	if pois.Name != "pois" || len(pois.Features) != 1 {
+		t.Fatalf("incorrect pois layer: %v %d", pois.Name, len(pois.Features))
+	}
+
+	props := pois.Features[0].Properties
+	if v := props["amenity"]; v != "cafe" {
+		t.Errorf("incorrect amenity: %v", v)
+	}
+
+	if _, ok := props["name"]; ok {
+		t.Errorf("should only include selected keys: %v", props)
+	}
+
+	roads := layers[1]
+	if roads.Name != "roads" || len(roads.Features) != 1 {
+		t.Fatalf("incorrect roads layer: %v %d", roads.Name, len(roads.Features))
+	}
+
+	if v := roads.Features[0].Properties["ref"]; v != "A1" {
+		t.Errorf("should include all tags: %v", roads.Features[0].Properties)
+	}
+
+	// a tile with nothing in it
+	data, err = r.Tile(maptile.At(orb.Point{-50, -50}, 10))
+	if err != nil {
+		t.Fatalf("tile error: %v", err)
+	}
+
+	if data != nil {
+		t.Errorf("empty tile should be nil")
+	}
+}
+
+func TestFeatureID(t *testing.T) {
+	cases := []struct {
+		name     string
+		typ      string
+		id       int
+		expected uint64
+	}{
+		{name: "node", typ: "node", id: 5, expected: 51},
+		{name: "way", typ: "way", id: 5, expected: 52},
+		{name: "relation", typ: "relation", id: 5, expected: 53},
+		{name: "new element", typ: "node", id: -1, expected: 0},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			f := geojson.NewFeature(orb.Point{1, 1})
+			f.Properties["type"] = tc.typ
+			f.Properties["id"] = tc.id
+
+			lf := layerFeature(f, &Layer{Name: "a"}, nil)
+			if tc.expected == 0 {
+				if lf.ID != nil {
+					t.Errorf("should not have an id: %v", lf.ID)
+				}
+				return
+			}
+
+			if lf.ID != tc.expected {
+				t.Errorf("incorrect id: %v != %v", lf.ID, tc.expected)
+			}
+		})
+	}
+}
+
+func TestRenderer_Range(t *testing.T) {
+	r, err := New([]*Layer{
+		{Name: "roads", Filter: osm.HasTag("highway"), MinZoom: 5},
+	}, Gzip(true))
+	if err != nil {
+		t.Fatalf("new error: %v", err)
+	}
+
+	if err := r.Add(testOSM()); err != nil {
+		t.Fatalf("add error: %v", err)
+	}
+
+	tiles := map[maptile.Tile]bool{}
+	bound := orb.Bound{Min: orb.Point{0, 0}, Max: orb.Point{2, 2}}
+	err = r.Range(bound, 3, 6, func(tile maptile.Tile, data []byte) error {
+		if _, err := mvt.UnmarshalGzipped(data); err != nil {
+			t.Errorf("unmarshal error: %v", err)
+		}
+
+		tiles[tile] = true
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("range error: %v", err)
+	}
+
+	for tile := range tiles {
+		if tile.Z < 5 {
+			t.Errorf("should not render below min zoom: %v", tile)
+		}
+	}
+
+	if len(tiles) != 2 {
+		t.Errorf("incorrect number of tiles: %v", tiles)
+	}
+}
+
+func TestNew_errors(t *testing.T) {
+	if _, err := New(nil); err == nil {
+		t.Errorf("should require a layer")
+	}
+
+	if _, err := New([]*Layer{{Name: "a"}}, Buffer(-1)); err == nil {
+		t.Errorf("should error on negative buffer")
+	}
+}
@@ -1,6 +1,7 @@
 package osmpbf
 
 import (
+	"bufio"
 	"bytes"
 	"compress/zlib"
 	"context"
@@ -8,11 +9,13 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/gogo/protobuf/proto"
 	"github.com/paulmach/osm"
+	"github.com/paulmach/osm/metrics"
 	"github.com/paulmach/osm/osmpbf/internal/osmpbf"
 )
 
@@ -27,6 +30,13 @@ var (
 		"DenseNodes":            true,
 		"HistoricalInformation": true,
 	}
+
+	// ignoredFeatures are optional features that do not change the data.
+	ignoredFeatures = map[string]bool{
+		"Has_Metadata":      true,
+		"Sort.Type_then_ID": true,
+		"Sort.Geographic":   true,
+	}
 )
 
 // osm block data types
@@ -35,22 +45,52 @@ const (
 	osmDataType   = "OSMData"
 )
 
+// osmDataHeader is the start of an encoded OSMData blob header,
+// the type field, used to find the next block after corrupt data.
+var osmDataHeader = append([]byte{0x0a, byte(len(osmDataType))}, osmDataType...)
+
 // Header contains the contents of the header in the pbf file.
 type Header struct {
-	Bounds               *osm.Bounds
-	RequiredFeatures     []string
-	OptionalFeatures     []string
-	WritingProgram       string
-	Source               string
+	// Bounds is the bounding box of the data, nil if not set.
+	Bounds *osm.Bounds
+
+	// RequiredFeatures must be supported to read the file,
+	// e.g. DenseNodes or HistoricalInformation.
+	RequiredFeatures []string
+	OptionalFeatures []string
+
+	// WritingProgram is the program that wrote the file.
+	WritingProgram string
+	Source         string
+
+	// The replication state, from the Osmosis state.txt file, the data is
+	// current to. Used to continue updating the data with replication diffs.
 	ReplicationTimestamp time.Time
 	ReplicationSeqNum    uint64
 	ReplicationBaseURL   string
 }
 
+// Provenance returns the provenance embedded in the source field by
+// the WithProvenance encoder option. Returns nil if the source
+// is not a provenance, e.g. a file written by another tool.
+func (h *Header) Provenance() *osm.Provenance {
+	if !strings.HasPrefix(h.Source, "{") {
+		return nil
+	}
+
+	p, err := osm.ReadProvenance(strings.NewReader(h.Source))
+	if err != nil {
+		return nil
+	}
+
+	return p
+}
+
 // iPair is the group sent on the chan into the decoder
 // goroutines that unzip and decode the pbf from the headerblock.
 type iPair struct {
 	Offset int64
+	Size   int64
 	Blob   *osmpbf.Blob
 	Err    error
 }
@@ -61,6 +101,9 @@ type oPair struct {
 	Offset  int64
 	Objects []osm.Object
 	Err     error
+
+	// Info is set if building an index.
+	Info *BlockInfo
 }
 
 // A Decoder reads and decodes OpenStreetMap PBF data from an input stream.
@@ -69,9 +112,21 @@ type decoder struct {
 	r         io.Reader
 	bytesRead int64
 
-	ctx    context.Context
-	cancel func()
-	wg     sync.WaitGroup
+	// for seeking to the indexed blocks or the resume position
+	rs      io.ReadSeeker
+	indexed bool
+	blocks  []*BlockInfo
+	resume  Position
+
+	ctx     context.Context
+	cancel  func()
+	wg      sync.WaitGroup
+	metrics metrics.Recorder
+	logger  osm.Logger
+
+	skipCorrupt bool
+	index       *Index
+	filter      *filter
 
 	// for data decoders
 	inputs     []chan<- iPair
@@ -107,22 +162,72 @@ func (dec *decoder) Start(n int) error {
 	}
 	dec.serializer = make(chan oPair, n)
 
+	if dec.skipCorrupt {
+		// resyncing reads a byte at a time
+		dec.r = bufio.NewReader(dec.r)
+	}
+
 	sizeBuf := make([]byte, 4)
 	headerBuf := make([]byte, maxBlobHeaderSize)
 	blobBuf := make([]byte, maxBlobSize)
 
+	if dec.rs != nil {
+		// the header is at the start of the file
+		if _, err := dec.rs.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+	}
+
 	// read OSMHeader
 	blobHeader, blob, err := dec.readFileBlock(sizeBuf, headerBuf, blobBuf)
 	if err != nil {
 		return err
 	}
 
+	if dec.indexed {
+		blocks := dec.blocks[:0]
+		for _, bi := range dec.blocks {
+			if bi.Offset < dec.resume.Offset {
+				continue
+			}
+
+			if dec.filter == nil || dec.filter.wantBlock(bi) {
+				blocks = append(blocks, bi)
+			}
+		}
+		dec.blocks = blocks
+	}
+
+	// the first block is data, decode it if not reading only indexed
+	// blocks or it is one of them, and not resuming after it.
+	firstData := blobHeader.GetType() != osmHeaderType && dec.resume.Offset == 0
+	if firstData && dec.indexed {
+		firstData = len(dec.blocks) > 0 && dec.blocks[0].Offset == 0
+		if firstData {
+			dec.blocks = dec.blocks[1:]
+		}
+	}
+
+	if !dec.indexed && dec.resume.Offset > dec.bytesRead {
+		if err := dec.seek(dec.resume.Offset); err != nil {
+			return err
+		}
+	}
+
 	if blobHeader.GetType() == osmHeaderType {
 		var err error
 		dec.header, err = decodeOSMHeader(blob)
 		if err != nil {
 			return err
 		}
+
+		for _, f := range dec.header.OptionalFeatures {
+			if !ignoredFeatures[f] && !parseCapabilities[f] && !strings.HasPrefix(f, "timestamp=") {
+				dec.warn("osmpbf: ignoring optional feature", "feature", f)
+			}
+		}
+	} else {
+		dec.warn("osmpbf: missing OSMHeader block")
 	}
 
 	dec.wg.Add(n + 2)
@@ -140,10 +245,9 @@ func (dec *decoder) Start(n int) error {
 		input := make(chan iPair, n)
 		output := make(chan oPair, n)
 
-		dd := &dataDecoder{}
-		if i == 0 && blobHeader.GetType() != osmHeaderType {
-			objects, err := dd.Decode(blob)
-			output <- oPair{0, objects, err}
+		dd := &dataDecoder{filter: dec.filter}
+		if i == 0 && firstData {
+			output <- dec.decodePair(dd, iPair{Offset: 0, Size: dec.bytesRead, Blob: blob})
 		}
 
 		go func() {
@@ -154,10 +258,9 @@ func (dec *decoder) Start(n int) error {
 				var out oPair
 				if p.Err == nil {
 					// send decoded objects or decoding error
-					objects, err := dd.Decode(p.Blob)
-					out = oPair{p.Offset, objects, err}
+					out = dec.decodePair(dd, p)
 				} else {
-					out = oPair{0, nil, p.Err} // send input error as is
+					out = oPair{Err: p.Err} // send input error as is
 				}
 
 				select {
@@ -189,13 +292,29 @@ func (dec *decoder) Start(n int) error {
 			input := dec.inputs[i]
 			i = (i + 1) % n
 
+			err = nil
+			if dec.indexed {
+				err = dec.seekBlock()
+			}
+
 			offset := dec.bytesRead
-			blobHeader, blob, err = dec.readFileBlock(sizeBuf, headerBuf, blobBuf)
+			if err == nil {
+				blobHeader, blob, err = dec.readFileBlock(sizeBuf, headerBuf, blobBuf)
+			}
+			for err != nil && dec.skipCorrupt && err != io.EOF && err != io.ErrUnexpectedEOF && dec.ctx.Err() == nil {
+				dec.warn("osmpbf: skipping corrupt block", "offset", offset, "err", err.Error())
+				offset, blobHeader, blob, err = dec.resync(headerBuf, blobBuf)
+			}
+
+			if err == io.ErrUnexpectedEOF {
+				dec.warn("osmpbf: truncated block", "offset", offset)
+			}
+
 			if err == nil && blobHeader.GetType() != osmDataType {
 				err = fmt.Errorf("unexpected fileblock of type %s", blobHeader.GetType())
 			}
 
-			pair := iPair{Offset: offset, Blob: blob, Err: nil}
+			pair := iPair{Offset: offset, Size: dec.bytesRead - offset, Blob: blob, Err: nil}
 			if err != nil {
 				pair = iPair{Offset: 0, Blob: nil, Err: err}
 			}
@@ -241,6 +360,100 @@ func (dec *decoder) Start(n int) error {
 	return nil
 }
 
+// seekBlock moves the reader to the next indexed block to read.
+func (dec *decoder) seekBlock() error {
+	if len(dec.blocks) == 0 {
+		return io.EOF
+	}
+
+	offset := dec.blocks[0].Offset
+	dec.blocks = dec.blocks[1:]
+	if offset == dec.bytesRead {
+		return nil
+	}
+
+	return dec.seek(offset)
+}
+
+// seek moves the reader to the offset in the file.
+func (dec *decoder) seek(offset int64) error {
+	if _, err := dec.rs.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+
+	if br, ok := dec.r.(*bufio.Reader); ok {
+		br.Reset(dec.rs)
+	}
+
+	dec.bytesRead = offset
+	return nil
+}
+
+// decodePair decodes the block, skipping it if corrupt and requested,
+// and describes the block for the index if needed.
+func (dec *decoder) decodePair(dd *dataDecoder, p iPair) oPair {
+	objects, err := dec.decode(dd, p.Blob)
+	if err != nil && dec.skipCorrupt {
+		dec.warn("osmpbf: skipping corrupt block", "offset", p.Offset, "err", err.Error())
+		return oPair{Offset: p.Offset}
+	}
+
+	out := oPair{Offset: p.Offset, Objects: objects, Err: err}
+	if err == nil && dec.index != nil {
+		out.Info = newBlockInfo(p.Offset, p.Size, objects)
+	}
+
+	return out
+}
+
+func (dec *decoder) warn(msg string, keyvals ...interface{}) {
+	if dec.logger != nil {
+		dec.logger.Warn(msg, keyvals...)
+	}
+}
+
+// decode decodes the block and records the metrics if set.
+func (dec *decoder) decode(dd *dataDecoder, blob *osmpbf.Blob) ([]osm.Object, error) {
+	if dec.metrics == nil {
+		return dd.Decode(blob)
+	}
+
+	start := time.Now()
+	objects, err := dd.Decode(blob)
+	if err != nil {
+		return objects, err
+	}
+
+	dec.metrics.Observe(metrics.PBFBlockDecodeSeconds, time.Since(start).Seconds())
+	dec.metrics.Add(metrics.PBFBlocksDecoded, 1)
+
+	var nodes, ways, relations int
+	for _, o := range objects {
+		switch o.(type) {
+		case *osm.Node:
+			nodes++
+		case *osm.Way:
+			ways++
+		case *osm.Relation:
+			relations++
+		}
+	}
+
+	if nodes > 0 {
+		dec.metrics.Add(metrics.PBFElementsDecoded, float64(nodes), metrics.Label{Name: "type", Value: "node"})
+	}
+
+	if ways > 0 {
+		dec.metrics.Add(metrics.PBFElementsDecoded, float64(ways), metrics.Label{Name: "type", Value: "way"})
+	}
+
+	if relations > 0 {
+		dec.metrics.Add(metrics.PBFElementsDecoded, float64(relations), metrics.Label{Name: "type", Value: "relation"})
+	}
+
+	return objects, nil
+}
+
 // Next reads the next object from the input stream and returns either a
 // Node, Way or Relation struct representing the underlying OpenStreetMap PBF
 // data, or error encountered. The end of the input stream is reported by an io.EOF error.
@@ -258,6 +471,16 @@ func (dec *decoder) Next() (osm.Object, error) {
 		dec.cOffset = cd.Offset
 		dec.cData = cd
 		dec.cIndex = 0
+
+		if dec.resume.Index > 0 && cd.Offset == dec.resume.Offset {
+			// continue after the last element scanned before
+			dec.cIndex = dec.resume.Index
+		}
+		dec.resume.Index = 0
+
+		if cd.Info != nil {
+			dec.index.Blocks = append(dec.index.Blocks, cd.Info)
+		}
 	}
 
 	v := dec.cData.Objects[dec.cIndex]
@@ -271,8 +494,13 @@ func (dec *decoder) readFileBlock(sizeBuf, headerBuf, blobBuf []byte) (*osmpbf.B
 		return nil, nil, err
 	}
 
-	headerBuf = headerBuf[:blobHeaderSize]
-	blobHeader, err := dec.readBlobHeader(headerBuf)
+	return dec.readBlock(headerBuf[:blobHeaderSize], 0, blobBuf)
+}
+
+// readBlock reads the blob header, of which the first n bytes have
+// already been read into the buffer, and the blob.
+func (dec *decoder) readBlock(headerBuf []byte, n int, blobBuf []byte) (*osmpbf.BlobHeader, *osmpbf.Blob, error) {
+	blobHeader, err := dec.readBlobHeader(headerBuf, n)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -283,14 +511,48 @@ func (dec *decoder) readFileBlock(sizeBuf, headerBuf, blobBuf []byte) (*osmpbf.B
 		return nil, nil, err
 	}
 
-	dec.bytesRead += 4 + int64(blobHeaderSize) + int64(blobHeader.GetDatasize())
 	return blobHeader, blob, nil
 }
 
+// resync reads ahead, after corrupt data, to the next OSMData block
+// and returns it with its offset.
+func (dec *decoder) resync(headerBuf, blobBuf []byte) (int64, *osmpbf.BlobHeader, *osmpbf.Blob, error) {
+	l := 4 + len(osmDataHeader)
+	window := make([]byte, 0, l)
+
+	var b [1]byte
+	for {
+		if _, err := io.ReadFull(dec.r, b[:]); err != nil {
+			return 0, nil, nil, err
+		}
+		dec.bytesRead++
+
+		if len(window) == l {
+			window = append(window[:0], window[1:]...)
+		}
+		window = append(window, b[0])
+
+		if len(window) < l || !bytes.Equal(window[4:], osmDataHeader) {
+			continue
+		}
+
+		size := binary.BigEndian.Uint32(window)
+		if size < uint32(len(osmDataHeader)) || size >= maxBlobHeaderSize {
+			continue
+		}
+
+		offset := dec.bytesRead - int64(l)
+		n := copy(headerBuf, osmDataHeader)
+		blobHeader, blob, err := dec.readBlock(headerBuf[:size], n, blobBuf)
+		return offset, blobHeader, blob, err
+	}
+}
+
 func (dec *decoder) readBlobHeaderSize(buf []byte) (uint32, error) {
 	if _, err := io.ReadFull(dec.r, buf); err != nil {
 		return 0, err
 	}
+	dec.bytesRead += int64(len(buf))
 
 	size := binary.BigEndian.Uint32(buf)
 	if size >= maxBlobHeaderSize {
@@ -299,10 +561,11 @@ func (dec *decoder) readBlobHeaderSize(buf []byte) (uint32, error) {
 	return size, nil
 }
 
-func (dec *decoder) readBlobHeader(buf []byte) (*osmpbf.BlobHeader, error) {
-	if _, err := io.ReadFull(dec.r, buf); err != nil {
+func (dec *decoder) readBlobHeader(buf []byte, n int) (*osmpbf.BlobHeader, error) {
+	if _, err := io.ReadFull(dec.r, buf[n:]); err != nil {
 		return nil, err
 	}
+	dec.bytesRead += int64(len(buf) - n)
 
 	blobHeader := &osmpbf.BlobHeader{}
 	if err := proto.Unmarshal(buf, blobHeader); err != nil {
@@ -319,6 +582,7 @@ func (dec *decoder) readBlob(blobHeader *osmpbf.BlobHeader, buf []byte) (*osmpbf
 	if _, err := io.ReadFull(dec.r, buf); err != nil {
 		return nil, err
 	}
+	dec.bytesRead += int64(len(buf))
 
 	blob := &osmpbf.Blob{}
 	if err := proto.Unmarshal(buf, blob); err != nil {
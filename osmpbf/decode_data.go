@@ -19,7 +19,8 @@ type elementInfo struct {
 
 // dataDecoder is a decoder for Blob with OSMData (PrimitiveBlock).
 type dataDecoder struct {
-	q []osm.Object
+	q      []osm.Object
+	filter *filter
 }
 
 func (dec *dataDecoder) Decode(blob *osmpbf.Blob) ([]osm.Object, error) {
@@ -30,12 +31,30 @@ func (dec *dataDecoder) Decode(blob *osmpbf.Blob) ([]osm.Object, error) {
 		return nil, err
 	}
 
+	if dec.filter != nil {
+		data, err = dec.filter.primitiveBlock(data)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	primitiveBlock := &osmpbf.PrimitiveBlock{}
 	if err := proto.Unmarshal(data, primitiveBlock); err != nil {
 		return nil, err
 	}
 
 	dec.parsePrimitiveBlock(primitiveBlock)
+
+	if dec.filter != nil && (dec.filter.minID != 0 || dec.filter.maxID != 0) {
+		q := dec.q[:0]
+		for _, o := range dec.q {
+			if dec.filter.wantObject(o) {
+				q = append(q, o)
+			}
+		}
+		dec.q = q
+	}
+
 	return dec.q, nil
 }
 
@@ -0,0 +1,624 @@
+package osmpbf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+
+	"github.com/paulmach/osm"
+	"github.com/paulmach/osm/osmpbf/internal/osmpbf"
+)
+
+const (
+	defaultBlockSize = 8000
+
+	// the defaults from the osmformat.proto, used for all blocks written.
+	granularity     = 100
+	dateGranularity = 1000
+)
+
+// An EncoderOption is a setting for writing pbf data.
+type EncoderOption func(*Encoder) error
+
+// WithHeader sets the information written in the OSMHeader block.
+// The required features are set by the encoder and will be ignored.
+// Options setting single header fields should come after this one.
+func WithHeader(h *Header) EncoderOption {
+	return func(e *Encoder) error {
+		if h == nil {
+			e.header = nil
+			return nil
+		}
+
+		hc := *h
+		e.header = &hc
+		return nil
+	}
+}
+
+// WritingProgram sets the program that wrote the file in the header.
+// The default is github.com/paulmach/osm.
+func WritingProgram(name string) EncoderOption {
+	return func(e *Encoder) error {
+		e.headerToSet().WritingProgram = name
+		return nil
+	}
+}
+
+// HeaderBounds sets the bounding box of the data in the header.
+func HeaderBounds(b *osm.Bounds) EncoderOption {
+	return func(e *Encoder) error {
+		e.headerToSet().Bounds = b
+		return nil
+	}
+}
+
+// ReplicationTimestamp sets the timestamp of the replication state,
+// the timestamp in the Osmosis state.txt file, the data is current to.
+func ReplicationTimestamp(t time.Time) EncoderOption {
+	return func(e *Encoder) error {
+		e.headerToSet().ReplicationTimestamp = t
+		return nil
+	}
+}
+
+// ReplicationSeqNum sets the replication sequence number, the sequenceNumber
+// in the Osmosis state.txt file, the data is current to.
+func ReplicationSeqNum(n uint64) EncoderOption {
+	return func(e *Encoder) error {
+		e.headerToSet().ReplicationSeqNum = n
+		return nil
+	}
+}
+
+// ReplicationBaseURL sets the url of the replication diffs that can be
+// applied to keep the data up to date.
+func ReplicationBaseURL(url string) EncoderOption {
+	return func(e *Encoder) error {
+		e.headerToSet().ReplicationBaseURL = url
+		return nil
+	}
+}
+
+// BlockSize sets the maximum number of elements written in each data block.
+// The default is 8000, the value used by most other tools.
+func BlockSize(n int) EncoderOption {
+	return func(e *Encoder) error {
+		if n <= 0 {
+			return errors.New("osmpbf: block size must be positive")
+		}
+
+		e.blockSize = n
+		return nil
+	}
+}
+
+// History will write the visible flag for every element and mark the file
+// as containing historical information. Without this option all elements are
+// assumed to be visible, which is correct for most extracts.
+func History(yes bool) EncoderOption {
+	return func(e *Encoder) error {
+		e.history = yes
+		return nil
+	}
+}
+
+// Deterministic will write the tags of every element sorted by key and value
+// so the output is byte identical for the same data, no matter the order of
+// the tags. The string table of each block, and the order of the blocks,
+// only depend on the elements and are always the same.
+func Deterministic(yes bool) EncoderOption {
+	return func(e *Encoder) error {
+		e.deterministic = yes
+		return nil
+	}
+}
+
+// Append will not write the OSMHeader block, for adding data blocks to
+// the end of a file that already has one, e.g. written by another encoder.
+func Append(yes bool) EncoderOption {
+	return func(e *Encoder) error {
+		e.wroteHeader = yes
+		return nil
+	}
+}
+
+// WithLogger sets the logger that receives warnings, e.g. for elements
+// that are not in node, way, relation order and are written in smaller
+// groups.
+func WithLogger(l osm.Logger) EncoderOption {
+	return func(e *Encoder) error {
+		e.logger = l
+		return nil
+	}
+}
+
+// WithProvenance embeds the provenance, as json, in the source field of
+// the header. The tool and replication sequence number, if set, are also
+// written to the writing program and replication header fields.
+// It can be read back using Header.Provenance.
+func WithProvenance(p *osm.Provenance) EncoderOption {
+	return func(e *Encoder) error {
+		data, err := json.Marshal(p)
+		if err != nil {
+			return err
+		}
+
+		h := e.headerToSet()
+		h.Source = string(data)
+		if p.Tool != "" {
+			h.WritingProgram = p.Tool
+		}
+
+		if p.ReplicationSeqNum != 0 {
+			h.ReplicationSeqNum = p.ReplicationSeqNum
+		}
+
+		return nil
+	}
+}
+
+// An Encoder writes osm data to an output stream in the OpenStreetMap PBF format.
+// Elements should be encoded in the typical node, way, relation order so
+// they can be grouped efficiently. Close must be called to write the final block.
+type Encoder struct {
+	w         io.Writer
+	header    *Header
+	blockSize int
+	history   bool
+	logger    osm.Logger
+
+	deterministic bool
+
+	wroteHeader bool
+	lastType    osm.Type
+	unordered   bool
+	block       *blockBuilder
+	err         error
+}
+
+// NewEncoder returns a new encoder that writes to w.
+func NewEncoder(w io.Writer, opts ...EncoderOption) (*Encoder, error) {
+	e := &Encoder{
+		w:         w,
+		blockSize: defaultBlockSize,
+	}
+
+	for _, opt := range opts {
+		if err := opt(e); err != nil {
+			return nil, err
+		}
+	}
+
+	e.block = newBlockBuilder(e.history, e.deterministic)
+	return e, nil
+}
+
+// Encode adds the object to the current data block, writing the block
+// if it is full. Only nodes, ways and relations are supported.
+func (e *Encoder) Encode(o osm.Object) error {
+	if e.err != nil {
+		return e.err
+	}
+
+	if err := e.writeHeader(); err != nil {
+		return err
+	}
+
+	if o, ok := o.(osm.Element); ok {
+		e.checkOrder(o.ElementID())
+	}
+
+	switch o := o.(type) {
+	case *osm.Node:
+		e.block.addNode(o)
+	case *osm.Way:
+		e.block.addWay(o)
+	case *osm.Relation:
+		e.block.addRelation(o)
+	default:
+		return fmt.Errorf("osmpbf: unsupported type %T", o)
+	}
+
+	if e.block.count >= e.blockSize {
+		return e.Flush()
+	}
+
+	return nil
+}
+
+// checkOrder reports the first element that comes after one of a later type.
+func (e *Encoder) checkOrder(id osm.ElementID) {
+	t := id.Type()
+	if e.logger != nil && !e.unordered && typeOrder[t] < typeOrder[e.lastType] {
+		e.unordered = true
+		e.logger.Warn("osmpbf: elements are not in node, way, relation order",
+			"feature", id.FeatureID().String(), "after", string(e.lastType))
+	}
+
+	e.lastType = t
+}
+
+var typeOrder = map[osm.Type]int{
+	osm.TypeNode:     1,
+	osm.TypeWay:      2,
+	osm.TypeRelation: 3,
+}
+
+// Flush writes any pending elements as a data block.
+func (e *Encoder) Flush() error {
+	if e.err != nil {
+		return e.err
+	}
+
+	if err := e.writeHeader(); err != nil {
+		return err
+	}
+
+	if e.block.count == 0 {
+		return nil
+	}
+
+	data, err := e.block.primitiveBlock().Marshal()
+	if err != nil {
+		e.err = err
+		return err
+	}
+
+	e.block = newBlockBuilder(e.history, e.deterministic)
+	e.err = e.writeFileBlock(osmDataType, data)
+	return e.err
+}
+
+// Close flushes the final data block. It does not close the underlying writer.
+func (e *Encoder) Close() error {
+	return e.Flush()
+}
+
+// headerToSet returns the header the options set fields on.
+func (e *Encoder) headerToSet() *Header {
+	if e.header == nil {
+		e.header = &Header{}
+	}
+
+	return e.header
+}
+
+func (e *Encoder) writeHeader() error {
+	if e.wroteHeader {
+		return nil
+	}
+	e.wroteHeader = true
+
+	data, err := encodeOSMHeader(e.header, e.history).Marshal()
+	if err != nil {
+		e.err = err
+		return err
+	}
+
+	e.err = e.writeFileBlock(osmHeaderType, data)
+	return e.err
+}
+
+func (e *Encoder) writeFileBlock(t string, data []byte) error {
+	buf := &bytes.Buffer{}
+	zw := zlib.NewWriter(buf)
+	if _, err := zw.Write(data); err != nil {
+		return err
+	}
+
+	if err := zw.Close(); err != nil {
+		return err
+	}
+
+	blob, err := (&osmpbf.Blob{
+		RawSize:  int32(len(data)),
+		ZlibData: buf.Bytes(),
+	}).Marshal()
+	if err != nil {
+		return err
+	}
+
+	if len(blob) >= maxBlobSize {
+		return errors.New("osmpbf: Blob size >= 32Mb, use a smaller block size")
+	}
+
+	blobHeader, err := (&osmpbf.BlobHeader{
+		Type:     t,
+		Datasize: int32(len(blob)),
+	}).Marshal()
+	if err != nil {
+		return err
+	}
+
+	size := make([]byte, 4)
+	binary.BigEndian.PutUint32(size, uint32(len(blobHeader)))
+
+	for _, d := range [][]byte{size, blobHeader, blob} {
+		if _, err := e.w.Write(d); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func encodeOSMHeader(h *Header, history bool) *osmpbf.HeaderBlock {
+	hb := &osmpbf.HeaderBlock{
+		RequiredFeatures: []string{"OsmSchema-V0.6", "DenseNodes"},
+		Writingprogram:   "github.com/paulmach/osm",
+	}
+
+	if history {
+		hb.RequiredFeatures = append(hb.RequiredFeatures, "HistoricalInformation")
+	}
+
+	if h == nil {
+		return hb
+	}
+
+	hb.OptionalFeatures = h.OptionalFeatures
+	if h.WritingProgram != "" {
+		hb.Writingprogram = h.WritingProgram
+	}
+	hb.Source = h.Source
+	hb.OsmosisReplicationBaseUrl = h.ReplicationBaseURL
+	hb.OsmosisReplicationSequenceNumber = int64(h.ReplicationSeqNum)
+
+	if !h.ReplicationTimestamp.IsZero() {
+		hb.OsmosisReplicationTimestamp = h.ReplicationTimestamp.Unix()
+	}
+
+	if h.Bounds != nil {
+		// Units are always in nanodegree and do not obey granularity rules. See osmformat.proto
+		hb.Bbox = &osmpbf.HeaderBBox{
+			Left:   int64(math.Round(h.Bounds.MinLon * 1e9)),
+			Right:  int64(math.Round(h.Bounds.MaxLon * 1e9)),
+			Top:    int64(math.Round(h.Bounds.MaxLat * 1e9)),
+			Bottom: int64(math.Round(h.Bounds.MinLat * 1e9)),
+		}
+	}
+
+	return hb
+}
+
+// blockBuilder accumulates elements into the primitive groups
+// of a single PrimitiveBlock.
+type blockBuilder struct {
+	history  bool
+	sortTags bool
+	count    int
+
+	strings map[string]int32
+	table   []string
+
+	groups []*osmpbf.PrimitiveGroup
+	dense  *denseState
+}
+
+// denseState holds the previous values needed for the delta encoding
+// of the current dense nodes group.
+type denseState struct {
+	id, lat, lon         int64
+	timestamp, changeset int64
+	uid, userSid         int32
+}
+
+func newBlockBuilder(history, sortTags bool) *blockBuilder {
+	return &blockBuilder{
+		history:  history,
+		sortTags: sortTags,
+		// index 0 is reserved as the delimiter in dense nodes key/vals,
+		// the empty string is not mapped to it so empty tag keys and
+		// values get their own index.
+		strings: make(map[string]int32),
+		table:   []string{""},
+	}
+}
+
+func (b *blockBuilder) stringID(s string) int32 {
+	if id, ok := b.strings[s]; ok {
+		return id
+	}
+
+	id := int32(len(b.table))
+	b.strings[s] = id
+	b.table = append(b.table, s)
+	return id
+}
+
+// group returns the current group if it is of the given type, or starts
+// a new one. The spec requires each group to contain a single type.
+func (b *blockBuilder) group(t osm.Type) *osmpbf.PrimitiveGroup {
+	if len(b.groups) > 0 {
+		g := b.groups[len(b.groups)-1]
+		switch {
+		case t == osm.TypeNode && g.Dense != nil,
+			t == osm.TypeWay && len(g.Ways) > 0,
+			t == osm.TypeRelation && len(g.Relations) > 0:
+			return g
+		}
+	}
+
+	g := &osmpbf.PrimitiveGroup{}
+	if t == osm.TypeNode {
+		g.Dense = &osmpbf.DenseNodes{Denseinfo: &osmpbf.DenseInfo{}}
+		b.dense = &denseState{}
+	}
+
+	b.groups = append(b.groups, g)
+	return g
+}
+
+func (b *blockBuilder) addNode(n *osm.Node) {
+	dn := b.group(osm.TypeNode).Dense
+	s := b.dense
+	b.count++
+
+	id := int64(n.ID)
+	lat := toGranularity(n.Lat)
+	lon := toGranularity(n.Lon)
+	dn.Id = append(dn.Id, id-s.id)
+	dn.Lat = append(dn.Lat, lat-s.lat)
+	dn.Lon = append(dn.Lon, lon-s.lon)
+	s.id, s.lat, s.lon = id, lat, lon
+
+	for _, t := range b.sorted(n.Tags) {
+		dn.KeysVals = append(dn.KeysVals, b.stringID(t.Key), b.stringID(t.Value))
+	}
+	dn.KeysVals = append(dn.KeysVals, 0)
+
+	di := dn.Denseinfo
+	timestamp := toDateGranularity(n.Timestamp)
+	changeset := int64(n.ChangesetID)
+	uid := int32(n.UserID)
+	userSid := b.stringID(n.User)
+
+	di.Version = append(di.Version, int32(n.Version))
+	di.Timestamp = append(di.Timestamp, timestamp-s.timestamp)
+	di.Changeset = append(di.Changeset, changeset-s.changeset)
+	di.Uid = append(di.Uid, uid-s.uid)
+	di.UserSid = append(di.UserSid, userSid-s.userSid)
+	s.timestamp, s.changeset, s.uid, s.userSid = timestamp, changeset, uid, userSid
+
+	if b.history {
+		di.Visible = append(di.Visible, n.Visible)
+	}
+}
+
+func (b *blockBuilder) addWay(w *osm.Way) {
+	g := b.group(osm.TypeWay)
+	b.count++
+
+	pw := &osmpbf.Way{
+		Id:   int64(w.ID),
+		Info: b.info(w.Version, w.Timestamp, w.ChangesetID, w.UserID, w.User, w.Visible),
+	}
+	pw.Keys, pw.Vals = b.tags(w.Tags)
+
+	if len(w.Nodes) > 0 {
+		var prev int64
+		pw.Refs = make([]int64, len(w.Nodes))
+		for i, wn := range w.Nodes {
+			pw.Refs[i] = int64(wn.ID) - prev // delta encoding
+			prev = int64(wn.ID)
+		}
+	}
+
+	g.Ways = append(g.Ways, pw)
+}
+
+func (b *blockBuilder) addRelation(r *osm.Relation) {
+	g := b.group(osm.TypeRelation)
+	b.count++
+
+	pr := &osmpbf.Relation{
+		Id:   int64(r.ID),
+		Info: b.info(r.Version, r.Timestamp, r.ChangesetID, r.UserID, r.User, r.Visible),
+	}
+	pr.Keys, pr.Vals = b.tags(r.Tags)
+
+	if len(r.Members) > 0 {
+		var prev int64
+		pr.RolesSid = make([]int32, len(r.Members))
+		pr.Memids = make([]int64, len(r.Members))
+		pr.Types = make([]osmpbf.Relation_MemberType, len(r.Members))
+		for i, m := range r.Members {
+			pr.RolesSid[i] = b.stringID(m.Role)
+			pr.Memids[i] = m.Ref - prev // delta encoding
+			prev = m.Ref
+
+			switch m.Type {
+			case osm.TypeNode:
+				pr.Types[i] = osmpbf.Relation_NODE
+			case osm.TypeWay:
+				pr.Types[i] = osmpbf.Relation_WAY
+			case osm.TypeRelation:
+				pr.Types[i] = osmpbf.Relation_RELATION
+			}
+		}
+	}
+
+	g.Relations = append(g.Relations, pr)
+}
+
+func (b *blockBuilder) tags(tags osm.Tags) ([]uint32, []uint32) {
+	if len(tags) == 0 {
+		return nil, nil
+	}
+
+	keys := make([]uint32, len(tags))
+	vals := make([]uint32, len(tags))
+	for i, t := range b.sorted(tags) {
+		keys[i] = uint32(b.stringID(t.Key))
+		vals[i] = uint32(b.stringID(t.Value))
+	}
+
+	return keys, vals
+}
+
+// sorted returns the tags in the order they are written,
+// a sorted copy if writing deterministic output.
+func (b *blockBuilder) sorted(tags osm.Tags) osm.Tags {
+	if !b.sortTags {
+		return tags
+	}
+
+	sorted := append(osm.Tags(nil), tags...)
+	sorted.SortByKeyValue()
+	return sorted
+}
+
+func (b *blockBuilder) info(
+	version int,
+	timestamp time.Time,
+	changeset osm.ChangesetID,
+	uid osm.UserID,
+	user string,
+	visible bool,
+) *osmpbf.Info {
+	v := int32(version)
+	info := &osmpbf.Info{
+		Version:   &v,
+		Timestamp: toDateGranularity(timestamp),
+		Changeset: int64(changeset),
+		Uid:       int32(uid),
+		UserSid:   uint32(b.stringID(user)),
+	}
+
+	if b.history {
+		info.Visible = &visible
+	}
+
+	return info
+}
+
+func (b *blockBuilder) primitiveBlock() *osmpbf.PrimitiveBlock {
+	g, dg := int32(granularity), int32(dateGranularity)
+	return &osmpbf.PrimitiveBlock{
+		Stringtable:     &osmpbf.StringTable{S: b.table},
+		Primitivegroup:  b.groups,
+		Granularity:     &g,
+		DateGranularity: &dg,
+	}
+}
+
+func toGranularity(v float64) int64 {
+	return int64(math.Round(v * 1e9 / granularity))
+}
+
+func toDateGranularity(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+
+	// UnixNano overflows outside the years 1678 to 2262
+	ms := t.Unix()*1000 + int64(t.Nanosecond())/int64(time.Millisecond)
+	return ms / dateGranularity
+}
@@ -0,0 +1,454 @@
+package osmpbf
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/paulmach/osm"
+)
+
+func TestEncoder(t *testing.T) {
+	ts := time.Date(2018, 1, 2, 3, 4, 5, 0, time.UTC)
+	objects := osm.Objects{
+		&osm.Node{ID: 1, Lat: 1.5, Lon: -2.25, Version: 3, ChangesetID: 5, UserID: 7, User: "user",
+			Visible: true, Timestamp: ts, Tags: osm.Tags{{Key: "amenity", Value: "cafe"}}},
+		&osm.Node{ID: 2, Lat: 1.6, Lon: -2.35, Version: 1, ChangesetID: 4, UserID: 8, User: "other",
+			Visible: true, Timestamp: ts.Add(time.Hour)},
+		&osm.Way{ID: 3, Version: 1, ChangesetID: 5, UserID: 7, User: "user", Visible: true, Timestamp: ts,
+			Nodes: osm.WayNodes{{ID: 2}, {ID: 1}}, Tags: osm.Tags{{Key: "highway", Value: "primary"}}},
+		&osm.Relation{ID: 4, Version: 2, ChangesetID: 6, UserID: 7, User: "user", Visible: true, Timestamp: ts,
+			Members: osm.Members{
+				{Type: osm.TypeWay, Ref: 3, Role: "outer"},
+				{Type: osm.TypeNode, Ref: 1, Role: "label"},
+			},
+			Tags: osm.Tags{{Key: "type", Value: "multipolygon"}}},
+	}
+
+	buf := &bytes.Buffer{}
+	enc, err := NewEncoder(buf, BlockSize(3), WithHeader(&Header{
+		Source:               "test",
+		ReplicationTimestamp: ts,
+		ReplicationSeqNum:    10,
+		Bounds:               &osm.Bounds{MinLat: 1, MaxLat: 2, MinLon: -3, MaxLon: -2},
+	}))
+	if err != nil {
+		t.Fatalf("new encoder error: %v", err)
+	}
+
+	for _, o := range objects {
+		if err := enc.Encode(o); err != nil {
+			t.Fatalf("encode error: %v", err)
+		}
+	}
+
+	if err := enc.Close(); err != nil {
+		t.Fatalf("close error: %v", err)
+	}
+
+	scanner := New(context.Background(), buf, 1)
+	defer scanner.Close()
+
+	header, err := scanner.Header()
+	if err != nil {
+		t.Fatalf("header error: %v", err)
+	}
+
+	if header.Source != "test" || header.ReplicationSeqNum != 10 || !header.ReplicationTimestamp.Equal(ts) {
+		t.Errorf("incorrect header: %+v", header)
+	}
+
+	if header.Bounds.MinLon != -3 || header.Bounds.MaxLat != 2 {
+		t.Errorf("incorrect header bounds: %+v", header.Bounds)
+	}
+
+	var result osm.Objects
+	for scanner.Scan() {
+		result = append(result, scanner.Object())
+	}
+
+	if err := scanner.Err(); err != nil {
+		t.Fatalf("scan error: %v", err)
+	}
+
+	if !reflect.DeepEqual(result, objects) {
+		t.Errorf("incorrect objects")
+		for i := range result {
+			t.Logf("%+v", result[i])
+			t.Logf("%+v", objects[i])
+		}
+	}
+}
+
+func TestEncoder_history(t *testing.T) {
+	buf := &bytes.Buffer{}
+	enc, err := NewEncoder(buf, History(true))
+	if err != nil {
+		t.Fatalf("new encoder error: %v", err)
+	}
+
+	n := &osm.Node{ID: 1, Version: 2, Visible: false, Timestamp: time.Unix(100, 0).UTC()}
+	if err := enc.Encode(n); err != nil {
+		t.Fatalf("encode error: %v", err)
+	}
+
+	if err := enc.Close(); err != nil {
+		t.Fatalf("close error: %v", err)
+	}
+
+	scanner := New(context.Background(), buf, 1)
+	defer scanner.Close()
+
+	if !scanner.Scan() {
+		t.Fatalf("should scan node: %v", scanner.Err())
+	}
+
+	if v := scanner.Object().(*osm.Node).Visible; v {
+		t.Errorf("should not be visible")
+	}
+
+	header, _ := scanner.Header()
+	if !reflect.DeepEqual(header.RequiredFeatures, []string{"OsmSchema-V0.6", "DenseNodes", "HistoricalInformation"}) {
+		t.Errorf("incorrect required features: %v", header.RequiredFeatures)
+	}
+}
+
+func TestEncoder_emptyTags(t *testing.T) {
+	// the empty string must not be encoded as the dense nodes delimiter
+	objects := osm.Objects{
+		&osm.Node{ID: 1, Visible: true, Timestamp: time.Unix(100, 0).UTC(),
+			Tags: osm.Tags{{Key: "", Value: "empty key"}, {Key: "note", Value: ""}}},
+		&osm.Node{ID: 2, Visible: true, Timestamp: time.Unix(100, 0).UTC(),
+			Tags: osm.Tags{{Key: "amenity", Value: "cafe"}}},
+		&osm.Way{ID: 3, Visible: true, Timestamp: time.Unix(100, 0).UTC(),
+			Nodes: osm.WayNodes{{ID: 1}, {ID: 2}}, Tags: osm.Tags{{Key: "", Value: ""}}},
+	}
+
+	buf := &bytes.Buffer{}
+	enc, err := NewEncoder(buf)
+	if err != nil {
+		t.Fatalf("new encoder error: %v", err)
+	}
+
+	for _, o := range objects {
+		if err := enc.Encode(o); err != nil {
+			t.Fatalf("encode error: %v", err)
+		}
+	}
+
+	if err := enc.Close(); err != nil {
+		t.Fatalf("close error: %v", err)
+	}
+
+	scanner := New(context.Background(), buf, 1)
+	defer scanner.Close()
+
+	var result osm.Objects
+	for scanner.Scan() {
+		result = append(result, scanner.Object())
+	}
+
+	if err := scanner.Err(); err != nil {
+		t.Fatalf("scan error: %v", err)
+	}
+
+	if !reflect.DeepEqual(result, objects) {
+		t.Errorf("incorrect objects")
+		for i := range result {
+			t.Logf("%+v", result[i])
+			t.Logf("%+v", objects[i])
+		}
+	}
+}
+
+func TestEncoder_roundTrip(t *testing.T) {
+	f, err := os.Open(Delaware)
+	if err != nil {
+		t.Fatalf("unable to open file: %v", err)
+	}
+	defer f.Close()
+
+	scanner := New(context.Background(), f, 1)
+	defer scanner.Close()
+
+	buf := &bytes.Buffer{}
+	enc, err := NewEncoder(buf)
+	if err != nil {
+		t.Fatalf("new encoder error: %v", err)
+	}
+
+	var expected osm.Objects
+	for i := 0; i < 20000 && scanner.Scan(); i++ {
+		expected = append(expected, scanner.Object())
+		if err := enc.Encode(scanner.Object()); err != nil {
+			t.Fatalf("encode error: %v", err)
+		}
+	}
+
+	if err := enc.Close(); err != nil {
+		t.Fatalf("close error: %v", err)
+	}
+
+	result := New(context.Background(), buf, 1)
+	defer result.Close()
+
+	i := 0
+	for result.Scan() {
+		if !reflect.DeepEqual(result.Object(), expected[i]) {
+			t.Fatalf("incorrect object %d: %v != %v", i, result.Object(), expected[i])
+		}
+		i++
+	}
+
+	if err := result.Err(); err != nil {
+		t.Fatalf("scan error: %v", err)
+	}
+
+	if i != len(expected) {
+		t.Errorf("incorrect number of objects: %d != %d", i, len(expected))
+	}
+}
+
+func TestEncoder_Append(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	enc, _ := NewEncoder(buf)
+	if err := enc.Encode(&osm.Node{ID: 1, Visible: true}); err != nil {
+		t.Fatalf("encode error: %v", err)
+	}
+
+	if err := enc.Close(); err != nil {
+		t.Fatalf("close error: %v", err)
+	}
+
+	enc, _ = NewEncoder(buf, Append(true))
+	if err := enc.Encode(&osm.Way{ID: 2, Visible: true}); err != nil {
+		t.Fatalf("encode error: %v", err)
+	}
+
+	if err := enc.Close(); err != nil {
+		t.Fatalf("close error: %v", err)
+	}
+
+	logger := &testLogger{}
+	scanner := New(context.Background(), buf, 1)
+	scanner.Logger = logger
+	defer scanner.Close()
+
+	var ids []osm.ObjectID
+	for scanner.Scan() {
+		ids = append(ids, scanner.Object().ObjectID())
+	}
+
+	if err := scanner.Err(); err != nil {
+		t.Fatalf("scan error: %v", err)
+	}
+
+	expected := []osm.ObjectID{osm.NodeID(1).ObjectID(0), osm.WayID(2).ObjectID(0)}
+	if !reflect.DeepEqual(ids, expected) {
+		t.Errorf("incorrect objects: %v", ids)
+	}
+
+	if len(logger.warnings) != 0 {
+		t.Errorf("should not have warnings: %v", logger.warnings)
+	}
+}
+
+func TestEncoder_headerOptions(t *testing.T) {
+	ts := time.Date(2018, 1, 2, 3, 4, 5, 0, time.UTC)
+	h := &Header{Source: "test", WritingProgram: "other"}
+
+	buf := &bytes.Buffer{}
+	enc, err := NewEncoder(buf,
+		WithHeader(h),
+		WritingProgram("osmium/1.8.0"),
+		HeaderBounds(&osm.Bounds{MinLat: 1, MaxLat: 2, MinLon: -3, MaxLon: -2}),
+		ReplicationTimestamp(ts),
+		ReplicationSeqNum(2468),
+		ReplicationBaseURL("https://planet.openstreetmap.org/replication/minute"),
+	)
+	if err != nil {
+		t.Fatalf("new encoder error: %v", err)
+	}
+
+	if err := enc.Close(); err != nil {
+		t.Fatalf("close error: %v", err)
+	}
+
+	if h.WritingProgram != "other" {
+		t.Errorf("should not modify the header option: %+v", h)
+	}
+
+	scanner := New(context.Background(), buf, 1)
+	defer scanner.Close()
+
+	header, err := scanner.Header()
+	if err != nil {
+		t.Fatalf("header error: %v", err)
+	}
+
+	expected := &Header{
+		Bounds:               &osm.Bounds{MinLat: 1, MaxLat: 2, MinLon: -3, MaxLon: -2},
+		RequiredFeatures:     []string{"OsmSchema-V0.6", "DenseNodes"},
+		WritingProgram:       "osmium/1.8.0",
+		Source:               "test",
+		ReplicationTimestamp: ts,
+		ReplicationSeqNum:    2468,
+		ReplicationBaseURL:   "https://planet.openstreetmap.org/replication/minute",
+	}
+
+	if !reflect.DeepEqual(header, expected) {
+		t.Errorf("incorrect header: %+v", header)
+	}
+}
+
+func TestEncoder_WithProvenance(t *testing.T) {
+	p := &osm.Provenance{Tool: "extract/1.0", ReplicationSeqNum: 2468}
+	p.AddInput("delaware-latest.osm.pbf", nil)
+	p.AddFilter("highways")
+
+	buf := &bytes.Buffer{}
+	enc, err := NewEncoder(buf, WithProvenance(p))
+	if err != nil {
+		t.Fatalf("new encoder error: %v", err)
+	}
+
+	if err := enc.Close(); err != nil {
+		t.Fatalf("close error: %v", err)
+	}
+
+	scanner := New(context.Background(), buf, 1)
+	defer scanner.Close()
+
+	header, err := scanner.Header()
+	if err != nil {
+		t.Fatalf("header error: %v", err)
+	}
+
+	if header.WritingProgram != "extract/1.0" || header.ReplicationSeqNum != 2468 {
+		t.Errorf("incorrect header: %+v", header)
+	}
+
+	if v := header.Provenance(); !reflect.DeepEqual(v, p) {
+		t.Errorf("incorrect provenance: %+v", v)
+	}
+
+	header.Source = "survey"
+	if v := header.Provenance(); v != nil {
+		t.Errorf("should be nil for other sources: %+v", v)
+	}
+}
+
+func TestEncoder_deterministic(t *testing.T) {
+	encode := func(tags osm.Tags) []byte {
+		buf := &bytes.Buffer{}
+		enc, err := NewEncoder(buf, Deterministic(true))
+		if err != nil {
+			t.Fatalf("new encoder error: %v", err)
+		}
+
+		objects := osm.Objects{
+			&osm.Node{ID: 1, Tags: tags},
+			&osm.Way{ID: 2, Tags: tags},
+			&osm.Relation{ID: 3, Tags: tags},
+		}
+
+		for _, o := range objects {
+			if err := enc.Encode(o); err != nil {
+				t.Fatalf("encode error: %v", err)
+			}
+		}
+
+		if err := enc.Close(); err != nil {
+			t.Fatalf("close error: %v", err)
+		}
+
+		return buf.Bytes()
+	}
+
+	tags := osm.Tags{{Key: "z", Value: "26"}, {Key: "a", Value: "1"}}
+	d1 := encode(tags)
+	d2 := encode(osm.Tags{{Key: "a", Value: "1"}, {Key: "z", Value: "26"}})
+	if !bytes.Equal(d1, d2) {
+		t.Errorf("should be byte identical")
+	}
+
+	if tags[0].Key != "z" {
+		t.Errorf("should not modify the input tags: %v", tags)
+	}
+}
+
+func TestEncoder_errors(t *testing.T) {
+	if _, err := NewEncoder(&bytes.Buffer{}, BlockSize(0)); err == nil {
+		t.Errorf("should error on zero block size")
+	}
+
+	enc, _ := NewEncoder(&bytes.Buffer{})
+	if err := enc.Encode(&osm.Changeset{ID: 1}); err == nil {
+		t.Errorf("should error on unsupported type")
+	}
+}
+
+func TestEncoder_WithLogger(t *testing.T) {
+	logger := &testLogger{}
+	enc, _ := NewEncoder(&bytes.Buffer{}, WithLogger(logger))
+
+	objects := osm.Objects{
+		&osm.Node{ID: 1},
+		&osm.Way{ID: 2},
+		&osm.Node{ID: 3},
+		&osm.Relation{ID: 4},
+		&osm.Node{ID: 5},
+	}
+
+	for _, o := range objects {
+		if err := enc.Encode(o); err != nil {
+			t.Fatalf("encode error: %v", err)
+		}
+	}
+
+	expected := []string{"osmpbf: elements are not in node, way, relation order [feature node/3 after way]"}
+	if w := logger.Warnings(); !reflect.DeepEqual(w, expected) {
+		t.Errorf("incorrect warnings: %v", w)
+	}
+}
+
+func TestToDateGranularity(t *testing.T) {
+	cases := []struct {
+		name     string
+		time     time.Time
+		expected int64
+	}{
+		{
+			name:     "zero",
+			time:     time.Time{},
+			expected: 0,
+		},
+		{
+			name:     "milliseconds are dropped",
+			time:     time.Date(2018, 1, 2, 3, 4, 5, 999000000, time.UTC),
+			expected: 1514862245,
+		},
+		{
+			name:     "after 2262",
+			time:     time.Date(2500, 1, 1, 0, 0, 0, 0, time.UTC),
+			expected: 16725225600,
+		},
+		{
+			name:     "before 1678",
+			time:     time.Date(1500, 1, 1, 0, 0, 0, 0, time.UTC),
+			expected: -14831769600,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if v := toDateGranularity(tc.time); v != tc.expected {
+				t.Errorf("incorrect value: %v != %v", v, tc.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package osmpbf
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"github.com/paulmach/osm"
+)
+
+var errInvalidBlock = errors.New("osmpbf: invalid primitive block")
+
+// filter limits the decoded elements by type and id.
+type filter struct {
+	types map[osm.Type]bool
+	minID int64
+	maxID int64
+}
+
+// newFilter returns nil if the types and range do not filter anything.
+func newFilter(types []osm.Type, minID, maxID int64) *filter {
+	if len(types) == 0 && minID == 0 && maxID == 0 {
+		return nil
+	}
+
+	f := &filter{minID: minID, maxID: maxID}
+	if len(types) > 0 {
+		f.types = make(map[osm.Type]bool, len(types))
+		for _, t := range types {
+			f.types[t] = true
+		}
+	}
+
+	return f
+}
+
+func (f *filter) wantType(t osm.Type) bool {
+	return f.types == nil || f.types[t]
+}
+
+func (f *filter) wantIDs(min, max int64) bool {
+	if max < f.minID {
+		return false
+	}
+
+	return f.maxID == 0 || min <= f.maxID
+}
+
+// wantBlock returns true if the indexed block has some of the elements.
+func (f *filter) wantBlock(bi *BlockInfo) bool {
+	for _, r := range bi.Ranges {
+		if f.wantType(r.Type) && f.wantIDs(r.Min, r.Max) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// wantObject returns true if the decoded element is in the id range.
+func (f *filter) wantObject(o osm.Object) bool {
+	var id int64
+	switch o := o.(type) {
+	case *osm.Node:
+		id = int64(o.ID)
+	case *osm.Way:
+		id = int64(o.ID)
+	case *osm.Relation:
+		id = int64(o.ID)
+	default:
+		return true
+	}
+
+	return f.wantIDs(id, id)
+}
+
+// groupTypes maps the first field of a primitive group,
+// all the elements are the same type, to the element type.
+var groupTypes = map[uint64]osm.Type{
+	1: osm.TypeNode,
+	2: osm.TypeNode,
+	3: osm.TypeWay,
+	4: osm.TypeRelation,
+	5: osm.TypeChangeset,
+}
+
+// primitiveBlock removes the primitive groups with unwanted element types
+// from the encoded primitive block so they are not decoded.
+func (f *filter) primitiveBlock(data []byte) ([]byte, error) {
+	if f.types == nil {
+		return data, nil
+	}
+
+	var result []byte
+	for i := 0; i < len(data); {
+		start := i
+		key, n := binary.Uvarint(data[i:])
+		if n <= 0 {
+			return nil, errInvalidBlock
+		}
+		i += n
+
+		var l uint64
+		switch key & 7 {
+		case 0: // varint
+			_, n = binary.Uvarint(data[i:])
+		case 1: // fixed64
+			n = 8
+		case 2: // length delimited
+			l, n = binary.Uvarint(data[i:])
+			if n > 0 {
+				n += int(l)
+			}
+		case 5: // fixed32
+			n = 4
+		default:
+			return nil, errInvalidBlock
+		}
+
+		if n <= 0 || i+n > len(data) || i+n < i {
+			return nil, errInvalidBlock
+		}
+		i += n
+
+		// primitive group field
+		if key == 2<<3|2 && !f.wantGroup(data[i-int(l):i]) {
+			if result == nil {
+				result = append(make([]byte, 0, len(data)), data[:start]...)
+			}
+			continue
+		}
+
+		if result != nil {
+			result = append(result, data[start:i]...)
+		}
+	}
+
+	if result == nil {
+		return data, nil
+	}
+
+	return result, nil
+}
+
+func (f *filter) wantGroup(group []byte) bool {
+	key, n := binary.Uvarint(group)
+	if n <= 0 {
+		// empty or invalid, let the decoder handle it
+		return true
+	}
+
+	t, ok := groupTypes[key>>3]
+	return !ok || f.wantType(t)
+}
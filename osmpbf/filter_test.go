@@ -0,0 +1,148 @@
+package osmpbf
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"testing"
+
+	"github.com/paulmach/osm"
+)
+
+func TestScanner_Types(t *testing.T) {
+	data, err := ioutil.ReadFile(Delaware)
+	if err != nil {
+		t.Fatalf("unable to read file: %v", err)
+	}
+
+	scanner := New(context.Background(), bytes.NewReader(data), 2)
+	nodes, ways, relations := countTypes(t, scanner)
+	scanner.Close()
+
+	cases := []struct {
+		name      string
+		types     []osm.Type
+		nodes     int
+		ways      int
+		relations int
+	}{
+		{
+			name:  "ways",
+			types: []osm.Type{osm.TypeWay},
+			ways:  ways,
+		},
+		{
+			name:      "nodes and relations",
+			types:     []osm.Type{osm.TypeNode, osm.TypeRelation},
+			nodes:     nodes,
+			relations: relations,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			scanner := New(context.Background(), bytes.NewReader(data), 2)
+			scanner.Types = tc.types
+			defer scanner.Close()
+
+			n, w, r := countTypes(t, scanner)
+			if n != tc.nodes || w != tc.ways || r != tc.relations {
+				t.Errorf("incorrect counts: %v %v %v", n, w, r)
+			}
+		})
+	}
+}
+
+func TestScanner_idRange(t *testing.T) {
+	buf := &bytes.Buffer{}
+	enc, _ := NewEncoder(buf, BlockSize(10))
+	for i := 1; i <= 30; i++ {
+		enc.Encode(&osm.Node{ID: osm.NodeID(i), Lat: 1, Lon: 2, Visible: true})
+	}
+
+	for i := 1; i <= 30; i++ {
+		enc.Encode(&osm.Way{ID: osm.WayID(i), Visible: true, Nodes: osm.WayNodes{{ID: 1}, {ID: 2}}})
+	}
+	enc.Close()
+
+	index := &Index{}
+	scanner := New(context.Background(), bytes.NewReader(buf.Bytes()), 1)
+	scanner.Index = index
+	countTypes(t, scanner)
+	scanner.Close()
+
+	scanners := map[string]*Scanner{
+		"scan":    New(context.Background(), bytes.NewReader(buf.Bytes()), 2),
+		"indexed": NewIndexed(context.Background(), bytes.NewReader(buf.Bytes()), index, 2),
+	}
+
+	for name, scanner := range scanners {
+		t.Run(name, func(t *testing.T) {
+			scanner.Types = []osm.Type{osm.TypeWay}
+			scanner.MinID = 5
+			scanner.MaxID = 12
+			defer scanner.Close()
+
+			var ids []osm.FeatureID
+			for scanner.Scan() {
+				ids = append(ids, scanner.Object().(osm.Element).FeatureID())
+			}
+
+			if err := scanner.Err(); err != nil {
+				t.Fatalf("scan error: %v", err)
+			}
+
+			expected := featureIDs(osm.TypeWay, 5, 12)
+			if len(ids) != len(expected) || ids[0] != expected[0] || ids[len(ids)-1] != expected[len(expected)-1] {
+				t.Errorf("incorrect ids: %v", ids)
+			}
+		})
+	}
+}
+
+func TestFilter_primitiveBlock(t *testing.T) {
+	f := newFilter([]osm.Type{osm.TypeWay}, 0, 0)
+
+	data := []byte{
+		0x0a, 0x01, 0x00, // stringtable
+		0x12, 0x02, 0x12, 0x00, // dense nodes group
+		0x12, 0x02, 0x1a, 0x00, // ways group
+		0x88, 0x01, 0x64, // granularity
+	}
+
+	result, err := f.primitiveBlock(data)
+	if err != nil {
+		t.Fatalf("filter error: %v", err)
+	}
+
+	expected := []byte{0x0a, 0x01, 0x00, 0x12, 0x02, 0x1a, 0x00, 0x88, 0x01, 0x64}
+	if !bytes.Equal(result, expected) {
+		t.Errorf("incorrect result: %x", result)
+	}
+
+	if _, err := f.primitiveBlock(data[:5]); err != errInvalidBlock {
+		t.Errorf("should error on truncated data: %v", err)
+	}
+}
+
+func countTypes(t testing.TB, scanner *Scanner) (int, int, int) {
+	t.Helper()
+
+	var nodes, ways, relations int
+	for scanner.Scan() {
+		switch scanner.Object().(type) {
+		case *osm.Node:
+			nodes++
+		case *osm.Way:
+			ways++
+		case *osm.Relation:
+			relations++
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		t.Fatalf("scan error: %v", err)
+	}
+
+	return nodes, ways, relations
+}
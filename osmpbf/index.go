@@ -0,0 +1,195 @@
+package osmpbf
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+
+	"github.com/paulmach/osm"
+)
+
+// An Index lists the data blocks of a pbf file with their position and the
+// elements they contain. It is built while scanning, see Scanner.Index,
+// and can be saved as a sidecar file so later bounding box or id range
+// queries only read the relevant blocks, see NewIndexed.
+type Index struct {
+	Blocks []*BlockInfo `json:"blocks"`
+}
+
+// BlockInfo describes a data block in a pbf file.
+type BlockInfo struct {
+	// Offset and Size are the position of the block, including
+	// the blob header, in the file in bytes.
+	Offset int64 `json:"offset"`
+	Size   int64 `json:"size"`
+
+	// Ranges are the ids of the elements in the block by type.
+	Ranges []IDRange `json:"ranges,omitempty"`
+
+	// Bounds contains the nodes in the block, nil if there are no nodes.
+	Bounds *osm.Bounds `json:"bounds,omitempty"`
+}
+
+// IDRange is the range of ids, inclusive, of a type of element in a block.
+type IDRange struct {
+	Type  osm.Type `json:"type"`
+	Min   int64    `json:"min"`
+	Max   int64    `json:"max"`
+	Count int      `json:"count"`
+}
+
+// ReadIndex reads an index written by WriteTo.
+func ReadIndex(r io.Reader) (*Index, error) {
+	idx := &Index{}
+	if err := json.NewDecoder(r).Decode(idx); err != nil {
+		return nil, err
+	}
+
+	return idx, nil
+}
+
+// WriteTo writes the index, as json, to the writer.
+func (idx *Index) WriteTo(w io.Writer) (int64, error) {
+	data, err := json.Marshal(idx)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := w.Write(data)
+	return int64(n), err
+}
+
+// Intersecting returns an index of the blocks with nodes within the bound.
+// Ways and relations do not have a location, so blocks with only
+// ways and relations are not included.
+func (idx *Index) Intersecting(b *osm.Bounds) *Index {
+	result := &Index{Blocks: []*BlockInfo{}}
+	for _, bi := range idx.Blocks {
+		if bi.Bounds == nil {
+			continue
+		}
+
+		if bi.Bounds.MaxLat < b.MinLat || bi.Bounds.MinLat > b.MaxLat ||
+			bi.Bounds.MaxLon < b.MinLon || bi.Bounds.MinLon > b.MaxLon {
+			continue
+		}
+
+		result.Blocks = append(result.Blocks, bi)
+	}
+
+	return result
+}
+
+// IDRange returns an index of the blocks with elements of the type
+// with ids between min and max, inclusive.
+func (idx *Index) IDRange(t osm.Type, min, max int64) *Index {
+	result := &Index{Blocks: []*BlockInfo{}}
+	for _, bi := range idx.Blocks {
+		if bi.contains(t, min, max) {
+			result.Blocks = append(result.Blocks, bi)
+		}
+	}
+
+	return result
+}
+
+func (bi *BlockInfo) contains(t osm.Type, min, max int64) bool {
+	for _, r := range bi.Ranges {
+		if r.Type == t && r.Max >= min && r.Min <= max {
+			return true
+		}
+	}
+
+	return false
+}
+
+// sorted returns the blocks ordered by offset so they are
+// read with increasing seeks.
+func (idx *Index) sorted() []*BlockInfo {
+	blocks := make([]*BlockInfo, len(idx.Blocks))
+	copy(blocks, idx.Blocks)
+
+	sort.Slice(blocks, func(i, j int) bool {
+		return blocks[i].Offset < blocks[j].Offset
+	})
+
+	return blocks
+}
+
+// newBlockInfo describes the decoded block.
+func newBlockInfo(offset, size int64, objects []osm.Object) *BlockInfo {
+	bi := &BlockInfo{Offset: offset, Size: size}
+
+	var r *IDRange
+	for _, o := range objects {
+		var (
+			t  osm.Type
+			id int64
+		)
+
+		switch o := o.(type) {
+		case *osm.Node:
+			t, id = osm.TypeNode, int64(o.ID)
+			bi.extend(o)
+		case *osm.Way:
+			t, id = osm.TypeWay, int64(o.ID)
+		case *osm.Relation:
+			t, id = osm.TypeRelation, int64(o.ID)
+		default:
+			continue
+		}
+
+		if r == nil || r.Type != t {
+			r = bi.rangeFor(t)
+		}
+
+		if r.Count == 0 || id < r.Min {
+			r.Min = id
+		}
+
+		if r.Count == 0 || id > r.Max {
+			r.Max = id
+		}
+
+		r.Count++
+	}
+
+	return bi
+}
+
+func (bi *BlockInfo) rangeFor(t osm.Type) *IDRange {
+	for i := range bi.Ranges {
+		if bi.Ranges[i].Type == t {
+			return &bi.Ranges[i]
+		}
+	}
+
+	bi.Ranges = append(bi.Ranges, IDRange{Type: t})
+	return &bi.Ranges[len(bi.Ranges)-1]
+}
+
+func (bi *BlockInfo) extend(n *osm.Node) {
+	if bi.Bounds == nil {
+		bi.Bounds = &osm.Bounds{
+			MinLat: n.Lat, MaxLat: n.Lat,
+			MinLon: n.Lon, MaxLon: n.Lon,
+		}
+		return
+	}
+
+	if n.Lat < bi.Bounds.MinLat {
+		bi.Bounds.MinLat = n.Lat
+	}
+
+	if n.Lat > bi.Bounds.MaxLat {
+		bi.Bounds.MaxLat = n.Lat
+	}
+
+	if n.Lon < bi.Bounds.MinLon {
+		bi.Bounds.MinLon = n.Lon
+	}
+
+	if n.Lon > bi.Bounds.MaxLon {
+		bi.Bounds.MaxLon = n.Lon
+	}
+}
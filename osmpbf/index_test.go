@@ -0,0 +1,131 @@
+package osmpbf
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/paulmach/osm"
+)
+
+func TestIndex(t *testing.T) {
+	buf := &bytes.Buffer{}
+	enc, _ := NewEncoder(buf, BlockSize(10))
+	for i := 1; i <= 30; i++ {
+		enc.Encode(&osm.Node{ID: osm.NodeID(i), Lat: float64(i), Lon: float64(-i), Visible: true})
+	}
+
+	for i := 1; i <= 15; i++ {
+		enc.Encode(&osm.Way{ID: osm.WayID(i), Visible: true, Nodes: osm.WayNodes{{ID: 1}, {ID: 2}}})
+	}
+	enc.Close()
+
+	index := &Index{}
+	scanner := New(context.Background(), bytes.NewReader(buf.Bytes()), 2)
+	scanner.Index = index
+
+	count := 0
+	for scanner.Scan() {
+		count++
+	}
+	scanner.Close()
+
+	if err := scanner.Err(); err != nil {
+		t.Fatalf("scan error: %v", err)
+	}
+
+	if count != 45 {
+		t.Errorf("incorrect count: %v", count)
+	}
+
+	if len(index.Blocks) != 5 {
+		t.Fatalf("incorrect number of blocks: %v", len(index.Blocks))
+	}
+
+	expected := []IDRange{{Type: osm.TypeNode, Min: 11, Max: 20, Count: 10}}
+	if r := index.Blocks[1].Ranges; !reflect.DeepEqual(r, expected) {
+		t.Errorf("incorrect ranges: %v", r)
+	}
+
+	expectedBounds := &osm.Bounds{MinLat: 11, MaxLat: 20, MinLon: -20, MaxLon: -11}
+	if b := index.Blocks[1].Bounds; !reflect.DeepEqual(b, expectedBounds) {
+		t.Errorf("incorrect bounds: %v", b)
+	}
+
+	if b := index.Blocks[3].Bounds; b != nil {
+		t.Errorf("way block should not have bounds: %v", b)
+	}
+
+	last := index.Blocks[len(index.Blocks)-1]
+	if v := last.Offset + last.Size; v != int64(buf.Len()) {
+		t.Errorf("incorrect end of last block: %v != %v", v, buf.Len())
+	}
+
+	// round trip the sidecar file
+	sidecar := &bytes.Buffer{}
+	if _, err := index.WriteTo(sidecar); err != nil {
+		t.Fatalf("write error: %v", err)
+	}
+
+	index, err := ReadIndex(sidecar)
+	if err != nil {
+		t.Fatalf("read error: %v", err)
+	}
+
+	cases := []struct {
+		name  string
+		index *Index
+		ids   []osm.FeatureID
+	}{
+		{
+			name:  "node range",
+			index: index.IDRange(osm.TypeNode, 15, 25),
+			ids:   featureIDs(osm.TypeNode, 11, 30),
+		},
+		{
+			name:  "way range",
+			index: index.IDRange(osm.TypeWay, 12, 12),
+			ids:   featureIDs(osm.TypeWay, 11, 15),
+		},
+		{
+			name:  "bound",
+			index: index.Intersecting(&osm.Bounds{MinLat: 2, MaxLat: 3, MinLon: -3, MaxLon: -2}),
+			ids:   featureIDs(osm.TypeNode, 1, 10),
+		},
+		{
+			name:  "none",
+			index: index.IDRange(osm.TypeRelation, 1, 100),
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			scanner := NewIndexed(context.Background(), bytes.NewReader(buf.Bytes()), tc.index, 2)
+			defer scanner.Close()
+
+			var ids []osm.FeatureID
+			for scanner.Scan() {
+				ids = append(ids, scanner.Object().(osm.Element).FeatureID())
+			}
+
+			if err := scanner.Err(); err != nil {
+				t.Fatalf("scan error: %v", err)
+			}
+
+			if !reflect.DeepEqual(ids, tc.ids) {
+				t.Errorf("incorrect ids: %v", ids)
+			}
+		})
+	}
+}
+
+func featureIDs(t osm.Type, min, max int64) []osm.FeatureID {
+	var result []osm.FeatureID
+	for i := min; i <= max; i++ {
+		fid, _ := t.FeatureID(i)
+		result = append(result, fid)
+	}
+
+	return result
+}
@@ -2,10 +2,12 @@ package osmpbf
 
 import (
 	"context"
+	"errors"
 	"io"
 	"sync/atomic"
 
 	"github.com/paulmach/osm"
+	"github.com/paulmach/osm/metrics"
 )
 
 var _ osm.Scanner = &Scanner{}
@@ -20,6 +22,37 @@ var _ osm.Scanner = &Scanner{}
 // The Scanner API is based on bufio.Scanner
 // https://golang.org/pkg/bufio/#Scanner
 type Scanner struct {
+	// Metrics, if set before scanning, records the blocks and elements
+	// decoded and the block decode times, see the metrics package.
+	Metrics metrics.Recorder
+
+	// Logger, if set before scanning, receives warnings, e.g. for a missing
+	// header block, ignored optional features or a truncated block.
+	Logger osm.Logger
+
+	// SkipCorrupt, if set before scanning, skips blocks that can not be
+	// read or decoded, e.g. a bad zlib stream, instead of stopping the scan.
+	// The skipped blocks are logged and reading continues at the next block,
+	// so one bad block does not stop a long running job.
+	SkipCorrupt bool
+
+	// Index, if set before scanning, has the data blocks appended as
+	// they are scanned. After scanning the whole file it can be saved as
+	// a sidecar file and used by NewIndexed to read only relevant blocks.
+	// The Types and id range should not be set when building an index.
+	Index *Index
+
+	// Types, if set before scanning, limits the elements to these types.
+	// Primitive groups with other types are skipped without being decoded,
+	// making way or relation only processing much faster.
+	Types []osm.Type
+
+	// MinID and MaxID, if set before scanning, limit the elements to those
+	// with ids in the range, inclusive. A zero MaxID has no upper limit.
+	// When reading with NewIndexed, blocks outside the range, or without
+	// the Types, are not read at all.
+	MinID, MaxID int64
+
 	ctx    context.Context
 	closed bool
 
@@ -46,6 +79,18 @@ func New(ctx context.Context, r io.Reader, procs int) *Scanner {
 	return s
 }
 
+// NewIndexed returns a new Scanner that reads only the blocks in the index,
+// for example the result of an Intersecting or IDRange query, seeking
+// directly to each one. The index must have been built from the same file.
+func NewIndexed(ctx context.Context, r io.ReadSeeker, index *Index, procs int) *Scanner {
+	s := New(ctx, r, procs)
+	s.decoder.rs = r
+	s.decoder.indexed = true
+	s.decoder.blocks = index.sorted()
+
+	return s
+}
+
 // FullyScannedBytes returns the number of bytes that have been read
 // and fully scanned. OSM protobuf files contain data blocks with
 // 8000 nodes each. The returned value contains the bytes for the blocks
@@ -68,6 +113,57 @@ func (s *Scanner) PreviousFullyScannedBytes() int64 {
 	return atomic.LoadInt64(&s.decoder.pOffset)
 }
 
+// A Position is the location of the scanner in a pbf file. It can be saved
+// as a checkpoint and passed to Seek to resume scanning after a crash.
+type Position struct {
+	// Offset is the position, in bytes, of the current data block.
+	Offset int64 `json:"offset"`
+
+	// Index is the number of elements already scanned in the block.
+	Index int `json:"index"`
+}
+
+// Position returns the location of the scanner, after the most recent
+// element returned by Scan.
+func (s *Scanner) Position() Position {
+	return Position{
+		Offset: atomic.LoadInt64(&s.decoder.cOffset),
+		Index:  s.decoder.cIndex,
+	}
+}
+
+// Seek sets the scanner to resume from the position, so the next call to
+// Scan returns the element after the one scanned when the position was saved.
+// It must be called before scanning and requires the reader to be an
+// io.ReadSeeker. The header is still read from the start of the file.
+// The position is only valid for the same file with the same Types,
+// id range and index, since they change the elements in each block.
+func (s *Scanner) Seek(p Position) error {
+	if s.started {
+		return errors.New("osmpbf: seek after scanning started")
+	}
+
+	if p.Offset < 0 || p.Index < 0 {
+		return errors.New("osmpbf: invalid position")
+	}
+
+	rs, ok := s.decoder.r.(io.ReadSeeker)
+	if s.decoder.rs != nil {
+		rs, ok = s.decoder.rs, true
+	}
+
+	if !ok {
+		return errors.New("osmpbf: seek requires an io.ReadSeeker")
+	}
+
+	s.decoder.rs = rs
+	s.decoder.resume = p
+	s.decoder.cOffset = p.Offset
+	s.decoder.cIndex = p.Index
+
+	return nil
+}
+
 // Close cleans up all the reading goroutines, it does not
 // close the underlying reader.
 func (s *Scanner) Close() error {
@@ -81,6 +177,11 @@ func (s *Scanner) Header() (*Header, error) {
 	if !s.started {
 		s.started = true
 		// the header gets read before Start returns
+		s.decoder.metrics = s.Metrics
+		s.decoder.logger = s.Logger
+		s.decoder.skipCorrupt = s.SkipCorrupt
+		s.decoder.index = s.Index
+		s.decoder.filter = newFilter(s.Types, s.MinID, s.MaxID)
 		s.err = s.decoder.Start(s.procs)
 	}
 
@@ -96,6 +197,11 @@ func (s *Scanner) Header() (*Header, error) {
 func (s *Scanner) Scan() bool {
 	if !s.started {
 		s.started = true
+		s.decoder.metrics = s.Metrics
+		s.decoder.logger = s.Logger
+		s.decoder.skipCorrupt = s.SkipCorrupt
+		s.decoder.index = s.Index
+		s.decoder.filter = newFilter(s.Types, s.MinID, s.MaxID)
 		s.err = s.decoder.Start(s.procs)
 	}
 
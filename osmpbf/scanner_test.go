@@ -1,13 +1,21 @@
 package osmpbf
 
 import (
+	"bytes"
 	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
 	"os"
 	"reflect"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/paulmach/osm"
+	"github.com/paulmach/osm/metrics"
 )
 
 var (
@@ -41,6 +49,179 @@ func TestScanner(t *testing.T) {
 	}
 }
 
+func TestScanner_Metrics(t *testing.T) {
+	f, err := os.Open(Delaware)
+	if err != nil {
+		t.Fatalf("unable to open file: %v", err)
+	}
+	defer f.Close()
+
+	m := metrics.NewPrometheus()
+	scanner := New(context.Background(), f, 2)
+	scanner.Metrics = m
+	defer scanner.Close()
+
+	count := 0
+	for scanner.Scan() {
+		count++
+	}
+
+	if err := scanner.Err(); err != nil {
+		t.Fatalf("scan error: %v", err)
+	}
+
+	buf := &bytes.Buffer{}
+	m.Write(buf)
+
+	var decoded int
+	for _, typ := range []string{"node", "way", "relation"} {
+		var v int
+		prefix := `osm_pbf_elements_decoded_total{type="` + typ + `"} `
+		for _, line := range strings.Split(buf.String(), "\n") {
+			if strings.HasPrefix(line, prefix) {
+				v, _ = strconv.Atoi(line[len(prefix):])
+			}
+		}
+		decoded += v
+	}
+
+	if decoded != count {
+		t.Errorf("incorrect decoded count: %v != %v", decoded, count)
+	}
+
+	if !strings.Contains(buf.String(), "osm_pbf_blocks_decoded_total ") {
+		t.Errorf("missing blocks metric")
+	}
+}
+
+func TestScanner_Logger(t *testing.T) {
+	buf := &bytes.Buffer{}
+	enc, _ := NewEncoder(buf)
+	for i := 1; i <= 100; i++ {
+		enc.Encode(&osm.Node{ID: osm.NodeID(i), Lat: 1, Lon: 2, Visible: true})
+	}
+	enc.Close()
+
+	logger := &testLogger{}
+	data := buf.Bytes()[:buf.Len()-10]
+
+	scanner := New(context.Background(), bytes.NewReader(data), 1)
+	scanner.Logger = logger
+	defer scanner.Close()
+
+	for scanner.Scan() {
+	}
+
+	if scanner.Err() == nil {
+		t.Errorf("should error on truncated data")
+	}
+
+	if w := logger.Warnings(); len(w) != 1 || !strings.HasPrefix(w[0], "osmpbf: truncated block [offset ") {
+		t.Errorf("incorrect warnings: %v", w)
+	}
+}
+
+func TestScanner_SkipCorrupt(t *testing.T) {
+	buf := &bytes.Buffer{}
+	enc, _ := NewEncoder(buf, BlockSize(10))
+	for i := 1; i <= 30; i++ {
+		enc.Encode(&osm.Node{ID: osm.NodeID(i), Lat: 1, Lon: 2, Visible: true})
+	}
+	enc.Close()
+
+	// the offsets of the blob header sizes of the 3 data blocks
+	var blocks []int
+	for i := 4; i < buf.Len(); i++ {
+		if bytes.HasPrefix(buf.Bytes()[i:], osmDataHeader) {
+			blocks = append(blocks, i-4)
+		}
+	}
+
+	if len(blocks) != 3 {
+		t.Fatalf("incorrect number of blocks: %v", blocks)
+	}
+
+	cases := []struct {
+		name    string
+		corrupt func([]byte)
+	}{
+		{
+			name: "bad block data",
+			corrupt: func(data []byte) {
+				for i := blocks[1] + 30; i < blocks[2]-10; i++ {
+					data[i] = 0xff
+				}
+			},
+		},
+		{
+			name: "bad blob header size",
+			corrupt: func(data []byte) {
+				copy(data[blocks[1]:], []byte{0xff, 0xff, 0xff, 0xff})
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			data := append([]byte(nil), buf.Bytes()...)
+			tc.corrupt(data)
+
+			// fails without the option
+			scanner := New(context.Background(), bytes.NewReader(data), 1)
+			for scanner.Scan() {
+			}
+			scanner.Close()
+
+			if scanner.Err() == nil {
+				t.Errorf("should error on corrupt data")
+			}
+
+			logger := &testLogger{}
+			scanner = New(context.Background(), bytes.NewReader(data), 2)
+			scanner.Logger = logger
+			scanner.SkipCorrupt = true
+			defer scanner.Close()
+
+			var ids []osm.NodeID
+			for scanner.Scan() {
+				ids = append(ids, scanner.Object().(*osm.Node).ID)
+			}
+
+			if err := scanner.Err(); err != nil {
+				t.Fatalf("scan error: %v", err)
+			}
+
+			if len(ids) != 20 || ids[9] != 10 || ids[10] != 21 {
+				t.Errorf("incorrect nodes: %v", ids)
+			}
+
+			w := logger.Warnings()
+			if len(w) != 1 || !strings.HasPrefix(w[0], fmt.Sprintf("osmpbf: skipping corrupt block [offset %d ", blocks[1])) {
+				t.Errorf("incorrect warnings: %v", w)
+			}
+		})
+	}
+}
+
+type testLogger struct {
+	mu       sync.Mutex
+	warnings []string
+}
+
+func (l *testLogger) Warn(msg string, keyvals ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.warnings = append(l.warnings, fmt.Sprintf("%s %v", msg, keyvals))
+}
+
+func (l *testLogger) Warnings() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.warnings
+}
+
 func TestScanner_intermediateStart(t *testing.T) {
 	f, err := os.Open(Delaware)
 	if err != nil {
@@ -77,6 +258,103 @@ func TestScanner_intermediateStart(t *testing.T) {
 	scanner.Close()
 }
 
+func TestScanner_Seek(t *testing.T) {
+	data, err := ioutil.ReadFile(Delaware)
+	if err != nil {
+		t.Fatalf("unable to read file: %v", err)
+	}
+
+	scanner := New(context.Background(), bytes.NewReader(data), 1)
+	for i := 0; i < 30000; i++ {
+		scanner.Scan()
+	}
+	position := scanner.Position()
+
+	var expected []osm.ObjectID
+	for scanner.Scan() {
+		expected = append(expected, scanner.Object().ObjectID())
+	}
+	scanner.Close()
+
+	if position.Offset != 214162 || position.Index == 0 {
+		t.Errorf("incorrect position: %+v", position)
+	}
+
+	for _, skip := range []bool{false, true} {
+		t.Run(fmt.Sprintf("skip corrupt %v", skip), func(t *testing.T) {
+			scanner := New(context.Background(), bytes.NewReader(data), 2)
+			scanner.SkipCorrupt = skip
+			defer scanner.Close()
+
+			err := scanner.Seek(position)
+			if err != nil {
+				t.Fatalf("seek error: %v", err)
+			}
+
+			if p := scanner.Position(); p != position {
+				t.Errorf("incorrect position before scanning: %+v", p)
+			}
+
+			var ids []osm.ObjectID
+			for scanner.Scan() {
+				ids = append(ids, scanner.Object().ObjectID())
+			}
+
+			if err := scanner.Err(); err != nil {
+				t.Fatalf("scan error: %v", err)
+			}
+
+			if !reflect.DeepEqual(ids, expected) {
+				t.Errorf("incorrect elements, got %d, expected %d", len(ids), len(expected))
+			}
+		})
+	}
+
+	t.Run("indexed", func(t *testing.T) {
+		index := &Index{}
+		scanner := New(context.Background(), bytes.NewReader(data), 1)
+		scanner.Index = index
+		for scanner.Scan() {
+		}
+		scanner.Close()
+
+		scanner = NewIndexed(context.Background(), bytes.NewReader(data), index, 1)
+		defer scanner.Close()
+
+		err := scanner.Seek(position)
+		if err != nil {
+			t.Fatalf("seek error: %v", err)
+		}
+
+		var ids []osm.ObjectID
+		for scanner.Scan() {
+			ids = append(ids, scanner.Object().ObjectID())
+		}
+
+		if !reflect.DeepEqual(ids, expected) {
+			t.Errorf("incorrect elements, got %d, expected %d", len(ids), len(expected))
+		}
+	})
+
+	t.Run("requires a seeker", func(t *testing.T) {
+		r := struct{ io.Reader }{bytes.NewReader(data)}
+		scanner := New(context.Background(), r, 1)
+		if err := scanner.Seek(position); err == nil {
+			t.Errorf("expected error for non seeker")
+		}
+	})
+
+	t.Run("after scanning started", func(t *testing.T) {
+		scanner := New(context.Background(), bytes.NewReader(data), 1)
+		defer scanner.Close()
+
+		scanner.Scan()
+		if err := scanner.Seek(position); err == nil {
+			t.Errorf("expected error after scanning")
+		}
+	})
+}
+
 func TestScanner_context(t *testing.T) {
 	ctx, cancel := context.WithCancel(context.Background())
 	f, err := os.Open(Delaware)
@@ -0,0 +1,258 @@
+// Package osmredis provides an osm datasource backed by Redis so multiple
+// worker processes can share a hot element cache, eg. when annotating
+// replication diffs.
+package osmredis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/paulmach/osm"
+)
+
+var errNotFound = errors.New("osmredis: element not found")
+
+// Datasource stores the versions of every element in a redis hash,
+// keyed by version, with the osmpb encoded element as the value.
+// It implements the osm.HistoryDatasourcer interface.
+type Datasource struct {
+	prefix   string
+	ttl      time.Duration
+	maxIdle  int
+	password string
+
+	pool *pool
+}
+
+var _ osm.HistoryDatasourcer = &Datasource{}
+var _ osm.HistoryStore = &Datasource{}
+
+// New creates a datasource using the redis server at the address.
+// Connections are created as needed.
+func New(addr string, opts ...Option) (*Datasource, error) {
+	ds := &Datasource{
+		prefix:  "osm:",
+		maxIdle: 4,
+	}
+
+	for _, opt := range opts {
+		if err := opt(ds); err != nil {
+			return nil, err
+		}
+	}
+
+	dialer := &net.Dialer{}
+	ds.pool = &pool{
+		idle: make(chan *conn, ds.maxIdle),
+		dial: func(ctx context.Context) (net.Conn, error) {
+			c, err := dialer.DialContext(ctx, "tcp", addr)
+			if err != nil || ds.password == "" {
+				return c, err
+			}
+
+			if _, err := newConn(c).do(ctx, []byte("AUTH"), []byte(ds.password)); err != nil {
+				c.Close()
+				return nil, err
+			}
+
+			return c, nil
+		},
+	}
+
+	return ds, nil
+}
+
+// Close closes the idle connections.
+func (ds *Datasource) Close() error {
+	return ds.pool.close()
+}
+
+func (ds *Datasource) key(id osm.FeatureID) []byte {
+	return []byte(ds.prefix + id.String())
+}
+
+// Add stores the nodes, ways and relations. Other types are ignored.
+// The elements are written using a single pipelined request.
+func (ds *Datasource) Add(ctx context.Context, objects ...osm.Object) error {
+	var cmds [][][]byte
+	for _, o := range objects {
+		var (
+			id      osm.FeatureID
+			version int
+			data    []byte
+			err     error
+		)
+
+		switch o := o.(type) {
+		case *osm.Node:
+			id, version = o.FeatureID(), o.Version
+			data, err = osm.Nodes{o}.Marshal()
+		case *osm.Way:
+			id, version = o.FeatureID(), o.Version
+			data, err = osm.Ways{o}.Marshal()
+		case *osm.Relation:
+			id, version = o.FeatureID(), o.Version
+			data, err = osm.Relations{o}.Marshal()
+		default:
+			continue
+		}
+
+		if err != nil {
+			return err
+		}
+
+		key := ds.key(id)
+		cmds = append(cmds, [][]byte{[]byte("HSET"), key, []byte(strconv.Itoa(version)), data})
+		if ds.ttl != 0 {
+			ms := strconv.FormatInt(int64(ds.ttl/time.Millisecond), 10)
+			cmds = append(cmds, [][]byte{[]byte("PEXPIRE"), key, []byte(ms)})
+		}
+	}
+
+	if len(cmds) == 0 {
+		return nil
+	}
+
+	c, err := ds.pool.get(ctx)
+	if err != nil {
+		return err
+	}
+	defer ds.pool.put(c)
+
+	_, err = c.pipeline(ctx, cmds)
+	return err
+}
+
+// versions returns the encoded versions of the element.
+func (ds *Datasource) versions(ctx context.Context, id osm.FeatureID) ([][]byte, error) {
+	c, err := ds.pool.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer ds.pool.put(c)
+
+	reply, err := c.do(ctx, []byte("HGETALL"), ds.key(id))
+	if err != nil {
+		return nil, err
+	}
+
+	values, ok := reply.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("osmredis: unexpected reply %T", reply)
+	}
+
+	if len(values) == 0 {
+		return nil, errNotFound
+	}
+
+	// field, value pairs
+	result := make([][]byte, 0, len(values)/2)
+	for i := 1; i < len(values); i += 2 {
+		data, ok := values[i].([]byte)
+		if !ok {
+			return nil, fmt.Errorf("osmredis: unexpected reply %T", values[i])
+		}
+		result = append(result, data)
+	}
+
+	return result, nil
+}
+
+// NodeHistory returns all the stored versions of the node sorted by version.
+func (ds *Datasource) NodeHistory(ctx context.Context, id osm.NodeID) (osm.Nodes, error) {
+	versions, err := ds.versions(ctx, id.FeatureID())
+	if err != nil {
+		return nil, err
+	}
+
+	var result osm.Nodes
+	for _, data := range versions {
+		nodes, err := osm.UnmarshalNodes(data)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, nodes...)
+	}
+
+	result.SortByIDVersion()
+	return result, nil
+}
+
+// WayHistory returns all the stored versions of the way sorted by version.
+func (ds *Datasource) WayHistory(ctx context.Context, id osm.WayID) (osm.Ways, error) {
+	versions, err := ds.versions(ctx, id.FeatureID())
+	if err != nil {
+		return nil, err
+	}
+
+	var result osm.Ways
+	for _, data := range versions {
+		ways, err := osm.UnmarshalWays(data)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, ways...)
+	}
+
+	result.SortByIDVersion()
+	return result, nil
+}
+
+// RelationHistory returns all the stored versions of the relation sorted by version.
+func (ds *Datasource) RelationHistory(ctx context.Context, id osm.RelationID) (osm.Relations, error) {
+	versions, err := ds.versions(ctx, id.FeatureID())
+	if err != nil {
+		return nil, err
+	}
+
+	var result osm.Relations
+	for _, data := range versions {
+		relations, err := osm.UnmarshalRelations(data)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, relations...)
+	}
+
+	result.SortByIDVersion()
+	return result, nil
+}
+
+// Node returns the latest stored version of the node.
+func (ds *Datasource) Node(ctx context.Context, id osm.NodeID) (*osm.Node, error) {
+	nodes, err := ds.NodeHistory(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return nodes[len(nodes)-1], nil
+}
+
+// Way returns the latest stored version of the way.
+func (ds *Datasource) Way(ctx context.Context, id osm.WayID) (*osm.Way, error) {
+	ways, err := ds.WayHistory(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return ways[len(ways)-1], nil
+}
+
+// Relation returns the latest stored version of the relation.
+func (ds *Datasource) Relation(ctx context.Context, id osm.RelationID) (*osm.Relation, error) {
+	relations, err := ds.RelationHistory(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return relations[len(relations)-1], nil
+}
+
+// NotFound returns true if the error returned is a not found error.
+func (ds *Datasource) NotFound(err error) bool {
+	return err == errNotFound
+}
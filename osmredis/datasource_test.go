@@ -0,0 +1,264 @@
+package osmredis
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/paulmach/osm"
+)
+
+func TestDatasource(t *testing.T) {
+	ctx := context.Background()
+	s := newFakeServer(t)
+	defer s.Close()
+
+	ds, err := New(s.Addr(), Prefix("test:"), TTL(time.Hour), Password("secret"))
+	if err != nil {
+		t.Fatalf("new error: %v", err)
+	}
+	defer ds.Close()
+
+	err = ds.Add(ctx,
+		&osm.Node{ID: 1, Version: 2, Lat: 1, Lon: 2, Visible: true, Tags: osm.Tags{{Key: "a", Value: "b"}}},
+		&osm.Node{ID: 1, Version: 1, Lat: 3, Lon: 4, Visible: true},
+		&osm.Way{ID: 2, Version: 1, Visible: true, Nodes: osm.WayNodes{{ID: 1}, {ID: 3}}},
+		&osm.Relation{ID: 3, Version: 5, Visible: true, Members: osm.Members{{Type: osm.TypeWay, Ref: 2, Role: "outer"}}},
+		&osm.Changeset{ID: 4},
+	)
+	if err != nil {
+		t.Fatalf("add error: %v", err)
+	}
+
+	s.lock.Lock()
+	ttl := s.ttl["test:node/1"]
+	s.lock.Unlock()
+
+	if ttl != "3600000" {
+		t.Errorf("should set ttl: %v", ttl)
+	}
+
+	nodes, err := ds.NodeHistory(ctx, 1)
+	if err != nil {
+		t.Fatalf("history error: %v", err)
+	}
+
+	if len(nodes) != 2 || nodes[0].Version != 1 || nodes[1].Version != 2 {
+		t.Errorf("incorrect history: %v", nodes)
+	}
+
+	n, err := ds.Node(ctx, 1)
+	if err != nil {
+		t.Fatalf("node error: %v", err)
+	}
+
+	if n.Version != 2 || n.Lat != 1 || n.Tags.Find("a") != "b" {
+		t.Errorf("incorrect node: %+v", n)
+	}
+
+	w, err := ds.Way(ctx, 2)
+	if err != nil {
+		t.Fatalf("way error: %v", err)
+	}
+
+	if !reflect.DeepEqual(w.Nodes.NodeIDs(), []osm.NodeID{1, 3}) {
+		t.Errorf("incorrect way: %+v", w)
+	}
+
+	r, err := ds.Relation(ctx, 3)
+	if err != nil {
+		t.Fatalf("relation error: %v", err)
+	}
+
+	if r.Version != 5 || r.Members[0].Role != "outer" {
+		t.Errorf("incorrect relation: %+v", r)
+	}
+
+	if _, err := ds.Node(ctx, 5); !ds.NotFound(err) {
+		t.Errorf("should be not found error: %v", err)
+	}
+
+	if _, err := ds.Way(ctx, 5); !ds.NotFound(err) {
+		t.Errorf("should be not found error: %v", err)
+	}
+
+	if _, err := ds.Relation(ctx, 5); !ds.NotFound(err) {
+		t.Errorf("should be not found error: %v", err)
+	}
+
+	// connections are reused
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.conns != 1 {
+		t.Errorf("should reuse connection: %v", s.conns)
+	}
+}
+
+func TestDatasource_errors(t *testing.T) {
+	ctx := context.Background()
+	s := newFakeServer(t)
+	defer s.Close()
+
+	ds, _ := New(s.Addr(), Password("wrong"))
+	if _, err := ds.Node(ctx, 1); err == nil || ds.NotFound(err) {
+		t.Errorf("should return auth error: %v", err)
+	}
+
+	if _, err := New(s.Addr(), TTL(0)); err == nil {
+		t.Errorf("should error on invalid ttl")
+	}
+
+	if _, err := New(s.Addr(), MaxIdle(-1)); err == nil {
+		t.Errorf("should error on invalid max idle")
+	}
+}
+
+// fakeServer implements the few redis commands used by the datasource.
+type fakeServer struct {
+	t *testing.T
+	l net.Listener
+
+	lock   sync.Mutex
+	hashes map[string]map[string]string
+	ttl    map[string]string
+	conns  int
+}
+
+func newFakeServer(t *testing.T) *fakeServer {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen error: %v", err)
+	}
+
+	s := &fakeServer{
+		t:      t,
+		l:      l,
+		hashes: make(map[string]map[string]string),
+		ttl:    make(map[string]string),
+	}
+
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+
+			s.lock.Lock()
+			s.conns++
+			s.lock.Unlock()
+
+			go s.serve(c)
+		}
+	}()
+
+	return s
+}
+
+func (s *fakeServer) Addr() string {
+	return s.l.Addr().String()
+}
+
+func (s *fakeServer) Close() error {
+	return s.l.Close()
+}
+
+func (s *fakeServer) serve(c net.Conn) {
+	defer c.Close()
+	r := bufio.NewReader(c)
+
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			return
+		}
+
+		s.lock.Lock()
+		reply := s.handle(args)
+		s.lock.Unlock()
+
+		if _, err := io.WriteString(c, reply); err != nil {
+			return
+		}
+	}
+}
+
+func (s *fakeServer) handle(args []string) string {
+	switch strings.ToUpper(args[0]) {
+	case "AUTH":
+		if args[1] != "secret" {
+			return "-WRONGPASS invalid password\r\n"
+		}
+		return "+OK\r\n"
+	case "HSET":
+		h := s.hashes[args[1]]
+		if h == nil {
+			h = make(map[string]string)
+			s.hashes[args[1]] = h
+		}
+		h[args[2]] = args[3]
+		return ":1\r\n"
+	case "PEXPIRE":
+		s.ttl[args[1]] = args[2]
+		return ":1\r\n"
+	case "HGETALL":
+		h := s.hashes[args[1]]
+
+		var fields []string
+		for f := range h {
+			fields = append(fields, f)
+		}
+		sort.Strings(fields)
+
+		reply := fmt.Sprintf("*%d\r\n", 2*len(fields))
+		for _, f := range fields {
+			reply += fmt.Sprintf("$%d\r\n%s\r\n$%d\r\n%s\r\n", len(f), f, len(h[f]), h[f])
+		}
+		return reply
+	}
+
+	return "-ERR unknown command\r\n"
+}
+
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+
+	n, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+	if err != nil {
+		return nil, err
+	}
+
+	args := make([]string, n)
+	for i := range args {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+
+		l, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+		if err != nil {
+			return nil, err
+		}
+
+		data := make([]byte, l+2)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, err
+		}
+		args[i] = string(data[:l])
+	}
+
+	return args, nil
+}
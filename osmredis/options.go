@@ -0,0 +1,50 @@
+package osmredis
+
+import (
+	"errors"
+	"time"
+)
+
+// An Option is a setting for the datasource.
+type Option func(*Datasource) error
+
+// Prefix sets the prefix for all the keys. The default is "osm:".
+func Prefix(p string) Option {
+	return func(ds *Datasource) error {
+		ds.prefix = p
+		return nil
+	}
+}
+
+// TTL sets the expiration of the element keys, it is reset every time
+// a version of the element is added. By default the keys do not expire.
+func TTL(d time.Duration) Option {
+	return func(ds *Datasource) error {
+		if d < time.Millisecond {
+			return errors.New("osmredis: ttl must be at least a millisecond")
+		}
+
+		ds.ttl = d
+		return nil
+	}
+}
+
+// MaxIdle sets the number of idle connections kept for reuse. The default is 4.
+func MaxIdle(n int) Option {
+	return func(ds *Datasource) error {
+		if n < 0 {
+			return errors.New("osmredis: max idle must not be negative")
+		}
+
+		ds.maxIdle = n
+		return nil
+	}
+}
+
+// Password will authenticate new connections using the AUTH command.
+func Password(p string) Option {
+	return func(ds *Datasource) error {
+		ds.password = p
+		return nil
+	}
+}
@@ -0,0 +1,203 @@
+package osmredis
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+)
+
+// redisError is an error reply from the server.
+type redisError string
+
+func (e redisError) Error() string {
+	return "osmredis: " + string(e)
+}
+
+// conn is a minimal client connection speaking the redis protocol, RESP.
+type conn struct {
+	c net.Conn
+	r *bufio.Reader
+	w *bufio.Writer
+
+	// broken is set if the connection is in an unknown state
+	// after an io error and should not be reused.
+	broken bool
+}
+
+func newConn(c net.Conn) *conn {
+	return &conn{
+		c: c,
+		r: bufio.NewReader(c),
+		w: bufio.NewWriter(c),
+	}
+}
+
+func (c *conn) setDeadline(ctx context.Context) error {
+	deadline, _ := ctx.Deadline()
+	return c.c.SetDeadline(deadline)
+}
+
+// send buffers a command as an array of bulk strings.
+func (c *conn) send(args ...[]byte) {
+	fmt.Fprintf(c.w, "*%d\r\n", len(args))
+	for _, a := range args {
+		fmt.Fprintf(c.w, "$%d\r\n", len(a))
+		c.w.Write(a)
+		c.w.WriteString("\r\n")
+	}
+}
+
+// pipeline writes the commands and reads all the replies. An error reply
+// to any of the commands is returned after all replies have been read.
+func (c *conn) pipeline(ctx context.Context, cmds [][][]byte) ([]interface{}, error) {
+	if err := c.setDeadline(ctx); err != nil {
+		c.broken = true
+		return nil, err
+	}
+
+	for _, cmd := range cmds {
+		c.send(cmd...)
+	}
+
+	if err := c.w.Flush(); err != nil {
+		c.broken = true
+		return nil, err
+	}
+
+	var replyErr error
+	replies := make([]interface{}, len(cmds))
+	for i := range cmds {
+		reply, err := c.readReply()
+		if e, ok := err.(redisError); ok {
+			if replyErr == nil {
+				replyErr = e
+			}
+			continue
+		}
+
+		if err != nil {
+			c.broken = true
+			return nil, err
+		}
+
+		replies[i] = reply
+	}
+
+	return replies, replyErr
+}
+
+func (c *conn) do(ctx context.Context, args ...[]byte) (interface{}, error) {
+	replies, err := c.pipeline(ctx, [][][]byte{args})
+	if err != nil {
+		return nil, err
+	}
+
+	return replies[0], nil
+}
+
+// readReply reads a reply returning a string, int64, []byte,
+// []interface{} or nil for null replies.
+func (c *conn) readReply() (interface{}, error) {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, errors.New("osmredis: invalid reply")
+	}
+	line = line[:len(line)-2]
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, redisError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return nil, err
+		}
+
+		data := make([]byte, n+2)
+		if _, err := io.ReadFull(c.r, data); err != nil {
+			return nil, err
+		}
+
+		return data[:n], nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return nil, err
+		}
+
+		result := make([]interface{}, n)
+		for i := range result {
+			result[i], err = c.readReply()
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		return result, nil
+	}
+
+	return nil, fmt.Errorf("osmredis: invalid reply type %q", line[0])
+}
+
+func (c *conn) Close() error {
+	return c.c.Close()
+}
+
+// pool keeps idle connections for reuse.
+type pool struct {
+	dial func(ctx context.Context) (net.Conn, error)
+	idle chan *conn
+}
+
+func (p *pool) get(ctx context.Context) (*conn, error) {
+	select {
+	case c := <-p.idle:
+		return c, nil
+	default:
+	}
+
+	c, err := p.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return newConn(c), nil
+}
+
+func (p *pool) put(c *conn) {
+	if c.broken {
+		c.Close()
+		return
+	}
+
+	c.c.SetDeadline(time.Time{})
+	select {
+	case p.idle <- c:
+	default:
+		c.Close()
+	}
+}
+
+func (p *pool) close() error {
+	for {
+		select {
+		case c := <-p.idle:
+			c.Close()
+		default:
+			return nil
+		}
+	}
+}
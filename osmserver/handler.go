@@ -0,0 +1,500 @@
+// Package osmserver provides an http handler serving the read calls of the
+// OSM v0.6 API from a local datasource. It can be used for offline testing
+// of editors and tools, like the osmapi package, and to serve private data.
+package osmserver
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/paulmach/osm"
+)
+
+// A Datasource is the element store served by the handler.
+type Datasource interface {
+	osm.HistoryDatasourcer
+}
+
+// A Mapper is a datasource that supports the map call, the elements within
+// a bounding box, like the Store. The map call responds with 501 Not
+// Implemented otherwise.
+type Mapper interface {
+	Map(context.Context, *osm.Bounds) (*osm.OSM, error)
+}
+
+// A Handler serves the osm api read calls:
+//
+//	/api/versions
+//	/api/0.6/map?bbox=min_lon,min_lat,max_lon,max_lat
+//	/api/0.6/[node|way|relation]/#id
+//	/api/0.6/[node|way|relation]/#id/#version
+//	/api/0.6/[node|way|relation]/#id/history
+//	/api/0.6/[way|relation]/#id/full
+//	/api/0.6/[nodes|ways|relations]?[nodes|ways|relations]=#id,#id,...
+//
+// The response is OSM XML, or JSON if the path has a .json suffix or
+// the request accepts application/json. The element calls support the
+// at=2006-01-02T15:04:05Z parameter to return the versions at a time.
+type Handler struct {
+	ds        Datasource
+	generator string
+	maxArea   float64
+}
+
+var _ http.Handler = &Handler{}
+
+// NewHandler creates a handler for the datasource.
+func NewHandler(ds Datasource, opts ...Option) *Handler {
+	h := &Handler{
+		ds:        ds,
+		generator: "github.com/paulmach/osm/osmserver",
+		maxArea:   DefaultMaxArea,
+	}
+
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	return h
+}
+
+// DefaultMaxArea is the largest map call bounding box,
+// in square degrees, the same as the osm api.
+const DefaultMaxArea = 0.25
+
+// httpError is an error response.
+type httpError struct {
+	code    int
+	message string
+}
+
+func (e *httpError) Error() string {
+	return e.message
+}
+
+func errorf(code int, format string, args ...interface{}) error {
+	return &httpError{code: code, message: fmt.Sprintf(format, args...)}
+}
+
+var errGone = &httpError{code: http.StatusGone}
+
+// ServeHTTP handles the requests, the handler can be mounted anywhere
+// as long as the paths end with the api paths, e.g. /api/0.6/node/1.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" && r.Method != "HEAD" {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	path := r.URL.Path
+	asJSON := strings.HasSuffix(path, ".json") ||
+		strings.Contains(r.Header.Get("Accept"), "application/json")
+	path = strings.TrimSuffix(path, ".json")
+
+	o, err := h.route(r.Context(), path, r)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	o.Version = 0.6
+	o.Generator = h.generator
+	o.Copyright = osm.Copyright
+	o.Attribution = osm.Attribution
+	o.License = osm.License
+
+	if asJSON {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		json.NewEncoder(w).Encode(o)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+	w.Write([]byte(xml.Header))
+	xml.NewEncoder(w).Encode(o)
+}
+
+func (h *Handler) route(ctx context.Context, path string, r *http.Request) (*osm.OSM, error) {
+	if strings.HasSuffix(path, "/api/versions") {
+		return &osm.OSM{}, nil
+	}
+
+	i := strings.LastIndex(path, "/api/0.6/")
+	if i == -1 {
+		return nil, errorf(http.StatusNotFound, "not found")
+	}
+
+	at, err := parseAt(r.URL.Query().Get("at"))
+	if err != nil {
+		return nil, err
+	}
+
+	parts := strings.Split(path[i+len("/api/0.6/"):], "/")
+	switch {
+	case len(parts) == 1 && parts[0] == "map":
+		return h.mapCall(ctx, r.URL.Query().Get("bbox"))
+	case len(parts) == 1:
+		return h.multi(ctx, parts[0], r.URL.Query().Get(parts[0]), at)
+	}
+
+	typ := osm.Type(parts[0])
+	if typ != osm.TypeNode && typ != osm.TypeWay && typ != osm.TypeRelation {
+		return nil, errorf(http.StatusNotFound, "not found")
+	}
+
+	id, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return nil, errorf(http.StatusBadRequest, "invalid id %q", parts[1])
+	}
+	fid, err := typ.FeatureID(id)
+	if err != nil {
+		return nil, errorf(http.StatusBadRequest, "%v", err)
+	}
+
+	switch {
+	case len(parts) == 2:
+		return h.element(ctx, fid, at)
+	case len(parts) == 3 && parts[2] == "history":
+		return h.history(ctx, fid)
+	case len(parts) == 3 && parts[2] == "full" && typ != osm.TypeNode:
+		return h.full(ctx, fid, at)
+	case len(parts) == 3:
+		v, err := strconv.Atoi(parts[2])
+		if err != nil {
+			return nil, errorf(http.StatusNotFound, "not found")
+		}
+
+		return h.version(ctx, fid, v)
+	}
+
+	return nil, errorf(http.StatusNotFound, "not found")
+}
+
+func (h *Handler) element(ctx context.Context, fid osm.FeatureID, at time.Time) (*osm.OSM, error) {
+	e, err := h.latest(ctx, fid, at)
+	if err != nil {
+		return nil, err
+	}
+
+	if !visible(e) {
+		return nil, errGone
+	}
+
+	o := &osm.OSM{}
+	o.Append(e)
+	return o, nil
+}
+
+func (h *Handler) version(ctx context.Context, fid osm.FeatureID, v int) (*osm.OSM, error) {
+	history, err := h.elementHistory(ctx, fid)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, e := range history {
+		if e.ElementID().Version() == v {
+			o := &osm.OSM{}
+			o.Append(e)
+			return o, nil
+		}
+	}
+
+	return nil, errorf(http.StatusNotFound, "%s version %d not found", fid, v)
+}
+
+func (h *Handler) history(ctx context.Context, fid osm.FeatureID) (*osm.OSM, error) {
+	history, err := h.elementHistory(ctx, fid)
+	if err != nil {
+		return nil, err
+	}
+
+	o := &osm.OSM{}
+	for _, e := range history {
+		o.Append(e)
+	}
+
+	return o, nil
+}
+
+// full returns the way and its nodes, or the relation, its direct
+// members and the nodes of the member ways, like the osm api.
+func (h *Handler) full(ctx context.Context, fid osm.FeatureID, at time.Time) (*osm.OSM, error) {
+	o, err := h.element(ctx, fid, at)
+	if err != nil {
+		return nil, err
+	}
+
+	var ways osm.Ways
+	seen := make(map[osm.FeatureID]bool)
+	add := func(fid osm.FeatureID) error {
+		if seen[fid] {
+			return nil
+		}
+		seen[fid] = true
+
+		// missing and deleted children are left out
+		e, err := h.latest(ctx, fid, at)
+		if h.notFound(err) {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if !visible(e) {
+			return nil
+		}
+
+		if w, ok := e.(*osm.Way); ok {
+			ways = append(ways, w)
+		}
+
+		o.Append(e)
+		return nil
+	}
+
+	if len(o.Ways) == 1 {
+		ways = o.Ways
+	}
+
+	if len(o.Relations) == 1 {
+		for _, m := range o.Relations[0].Members {
+			if err := add(m.FeatureID()); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for _, w := range ways {
+		for _, wn := range w.Nodes {
+			if err := add(wn.FeatureID()); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return o, nil
+}
+
+// multi returns the latest versions of the comma separated ids,
+// deleted elements are included. Any missing element is a 404.
+func (h *Handler) multi(ctx context.Context, plural, ids string, at time.Time) (*osm.OSM, error) {
+	var typ osm.Type
+	switch plural {
+	case "nodes":
+		typ = osm.TypeNode
+	case "ways":
+		typ = osm.TypeWay
+	case "relations":
+		typ = osm.TypeRelation
+	default:
+		return nil, errorf(http.StatusNotFound, "not found")
+	}
+
+	if ids == "" {
+		return nil, errorf(http.StatusBadRequest, "the parameter %s is required", plural)
+	}
+
+	o := &osm.OSM{}
+	for _, s := range strings.Split(ids, ",") {
+		id, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+		if err != nil {
+			return nil, errorf(http.StatusBadRequest, "invalid id %q", s)
+		}
+
+		fid, err := typ.FeatureID(id)
+		if err != nil {
+			return nil, errorf(http.StatusBadRequest, "%v", err)
+		}
+
+		e, err := h.latest(ctx, fid, at)
+		if err != nil {
+			return nil, err
+		}
+
+		o.Append(e)
+	}
+
+	return o, nil
+}
+
+func (h *Handler) mapCall(ctx context.Context, bbox string) (*osm.OSM, error) {
+	m, ok := h.ds.(Mapper)
+	if !ok {
+		return nil, errorf(http.StatusNotImplemented, "map call not supported")
+	}
+
+	parts := strings.Split(bbox, ",")
+	if len(parts) != 4 {
+		return nil, errorf(http.StatusBadRequest, "the parameter bbox is required, and must be of the form min_lon,min_lat,max_lon,max_lat")
+	}
+
+	var v [4]float64
+	for i, p := range parts {
+		f, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+		if err != nil {
+			return nil, errorf(http.StatusBadRequest, "invalid bbox value %q", p)
+		}
+		v[i] = f
+	}
+
+	b := &osm.Bounds{MinLon: v[0], MinLat: v[1], MaxLon: v[2], MaxLat: v[3]}
+	if b.MinLon > b.MaxLon || b.MinLat > b.MaxLat ||
+		b.MinLon < -180 || b.MaxLon > 180 || b.MinLat < -90 || b.MaxLat > 90 {
+		return nil, errorf(http.StatusBadRequest, "the latitudes must be between -90 and 90, longitudes between -180 and 180 and the minima must be less than the maxima")
+	}
+
+	if area := (b.MaxLon - b.MinLon) * (b.MaxLat - b.MinLat); h.maxArea > 0 && area > h.maxArea {
+		return nil, errorf(http.StatusBadRequest, "the maximum bbox size is %v, and your request was too large", h.maxArea)
+	}
+
+	o, err := m.Map(ctx, b)
+	if err != nil {
+		return nil, err
+	}
+	o.Bounds = b
+
+	return o, nil
+}
+
+// latest returns the highest version, deleted or not, at the time if not zero.
+func (h *Handler) latest(ctx context.Context, fid osm.FeatureID, at time.Time) (osm.Element, error) {
+	history, err := h.elementHistory(ctx, fid)
+	if err != nil {
+		return nil, err
+	}
+
+	var result osm.Element
+	for _, e := range history {
+		if !at.IsZero() && timestamp(e).After(at) {
+			continue
+		}
+
+		if result == nil || e.ElementID().Version() > result.ElementID().Version() {
+			result = e
+		}
+	}
+
+	if result == nil {
+		return nil, errorf(http.StatusNotFound, "%s not found", fid)
+	}
+
+	return result, nil
+}
+
+func (h *Handler) elementHistory(ctx context.Context, fid osm.FeatureID) (osm.Elements, error) {
+	var result osm.Elements
+	switch fid.Type() {
+	case osm.TypeNode:
+		nodes, err := h.ds.NodeHistory(ctx, fid.NodeID())
+		if err != nil {
+			return nil, err
+		}
+
+		for _, n := range nodes {
+			result = append(result, n)
+		}
+	case osm.TypeWay:
+		ways, err := h.ds.WayHistory(ctx, fid.WayID())
+		if err != nil {
+			return nil, err
+		}
+
+		for _, w := range ways {
+			result = append(result, w)
+		}
+	case osm.TypeRelation:
+		relations, err := h.ds.RelationHistory(ctx, fid.RelationID())
+		if err != nil {
+			return nil, err
+		}
+
+		for _, r := range relations {
+			result = append(result, r)
+		}
+	}
+
+	if len(result) == 0 {
+		return nil, errorf(http.StatusNotFound, "%s not found", fid)
+	}
+
+	return result, nil
+}
+
+func (h *Handler) notFound(err error) bool {
+	if e, ok := err.(*httpError); ok {
+		return e.code == http.StatusNotFound
+	}
+
+	return err != nil && h.ds.NotFound(err)
+}
+
+func (h *Handler) writeError(w http.ResponseWriter, err error) {
+	var e *httpError
+	switch {
+	case h.ds.NotFound(err):
+		e = &httpError{code: http.StatusNotFound}
+	case err == context.Canceled:
+		return
+	default:
+		var ok bool
+		if e, ok = err.(*httpError); !ok {
+			e = &httpError{code: http.StatusInternalServerError, message: err.Error()}
+		}
+	}
+
+	// the osm api reports the error in the Error header
+	if e.message != "" {
+		w.Header().Set("Error", e.message)
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(e.code)
+	w.Write([]byte(e.message))
+}
+
+func parseAt(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, errorf(http.StatusBadRequest, "invalid at time %q", s)
+	}
+
+	return t, nil
+}
+
+func visible(e osm.Element) bool {
+	switch e := e.(type) {
+	case *osm.Node:
+		return e.Visible
+	case *osm.Way:
+		return e.Visible
+	case *osm.Relation:
+		return e.Visible
+	}
+
+	return false
+}
+
+func timestamp(e osm.Element) time.Time {
+	switch e := e.(type) {
+	case *osm.Node:
+		return e.Timestamp
+	case *osm.Way:
+		return e.Timestamp
+	case *osm.Relation:
+		return e.Timestamp
+	}
+
+	return time.Time{}
+}
@@ -0,0 +1,246 @@
+package osmserver
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/paulmach/osm"
+	"github.com/paulmach/osm/osmapi"
+)
+
+func testStore() *Store {
+	t1 := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+	t2 := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	s := &Store{}
+	s.Add(context.Background(),
+		&osm.Node{ID: 1, Version: 1, Visible: true, Lat: 0.1, Lon: 0.1, Timestamp: t1},
+		&osm.Node{ID: 1, Version: 2, Visible: true, Lat: 0.15, Lon: 0.15, Timestamp: t2},
+		&osm.Node{ID: 2, Version: 1, Visible: true, Lat: 5, Lon: 5, Timestamp: t1},
+		&osm.Node{ID: 3, Version: 1, Visible: true, Lat: 0.2, Lon: 0.2, Timestamp: t1},
+		&osm.Node{ID: 3, Version: 2, Visible: false, Timestamp: t2},
+		&osm.Way{ID: 10, Version: 1, Visible: true, Nodes: osm.WayNodes{{ID: 1}, {ID: 2}}, Timestamp: t1},
+		&osm.Way{ID: 11, Version: 1, Visible: true, Nodes: osm.WayNodes{{ID: 2}}, Timestamp: t1},
+		&osm.Relation{ID: 20, Version: 1, Visible: true, Timestamp: t1, Members: osm.Members{
+			{Type: osm.TypeWay, Ref: 10, Role: "outer"},
+			{Type: osm.TypeNode, Ref: 3},
+		}},
+		&osm.Relation{ID: 21, Version: 1, Visible: true, Timestamp: t1, Members: osm.Members{
+			{Type: osm.TypeRelation, Ref: 20},
+		}},
+	)
+
+	return s
+}
+
+func testAPI(ds Datasource, opts ...Option) (*osmapi.Datasource, func()) {
+	ts := httptest.NewServer(NewHandler(ds, opts...))
+	api := osmapi.NewDatasource(ts.Client())
+	api.BaseURL = ts.URL + "/api/0.6"
+
+	return api, ts.Close
+}
+
+func TestHandler_elements(t *testing.T) {
+	api, close := testAPI(testStore())
+	defer close()
+	ctx := context.Background()
+
+	n, err := api.Node(ctx, 1)
+	if err != nil {
+		t.Fatalf("node error: %v", err)
+	}
+
+	if n.Version != 2 || n.Lat != 0.15 {
+		t.Errorf("incorrect node: %+v", n)
+	}
+
+	n, err = api.Node(ctx, 1, osmapi.At(time.Date(2018, 6, 1, 0, 0, 0, 0, time.UTC)))
+	if err != nil {
+		t.Fatalf("node at error: %v", err)
+	}
+
+	if n.Version != 1 {
+		t.Errorf("incorrect node version at time: %v", n.Version)
+	}
+
+	n, err = api.NodeVersion(ctx, 3, 1)
+	if err != nil {
+		t.Fatalf("node version error: %v", err)
+	}
+
+	if n.Version != 1 || !n.Visible {
+		t.Errorf("incorrect node version: %+v", n)
+	}
+
+	_, err = api.Node(ctx, 3)
+	if _, ok := err.(*osmapi.GoneError); !ok {
+		t.Errorf("expected gone error: %v", err)
+	}
+
+	_, err = api.Way(ctx, 100)
+	if !api.NotFound(err) {
+		t.Errorf("expected not found: %v", err)
+	}
+
+	nodes, err := api.NodeHistory(ctx, 3)
+	if err != nil {
+		t.Fatalf("history error: %v", err)
+	}
+
+	if len(nodes) != 2 {
+		t.Errorf("incorrect history: %v", nodes)
+	}
+
+	nodes, err = api.Nodes(ctx, []osm.NodeID{1, 3})
+	if err != nil {
+		t.Fatalf("nodes error: %v", err)
+	}
+
+	if len(nodes) != 2 || nodes[1].Visible {
+		t.Errorf("incorrect nodes: %v", nodes)
+	}
+}
+
+func TestHandler_full(t *testing.T) {
+	api, close := testAPI(testStore())
+	defer close()
+	ctx := context.Background()
+
+	o, err := api.WayFull(ctx, 10)
+	if err != nil {
+		t.Fatalf("way full error: %v", err)
+	}
+
+	if len(o.Ways) != 1 || len(o.Nodes) != 2 {
+		t.Errorf("incorrect way full: %v ways, %v nodes", len(o.Ways), len(o.Nodes))
+	}
+
+	o, err = api.RelationFull(ctx, 20)
+	if err != nil {
+		t.Fatalf("relation full error: %v", err)
+	}
+
+	// node 3 is deleted
+	if len(o.Relations) != 1 || len(o.Ways) != 1 || len(o.Nodes) != 2 {
+		t.Errorf("incorrect relation full: %v relations, %v ways, %v nodes",
+			len(o.Relations), len(o.Ways), len(o.Nodes))
+	}
+}
+
+func TestHandler_map(t *testing.T) {
+	api, close := testAPI(testStore())
+	defer close()
+	ctx := context.Background()
+
+	o, err := api.Map(ctx, &osm.Bounds{MinLat: 0, MaxLat: 0.3, MinLon: 0, MaxLon: 0.3})
+	if err != nil {
+		t.Fatalf("map error: %v", err)
+	}
+
+	ids := o.FeatureIDs()
+	expected := osm.FeatureIDs{
+		osm.NodeID(1).FeatureID(),
+		osm.NodeID(2).FeatureID(),
+		osm.WayID(10).FeatureID(),
+		osm.RelationID(20).FeatureID(),
+		osm.RelationID(21).FeatureID(),
+	}
+
+	if len(ids) != len(expected) {
+		t.Fatalf("incorrect elements: %v", ids)
+	}
+
+	for i := range ids {
+		if ids[i] != expected[i] {
+			t.Errorf("incorrect element: %v != %v", ids[i], expected[i])
+		}
+	}
+
+	_, err = api.Map(ctx, &osm.Bounds{MinLat: 0, MaxLat: 1, MinLon: 0, MaxLon: 1})
+	if e, ok := err.(*osmapi.UnexpectedStatusCodeError); !ok || e.Code != http.StatusBadRequest {
+		t.Errorf("expected bad request for large area: %v", err)
+	}
+
+	api, close2 := testAPI(&testStore().HistoryDatasource)
+	defer close2()
+
+	_, err = api.Map(ctx, &osm.Bounds{MinLat: 0, MaxLat: 0.3, MinLon: 0, MaxLon: 0.3})
+	if e, ok := err.(*osmapi.UnexpectedStatusCodeError); !ok || e.Code != http.StatusNotImplemented {
+		t.Errorf("expected not implemented: %v", err)
+	}
+}
+
+func TestHandler_json(t *testing.T) {
+	ts := httptest.NewServer(NewHandler(testStore(), Generator("test")))
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/api/0.6/way/10.json")
+	if err != nil {
+		t.Fatalf("request error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if ct := resp.Header.Get("Content-Type"); ct != "application/json; charset=utf-8" {
+		t.Errorf("incorrect content type: %v", ct)
+	}
+
+	data, _ := ioutil.ReadAll(resp.Body)
+	o := &osm.OSM{}
+	if err := json.Unmarshal(data, o); err != nil {
+		t.Fatalf("unmarshal error: %v", err)
+	}
+
+	if o.Generator != "test" || len(o.Ways) != 1 || o.Ways[0].ID != 10 {
+		t.Errorf("incorrect response: %s", data)
+	}
+}
+
+func TestHandler_errors(t *testing.T) {
+	ts := httptest.NewServer(NewHandler(testStore()))
+	defer ts.Close()
+
+	cases := []struct {
+		path string
+		code int
+	}{
+		{path: "/api/versions", code: http.StatusOK},
+		{path: "/api/0.6/node/abc", code: http.StatusBadRequest},
+		{path: "/api/0.6/node/1/full", code: http.StatusNotFound},
+		{path: "/api/0.6/node/1/9", code: http.StatusNotFound},
+		{path: "/api/0.6/changeset/1", code: http.StatusNotFound},
+		{path: "/api/0.6/nodes", code: http.StatusBadRequest},
+		{path: "/api/0.6/map?bbox=1,2", code: http.StatusBadRequest},
+		{path: "/api/0.6/node/1?at=yesterday", code: http.StatusBadRequest},
+		{path: "/other", code: http.StatusNotFound},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.path, func(t *testing.T) {
+			resp, err := http.Get(ts.URL + tc.path)
+			if err != nil {
+				t.Fatalf("request error: %v", err)
+			}
+			resp.Body.Close()
+
+			if resp.StatusCode != tc.code {
+				t.Errorf("incorrect status code: %v != %v", resp.StatusCode, tc.code)
+			}
+		})
+	}
+
+	resp, err := http.Post(ts.URL+"/api/0.6/node/1", "text/xml", nil)
+	if err != nil {
+		t.Fatalf("request error: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("incorrect status code: %v", resp.StatusCode)
+	}
+}
@@ -0,0 +1,19 @@
+package osmserver
+
+// An Option is a setting for the handler.
+type Option func(*Handler)
+
+// Generator sets the generator attribute of the responses.
+func Generator(g string) Option {
+	return func(h *Handler) {
+		h.generator = g
+	}
+}
+
+// MaxArea sets the largest map call bounding box in square degrees.
+// Zero is unlimited. The default is DefaultMaxArea, 0.25, like the osm api.
+func MaxArea(area float64) Option {
+	return func(h *Handler) {
+		h.maxArea = area
+	}
+}
@@ -0,0 +1,148 @@
+package osmserver
+
+import (
+	"context"
+	"sort"
+
+	"github.com/paulmach/osm"
+)
+
+// A Store is an in memory datasource that also supports the map call.
+// Add the elements, all versions or just the latest, using Add. Current
+// versions must be visible. It is not safe to add elements while serving
+// requests.
+type Store struct {
+	osm.HistoryDatasource
+}
+
+var _ Datasource = &Store{}
+var _ Mapper = &Store{}
+
+// Map returns the latest visible elements like the osm api map call:
+// the nodes within the bounds, the ways using those nodes, all the nodes
+// of those ways, the relations with any of these elements as a member and
+// the relations with those relations as a member.
+func (s *Store) Map(ctx context.Context, b *osm.Bounds) (*osm.OSM, error) {
+	o := &osm.OSM{}
+
+	nodes := make(map[osm.NodeID]bool)
+	for id, history := range s.Nodes {
+		if n := latestNode(history); n != nil && b.ContainsNode(n) {
+			nodes[id] = true
+			o.Nodes = append(o.Nodes, n)
+		}
+	}
+
+	ways := make(map[osm.WayID]bool)
+	for id, history := range s.Ways {
+		w := latestWay(history)
+		if w == nil {
+			continue
+		}
+
+		for _, wn := range w.Nodes {
+			if nodes[wn.ID] {
+				ways[id] = true
+				o.Ways = append(o.Ways, w)
+				break
+			}
+		}
+	}
+
+	// the nodes of the ways outside of the bounds
+	for _, w := range o.Ways {
+		for _, wn := range w.Nodes {
+			if nodes[wn.ID] {
+				continue
+			}
+			nodes[wn.ID] = true
+
+			if n := latestNode(s.Nodes[wn.ID]); n != nil {
+				o.Nodes = append(o.Nodes, n)
+			}
+		}
+	}
+
+	relations := make(map[osm.RelationID]bool)
+	for id, history := range s.Relations {
+		r := latestRelation(history)
+		if r == nil {
+			continue
+		}
+
+		for _, m := range r.Members {
+			if (m.Type == osm.TypeNode && nodes[osm.NodeID(m.Ref)]) ||
+				(m.Type == osm.TypeWay && ways[osm.WayID(m.Ref)]) {
+				relations[id] = true
+				o.Relations = append(o.Relations, r)
+				break
+			}
+		}
+	}
+
+	// the parent relations, only one level
+	for id, history := range s.Relations {
+		r := latestRelation(history)
+		if r == nil || relations[id] {
+			continue
+		}
+
+		for _, m := range r.Members {
+			if m.Type == osm.TypeRelation && relations[osm.RelationID(m.Ref)] {
+				o.Relations = append(o.Relations, r)
+				break
+			}
+		}
+	}
+
+	sort.Slice(o.Nodes, func(i, j int) bool { return o.Nodes[i].ID < o.Nodes[j].ID })
+	sort.Slice(o.Ways, func(i, j int) bool { return o.Ways[i].ID < o.Ways[j].ID })
+	sort.Slice(o.Relations, func(i, j int) bool { return o.Relations[i].ID < o.Relations[j].ID })
+
+	return o, nil
+}
+
+func latestNode(history osm.Nodes) *osm.Node {
+	var result *osm.Node
+	for _, n := range history {
+		if result == nil || n.Version > result.Version {
+			result = n
+		}
+	}
+
+	if result == nil || !result.Visible {
+		return nil
+	}
+
+	return result
+}
+
+func latestWay(history osm.Ways) *osm.Way {
+	var result *osm.Way
+	for _, w := range history {
+		if result == nil || w.Version > result.Version {
+			result = w
+		}
+	}
+
+	if result == nil || !result.Visible {
+		return nil
+	}
+
+	return result
+}
+
+func latestRelation(history osm.Relations) *osm.Relation {
+	var result *osm.Relation
+	for _, r := range history {
+		if result == nil || r.Version > result.Version {
+			result = r
+		}
+	}
+
+	if result == nil || !result.Visible {
+		return nil
+	}
+
+	return result
+}
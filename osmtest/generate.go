@@ -0,0 +1,263 @@
+package osmtest
+
+import (
+	"math/rand"
+	"time"
+
+	"github.com/paulmach/osm"
+)
+
+// Generator creates random, but reproducible, osm data for property style
+// tests. The values are chosen so they can be represented exactly by all
+// the encodings: coordinates have 7 decimal places, timestamps are whole
+// seconds in UTC and ids are positive and unique within a generator.
+type Generator struct {
+	r *rand.Rand
+
+	nodeID      osm.NodeID
+	wayID       osm.WayID
+	relationID  osm.RelationID
+	changesetID osm.ChangesetID
+
+	nodeIDs []osm.NodeID
+	wayIDs  []osm.WayID
+	relIDs  []osm.RelationID
+}
+
+// NewGenerator creates a generator. The same seed will always
+// generate the same sequence of data.
+func NewGenerator(seed int64) *Generator {
+	return &Generator{r: rand.New(rand.NewSource(seed))}
+}
+
+var (
+	tagKeys = []string{
+		"amenity", "building", "highway", "name", "name:de",
+		"note", "oneway", "source", "surface", "website",
+	}
+	tagValues = []string{
+		"yes", "no", "residential", "Main Street", "Straße",
+		"a;b", "with \"quotes\" & <brackets>", "東京", " spaces ", "",
+	}
+	roles = []string{"", "outer", "inner", "stop", "platform", "label"}
+	users = []string{"", "alice", "bob", "carol", "Ünïcode"}
+)
+
+// Node returns a random node.
+func (g *Generator) Node() *osm.Node {
+	g.nodeID += osm.NodeID(1 + g.r.Intn(10))
+	g.nodeIDs = append(g.nodeIDs, g.nodeID)
+
+	user, uid := g.user()
+	return &osm.Node{
+		ID:          g.nodeID,
+		Lat:         g.coordinate(90),
+		Lon:         g.coordinate(180),
+		User:        user,
+		UserID:      uid,
+		Visible:     g.r.Intn(10) != 0,
+		Version:     1 + g.r.Intn(10),
+		ChangesetID: g.changeset(),
+		Timestamp:   g.timestamp(),
+		Tags:        g.tags(),
+	}
+}
+
+// Way returns a random way. The way nodes reference previously
+// generated nodes if there are any.
+func (g *Generator) Way() *osm.Way {
+	g.wayID += osm.WayID(1 + g.r.Intn(10))
+	g.wayIDs = append(g.wayIDs, g.wayID)
+
+	n := 2 + g.r.Intn(9)
+	nodes := make(osm.WayNodes, n)
+	for i := range nodes {
+		nodes[i].ID = g.nodeRef()
+	}
+
+	user, uid := g.user()
+	return &osm.Way{
+		ID:          g.wayID,
+		User:        user,
+		UserID:      uid,
+		Visible:     g.r.Intn(10) != 0,
+		Version:     1 + g.r.Intn(10),
+		ChangesetID: g.changeset(),
+		Timestamp:   g.timestamp(),
+		Nodes:       nodes,
+		Tags:        g.tags(),
+	}
+}
+
+// Relation returns a random relation. The members reference previously
+// generated nodes, ways and relations if there are any.
+func (g *Generator) Relation() *osm.Relation {
+	g.relationID += osm.RelationID(1 + g.r.Intn(10))
+
+	n := 1 + g.r.Intn(5)
+	members := make(osm.Members, n)
+	for i := range members {
+		members[i].Role = roles[g.r.Intn(len(roles))]
+
+		switch g.r.Intn(3) {
+		case 0:
+			members[i].Type = osm.TypeNode
+			members[i].Ref = int64(g.nodeRef())
+		case 1:
+			members[i].Type = osm.TypeWay
+			members[i].Ref = int64(g.wayRef())
+		case 2:
+			members[i].Type = osm.TypeRelation
+			members[i].Ref = int64(g.relationRef())
+		}
+	}
+	g.relIDs = append(g.relIDs, g.relationID)
+
+	user, uid := g.user()
+	return &osm.Relation{
+		ID:          g.relationID,
+		User:        user,
+		UserID:      uid,
+		Visible:     g.r.Intn(10) != 0,
+		Version:     1 + g.r.Intn(10),
+		ChangesetID: g.changeset(),
+		Timestamp:   g.timestamp(),
+		Members:     members,
+		Tags:        g.tags(),
+	}
+}
+
+// Changeset returns a random changeset with a discussion of up to 2 comments.
+func (g *Generator) Changeset() *osm.Changeset {
+	g.changesetID += osm.ChangesetID(1 + g.r.Intn(10))
+
+	user, uid := g.user()
+	cs := &osm.Changeset{
+		ID:           g.changesetID,
+		User:         user,
+		UserID:       uid,
+		CreatedAt:    g.timestamp(),
+		Open:         g.r.Intn(4) == 0,
+		ChangesCount: g.r.Intn(1000),
+		Tags:         g.tags(),
+	}
+
+	if n := g.r.Intn(3); n > 0 {
+		cs.CommentsCount = n
+		cs.Discussion = &osm.ChangesetDiscussion{}
+		for i := 0; i < n; i++ {
+			user, uid := g.user()
+			cs.Discussion.Comments = append(cs.Discussion.Comments, &osm.ChangesetComment{
+				User:      user,
+				UserID:    uid,
+				Timestamp: g.timestamp(),
+				Text:      tagValues[g.r.Intn(len(tagValues))],
+			})
+		}
+	}
+
+	if !cs.Open {
+		cs.ClosedAt = cs.CreatedAt.Add(time.Duration(g.r.Intn(3600)) * time.Second)
+	}
+
+	if g.r.Intn(5) != 0 {
+		lat1, lat2 := g.coordinate(90), g.coordinate(90)
+		lon1, lon2 := g.coordinate(180), g.coordinate(180)
+		if lat1 > lat2 {
+			lat1, lat2 = lat2, lat1
+		}
+		if lon1 > lon2 {
+			lon1, lon2 = lon2, lon1
+		}
+		cs.MinLat, cs.MaxLat, cs.MinLon, cs.MaxLon = lat1, lat2, lon1, lon2
+	}
+
+	return cs
+}
+
+// OSM returns an osm object with the given number of nodes, ways and
+// relations. The ways reference the nodes and the relations reference
+// the other elements.
+func (g *Generator) OSM(nodes, ways, relations int) *osm.OSM {
+	o := &osm.OSM{}
+	for i := 0; i < nodes; i++ {
+		o.Nodes = append(o.Nodes, g.Node())
+	}
+
+	for i := 0; i < ways; i++ {
+		o.Ways = append(o.Ways, g.Way())
+	}
+
+	for i := 0; i < relations; i++ {
+		o.Relations = append(o.Relations, g.Relation())
+	}
+
+	return o
+}
+
+// coordinate returns a value in the [-max, max] range with 7 decimals,
+// the precision of the osm database.
+func (g *Generator) coordinate(max int64) float64 {
+	v := g.r.Int63n(2*max*1e7+1) - max*1e7
+	return float64(v) / 1e7
+}
+
+func (g *Generator) timestamp() time.Time {
+	// between 2005 and 2025
+	return time.Unix(1104537600+g.r.Int63n(20*365*24*3600), 0).UTC()
+}
+
+func (g *Generator) changeset() osm.ChangesetID {
+	return osm.ChangesetID(1 + g.r.Int63n(100000000))
+}
+
+func (g *Generator) user() (string, osm.UserID) {
+	i := g.r.Intn(len(users))
+	if i == 0 {
+		return "", 0
+	}
+
+	return users[i], osm.UserID(i * 1000)
+}
+
+// tags returns up to 4 tags with unique keys.
+func (g *Generator) tags() osm.Tags {
+	n := g.r.Intn(5)
+	if n == 0 {
+		return nil
+	}
+
+	tags := make(osm.Tags, 0, n)
+	for _, i := range g.r.Perm(len(tagKeys))[:n] {
+		tags = append(tags, osm.Tag{
+			Key:   tagKeys[i],
+			Value: tagValues[g.r.Intn(len(tagValues))],
+		})
+	}
+
+	return tags
+}
+
+func (g *Generator) nodeRef() osm.NodeID {
+	if len(g.nodeIDs) == 0 {
+		return osm.NodeID(1 + g.r.Int63n(1000000))
+	}
+
+	return g.nodeIDs[g.r.Intn(len(g.nodeIDs))]
+}
+
+func (g *Generator) wayRef() osm.WayID {
+	if len(g.wayIDs) == 0 {
+		return osm.WayID(1 + g.r.Int63n(1000000))
+	}
+
+	return g.wayIDs[g.r.Intn(len(g.wayIDs))]
+}
+
+func (g *Generator) relationRef() osm.RelationID {
+	if len(g.relIDs) == 0 {
+		return osm.RelationID(1 + g.r.Int63n(1000000))
+	}
+
+	return g.relIDs[g.r.Intn(len(g.relIDs))]
+}
@@ -0,0 +1,276 @@
+package osmtest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/paulmach/osm"
+	"github.com/paulmach/osm/osmpbf"
+)
+
+// An EncodeFunc encodes the osm data into bytes.
+type EncodeFunc func(*osm.OSM) ([]byte, error)
+
+// A DecodeFunc decodes the bytes created by the matching EncodeFunc.
+type DecodeFunc func([]byte) (*osm.OSM, error)
+
+// AssertRoundTrip encodes and then decodes the data and reports an error
+// if the result is different from the input. It can be used to validate
+// custom encoders. See Diff for how the data is compared.
+func AssertRoundTrip(t testing.TB, o *osm.OSM, encode EncodeFunc, decode DecodeFunc) {
+	t.Helper()
+
+	data, err := encode(o)
+	if err != nil {
+		t.Fatalf("encode error: %v", err)
+	}
+
+	result, err := decode(data)
+	if err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+
+	for _, d := range Diff(o, result) {
+		t.Error(d)
+	}
+}
+
+// AssertOSMPBRoundTrip validates the nodes, ways and relations using the
+// osm package protobuf encoding, osm.OSM.Marshal, and the changesets using
+// osm.Changeset.Marshal.
+func AssertOSMPBRoundTrip(t testing.TB, o *osm.OSM) {
+	t.Helper()
+
+	elements := &osm.OSM{Nodes: o.Nodes, Ways: o.Ways, Relations: o.Relations}
+	AssertRoundTrip(t, elements,
+		func(o *osm.OSM) ([]byte, error) { return o.Marshal() },
+		osm.UnmarshalOSM,
+	)
+
+	for _, cs := range o.Changesets {
+		data, err := cs.Marshal()
+		if err != nil {
+			t.Fatalf("changeset marshal error: %v", err)
+		}
+
+		result, err := osm.UnmarshalChangeset(data)
+		if err != nil {
+			t.Fatalf("changeset unmarshal error: %v", err)
+		}
+
+		for _, d := range diffChangeset(cs, result) {
+			t.Error(d)
+		}
+	}
+}
+
+// AssertXMLRoundTrip validates the data using the osm xml format.
+func AssertXMLRoundTrip(t testing.TB, o *osm.OSM) {
+	t.Helper()
+
+	AssertRoundTrip(t, o,
+		func(o *osm.OSM) ([]byte, error) { return xml.Marshal(o) },
+		func(data []byte) (*osm.OSM, error) {
+			result := &osm.OSM{}
+			err := xml.Unmarshal(data, result)
+			return result, err
+		},
+	)
+}
+
+// AssertJSONRoundTrip validates the data using the osmjson format.
+func AssertJSONRoundTrip(t testing.TB, o *osm.OSM) {
+	t.Helper()
+
+	AssertRoundTrip(t, o,
+		func(o *osm.OSM) ([]byte, error) { return json.Marshal(o) },
+		func(data []byte) (*osm.OSM, error) {
+			result := &osm.OSM{}
+			err := json.Unmarshal(data, result)
+			return result, err
+		},
+	)
+}
+
+// AssertPBFRoundTrip validates the nodes, ways and relations using the
+// osmpbf package. The data is written with history so the element
+// visibility is preserved. Changesets are not part of the pbf format.
+func AssertPBFRoundTrip(t testing.TB, o *osm.OSM) {
+	t.Helper()
+
+	elements := &osm.OSM{Nodes: o.Nodes, Ways: o.Ways, Relations: o.Relations}
+	AssertRoundTrip(t, elements, encodePBF, decodePBF)
+}
+
+func encodePBF(o *osm.OSM) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	enc, err := osmpbf.NewEncoder(buf, osmpbf.History(true))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, obj := range o.Objects() {
+		if err := enc.Encode(obj); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func decodePBF(data []byte) (*osm.OSM, error) {
+	scanner := osmpbf.New(context.Background(), bytes.NewReader(data), 1)
+	defer scanner.Close()
+
+	o := &osm.OSM{}
+	for scanner.Scan() {
+		o.Append(scanner.Object())
+	}
+
+	return o, scanner.Err()
+}
+
+// coordinateTolerance allows for the floating point differences between
+// encodings that store coordinates as scaled integers.
+const coordinateTolerance = 1e-9
+
+// Diff returns a description of the differences in the nodes, ways,
+// relations and changesets, including their discussions. The elements are compared using their Equal
+// methods so the tag order is ignored. Coordinates only need to match to
+// within the precision of the encodings.
+func Diff(expected, actual *osm.OSM) []string {
+	var diffs []string
+	if len(expected.Nodes) != len(actual.Nodes) {
+		diffs = append(diffs, fmt.Sprintf("expected %d nodes, got %d", len(expected.Nodes), len(actual.Nodes)))
+	} else {
+		for i, n := range expected.Nodes {
+			if !equalNode(n, actual.Nodes[i]) {
+				diffs = append(diffs, fmt.Sprintf("node %d: expected %+v, got %+v", i, n, actual.Nodes[i]))
+			}
+		}
+	}
+
+	if len(expected.Ways) != len(actual.Ways) {
+		diffs = append(diffs, fmt.Sprintf("expected %d ways, got %d", len(expected.Ways), len(actual.Ways)))
+	} else {
+		for i, w := range expected.Ways {
+			if !w.Equal(actual.Ways[i]) {
+				diffs = append(diffs, fmt.Sprintf("way %d: expected %+v, got %+v", i, w, actual.Ways[i]))
+			}
+		}
+	}
+
+	if len(expected.Relations) != len(actual.Relations) {
+		diffs = append(diffs, fmt.Sprintf("expected %d relations, got %d", len(expected.Relations), len(actual.Relations)))
+	} else {
+		for i, r := range expected.Relations {
+			if !r.Equal(actual.Relations[i]) {
+				diffs = append(diffs, fmt.Sprintf("relation %d: expected %+v, got %+v", i, r, actual.Relations[i]))
+			}
+		}
+	}
+
+	if len(expected.Changesets) != len(actual.Changesets) {
+		diffs = append(diffs, fmt.Sprintf("expected %d changesets, got %d", len(expected.Changesets), len(actual.Changesets)))
+	} else {
+		for i, cs := range expected.Changesets {
+			diffs = append(diffs, diffChangeset(cs, actual.Changesets[i])...)
+		}
+	}
+
+	return diffs
+}
+
+func equalNode(expected, actual *osm.Node) bool {
+	if expected == nil || actual == nil {
+		return expected == actual
+	}
+
+	if !equalCoordinate(expected.Lat, actual.Lat) || !equalCoordinate(expected.Lon, actual.Lon) {
+		return false
+	}
+
+	c := *actual
+	c.Lat, c.Lon = expected.Lat, expected.Lon
+	return expected.Equal(&c)
+}
+
+func diffChangeset(expected, actual *osm.Changeset) []string {
+	e, a := *expected, *actual
+	if !equalCoordinate(e.MinLat, a.MinLat) || !equalCoordinate(e.MaxLat, a.MaxLat) ||
+		!equalCoordinate(e.MinLon, a.MinLon) || !equalCoordinate(e.MaxLon, a.MaxLon) {
+		return []string{fmt.Sprintf("changeset %d: expected bounds %v, got %v", e.ID, e.Bounds(), a.Bounds())}
+	}
+
+	if !e.CreatedAt.Equal(a.CreatedAt) || !e.ClosedAt.Equal(a.ClosedAt) {
+		return []string{fmt.Sprintf("changeset %d: expected times %v %v, got %v %v",
+			e.ID, e.CreatedAt, e.ClosedAt, a.CreatedAt, a.ClosedAt)}
+	}
+
+	if !equalTags(e.Tags, a.Tags) {
+		return []string{fmt.Sprintf("changeset %d: expected tags %v, got %v", e.ID, e.Tags, a.Tags)}
+	}
+
+	if !equalDiscussion(e.Discussion, a.Discussion) {
+		return []string{fmt.Sprintf("changeset %d: expected discussion %+v, got %+v", e.ID, e.Discussion, a.Discussion)}
+	}
+
+	if e.ID != a.ID || e.User != a.User || e.UserID != a.UserID || e.Open != a.Open ||
+		e.ChangesCount != a.ChangesCount || e.CommentsCount != a.CommentsCount {
+		return []string{fmt.Sprintf("changeset %d: expected %+v, got %+v", e.ID, expected, actual)}
+	}
+
+	return nil
+}
+
+func equalDiscussion(a, b *osm.ChangesetDiscussion) bool {
+	var ac, bc []*osm.ChangesetComment
+	if a != nil {
+		ac = a.Comments
+	}
+	if b != nil {
+		bc = b.Comments
+	}
+
+	if len(ac) != len(bc) {
+		return false
+	}
+
+	for i := range ac {
+		c1, c2 := ac[i], bc[i]
+		if c1.User != c2.User || c1.UserID != c2.UserID ||
+			c1.Text != c2.Text || !c1.Timestamp.Equal(c2.Timestamp) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func equalCoordinate(a, b float64) bool {
+	return math.Abs(a-b) < coordinateTolerance
+}
+
+func equalTags(a, b osm.Tags) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	m := a.Map()
+	for _, t := range b {
+		if v, ok := m[t.Key]; !ok || v != t.Value {
+			return false
+		}
+	}
+
+	return true
+}
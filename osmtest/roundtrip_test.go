@@ -0,0 +1,55 @@
+package osmtest
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGenerator_deterministic(t *testing.T) {
+	o1 := NewGenerator(42).OSM(10, 5, 3)
+	o2 := NewGenerator(42).OSM(10, 5, 3)
+
+	if !reflect.DeepEqual(o1, o2) {
+		t.Errorf("same seed should generate the same data")
+	}
+
+	if len(o1.Nodes) != 10 || len(o1.Ways) != 5 || len(o1.Relations) != 3 {
+		t.Errorf("incorrect counts: %d %d %d", len(o1.Nodes), len(o1.Ways), len(o1.Relations))
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	for seed := int64(0); seed < 10; seed++ {
+		g := NewGenerator(seed)
+		o := g.OSM(100, 20, 10)
+		for i := 0; i < 10; i++ {
+			o.Changesets = append(o.Changesets, g.Changeset())
+		}
+
+		t.Run("osmpb", func(t *testing.T) { AssertOSMPBRoundTrip(t, o) })
+		t.Run("xml", func(t *testing.T) { AssertXMLRoundTrip(t, o) })
+		t.Run("json", func(t *testing.T) { AssertJSONRoundTrip(t, o) })
+		t.Run("pbf", func(t *testing.T) { AssertPBFRoundTrip(t, o) })
+	}
+}
+
+func TestDiff(t *testing.T) {
+	o1 := NewGenerator(1).OSM(3, 2, 1)
+	o2 := NewGenerator(1).OSM(3, 2, 1)
+
+	if d := Diff(o1, o2); len(d) != 0 {
+		t.Errorf("should be no differences: %v", d)
+	}
+
+	o2.Nodes[1].Lat += 1e-12
+	if d := Diff(o1, o2); len(d) != 0 {
+		t.Errorf("small coordinate differences should be ignored: %v", d)
+	}
+
+	o2.Nodes[1].Lat += 1e-7
+	o2.Ways[0].Nodes[0].ID++
+	o2.Relations = nil
+	if d := Diff(o1, o2); len(d) != 3 {
+		t.Errorf("incorrect differences: %v", d)
+	}
+}
@@ -0,0 +1,83 @@
+package osmxml
+
+import (
+	"bufio"
+	"compress/bzip2"
+	"context"
+	"io"
+
+	"github.com/paulmach/osm"
+)
+
+var _ osm.Scanner = &ChangesetScanner{}
+
+// ChangesetScanner streams the changesets, with their discussions, from
+// the weekly changeset dump, changesets-latest.osm.bz2 found at
+// https://planet.osm.org/planet/. The dump is tens of gigabytes when
+// uncompressed so only the current changeset is kept in memory.
+// Bzip2 compressed input is detected and decompressed automatically.
+type ChangesetScanner struct {
+	scanner *Scanner
+	next    *osm.Changeset
+}
+
+// bzip2Magic are the first bytes of a bzip2 stream, "BZh".
+var bzip2Magic = []byte("BZh")
+
+// NewChangesetScanner returns a new changeset scanner to read from r,
+// which can be bzip2 compressed or plain xml. Only the changesets are
+// returned, any other elements in the file are skipped.
+func NewChangesetScanner(ctx context.Context, r io.Reader, opts ...Option) *ChangesetScanner {
+	br := bufio.NewReader(r)
+
+	var reader io.Reader = br
+	if magic, err := br.Peek(len(bzip2Magic)); err == nil && string(magic) == string(bzip2Magic) {
+		reader = bzip2.NewReader(br)
+	}
+
+	return &ChangesetScanner{
+		scanner: New(ctx, reader, opts...),
+	}
+}
+
+// Scan advances to the next changeset, which will then be available
+// through the Changeset method. It returns false when the scan stops.
+// After Scan returns false, the Err method will return any error that
+// occurred during scanning.
+func (s *ChangesetScanner) Scan() bool {
+	for s.scanner.Scan() {
+		if cs, ok := s.scanner.Object().(*osm.Changeset); ok {
+			s.next = cs
+			return true
+		}
+	}
+
+	s.next = nil
+	return false
+}
+
+// Changeset returns the most recent changeset generated by a call to Scan.
+func (s *ChangesetScanner) Changeset() *osm.Changeset {
+	return s.next
+}
+
+// Object returns the most recent changeset generated by a call to Scan
+// as an osm.Object, to implement the osm.Scanner interface.
+func (s *ChangesetScanner) Object() osm.Object {
+	if s.next == nil {
+		return nil
+	}
+
+	return s.next
+}
+
+// Err returns the first non-EOF error that was encountered by the scanner.
+func (s *ChangesetScanner) Err() error {
+	return s.scanner.Err()
+}
+
+// Close causes all future calls to Scan to return false.
+// Does not close the underlying reader.
+func (s *ChangesetScanner) Close() error {
+	return s.scanner.Close()
+}
@@ -0,0 +1,114 @@
+package osmxml
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/paulmach/osm"
+)
+
+func TestChangesetScanner(t *testing.T) {
+	cases := []struct {
+		name     string
+		filename string
+	}{
+		{
+			name:     "bzip2",
+			filename: "../testdata/changesets-dump.osm.bz2",
+		},
+		{
+			name:     "plain xml",
+			filename: "../testdata/changesets-dump.osm",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			f, err := os.Open(tc.filename)
+			if err != nil {
+				t.Fatalf("could not open file: %v", err)
+			}
+			defer f.Close()
+
+			scanner := NewChangesetScanner(context.Background(), f)
+			defer scanner.Close()
+
+			var ids []osm.ChangesetID
+			var comments int
+			for scanner.Scan() {
+				cs := scanner.Changeset()
+				ids = append(ids, cs.ID)
+				if cs.Discussion != nil {
+					comments += len(cs.Discussion.Comments)
+				}
+
+				if scanner.Object().(*osm.Changeset) != cs {
+					t.Errorf("object should be the changeset")
+				}
+			}
+
+			if err := scanner.Err(); err != nil {
+				t.Fatalf("scan error: %v", err)
+			}
+
+			if len(ids) != 3 || ids[0] != 1 || ids[2] != 145000000 {
+				t.Errorf("incorrect changesets: %v", ids)
+			}
+
+			if comments != 2 {
+				t.Errorf("incorrect number of comments: %v", comments)
+			}
+
+			if scanner.Object() != nil {
+				t.Errorf("object should be nil after the scan is done")
+			}
+		})
+	}
+}
+
+func TestChangesetScanner_skipsElements(t *testing.T) {
+	data := []byte(`<osm>
+		<node id="1" lat="1" lon="2"/>
+		<changeset id="2"/>
+		<way id="3"/>
+	</osm>`)
+
+	scanner := NewChangesetScanner(context.Background(), bytes.NewReader(data))
+	defer scanner.Close()
+
+	count := 0
+	for scanner.Scan() {
+		count++
+		if id := scanner.Changeset().ID; id != 2 {
+			t.Errorf("incorrect changeset: %v", id)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		t.Fatalf("scan error: %v", err)
+	}
+
+	if count != 1 {
+		t.Errorf("incorrect number of changesets: %v", count)
+	}
+}
+
+func TestChangesetScanner_corruptBzip2(t *testing.T) {
+	data, err := ioutil.ReadFile("../testdata/changesets-dump.osm.bz2")
+	if err != nil {
+		t.Fatalf("could not read file: %v", err)
+	}
+
+	scanner := NewChangesetScanner(context.Background(), bytes.NewReader(data[:len(data)/2]))
+	defer scanner.Close()
+
+	for scanner.Scan() {
+	}
+
+	if scanner.Err() == nil {
+		t.Errorf("expected error for truncated data")
+	}
+}
@@ -0,0 +1,27 @@
+package osmxml
+
+import "github.com/paulmach/osm"
+
+// An Option is a setting for creating the scanner.
+type Option func(*Scanner) error
+
+// PreserveUnknown keeps the xml attributes and child elements of nodes,
+// ways and relations that are not part of the osm format in the XMLExtra
+// field. They are written out again when marshalling the elements to xml,
+// so vendor extensions survive a pipeline. By default they are discarded.
+func PreserveUnknown() Option {
+	return func(s *Scanner) error {
+		s.preserveUnknown = true
+		return nil
+	}
+}
+
+// WithLogger sets the logger that receives warnings, e.g. for skipped
+// elements that are not part of the osm format. Each unknown element name
+// is only reported once.
+func WithLogger(l osm.Logger) Option {
+	return func(s *Scanner) error {
+		s.logger = l
+		return nil
+	}
+}
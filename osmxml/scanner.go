@@ -28,10 +28,16 @@ type Scanner struct {
 	decoder *xml.Decoder
 	next    osm.Object
 	err     error
+
+	preserveUnknown bool
+
+	logger  osm.Logger
+	skipped map[string]bool
 }
 
-// New returns a new Scanner to read from r.
-func New(ctx context.Context, r io.Reader) *Scanner {
+// New returns a new Scanner to read from r. Any option
+// errors are returned by Err after the first call to Scan.
+func New(ctx context.Context, r io.Reader, opts ...Option) *Scanner {
 	if ctx == nil {
 		ctx = context.Background()
 	}
@@ -40,6 +46,13 @@ func New(ctx context.Context, r io.Reader) *Scanner {
 		decoder: xml.NewDecoder(r),
 	}
 
+	for _, opt := range opts {
+		if err := opt(s); err != nil {
+			s.err = err
+			break
+		}
+	}
+
 	s.ctx, s.done = context.WithCancel(ctx)
 	return s
 }
@@ -85,15 +98,33 @@ Loop:
 		switch strings.ToLower(se.Name.Local) {
 		case "node":
 			node := &osm.Node{}
-			err = s.decoder.DecodeElement(&node, &se)
+			if s.preserveUnknown {
+				x := &extraNode{Node: node}
+				err = s.decoder.DecodeElement(x, &se)
+				node.XMLExtra = xmlExtra(x.XMLExtra)
+			} else {
+				err = s.decoder.DecodeElement(&node, &se)
+			}
 			s.next = node
 		case "way":
 			way := &osm.Way{}
-			err = s.decoder.DecodeElement(&way, &se)
+			if s.preserveUnknown {
+				x := &extraWay{Way: way}
+				err = s.decoder.DecodeElement(x, &se)
+				way.XMLExtra = xmlExtra(x.XMLExtra)
+			} else {
+				err = s.decoder.DecodeElement(&way, &se)
+			}
 			s.next = way
 		case "relation":
 			relation := &osm.Relation{}
-			err = s.decoder.DecodeElement(&relation, &se)
+			if s.preserveUnknown {
+				x := &extraRelation{Relation: relation}
+				err = s.decoder.DecodeElement(x, &se)
+				relation.XMLExtra = xmlExtra(x.XMLExtra)
+			} else {
+				err = s.decoder.DecodeElement(&relation, &se)
+			}
 			s.next = relation
 		case "changeset":
 			cs := &osm.Changeset{}
@@ -108,6 +139,7 @@ Loop:
 			err = s.decoder.DecodeElement(&u, &se)
 			s.next = u
 		default:
+			s.skip(se.Name.Local)
 			continue Loop
 		}
 
@@ -120,8 +152,62 @@ Loop:
 	}
 }
 
+// The extra types decode the xml attributes and child elements that
+// are not part of the osm format together with the element.
+type extraNode struct {
+	*osm.Node
+	osm.XMLExtra
+}
+
+type extraWay struct {
+	*osm.Way
+	osm.XMLExtra
+}
+
+type extraRelation struct {
+	*osm.Relation
+	osm.XMLExtra
+}
+
+// xmlExtra returns nil if there is no extra data.
+func xmlExtra(x osm.XMLExtra) *osm.XMLExtra {
+	if len(x.Attrs) == 0 && len(x.Elements) == 0 {
+		return nil
+	}
+
+	return &x
+}
+
+// containers are the elements that wrap the data and are expected to be skipped.
+var containers = map[string]bool{
+	"osm":       true,
+	"osmchange": true,
+	"create":    true,
+	"modify":    true,
+	"delete":    true,
+	"bounds":    true,
+	"bound":     true,
+	"meta":      true,
+	"remark":    true,
+}
+
+// skip reports the first time an unknown element is skipped.
+func (s *Scanner) skip(name string) {
+	if s.logger == nil || containers[strings.ToLower(name)] || s.skipped[name] {
+		return
+	}
+
+	if s.skipped == nil {
+		s.skipped = make(map[string]bool)
+	}
+	s.skipped[name] = true
+
+	s.logger.Warn("osmxml: skipping unknown element", "name", name)
+}
+
 // Object returns the most recent token generated by a call to Scan
 // as a new osm.Object. This interface is implemented by:
+//
 //	*osm.Node
 //	*osm.Way
 //	*osm.Relation
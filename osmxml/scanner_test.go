@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"compress/bzip2"
 	"context"
+	"encoding/xml"
+	"fmt"
 	"io"
 	"os"
 	"testing"
@@ -144,6 +146,133 @@ func TestScanner_userNote(t *testing.T) {
 	}
 }
 
+func TestScanner_PreserveUnknown(t *testing.T) {
+	data := []byte(`<osm>
+		<node id="1" lat="1" lon="2" vendor:score="5" xmlns:vendor="http://example.com">
+			<tag k="amenity" v="cafe" />
+			<vendor:review stars="4">Good coffee</vendor:review>
+		</node>
+	</osm>`)
+
+	scanner := New(context.Background(), bytes.NewReader(data))
+	if !scanner.Scan() {
+		t.Fatalf("should scan: %v", scanner.Err())
+	}
+
+	n := scanner.Object().(*osm.Node)
+	if n.XMLExtra != nil {
+		t.Errorf("should discard unknown data by default: %v", n.XMLExtra)
+	}
+
+	scanner = New(context.Background(), bytes.NewReader(data), PreserveUnknown())
+	if !scanner.Scan() {
+		t.Fatalf("should scan: %v", scanner.Err())
+	}
+
+	n = scanner.Object().(*osm.Node)
+	if len(n.Tags) != 1 {
+		t.Errorf("incorrect tags: %v", n.Tags)
+	}
+
+	if n.XMLExtra == nil || len(n.XMLExtra.Attrs) != 2 || len(n.XMLExtra.Elements) != 1 {
+		t.Fatalf("should keep unknown data: %v", n.XMLExtra)
+	}
+
+	out, err := xml.Marshal(n)
+	if err != nil {
+		t.Fatalf("marshal error: %v", err)
+	}
+
+	scanner = New(context.Background(), bytes.NewReader(out), PreserveUnknown())
+	if !scanner.Scan() {
+		t.Fatalf("should scan: %v", scanner.Err())
+	}
+
+	e := scanner.Object().(*osm.Node).XMLExtra.Elements[0]
+	if e.XMLName.Local != "review" || e.InnerXML != "Good coffee" {
+		t.Errorf("incorrect element after round trip: %+v", e)
+	}
+
+	found := false
+	for _, a := range e.Attrs {
+		if a.Name.Local == "stars" && a.Value == "4" {
+			found = true
+		}
+	}
+
+	if !found {
+		t.Errorf("element attribute not kept: %+v", e.Attrs)
+	}
+
+	if !bytes.Contains(out, []byte(`score="5"`)) {
+		t.Errorf("attribute not written: %s", out)
+	}
+}
+
+func TestScanner_PreserveUnknown_wayRelation(t *testing.T) {
+	data := []byte(`<osm>
+		<way id="1"><nd ref="1" /><center lat="1" lon="2" /></way>
+		<relation id="2" vendor="x"><member type="way" ref="1" role="" /></relation>
+	</osm>`)
+
+	scanner := New(context.Background(), bytes.NewReader(data), PreserveUnknown())
+	if !scanner.Scan() {
+		t.Fatalf("should scan: %v", scanner.Err())
+	}
+
+	w := scanner.Object().(*osm.Way)
+	if len(w.Nodes) != 1 || w.XMLExtra == nil || w.XMLExtra.Elements[0].XMLName.Local != "center" {
+		t.Errorf("incorrect way: %v %v", w.Nodes, w.XMLExtra)
+	}
+
+	if !scanner.Scan() {
+		t.Fatalf("should scan: %v", scanner.Err())
+	}
+
+	r := scanner.Object().(*osm.Relation)
+	if len(r.Members) != 1 || r.XMLExtra == nil || r.XMLExtra.Attrs[0].Value != "x" {
+		t.Errorf("incorrect relation: %v %v", r.Members, r.XMLExtra)
+	}
+}
+
+func TestScanner_WithLogger(t *testing.T) {
+	data := []byte(`<osm>
+		<bounds minlat="1" minlon="2" maxlat="3" maxlon="4" />
+		<node id="1" lat="1" lon="2" />
+		<area id="2" />
+		<area id="3" />
+		<node id="4" lat="1" lon="2" />
+	</osm>`)
+
+	logger := &testLogger{}
+	scanner := New(context.Background(), bytes.NewReader(data), WithLogger(logger))
+
+	count := 0
+	for scanner.Scan() {
+		count++
+	}
+
+	if err := scanner.Err(); err != nil {
+		t.Fatalf("scan error: %v", err)
+	}
+
+	if count != 2 {
+		t.Errorf("incorrect number of objects: %v", count)
+	}
+
+	if len(logger.warnings) != 1 || logger.warnings[0] != "osmxml: skipping unknown element [name area]" {
+		t.Errorf("incorrect warnings: %v", logger.warnings)
+	}
+}
+
+type testLogger struct {
+	warnings []string
+}
+
+func (l *testLogger) Warn(msg string, keyvals ...interface{}) {
+	l.warnings = append(l.warnings, fmt.Sprintf("%s %v", msg, keyvals))
+}
+
 func TestAndorra(t *testing.T) {
 	f, err := os.Open("../testdata/andorra-latest.osm.bz2")
 	if err != nil {
@@ -0,0 +1,123 @@
+package overpass
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+
+	"github.com/paulmach/osm"
+)
+
+// NominatimURL is the search endpoint of the main Nominatim instance,
+// used to resolve area names.
+const NominatimURL = "https://nominatim.openstreetmap.org/search"
+
+// The area ids are the ids of the closed ways and multipolygon
+// relations they are derived from plus these offsets.
+const (
+	wayAreaOffset      = 2400000000
+	relationAreaOffset = 3600000000
+)
+
+// ErrAreaNotFound is returned by ResolveArea if there are no
+// ways or relations with the name.
+var ErrAreaNotFound = errors.New("overpass: area not found")
+
+// AreaID is the id of an Overpass area, the areas derived from closed
+// ways and multipolygon relations that can be used to filter queries.
+type AreaID int64
+
+// WayArea returns the id of the area derived from the closed way.
+func WayArea(id osm.WayID) AreaID {
+	return AreaID(id + wayAreaOffset)
+}
+
+// RelationArea returns the id of the area derived from the relation.
+func RelationArea(id osm.RelationID) AreaID {
+	return AreaID(id + relationAreaOffset)
+}
+
+// WayID returns the id of the way the area is derived from.
+// Returns false if the area is derived from a relation.
+func (id AreaID) WayID() (osm.WayID, bool) {
+	if id <= wayAreaOffset || id > relationAreaOffset {
+		return 0, false
+	}
+
+	return osm.WayID(id - wayAreaOffset), true
+}
+
+// RelationID returns the id of the relation the area is derived from.
+// Returns false if the area is derived from a way.
+func (id AreaID) RelationID() (osm.RelationID, bool) {
+	if id <= relationAreaOffset {
+		return 0, false
+	}
+
+	return osm.RelationID(id - relationAreaOffset), true
+}
+
+// ResolveArea runs ResolveArea using the DefaultDatasource.
+func ResolveArea(ctx context.Context, name string) (AreaID, error) {
+	return DefaultDatasource.ResolveArea(ctx, name)
+}
+
+// ResolveArea searches Nominatim for the name, e.g. "Berlin, Germany", and
+// returns the area of the first way or relation found, like the geocodeArea
+// shortcut of overpass turbo. Nominatim allows at most one request per
+// second, the default client limits the rate.
+func (ds *Datasource) ResolveArea(ctx context.Context, name string) (AreaID, error) {
+	client := ds.Client
+	if client == nil {
+		client = DefaultDatasource.Client
+	}
+
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	u := ds.NominatimURL
+	if u == "" {
+		u = NominatimURL
+	}
+
+	u += "?" + url.Values{"q": {name}, "format": {"jsonv2"}}.Encode()
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, &UnexpectedStatusCodeError{
+			Code: resp.StatusCode,
+			URL:  u,
+		}
+	}
+
+	var places []struct {
+		OSMType string `json:"osm_type"`
+		OSMID   int64  `json:"osm_id"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&places); err != nil {
+		return 0, err
+	}
+
+	for _, p := range places {
+		switch p.OSMType {
+		case "way":
+			return WayArea(osm.WayID(p.OSMID)), nil
+		case "relation":
+			return RelationArea(osm.RelationID(p.OSMID)), nil
+		}
+	}
+
+	return 0, ErrAreaNotFound
+}
@@ -0,0 +1,84 @@
+package overpass
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/paulmach/osm"
+)
+
+func TestAreaID(t *testing.T) {
+	a := RelationArea(62422)
+	if a != 3600062422 {
+		t.Errorf("incorrect relation area: %v", a)
+	}
+
+	if id, ok := a.RelationID(); !ok || id != 62422 {
+		t.Errorf("incorrect relation id: %v %v", id, ok)
+	}
+
+	if _, ok := a.WayID(); ok {
+		t.Errorf("should not be a way area")
+	}
+
+	a = WayArea(4)
+	if a != 2400000004 {
+		t.Errorf("incorrect way area: %v", a)
+	}
+
+	if id, ok := a.WayID(); !ok || id != 4 {
+		t.Errorf("incorrect way id: %v %v", id, ok)
+	}
+
+	if _, ok := a.RelationID(); ok {
+		t.Errorf("should not be a relation area")
+	}
+
+	if _, ok := AreaID(1).WayID(); ok {
+		t.Errorf("should not be a way area")
+	}
+}
+
+func TestDatasource_ResolveArea(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("q") {
+		case "Berlin":
+			w.Write([]byte(`[
+				{"osm_type": "node", "osm_id": 240109189},
+				{"osm_type": "relation", "osm_id": 62422}
+			]`))
+		case "Park":
+			w.Write([]byte(`[{"osm_type": "way", "osm_id": 10}]`))
+		default:
+			w.Write([]byte(`[]`))
+		}
+	}))
+	defer ts.Close()
+
+	ds := &Datasource{NominatimURL: ts.URL, Client: ts.Client()}
+
+	a, err := ds.ResolveArea(context.Background(), "Berlin")
+	if err != nil {
+		t.Fatalf("resolve error: %v", err)
+	}
+
+	if a != RelationArea(osm.RelationID(62422)) {
+		t.Errorf("should skip nodes: %v", a)
+	}
+
+	a, err = ds.ResolveArea(context.Background(), "Park")
+	if err != nil {
+		t.Fatalf("resolve error: %v", err)
+	}
+
+	if a != WayArea(10) {
+		t.Errorf("incorrect area: %v", a)
+	}
+
+	_, err = ds.ResolveArea(context.Background(), "Nowhere")
+	if err != ErrAreaNotFound {
+		t.Errorf("incorrect error: %v", err)
+	}
+}
@@ -0,0 +1,323 @@
+package overpass
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// CSVScanner reads the rows of an [out:csv(...)] response.
+// Rows can be decoded into structs with the column names in `overpass`
+// field tags, e.g.
+//
+//	type Cafe struct {
+//		ID   osm.NodeID `overpass:"::id"`
+//		Lat  float64    `overpass:"::lat"`
+//		Lon  float64    `overpass:"::lon"`
+//		Name string     `overpass:"name"`
+//	}
+type CSVScanner struct {
+	reader  *csv.Reader
+	columns []string
+	body    io.Closer
+
+	record []string
+	err    error
+}
+
+// CSVOption configures how the csv response is read.
+type CSVOption func(*CSVScanner)
+
+// Separator sets the column separator, the default is a tab
+// as for Overpass.
+func Separator(sep rune) CSVOption {
+	return func(s *CSVScanner) {
+		s.reader.Comma = sep
+	}
+}
+
+// Columns sets the column names of a response without a header line,
+// i.e. [out:csv(...; false)]. By default the first line is the header.
+func Columns(names ...string) CSVOption {
+	return func(s *CSVScanner) {
+		s.columns = names
+	}
+}
+
+// NewCSV returns a new scanner to read an [out:csv] response from r.
+func NewCSV(r io.Reader, opts ...CSVOption) *CSVScanner {
+	s := &CSVScanner{reader: csv.NewReader(r)}
+	s.reader.Comma = '\t'
+	s.reader.LazyQuotes = true
+	s.reader.ReuseRecord = true
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// QueryCSV runs QueryCSV using the DefaultDatasource.
+func QueryCSV(ctx context.Context, query string) (*CSVScanner, error) {
+	return DefaultDatasource.QueryCSV(ctx, query)
+}
+
+// QueryCSV runs a query with [out:csv(...)] output. The separator and
+// columns, if there is no header line, are taken from the query.
+// The scanner must be closed to release the connection.
+func (ds *Datasource) QueryCSV(ctx context.Context, query string) (*CSVScanner, error) {
+	opts, err := csvOptions(query)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := ds.post(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	s := NewCSV(resp.Body, opts...)
+	s.body = resp.Body
+	return s, nil
+}
+
+var csvSetting = regexp.MustCompile(`\[out:csv\((.*?)\)\]`)
+
+// csvOptions returns the options for the [out:csv(fields; header; sep)]
+// setting of the query.
+func csvOptions(query string) ([]CSVOption, error) {
+	m := csvSetting.FindStringSubmatch(query)
+	if m == nil {
+		return nil, errors.New("overpass: query does not have csv output")
+	}
+
+	parts := splitQuoted(m[1], ';')
+
+	var opts []CSVOption
+	if len(parts) > 1 && strings.TrimSpace(parts[1]) == "false" {
+		var names []string
+		for _, f := range splitQuoted(parts[0], ',') {
+			names = append(names, unquote(strings.TrimSpace(f)))
+		}
+		opts = append(opts, Columns(names...))
+	}
+
+	if len(parts) > 2 {
+		sep := []rune(unquote(strings.TrimSpace(parts[2])))
+		if len(sep) != 1 {
+			return nil, fmt.Errorf("overpass: unsupported csv separator: %s", parts[2])
+		}
+		opts = append(opts, Separator(sep[0]))
+	}
+
+	return opts, nil
+}
+
+// splitQuoted splits the string at the separator outside of quotes.
+func splitQuoted(s string, sep byte) []string {
+	var (
+		parts []string
+		quote byte
+		start int
+	)
+
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '\\':
+			i++
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == sep:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+
+	return append(parts, s[start:])
+}
+
+func unquote(s string) string {
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		s = s[1 : len(s)-1]
+	}
+
+	return strings.NewReplacer(`\t`, "\t", `\n`, "\n", `\"`, `"`, `\'`, "'", `\\`, `\`).Replace(s)
+}
+
+// Close causes all future calls to Scan to return false.
+// Closes the response body for scanners returned by QueryCSV.
+func (s *CSVScanner) Close() error {
+	if s.err == nil {
+		s.err = io.EOF
+	}
+
+	if s.body != nil {
+		return s.body.Close()
+	}
+
+	return nil
+}
+
+// Scan advances to the next row. It returns false at the end of the
+// response or an error, which will be returned by Err.
+func (s *CSVScanner) Scan() bool {
+	if s.err != nil {
+		return false
+	}
+
+	if s.columns == nil {
+		header, err := s.reader.Read()
+		if err != nil {
+			s.err = err
+			return false
+		}
+
+		// the header names the meta columns @id, @lat etc.
+		s.columns = make([]string, len(header))
+		for i, h := range header {
+			if strings.HasPrefix(h, "@") {
+				h = "::" + h[1:]
+			}
+			s.columns[i] = h
+		}
+	}
+
+	s.record, s.err = s.reader.Read()
+	return s.err == nil
+}
+
+// Columns returns the column names. The meta columns, like @id
+// in the header line, are named as in the query, e.g. ::id.
+func (s *CSVScanner) Columns() []string {
+	return s.columns
+}
+
+// Record returns the values of the current row. The slice is reused
+// by the next call to Scan.
+func (s *CSVScanner) Record() []string {
+	return s.record
+}
+
+// Get returns the value of the column in the current row.
+func (s *CSVScanner) Get(column string) string {
+	for i, c := range s.columns {
+		if c == column && i < len(s.record) {
+			return s.record[i]
+		}
+	}
+
+	return ""
+}
+
+// Decode sets the fields of the struct pointed to by v from the current
+// row. The column of each field is set with the `overpass` field tag,
+// fields without the tag are ignored. Strings, booleans and numbers,
+// including types like osm.NodeID, are supported. Empty values are
+// decoded as the zero value.
+func (s *CSVScanner) Decode(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+		return errors.New("overpass: decode requires a pointer to a struct")
+	}
+	rv = rv.Elem()
+
+	for _, f := range csvFields(rv.Type()) {
+		var value string
+		for i, c := range s.columns {
+			if c == f.column && i < len(s.record) {
+				value = s.record[i]
+				break
+			}
+		}
+
+		if err := setValue(rv.Field(f.index), value); err != nil {
+			return fmt.Errorf("overpass: column %s: %v", f.column, err)
+		}
+	}
+
+	return nil
+}
+
+// Err returns the first non-EOF error that was encountered by the scanner.
+func (s *CSVScanner) Err() error {
+	if s.err == io.EOF {
+		return nil
+	}
+
+	return s.err
+}
+
+type csvField struct {
+	index  int
+	column string
+}
+
+var csvFieldCache sync.Map // map[reflect.Type][]csvField
+
+func csvFields(t reflect.Type) []csvField {
+	if fs, ok := csvFieldCache.Load(t); ok {
+		return fs.([]csvField)
+	}
+
+	var fs []csvField
+	for i := 0; i < t.NumField(); i++ {
+		if c := t.Field(i).Tag.Get("overpass"); c != "" && c != "-" {
+			fs = append(fs, csvField{index: i, column: c})
+		}
+	}
+
+	csvFieldCache.Store(t, fs)
+	return fs
+}
+
+func setValue(v reflect.Value, s string) error {
+	if s == "" {
+		v.Set(reflect.Zero(v.Type()))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		i, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(i)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported type %v", v.Type())
+	}
+
+	return nil
+}
@@ -0,0 +1,163 @@
+package overpass
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/paulmach/osm"
+)
+
+type cafe struct {
+	ID      osm.NodeID `overpass:"::id"`
+	Type    osm.Type   `overpass:"::type"`
+	Lat     float64    `overpass:"::lat"`
+	Name    string     `overpass:"name"`
+	Seats   int        `overpass:"capacity"`
+	Outdoor bool       `overpass:"outdoor_seating_bool"`
+	Other   string
+}
+
+func TestCSVScanner(t *testing.T) {
+	data := "@id\t@type\t@lat\tname\tcapacity\toutdoor_seating_bool\n" +
+		"1\tnode\t52.5\tCafe \"A\"\t20\ttrue\n" +
+		"2\tnode\t52.6\t\t\t\n"
+
+	s := NewCSV(strings.NewReader(data))
+
+	var cafes []cafe
+	for s.Scan() {
+		c := cafe{Other: "keep"}
+		if err := s.Decode(&c); err != nil {
+			t.Fatalf("decode error: %v", err)
+		}
+		cafes = append(cafes, c)
+	}
+
+	if err := s.Err(); err != nil {
+		t.Fatalf("scan error: %v", err)
+	}
+
+	expected := []cafe{
+		{ID: 1, Type: osm.TypeNode, Lat: 52.5, Name: `Cafe "A"`, Seats: 20, Outdoor: true, Other: "keep"},
+		{ID: 2, Type: osm.TypeNode, Lat: 52.6, Other: "keep"},
+	}
+	if !reflect.DeepEqual(cafes, expected) {
+		t.Errorf("incorrect cafes: %+v", cafes)
+	}
+}
+
+func TestCSVScanner_header(t *testing.T) {
+	s := NewCSV(strings.NewReader("@id;name\n1;a\n2;b\n"), Separator(';'))
+
+	var names []string
+	for s.Scan() {
+		names = append(names, s.Get("name"))
+	}
+
+	if !reflect.DeepEqual(s.Columns(), []string{"::id", "name"}) {
+		t.Errorf("incorrect columns: %v", s.Columns())
+	}
+
+	if !reflect.DeepEqual(names, []string{"a", "b"}) {
+		t.Errorf("incorrect names: %v", names)
+	}
+}
+
+func TestCSVScanner_Decode_errors(t *testing.T) {
+	s := NewCSV(strings.NewReader("1\tnot a number\n"), Columns("::id", "capacity"))
+	if !s.Scan() {
+		t.Fatalf("should scan row: %v", s.Err())
+	}
+
+	if err := s.Decode(&cafe{}); err == nil {
+		t.Errorf("should return parse error")
+	}
+
+	if err := s.Decode(cafe{}); err == nil {
+		t.Errorf("should require a pointer")
+	}
+}
+
+func TestDatasource_QueryCSV(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/csv")
+		w.Write([]byte("1,Cafe A\n2,Cafe B\n"))
+	}))
+	defer ts.Close()
+
+	ds := &Datasource{BaseURL: ts.URL, Client: ts.Client()}
+	s, err := ds.QueryCSV(context.Background(),
+		`[out:csv(::id, "name"; false; ",")];node[amenity=cafe];out;`)
+	if err != nil {
+		t.Fatalf("query error: %v", err)
+	}
+	defer s.Close()
+
+	var cafes []cafe
+	for s.Scan() {
+		var c cafe
+		s.Decode(&c)
+		cafes = append(cafes, c)
+	}
+
+	if err := s.Err(); err != nil {
+		t.Fatalf("scan error: %v", err)
+	}
+
+	expected := []cafe{{ID: 1, Name: "Cafe A"}, {ID: 2, Name: "Cafe B"}}
+	if !reflect.DeepEqual(cafes, expected) {
+		t.Errorf("incorrect cafes: %+v", cafes)
+	}
+
+	if _, err := ds.QueryCSV(context.Background(), `[out:json];node;out;`); err == nil {
+		t.Errorf("should require csv output")
+	}
+}
+
+func TestCSVOptions(t *testing.T) {
+	cases := []struct {
+		name    string
+		query   string
+		columns []string
+		sep     rune
+	}{
+		{
+			name:  "defaults",
+			query: `[out:csv(::id, name)];`,
+			sep:   '\t',
+		},
+		{
+			name:    "no header",
+			query:   `[out:csv(::id, "addr:street", 'name;x'; false)];`,
+			columns: []string{"::id", "addr:street", "name;x"},
+			sep:     '\t',
+		},
+		{
+			name:  "separator",
+			query: `[out:csv(::id; true; "|")];`,
+			sep:   '|',
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			opts, err := csvOptions(tc.query)
+			if err != nil {
+				t.Fatalf("options error: %v", err)
+			}
+
+			s := NewCSV(strings.NewReader(""), opts...)
+			if !reflect.DeepEqual(s.columns, tc.columns) {
+				t.Errorf("incorrect columns: %q", s.columns)
+			}
+
+			if s.reader.Comma != tc.sep {
+				t.Errorf("incorrect separator: %q", s.reader.Comma)
+			}
+		})
+	}
+}
@@ -0,0 +1,200 @@
+// Package overpass provides a client for the Overpass API that streams
+// the elements of the responses.
+//
+// Unlike the osmapi package, the client of the DefaultDatasource uses an
+// osmhttp transport, it sets the UserAgent and waits and retries when the
+// server responds with 429 Too Many Requests. Use a client from
+// osmhttp.NewClient to identify your application instead.
+package overpass
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/paulmach/osm/osmhttp"
+)
+
+// BaseURL is the interpreter endpoint of the main Overpass instance.
+// See https://wiki.openstreetmap.org/wiki/Overpass_API#Public_Overpass_API_instances
+// for other public instances.
+const BaseURL = "https://overpass-api.de/api/interpreter"
+
+// UserAgent is set by the DefaultDatasource client on every request.
+// Use a client from osmhttp.NewClient to identify your application.
+const UserAgent = "github.com/paulmach/osm"
+
+// Datasource defines context about the http client to use to make requests.
+type Datasource struct {
+	BaseURL string
+	Client  *http.Client
+
+	// NominatimURL is the search endpoint used by ResolveArea.
+	// Defaults to the NominatimURL constant.
+	NominatimURL string
+}
+
+// DefaultDatasource is the Datasource used by package level convenience functions.
+var DefaultDatasource = &Datasource{
+	BaseURL: BaseURL,
+	Client: &http.Client{
+		// large queries can run for a long time, the query [timeout:...]
+		// setting is the better way to limit them.
+		Timeout:   15 * time.Minute,
+		Transport: defaultTransport(),
+	},
+}
+
+// defaultTransport sets the user agent and waits when rate limited. The user
+// agent is a valid constant, if the transport can not be created anyway the
+// http.DefaultTransport is used instead of failing at init.
+func defaultTransport() http.RoundTripper {
+	t, err := osmhttp.NewTransport(UserAgent)
+	if err != nil {
+		return http.DefaultTransport
+	}
+
+	return t
+}
+
+// NewDatasource creates a Datasource using the given client.
+func NewDatasource(client *http.Client) *Datasource {
+	return &Datasource{
+		Client: client,
+	}
+}
+
+// Query runs the query using the DefaultDatasource.
+func Query(ctx context.Context, query string) (*Scanner, error) {
+	return DefaultDatasource.Query(ctx, query)
+}
+
+// Query runs the Overpass QL, or xml, query and returns a scanner over the
+// elements of the response. The elements are decoded as they arrive so
+// large results do not need to fit in memory. The output format, json or
+// xml, is set in the query, e.g. [out:json]. The scanner must be closed
+// to release the connection.
+func (ds *Datasource) Query(ctx context.Context, query string) (*Scanner, error) {
+	resp, err := ds.post(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	var s *Scanner
+	ct := resp.Header.Get("Content-Type")
+	switch {
+	case strings.Contains(ct, "json"):
+		s = NewJSON(ctx, resp.Body)
+	case strings.Contains(ct, "xml"):
+		s = NewXML(ctx, resp.Body)
+	default:
+		resp.Body.Close()
+		return nil, fmt.Errorf("overpass: unsupported content type: %s", ct)
+	}
+
+	s.body = resp.Body
+	return s, nil
+}
+
+// post sends the query and checks the status code of the response.
+// The caller must close the response body.
+func (ds *Datasource) post(ctx context.Context, query string) (*http.Response, error) {
+	client := ds.Client
+	if client == nil {
+		client = DefaultDatasource.Client
+	}
+
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	u := ds.baseURL()
+	req, err := http.NewRequest("POST", u, strings.NewReader(url.Values{"data": {query}}.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		// the body is an html page with the reason, e.g. the syntax error
+		data, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 4096))
+		resp.Body.Close()
+
+		return nil, &UnexpectedStatusCodeError{
+			Code:    resp.StatusCode,
+			URL:     u,
+			Message: errorMessage(string(data)),
+		}
+	}
+
+	return resp, nil
+}
+
+func (ds *Datasource) baseURL() string {
+	if ds.BaseURL != "" {
+		return ds.BaseURL
+	}
+
+	return BaseURL
+}
+
+// errorMessage extracts the error lines from the html error page.
+func errorMessage(body string) string {
+	var msgs []string
+	for _, line := range strings.Split(body, "\n") {
+		if i := strings.Index(line, "<strong"); i != -1 && strings.Contains(line, "Error") {
+			line = line[i:]
+			for strings.Contains(line, "<") {
+				s := strings.Index(line, "<")
+				e := strings.Index(line[s:], ">")
+				if e == -1 {
+					break
+				}
+				line = line[:s] + line[s+e+1:]
+			}
+			msgs = append(msgs, strings.TrimSpace(line))
+		}
+	}
+
+	return strings.Join(msgs, "; ")
+}
+
+// UnexpectedStatusCodeError is returned for a non 200 status code.
+// The server responds with 400 for query errors, 429 if there are too
+// many queries from the same ip and 504 if the server is overloaded.
+type UnexpectedStatusCodeError struct {
+	Code    int
+	URL     string
+	Message string
+}
+
+// Error returns an error message with some information.
+func (e *UnexpectedStatusCodeError) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("overpass: unexpected status code of %d from url %s: %s", e.Code, e.URL, e.Message)
+	}
+
+	return fmt.Sprintf("overpass: unexpected status code of %d from url %s", e.Code, e.URL)
+}
+
+// RuntimeError is returned when the query fails after the response
+// started, e.g. it timed out or ran out of memory. The response is
+// then incomplete, the server reports the problem in a remark.
+type RuntimeError struct {
+	Remark string
+}
+
+// Error returns the remark from the server.
+func (e *RuntimeError) Error() string {
+	return "overpass: " + e.Remark
+}
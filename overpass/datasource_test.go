@@ -0,0 +1,73 @@
+package overpass
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/paulmach/osm/osmhttp"
+)
+
+func TestDatasource_Query(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Errorf("should post the query: %v", r.Method)
+		}
+
+		switch r.FormValue("data") {
+		case "[out:json];node(1);out;":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(jsonResponse))
+		case "node(1);out;":
+			w.Header().Set("Content-Type", "application/osm3s+xml")
+			w.Write([]byte(xmlResponse))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`<p><strong style="color:#FF0000">Error</strong>: line 1: parse error: ';' expected </p>`))
+		}
+	}))
+	defer ts.Close()
+
+	ds := &Datasource{BaseURL: ts.URL, Client: ts.Client()}
+	for _, q := range []string{"[out:json];node(1);out;", "node(1);out;"} {
+		s, err := ds.Query(context.Background(), q)
+		if err != nil {
+			t.Fatalf("query error: %v", err)
+		}
+
+		count := 0
+		for s.Scan() {
+			count++
+		}
+
+		if err := s.Err(); err != nil {
+			t.Errorf("scan error: %v", err)
+		}
+		s.Close()
+
+		if count != 3 {
+			t.Errorf("incorrect number of elements: %v", count)
+		}
+	}
+
+	_, err := ds.Query(context.Background(), "node(1) out;")
+	e, ok := err.(*UnexpectedStatusCodeError)
+	if !ok {
+		t.Fatalf("incorrect error: %v", err)
+	}
+
+	if e.Code != http.StatusBadRequest {
+		t.Errorf("incorrect code: %v", e.Code)
+	}
+
+	if e.Message != "Error: line 1: parse error: ';' expected" {
+		t.Errorf("incorrect message: %v", e.Message)
+	}
+}
+
+func TestDefaultDatasource(t *testing.T) {
+	if _, ok := DefaultDatasource.Client.Transport.(*osmhttp.Transport); !ok {
+		t.Errorf("should use an osmhttp transport: %T", DefaultDatasource.Client.Transport)
+	}
+}
@@ -0,0 +1,310 @@
+package overpass
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/paulmach/osm"
+)
+
+var _ osm.Scanner = &Scanner{}
+
+// Scanner reads the elements of an Overpass response as they arrive.
+// Only one element is decoded at a time so memory use does not depend on
+// the size of the response. Successive calls to the Scan method will step
+// through the elements. Overpass areas and other derived elements are skipped.
+//
+// A runtime error remark, e.g. the query timed out, is returned by Err
+// as a *RuntimeError since the response is incomplete.
+//
+// The Scanner API is based on bufio.Scanner
+// https://golang.org/pkg/bufio/#Scanner
+type Scanner struct {
+	ctx    context.Context
+	done   context.CancelFunc
+	closed bool
+	body   io.Closer
+
+	json  *json.Decoder
+	state int
+
+	xml *xml.Decoder
+
+	next osm.Object
+	err  error
+}
+
+// states of the json decoding
+const (
+	jsonStart = iota
+	jsonElements
+	jsonAfterElements
+)
+
+// NewJSON returns a new Scanner to read an [out:json] response from r.
+func NewJSON(ctx context.Context, r io.Reader) *Scanner {
+	s := newScanner(ctx)
+	s.json = json.NewDecoder(r)
+	return s
+}
+
+// NewXML returns a new Scanner to read an [out:xml] response from r.
+func NewXML(ctx context.Context, r io.Reader) *Scanner {
+	s := newScanner(ctx)
+	s.xml = xml.NewDecoder(r)
+	return s
+}
+
+func newScanner(ctx context.Context) *Scanner {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	s := &Scanner{}
+	s.ctx, s.done = context.WithCancel(ctx)
+	return s
+}
+
+// Close causes all future calls to Scan to return false.
+// Closes the response body for scanners returned by Query.
+func (s *Scanner) Close() error {
+	s.closed = true
+	s.done()
+
+	if s.body != nil {
+		return s.body.Close()
+	}
+
+	return nil
+}
+
+// Scan advances the Scanner to the next element, which will then be available
+// through the Object method. It returns false when the scan stops, either
+// by reaching the end of the input, an io error, a decoding error, a runtime
+// error remark or the context being cancelled. After Scan returns false,
+// the Err method will return any error that occurred during scanning,
+// except that if it was io.EOF, Err will return nil.
+func (s *Scanner) Scan() bool {
+	if s.err != nil || s.closed {
+		return false
+	}
+
+	s.next = nil
+	for s.next == nil {
+		if s.ctx.Err() != nil {
+			return false
+		}
+
+		if s.json != nil {
+			s.err = s.scanJSON()
+		} else {
+			s.err = s.scanXML()
+		}
+
+		if s.err != nil {
+			s.next = nil
+			return false
+		}
+	}
+
+	return true
+}
+
+// scanJSON decodes the next object, or sets nothing for skipped elements.
+func (s *Scanner) scanJSON() error {
+	switch s.state {
+	case jsonStart:
+		if err := expectDelim(s.json, '{'); err != nil {
+			return err
+		}
+		return s.jsonKeys()
+	case jsonElements:
+		if !s.json.More() {
+			if err := expectDelim(s.json, ']'); err != nil {
+				return err
+			}
+			s.state = jsonAfterElements
+			return s.jsonKeys()
+		}
+
+		var raw json.RawMessage
+		if err := s.json.Decode(&raw); err != nil {
+			return err
+		}
+
+		return s.decodeJSONElement(raw)
+	}
+
+	return io.EOF
+}
+
+// jsonKeys reads the top level keys until the start of the elements array
+// or the end of the response, checking the remarks for errors.
+func (s *Scanner) jsonKeys() error {
+	for s.json.More() {
+		t, err := s.json.Token()
+		if err != nil {
+			return err
+		}
+
+		switch t {
+		case "elements":
+			if s.state != jsonStart {
+				return errors.New("overpass: multiple elements arrays")
+			}
+
+			if err := expectDelim(s.json, '['); err != nil {
+				return err
+			}
+			s.state = jsonElements
+			return nil
+		case "remark":
+			var remark string
+			if err := s.json.Decode(&remark); err != nil {
+				return err
+			}
+
+			if err := remarkError(remark); err != nil {
+				return err
+			}
+		default:
+			// version, generator, osm3s etc.
+			var skip json.RawMessage
+			if err := s.json.Decode(&skip); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := expectDelim(s.json, '}'); err != nil {
+		return err
+	}
+	s.state = jsonAfterElements
+
+	return io.EOF
+}
+
+func (s *Scanner) decodeJSONElement(raw json.RawMessage) error {
+	t := struct {
+		Type string `json:"type"`
+	}{}
+	if err := json.Unmarshal(raw, &t); err != nil {
+		return err
+	}
+
+	var obj osm.Object
+	switch t.Type {
+	case "node":
+		obj = &osm.Node{}
+	case "way":
+		obj = &osm.Way{}
+	case "relation":
+		obj = &osm.Relation{}
+	default:
+		// areas, counts etc.
+		return nil
+	}
+
+	if err := json.Unmarshal(raw, obj); err != nil {
+		return err
+	}
+
+	s.next = obj
+	return nil
+}
+
+func expectDelim(dec *json.Decoder, d json.Delim) error {
+	t, err := dec.Token()
+	if err != nil {
+		if err == io.EOF {
+			return io.ErrUnexpectedEOF
+		}
+		return err
+	}
+
+	if t != d {
+		return errors.New("overpass: invalid json response, expected " + d.String())
+	}
+
+	return nil
+}
+
+// scanXML decodes the next object, or sets nothing for skipped elements.
+func (s *Scanner) scanXML() error {
+	t, err := s.xml.Token()
+	if err != nil {
+		return err
+	}
+
+	se, ok := t.(xml.StartElement)
+	if !ok {
+		return nil
+	}
+
+	switch se.Name.Local {
+	case "node":
+		n := &osm.Node{}
+		err = s.xml.DecodeElement(n, &se)
+		s.next = n
+	case "way":
+		w := &osm.Way{}
+		err = s.xml.DecodeElement(w, &se)
+		s.next = w
+	case "relation":
+		r := &osm.Relation{}
+		err = s.xml.DecodeElement(r, &se)
+		s.next = r
+	case "remark":
+		var remark string
+		if err := s.xml.DecodeElement(&remark, &se); err != nil {
+			return err
+		}
+		return remarkError(remark)
+	case "area":
+		return s.xml.Skip()
+	}
+
+	return err
+}
+
+// remarkError returns an error if the remark is about a runtime error.
+// Other remarks, e.g. runtime remarks about the query, are ignored.
+func remarkError(remark string) error {
+	remark = strings.TrimSpace(remark)
+	if strings.HasPrefix(remark, "runtime error") {
+		return &RuntimeError{Remark: remark}
+	}
+
+	return nil
+}
+
+// Object returns the most recent element generated by a call to Scan
+// as a new osm.Object. This interface is implemented by:
+//
+//	*osm.Node
+//	*osm.Way
+//	*osm.Relation
+func (s *Scanner) Object() osm.Object {
+	return s.next
+}
+
+// Err returns the first non-EOF error that was encountered by the Scanner.
+func (s *Scanner) Err() error {
+	if s.err == io.EOF {
+		return nil
+	}
+
+	if s.err != nil {
+		return s.err
+	}
+
+	if s.closed {
+		return osm.ErrScannerClosed
+	}
+
+	return s.ctx.Err()
+}
@@ -0,0 +1,163 @@
+package overpass
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/paulmach/osm"
+)
+
+const jsonResponse = `{
+  "version": 0.6,
+  "generator": "Overpass API 0.7.56",
+  "osm3s": {"timestamp_osm_base": "2018-01-01T00:00:00Z"},
+  "elements": [
+    {"type": "node", "id": 1, "lat": 1.5, "lon": 2.5, "tags": {"amenity": "cafe"}},
+    {"type": "area", "id": 3600000001},
+    {"type": "way", "id": 2, "nodes": [1, 3]},
+    {"type": "relation", "id": 3, "members": [{"type": "way", "ref": 2, "role": "outer"}]}
+  ]
+}`
+
+const xmlResponse = `<?xml version="1.0" encoding="UTF-8"?>
+<osm version="0.6" generator="Overpass API 0.7.56">
+<note>The data included in this document is from www.openstreetmap.org.</note>
+<meta osm_base="2018-01-01T00:00:00Z"/>
+  <node id="1" lat="1.5" lon="2.5"><tag k="amenity" v="cafe"/></node>
+  <area id="3600000001"><tag k="name" v="area"/></area>
+  <way id="2"><nd ref="1"/><nd ref="3"/></way>
+  <relation id="3"><member type="way" ref="2" role="outer"/></relation>
+</osm>`
+
+func TestScanner(t *testing.T) {
+	cases := []struct {
+		name    string
+		scanner *Scanner
+	}{
+		{
+			name:    "json",
+			scanner: NewJSON(context.Background(), strings.NewReader(jsonResponse)),
+		},
+		{
+			name:    "xml",
+			scanner: NewXML(context.Background(), strings.NewReader(xmlResponse)),
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := tc.scanner
+			defer s.Close()
+
+			var ids osm.FeatureIDs
+			var node *osm.Node
+			for s.Scan() {
+				e := s.Object().(osm.Element)
+				ids = append(ids, e.FeatureID())
+
+				if n, ok := e.(*osm.Node); ok {
+					node = n
+				}
+			}
+
+			if err := s.Err(); err != nil {
+				t.Fatalf("scan error: %v", err)
+			}
+
+			expected := osm.FeatureIDs{
+				osm.NodeID(1).FeatureID(),
+				osm.WayID(2).FeatureID(),
+				osm.RelationID(3).FeatureID(),
+			}
+			if !reflect.DeepEqual(ids, expected) {
+				t.Errorf("incorrect ids: %v", ids)
+			}
+
+			if node.Lat != 1.5 || node.Lon != 2.5 || node.Tags.Find("amenity") != "cafe" {
+				t.Errorf("incorrect node: %+v", node)
+			}
+		})
+	}
+}
+
+func TestScanner_runtimeError(t *testing.T) {
+	cases := []struct {
+		name    string
+		scanner *Scanner
+	}{
+		{
+			name: "json",
+			scanner: NewJSON(context.Background(), strings.NewReader(`{
+				"elements": [{"type": "node", "id": 1}],
+				"remark": "runtime error: Query timed out in \"query\" at line 1 after 26 seconds."
+			}`)),
+		},
+		{
+			name: "xml",
+			scanner: NewXML(context.Background(), strings.NewReader(`<osm>
+				<node id="1"/>
+				<remark> runtime error: Query timed out in "query" at line 1 after 26 seconds. </remark>
+			</osm>`)),
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := tc.scanner
+
+			count := 0
+			for s.Scan() {
+				count++
+			}
+
+			if count != 1 {
+				t.Errorf("should scan elements before the remark: %v", count)
+			}
+
+			if _, ok := s.Err().(*RuntimeError); !ok {
+				t.Errorf("incorrect error: %v", s.Err())
+			}
+		})
+	}
+}
+
+func TestScanner_remark(t *testing.T) {
+	s := NewJSON(context.Background(), strings.NewReader(`{
+		"remark": "runtime remark: Timeout is 180 and maxsize is 536870912.",
+		"elements": []
+	}`))
+
+	if s.Scan() {
+		t.Errorf("should not scan anything")
+	}
+
+	if err := s.Err(); err != nil {
+		t.Errorf("should ignore other remarks: %v", err)
+	}
+}
+
+func TestScanner_invalid(t *testing.T) {
+	s := NewJSON(context.Background(), strings.NewReader(`[]`))
+	if s.Scan() {
+		t.Errorf("should not scan anything")
+	}
+
+	if s.Err() == nil {
+		t.Errorf("should return an error")
+	}
+}
+
+func TestScanner_Close(t *testing.T) {
+	s := NewJSON(context.Background(), strings.NewReader(jsonResponse))
+	s.Close()
+
+	if s.Scan() {
+		t.Errorf("should not scan after close")
+	}
+
+	if s.Err() != osm.ErrScannerClosed {
+		t.Errorf("incorrect error: %v", s.Err())
+	}
+}
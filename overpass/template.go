@@ -0,0 +1,119 @@
+package overpass
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/paulmach/osm"
+)
+
+// Template replaces the {{name}} placeholders in the query, as used by
+// overpass turbo, with the values, formatted and escaped by type:
+//
+//	*osm.Bounds  the south,west,north,east bbox, e.g. node({{bbox}})
+//	AreaID       an area filter, e.g. node({{area}}) becomes node(area:3600062422)
+//	osm.Tag      a tag filter, ["key"="value"], or ["key"] if the value is empty
+//	osm.Tags     the tag filters of all the tags
+//	string       a quoted and escaped string
+//	int, float   the number
+//
+// Values are never interpolated unescaped so user input can not change
+// the structure of the query. Missing values and unsupported types
+// return an error.
+func Template(query string, values map[string]interface{}) (string, error) {
+	buf := &bytes.Buffer{}
+
+	for {
+		i := strings.Index(query, "{{")
+		if i == -1 {
+			break
+		}
+
+		j := strings.Index(query[i:], "}}")
+		if j == -1 {
+			return "", fmt.Errorf("overpass: unclosed placeholder at %d", i)
+		}
+
+		name := strings.TrimSpace(query[i+2 : i+j])
+		v, ok := values[name]
+		if !ok {
+			return "", fmt.Errorf("overpass: missing value for %s", name)
+		}
+
+		buf.WriteString(query[:i])
+		if err := writeValue(buf, v); err != nil {
+			return "", fmt.Errorf("overpass: %s: %v", name, err)
+		}
+
+		query = query[i+j+2:]
+	}
+
+	buf.WriteString(query)
+	return buf.String(), nil
+}
+
+func writeValue(buf *bytes.Buffer, v interface{}) error {
+	switch v := v.(type) {
+	case *osm.Bounds:
+		writeBounds(buf, v)
+	case osm.Bounds:
+		writeBounds(buf, &v)
+	case AreaID:
+		buf.WriteString("area:")
+		buf.WriteString(strconv.FormatInt(int64(v), 10))
+	case osm.Tag:
+		writeTag(buf, v)
+	case osm.Tags:
+		for _, t := range v {
+			writeTag(buf, t)
+		}
+	case string:
+		writeString(buf, v)
+	case int:
+		buf.WriteString(strconv.Itoa(v))
+	case int64:
+		buf.WriteString(strconv.FormatInt(v, 10))
+	case float64:
+		buf.WriteString(strconv.FormatFloat(v, 'f', -1, 64))
+	default:
+		return fmt.Errorf("unsupported type %T", v)
+	}
+
+	return nil
+}
+
+func writeBounds(buf *bytes.Buffer, b *osm.Bounds) {
+	for i, f := range []float64{b.MinLat, b.MinLon, b.MaxLat, b.MaxLon} {
+		if i != 0 {
+			buf.WriteByte(',')
+		}
+		buf.WriteString(strconv.FormatFloat(f, 'f', -1, 64))
+	}
+}
+
+func writeTag(buf *bytes.Buffer, t osm.Tag) {
+	buf.WriteByte('[')
+	writeString(buf, t.Key)
+	if t.Value != "" {
+		buf.WriteByte('=')
+		writeString(buf, t.Value)
+	}
+	buf.WriteByte(']')
+}
+
+var stringEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	`"`, `\"`,
+	"\n", `\n`,
+	"\t", `\t`,
+)
+
+// writeString writes the string in double quotes with the escape
+// sequences of the query language.
+func writeString(buf *bytes.Buffer, s string) {
+	buf.WriteByte('"')
+	stringEscaper.WriteString(buf, s)
+	buf.WriteByte('"')
+}
@@ -0,0 +1,70 @@
+package overpass
+
+import (
+	"testing"
+
+	"github.com/paulmach/osm"
+)
+
+func TestTemplate(t *testing.T) {
+	q, err := Template(`[out:json];node{{ tags }}{{name}}({{bbox}});way({{area}})[level={{level}}];out;`,
+		map[string]interface{}{
+			"bbox":  &osm.Bounds{MinLat: 52.5, MinLon: 13.25, MaxLat: 52.6, MaxLon: 13.5},
+			"area":  AreaID(3600062422),
+			"tags":  osm.Tags{{Key: "amenity", Value: "cafe"}, {Key: "name"}},
+			"name":  osm.Tag{Key: "name", Value: `Joe's "Cafe"];out;`},
+			"level": 1,
+		})
+	if err != nil {
+		t.Fatalf("template error: %v", err)
+	}
+
+	expected := `[out:json];node["amenity"="cafe"]["name"]["name"="Joe's \"Cafe\"];out;"]` +
+		`(52.5,13.25,52.6,13.5);way(area:3600062422)[level=1];out;`
+	if q != expected {
+		t.Errorf("incorrect query:\n%s\n%s", q, expected)
+	}
+}
+
+func TestTemplate_errors(t *testing.T) {
+	cases := []struct {
+		name   string
+		query  string
+		values map[string]interface{}
+	}{
+		{
+			name:  "missing value",
+			query: `node({{bbox}});`,
+		},
+		{
+			name:  "unclosed",
+			query: `node({{bbox);`,
+		},
+		{
+			name:   "unsupported type",
+			query:  `node({{bbox}});`,
+			values: map[string]interface{}{"bbox": []float64{1, 2, 3, 4}},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := Template(tc.query, tc.values); err == nil {
+				t.Errorf("should return error")
+			}
+		})
+	}
+}
+
+func TestTemplate_string(t *testing.T) {
+	q, err := Template(`node[name={{name}}];`, map[string]interface{}{
+		"name": "a\\b\"c\nd",
+	})
+	if err != nil {
+		t.Fatalf("template error: %v", err)
+	}
+
+	if q != `node[name="a\\b\"c\nd"];` {
+		t.Errorf("incorrect query: %v", q)
+	}
+}
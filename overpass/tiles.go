@@ -0,0 +1,134 @@
+package overpass
+
+import (
+	"context"
+	"errors"
+	"math"
+
+	"github.com/paulmach/osm"
+)
+
+// SplitBounds splits the bounds into a grid of tiles at most size degrees
+// wide and high. Large area queries can time out or run out of memory on
+// the server, running them per tile with QueryTiles avoids that.
+func SplitBounds(b *osm.Bounds, size float64) ([]*osm.Bounds, error) {
+	if size <= 0 {
+		return nil, errors.New("overpass: tile size must be positive")
+	}
+
+	if b.MinLat > b.MaxLat || b.MinLon > b.MaxLon {
+		return nil, errors.New("overpass: invalid bounds")
+	}
+
+	rows := int(math.Ceil((b.MaxLat - b.MinLat) / size))
+	cols := int(math.Ceil((b.MaxLon - b.MinLon) / size))
+	if rows == 0 {
+		rows = 1
+	}
+	if cols == 0 {
+		cols = 1
+	}
+
+	tiles := make([]*osm.Bounds, 0, rows*cols)
+	for r := 0; r < rows; r++ {
+		for c := 0; c < cols; c++ {
+			// the last row and column end at the edge of the bounds
+			// so rounding errors don't leave gaps.
+			t := &osm.Bounds{
+				MinLat: b.MinLat + float64(r)*size,
+				MaxLat: b.MaxLat,
+				MinLon: b.MinLon + float64(c)*size,
+				MaxLon: b.MaxLon,
+			}
+
+			if r < rows-1 {
+				t.MaxLat = b.MinLat + float64(r+1)*size
+			}
+
+			if c < cols-1 {
+				t.MaxLon = b.MinLon + float64(c+1)*size
+			}
+
+			tiles = append(tiles, t)
+		}
+	}
+
+	return tiles, nil
+}
+
+// QueryTiles runs QueryTiles using the DefaultDatasource.
+func QueryTiles(ctx context.Context, tiles []*osm.Bounds, query func(*osm.Bounds) string) (*osm.OSM, error) {
+	return DefaultDatasource.QueryTiles(ctx, tiles, query)
+}
+
+// QueryTiles runs the query returned for each tile, one at a time, and
+// merges the results. Elements in more than one tile, e.g. ways crossing
+// the tile edges and their nodes, are only included once, keeping the
+// highest version. Any error stops the remaining queries.
+func (ds *Datasource) QueryTiles(
+	ctx context.Context,
+	tiles []*osm.Bounds,
+	query func(*osm.Bounds) string,
+) (*osm.OSM, error) {
+	m := &merger{
+		result: &osm.OSM{},
+		index:  make(map[osm.FeatureID]int),
+	}
+
+	for _, t := range tiles {
+		if err := ds.queryTile(ctx, query(t), m); err != nil {
+			return nil, err
+		}
+	}
+
+	return m.result, nil
+}
+
+func (ds *Datasource) queryTile(ctx context.Context, query string, m *merger) error {
+	s, err := ds.Query(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	for s.Scan() {
+		m.add(s.Object().(osm.Element))
+	}
+
+	return s.Err()
+}
+
+// merger dedupes the elements from multiple queries.
+type merger struct {
+	result *osm.OSM
+	index  map[osm.FeatureID]int
+}
+
+func (m *merger) add(e osm.Element) {
+	fid := e.FeatureID()
+	i, ok := m.index[fid]
+
+	switch e := e.(type) {
+	case *osm.Node:
+		if !ok {
+			m.index[fid] = len(m.result.Nodes)
+			m.result.Nodes = append(m.result.Nodes, e)
+		} else if e.Version > m.result.Nodes[i].Version {
+			m.result.Nodes[i] = e
+		}
+	case *osm.Way:
+		if !ok {
+			m.index[fid] = len(m.result.Ways)
+			m.result.Ways = append(m.result.Ways, e)
+		} else if e.Version > m.result.Ways[i].Version {
+			m.result.Ways[i] = e
+		}
+	case *osm.Relation:
+		if !ok {
+			m.index[fid] = len(m.result.Relations)
+			m.result.Relations = append(m.result.Relations, e)
+		} else if e.Version > m.result.Relations[i].Version {
+			m.result.Relations[i] = e
+		}
+	}
+}
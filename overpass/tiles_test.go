@@ -0,0 +1,78 @@
+package overpass
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/paulmach/osm"
+)
+
+func TestSplitBounds(t *testing.T) {
+	b := &osm.Bounds{MinLat: 0, MaxLat: 1, MinLon: 0, MaxLon: 2.5}
+
+	tiles, err := SplitBounds(b, 1)
+	if err != nil {
+		t.Fatalf("split error: %v", err)
+	}
+
+	expected := []*osm.Bounds{
+		{MinLat: 0, MaxLat: 1, MinLon: 0, MaxLon: 1},
+		{MinLat: 0, MaxLat: 1, MinLon: 1, MaxLon: 2},
+		{MinLat: 0, MaxLat: 1, MinLon: 2, MaxLon: 2.5},
+	}
+	if !reflect.DeepEqual(tiles, expected) {
+		t.Errorf("incorrect tiles: %v", tiles)
+	}
+
+	if _, err := SplitBounds(b, 0); err == nil {
+		t.Errorf("should error for zero size")
+	}
+
+	if _, err := SplitBounds(&osm.Bounds{MinLat: 1}, 1); err == nil {
+		t.Errorf("should error for invalid bounds")
+	}
+}
+
+func TestDatasource_QueryTiles(t *testing.T) {
+	// each tile returns its own node and the shared node 1,
+	// the second tile has a newer version of the shared node.
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var tile int
+		fmt.Sscanf(r.FormValue("data"), "tile %d", &tile)
+
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"elements": [
+			{"type": "node", "id": 1, "version": %d},
+			{"type": "node", "id": %d, "version": 1},
+			{"type": "way", "id": 1, "version": 1, "nodes": [1]}
+		]}`, tile, 10+tile)
+	}))
+	defer ts.Close()
+
+	tiles, _ := SplitBounds(&osm.Bounds{MinLat: 0, MaxLat: 1, MinLon: 0, MaxLon: 2}, 1)
+	query := func(b *osm.Bounds) string {
+		return fmt.Sprintf("tile %d", int(b.MinLon)+1)
+	}
+
+	ds := &Datasource{BaseURL: ts.URL, Client: ts.Client()}
+	o, err := ds.QueryTiles(context.Background(), tiles, query)
+	if err != nil {
+		t.Fatalf("query error: %v", err)
+	}
+
+	if !reflect.DeepEqual(o.Nodes.IDs(), []osm.NodeID{1, 11, 12}) {
+		t.Errorf("incorrect nodes: %v", o.Nodes.IDs())
+	}
+
+	if o.Nodes[0].Version != 2 {
+		t.Errorf("should keep the highest version: %v", o.Nodes[0].Version)
+	}
+
+	if len(o.Ways) != 1 {
+		t.Errorf("should dedupe ways: %v", len(o.Ways))
+	}
+}
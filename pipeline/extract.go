@@ -0,0 +1,118 @@
+package pipeline
+
+import (
+	"context"
+
+	"github.com/paulmach/osm"
+	"github.com/paulmach/osm/idset"
+)
+
+// An OpenFunc returns a new scanner over the input, it is called once
+// for every pass. The scanners are closed after each pass.
+type OpenFunc func() (osm.Scanner, error)
+
+// An ExtractOption is a setting for Extract.
+type ExtractOption func(*extract)
+
+type extract struct {
+	nodes     idset.IDSet
+	ways      idset.IDSet
+	relations idset.IDSet
+}
+
+// ApproximateNodes marks the needed nodes in a bloom filter sized for n
+// nodes with a false positive rate p. It uses a fixed amount of memory,
+// about 1.2 bytes per node for 1%, but a fraction p of the other nodes
+// will also be written. The default is an exact bitmap.
+func ApproximateNodes(n int, p float64) ExtractOption {
+	return func(e *extract) {
+		e.nodes = idset.NewBloom(n, p)
+	}
+}
+
+// Extract writes the objects the keep function returns true for and the
+// elements they reference, so the output is complete. It reads the input
+// twice: the first pass marks the kept elements, the nodes of kept ways
+// and the node and way members of kept relations, the second pass writes
+// the marked elements, through a pipeline, in the input order. Ways that
+// are only members of kept relations are written without their nodes
+// being added. The ids are marked in memory efficient sets so this works
+// for planet sized inputs.
+func Extract(ctx context.Context, open OpenFunc, keep FilterFunc, w Writer, opts ...ExtractOption) error {
+	e := &extract{
+		nodes:     idset.NewBitmap(),
+		ways:      idset.NewBitmap(),
+		relations: idset.NewBitmap(),
+	}
+
+	for _, opt := range opts {
+		opt(e)
+	}
+
+	if err := e.mark(ctx, open, keep); err != nil {
+		return err
+	}
+
+	scanner, err := open()
+	if err != nil {
+		return err
+	}
+	defer scanner.Close()
+
+	return New(ctx, scanner).
+		Filter(func(o osm.Object) bool {
+			switch o := o.(type) {
+			case *osm.Node:
+				return e.nodes.Contains(int64(o.ID))
+			case *osm.Way:
+				return e.ways.Contains(int64(o.ID))
+			case *osm.Relation:
+				return e.relations.Contains(int64(o.ID))
+			}
+
+			return keep(o)
+		}).
+		WriteTo(w)
+}
+
+// mark is the first pass, finding the needed elements.
+func (e *extract) mark(ctx context.Context, open OpenFunc, keep FilterFunc) error {
+	scanner, err := open()
+	if err != nil {
+		return err
+	}
+	defer scanner.Close()
+
+	for scanner.Scan() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		o := scanner.Object()
+		if !keep(o) {
+			continue
+		}
+
+		switch o := o.(type) {
+		case *osm.Node:
+			e.nodes.Add(int64(o.ID))
+		case *osm.Way:
+			e.ways.Add(int64(o.ID))
+			for _, wn := range o.Nodes {
+				e.nodes.Add(int64(wn.ID))
+			}
+		case *osm.Relation:
+			e.relations.Add(int64(o.ID))
+			for _, m := range o.Members {
+				switch m.Type {
+				case osm.TypeNode:
+					e.nodes.Add(m.Ref)
+				case osm.TypeWay:
+					e.ways.Add(m.Ref)
+				}
+			}
+		}
+	}
+
+	return scanner.Err()
+}
@@ -0,0 +1,98 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/paulmach/osm"
+	"github.com/paulmach/osm/osmtest"
+)
+
+func TestExtract(t *testing.T) {
+	objects := osm.Objects{
+		&osm.Node{ID: 1},
+		&osm.Node{ID: 2},
+		&osm.Node{ID: 3, Tags: osm.Tags{{Key: "amenity", Value: "cafe"}}},
+		&osm.Node{ID: 4},
+		&osm.Node{ID: 5},
+		&osm.Way{ID: 10, Nodes: osm.WayNodes{{ID: 1}, {ID: 2}}, Tags: osm.Tags{{Key: "highway", Value: "primary"}}},
+		&osm.Way{ID: 11, Nodes: osm.WayNodes{{ID: 4}, {ID: 5}}},
+		&osm.Way{ID: 12, Nodes: osm.WayNodes{{ID: 5}, {ID: 1}}},
+		&osm.Relation{ID: 20, Members: osm.Members{{Type: osm.TypeWay, Ref: 11}}, Tags: osm.Tags{{Key: "route", Value: "bus"}}},
+		&osm.Relation{ID: 21, Members: osm.Members{{Type: osm.TypeWay, Ref: 12}}},
+	}
+
+	keep := func(o osm.Object) bool {
+		e, ok := o.(osm.Element)
+		if !ok {
+			return false
+		}
+
+		tags := e.TagMap()
+		return tags["amenity"] != "" || tags["highway"] != "" || tags["route"] != ""
+	}
+
+	cases := []struct {
+		name string
+		opts []ExtractOption
+	}{
+		{name: "bitmap"},
+		{name: "bloom", opts: []ExtractOption{ApproximateNodes(1000, 0.0001)}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			passes := 0
+			open := func() (osm.Scanner, error) {
+				passes++
+				return osmtest.NewScanner(objects), nil
+			}
+
+			var result osm.ObjectIDs
+			err := Extract(context.Background(), open, keep, WriterFunc(func(o osm.Object) error {
+				result = append(result, o.ObjectID())
+				return nil
+			}), tc.opts...)
+			if err != nil {
+				t.Fatalf("extract error: %v", err)
+			}
+
+			expected := osm.ObjectIDs{
+				osm.NodeID(1).ObjectID(0),
+				osm.NodeID(2).ObjectID(0),
+				osm.NodeID(3).ObjectID(0),
+				osm.WayID(10).ObjectID(0),
+				osm.WayID(11).ObjectID(0),
+				osm.RelationID(20).ObjectID(0),
+			}
+			if !reflect.DeepEqual(result, expected) {
+				t.Errorf("incorrect objects: %v", result)
+			}
+
+			if passes != 2 {
+				t.Errorf("incorrect number of passes: %v", passes)
+			}
+		})
+	}
+}
+
+func TestExtract_errors(t *testing.T) {
+	someErr := errors.New("some error")
+	keep := func(osm.Object) bool { return true }
+	discard := WriterFunc(func(osm.Object) error { return nil })
+
+	open := func() (osm.Scanner, error) { return nil, someErr }
+	if err := Extract(context.Background(), open, keep, discard); err != someErr {
+		t.Errorf("should return open error: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	open = func() (osm.Scanner, error) { return testScanner(3), nil }
+	if err := Extract(ctx, open, keep, discard); err != context.Canceled {
+		t.Errorf("should return context error: %v", err)
+	}
+}
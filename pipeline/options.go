@@ -0,0 +1,33 @@
+package pipeline
+
+import (
+	"errors"
+
+	"github.com/paulmach/osm"
+)
+
+// An Option is a setting for creating the pipeline.
+type Option func(*Pipeline) error
+
+// BufferSize sets the size of the channels between the stages.
+// The default is 100.
+func BufferSize(n int) Option {
+	return func(p *Pipeline) error {
+		if n < 0 {
+			return errors.New("pipeline: buffer size must not be negative")
+		}
+
+		p.bufferSize = n
+		return nil
+	}
+}
+
+// WithProvenance sets the provenance the stages are recorded in,
+// see Pipeline.Describe. The provenance is updated as the pipeline
+// is built and can then be passed to the writer.
+func WithProvenance(prov *osm.Provenance) Option {
+	return func(p *Pipeline) error {
+		p.provenance = prov
+		return nil
+	}
+}
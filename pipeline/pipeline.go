@@ -0,0 +1,206 @@
+// Package pipeline provides a composable way to filter, transform and write
+// the objects from a scanner with every stage running concurrently.
+//
+//	err := pipeline.New(ctx, scanner).
+//		Filter(func(o osm.Object) bool { ... }).
+//		Transform(func(o osm.Object) (osm.Object, error) { ... }).
+//		WriteTo(writer)
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/paulmach/osm"
+)
+
+// A FilterFunc returns true if the object should continue down the pipeline.
+type FilterFunc func(osm.Object) bool
+
+// A TransformFunc modifies or replaces the object. Returning a nil object
+// will remove it from the pipeline, returning an error stops the pipeline.
+type TransformFunc func(osm.Object) (osm.Object, error)
+
+// A Writer is the final stage of the pipeline. This interface is met by
+// the writers in the geoparquet, osmarrow and osmcsv packages.
+type Writer interface {
+	Write(osm.Object) error
+}
+
+// A WriterFunc is a function that implements the Writer interface.
+type WriterFunc func(osm.Object) error
+
+// Write calls the function.
+func (f WriterFunc) Write(o osm.Object) error {
+	return f(o)
+}
+
+type stage func(osm.Object) (osm.Object, error)
+
+// Pipeline reads the objects from the source scanner and passes them
+// through the stages. The stages are connected by bounded channels
+// so a slow stage will block the ones before it.
+type Pipeline struct {
+	ctx        context.Context
+	src        osm.Scanner
+	bufferSize int
+	stages     []stage
+	provenance *osm.Provenance
+
+	// option error returned when the pipeline is run
+	err error
+}
+
+// New creates a pipeline reading from the scanner. Canceling the
+// context will stop the pipeline. The scanner is not closed.
+// Any option errors are returned when the pipeline is run.
+func New(ctx context.Context, src osm.Scanner, opts ...Option) *Pipeline {
+	p := &Pipeline{
+		ctx:        ctx,
+		src:        src,
+		bufferSize: 100,
+	}
+
+	for _, opt := range opts {
+		if err := opt(p); err != nil {
+			p.err = err
+			break
+		}
+	}
+
+	return p
+}
+
+// Filter adds a stage that removes the objects the function returns false for.
+func (p *Pipeline) Filter(f FilterFunc) *Pipeline {
+	p.stages = append(p.stages, func(o osm.Object) (osm.Object, error) {
+		if f(o) {
+			return o, nil
+		}
+
+		return nil, nil
+	})
+
+	return p
+}
+
+// Transform adds a stage that modifies or replaces the objects.
+func (p *Pipeline) Transform(t TransformFunc) *Pipeline {
+	p.stages = append(p.stages, stage(t))
+	return p
+}
+
+// Describe records the description of the previous stage as an applied
+// filter in the provenance set with the WithProvenance option.
+// It does nothing if there is no provenance.
+func (p *Pipeline) Describe(description string) *Pipeline {
+	if p.provenance != nil {
+		p.provenance.AddFilter(description)
+	}
+
+	return p
+}
+
+// WriteTo runs the pipeline writing the results to the writer. It blocks
+// until all the objects have been processed or there is an error. The first
+// error, from the scanner, a transform or the writer, stops all the stages
+// and is returned.
+func (p *Pipeline) WriteTo(w Writer) error {
+	if p.err != nil {
+		return p.err
+	}
+
+	if p.src == nil {
+		return errors.New("pipeline: source scanner is nil")
+	}
+
+	ctx, cancel := context.WithCancel(p.ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+	)
+
+	fail := func(err error) {
+		once.Do(func() {
+			firstErr = err
+			cancel()
+		})
+	}
+
+	src := make(chan osm.Object, p.bufferSize)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer close(src)
+
+		for p.src.Scan() {
+			select {
+			case src <- p.src.Object():
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		if err := p.src.Err(); err != nil {
+			fail(err)
+		}
+	}()
+
+	in := src
+	for _, s := range p.stages {
+		out := make(chan osm.Object, p.bufferSize)
+
+		wg.Add(1)
+		go func(s stage, in <-chan osm.Object, out chan<- osm.Object) {
+			defer wg.Done()
+			defer close(out)
+
+			for o := range in {
+				o, err := s(o)
+				if err != nil {
+					fail(err)
+					return
+				}
+
+				if o == nil {
+					continue
+				}
+
+				select {
+				case out <- o:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}(s, in, out)
+
+		in = out
+	}
+
+	for o := range in {
+		if ctx.Err() != nil {
+			break
+		}
+
+		if err := w.Write(o); err != nil {
+			fail(err)
+			break
+		}
+	}
+
+	// unblock any stages waiting on the writer
+	cancel()
+	for range in {
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+
+	return p.ctx.Err()
+}
@@ -0,0 +1,145 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/paulmach/osm"
+	"github.com/paulmach/osm/osmtest"
+)
+
+func testScanner(n int) *osmtest.Scanner {
+	var objects osm.Objects
+	for i := 1; i <= n; i++ {
+		objects = append(objects, &osm.Node{ID: osm.NodeID(i)})
+	}
+	objects = append(objects, &osm.Way{ID: 1})
+
+	return osmtest.NewScanner(objects)
+}
+
+func TestPipeline(t *testing.T) {
+	var ids []osm.NodeID
+	err := New(context.Background(), testScanner(10), BufferSize(1)).
+		Filter(func(o osm.Object) bool {
+			_, ok := o.(*osm.Node)
+			return ok
+		}).
+		Transform(func(o osm.Object) (osm.Object, error) {
+			n := o.(*osm.Node)
+			if n.ID%2 == 0 {
+				return nil, nil
+			}
+
+			n.Tags = osm.Tags{{Key: "odd", Value: "yes"}}
+			return n, nil
+		}).
+		WriteTo(WriterFunc(func(o osm.Object) error {
+			n := o.(*osm.Node)
+			if n.Tags.Find("odd") != "yes" {
+				t.Errorf("should be transformed: %v", n)
+			}
+
+			ids = append(ids, n.ID)
+			return nil
+		}))
+	if err != nil {
+		t.Fatalf("pipeline error: %v", err)
+	}
+
+	if !reflect.DeepEqual(ids, []osm.NodeID{1, 3, 5, 7, 9}) {
+		t.Errorf("incorrect objects, order should be kept: %v", ids)
+	}
+}
+
+func TestPipeline_Describe(t *testing.T) {
+	prov := &osm.Provenance{}
+	err := New(context.Background(), testScanner(2), WithProvenance(prov)).
+		Filter(func(o osm.Object) bool {
+			_, ok := o.(*osm.Node)
+			return ok
+		}).Describe("nodes").
+		Transform(func(o osm.Object) (osm.Object, error) {
+			return o, nil
+		}).Describe("noop").
+		WriteTo(WriterFunc(func(osm.Object) error { return nil }))
+	if err != nil {
+		t.Fatalf("pipeline error: %v", err)
+	}
+
+	if !reflect.DeepEqual(prov.Filters, []string{"nodes", "noop"}) {
+		t.Errorf("incorrect filters: %v", prov.Filters)
+	}
+
+	// no provenance is ok
+	New(context.Background(), testScanner(2)).Describe("nothing")
+}
+
+func TestPipeline_errors(t *testing.T) {
+	someErr := errors.New("some error")
+	discard := WriterFunc(func(osm.Object) error { return nil })
+
+	t.Run("scanner error", func(t *testing.T) {
+		s := testScanner(10)
+		s.ScanError = someErr
+
+		err := New(context.Background(), s).WriteTo(discard)
+		if err != someErr {
+			t.Errorf("incorrect error: %v", err)
+		}
+	})
+
+	t.Run("transform error", func(t *testing.T) {
+		err := New(context.Background(), testScanner(1000), BufferSize(0)).
+			Transform(func(o osm.Object) (osm.Object, error) {
+				if o.(*osm.Node).ID == 10 {
+					return nil, someErr
+				}
+				return o, nil
+			}).
+			WriteTo(discard)
+		if err != someErr {
+			t.Errorf("incorrect error: %v", err)
+		}
+	})
+
+	t.Run("writer error", func(t *testing.T) {
+		count := 0
+		err := New(context.Background(), testScanner(1000)).
+			WriteTo(WriterFunc(func(osm.Object) error {
+				count++
+				if count == 5 {
+					return someErr
+				}
+				return nil
+			}))
+		if err != someErr {
+			t.Errorf("incorrect error: %v", err)
+		}
+
+		if count != 5 {
+			t.Errorf("should stop writing after error: %v", count)
+		}
+	})
+
+	t.Run("canceled context", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		err := New(ctx, testScanner(1000)).
+			WriteTo(WriterFunc(func(osm.Object) error {
+				cancel()
+				return nil
+			}))
+		if err != context.Canceled {
+			t.Errorf("incorrect error: %v", err)
+		}
+	})
+
+	t.Run("option error", func(t *testing.T) {
+		err := New(context.Background(), testScanner(1), BufferSize(-1)).WriteTo(discard)
+		if err == nil {
+			t.Errorf("should return option error")
+		}
+	})
+}
@@ -0,0 +1,51 @@
+package pipeline
+
+import "github.com/paulmach/osm"
+
+// DropUninterestingNodes returns a transform that removes the nodes without
+// any interesting tags, see osm.IsUninteresting, that are not referenced by
+// a way. The referenced function is usually backed by an index of the ways,
+// for example:
+//
+//	idx, err := refindex.Build(scanner)
+//	referenced := func(id osm.NodeID) bool {
+//		return len(idx.WaysForNode(id)) > 0
+//	}
+//
+// A nil function is treated as no nodes being referenced.
+func DropUninterestingNodes(referenced func(osm.NodeID) bool) TransformFunc {
+	return func(o osm.Object) (osm.Object, error) {
+		n, ok := o.(*osm.Node)
+		if !ok || n.IsInteresting() {
+			return o, nil
+		}
+
+		if referenced != nil && referenced(n.ID) {
+			return o, nil
+		}
+
+		return nil, nil
+	}
+}
+
+// CleanWayNodes returns a transform that removes consecutive duplicate node
+// ids and zero length segments from the ways, see osm.WayNodes.Clean.
+// This is a common cleanup step before routing or rendering.
+// Ways with less than 2 nodes after cleaning are invalid and passed to
+// the invalid function, the way is removed if it returns false.
+// A nil function keeps the invalid ways.
+func CleanWayNodes(invalid func(*osm.Way) bool) TransformFunc {
+	return func(o osm.Object) (osm.Object, error) {
+		w, ok := o.(*osm.Way)
+		if !ok {
+			return o, nil
+		}
+
+		w.Nodes = w.Nodes.Clean()
+		if len(w.Nodes) >= 2 || invalid == nil || invalid(w) {
+			return w, nil
+		}
+
+		return nil, nil
+	}
+}
@@ -0,0 +1,116 @@
+package pipeline
+
+import (
+	"testing"
+
+	"github.com/paulmach/osm"
+)
+
+func TestDropUninterestingNodes(t *testing.T) {
+	referenced := func(id osm.NodeID) bool { return id == 2 }
+	transform := DropUninterestingNodes(referenced)
+
+	cases := []struct {
+		name string
+		obj  osm.Object
+		keep bool
+	}{
+		{
+			name: "untagged",
+			obj:  &osm.Node{ID: 1},
+			keep: false,
+		},
+		{
+			name: "untagged but referenced",
+			obj:  &osm.Node{ID: 2},
+			keep: true,
+		},
+		{
+			name: "uninteresting",
+			obj:  &osm.Node{ID: 3, Tags: osm.Tags{{Key: "created_by", Value: "JOSM"}, {Key: "tiger:cfcc", Value: "A41"}}},
+			keep: false,
+		},
+		{
+			name: "interesting",
+			obj:  &osm.Node{ID: 4, Tags: osm.Tags{{Key: "amenity", Value: "cafe"}}},
+			keep: true,
+		},
+		{
+			name: "way",
+			obj:  &osm.Way{ID: 1},
+			keep: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			o, err := transform(tc.obj)
+			if err != nil {
+				t.Fatalf("transform error: %v", err)
+			}
+
+			if keep := o != nil; keep != tc.keep {
+				t.Errorf("incorrect keep: %v != %v", keep, tc.keep)
+			}
+		})
+	}
+}
+
+func TestCleanWayNodes(t *testing.T) {
+	var flagged []osm.WayID
+	transform := CleanWayNodes(func(w *osm.Way) bool {
+		flagged = append(flagged, w.ID)
+		return w.ID == 2
+	})
+
+	cases := []struct {
+		name  string
+		obj   osm.Object
+		nodes int
+		keep  bool
+	}{
+		{
+			name:  "valid",
+			obj:   &osm.Way{ID: 1, Nodes: osm.WayNodes{{ID: 1}, {ID: 1}, {ID: 2}}},
+			nodes: 2,
+			keep:  true,
+		},
+		{
+			name:  "invalid but kept",
+			obj:   &osm.Way{ID: 2, Nodes: osm.WayNodes{{ID: 1}, {ID: 1}}},
+			nodes: 1,
+			keep:  true,
+		},
+		{
+			name: "invalid",
+			obj:  &osm.Way{ID: 3, Nodes: osm.WayNodes{{ID: 1}}},
+			keep: false,
+		},
+		{
+			name: "node",
+			obj:  &osm.Node{ID: 1},
+			keep: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			o, err := transform(tc.obj)
+			if err != nil {
+				t.Fatalf("transform error: %v", err)
+			}
+
+			if keep := o != nil; keep != tc.keep {
+				t.Fatalf("incorrect keep: %v != %v", keep, tc.keep)
+			}
+
+			if w, ok := o.(*osm.Way); ok && len(w.Nodes) != tc.nodes {
+				t.Errorf("incorrect nodes: %v", w.Nodes)
+			}
+		})
+	}
+
+	if len(flagged) != 2 || flagged[0] != 2 || flagged[1] != 3 {
+		t.Errorf("incorrect invalid ways: %v", flagged)
+	}
+}
@@ -0,0 +1,333 @@
+// Package postgis loads osm data into a PostgreSQL/PostGIS database using
+// the pgsnapshot table layout.
+package postgis
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/osm"
+)
+
+// stagedTables are copied into, the way nodes and relation members
+// are staged as arrays on the ways and relations.
+var stagedTables = []string{
+	nodesTable,
+	waysTable,
+	relationsTable,
+}
+
+// Loader streams osm elements into the database using COPY. The database/sql
+// driver must support COPY using prepared statements, like github.com/lib/pq.
+type Loader struct {
+	db *sql.DB
+
+	schema      string
+	jsonb       bool
+	wayGeometry bool
+}
+
+// New creates a loader for the given database.
+func New(db *sql.DB, opts ...Option) (*Loader, error) {
+	l := &Loader{db: db}
+	for _, opt := range opts {
+		if err := opt(l); err != nil {
+			return nil, err
+		}
+	}
+
+	return l, nil
+}
+
+// Load copies all the nodes, ways and relations from the scanner into the
+// tables. Everything is done in one transaction on one connection, so the load
+// is all or nothing and does not need more than one connection from the pool.
+// The rows are copied into temporary staging tables, with a _load suffix, and
+// then moved into the tables. A connection can only do one copy at a time so
+// a new copy is started when the scanned element type changes, the input
+// should be sorted by type, like osm files are, to keep that to a few copies.
+func (l *Loader) Load(ctx context.Context, s osm.Scanner) error {
+	tx, err := l.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	ld := &load{Loader: l, ctx: ctx, tx: tx}
+	if l.wayGeometry {
+		ld.locations = make(map[osm.NodeID]orb.Point)
+	}
+
+	if err := ld.createStaging(); err != nil {
+		ld.abort()
+		return err
+	}
+
+	for s.Scan() {
+		if err := ld.add(s.Object()); err != nil {
+			ld.abort()
+			return err
+		}
+	}
+
+	if err := s.Err(); err != nil {
+		ld.abort()
+		return err
+	}
+
+	if err := ld.finishCopy(); err != nil {
+		ld.abort()
+		return err
+	}
+
+	if err := ld.move(); err != nil {
+		ld.abort()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+type load struct {
+	*Loader
+	ctx context.Context
+	tx  *sql.Tx
+
+	// the current copy
+	copying string
+	stmt    *sql.Stmt
+
+	locations map[osm.NodeID]orb.Point
+}
+
+func (ld *load) add(o osm.Object) error {
+	switch o := o.(type) {
+	case *osm.Node:
+		if ld.locations != nil {
+			ld.locations[o.ID] = o.Point()
+		}
+
+		return ld.copy(nodesTable,
+			int64(o.ID), o.Version, int64(o.UserID), o.Timestamp.UTC(), int64(o.ChangesetID),
+			ld.tags(o.Tags), pointEWKT(o.Point()))
+	case *osm.Way:
+		var geom interface{}
+		if ld.wayGeometry {
+			geom = ld.lineStringEWKT(o)
+		}
+
+		return ld.copy(waysTable,
+			int64(o.ID), o.Version, int64(o.UserID), o.Timestamp.UTC(), int64(o.ChangesetID),
+			ld.tags(o.Tags), nodesArray(o.Nodes), geom)
+	case *osm.Relation:
+		ids := make([]string, len(o.Members))
+		types := make([]string, len(o.Members))
+		roles := make([]string, len(o.Members))
+		for i, m := range o.Members {
+			ids[i] = strconv.FormatInt(m.Ref, 10)
+			types[i] = memberType(m.Type)
+			roles[i] = arrayQuote(m.Role)
+		}
+
+		return ld.copy(relationsTable,
+			int64(o.ID), o.Version, int64(o.UserID), o.Timestamp.UTC(), int64(o.ChangesetID),
+			ld.tags(o.Tags), array(ids), array(types), array(roles))
+	}
+
+	return nil
+}
+
+// copy adds the row to the staging table, finishing the current copy
+// and starting a new one if the table changes.
+func (ld *load) copy(table string, values ...interface{}) error {
+	if ld.copying != table {
+		if err := ld.finishCopy(); err != nil {
+			return err
+		}
+
+		stmt, err := ld.tx.PrepareContext(ld.ctx, ld.copyStatement(table))
+		if err != nil {
+			return err
+		}
+
+		ld.copying, ld.stmt = table, stmt
+	}
+
+	_, err := ld.stmt.ExecContext(ld.ctx, values...)
+	return err
+}
+
+// finishCopy flushes and closes the current copy, if any.
+func (ld *load) finishCopy() error {
+	stmt := ld.stmt
+	if stmt == nil {
+		return nil
+	}
+	ld.copying, ld.stmt = "", nil
+
+	// an exec without values flushes the copy
+	if _, err := stmt.ExecContext(ld.ctx); err != nil {
+		stmt.Close()
+		return err
+	}
+
+	return stmt.Close()
+}
+
+// createStaging creates the empty staging tables like the tables. They are
+// temporary, so loads in other sessions do not conflict, and dropped when
+// the transaction ends.
+func (ld *load) createStaging() error {
+	var stmts []string
+	for _, t := range stagedTables {
+		stmts = append(stmts, fmt.Sprintf("CREATE TEMP TABLE %s (LIKE %s INCLUDING DEFAULTS) ON COMMIT DROP",
+			ld.stagingTable(t), ld.table(t)))
+	}
+
+	stmts = append(stmts, fmt.Sprintf("ALTER TABLE %s "+
+		"ADD COLUMN member_ids bigint[], "+
+		"ADD COLUMN member_types character(1)[], "+
+		"ADD COLUMN member_roles text[]", ld.stagingTable(relationsTable)))
+
+	return execAll(ld.ctx, ld.tx, stmts)
+}
+
+// move inserts the staged rows into the tables, the way nodes and
+// relation members are expanded from the arrays.
+func (ld *load) move() error {
+	var stmts []string
+	for _, t := range stagedTables {
+		cols := columnList(t)
+		stmts = append(stmts, fmt.Sprintf("INSERT INTO %s (%s) SELECT %s FROM %s",
+			ld.table(t), cols, cols, ld.stagingTable(t)))
+	}
+
+	stmts = append(stmts,
+		fmt.Sprintf("INSERT INTO %s (%s) SELECT w.id, n.node_id, n.sequence_id - 1 FROM %s w, "+
+			"unnest(w.nodes) WITH ORDINALITY AS n(node_id, sequence_id)",
+			ld.table(wayNodesTable), columnList(wayNodesTable), ld.stagingTable(waysTable)),
+		fmt.Sprintf("INSERT INTO %s (%s) SELECT r.id, m.member_id, m.member_type, m.member_role, m.sequence_id - 1 FROM %s r, "+
+			"unnest(r.member_ids, r.member_types, r.member_roles) WITH ORDINALITY AS m(member_id, member_type, member_role, sequence_id)",
+			ld.table(relationMembersTable), columnList(relationMembersTable), ld.stagingTable(relationsTable)),
+	)
+
+	return execAll(ld.ctx, ld.tx, stmts)
+}
+
+// abort closes the current copy and rolls back the transaction,
+// which also drops the staging tables.
+func (ld *load) abort() {
+	if ld.stmt != nil {
+		ld.stmt.Close()
+		ld.copying, ld.stmt = "", nil
+	}
+
+	ld.tx.Rollback()
+}
+
+// tags returns the text representation for the hstore or jsonb column.
+func (ld *load) tags(tags osm.Tags) interface{} {
+	if ld.jsonb {
+		data, _ := json.Marshal(tags.Map())
+		return string(data)
+	}
+
+	buf := &bytes.Buffer{}
+	for i, t := range tags {
+		if i != 0 {
+			buf.WriteString(", ")
+		}
+
+		buf.WriteString(hstoreQuote(t.Key))
+		buf.WriteString("=>")
+		buf.WriteString(hstoreQuote(t.Value))
+	}
+
+	return buf.String()
+}
+
+func (ld *load) lineStringEWKT(w *osm.Way) interface{} {
+	ls := make(orb.LineString, 0, len(w.Nodes))
+	for _, wn := range w.Nodes {
+		if wn.Lat != 0 || wn.Lon != 0 {
+			ls = append(ls, wn.Point())
+		} else if p, ok := ld.locations[wn.ID]; ok {
+			ls = append(ls, p)
+		}
+	}
+
+	if len(ls) < 2 {
+		return nil
+	}
+
+	buf := &bytes.Buffer{}
+	buf.WriteString("SRID=4326;LINESTRING(")
+	for i, p := range ls {
+		if i != 0 {
+			buf.WriteByte(',')
+		}
+		writePoint(buf, p)
+	}
+	buf.WriteByte(')')
+
+	return buf.String()
+}
+
+func pointEWKT(p orb.Point) string {
+	buf := &bytes.Buffer{}
+	buf.WriteString("SRID=4326;POINT(")
+	writePoint(buf, p)
+	buf.WriteByte(')')
+
+	return buf.String()
+}
+
+func writePoint(buf *bytes.Buffer, p orb.Point) {
+	buf.WriteString(strconv.FormatFloat(p[0], 'f', -1, 64))
+	buf.WriteByte(' ')
+	buf.WriteString(strconv.FormatFloat(p[1], 'f', -1, 64))
+}
+
+func nodesArray(nodes osm.WayNodes) string {
+	ids := make([]string, len(nodes))
+	for i, wn := range nodes {
+		ids[i] = strconv.FormatInt(int64(wn.ID), 10)
+	}
+
+	return array(ids)
+}
+
+// array returns the text representation of an array column,
+// the elements must already be quoted if necessary.
+func array(elements []string) string {
+	return "{" + strings.Join(elements, ",") + "}"
+}
+
+func memberType(t osm.Type) string {
+	switch t {
+	case osm.TypeNode:
+		return "N"
+	case osm.TypeWay:
+		return "W"
+	case osm.TypeRelation:
+		return "R"
+	}
+
+	return ""
+}
+
+var hstoreReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+
+func hstoreQuote(s string) string {
+	return `"` + hstoreReplacer.Replace(s) + `"`
+}
+
+// arrayQuote quotes an array element, the escaping is the same as hstore.
+func arrayQuote(s string) string {
+	return hstoreQuote(s)
+}
@@ -0,0 +1,217 @@
+package postgis
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/paulmach/osm"
+	"github.com/paulmach/osm/internal/sqltest"
+	"github.com/paulmach/osm/osmtest"
+)
+
+func TestLoader_Load(t *testing.T) {
+	db, rec := openTestDB(t)
+	defer db.Close()
+
+	l, err := New(db, Schema("osm"), WayGeometry(true))
+	if err != nil {
+		t.Fatalf("new error: %v", err)
+	}
+
+	ts := time.Date(2018, 1, 2, 3, 4, 5, 0, time.UTC)
+	objects := osm.Objects{
+		&osm.Node{ID: 1, Lat: 1.5, Lon: 2, Version: 1, UserID: 3, ChangesetID: 4, Timestamp: ts,
+			Tags: osm.Tags{{Key: "name", Value: `a "quoted" name`}}},
+		&osm.Node{ID: 2, Lat: 1, Lon: 2.5, Version: 1, UserID: 3, ChangesetID: 4, Timestamp: ts},
+		&osm.Way{ID: 5, Version: 2, UserID: 3, ChangesetID: 4, Timestamp: ts,
+			Nodes: osm.WayNodes{{ID: 1}, {ID: 2}}},
+		&osm.Relation{ID: 6, Version: 3, UserID: 3, ChangesetID: 4, Timestamp: ts,
+			Members: osm.Members{{Type: osm.TypeWay, Ref: 5, Role: "outer"}}},
+	}
+
+	if err := l.Load(context.Background(), osmtest.NewScanner(objects)); err != nil {
+		t.Fatalf("load error: %v", err)
+	}
+
+	// one transaction for the whole load
+	if rec.Commits != 1 || rec.Rollbacks != 0 {
+		t.Errorf("incorrect commits/rollbacks: %d %d", rec.Commits, rec.Rollbacks)
+	}
+
+	if v := rec.Execs[0]; v != `CREATE TEMP TABLE "nodes_load" (LIKE "osm"."nodes" INCLUDING DEFAULTS) ON COMMIT DROP` {
+		t.Errorf("incorrect staging table: %v", v)
+	}
+
+	move := rec.Execs[len(rec.Execs)-1]
+	if move != `INSERT INTO "osm"."relation_members" ("relation_id", "member_id", "member_type", "member_role", "sequence_id") `+
+		`SELECT r.id, m.member_id, m.member_type, m.member_role, m.sequence_id - 1 FROM "relations_load" r, `+
+		`unnest(r.member_ids, r.member_types, r.member_roles) WITH ORDINALITY AS m(member_id, member_type, member_role, sequence_id)` {
+		t.Errorf("incorrect move: %v", move)
+	}
+
+	stmt := `COPY "nodes_load" ("id", "version", "user_id", "tstamp", "changeset_id", "tags", "geom") FROM STDIN`
+	expected := [][]driver.Value{
+		{int64(1), int64(1), int64(3), ts, int64(4), `"name"=>"a \"quoted\" name"`, "SRID=4326;POINT(2 1.5)"},
+		{int64(2), int64(1), int64(3), ts, int64(4), "", "SRID=4326;POINT(2.5 1)"},
+	}
+	if v := rec.Rows[stmt]; !reflect.DeepEqual(v, expected) {
+		t.Errorf("incorrect nodes: %v", v)
+	}
+
+	ways := rec.Rows[l.copyStatement(waysTable)]
+	if len(ways) != 1 || ways[0][6] != "{1,2}" || ways[0][7] != "SRID=4326;LINESTRING(2 1.5,2.5 1)" {
+		t.Errorf("incorrect ways: %v", ways)
+	}
+
+	relations := rec.Rows[l.copyStatement(relationsTable)]
+	if len(relations) != 1 || relations[0][6] != "{5}" || relations[0][7] != "{W}" || relations[0][8] != `{"outer"}` {
+		t.Errorf("incorrect relations: %v", relations)
+	}
+}
+
+func TestLoader_Load_copies(t *testing.T) {
+	db, rec := openTestDB(t)
+	defer db.Close()
+
+	// the copies are restarted if the types are not sorted
+	l, _ := New(db)
+	objects := osm.Objects{
+		&osm.Node{ID: 1},
+		&osm.Way{ID: 2},
+		&osm.Node{ID: 3},
+	}
+
+	if err := l.Load(context.Background(), osmtest.NewScanner(objects)); err != nil {
+		t.Fatalf("load error: %v", err)
+	}
+
+	if v := rec.Rows[l.copyStatement(nodesTable)]; len(v) != 2 {
+		t.Errorf("incorrect nodes: %v", v)
+	}
+
+	if v := rec.Rows[l.copyStatement(waysTable)]; len(v) != 1 {
+		t.Errorf("incorrect ways: %v", v)
+	}
+}
+
+func TestLoader_Load_jsonb(t *testing.T) {
+	db, rec := openTestDB(t)
+	defer db.Close()
+
+	l, _ := New(db, JSONBTags(true))
+	objects := osm.Objects{
+		&osm.Node{ID: 1, Tags: osm.Tags{{Key: "amenity", Value: "cafe"}}},
+	}
+
+	if err := l.Load(context.Background(), osmtest.NewScanner(objects)); err != nil {
+		t.Fatalf("load error: %v", err)
+	}
+
+	nodes := rec.Rows[l.copyStatement(nodesTable)]
+	if len(nodes) != 1 || nodes[0][5] != `{"amenity":"cafe"}` {
+		t.Errorf("incorrect nodes: %v", nodes)
+	}
+}
+
+func TestLoader_Load_error(t *testing.T) {
+	db, rec := openTestDB(t)
+	defer db.Close()
+
+	l, _ := New(db)
+	scanner := osmtest.NewScanner(osm.Objects{&osm.Node{ID: 1}})
+	scanner.ScanError = errors.New("scan error")
+
+	if err := l.Load(context.Background(), scanner); err == nil {
+		t.Errorf("should return scan error")
+	}
+
+	if rec.Commits != 0 || rec.Rollbacks != 1 {
+		t.Errorf("should roll back: %d %d", rec.Commits, rec.Rollbacks)
+	}
+}
+
+func TestLoader_Load_commitError(t *testing.T) {
+	db, rec := openTestDB(t)
+	defer db.Close()
+
+	rec.FailCommit = 1
+
+	l, _ := New(db)
+	objects := osm.Objects{
+		&osm.Node{ID: 1},
+		&osm.Way{ID: 2},
+	}
+
+	if err := l.Load(context.Background(), osmtest.NewScanner(objects)); err == nil {
+		t.Fatalf("should return commit error")
+	}
+}
+
+func TestLoader_Load_oneConnection(t *testing.T) {
+	db, _ := openTestDB(t)
+	defer db.Close()
+
+	db.SetMaxOpenConns(1)
+
+	l, _ := New(db)
+	objects := osm.Objects{
+		&osm.Node{ID: 1},
+		&osm.Way{ID: 2},
+		&osm.Relation{ID: 3},
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- l.Load(context.Background(), osmtest.NewScanner(objects))
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("load error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("load should only need one connection")
+	}
+}
+
+func TestLoader_CreateTables(t *testing.T) {
+	db, rec := openTestDB(t)
+	defer db.Close()
+
+	l, _ := New(db, JSONBTags(true))
+	if err := l.CreateTables(context.Background()); err != nil {
+		t.Fatalf("create error: %v", err)
+	}
+
+	for _, s := range rec.Execs {
+		if strings.Contains(s, "hstore") {
+			t.Errorf("should not use hstore: %v", s)
+		}
+	}
+
+	if len(rec.Execs) != 6 {
+		t.Errorf("incorrect number of statements: %v", len(rec.Execs))
+	}
+}
+
+func TestNew_errors(t *testing.T) {
+	if _, err := New(nil, Schema("")); err == nil {
+		t.Errorf("should error on empty schema")
+	}
+}
+
+func openTestDB(t *testing.T) (*sql.DB, *sqltest.Recorder) {
+	db, rec, err := sqltest.Open()
+	if err != nil {
+		t.Fatalf("open error: %v", err)
+	}
+
+	return db, rec
+}
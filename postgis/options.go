@@ -0,0 +1,38 @@
+package postgis
+
+import "errors"
+
+// An Option is a setting for loading the data.
+type Option func(*Loader) error
+
+// Schema sets the database schema the tables are created in and loaded into.
+// The default is the search path of the connection, usually `public`.
+func Schema(name string) Option {
+	return func(l *Loader) error {
+		if name == "" {
+			return errors.New("postgis: schema name must not be empty")
+		}
+
+		l.schema = name
+		return nil
+	}
+}
+
+// JSONBTags will store the tags in a jsonb column instead of the hstore
+// default. This removes the need for the hstore extension.
+func JSONBTags(yes bool) Option {
+	return func(l *Loader) error {
+		l.jsonb = yes
+		return nil
+	}
+}
+
+// WayGeometry will build a linestring geometry for every way. If the way
+// nodes are not annotated with their locations the node locations are kept
+// in memory as they are loaded, so nodes must come before the ways.
+func WayGeometry(yes bool) Option {
+	return func(l *Loader) error {
+		l.wayGeometry = yes
+		return nil
+	}
+}
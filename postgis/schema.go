@@ -0,0 +1,162 @@
+package postgis
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// the pgsnapshot schema tables
+const (
+	nodesTable           = "nodes"
+	waysTable            = "ways"
+	wayNodesTable        = "way_nodes"
+	relationsTable       = "relations"
+	relationMembersTable = "relation_members"
+)
+
+var columns = map[string][]string{
+	nodesTable:           {"id", "version", "user_id", "tstamp", "changeset_id", "tags", "geom"},
+	waysTable:            {"id", "version", "user_id", "tstamp", "changeset_id", "tags", "nodes", "linestring"},
+	wayNodesTable:        {"way_id", "node_id", "sequence_id"},
+	relationsTable:       {"id", "version", "user_id", "tstamp", "changeset_id", "tags"},
+	relationMembersTable: {"relation_id", "member_id", "member_type", "member_role", "sequence_id"},
+}
+
+// CreateTables creates the required extensions and the pgsnapshot style tables,
+// if they do not already exist. Indexes should be created after loading the data
+// using CreateIndexes since that is a lot faster.
+func (l *Loader) CreateTables(ctx context.Context) error {
+	tagsType := "hstore"
+	if l.jsonb {
+		tagsType = "jsonb"
+	}
+
+	stmts := []string{"CREATE EXTENSION IF NOT EXISTS postgis"}
+	if !l.jsonb {
+		stmts = append(stmts, "CREATE EXTENSION IF NOT EXISTS hstore")
+	}
+
+	if l.schema != "" {
+		stmts = append(stmts, "CREATE SCHEMA IF NOT EXISTS "+quoteIdent(l.schema))
+	}
+
+	stmts = append(stmts,
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	id bigint NOT NULL,
+	version int NOT NULL,
+	user_id int NOT NULL,
+	tstamp timestamp without time zone NOT NULL,
+	changeset_id bigint NOT NULL,
+	tags %s,
+	geom geometry(Point, 4326)
+)`, l.table(nodesTable), tagsType),
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	id bigint NOT NULL,
+	version int NOT NULL,
+	user_id int NOT NULL,
+	tstamp timestamp without time zone NOT NULL,
+	changeset_id bigint NOT NULL,
+	tags %s,
+	nodes bigint[],
+	linestring geometry(LineString, 4326)
+)`, l.table(waysTable), tagsType),
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	way_id bigint NOT NULL,
+	node_id bigint NOT NULL,
+	sequence_id int NOT NULL
+)`, l.table(wayNodesTable)),
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	id bigint NOT NULL,
+	version int NOT NULL,
+	user_id int NOT NULL,
+	tstamp timestamp without time zone NOT NULL,
+	changeset_id bigint NOT NULL,
+	tags %s
+)`, l.table(relationsTable), tagsType),
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	relation_id bigint NOT NULL,
+	member_id bigint NOT NULL,
+	member_type character(1) NOT NULL,
+	member_role text NOT NULL,
+	sequence_id int NOT NULL
+)`, l.table(relationMembersTable)),
+	)
+
+	return l.exec(ctx, stmts)
+}
+
+// CreateIndexes adds the primary keys and the spatial and lookup indexes.
+func (l *Loader) CreateIndexes(ctx context.Context) error {
+	stmts := []string{
+		fmt.Sprintf("ALTER TABLE %s ADD PRIMARY KEY (id)", l.table(nodesTable)),
+		fmt.Sprintf("ALTER TABLE %s ADD PRIMARY KEY (id)", l.table(waysTable)),
+		fmt.Sprintf("ALTER TABLE %s ADD PRIMARY KEY (way_id, sequence_id)", l.table(wayNodesTable)),
+		fmt.Sprintf("ALTER TABLE %s ADD PRIMARY KEY (id)", l.table(relationsTable)),
+		fmt.Sprintf("ALTER TABLE %s ADD PRIMARY KEY (relation_id, sequence_id)", l.table(relationMembersTable)),
+		fmt.Sprintf("CREATE INDEX ON %s USING gist (geom)", l.table(nodesTable)),
+		fmt.Sprintf("CREATE INDEX ON %s USING gist (linestring)", l.table(waysTable)),
+		fmt.Sprintf("CREATE INDEX ON %s (node_id)", l.table(wayNodesTable)),
+		fmt.Sprintf("CREATE INDEX ON %s (member_id, member_type)", l.table(relationMembersTable)),
+	}
+
+	return l.exec(ctx, stmts)
+}
+
+func (l *Loader) exec(ctx context.Context, stmts []string) error {
+	return execAll(ctx, l.db, stmts)
+}
+
+// execer is a *sql.DB or *sql.Tx.
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+func execAll(ctx context.Context, db execer, stmts []string) error {
+	for _, s := range stmts {
+		if _, err := db.ExecContext(ctx, s); err != nil {
+			return fmt.Errorf("postgis: %v: %s", err, s)
+		}
+	}
+
+	return nil
+}
+
+func (l *Loader) table(name string) string {
+	if l.schema == "" {
+		return quoteIdent(name)
+	}
+
+	return quoteIdent(l.schema) + "." + quoteIdent(name)
+}
+
+// stagingTable returns the temporary table the data is copied into before
+// it is moved into the table. Temporary tables can not have a schema.
+func (l *Loader) stagingTable(name string) string {
+	return quoteIdent(name + "_load")
+}
+
+// copyStatement returns the statement to start a copy into the staging table.
+// Drivers like github.com/lib/pq implement bulk copy using this prepared statement.
+func (l *Loader) copyStatement(name string) string {
+	cols := columnList(name)
+	if name == relationsTable {
+		cols += `, "member_ids", "member_types", "member_roles"`
+	}
+
+	return fmt.Sprintf("COPY %s (%s) FROM STDIN", l.stagingTable(name), cols)
+}
+
+func columnList(name string) string {
+	cols := make([]string, len(columns[name]))
+	for i, c := range columns[name] {
+		cols[i] = quoteIdent(c)
+	}
+
+	return strings.Join(cols, ", ")
+}
+
+func quoteIdent(name string) string {
+	return `"` + strings.Replace(name, `"`, `""`, -1) + `"`
+}
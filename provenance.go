@@ -0,0 +1,63 @@
+package osm
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// Provenance describes how a dataset was generated: the inputs, the filters
+// applied and the tool that wrote it. It is carried through a processing
+// job and embedded by the writers, e.g. in the osmpbf header, or saved as
+// a sidecar json file so the output can be reproduced or audited later.
+type Provenance struct {
+	// Tool is the name and version of the program, e.g. osmium/1.8.0.
+	Tool string `json:"tool,omitempty"`
+
+	Inputs  []ProvenanceInput `json:"inputs,omitempty"`
+	Filters []string          `json:"filters,omitempty"`
+
+	// ReplicationSeqNum is the replication sequence number the data
+	// is current to, zero if not known.
+	ReplicationSeqNum uint64 `json:"replication_seq_num,omitempty"`
+}
+
+// ProvenanceInput is a file or url read to generate the dataset.
+type ProvenanceInput struct {
+	Name string `json:"name"`
+
+	// Provenance of the input, if it was generated by an earlier job.
+	Provenance *Provenance `json:"provenance,omitempty"`
+}
+
+// AddInput records an input of the dataset. The provenance of the
+// input can be nil.
+func (p *Provenance) AddInput(name string, from *Provenance) {
+	p.Inputs = append(p.Inputs, ProvenanceInput{Name: name, Provenance: from})
+}
+
+// AddFilter records a description of a filter or transform applied to
+// the data, in the order they are applied.
+func (p *Provenance) AddFilter(description string) {
+	p.Filters = append(p.Filters, description)
+}
+
+// ReadProvenance reads the provenance written by WriteTo.
+func ReadProvenance(r io.Reader) (*Provenance, error) {
+	p := &Provenance{}
+	if err := json.NewDecoder(r).Decode(p); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// WriteTo writes the provenance, as json, to the writer.
+func (p *Provenance) WriteTo(w io.Writer) (int64, error) {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := w.Write(data)
+	return int64(n), err
+}
@@ -0,0 +1,40 @@
+package osm
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestProvenance_WriteTo(t *testing.T) {
+	extract := &Provenance{Tool: "osmium/1.8.0", ReplicationSeqNum: 2468}
+	extract.AddInput("planet-latest.osm.pbf", nil)
+	extract.AddFilter("bbox -75.8,38.4,-75,39.9")
+
+	p := &Provenance{Tool: "github.com/paulmach/osm"}
+	p.AddInput("delaware-latest.osm.pbf", extract)
+	p.AddFilter("highways")
+	p.AddFilter("drop uninteresting nodes")
+
+	buf := &bytes.Buffer{}
+	if _, err := p.WriteTo(buf); err != nil {
+		t.Fatalf("write error: %v", err)
+	}
+
+	expected := `{"tool":"github.com/paulmach/osm","inputs":[{"name":"delaware-latest.osm.pbf",` +
+		`"provenance":{"tool":"osmium/1.8.0","inputs":[{"name":"planet-latest.osm.pbf"}],` +
+		`"filters":["bbox -75.8,38.4,-75,39.9"],"replication_seq_num":2468}}],` +
+		`"filters":["highways","drop uninteresting nodes"]}`
+	if v := buf.String(); v != expected {
+		t.Errorf("incorrect json: %v", v)
+	}
+
+	result, err := ReadProvenance(buf)
+	if err != nil {
+		t.Fatalf("read error: %v", err)
+	}
+
+	if !reflect.DeepEqual(result, p) {
+		t.Errorf("incorrect provenance: %+v", result)
+	}
+}
@@ -0,0 +1,88 @@
+package osm
+
+import "context"
+
+// RedactionID is the id of a redaction. Redactions hide element versions
+// that can't be distributed, usually for copyright reasons. The api only
+// returns redacted versions in the history to moderators, with the
+// redacted attribute set to the redaction id.
+// See https://wiki.openstreetmap.org/wiki/Redaction
+type RedactionID int64
+
+// Unredacted returns the nodes that are not redacted.
+// The original slice is not modified.
+func (ns Nodes) Unredacted() Nodes {
+	result := make(Nodes, 0, len(ns))
+	for _, n := range ns {
+		if n.Redacted == 0 {
+			result = append(result, n)
+		}
+	}
+
+	return result
+}
+
+// Unredacted returns the ways that are not redacted.
+// The original slice is not modified.
+func (ws Ways) Unredacted() Ways {
+	result := make(Ways, 0, len(ws))
+	for _, w := range ws {
+		if w.Redacted == 0 {
+			result = append(result, w)
+		}
+	}
+
+	return result
+}
+
+// Unredacted returns the relations that are not redacted.
+// The original slice is not modified.
+func (rs Relations) Unredacted() Relations {
+	result := make(Relations, 0, len(rs))
+	for _, r := range rs {
+		if r.Redacted == 0 {
+			result = append(result, r)
+		}
+	}
+
+	return result
+}
+
+// UnredactedDatasource wraps a history datasource and removes the redacted
+// versions from the histories, so they are not used when processing the
+// history, e.g. by the annotate package.
+type UnredactedDatasource struct {
+	HistoryDatasourcer
+}
+
+var _ HistoryDatasourcer = &UnredactedDatasource{}
+
+// NodeHistory returns the unredacted versions of the node.
+func (ds *UnredactedDatasource) NodeHistory(ctx context.Context, id NodeID) (Nodes, error) {
+	nodes, err := ds.HistoryDatasourcer.NodeHistory(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return nodes.Unredacted(), nil
+}
+
+// WayHistory returns the unredacted versions of the way.
+func (ds *UnredactedDatasource) WayHistory(ctx context.Context, id WayID) (Ways, error) {
+	ways, err := ds.HistoryDatasourcer.WayHistory(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return ways.Unredacted(), nil
+}
+
+// RelationHistory returns the unredacted versions of the relation.
+func (ds *UnredactedDatasource) RelationHistory(ctx context.Context, id RelationID) (Relations, error) {
+	relations, err := ds.HistoryDatasourcer.RelationHistory(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return relations.Unredacted(), nil
+}
@@ -0,0 +1,78 @@
+package osm
+
+import (
+	"context"
+	"encoding/xml"
+	"testing"
+)
+
+func TestRedaction_unmarshalXML(t *testing.T) {
+	data := []byte(`<osm>
+ <node id="1" version="1" changeset="10" lat="1" lon="2" visible="true"/>
+ <node id="1" version="2" changeset="11" lat="1" lon="2" visible="true" redacted="7"/>
+ <way id="2" version="3" visible="true" redacted="8"/>
+ <relation id="3" version="1" visible="true" redacted="9"/>
+</osm>`)
+
+	o := &OSM{}
+	if err := xml.Unmarshal(data, o); err != nil {
+		t.Fatalf("unmarshal error: %v", err)
+	}
+
+	if o.Nodes[0].Redacted != 0 || o.Nodes[1].Redacted != 7 {
+		t.Errorf("incorrect node redactions: %v %v", o.Nodes[0].Redacted, o.Nodes[1].Redacted)
+	}
+
+	if o.Ways[0].Redacted != 8 || o.Relations[0].Redacted != 9 {
+		t.Errorf("incorrect redactions: %v %v", o.Ways[0].Redacted, o.Relations[0].Redacted)
+	}
+
+	// written back out
+	out, err := xml.Marshal(o.Nodes[1])
+	if err != nil {
+		t.Fatalf("marshal error: %v", err)
+	}
+
+	n := &Node{}
+	if err := xml.Unmarshal(out, n); err != nil {
+		t.Fatalf("unmarshal error: %v", err)
+	}
+
+	if !n.Equal(o.Nodes[1]) || n.Equal(o.Nodes[0]) {
+		t.Errorf("incorrect round trip: %s", out)
+	}
+}
+
+func TestUnredactedDatasource(t *testing.T) {
+	ctx := context.Background()
+	ds := &UnredactedDatasource{&HistoryDatasource{
+		Nodes: map[NodeID]Nodes{
+			1: {{ID: 1, Version: 1}, {ID: 1, Version: 2, Redacted: 1}, {ID: 1, Version: 3}},
+		},
+		Ways: map[WayID]Ways{
+			1: {{ID: 1, Version: 1, Redacted: 1}, {ID: 1, Version: 2}},
+		},
+		Relations: map[RelationID]Relations{
+			1: {{ID: 1, Version: 1, Redacted: 1}},
+		},
+	}}
+
+	nodes, err := ds.NodeHistory(ctx, 1)
+	if err != nil || len(nodes) != 2 || nodes[1].Version != 3 {
+		t.Errorf("incorrect nodes: %v %v", nodes, err)
+	}
+
+	ways, err := ds.WayHistory(ctx, 1)
+	if err != nil || len(ways) != 1 || ways[0].Version != 2 {
+		t.Errorf("incorrect ways: %v %v", ways, err)
+	}
+
+	relations, err := ds.RelationHistory(ctx, 1)
+	if err != nil || len(relations) != 0 {
+		t.Errorf("incorrect relations: %v %v", relations, err)
+	}
+
+	if _, err := ds.NodeHistory(ctx, 2); !ds.NotFound(err) {
+		t.Errorf("should return not found: %v", err)
+	}
+}
@@ -0,0 +1,135 @@
+// Package refindex builds a reverse reference index, from children to
+// parents, over a stream of osm data. It answers "which ways contain this
+// node" and "which relations contain this element", as needed for impact
+// analysis when processing diffs.
+package refindex
+
+import (
+	"github.com/paulmach/osm"
+)
+
+// Index maps nodes to the ways that contain them and members to the
+// relations that contain them. Only the ids are stored. The index is
+// not safe for concurrent modification.
+type Index struct {
+	nodeWays         map[osm.NodeID][]osm.WayID
+	elementRelations map[osm.FeatureID][]osm.RelationID
+}
+
+// New creates an empty index.
+func New() *Index {
+	return &Index{
+		nodeWays:         make(map[osm.NodeID][]osm.WayID),
+		elementRelations: make(map[osm.FeatureID][]osm.RelationID),
+	}
+}
+
+// Build creates an index from the ways and relations in the scanner.
+// The scanner is not closed.
+func Build(s osm.Scanner) (*Index, error) {
+	idx := New()
+	for s.Scan() {
+		idx.Add(s.Object())
+	}
+
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+
+	return idx, nil
+}
+
+// Add indexes the references of a way or relation. Other types are ignored.
+// Adding the same way or relation again, eg. a newer version, will add any
+// new references but will not remove the old ones.
+func (idx *Index) Add(o osm.Object) {
+	switch o := o.(type) {
+	case *osm.Way:
+		for _, wn := range o.Nodes {
+			ways := idx.nodeWays[wn.ID]
+			if !containsWay(ways, o.ID) {
+				idx.nodeWays[wn.ID] = append(ways, o.ID)
+			}
+		}
+	case *osm.Relation:
+		for _, m := range o.Members {
+			fid := m.FeatureID()
+			relations := idx.elementRelations[fid]
+			if !containsRelation(relations, o.ID) {
+				idx.elementRelations[fid] = append(relations, o.ID)
+			}
+		}
+	}
+}
+
+// WaysForNode returns the ids of the ways that contain the node.
+func (idx *Index) WaysForNode(id osm.NodeID) []osm.WayID {
+	return idx.nodeWays[id]
+}
+
+// RelationsForElement returns the ids of the relations that have
+// the node, way or relation as a member.
+func (idx *Index) RelationsForElement(id osm.FeatureID) []osm.RelationID {
+	return idx.elementRelations[id]
+}
+
+// Affected returns the ways and relations that directly, or indirectly
+// through other ways and relations, contain any of the given elements.
+// The given elements are not included in the result, unless they are part
+// of a relation cycle. The result is sorted.
+func (idx *Index) Affected(ids ...osm.FeatureID) osm.FeatureIDs {
+	seen := make(map[osm.FeatureID]struct{})
+	var result osm.FeatureIDs
+
+	add := func(id osm.FeatureID) {
+		if _, ok := seen[id]; ok {
+			return
+		}
+
+		seen[id] = struct{}{}
+		result = append(result, id)
+	}
+
+	for _, id := range ids {
+		if id.Type() == osm.TypeNode {
+			for _, wid := range idx.nodeWays[id.NodeID()] {
+				add(wid.FeatureID())
+			}
+		}
+
+		for _, rid := range idx.elementRelations[id] {
+			add(rid.FeatureID())
+		}
+	}
+
+	// relations containing the affected ways and relations,
+	// the result grows as we go.
+	for i := 0; i < len(result); i++ {
+		for _, rid := range idx.elementRelations[result[i]] {
+			add(rid.FeatureID())
+		}
+	}
+
+	result.Sort()
+	return result
+}
+
+func containsWay(ids []osm.WayID, id osm.WayID) bool {
+	for _, i := range ids {
+		if i == id {
+			return true
+		}
+	}
+
+	return false
+}
+
+func containsRelation(ids []osm.RelationID, id osm.RelationID) bool {
+	for _, i := range ids {
+		if i == id {
+			return true
+		}
+	}
+
+	return false
+}
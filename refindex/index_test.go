@@ -0,0 +1,128 @@
+package refindex
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/paulmach/osm"
+	"github.com/paulmach/osm/osmtest"
+)
+
+func testIndex(t *testing.T) *Index {
+	idx, err := Build(osmtest.NewScanner(osm.Objects{
+		&osm.Node{ID: 1},
+		&osm.Way{ID: 10, Nodes: osm.WayNodes{{ID: 1}, {ID: 2}, {ID: 1}}},
+		&osm.Way{ID: 11, Nodes: osm.WayNodes{{ID: 2}, {ID: 3}}},
+		&osm.Relation{ID: 20, Members: osm.Members{
+			{Type: osm.TypeWay, Ref: 10},
+			{Type: osm.TypeNode, Ref: 3},
+		}},
+		&osm.Relation{ID: 21, Members: osm.Members{
+			{Type: osm.TypeRelation, Ref: 20},
+			{Type: osm.TypeRelation, Ref: 22},
+		}},
+		&osm.Relation{ID: 22, Members: osm.Members{
+			{Type: osm.TypeRelation, Ref: 21},
+		}},
+	}))
+	if err != nil {
+		t.Fatalf("build error: %v", err)
+	}
+
+	return idx
+}
+
+func TestIndex(t *testing.T) {
+	idx := testIndex(t)
+
+	if v := idx.WaysForNode(1); !reflect.DeepEqual(v, []osm.WayID{10}) {
+		t.Errorf("incorrect ways for node 1: %v", v)
+	}
+
+	if v := idx.WaysForNode(2); !reflect.DeepEqual(v, []osm.WayID{10, 11}) {
+		t.Errorf("incorrect ways for node 2: %v", v)
+	}
+
+	if v := idx.WaysForNode(4); len(v) != 0 {
+		t.Errorf("should not have ways: %v", v)
+	}
+
+	if v := idx.RelationsForElement(osm.WayID(10).FeatureID()); !reflect.DeepEqual(v, []osm.RelationID{20}) {
+		t.Errorf("incorrect relations for way: %v", v)
+	}
+
+	if v := idx.RelationsForElement(osm.NodeID(3).FeatureID()); !reflect.DeepEqual(v, []osm.RelationID{20}) {
+		t.Errorf("incorrect relations for node: %v", v)
+	}
+
+	// adding a new version only adds new references
+	idx.Add(&osm.Way{ID: 11, Version: 2, Nodes: osm.WayNodes{{ID: 2}, {ID: 4}}})
+	if v := idx.WaysForNode(4); !reflect.DeepEqual(v, []osm.WayID{11}) {
+		t.Errorf("incorrect ways for node 4: %v", v)
+	}
+
+	if v := idx.WaysForNode(2); !reflect.DeepEqual(v, []osm.WayID{10, 11}) {
+		t.Errorf("incorrect ways for node 2: %v", v)
+	}
+}
+
+func TestIndex_Affected(t *testing.T) {
+	idx := testIndex(t)
+
+	cases := []struct {
+		name     string
+		ids      osm.FeatureIDs
+		expected osm.FeatureIDs
+	}{
+		{
+			name: "node",
+			ids:  osm.FeatureIDs{osm.NodeID(1).FeatureID()},
+			expected: osm.FeatureIDs{
+				osm.WayID(10).FeatureID(),
+				osm.RelationID(20).FeatureID(),
+				osm.RelationID(21).FeatureID(),
+				osm.RelationID(22).FeatureID(),
+			},
+		},
+		{
+			name: "node and way",
+			ids:  osm.FeatureIDs{osm.NodeID(2).FeatureID(), osm.WayID(11).FeatureID()},
+			expected: osm.FeatureIDs{
+				osm.WayID(10).FeatureID(),
+				osm.WayID(11).FeatureID(),
+				osm.RelationID(20).FeatureID(),
+				osm.RelationID(21).FeatureID(),
+				osm.RelationID(22).FeatureID(),
+			},
+		},
+		{
+			name:     "relation cycle",
+			ids:      osm.FeatureIDs{osm.RelationID(22).FeatureID()},
+			expected: osm.FeatureIDs{osm.RelationID(21).FeatureID(), osm.RelationID(22).FeatureID()},
+		},
+		{
+			name: "not found",
+			ids:  osm.FeatureIDs{osm.NodeID(100).FeatureID()},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			v := idx.Affected(tc.ids...)
+			if !reflect.DeepEqual(v, tc.expected) {
+				t.Errorf("incorrect affected: %v", v)
+				t.Logf("expected: %v", tc.expected)
+			}
+		})
+	}
+}
+
+func TestBuild_error(t *testing.T) {
+	s := osmtest.NewScanner(nil)
+	s.ScanError = errors.New("some error")
+
+	if _, err := Build(s); err == nil {
+		t.Errorf("should return scanner error")
+	}
+}
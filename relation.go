@@ -2,6 +2,7 @@ package osm
 
 import (
 	"encoding/json"
+	"encoding/xml"
 	"sort"
 	"time"
 
@@ -38,6 +39,7 @@ type Relation struct {
 	Version     int                `xml:"version,attr" json:"version,omitempty"`
 	ChangesetID ChangesetID        `xml:"changeset,attr" json:"changeset,omitempty"`
 	Timestamp   time.Time          `xml:"timestamp,attr" json:"timestamp,omitempty"`
+	Redacted    RedactionID        `xml:"redacted,attr,omitempty" json:"redacted,omitempty"`
 
 	Tags    Tags    `xml:"tag" json:"tags,omitempty"`
 	Members Members `xml:"member" json:"members"`
@@ -53,6 +55,10 @@ type Relation struct {
 
 	// Bounds are included by overpass, and maybe others
 	Bounds *Bounds `xml:"bounds,omitempty" json:"bounds,omitempty"`
+	// XMLExtra are the xml attributes and child elements that are not
+	// part of the osm format. They are only set by the osmxml scanner
+	// with the PreserveUnknown option and written out again by MarshalXML.
+	XMLExtra *XMLExtra `xml:"-" json:"-"`
 }
 
 // Members represents an ordered list of relation members.
@@ -171,6 +177,81 @@ func (r *Relation) applyUpdate(u Update) error {
 	return nil
 }
 
+// MarshalXML implements the xml.Marshaler interface to write out the
+// XMLExtra attributes and elements with the relation. The attributes of
+// the start element are kept so wrappers can add their own.
+func (r Relation) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	start.Name = xml.Name{Local: "relation"}
+
+	type plain Relation
+	if r.XMLExtra == nil {
+		return e.EncodeElement(plain(r), start)
+	}
+
+	return e.EncodeElement(struct {
+		plain
+		*XMLExtra
+	}{plain(r), r.XMLExtra}, start)
+}
+
+// Copy returns a deep copy of the relation, the members, tags
+// and updates are not shared.
+func (r *Relation) Copy() *Relation {
+	c := *r
+	c.Tags = r.Tags.copy()
+	c.Updates = r.Updates.copy()
+	c.Committed = copyTimePtr(r.Committed)
+	c.XMLExtra = r.XMLExtra.copy()
+	c.Bounds = copyBounds(r.Bounds)
+
+	if r.Members != nil {
+		c.Members = append(Members(nil), r.Members...)
+	}
+
+	return &c
+}
+
+// Equal compares the relations. The tags are compared ignoring their order.
+// Use the IgnoreMetadata option to only compare the data, members are then
+// compared using only their type, ref and role.
+func (r *Relation) Equal(o *Relation, opts ...EqualOption) bool {
+	if r == nil || o == nil {
+		return r == o
+	}
+
+	if r.ID != o.ID || r.Visible != o.Visible ||
+		len(r.Members) != len(o.Members) || !equalTags(r.Tags, o.Tags) {
+		return false
+	}
+
+	if newEqualOptions(opts).ignoreMetadata {
+		for i := range r.Members {
+			m1, m2 := r.Members[i], o.Members[i]
+			if m1.Type != m2.Type || m1.Ref != m2.Ref || m1.Role != m2.Role {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	for i := range r.Members {
+		if r.Members[i] != o.Members[i] {
+			return false
+		}
+	}
+
+	return r.User == o.User &&
+		r.UserID == o.UserID &&
+		r.Version == o.Version &&
+		r.ChangesetID == o.ChangesetID &&
+		r.Timestamp.Equal(o.Timestamp) &&
+		r.Redacted == o.Redacted &&
+		equalTimePtr(r.Committed, o.Committed) &&
+		equalUpdates(r.Updates, o.Updates) &&
+		equalBounds(r.Bounds, o.Bounds)
+}
+
 // FeatureIDs returns the a list of feature ids for the members.
 func (ms Members) FeatureIDs() FeatureIDs {
 	ids := make(FeatureIDs, len(ms), len(ms)+1)
@@ -234,13 +315,31 @@ func (rs Relations) ElementIDs() ElementIDs {
 	return result
 }
 
+// Marshal encodes the relation using protocol buffers. The encoding is smaller
+// than a list of one relation so it is useful for values in key value stores.
+// It can be decoded using UnmarshalRelation.
+func (r *Relation) Marshal(opts ...MarshalOption) ([]byte, error) {
+	return marshalElement(r, opts)
+}
+
+// UnmarshalRelation will unmarshal the data, encoded by Relation.Marshal,
+// into a single relation.
+func UnmarshalRelation(data []byte) (*Relation, error) {
+	o, err := unmarshalElement(data, TypeRelation)
+	if err != nil {
+		return nil, err
+	}
+
+	return o.Relations[0], nil
+}
+
 // Marshal encodes the relations using protocol buffers.
-func (rs Relations) Marshal() ([]byte, error) {
+func (rs Relations) Marshal(opts ...MarshalOption) ([]byte, error) {
 	o := OSM{
 		Relations: rs,
 	}
 
-	return o.Marshal()
+	return o.Marshal(opts...)
 }
 
 // UnmarshalRelations will unmarshal the data into a list of relations.
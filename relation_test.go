@@ -381,3 +381,51 @@ func TestRelations_SortByIDVersion(t *testing.T) {
 		t.Errorf("incorrect sort: %v", eids)
 	}
 }
+
+func TestRelation_Copy(t *testing.T) {
+	r := &Relation{
+		ID:      1,
+		Members: Members{{Type: TypeNode, Ref: 1, Role: "a"}},
+		Tags:    Tags{{Key: "a", Value: "b"}},
+	}
+
+	c := r.Copy()
+	if !reflect.DeepEqual(c, r) {
+		t.Errorf("copy should be equal")
+	}
+
+	c.Members[0].Role = "b"
+	c.Tags[0].Value = "c"
+	if r.Members[0].Role != "a" || r.Tags[0].Value != "b" {
+		t.Errorf("should not share data with the copy")
+	}
+}
+
+func TestRelation_Equal(t *testing.T) {
+	r := &Relation{
+		ID:      1,
+		Version: 2,
+		Members: Members{{Type: TypeNode, Ref: 1, Role: "a"}},
+		Tags:    Tags{{Key: "a", Value: "b"}},
+	}
+
+	if !r.Equal(r.Copy()) {
+		t.Errorf("copy should be equal")
+	}
+
+	c := r.Copy()
+	c.Members[0].Version = 3
+	c.ChangesetID = 10
+	if r.Equal(c) {
+		t.Errorf("should compare metadata")
+	}
+
+	if !r.Equal(c, IgnoreMetadata()) {
+		t.Errorf("should ignore metadata")
+	}
+
+	c.Members[0].Role = "b"
+	if r.Equal(c, IgnoreMetadata()) {
+		t.Errorf("should compare member roles")
+	}
+}
@@ -0,0 +1,386 @@
+package replication
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/paulmach/osm"
+	"github.com/paulmach/osm/metrics"
+)
+
+// A StateStore persists the last processed sequence number
+// so a catch up can be resumed after a restart.
+type StateStore interface {
+	// Load returns the last processed sequence number,
+	// ok is false if nothing has been saved.
+	Load(ctx context.Context) (n uint64, ok bool, err error)
+
+	// Save is called after each change is processed.
+	Save(ctx context.Context, n uint64) error
+}
+
+// FileStateStore stores the last processed sequence number in a file.
+type FileStateStore struct {
+	Path string
+}
+
+var _ StateStore = &FileStateStore{}
+
+// Load reads the sequence number from the file.
+// It is not ok if the file does not exist.
+func (s *FileStateStore) Load(ctx context.Context) (uint64, bool, error) {
+	data, err := ioutil.ReadFile(s.Path)
+	if os.IsNotExist(err) {
+		return 0, false, nil
+	}
+
+	if err != nil {
+		return 0, false, err
+	}
+
+	n, err := strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("replication: invalid state file %s: %v", s.Path, err)
+	}
+
+	return n, true, nil
+}
+
+// Save writes the sequence number to a temporary file
+// and renames it so the state file is never partially written.
+func (s *FileStateStore) Save(ctx context.Context, n uint64) error {
+	f, err := ioutil.TempFile(filepath.Dir(s.Path), filepath.Base(s.Path))
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.WriteString(strconv.FormatUint(n, 10) + "\n"); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+
+	return os.Rename(f.Name(), s.Path)
+}
+
+// A CatchupOption is a setting for the catch up loop.
+type CatchupOption func(*catchup)
+
+type catchup struct {
+	store        StateStore
+	follow       bool
+	pollInterval time.Duration
+	maxRetries   int
+	minBackoff   time.Duration
+	maxBackoff   time.Duration
+	onGap        func(SeqNum)
+	metrics      metrics.Recorder
+	logger       osm.Logger
+}
+
+// WithStateStore sets the store used to persist the last processed
+// sequence number. If the store has a saved sequence number the catch up
+// resumes after it, instead of starting at the from sequence number.
+func WithStateStore(s StateStore) CatchupOption {
+	return func(c *catchup) {
+		c.store = s
+	}
+}
+
+// Follow will keep polling for new changes once caught up, until the
+// context is canceled, instead of returning. The state is polled every
+// interval, if zero it is a minute for minutely replication, 10 minutes
+// for hourly and an hour for daily.
+func Follow(interval time.Duration) CatchupOption {
+	return func(c *catchup) {
+		c.follow = true
+		c.pollInterval = interval
+	}
+}
+
+// Retries sets the number of times a failed request, including truncated
+// or corrupt gzip data, is retried with exponential backoff, starting at
+// min and doubling up to max. The default is 8 retries from 1 second up to
+// 2 minutes.
+func Retries(n int, min, max time.Duration) CatchupOption {
+	return func(c *catchup) {
+		c.maxRetries = n
+		c.minBackoff = min
+		c.maxBackoff = max
+	}
+}
+
+// OnGap is called for sequence numbers that do not exist, after retrying,
+// even though later ones do. They are skipped. By default gaps are errors.
+func OnGap(f func(SeqNum)) CatchupOption {
+	return func(c *catchup) {
+		c.onGap = f
+	}
+}
+
+// WithMetrics records the number of processed changes, the last sequence
+// number, the pending changes and the replication lag, see the metrics
+// package. The lag is the age of the newest element in the last change.
+func WithMetrics(r metrics.Recorder) CatchupOption {
+	return func(c *catchup) {
+		c.metrics = r
+	}
+}
+
+// WithLogger sets the logger that receives warnings for retried
+// requests and skipped gaps.
+func WithLogger(l osm.Logger) CatchupOption {
+	return func(c *catchup) {
+		c.logger = l
+	}
+}
+
+// ErrGap is returned if a sequence number is missing and
+// the OnGap option is not set.
+var ErrGap = errors.New("replication: missing sequence number")
+
+// Catchup fetches the changes starting at the sequence number, up to the
+// current state, and calls the handler for each in order. The from sequence
+// number determines the interval, minute, hour or day, of the replication.
+// Delegates to the DefaultDatasource and uses its http.Client to make the request.
+func Catchup(
+	ctx context.Context,
+	from SeqNum,
+	handler func(SeqNum, *osm.Change) error,
+	opts ...CatchupOption,
+) error {
+	return DefaultDatasource.Catchup(ctx, from, handler, opts...)
+}
+
+// Catchup fetches the changes starting at the sequence number, up to the
+// current state, and calls the handler for each in order. The from sequence
+// number determines the interval, minute, hour or day, of the replication.
+//
+// Failed requests are retried with backoff, see the Retries option. Handler
+// errors stop the catch up and are returned. After the handler returns the
+// sequence number is saved to the state store, if set. When the context is
+// canceled the current change is finished, saved and the context error is
+// returned.
+func (ds *Datasource) Catchup(
+	ctx context.Context,
+	from SeqNum,
+	handler func(SeqNum, *osm.Change) error,
+	opts ...CatchupOption,
+) error {
+	c := &catchup{
+		maxRetries: 8,
+		minBackoff: time.Second,
+		maxBackoff: 2 * time.Minute,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	if c.pollInterval == 0 {
+		switch from.(type) {
+		case MinuteSeqNum:
+			c.pollInterval = time.Minute
+		case HourSeqNum:
+			c.pollInterval = 10 * time.Minute
+		case DaySeqNum:
+			c.pollInterval = time.Hour
+		}
+	}
+
+	next := from.Uint64()
+	if c.store != nil {
+		n, ok, err := c.store.Load(ctx)
+		if err != nil {
+			return err
+		}
+
+		if ok {
+			next = n + 1
+		}
+	}
+
+	for {
+		var state *State
+		err := c.retry(ctx, func() error {
+			var err error
+			state, err = ds.fetchState(ctx, seqNum(from, 0))
+			return err
+		})
+		if err != nil {
+			return err
+		}
+
+		for ; next <= state.SeqNum; next++ {
+			n := seqNum(from, next)
+
+			var change *osm.Change
+			err := c.retry(ctx, func() error {
+				var err error
+				change, err = ds.fetchIntervalData(ctx, ds.changeURL(n))
+				return err
+			})
+
+			if isNotFound(err) && next < state.SeqNum {
+				if c.onGap == nil {
+					return fmt.Errorf("%v: %v", ErrGap, n)
+				}
+
+				c.warn("replication: skipping missing sequence number", "seqnum", n.String())
+				c.onGap(n)
+				continue
+			}
+
+			if err != nil {
+				return err
+			}
+
+			// the handler and save are not canceled so
+			// the state stays consistent with what was processed.
+			if err := handler(n, change); err != nil {
+				return err
+			}
+
+			if c.store != nil {
+				if err := c.store.Save(context.Background(), next); err != nil {
+					return err
+				}
+			}
+
+			if c.metrics != nil {
+				c.record(n, state.SeqNum-next, change)
+			}
+
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+		}
+
+		if !c.follow {
+			return nil
+		}
+
+		if err := sleep(ctx, c.pollInterval); err != nil {
+			return err
+		}
+	}
+}
+
+func (c *catchup) record(n SeqNum, pending uint64, change *osm.Change) {
+	interval := metrics.Label{Name: "interval", Value: n.Dir()}
+
+	c.metrics.Add(metrics.ReplicationChanges, 1, interval)
+	c.metrics.Set(metrics.ReplicationSeqNum, float64(n.Uint64()), interval)
+	c.metrics.Set(metrics.ReplicationPending, float64(pending), interval)
+
+	var newest time.Time
+	for _, o := range []*osm.OSM{change.Create, change.Modify, change.Delete} {
+		if o == nil {
+			continue
+		}
+
+		for _, n := range o.Nodes {
+			if n.Timestamp.After(newest) {
+				newest = n.Timestamp
+			}
+		}
+
+		for _, w := range o.Ways {
+			if w.Timestamp.After(newest) {
+				newest = w.Timestamp
+			}
+		}
+
+		for _, r := range o.Relations {
+			if r.Timestamp.After(newest) {
+				newest = r.Timestamp
+			}
+		}
+	}
+
+	if !newest.IsZero() {
+		c.metrics.Set(metrics.ReplicationLagSeconds, time.Since(newest).Seconds(), interval)
+	}
+}
+
+// retry calls the function until it succeeds, backing off exponentially.
+func (c *catchup) retry(ctx context.Context, f func() error) error {
+	backoff := c.minBackoff
+
+	var err error
+	for i := 0; i <= c.maxRetries; i++ {
+		if i > 0 {
+			if err := sleep(ctx, backoff); err != nil {
+				return err
+			}
+
+			backoff *= 2
+			if backoff > c.maxBackoff {
+				backoff = c.maxBackoff
+			}
+		}
+
+		err = f()
+		if err == nil {
+			return nil
+		}
+
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if i < c.maxRetries {
+			c.warn("replication: retrying request", "attempt", i+1, "backoff", backoff.String(), "err", err.Error())
+		}
+	}
+
+	return err
+}
+
+func (c *catchup) warn(msg string, keyvals ...interface{}) {
+	if c.logger != nil {
+		c.logger.Warn(msg, keyvals...)
+	}
+}
+
+func isNotFound(err error) bool {
+	e, ok := err.(*UnexpectedStatusCodeError)
+	return ok && e.Code == http.StatusNotFound
+}
+
+func seqNum(like SeqNum, n uint64) SeqNum {
+	switch like.(type) {
+	case MinuteSeqNum:
+		return MinuteSeqNum(n)
+	case HourSeqNum:
+		return HourSeqNum(n)
+	case DaySeqNum:
+		return DaySeqNum(n)
+	}
+
+	panic(fmt.Sprintf("replication: unsupported sequence number type %T", like))
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
@@ -0,0 +1,167 @@
+package replication
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/paulmach/osm"
+	"github.com/paulmach/osm/metrics"
+)
+
+func TestDatasource_Catchup(t *testing.T) {
+	osc := func(id int) []byte {
+		buf := &bytes.Buffer{}
+		gw := gzip.NewWriter(buf)
+		gw.Write([]byte(`<osmChange><create><node id="` + strconv.Itoa(id) + `" lat="1" lon="2"/></create></osmChange>`))
+		gw.Close()
+		return buf.Bytes()
+	}
+
+	failures := 0
+	mux := http.NewServeMux()
+	mux.HandleFunc("/replication/minute/state.txt", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("sequenceNumber=5\ntimestamp=2016-07-16T06\\:28\\:02Z\n"))
+	})
+	mux.HandleFunc("/replication/minute/000/000/002.osc.gz", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(osc(2))
+	})
+	mux.HandleFunc("/replication/minute/000/000/003.osc.gz", func(w http.ResponseWriter, r *http.Request) {
+		// corrupt the first time
+		failures++
+		if failures == 1 {
+			w.Write([]byte("not gzip"))
+			return
+		}
+		w.Write(osc(3))
+	})
+	// 4 is missing
+	mux.HandleFunc("/replication/minute/000/000/005.osc.gz", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(osc(5))
+	})
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	ds := &Datasource{BaseURL: server.URL, Client: server.Client()}
+	ctx := context.Background()
+
+	dir, err := ioutil.TempDir("", "catchup")
+	if err != nil {
+		t.Fatalf("temp dir error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	store := &FileStateStore{Path: filepath.Join(dir, "state.txt")}
+
+	var (
+		seen []SeqNum
+		gaps []SeqNum
+	)
+	handler := func(n SeqNum, c *osm.Change) error {
+		if len(c.Create.Nodes) != 1 || uint64(c.Create.Nodes[0].ID) != n.Uint64() {
+			t.Errorf("incorrect change for %v: %v", n, c.Create.Nodes)
+		}
+		seen = append(seen, n)
+		return nil
+	}
+
+	m := metrics.NewPrometheus()
+	logger := &testLogger{}
+	err = ds.Catchup(ctx, MinuteSeqNum(2), handler,
+		WithStateStore(store),
+		Retries(2, time.Millisecond, time.Millisecond),
+		OnGap(func(n SeqNum) { gaps = append(gaps, n) }),
+		WithMetrics(m),
+		WithLogger(logger),
+	)
+	if err != nil {
+		t.Fatalf("catchup error: %v", err)
+	}
+
+	buf := &bytes.Buffer{}
+	m.Write(buf)
+	for _, line := range []string{
+		`osm_replication_changes_total{interval="minute"} 3`,
+		`osm_replication_sequence_number{interval="minute"} 5`,
+		`osm_replication_pending_changes{interval="minute"} 0`,
+	} {
+		if !strings.Contains(buf.String(), line+"\n") {
+			t.Errorf("missing metric: %v", line)
+		}
+	}
+
+	if !reflect.DeepEqual(seen, []SeqNum{MinuteSeqNum(2), MinuteSeqNum(3), MinuteSeqNum(5)}) {
+		t.Errorf("incorrect sequence numbers: %v", seen)
+	}
+
+	if !reflect.DeepEqual(gaps, []SeqNum{MinuteSeqNum(4)}) {
+		t.Errorf("incorrect gaps: %v", gaps)
+	}
+
+	// corrupt 3 once, missing 4 twice and the skip
+	if len(logger.warnings) != 4 ||
+		!strings.HasPrefix(logger.warnings[0], "replication: retrying request [attempt 1 backoff 1ms err ") ||
+		logger.warnings[3] != "replication: skipping missing sequence number [seqnum minute/4]" {
+		t.Errorf("incorrect warnings: %v", logger.warnings)
+	}
+
+	if n, ok, err := store.Load(ctx); err != nil || !ok || n != 5 {
+		t.Errorf("incorrect saved state: %v %v %v", n, ok, err)
+	}
+
+	// resumes from the saved state, nothing new
+	seen = nil
+	err = ds.Catchup(ctx, MinuteSeqNum(2), handler, WithStateStore(store))
+	if err != nil || len(seen) != 0 {
+		t.Errorf("should resume from saved state: %v %v", seen, err)
+	}
+
+	// gaps are errors by default
+	err = ds.Catchup(ctx, MinuteSeqNum(4), handler, Retries(0, 0, 0))
+	if err == nil {
+		t.Errorf("should return gap error")
+	}
+}
+
+type testLogger struct {
+	warnings []string
+}
+
+func (l *testLogger) Warn(msg string, keyvals ...interface{}) {
+	l.warnings = append(l.warnings, fmt.Sprintf("%s %v", msg, keyvals))
+}
+
+func TestFileStateStore(t *testing.T) {
+	ctx := context.Background()
+
+	dir, err := ioutil.TempDir("", "state")
+	if err != nil {
+		t.Fatalf("temp dir error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	s := &FileStateStore{Path: filepath.Join(dir, "state.txt")}
+	if _, ok, err := s.Load(ctx); ok || err != nil {
+		t.Errorf("missing file should not be ok: %v %v", ok, err)
+	}
+
+	if err := s.Save(ctx, 123); err != nil {
+		t.Fatalf("save error: %v", err)
+	}
+
+	if n, ok, err := s.Load(ctx); n != 123 || !ok || err != nil {
+		t.Errorf("incorrect load: %v %v %v", n, ok, err)
+	}
+}
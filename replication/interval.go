@@ -205,14 +205,17 @@ func (ds *Datasource) fetchState(ctx context.Context, n SeqNum) (*State, error)
 		return nil, err
 	}
 
-	resp, err := ds.Client.Do(req.WithContext(ctx))
+	resp, err := ds.client().Do(req.WithContext(ctx))
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != 200 {
-		return nil, fmt.Errorf("incorrect status code: %v", resp.StatusCode)
+		return nil, &UnexpectedStatusCodeError{
+			Code: resp.StatusCode,
+			URL:  url,
+		}
 	}
 
 	data, err := ioutil.ReadAll(resp.Body)
@@ -311,14 +314,17 @@ func (ds *Datasource) fetchIntervalData(ctx context.Context, url string) (*osm.C
 		return nil, err
 	}
 
-	resp, err := ds.Client.Do(req.WithContext(ctx))
+	resp, err := ds.client().Do(req.WithContext(ctx))
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != 200 {
-		return nil, fmt.Errorf("incorrect status code: %v", resp.StatusCode)
+		return nil, &UnexpectedStatusCodeError{
+			Code: resp.StatusCode,
+			URL:  url,
+		}
 	}
 
 	gzReader, err := gzip.NewReader(resp.Body)
@@ -0,0 +1,61 @@
+package osm
+
+import "math"
+
+// A RoundingMode defines how coordinates are rounded to the 7 decimal
+// places, about 1 centimeter, stored by the protocol buffer encoding.
+type RoundingMode int
+
+const (
+	// RoundHalfAwayFromZero rounds to the nearest value, with halfway
+	// values rounded away from zero. This is the default.
+	RoundHalfAwayFromZero RoundingMode = iota
+
+	// RoundHalfEven rounds to the nearest value, with halfway values
+	// rounded to the nearest even value, also known as banker's rounding.
+	RoundHalfEven
+
+	// RoundTruncate drops the extra decimals, rounding towards zero.
+	// Since the scaled value is not exact, e.g. 52.000792 * 1e7 is
+	// 520007919.99999994, this matches tools that cast the value to an
+	// integer and not the value as written.
+	RoundTruncate
+)
+
+// WithRounding sets how the coordinates are rounded by the Marshal methods.
+// Reproducing the byte identical output of other tools requires matching
+// their rounding.
+func WithRounding(mode RoundingMode) MarshalOption {
+	return func(o *marshalOptions) {
+		o.rounding = mode
+	}
+}
+
+// GeoToInt64 converts the latitude or longitude to the integer, in units of
+// 1e-7 degrees, used by the protocol buffer encoding.
+func GeoToInt64(l float64, mode RoundingMode) int64 {
+	switch mode {
+	case RoundHalfEven:
+		return int64(math.RoundToEven(l * locMultiple))
+	case RoundTruncate:
+		return int64(l * locMultiple)
+	}
+
+	// on rounding errors
+	//
+	// It is the case that 32.850314 * 10e6 = 32850313.999999996
+	// Simpily casting this as an int will truncate towards zero
+	// and result in an off by one. The true solution is to round
+	// the scaled result, like so:
+	//
+	// int64(math.Floor(stream.BaseData[i][0]*factor + 0.5))
+	//
+	// However, the code below does the same thing in this context,
+	// and is twice as fast:
+	sign := 0.5
+	if l < 0 {
+		sign = -0.5
+	}
+
+	return int64(l*locMultiple + sign)
+}
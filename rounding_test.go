@@ -0,0 +1,68 @@
+package osm
+
+import "testing"
+
+func TestGeoToInt64(t *testing.T) {
+	cases := []struct {
+		name  string
+		value float64
+		mode  RoundingMode
+		int   int64
+	}{
+		{name: "half away from zero", value: 2.5e-7, mode: RoundHalfAwayFromZero, int: 3},
+		{name: "half away from zero negative", value: -2.5e-7, mode: RoundHalfAwayFromZero, int: -3},
+		{name: "half away from zero inexact", value: 52.000792, mode: RoundHalfAwayFromZero, int: 520007920},
+		{name: "half even down", value: 2.5e-7, mode: RoundHalfEven, int: 2},
+		{name: "half even up", value: 3.5e-7, mode: RoundHalfEven, int: 4},
+		{name: "half even negative", value: -2.5e-7, mode: RoundHalfEven, int: -2},
+		{name: "half even inexact", value: 52.000792, mode: RoundHalfEven, int: 520007920},
+		{name: "truncate", value: 3.7e-7, mode: RoundTruncate, int: 3},
+		{name: "truncate negative", value: -3.7e-7, mode: RoundTruncate, int: -3},
+		{name: "truncate inexact", value: 52.000792, mode: RoundTruncate, int: 520007919},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if v := GeoToInt64(tc.value, tc.mode); v != tc.int {
+				t.Errorf("incorrect value: %v != %v", v, tc.int)
+			}
+		})
+	}
+}
+
+func TestWithRounding(t *testing.T) {
+	ns := Nodes{{ID: 1, Lat: 2.5e-7, Lon: -2.5e-7, Visible: true}}
+
+	data, err := ns.Marshal(WithRounding(RoundHalfEven))
+	if err != nil {
+		t.Fatalf("marshal error: %v", err)
+	}
+
+	nodes, err := UnmarshalNodes(data)
+	if err != nil {
+		t.Fatalf("unmarshal error: %v", err)
+	}
+
+	if v := nodes[0].Lat; v != 2e-7 {
+		t.Errorf("incorrect lat: %v", v)
+	}
+
+	if v := nodes[0].Lon; v != -2e-7 {
+		t.Errorf("incorrect lon: %v", v)
+	}
+
+	// the default rounds away from zero
+	data, err = ns.Marshal()
+	if err != nil {
+		t.Fatalf("marshal error: %v", err)
+	}
+
+	nodes, err = UnmarshalNodes(data)
+	if err != nil {
+		t.Fatalf("unmarshal error: %v", err)
+	}
+
+	if v := nodes[0].Lat; v != 3e-7 {
+		t.Errorf("incorrect lat: %v", v)
+	}
+}
@@ -0,0 +1,127 @@
+package osm
+
+import "context"
+
+// ToChannel scans the objects into the returned channel using a goroutine.
+// Once the scanner is done, or the context is canceled, the scanner error,
+// or the context error, is sent on the error channel and both channels are
+// closed. The scanner is not closed.
+//
+//	objects, errc := osm.ToChannel(ctx, scanner)
+//	for o := range objects {
+//		// do something
+//	}
+//
+//	if err := <-errc; err != nil {
+//		// scanner did not complete fully
+//	}
+func ToChannel(ctx context.Context, s Scanner) (<-chan Object, <-chan error) {
+	objects := make(chan Object)
+	errc := make(chan error, 1)
+
+	go func() {
+		defer close(errc)
+		defer close(objects)
+
+		for s.Scan() {
+			select {
+			case objects <- s.Object():
+			case <-ctx.Done():
+				errc <- ctx.Err()
+				return
+			}
+		}
+
+		errc <- s.Err()
+	}()
+
+	return objects, errc
+}
+
+// ChannelScanner implements the Scanner interface for objects
+// read from a channel.
+type ChannelScanner struct {
+	ch     <-chan Object
+	next   Object
+	closed bool
+}
+
+var _ Scanner = &ChannelScanner{}
+
+// NewChannelScanner creates a scanner reading from the channel until
+// it is closed.
+func NewChannelScanner(ch <-chan Object) *ChannelScanner {
+	return &ChannelScanner{ch: ch}
+}
+
+// Scan reads the next object from the channel. Returns false once
+// the channel is closed or the scanner has been closed.
+func (s *ChannelScanner) Scan() bool {
+	if s.closed {
+		return false
+	}
+
+	o, ok := <-s.ch
+	if !ok {
+		s.closed = true
+		return false
+	}
+
+	s.next = o
+	return true
+}
+
+// Object returns the current object.
+func (s *ChannelScanner) Object() Object {
+	return s.next
+}
+
+// Err always returns nil, errors should be handled by the sender.
+func (s *ChannelScanner) Err() error {
+	return nil
+}
+
+// Close stops the scanner. The channel is not drained.
+func (s *ChannelScanner) Close() error {
+	s.closed = true
+	return nil
+}
+
+// Scanner returns a scanner over the objects, for example the result of an
+// osmapi request, so it can be processed like the data from a dump file.
+// The objects are returned in the same order as the Objects method.
+func (o *OSM) Scanner() Scanner {
+	return &objectsScanner{objects: o.Objects()}
+}
+
+type objectsScanner struct {
+	objects Objects
+	offset  int
+	closed  bool
+}
+
+func (s *objectsScanner) Scan() bool {
+	if s.closed || s.offset >= len(s.objects) {
+		return false
+	}
+
+	s.offset++
+	return true
+}
+
+func (s *objectsScanner) Object() Object {
+	if s.offset == 0 {
+		return nil
+	}
+
+	return s.objects[s.offset-1]
+}
+
+func (s *objectsScanner) Err() error {
+	return nil
+}
+
+func (s *objectsScanner) Close() error {
+	s.closed = true
+	return nil
+}
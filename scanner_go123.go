@@ -0,0 +1,26 @@
+//go:build go1.23
+// +build go1.23
+
+package osm
+
+import "iter"
+
+// All returns an iterator over the objects in the scanner. The scanner
+// error should be checked once the iteration is complete.
+//
+//	for o := range osm.All(scanner) {
+//		// do something
+//	}
+//
+//	if err := scanner.Err(); err != nil {
+//		// scanner did not complete fully
+//	}
+func All(s Scanner) iter.Seq[Object] {
+	return func(yield func(Object) bool) {
+		for s.Scan() {
+			if !yield(s.Object()) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,32 @@
+//go:build go1.23
+// +build go1.23
+
+package osm
+
+import "testing"
+
+func TestAll(t *testing.T) {
+	s := testScannerOSM().Scanner()
+
+	count := 0
+	for o := range All(s) {
+		if o == nil {
+			t.Errorf("object should not be nil")
+		}
+		count++
+	}
+
+	if count != 5 {
+		t.Errorf("incorrect number of objects: %v", count)
+	}
+
+	// stops early
+	s = testScannerOSM().Scanner()
+	for range All(s) {
+		break
+	}
+
+	if !s.Scan() || s.Object().(*Node).ID != 2 {
+		t.Errorf("should continue where the iteration stopped")
+	}
+}
@@ -0,0 +1,107 @@
+package osm
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func testScannerOSM() *OSM {
+	return &OSM{
+		Nodes:      Nodes{{ID: 1}, {ID: 2}},
+		Ways:       Ways{{ID: 3}},
+		Relations:  Relations{{ID: 4}},
+		Changesets: Changesets{{ID: 5}},
+	}
+}
+
+func TestOSM_Scanner(t *testing.T) {
+	o := testScannerOSM()
+	s := o.Scanner()
+
+	if v := s.Object(); v != nil {
+		t.Errorf("should be nil before scan: %v", v)
+	}
+
+	var ids ObjectIDs
+	for s.Scan() {
+		ids = append(ids, s.Object().ObjectID())
+	}
+
+	if err := s.Err(); err != nil {
+		t.Errorf("should not have error: %v", err)
+	}
+
+	expected := ObjectIDs{
+		NodeID(1).ObjectID(0),
+		NodeID(2).ObjectID(0),
+		WayID(3).ObjectID(0),
+		RelationID(4).ObjectID(0),
+		ChangesetID(5).ObjectID(),
+	}
+	if !reflect.DeepEqual(ids, expected) {
+		t.Errorf("incorrect objects: %v", ids)
+	}
+
+	s = o.Scanner()
+	s.Close()
+	if s.Scan() {
+		t.Errorf("should not scan after close")
+	}
+}
+
+func TestToChannel(t *testing.T) {
+	ctx := context.Background()
+	objects, errc := ToChannel(ctx, testScannerOSM().Scanner())
+
+	s := NewChannelScanner(objects)
+	count := 0
+	for s.Scan() {
+		count++
+	}
+
+	if count != 5 {
+		t.Errorf("incorrect number of objects: %v", count)
+	}
+
+	if err := <-errc; err != nil {
+		t.Errorf("should not have error: %v", err)
+	}
+
+	if err := s.Err(); err != nil {
+		t.Errorf("should not have error: %v", err)
+	}
+}
+
+func TestToChannel_canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	objects, errc := ToChannel(ctx, testScannerOSM().Scanner())
+
+	<-objects
+	cancel()
+
+	if err := <-errc; err != context.Canceled {
+		t.Errorf("should return context error: %v", err)
+	}
+}
+
+func TestChannelScanner_Close(t *testing.T) {
+	ch := make(chan Object, 2)
+	ch <- &Node{ID: 1}
+	ch <- &Node{ID: 2}
+	close(ch)
+
+	s := NewChannelScanner(ch)
+	if !s.Scan() {
+		t.Fatalf("should scan")
+	}
+
+	if v := s.Object().(*Node).ID; v != 1 {
+		t.Errorf("incorrect object: %v", v)
+	}
+
+	s.Close()
+	if s.Scan() {
+		t.Errorf("should not scan after close")
+	}
+}
@@ -0,0 +1,102 @@
+package osm
+
+import (
+	"fmt"
+
+	"github.com/paulmach/osm/internal/osmpb"
+)
+
+// SchemaVersion is the version of the protocol buffer encoding written by
+// the Marshal methods. It is saved with the data so long lived caches can
+// still be read correctly after upgrading this package.
+//
+// Version history:
+//
+//	1 - the original encoding. Data without a version is read as version 1.
+//	    Pre-1970 node timestamps were not supported and, like before,
+//	    are read as the zero time.
+//	2 - adds the version, millisecond timestamps, the changeset discussion
+//	    and an explicit unset time so epoch and pre-1970 times round trip.
+//	3 - delta codes the way and relation update versions in a new field
+//	    to shrink the history of heavily edited elements.
+//	4 - splits large nodes and osm data into independently encoded chunks,
+//	    each its own message, to stay below the protocol buffer message
+//	    size limits, see WithChunkSize.
+//	5 - adds shared string dictionaries, see WithDictionary, so the common
+//	    keys and values are not saved with every small encoding.
+//
+// Data written by a newer version of this package, with a newer schema,
+// can not be read safely and the Unmarshal functions will return
+// a *SchemaVersionError.
+const SchemaVersion = 5
+
+// A SchemaVersionError is returned when unmarshalling protocol buffer
+// data with a schema version newer than supported by this package.
+type SchemaVersionError struct {
+	Version int
+}
+
+// Error returns a pretty string of the error.
+func (e *SchemaVersionError) Error() string {
+	return fmt.Sprintf("osm: unsupported schema version %d, max supported version is %d", e.Version, SchemaVersion)
+}
+
+func schemaVersion() *uint32 {
+	v := uint32(SchemaVersion)
+	return &v
+}
+
+// checkSchemaVersion returns an error if the version is not supported
+// and true if the data is in the original, version 1, encoding.
+func checkSchemaVersion(v uint32) (bool, error) {
+	if v > SchemaVersion {
+		return false, &SchemaVersionError{Version: int(v)}
+	}
+
+	return v <= 1, nil
+}
+
+// The upgrade functions modify the decoded version 1 data so it is read
+// correctly by the current decoding.
+
+func upgradeChangeV1(c *osmpb.Change) {
+	if c == nil {
+		return
+	}
+
+	upgradeOSMV1(c.Create)
+	upgradeOSMV1(c.Modify)
+	upgradeOSMV1(c.Delete)
+	upgradeOSMV1(c.Context)
+}
+
+func upgradeOSMV1(o *osmpb.OSM) {
+	if o == nil {
+		return
+	}
+
+	upgradeDenseNodesV1(o.DenseNodes)
+}
+
+// upgradeDenseNodesV1 resets the non-positive timestamps to unset. Version 1
+// encoded the dense node timestamps as the raw unix time and read all the
+// values before 1970, including the zero time, as the zero time.
+func upgradeDenseNodesV1(dn *osmpb.DenseNodes) {
+	if dn == nil || dn.DenseInfo == nil || len(dn.DenseInfo.Timestamps) == 0 {
+		return
+	}
+
+	timestamps := decodeInt64(dn.DenseInfo.Timestamps)
+
+	changed := false
+	for i, t := range timestamps {
+		if t <= 0 {
+			timestamps[i] = 0
+			changed = true
+		}
+	}
+
+	if changed {
+		dn.DenseInfo.Timestamps = encodeInt64(timestamps)
+	}
+}
@@ -0,0 +1,187 @@
+package osm
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/paulmach/osm/internal/osmpb"
+)
+
+func TestSchemaVersion_marshal(t *testing.T) {
+	o := &OSM{Nodes: Nodes{{ID: 1, Version: 1, Visible: true}}}
+	data, err := o.Marshal()
+	if err != nil {
+		t.Fatalf("marshal error: %v", err)
+	}
+
+	pbf := &osmpb.OSM{}
+	if err := proto.Unmarshal(data, pbf); err != nil {
+		t.Fatalf("unmarshal error: %v", err)
+	}
+
+	if v := pbf.GetSchemaVersion(); v != SchemaVersion {
+		t.Errorf("incorrect version: %v", v)
+	}
+
+	if v := pbf.GetDenseNodes().GetSchemaVersion(); v != 0 {
+		t.Errorf("version should only be set on the root: %v", v)
+	}
+}
+
+func TestSchemaVersion_newer(t *testing.T) {
+	v := uint32(SchemaVersion + 1)
+
+	cases := []struct {
+		name      string
+		message   proto.Message
+		unmarshal func([]byte) error
+	}{
+		{
+			name:    "osm",
+			message: &osmpb.OSM{SchemaVersion: &v},
+			unmarshal: func(data []byte) error {
+				_, err := UnmarshalOSM(data)
+				return err
+			},
+		},
+		{
+			name:    "change",
+			message: &osmpb.Change{SchemaVersion: &v},
+			unmarshal: func(data []byte) error {
+				_, err := UnmarshalChange(data)
+				return err
+			},
+		},
+		{
+			name:    "changeset",
+			message: &osmpb.Changeset{SchemaVersion: &v},
+			unmarshal: func(data []byte) error {
+				_, err := UnmarshalChangeset(data)
+				return err
+			},
+		},
+		{
+			name:    "nodes",
+			message: &osmpb.DenseNodes{SchemaVersion: &v},
+			unmarshal: func(data []byte) error {
+				_, err := UnmarshalNodes(data)
+				return err
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			data, err := proto.Marshal(tc.message)
+			if err != nil {
+				t.Fatalf("marshal error: %v", err)
+			}
+
+			err = tc.unmarshal(data)
+			if e, ok := err.(*SchemaVersionError); !ok || e.Version != SchemaVersion+1 {
+				t.Errorf("incorrect error: %v", err)
+			}
+		})
+	}
+}
+
+func TestSchemaVersion_legacy(t *testing.T) {
+	// version 1 wrote the raw unix time for dense nodes,
+	// including the time.Time{} unix value for unset times.
+	raw := []int64{-100, time.Time{}.Unix(), 1000}
+
+	data, err := proto.Marshal(&osmpb.DenseNodes{
+		Ids:  encodeInt64([]int64{1, 2, 3}),
+		Lats: []int64{0, 0, 0},
+		Lons: []int64{0, 0, 0},
+		DenseInfo: &osmpb.DenseInfo{
+			Visibles:   []bool{true, true, true},
+			Versions:   []int32{1, 1, 1},
+			Timestamps: encodeInt64(raw),
+		},
+	})
+	if err != nil {
+		t.Fatalf("marshal error: %v", err)
+	}
+
+	nodes, err := UnmarshalNodes(data)
+	if err != nil {
+		t.Fatalf("unmarshal error: %v", err)
+	}
+
+	if !nodes[0].Timestamp.IsZero() || !nodes[1].Timestamp.IsZero() {
+		t.Errorf("pre-1970 times should be zero: %v %v", nodes[0].Timestamp, nodes[1].Timestamp)
+	}
+
+	if v := nodes[2].Timestamp; !v.Equal(time.Unix(1000, 0)) {
+		t.Errorf("incorrect time: %v", v)
+	}
+
+	// the current version supports pre-1970 times
+	nodes[0].Timestamp = time.Unix(-100, 0).UTC()
+	data, err = nodes.Marshal()
+	if err != nil {
+		t.Fatalf("marshal error: %v", err)
+	}
+
+	nodes, err = UnmarshalNodes(data)
+	if err != nil {
+		t.Fatalf("unmarshal error: %v", err)
+	}
+
+	if v := nodes[0].Timestamp; !v.Equal(time.Unix(-100, 0)) {
+		t.Errorf("incorrect time: %v", v)
+	}
+}
+
+func TestSchemaVersion_legacyUpdates(t *testing.T) {
+	// version 2 wrote the raw update versions.
+	data, err := proto.Marshal(&osmpb.OSM{
+		Strings: []string{""},
+		Ways: []*osmpb.Way{
+			{
+				Id: 1,
+				Updates: &osmpb.DenseMembers{
+					Indexes:      encodeInt32([]int32{0, 1}),
+					Versions:     []int32{5, 3},
+					Timestamps:   encodeInt64([]int64{1000, 2000}),
+					ChangesetIds: encodeInt64([]int64{10, 11}),
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("marshal error: %v", err)
+	}
+
+	ways, err := UnmarshalWays(data)
+	if err != nil {
+		t.Fatalf("unmarshal error: %v", err)
+	}
+
+	updates := ways[0].Updates
+	if len(updates) != 2 || updates[0].Version != 5 || updates[1].Version != 3 {
+		t.Errorf("incorrect updates: %+v", updates)
+	}
+
+	// the current version delta codes them
+	encoded := marshalUpdates(updates, RoundHalfAwayFromZero)
+	if encoded.Versions != nil {
+		t.Errorf("should not write raw versions: %v", encoded.Versions)
+	}
+
+	if v := encoded.VersionDeltas; len(v) != 2 || v[0] != 5 || v[1] != -2 {
+		t.Errorf("incorrect version deltas: %v", v)
+	}
+
+	result, err := unmarshalUpdates(encoded)
+	if err != nil {
+		t.Fatalf("unmarshal error: %v", err)
+	}
+
+	if !reflect.DeepEqual(result, updates) {
+		t.Errorf("incorrect updates: %+v", result)
+	}
+}
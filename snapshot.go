@@ -0,0 +1,470 @@
+package osm
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// A SnapshotDatasource is an in memory datasource that supports concurrent
+// readers while a writer applies changes, e.g. the minutely replication
+// diffs. Readers get an immutable Snapshot, applying a change creates a new
+// one with copy-on-write, so queries never wait for an update and always see
+// a consistent state. The node, way and relation lookups and the parent
+// indexes use the latest version of each element, like InMemoryDatasource.
+type SnapshotDatasource struct {
+	mu      sync.Mutex // serializes the writers
+	current atomic.Value
+}
+
+var _ HistoryDatasourcer = &SnapshotDatasource{}
+
+// NewSnapshotDatasource builds the first snapshot from the data, which
+// can contain multiple versions of the elements. The osm object should
+// not be modified after the datasource is created.
+//
+// The visible flag of the data is only used if it is set on some elements,
+// e.g. history or api data. Extracts do not set it, their elements are not
+// deleted until a change deletes them.
+func NewSnapshotDatasource(o *OSM) *SnapshotDatasource {
+	h := &HistoryDatasource{}
+	h.add(o)
+	visibility := hasVisible(o)
+
+	w := newSnapshotWriter(&Snapshot{})
+	for id, nodes := range h.Nodes {
+		nodes.SortByIDVersion()
+		w.s.nodes.set(w.edit, uint64(id), nodes)
+		w.setDeleted(id.FeatureID(), visibility && !nodes[len(nodes)-1].Visible)
+	}
+
+	for id, ways := range h.Ways {
+		ways.SortByIDVersion()
+		w.s.ways.set(w.edit, uint64(id), ways)
+		w.setDeleted(id.FeatureID(), visibility && !ways[len(ways)-1].Visible)
+		w.indexWay(nil, ways[len(ways)-1], true)
+	}
+
+	for id, relations := range h.Relations {
+		relations.SortByIDVersion()
+		w.s.relations.set(w.edit, uint64(id), relations)
+		w.setDeleted(id.FeatureID(), visibility && !relations[len(relations)-1].Visible)
+		w.indexRelation(nil, relations[len(relations)-1], true)
+	}
+
+	ds := &SnapshotDatasource{}
+	ds.current.Store(w.s)
+
+	return ds
+}
+
+// Snapshot returns the current state. It does not change when changes
+// are applied so it should be used for all the lookups of one query.
+func (ds *SnapshotDatasource) Snapshot() *Snapshot {
+	return ds.current.Load().(*Snapshot)
+}
+
+// Apply adds the created, modified and deleted elements of the change as new
+// versions and publishes a new snapshot. Deleted elements are marked not
+// visible and removed from the parent indexes. Versions that are not newer
+// than the latest one are skipped, so a change can be applied again, e.g.
+// when replication is restarted. The elements of the change are not
+// modified, elements with a different visible flag are copied, but they
+// must not be modified after. Apply is safe to call concurrently with readers, writers are serialized.
+func (ds *SnapshotDatasource) Apply(ctx context.Context, c *Change) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	s := *ds.Snapshot()
+	w := newSnapshotWriter(&s)
+
+	for _, part := range []struct {
+		o       *OSM
+		visible bool
+	}{
+		{c.Create, true},
+		{c.Modify, true},
+		{c.Delete, false},
+	} {
+		if part.o == nil {
+			continue
+		}
+
+		for _, n := range part.o.Nodes {
+			if n.Visible != part.visible {
+				c := *n
+				c.Visible = part.visible
+				n = &c
+			}
+			w.addNode(n)
+		}
+
+		for _, way := range part.o.Ways {
+			if way.Visible != part.visible {
+				c := *way
+				c.Visible = part.visible
+				way = &c
+			}
+			w.addWay(way)
+		}
+
+		for _, r := range part.o.Relations {
+			if r.Visible != part.visible {
+				c := *r
+				c.Visible = part.visible
+				r = &c
+			}
+			w.addRelation(r)
+		}
+	}
+
+	ds.current.Store(w.s)
+	return nil
+}
+
+// Node returns the latest version of the node from the current snapshot.
+func (ds *SnapshotDatasource) Node(ctx context.Context, id NodeID) (*Node, error) {
+	return ds.Snapshot().Node(ctx, id)
+}
+
+// Way returns the latest version of the way from the current snapshot.
+func (ds *SnapshotDatasource) Way(ctx context.Context, id WayID) (*Way, error) {
+	return ds.Snapshot().Way(ctx, id)
+}
+
+// Relation returns the latest version of the relation from the current snapshot.
+func (ds *SnapshotDatasource) Relation(ctx context.Context, id RelationID) (*Relation, error) {
+	return ds.Snapshot().Relation(ctx, id)
+}
+
+// NodeHistory returns all the versions of the node from the current snapshot.
+func (ds *SnapshotDatasource) NodeHistory(ctx context.Context, id NodeID) (Nodes, error) {
+	return ds.Snapshot().NodeHistory(ctx, id)
+}
+
+// WayHistory returns all the versions of the way from the current snapshot.
+func (ds *SnapshotDatasource) WayHistory(ctx context.Context, id WayID) (Ways, error) {
+	return ds.Snapshot().WayHistory(ctx, id)
+}
+
+// RelationHistory returns all the versions of the relation from the current snapshot.
+func (ds *SnapshotDatasource) RelationHistory(ctx context.Context, id RelationID) (Relations, error) {
+	return ds.Snapshot().RelationHistory(ctx, id)
+}
+
+// NotFound returns true if the error returned is a not found error.
+func (ds *SnapshotDatasource) NotFound(err error) bool {
+	return err == errNotFound
+}
+
+// A Snapshot is an immutable state of a SnapshotDatasource. It is safe
+// for concurrent use. The returned elements and lists must not be modified.
+type Snapshot struct {
+	nodes            trie // Nodes by NodeID
+	ways             trie // Ways by WayID
+	relations        trie // Relations by RelationID
+	nodeWays         trie // Ways by NodeID
+	elementRelations trie // Relations by FeatureID
+	deleted          trie // true by FeatureID if the latest version is deleted
+}
+
+var _ HistoryDatasourcer = &Snapshot{}
+
+// Node returns the latest version of the node.
+func (s *Snapshot) Node(ctx context.Context, id NodeID) (*Node, error) {
+	nodes, _ := s.nodes.get(uint64(id)).(Nodes)
+	if len(nodes) == 0 {
+		return nil, errNotFound
+	}
+
+	return nodes[len(nodes)-1], nil
+}
+
+// Way returns the latest version of the way.
+func (s *Snapshot) Way(ctx context.Context, id WayID) (*Way, error) {
+	ways, _ := s.ways.get(uint64(id)).(Ways)
+	if len(ways) == 0 {
+		return nil, errNotFound
+	}
+
+	return ways[len(ways)-1], nil
+}
+
+// Relation returns the latest version of the relation.
+func (s *Snapshot) Relation(ctx context.Context, id RelationID) (*Relation, error) {
+	relations, _ := s.relations.get(uint64(id)).(Relations)
+	if len(relations) == 0 {
+		return nil, errNotFound
+	}
+
+	return relations[len(relations)-1], nil
+}
+
+// WaysForNode returns the ways that contain the node, sorted by id.
+// Returns an empty list if the node is not part of any ways.
+func (s *Snapshot) WaysForNode(ctx context.Context, id NodeID) (Ways, error) {
+	ways, _ := s.nodeWays.get(uint64(id)).(Ways)
+	return ways, nil
+}
+
+// RelationsForElement returns the relations that have the element as
+// a member, sorted by id. The element version is ignored.
+func (s *Snapshot) RelationsForElement(ctx context.Context, id ElementID) (Relations, error) {
+	relations, _ := s.elementRelations.get(featureKey(id.FeatureID())).(Relations)
+	return relations, nil
+}
+
+// NodeHistory returns all the versions of the node sorted by version.
+func (s *Snapshot) NodeHistory(ctx context.Context, id NodeID) (Nodes, error) {
+	if v, _ := s.nodes.get(uint64(id)).(Nodes); v != nil {
+		return v, nil
+	}
+
+	return nil, errNotFound
+}
+
+// WayHistory returns all the versions of the way sorted by version.
+func (s *Snapshot) WayHistory(ctx context.Context, id WayID) (Ways, error) {
+	if v, _ := s.ways.get(uint64(id)).(Ways); v != nil {
+		return v, nil
+	}
+
+	return nil, errNotFound
+}
+
+// RelationHistory returns all the versions of the relation sorted by version.
+func (s *Snapshot) RelationHistory(ctx context.Context, id RelationID) (Relations, error) {
+	if v, _ := s.relations.get(uint64(id)).(Relations); v != nil {
+		return v, nil
+	}
+
+	return nil, errNotFound
+}
+
+// NotFound returns true if the error returned is a not found error.
+func (s *Snapshot) NotFound(err error) bool {
+	return err == errNotFound
+}
+
+// isDeleted returns true if the latest version of the element is known to
+// be deleted. The visible flag of the latest version is not enough since
+// it is false for all the elements of an extract.
+func (s *Snapshot) isDeleted(id FeatureID) bool {
+	return s.deleted.get(featureKey(id)) != nil
+}
+
+// featureKey drops the empty version bits of the id so
+// they do not add levels to the trie.
+func featureKey(id FeatureID) uint64 {
+	return uint64(id) >> versionBits
+}
+
+// snapshotWriter builds a new snapshot, the trie nodes are copied
+// the first time they are modified.
+type snapshotWriter struct {
+	s    *Snapshot
+	edit *trieEdit
+}
+
+func newSnapshotWriter(s *Snapshot) *snapshotWriter {
+	return &snapshotWriter{s: s, edit: &trieEdit{}}
+}
+
+// The lists are appended to with a full slice expression so a new
+// array is allocated and the previous snapshots are not modified.
+
+func (w *snapshotWriter) addNode(n *Node) {
+	history, _ := w.s.nodes.get(uint64(n.ID)).(Nodes)
+	if l := len(history); l > 0 && history[l-1].Version >= n.Version {
+		return
+	}
+
+	w.s.nodes.set(w.edit, uint64(n.ID), append(history[:len(history):len(history)], n))
+	w.setDeleted(n.FeatureID(), !n.Visible)
+}
+
+func (w *snapshotWriter) addWay(way *Way) {
+	history, _ := w.s.ways.get(uint64(way.ID)).(Ways)
+
+	var latest *Way
+	if l := len(history); l > 0 {
+		latest = history[l-1]
+		if latest.Version >= way.Version {
+			return
+		}
+	}
+
+	w.s.ways.set(w.edit, uint64(way.ID), append(history[:len(history):len(history)], way))
+	w.setDeleted(way.FeatureID(), !way.Visible)
+	w.indexWay(latest, way, way.Visible)
+}
+
+func (w *snapshotWriter) addRelation(r *Relation) {
+	history, _ := w.s.relations.get(uint64(r.ID)).(Relations)
+
+	var latest *Relation
+	if l := len(history); l > 0 {
+		latest = history[l-1]
+		if latest.Version >= r.Version {
+			return
+		}
+	}
+
+	w.s.relations.set(w.edit, uint64(r.ID), append(history[:len(history):len(history)], r))
+	w.setDeleted(r.FeatureID(), !r.Visible)
+	w.indexRelation(latest, r, r.Visible)
+}
+
+func (w *snapshotWriter) setDeleted(id FeatureID, deleted bool) {
+	if deleted {
+		w.s.deleted.set(w.edit, featureKey(id), true)
+	} else {
+		w.s.deleted.delete(w.edit, featureKey(id))
+	}
+}
+
+// indexWay replaces the previous latest version of the way in the node
+// index, the new version is only added if index is true. The initial build
+// indexes all ways, like InMemoryDatasource, since extracts do not set the
+// visible flag, applied changes only the visible ones.
+func (w *snapshotWriter) indexWay(prev, way *Way, index bool) {
+	if prev != nil {
+		for _, wn := range prev.Nodes {
+			ways, _ := w.s.nodeWays.get(uint64(wn.ID)).(Ways)
+			if r := removeWay(ways, way.ID); len(r) != len(ways) {
+				w.setNodeWays(wn.ID, r)
+			}
+		}
+	}
+
+	if !index {
+		return
+	}
+
+	for _, wn := range way.Nodes {
+		ways, _ := w.s.nodeWays.get(uint64(wn.ID)).(Ways)
+
+		j := sort.Search(len(ways), func(j int) bool { return ways[j].ID >= way.ID })
+		if j < len(ways) && ways[j] == way {
+			continue // repeated node
+		}
+
+		result := make(Ways, 0, len(ways)+1)
+		result = append(result, ways[:j]...)
+		result = append(result, way)
+		result = append(result, ways[j:]...)
+		w.s.nodeWays.set(w.edit, uint64(wn.ID), result)
+	}
+}
+
+func (w *snapshotWriter) setNodeWays(id NodeID, ways Ways) {
+	if len(ways) == 0 {
+		w.s.nodeWays.delete(w.edit, uint64(id))
+		return
+	}
+
+	w.s.nodeWays.set(w.edit, uint64(id), ways)
+}
+
+// indexRelation replaces the previous latest version of the relation in
+// the member index, the new version is only added if index is true.
+func (w *snapshotWriter) indexRelation(prev, r *Relation, index bool) {
+	if prev != nil {
+		for _, m := range prev.Members {
+			fid := m.FeatureID()
+			relations, _ := w.s.elementRelations.get(featureKey(fid)).(Relations)
+			if result := removeRelation(relations, r.ID); len(result) != len(relations) {
+				w.setElementRelations(fid, result)
+			}
+		}
+	}
+
+	if !index {
+		return
+	}
+
+	for _, m := range r.Members {
+		fid := m.FeatureID()
+		relations, _ := w.s.elementRelations.get(featureKey(fid)).(Relations)
+
+		j := sort.Search(len(relations), func(j int) bool { return relations[j].ID >= r.ID })
+		if j < len(relations) && relations[j] == r {
+			continue // repeated member
+		}
+
+		result := make(Relations, 0, len(relations)+1)
+		result = append(result, relations[:j]...)
+		result = append(result, r)
+		result = append(result, relations[j:]...)
+		w.s.elementRelations.set(w.edit, featureKey(fid), result)
+	}
+}
+
+func (w *snapshotWriter) setElementRelations(id FeatureID, relations Relations) {
+	if len(relations) == 0 {
+		w.s.elementRelations.delete(w.edit, featureKey(id))
+		return
+	}
+
+	w.s.elementRelations.set(w.edit, featureKey(id), relations)
+}
+
+// removeWay returns a new list without the way, or the
+// same list if the way is not in it.
+func removeWay(ways Ways, id WayID) Ways {
+	for j, way := range ways {
+		if way.ID == id {
+			result := make(Ways, 0, len(ways)-1)
+			result = append(result, ways[:j]...)
+			return append(result, ways[j+1:]...)
+		}
+	}
+
+	return ways
+}
+
+// removeRelation returns a new list without the relation, or the
+// same list if the relation is not in it.
+func removeRelation(relations Relations, id RelationID) Relations {
+	for j, r := range relations {
+		if r.ID == id {
+			result := make(Relations, 0, len(relations)-1)
+			result = append(result, relations[:j]...)
+			return append(result, relations[j+1:]...)
+		}
+	}
+
+	return relations
+}
+
+// hasVisible returns true if any element has the visible flag set.
+func hasVisible(o *OSM) bool {
+	if o == nil {
+		return false
+	}
+
+	for _, n := range o.Nodes {
+		if n.Visible {
+			return true
+		}
+	}
+
+	for _, w := range o.Ways {
+		if w.Visible {
+			return true
+		}
+	}
+
+	for _, r := range o.Relations {
+		if r.Visible {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,207 @@
+package osm
+
+import (
+	"context"
+	"sync"
+	"testing"
+)
+
+func TestSnapshotDatasource(t *testing.T) {
+	ctx := context.Background()
+	ds := NewSnapshotDatasource(&OSM{
+		Nodes: Nodes{
+			{ID: 1, Version: 2},
+			{ID: 1, Version: 1},
+			{ID: 2, Version: 1},
+			{ID: 3, Version: 1},
+		},
+		Ways: Ways{
+			{ID: 10, Version: 1, Nodes: WayNodes{{ID: 1}, {ID: 3}}},
+			{ID: 10, Version: 2, Nodes: WayNodes{{ID: 1}, {ID: 2}, {ID: 1}}},
+			{ID: 9, Version: 1, Nodes: WayNodes{{ID: 2}, {ID: 3}}},
+		},
+		Relations: Relations{
+			{ID: 20, Version: 1, Members: Members{
+				{Type: TypeWay, Ref: 10},
+				{Type: TypeNode, Ref: 1},
+			}},
+		},
+	})
+
+	before := ds.Snapshot()
+	checkWays(t, before, 1, 10)
+	checkWays(t, before, 2, 9, 10)
+	checkWays(t, before, 3, 9)
+	checkRelations(t, before, NodeID(1).ElementID(0), 20)
+
+	if n, _ := before.Node(ctx, 1); n.Version != 2 {
+		t.Errorf("should return latest version: %v", n.Version)
+	}
+
+	err := ds.Apply(ctx, &Change{
+		Create: &OSM{
+			Nodes: Nodes{{ID: 4, Version: 1}},
+		},
+		Modify: &OSM{
+			Ways: Ways{{ID: 10, Version: 3, Nodes: WayNodes{{ID: 3}, {ID: 4}}}},
+			Relations: Relations{{ID: 20, Version: 2, Members: Members{
+				{Type: TypeWay, Ref: 9},
+			}}},
+		},
+		Delete: &OSM{
+			Ways: Ways{{ID: 9, Version: 2}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("apply error: %v", err)
+	}
+
+	// the old snapshot is unchanged
+	checkWays(t, before, 2, 9, 10)
+	checkWays(t, before, 4)
+	checkRelations(t, before, NodeID(1).ElementID(0), 20)
+	if _, err := before.Node(ctx, 4); !before.NotFound(err) {
+		t.Errorf("old snapshot should not have new node: %v", err)
+	}
+
+	after := ds.Snapshot()
+	checkWays(t, after, 1)
+	checkWays(t, after, 2)
+	checkWays(t, after, 3, 10)
+	checkWays(t, after, 4, 10)
+	checkRelations(t, after, NodeID(1).ElementID(0))
+	checkRelations(t, after, WayID(9).ElementID(0), 20)
+
+	if n, err := ds.Node(ctx, 4); err != nil || n.Version != 1 || !n.Visible {
+		t.Errorf("incorrect created node: %v %v", n, err)
+	}
+
+	if w, err := ds.Way(ctx, 9); err != nil || w.Visible {
+		t.Errorf("should be deleted: %v %v", w, err)
+	}
+
+	if ways, _ := ds.WayHistory(ctx, 10); len(ways) != 3 {
+		t.Errorf("incorrect history: %v", ways)
+	}
+
+	if ways, _ := before.WayHistory(ctx, 10); len(ways) != 2 {
+		t.Errorf("old history modified: %v", ways)
+	}
+
+	// applying again skips the versions that are not newer
+	err = ds.Apply(ctx, &Change{
+		Modify: &OSM{Ways: Ways{{ID: 10, Version: 3, Nodes: WayNodes{{ID: 1}}}}},
+	})
+	if err != nil {
+		t.Fatalf("apply error: %v", err)
+	}
+
+	checkWays(t, ds.Snapshot(), 1)
+	if ways, _ := ds.WayHistory(ctx, 10); len(ways) != 3 {
+		t.Errorf("should skip old versions: %v", ways)
+	}
+}
+
+func TestSnapshotDatasource_Apply_input(t *testing.T) {
+	ctx := context.Background()
+	ds := NewSnapshotDatasource(&OSM{
+		Nodes: Nodes{{ID: 1, Version: 1}},
+	})
+
+	n := &Node{ID: 1, Version: 2, Visible: true}
+	err := ds.Apply(ctx, &Change{Delete: &OSM{Nodes: Nodes{n}}})
+	if err != nil {
+		t.Fatalf("apply error: %v", err)
+	}
+
+	if !n.Visible {
+		t.Errorf("should not modify the change elements")
+	}
+
+	if latest, _ := ds.Node(ctx, 1); latest.Visible || latest.Version != 2 {
+		t.Errorf("should be deleted: %v", latest)
+	}
+}
+
+func TestSnapshotDatasource_concurrent(t *testing.T) {
+	ctx := context.Background()
+	ds := NewSnapshotDatasource(&OSM{
+		Nodes: Nodes{{ID: 1, Version: 1}, {ID: 2, Version: 1}},
+		Ways:  Ways{{ID: 1, Version: 1, Nodes: WayNodes{{ID: 1}, {ID: 2}}}},
+	})
+
+	done := make(chan struct{})
+	wg := sync.WaitGroup{}
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+
+				// a snapshot is consistent, the way and node versions match
+				s := ds.Snapshot()
+				w, _ := s.Way(ctx, 1)
+				n, _ := s.Node(ctx, 1)
+				if w.Version != n.Version {
+					t.Errorf("inconsistent snapshot: %v != %v", w.Version, n.Version)
+					return
+				}
+
+				if ways, _ := s.WaysForNode(ctx, 2); len(ways) != 1 || ways[0] != w {
+					t.Errorf("inconsistent index: %v", ways)
+					return
+				}
+			}
+		}()
+	}
+
+	for v := 2; v < 100; v++ {
+		err := ds.Apply(ctx, &Change{Modify: &OSM{
+			Nodes: Nodes{{ID: 1, Version: v}},
+			Ways:  Ways{{ID: 1, Version: v, Nodes: WayNodes{{ID: 1}, {ID: 2}}}},
+		}})
+		if err != nil {
+			t.Fatalf("apply error: %v", err)
+		}
+	}
+
+	close(done)
+	wg.Wait()
+}
+
+func checkWays(t testing.TB, s *Snapshot, id NodeID, expected ...WayID) {
+	t.Helper()
+
+	ways, _ := s.WaysForNode(context.Background(), id)
+	if len(ways) != len(expected) {
+		t.Errorf("node %d: incorrect ways: %v", id, ways)
+		return
+	}
+
+	for i, w := range ways {
+		if w.ID != expected[i] {
+			t.Errorf("node %d: incorrect ways: %v", id, ways)
+		}
+	}
+}
+
+func checkRelations(t testing.TB, s *Snapshot, id ElementID, expected ...RelationID) {
+	t.Helper()
+
+	relations, _ := s.RelationsForElement(context.Background(), id)
+	if len(relations) != len(expected) {
+		t.Errorf("%v: incorrect relations: %v", id, relations)
+		return
+	}
+
+	for i, r := range relations {
+		if r.ID != expected[i] {
+			t.Errorf("%v: incorrect relations: %v", id, relations)
+		}
+	}
+}
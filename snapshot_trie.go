@@ -0,0 +1,170 @@
+package osm
+
+import "math/bits"
+
+const (
+	trieBits  = 5
+	trieWidth = 1 << trieBits
+	trieMask  = trieWidth - 1
+)
+
+// A trie is a persistent hash array mapped trie keyed by id. Setting or
+// deleting a key only copies the nodes on its path, at most 13 nodes of
+// 32 entries, so applying a change to a snapshot costs the size of the
+// change and not the size of the data. The previous versions of the trie
+// are not modified and share all the other nodes.
+//
+// The ids are used as the hash, they are mostly sequential so the lower
+// bits are evenly distributed and the trie stays balanced. All 64 bits are
+// used by the deepest level so different keys never collide.
+type trie struct {
+	root *trieNode
+}
+
+// A trieEdit marks the nodes created by one writer. They are not part of
+// any published snapshot yet and can be modified in place, so building the
+// first snapshot does not copy a path for every element.
+type trieEdit struct {
+	_ byte // so every edit has a distinct address
+}
+
+type trieNode struct {
+	edit     *trieEdit
+	bitmap   uint32
+	children []interface{} // *trieNode or *trieLeaf
+}
+
+type trieLeaf struct {
+	key   uint64
+	value interface{}
+}
+
+// get returns nil if the key is not in the trie.
+func (t trie) get(key uint64) interface{} {
+	n := t.root
+	for shift := uint(0); n != nil; shift += trieBits {
+		bit := uint32(1) << ((key >> shift) & trieMask)
+		if n.bitmap&bit == 0 {
+			return nil
+		}
+
+		switch c := n.children[n.index(bit)].(type) {
+		case *trieLeaf:
+			if c.key == key {
+				return c.value
+			}
+			return nil
+		case *trieNode:
+			n = c
+		}
+	}
+
+	return nil
+}
+
+// set adds or replaces the value of the key. The nodes not owned by
+// the edit are copied.
+func (t *trie) set(edit *trieEdit, key uint64, value interface{}) {
+	if t.root == nil {
+		t.root = &trieNode{edit: edit}
+	}
+
+	t.root = t.root.set(edit, &trieLeaf{key: key, value: value}, 0)
+}
+
+// delete removes the key. The nodes not owned by the edit are copied.
+func (t *trie) delete(edit *trieEdit, key uint64) {
+	if t.root != nil {
+		t.root = t.root.delete(edit, key, 0)
+	}
+}
+
+func (n *trieNode) index(bit uint32) int {
+	return bits.OnesCount32(n.bitmap & (bit - 1))
+}
+
+// editable returns the node if it is owned by the edit, or a copy owned by it.
+func (n *trieNode) editable(edit *trieEdit) *trieNode {
+	if n.edit == edit {
+		return n
+	}
+
+	children := make([]interface{}, len(n.children), len(n.children)+1)
+	copy(children, n.children)
+
+	return &trieNode{edit: edit, bitmap: n.bitmap, children: children}
+}
+
+func (n *trieNode) set(edit *trieEdit, leaf *trieLeaf, shift uint) *trieNode {
+	n = n.editable(edit)
+
+	bit := uint32(1) << ((leaf.key >> shift) & trieMask)
+	i := n.index(bit)
+	if n.bitmap&bit == 0 {
+		n.bitmap |= bit
+		n.children = append(n.children, nil)
+		copy(n.children[i+1:], n.children[i:])
+		n.children[i] = leaf
+		return n
+	}
+
+	switch c := n.children[i].(type) {
+	case *trieLeaf:
+		if c.key == leaf.key {
+			n.children[i] = leaf
+			return n
+		}
+
+		// split the leaf into a new level
+		child := (&trieNode{edit: edit}).set(edit, c, shift+trieBits)
+		n.children[i] = child.set(edit, leaf, shift+trieBits)
+	case *trieNode:
+		n.children[i] = c.set(edit, leaf, shift+trieBits)
+	}
+
+	return n
+}
+
+// delete returns nil if the node is empty after removing the key.
+func (n *trieNode) delete(edit *trieEdit, key uint64, shift uint) *trieNode {
+	bit := uint32(1) << ((key >> shift) & trieMask)
+	if n.bitmap&bit == 0 {
+		return n
+	}
+
+	i := n.index(bit)
+	switch c := n.children[i].(type) {
+	case *trieLeaf:
+		if c.key != key {
+			return n
+		}
+
+		n = n.editable(edit)
+		n.remove(i, bit)
+	case *trieNode:
+		child := c.delete(edit, key, shift+trieBits)
+		if child == c {
+			return n
+		}
+
+		n = n.editable(edit)
+		if child == nil {
+			n.remove(i, bit)
+		} else {
+			n.children[i] = child
+		}
+	}
+
+	if n.bitmap == 0 {
+		return nil
+	}
+
+	return n
+}
+
+func (n *trieNode) remove(i int, bit uint32) {
+	n.bitmap &^= bit
+	copy(n.children[i:], n.children[i+1:])
+	n.children[len(n.children)-1] = nil
+	n.children = n.children[:len(n.children)-1]
+}
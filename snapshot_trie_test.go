@@ -0,0 +1,73 @@
+package osm
+
+import "testing"
+
+func TestTrie(t *testing.T) {
+	keys := []uint64{0, 1, 31, 32, 33, 1 << 40, 1<<40 + 32, 1<<63 | 5, 5}
+
+	var t1 trie
+	edit := &trieEdit{}
+	for i, k := range keys {
+		t1.set(edit, k, i)
+	}
+
+	for i, k := range keys {
+		if v := t1.get(k); v != i {
+			t.Errorf("incorrect value for %d: %v != %v", k, v, i)
+		}
+	}
+
+	if v := t1.get(64); v != nil {
+		t.Errorf("should not find missing key: %v", v)
+	}
+
+	// a new edit does not modify the previous version
+	t2 := t1
+	edit = &trieEdit{}
+	t2.set(edit, 1, "one")
+	t2.delete(edit, 32)
+	t2.delete(edit, 1<<40+32)
+	t2.delete(edit, 1000) // missing
+
+	if v := t1.get(1); v != 1 {
+		t.Errorf("previous version modified: %v", v)
+	}
+
+	if v := t1.get(32); v != 3 {
+		t.Errorf("previous version modified: %v", v)
+	}
+
+	if v := t2.get(1); v != "one" {
+		t.Errorf("incorrect value: %v", v)
+	}
+
+	if v := t2.get(32); v != nil {
+		t.Errorf("should be deleted: %v", v)
+	}
+
+	if v := t2.get(1 << 40); v != 5 {
+		t.Errorf("incorrect value after delete: %v", v)
+	}
+
+	for _, k := range keys {
+		t2.delete(edit, k)
+	}
+
+	if t2.root != nil {
+		t.Errorf("should be empty: %v", t2.root)
+	}
+}
+
+func TestTrie_many(t *testing.T) {
+	var tr trie
+	edit := &trieEdit{}
+	for i := uint64(0); i < 100000; i++ {
+		tr.set(edit, i*7, i)
+	}
+
+	for i := uint64(0); i < 100000; i++ {
+		if v := tr.get(i * 7); v != i {
+			t.Fatalf("incorrect value for %d: %v", i*7, v)
+		}
+	}
+}
@@ -0,0 +1,242 @@
+package osm
+
+import "fmt"
+
+// A SplitResult is the outcome of splitting a way.
+type SplitResult struct {
+	// Way is a copy of the original way with the longer part,
+	// the part with more nodes, it keeps the id and history.
+	Way *Way
+
+	// New is the other part with a new id and a copy of the tags.
+	New *Way
+
+	// Relations are copies of the relations that had the original way
+	// as a member, updated to include the new way.
+	Relations Relations
+}
+
+// SplitWay splits the way at the node, the node is the last node of one
+// part and the first node of the other. The node must be an inner node of
+// an unclosed way. The new way id is allocated from the allocator.
+//
+// The new way is added to each relation next to the original way, with the
+// same role, in traversal order so routes stay continuous. The order is
+// determined from the neighbouring members in the ways, which can be nil.
+// For turn restrictions the from and to members keep only the part that
+// connects to the via member. The inputs are not modified.
+func SplitWay(w *Way, at NodeID, a *IDAllocator, relations Relations, ways Ways) (*SplitResult, error) {
+	if len(w.Nodes) > 0 && w.Nodes[0].ID == w.Nodes[len(w.Nodes)-1].ID {
+		return nil, fmt.Errorf("osm: can not split closed way %d at a single node", w.ID)
+	}
+
+	index := -1
+	for i := 1; i < len(w.Nodes)-1; i++ {
+		if w.Nodes[i].ID == at {
+			if index != -1 {
+				return nil, fmt.Errorf("osm: node %d is in way %d more than once", at, w.ID)
+			}
+			index = i
+		}
+	}
+
+	if index == -1 {
+		return nil, fmt.Errorf("osm: node %d is not an inner node of way %d", at, w.ID)
+	}
+
+	first := append(WayNodes(nil), w.Nodes[:index+1]...)
+	second := append(WayNodes(nil), w.Nodes[index:]...)
+
+	orig := w.Copy()
+	orig.Updates = nil
+
+	n := a.NewWay(w.Tags.copy())
+
+	// the original way keeps the longer part, as editors do,
+	// so the most history stays with it.
+	newIsSecond := len(first) >= len(second)
+	if newIsSecond {
+		orig.Nodes, n.Nodes = first, second
+	} else {
+		orig.Nodes, n.Nodes = second, first
+	}
+
+	result := &SplitResult{Way: orig, New: n}
+
+	neighbors := make(map[WayID]*Way, len(ways))
+	for _, nw := range ways {
+		neighbors[nw.ID] = nw
+	}
+
+	for _, r := range relations {
+		if !relationHasWay(r, w.ID) {
+			continue
+		}
+
+		r = r.Copy()
+		r.Updates = nil
+
+		restriction := r.Tags.Find("type") == "restriction"
+
+		members := make(Members, 0, len(r.Members)+1)
+		for i, m := range r.Members {
+			if m.Type != TypeWay || m.Ref != int64(w.ID) {
+				members = append(members, m)
+				continue
+			}
+
+			nm := m
+			nm.Ref = int64(n.ID)
+			nm.Version, nm.ChangesetID = 0, 0
+			nm.Lat, nm.Lon = 0, 0
+
+			if restriction && (m.Role == "from" || m.Role == "to") {
+				if part := restrictionPart(r, first, second, neighbors); part != nil {
+					if sameWayNodes(part, orig.Nodes) {
+						members = append(members, m)
+					} else {
+						members = append(members, nm)
+					}
+
+					continue
+				}
+			}
+
+			// members are ordered first then second unless the relation
+			// traverses the way backwards.
+			if reversedMember(r.Members, i, first, second, neighbors) == newIsSecond {
+				members = append(members, nm, m)
+			} else {
+				members = append(members, m, nm)
+			}
+		}
+
+		r.Members = members
+		result.Relations = append(result.Relations, r)
+	}
+
+	return result, nil
+}
+
+// Build adds the edits of the split to the change builder. The new way is
+// created and the original way and relations are modified. Elements
+// without a version, not yet uploaded, are created.
+func (r *SplitResult) Build(b *ChangeBuilder) error {
+	if err := createOrModify(b, r.Way); err != nil {
+		return err
+	}
+
+	if err := b.Create(r.New); err != nil {
+		return err
+	}
+
+	for _, rel := range r.Relations {
+		if err := createOrModify(b, rel); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// reversedMember returns true if the relation traverses the way member at
+// the index from its last node to its first.
+func reversedMember(members Members, index int, first, second WayNodes, neighbors map[WayID]*Way) bool {
+	start := first[0].ID
+	end := second[len(second)-1].ID
+
+	if prev := neighborWay(members, index-1, neighbors); prev != nil {
+		if touches(prev, end) && !touches(prev, start) {
+			return true
+		}
+
+		if touches(prev, start) {
+			return false
+		}
+	}
+
+	if next := neighborWay(members, index+1, neighbors); next != nil {
+		if touches(next, start) && !touches(next, end) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// restrictionPart returns the part connected to the via member of the
+// restriction, nil if it can not be determined.
+func restrictionPart(r *Relation, first, second WayNodes, neighbors map[WayID]*Way) WayNodes {
+	connects := func(part WayNodes) bool {
+		ends := []NodeID{part[0].ID, part[len(part)-1].ID}
+		for _, m := range r.Members {
+			if m.Role != "via" {
+				continue
+			}
+
+			for _, id := range ends {
+				if m.Type == TypeNode && m.Ref == int64(id) {
+					return true
+				}
+
+				if m.Type == TypeWay && neighbors[WayID(m.Ref)] != nil && touches(neighbors[WayID(m.Ref)], id) {
+					return true
+				}
+			}
+		}
+
+		return false
+	}
+
+	f, s := connects(first), connects(second)
+	if f && !s {
+		return first
+	}
+
+	if s && !f {
+		return second
+	}
+
+	return nil
+}
+
+func neighborWay(members Members, index int, neighbors map[WayID]*Way) *Way {
+	if index < 0 || index >= len(members) || members[index].Type != TypeWay {
+		return nil
+	}
+
+	return neighbors[WayID(members[index].Ref)]
+}
+
+// touches returns true if the node is the first or last node of the way.
+func touches(w *Way, id NodeID) bool {
+	if len(w.Nodes) == 0 {
+		return false
+	}
+
+	return w.Nodes[0].ID == id || w.Nodes[len(w.Nodes)-1].ID == id
+}
+
+func relationHasWay(r *Relation, id WayID) bool {
+	for _, m := range r.Members {
+		if m.Type == TypeWay && m.Ref == int64(id) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func sameWayNodes(a, b WayNodes) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i].ID != b[i].ID {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,138 @@
+package osm
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitWay(t *testing.T) {
+	w := &Way{
+		ID:      10,
+		Version: 3,
+		Nodes:   WayNodes{{ID: 1}, {ID: 2}, {ID: 3}, {ID: 4}},
+		Tags:    Tags{{Key: "highway", Value: "primary"}},
+	}
+
+	ways := Ways{
+		{ID: 20, Nodes: WayNodes{{ID: 4}, {ID: 5}}},
+		{ID: 30, Nodes: WayNodes{{ID: 0}, {ID: 1}}},
+	}
+
+	relations := Relations{
+		{ID: 1, Version: 1, Tags: Tags{{Key: "type", Value: "route"}}, Members: Members{
+			{Type: TypeWay, Ref: 30},
+			{Type: TypeWay, Ref: 10, Role: "forward"},
+			{Type: TypeWay, Ref: 20},
+		}},
+		{ID: 2, Version: 1, Tags: Tags{{Key: "type", Value: "route"}}, Members: Members{
+			{Type: TypeWay, Ref: 20},
+			{Type: TypeWay, Ref: 10},
+			{Type: TypeWay, Ref: 30},
+		}},
+		{ID: 3, Version: 1, Tags: Tags{{Key: "type", Value: "restriction"}}, Members: Members{
+			{Type: TypeWay, Ref: 10, Role: "from"},
+			{Type: TypeNode, Ref: 4, Role: "via"},
+			{Type: TypeWay, Ref: 20, Role: "to"},
+		}},
+		{ID: 4, Version: 1, Members: Members{{Type: TypeWay, Ref: 20}}},
+	}
+
+	result, err := SplitWay(w, 2, NewIDAllocator(-5), relations, ways)
+	if err != nil {
+		t.Fatalf("split error: %v", err)
+	}
+
+	if ids := result.Way.Nodes.NodeIDs(); result.Way.ID != 10 || !reflect.DeepEqual(ids, []NodeID{2, 3, 4}) {
+		t.Errorf("original should keep the longer part: %v", ids)
+	}
+
+	if ids := result.New.Nodes.NodeIDs(); result.New.ID != -5 || !reflect.DeepEqual(ids, []NodeID{1, 2}) {
+		t.Errorf("incorrect new way: %v %v", result.New.ID, ids)
+	}
+
+	if result.New.Tags.Find("highway") != "primary" || &result.New.Tags[0] == &w.Tags[0] {
+		t.Errorf("should copy tags: %v", result.New.Tags)
+	}
+
+	if len(w.Nodes) != 4 {
+		t.Errorf("should not modify input way")
+	}
+
+	if len(result.Relations) != 3 {
+		t.Fatalf("incorrect number of relations: %v", len(result.Relations))
+	}
+
+	refs := func(r *Relation) []int64 {
+		var result []int64
+		for _, m := range r.Members {
+			result = append(result, m.Ref)
+		}
+		return result
+	}
+
+	// forward traversal, new first part goes before
+	if r := refs(result.Relations[0]); !reflect.DeepEqual(r, []int64{30, -5, 10, 20}) {
+		t.Errorf("incorrect forward order: %v", r)
+	}
+
+	if result.Relations[0].Members[1].Role != "forward" {
+		t.Errorf("should copy role: %v", result.Relations[0].Members[1])
+	}
+
+	// backward traversal
+	if r := refs(result.Relations[1]); !reflect.DeepEqual(r, []int64{20, 10, -5, 30}) {
+		t.Errorf("incorrect backward order: %v", r)
+	}
+
+	// restriction keeps only the part touching the via node
+	if r := refs(result.Relations[2]); !reflect.DeepEqual(r, []int64{10, 4, 20}) {
+		t.Errorf("incorrect restriction: %v", r)
+	}
+
+	b := NewChangeBuilder()
+	if err := result.Build(b); err != nil {
+		t.Fatalf("build error: %v", err)
+	}
+
+	if b.Len() != 5 {
+		t.Errorf("incorrect number of edits: %v", b.Len())
+	}
+}
+
+func TestSplitWay_errors(t *testing.T) {
+	a := &IDAllocator{}
+	cases := []struct {
+		name string
+		way  *Way
+		at   NodeID
+	}{
+		{
+			name: "closed",
+			way:  &Way{ID: 1, Nodes: WayNodes{{ID: 1}, {ID: 2}, {ID: 3}, {ID: 1}}},
+			at:   2,
+		},
+		{
+			name: "end node",
+			way:  &Way{ID: 1, Nodes: WayNodes{{ID: 1}, {ID: 2}, {ID: 3}}},
+			at:   3,
+		},
+		{
+			name: "not in way",
+			way:  &Way{ID: 1, Nodes: WayNodes{{ID: 1}, {ID: 2}, {ID: 3}}},
+			at:   4,
+		},
+		{
+			name: "repeated node",
+			way:  &Way{ID: 1, Nodes: WayNodes{{ID: 1}, {ID: 2}, {ID: 3}, {ID: 2}, {ID: 4}}},
+			at:   2,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := SplitWay(tc.way, tc.at, a, nil, nil); err == nil {
+				t.Errorf("expected error")
+			}
+		})
+	}
+}
@@ -0,0 +1,362 @@
+// Package sqlite exports osm data into a SQLite or Spatialite database file
+// for offline use.
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/paulmach/orb/encoding/wkb"
+	"github.com/paulmach/osm"
+	"github.com/paulmach/osm/osmgeojson"
+)
+
+const (
+	insertNode           = "INSERT INTO nodes (id, version, user_id, user, timestamp, changeset_id, lat, lon) VALUES (?, ?, ?, ?, ?, ?, ?, ?)"
+	insertNodeTag        = "INSERT INTO node_tags (node_id, key, value) VALUES (?, ?, ?)"
+	insertWay            = "INSERT INTO ways (id, version, user_id, user, timestamp, changeset_id) VALUES (?, ?, ?, ?, ?, ?)"
+	insertWayTag         = "INSERT INTO way_tags (way_id, key, value) VALUES (?, ?, ?)"
+	insertWayNode        = "INSERT INTO way_nodes (way_id, node_id, sequence_id) VALUES (?, ?, ?)"
+	insertRelation       = "INSERT INTO relations (id, version, user_id, user, timestamp, changeset_id) VALUES (?, ?, ?, ?, ?, ?)"
+	insertRelationTag    = "INSERT INTO relation_tags (relation_id, key, value) VALUES (?, ?, ?)"
+	insertRelationMember = "INSERT INTO relation_members (relation_id, member_type, member_id, member_role, sequence_id) VALUES (?, ?, ?, ?, ?)"
+
+	insertGeometry           = "INSERT INTO geometries (osm_type, osm_id, tags, geom, min_lon, min_lat, max_lon, max_lat) VALUES (?, ?, ?, ?, ?, ?, ?, ?)"
+	insertSpatialiteGeometry = "INSERT INTO geometries (osm_type, osm_id, tags, geom) VALUES (?, ?, ?, GeomFromWKB(?, 4326))"
+)
+
+var elementTables = []string{
+	`CREATE TABLE IF NOT EXISTS nodes (
+	id INTEGER PRIMARY KEY,
+	version INTEGER NOT NULL,
+	user_id INTEGER NOT NULL,
+	user TEXT NOT NULL,
+	timestamp TEXT NOT NULL,
+	changeset_id INTEGER NOT NULL,
+	lat REAL NOT NULL,
+	lon REAL NOT NULL
+)`,
+	`CREATE TABLE IF NOT EXISTS node_tags (
+	node_id INTEGER NOT NULL,
+	key TEXT NOT NULL,
+	value TEXT NOT NULL
+)`,
+	`CREATE TABLE IF NOT EXISTS ways (
+	id INTEGER PRIMARY KEY,
+	version INTEGER NOT NULL,
+	user_id INTEGER NOT NULL,
+	user TEXT NOT NULL,
+	timestamp TEXT NOT NULL,
+	changeset_id INTEGER NOT NULL
+)`,
+	`CREATE TABLE IF NOT EXISTS way_tags (
+	way_id INTEGER NOT NULL,
+	key TEXT NOT NULL,
+	value TEXT NOT NULL
+)`,
+	`CREATE TABLE IF NOT EXISTS way_nodes (
+	way_id INTEGER NOT NULL,
+	node_id INTEGER NOT NULL,
+	sequence_id INTEGER NOT NULL
+)`,
+	`CREATE TABLE IF NOT EXISTS relations (
+	id INTEGER PRIMARY KEY,
+	version INTEGER NOT NULL,
+	user_id INTEGER NOT NULL,
+	user TEXT NOT NULL,
+	timestamp TEXT NOT NULL,
+	changeset_id INTEGER NOT NULL
+)`,
+	`CREATE TABLE IF NOT EXISTS relation_tags (
+	relation_id INTEGER NOT NULL,
+	key TEXT NOT NULL,
+	value TEXT NOT NULL
+)`,
+	`CREATE TABLE IF NOT EXISTS relation_members (
+	relation_id INTEGER NOT NULL,
+	member_type TEXT NOT NULL,
+	member_id INTEGER NOT NULL,
+	member_role TEXT NOT NULL,
+	sequence_id INTEGER NOT NULL
+)`,
+}
+
+var elementIndexes = []string{
+	"CREATE INDEX IF NOT EXISTS node_tags_node_id ON node_tags (node_id)",
+	"CREATE INDEX IF NOT EXISTS node_tags_key_value ON node_tags (key, value)",
+	"CREATE INDEX IF NOT EXISTS way_tags_way_id ON way_tags (way_id)",
+	"CREATE INDEX IF NOT EXISTS way_tags_key_value ON way_tags (key, value)",
+	"CREATE INDEX IF NOT EXISTS way_nodes_way_id ON way_nodes (way_id, sequence_id)",
+	"CREATE INDEX IF NOT EXISTS way_nodes_node_id ON way_nodes (node_id)",
+	"CREATE INDEX IF NOT EXISTS relation_tags_relation_id ON relation_tags (relation_id)",
+	"CREATE INDEX IF NOT EXISTS relation_tags_key_value ON relation_tags (key, value)",
+	"CREATE INDEX IF NOT EXISTS relation_members_relation_id ON relation_members (relation_id, sequence_id)",
+	"CREATE INDEX IF NOT EXISTS relation_members_member ON relation_members (member_type, member_id)",
+}
+
+// Exporter writes osm elements, and optionally their geometries,
+// into a SQLite database. The database/sql driver, eg. github.com/mattn/go-sqlite3,
+// must be imported by the caller.
+type Exporter struct {
+	db *sql.DB
+
+	geometries bool
+	spatialite bool
+}
+
+// New creates an exporter for the given database.
+func New(db *sql.DB, opts ...Option) (*Exporter, error) {
+	e := &Exporter{db: db}
+	for _, opt := range opts {
+		if err := opt(e); err != nil {
+			return nil, err
+		}
+	}
+
+	return e, nil
+}
+
+// CreateTables creates the tables if they do not already exist.
+func (e *Exporter) CreateTables(ctx context.Context) error {
+	stmts := elementTables
+	if e.geometries {
+		stmts = append(stmts[:len(stmts):len(stmts)], e.geometryTable()...)
+	}
+
+	return e.exec(ctx, stmts)
+}
+
+func (e *Exporter) geometryTable() []string {
+	if e.spatialite {
+		return []string{
+			"SELECT InitSpatialMetadata(1)",
+			`CREATE TABLE IF NOT EXISTS geometries (
+	osm_type TEXT NOT NULL,
+	osm_id INTEGER NOT NULL,
+	tags TEXT NOT NULL
+)`,
+			"SELECT AddGeometryColumn('geometries', 'geom', 4326, 'GEOMETRY', 'XY')",
+		}
+	}
+
+	return []string{
+		`CREATE TABLE IF NOT EXISTS geometries (
+	osm_type TEXT NOT NULL,
+	osm_id INTEGER NOT NULL,
+	tags TEXT NOT NULL,
+	geom BLOB NOT NULL,
+	min_lon REAL NOT NULL,
+	min_lat REAL NOT NULL,
+	max_lon REAL NOT NULL,
+	max_lat REAL NOT NULL
+)`,
+	}
+}
+
+// CreateIndexes creates the lookup indexes and, for geometries, the spatial index.
+// It should be called after exporting the data since that is a lot faster.
+func (e *Exporter) CreateIndexes(ctx context.Context) error {
+	stmts := elementIndexes
+	if e.geometries {
+		stmts = append(stmts[:len(stmts):len(stmts)], "CREATE INDEX IF NOT EXISTS geometries_osm ON geometries (osm_type, osm_id)")
+		if e.spatialite {
+			stmts = append(stmts, "SELECT CreateSpatialIndex('geometries', 'geom')")
+		} else {
+			stmts = append(stmts, "CREATE INDEX IF NOT EXISTS geometries_bound ON geometries (min_lon, max_lon, min_lat, max_lat)")
+		}
+	}
+
+	return e.exec(ctx, stmts)
+}
+
+func (e *Exporter) exec(ctx context.Context, stmts []string) error {
+	for _, s := range stmts {
+		if _, err := e.db.ExecContext(ctx, s); err != nil {
+			return fmt.Errorf("sqlite: %v: %s", err, s)
+		}
+	}
+
+	return nil
+}
+
+// Export writes all the nodes, ways and relations from the scanner
+// into the tables in a single transaction.
+func (e *Exporter) Export(ctx context.Context, s osm.Scanner) error {
+	tx, err := e.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	ex := &export{ctx: ctx, tx: tx, stmts: make(map[string]*sql.Stmt)}
+	if e.geometries {
+		ex.o = &osm.OSM{}
+	}
+
+	if err := ex.run(s); err != nil {
+		ex.close()
+		tx.Rollback()
+		return err
+	}
+
+	if ex.o != nil {
+		if err := e.writeGeometries(ex); err != nil {
+			ex.close()
+			tx.Rollback()
+			return err
+		}
+	}
+
+	ex.close()
+	return tx.Commit()
+}
+
+func (e *Exporter) writeGeometries(ex *export) error {
+	fc, err := osmgeojson.Convert(ex.o,
+		osmgeojson.NoID(true),
+		osmgeojson.NoMeta(true),
+		osmgeojson.NoRelationMembership(true),
+	)
+	if err != nil {
+		return err
+	}
+
+	for _, f := range fc.Features {
+		tags, err := json.Marshal(f.Properties["tags"])
+		if err != nil {
+			return err
+		}
+
+		geom, err := wkb.Marshal(f.Geometry)
+		if err != nil {
+			return err
+		}
+
+		if e.spatialite {
+			err = ex.insert(insertSpatialiteGeometry, f.Properties["type"], f.Properties["id"], string(tags), geom)
+		} else {
+			b := f.Geometry.Bound()
+			err = ex.insert(insertGeometry, f.Properties["type"], f.Properties["id"], string(tags), geom,
+				b.Min[0], b.Min[1], b.Max[0], b.Max[1])
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+type export struct {
+	ctx   context.Context
+	tx    *sql.Tx
+	stmts map[string]*sql.Stmt
+
+	// the data needed to build geometries, if enabled
+	o *osm.OSM
+}
+
+func (ex *export) run(s osm.Scanner) error {
+	for s.Scan() {
+		if err := ex.add(s.Object()); err != nil {
+			return err
+		}
+	}
+
+	return s.Err()
+}
+
+func (ex *export) add(o osm.Object) error {
+	switch o := o.(type) {
+	case *osm.Node:
+		err := ex.insert(insertNode, int64(o.ID), o.Version, int64(o.UserID), o.User,
+			timestamp(o.Timestamp), int64(o.ChangesetID), o.Lat, o.Lon)
+		if err != nil {
+			return err
+		}
+
+		if err := ex.tags(insertNodeTag, int64(o.ID), o.Tags); err != nil {
+			return err
+		}
+	case *osm.Way:
+		err := ex.insert(insertWay, int64(o.ID), o.Version, int64(o.UserID), o.User,
+			timestamp(o.Timestamp), int64(o.ChangesetID))
+		if err != nil {
+			return err
+		}
+
+		if err := ex.tags(insertWayTag, int64(o.ID), o.Tags); err != nil {
+			return err
+		}
+
+		for i, wn := range o.Nodes {
+			if err := ex.insert(insertWayNode, int64(o.ID), int64(wn.ID), i); err != nil {
+				return err
+			}
+		}
+	case *osm.Relation:
+		err := ex.insert(insertRelation, int64(o.ID), o.Version, int64(o.UserID), o.User,
+			timestamp(o.Timestamp), int64(o.ChangesetID))
+		if err != nil {
+			return err
+		}
+
+		if err := ex.tags(insertRelationTag, int64(o.ID), o.Tags); err != nil {
+			return err
+		}
+
+		for i, m := range o.Members {
+			if err := ex.insert(insertRelationMember, int64(o.ID), string(m.Type), m.Ref, m.Role, i); err != nil {
+				return err
+			}
+		}
+	default:
+		return nil
+	}
+
+	if ex.o != nil {
+		ex.o.Append(o)
+	}
+
+	return nil
+}
+
+func (ex *export) tags(query string, id int64, tags osm.Tags) error {
+	for _, t := range tags {
+		if err := ex.insert(query, id, t.Key, t.Value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// insert executes the query, preparing the statement the first time it's used.
+func (ex *export) insert(query string, args ...interface{}) error {
+	stmt, ok := ex.stmts[query]
+	if !ok {
+		var err error
+		stmt, err = ex.tx.PrepareContext(ex.ctx, query)
+		if err != nil {
+			return err
+		}
+
+		ex.stmts[query] = stmt
+	}
+
+	_, err := stmt.ExecContext(ex.ctx, args...)
+	return err
+}
+
+func (ex *export) close() {
+	for _, stmt := range ex.stmts {
+		stmt.Close()
+	}
+}
+
+func timestamp(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
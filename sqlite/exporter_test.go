@@ -0,0 +1,160 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/encoding/wkb"
+	"github.com/paulmach/osm"
+	"github.com/paulmach/osm/internal/sqltest"
+	"github.com/paulmach/osm/osmtest"
+)
+
+func testObjects() osm.Objects {
+	ts := time.Date(2018, 1, 2, 3, 4, 5, 0, time.UTC)
+	return osm.Objects{
+		&osm.Node{ID: 1, Lat: 1, Lon: 2, Version: 1, UserID: 3, User: "user", ChangesetID: 4, Timestamp: ts,
+			Tags: osm.Tags{{Key: "amenity", Value: "cafe"}}},
+		&osm.Node{ID: 2, Lat: 1, Lon: 3, Version: 1, UserID: 3, User: "user", ChangesetID: 4, Timestamp: ts},
+		&osm.Way{ID: 5, Version: 2, UserID: 3, User: "user", ChangesetID: 4, Timestamp: ts,
+			Nodes: osm.WayNodes{{ID: 1}, {ID: 2}}, Tags: osm.Tags{{Key: "highway", Value: "primary"}}},
+		&osm.Relation{ID: 6, Version: 3, UserID: 3, User: "user", ChangesetID: 4, Timestamp: ts,
+			Members: osm.Members{{Type: osm.TypeWay, Ref: 5, Role: "outer"}}},
+	}
+}
+
+func TestExporter_Export(t *testing.T) {
+	db, rec, err := sqltest.Open()
+	if err != nil {
+		t.Fatalf("open error: %v", err)
+	}
+	defer db.Close()
+
+	e, _ := New(db)
+	if err := e.Export(context.Background(), osmtest.NewScanner(testObjects())); err != nil {
+		t.Fatalf("export error: %v", err)
+	}
+
+	if rec.Commits != 1 {
+		t.Errorf("should commit once: %d", rec.Commits)
+	}
+
+	expected := [][]driver.Value{
+		{int64(1), int64(1), int64(3), "user", "2018-01-02T03:04:05Z", int64(4), float64(1), float64(2)},
+		{int64(2), int64(1), int64(3), "user", "2018-01-02T03:04:05Z", int64(4), float64(1), float64(3)},
+	}
+	if v := rec.Rows[insertNode]; !reflect.DeepEqual(v, expected) {
+		t.Errorf("incorrect nodes: %v", v)
+	}
+
+	expected = [][]driver.Value{{int64(1), "amenity", "cafe"}}
+	if v := rec.Rows[insertNodeTag]; !reflect.DeepEqual(v, expected) {
+		t.Errorf("incorrect node tags: %v", v)
+	}
+
+	if v := rec.Rows[insertWayNode]; len(v) != 2 {
+		t.Errorf("incorrect way nodes: %v", v)
+	}
+
+	expected = [][]driver.Value{{int64(6), "way", int64(5), "outer", int64(0)}}
+	if v := rec.Rows[insertRelationMember]; !reflect.DeepEqual(v, expected) {
+		t.Errorf("incorrect relation members: %v", v)
+	}
+
+	if v := rec.Rows[insertGeometry]; len(v) != 0 {
+		t.Errorf("should not write geometries by default: %v", v)
+	}
+}
+
+func TestExporter_Export_geometries(t *testing.T) {
+	db, rec, err := sqltest.Open()
+	if err != nil {
+		t.Fatalf("open error: %v", err)
+	}
+	defer db.Close()
+
+	e, _ := New(db, Geometries(true))
+	if err := e.Export(context.Background(), osmtest.NewScanner(testObjects())); err != nil {
+		t.Fatalf("export error: %v", err)
+	}
+
+	rows := rec.Rows[insertGeometry]
+	if len(rows) != 2 {
+		t.Fatalf("incorrect number of geometries: %v", len(rows))
+	}
+
+	// way first, then the interesting node
+	row := rows[0]
+	if row[0] != "way" || row[1] != int64(5) || row[2] != `{"highway":"primary"}` {
+		t.Errorf("incorrect way row: %v", row)
+	}
+
+	geom, err := wkb.Unmarshal(row[3].([]byte))
+	if err != nil {
+		t.Fatalf("wkb error: %v", err)
+	}
+
+	if !orb.Equal(geom, orb.LineString{{2, 1}, {3, 1}}) {
+		t.Errorf("incorrect geometry: %v", geom)
+	}
+
+	if !reflect.DeepEqual(row[4:], []driver.Value{float64(2), float64(1), float64(3), float64(1)}) {
+		t.Errorf("incorrect bound: %v", row[4:])
+	}
+
+	if rows[1][0] != "node" || rows[1][1] != int64(1) {
+		t.Errorf("incorrect node row: %v", rows[1])
+	}
+}
+
+func TestExporter_Export_error(t *testing.T) {
+	db, rec, err := sqltest.Open()
+	if err != nil {
+		t.Fatalf("open error: %v", err)
+	}
+	defer db.Close()
+
+	scanner := osmtest.NewScanner(testObjects())
+	scanner.ScanError = errors.New("scan error")
+
+	e, _ := New(db)
+	if err := e.Export(context.Background(), scanner); err == nil {
+		t.Errorf("should return scan error")
+	}
+
+	if rec.Commits != 0 || rec.Rollbacks != 1 {
+		t.Errorf("should rollback: %d %d", rec.Commits, rec.Rollbacks)
+	}
+}
+
+func TestExporter_CreateTables(t *testing.T) {
+	db, rec, err := sqltest.Open()
+	if err != nil {
+		t.Fatalf("open error: %v", err)
+	}
+	defer db.Close()
+
+	e, _ := New(db, Geometries(true), Spatialite(true))
+	if err := e.CreateTables(context.Background()); err != nil {
+		t.Fatalf("create tables error: %v", err)
+	}
+
+	if err := e.CreateIndexes(context.Background()); err != nil {
+		t.Fatalf("create indexes error: %v", err)
+	}
+
+	last := rec.Execs[len(rec.Execs)-1]
+	if !strings.Contains(last, "CreateSpatialIndex") {
+		t.Errorf("should create spatial index: %v", last)
+	}
+
+	if len(rec.Execs) != len(elementTables)+3+len(elementIndexes)+2 {
+		t.Errorf("incorrect number of statements: %v", len(rec.Execs))
+	}
+}
@@ -0,0 +1,25 @@
+package sqlite
+
+// An Option is a setting for exporting the data.
+type Option func(*Exporter) error
+
+// Geometries will assemble the geometries of the data, using the osmgeojson
+// package, and write them to a `geometries` table. This requires keeping all
+// the data in memory so it should only be used for extracts.
+func Geometries(yes bool) Option {
+	return func(e *Exporter) error {
+		e.geometries = yes
+		return nil
+	}
+}
+
+// Spatialite will use Spatialite geometry columns and spatial indexes for the
+// assembled geometries. The database connection must have the spatialite
+// extension loaded. Without this option geometries are stored as WKB blobs
+// along with their bounding boxes.
+func Spatialite(yes bool) Option {
+	return func(e *Exporter) error {
+		e.spatialite = yes
+		return nil
+	}
+}
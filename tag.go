@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"errors"
 	"sort"
+	"strings"
 	"sync"
 )
 
@@ -23,12 +24,76 @@ var UninterestingTags = map[string]bool{
 	"tiger:upload_uuid": true,
 }
 
+// UninterestingTagPrefixes are key prefixes, such as "tiger:", where all the
+// matching keys are considered uninteresting. Like UninterestingTags this
+// can be modified to change the behavior of the helpers below.
+var UninterestingTagPrefixes = []string{
+	"tiger:",
+}
+
+// IsUninteresting returns true if the key is in the UninterestingTags set
+// or starts with one of the UninterestingTagPrefixes.
+func IsUninteresting(key string) bool {
+	if UninterestingTags[key] {
+		return true
+	}
+
+	for _, p := range UninterestingTagPrefixes {
+		if strings.HasPrefix(key, p) {
+			return true
+		}
+	}
+
+	return false
+}
+
 // Tag is a key+value item attached to osm nodes, ways and relations.
 type Tag struct {
 	Key   string `xml:"k,attr"`
 	Value string `xml:"v,attr"`
 }
 
+// Values splits a multi-value tag, eg. cuisine=pizza;burger, on semicolons.
+// Following the osm convention a double semicolon is an escaped semicolon
+// and is part of the value. Whitespace around the values is removed
+// and empty values are skipped.
+func (t Tag) Values() []string {
+	if t.Value == "" {
+		return nil
+	}
+
+	var (
+		result  []string
+		current strings.Builder
+	)
+
+	flush := func() {
+		if v := strings.TrimSpace(current.String()); v != "" {
+			result = append(result, v)
+		}
+		current.Reset()
+	}
+
+	v := t.Value
+	for i := 0; i < len(v); i++ {
+		if v[i] != ';' {
+			current.WriteByte(v[i])
+			continue
+		}
+
+		if i+1 < len(v) && v[i+1] == ';' {
+			current.WriteByte(';')
+			i++
+			continue
+		}
+
+		flush()
+	}
+	flush()
+
+	return result
+}
+
 // Tags is a collection of Tag objects with some helper functions.
 type Tags []Tag
 
@@ -44,6 +109,56 @@ func (ts Tags) Find(k string) string {
 	return ""
 }
 
+// AllValues returns the semicolon separated values for the key.
+// If the key is repeated the values of all the tags are returned.
+// Will return nil if not found.
+func (ts Tags) AllValues(k string) []string {
+	var result []string
+	for _, t := range ts {
+		if t.Key == k {
+			result = append(result, t.Values()...)
+		}
+	}
+
+	return result
+}
+
+// Name returns the best name for the given language preferences, eg. "de", "en".
+// The name:xx tags are checked in order followed by int_name and finally name.
+// Will return an empty string if there is no name.
+func (ts Tags) Name(languagePreferences ...string) string {
+	for _, lang := range languagePreferences {
+		if v := ts.Find("name:" + lang); v != "" {
+			return v
+		}
+	}
+
+	if len(languagePreferences) > 0 {
+		if v := ts.Find("int_name"); v != "" {
+			return v
+		}
+	}
+
+	return ts.Find("name")
+}
+
+// Names returns all the name:xx localizations as a map from the
+// language code, the xx part, to the name.
+func (ts Tags) Names() map[string]string {
+	result := make(map[string]string)
+	for _, t := range ts {
+		if !strings.HasPrefix(t.Key, "name:") || t.Value == "" {
+			continue
+		}
+
+		if lang := t.Key[len("name:"):]; lang != "" {
+			result[lang] = t.Value
+		}
+	}
+
+	return result
+}
+
 // Map returns the tags as a key/value map.
 func (ts Tags) Map() map[string]string {
 	result := make(map[string]string, len(ts))
@@ -54,10 +169,34 @@ func (ts Tags) Map() map[string]string {
 	return result
 }
 
+// HasTag returns a filter matching tag maps, see Tags.Map, with the given
+// key. If values are provided the tag value must be one of them.
+// It can be used as the layer filter of the gpkg and osmmvt packages.
+func HasTag(key string, values ...string) func(map[string]string) bool {
+	return func(tags map[string]string) bool {
+		v, ok := tags[key]
+		if !ok {
+			return false
+		}
+
+		if len(values) == 0 {
+			return true
+		}
+
+		for _, val := range values {
+			if v == val {
+				return true
+			}
+		}
+
+		return false
+	}
+}
+
 // AnyInteresting will return true if there is at last one interesting tag.
 func (ts Tags) AnyInteresting() bool {
 	for _, t := range ts {
-		if !UninterestingTags[t.Key] {
+		if !IsUninteresting(t.Key) {
 			return true
 		}
 	}
@@ -90,6 +229,42 @@ func (ts *Tags) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+// A TagChange is a tag whose value is different between two sets of tags.
+type TagChange struct {
+	Key  string
+	From string
+	To   string
+}
+
+// Diff compares the tags to a newer set of tags. Added are the tags only in
+// the other set, removed are the tags only in this set and changed are the
+// keys present in both with different values. The results are sorted by key.
+func (ts Tags) Diff(other Tags) (added, removed Tags, changed []TagChange) {
+	from := ts.Map()
+	to := other.Map()
+
+	for k, v := range to {
+		old, ok := from[k]
+		if !ok {
+			added = append(added, Tag{Key: k, Value: v})
+		} else if old != v {
+			changed = append(changed, TagChange{Key: k, From: old, To: v})
+		}
+	}
+
+	for k, v := range from {
+		if _, ok := to[k]; !ok {
+			removed = append(removed, Tag{Key: k, Value: v})
+		}
+	}
+
+	added.SortByKeyValue()
+	removed.SortByKeyValue()
+	sort.Slice(changed, func(i, j int) bool { return changed[i].Key < changed[j].Key })
+
+	return added, removed, changed
+}
+
 type tagsSort Tags
 
 // SortByKeyValue will do an inplace sort of the tags.
@@ -107,7 +282,7 @@ func (ts tagsSort) Less(i, j int) bool {
 }
 
 func (ts Tags) keyValues(ss *stringSet) (keys, values []uint32) {
-	for _, t := range ts {
+	for _, t := range ss.tags(ts) {
 		keys = append(keys, ss.Add(t.Key))
 		values = append(values, ss.Add(t.Value))
 	}
@@ -145,17 +320,37 @@ type stringSet struct {
 	lk     sync.Mutex
 	values []string
 	Set    map[string]uint32
+
+	// sortTags is set for deterministic output, see WithDeterministic.
+	sortTags bool
+
+	// dict strings are not added, the values come after them.
+	dict *Dictionary
 }
 
 func (ss *stringSet) Add(s string) uint32 {
+	if ss.dict != nil {
+		if i, ok := ss.dict.index[s]; ok {
+			return i
+		}
+	}
+
 	ss.lk.Lock()
 	defer ss.lk.Unlock()
 
+	offset := uint32(0)
+	if ss.dict != nil {
+		// zero id is reserved by the dictionary
+		offset = uint32(len(ss.dict.strings))
+	}
+
 	if ss.Set == nil {
 		ss.Set = make(map[string]uint32)
 
-		// zero id is reserved as null for dense nodes packing
-		ss.values = make([]string, 1, 100)
+		if ss.dict == nil {
+			// zero id is reserved as null for dense nodes packing
+			ss.values = make([]string, 1, 100)
+		}
 	}
 
 	if i, ok := ss.Set[s]; ok {
@@ -163,7 +358,7 @@ func (ss *stringSet) Add(s string) uint32 {
 	}
 
 	ss.values = append(ss.values, s)
-	ss.Set[s] = uint32(len(ss.values)) - 1
+	ss.Set[s] = offset + uint32(len(ss.values)) - 1
 
 	return ss.Set[s]
 }
@@ -32,6 +32,13 @@ func TestTags_AnyInteresting(t *testing.T) {
 			},
 			interesting: false,
 		},
+		{
+			name: "tiger prefix",
+			tags: Tags{
+				{Key: "tiger:cfcc", Value: "A41"},
+			},
+			interesting: false,
+		},
 	}
 
 	for _, tc := range cases {
@@ -117,3 +124,199 @@ func TestTags_SortByKeyValue(t *testing.T) {
 		t.Errorf("incorrect sort got %v", v)
 	}
 }
+
+func TestTags_Diff(t *testing.T) {
+	from := Tags{
+		{Key: "highway", Value: "residential"},
+		{Key: "name", Value: "Main St"},
+		{Key: "source", Value: "Bing"},
+	}
+
+	to := Tags{
+		{Key: "surface", Value: "asphalt"},
+		{Key: "name", Value: "Main Street"},
+		{Key: "highway", Value: "residential"},
+		{Key: "lanes", Value: "2"},
+	}
+
+	added, removed, changed := from.Diff(to)
+
+	expAdded := Tags{{Key: "lanes", Value: "2"}, {Key: "surface", Value: "asphalt"}}
+	if !reflect.DeepEqual(added, expAdded) {
+		t.Errorf("incorrect added: %v", added)
+	}
+
+	expRemoved := Tags{{Key: "source", Value: "Bing"}}
+	if !reflect.DeepEqual(removed, expRemoved) {
+		t.Errorf("incorrect removed: %v", removed)
+	}
+
+	expChanged := []TagChange{{Key: "name", From: "Main St", To: "Main Street"}}
+	if !reflect.DeepEqual(changed, expChanged) {
+		t.Errorf("incorrect changed: %v", changed)
+	}
+
+	added, removed, changed = from.Diff(from)
+	if added != nil || removed != nil || changed != nil {
+		t.Errorf("expected no differences: %v %v %v", added, removed, changed)
+	}
+}
+
+func TestTag_Values(t *testing.T) {
+	cases := []struct {
+		name   string
+		value  string
+		values []string
+	}{
+		{
+			name:   "empty",
+			value:  "",
+			values: nil,
+		},
+		{
+			name:   "single",
+			value:  "pizza",
+			values: []string{"pizza"},
+		},
+		{
+			name:   "multiple",
+			value:  "pizza; burger ;kebab",
+			values: []string{"pizza", "burger", "kebab"},
+		},
+		{
+			name:   "empty values",
+			value:  ";pizza;;;burger;",
+			values: []string{"pizza;", "burger"},
+		},
+		{
+			name:   "escaped semicolon",
+			value:  "A;;B;C",
+			values: []string{"A;B", "C"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			v := Tag{Key: "cuisine", Value: tc.value}.Values()
+			if !reflect.DeepEqual(v, tc.values) {
+				t.Errorf("incorrect values: %q != %q", v, tc.values)
+			}
+		})
+	}
+}
+
+func TestTags_AllValues(t *testing.T) {
+	tags := Tags{
+		{Key: "ref", Value: "A1;E15"},
+		{Key: "name", Value: "Main"},
+		{Key: "ref", Value: "M4"},
+	}
+
+	v := tags.AllValues("ref")
+	if !reflect.DeepEqual(v, []string{"A1", "E15", "M4"}) {
+		t.Errorf("incorrect values: %v", v)
+	}
+
+	if v := tags.AllValues("cuisine"); v != nil {
+		t.Errorf("expected nil: %v", v)
+	}
+}
+
+func TestTags_Name(t *testing.T) {
+	tags := Tags{
+		{Key: "name", Value: "München"},
+		{Key: "name:en", Value: "Munich"},
+		{Key: "name:it", Value: "Monaco di Baviera"},
+		{Key: "int_name", Value: "Muenchen"},
+	}
+
+	cases := []struct {
+		name  string
+		tags  Tags
+		langs []string
+		value string
+	}{
+		{
+			name:  "no preferences",
+			tags:  tags,
+			value: "München",
+		},
+		{
+			name:  "first preference",
+			tags:  tags,
+			langs: []string{"en", "it"},
+			value: "Munich",
+		},
+		{
+			name:  "second preference",
+			tags:  tags,
+			langs: []string{"fr", "it"},
+			value: "Monaco di Baviera",
+		},
+		{
+			name:  "int_name fallback",
+			tags:  tags,
+			langs: []string{"fr"},
+			value: "Muenchen",
+		},
+		{
+			name:  "name fallback",
+			tags:  Tags{{Key: "name", Value: "München"}},
+			langs: []string{"fr"},
+			value: "München",
+		},
+		{
+			name:  "no name",
+			tags:  Tags{{Key: "highway", Value: "primary"}},
+			value: "",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			v := tc.tags.Name(tc.langs...)
+			if v != tc.value {
+				t.Errorf("incorrect name: %v != %v", v, tc.value)
+			}
+		})
+	}
+}
+
+func TestTags_Names(t *testing.T) {
+	tags := Tags{
+		{Key: "name", Value: "München"},
+		{Key: "name:en", Value: "Munich"},
+		{Key: "name:zh-Hant", Value: "慕尼黑"},
+		{Key: "name:", Value: "bad"},
+		{Key: "old_name:en", Value: "Old"},
+	}
+
+	expected := map[string]string{
+		"en":      "Munich",
+		"zh-Hant": "慕尼黑",
+	}
+
+	if v := tags.Names(); !reflect.DeepEqual(v, expected) {
+		t.Errorf("incorrect names: %v", v)
+	}
+}
+
+func TestHasTag(t *testing.T) {
+	tags := map[string]string{"highway": "primary"}
+
+	if !HasTag("highway")(tags) {
+		t.Errorf("should match key")
+	}
+
+	if !HasTag("highway", "secondary", "primary")(tags) {
+		t.Errorf("should match value")
+	}
+
+	if HasTag("highway", "secondary")(tags) {
+		t.Errorf("should not match value")
+	}
+
+	if HasTag("building")(tags) {
+		t.Errorf("should not match key")
+	}
+}
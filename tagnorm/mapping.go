@@ -0,0 +1,113 @@
+package tagnorm
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/paulmach/osm"
+)
+
+// Any is used as the value of a rule to match, or keep, any value.
+// For example the rule color=* -> colour=* renames the key.
+const Any = "*"
+
+// A Rule replaces the From tag with the To tags.
+type Rule struct {
+	From osm.Tag
+	To   osm.Tags
+}
+
+// String returns the rule in the key=value -> key=value;key=value format.
+func (r Rule) String() string {
+	to := make([]string, 0, len(r.To))
+	for _, t := range r.To {
+		to = append(to, t.Key+"="+t.Value)
+	}
+
+	return r.From.Key + "=" + r.From.Value + " -> " + strings.Join(to, ";")
+}
+
+// A Mapping is a set of rules. The rules are checked in order
+// and only the first rule matching a tag is applied.
+type Mapping []Rule
+
+// ReadMapping reads a mapping in the json format:
+//
+//	[
+//		{"from": "highway=ford", "to": ["ford=yes"]},
+//		{"from": "color=*", "to": ["colour=*"]}
+//	]
+func ReadMapping(r io.Reader) (Mapping, error) {
+	var raw []struct {
+		From string   `json:"from"`
+		To   []string `json:"to"`
+	}
+
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		return nil, fmt.Errorf("tagnorm: invalid mapping: %v", err)
+	}
+
+	m := make(Mapping, 0, len(raw))
+	for _, rr := range raw {
+		from, err := parseTag(rr.From)
+		if err != nil {
+			return nil, err
+		}
+
+		rule := Rule{From: from}
+		for _, s := range rr.To {
+			t, err := parseTag(s)
+			if err != nil {
+				return nil, err
+			}
+
+			if t.Value == Any && from.Value != Any {
+				return nil, fmt.Errorf("tagnorm: rule %s can not keep the value of %s", s, rr.From)
+			}
+
+			rule.To = append(rule.To, t)
+		}
+
+		m = append(m, rule)
+	}
+
+	return m, nil
+}
+
+func parseTag(s string) (osm.Tag, error) {
+	i := strings.Index(s, "=")
+	if i <= 0 || i == len(s)-1 {
+		return osm.Tag{}, fmt.Errorf("tagnorm: invalid tag %q, must be key=value", s)
+	}
+
+	return osm.Tag{Key: s[:i], Value: s[i+1:]}, nil
+}
+
+// DefaultMapping is a list of common deprecated tags and their modern
+// equivalents, taken from the Deprecated features page on the osm wiki.
+var DefaultMapping = Mapping{
+	{From: osm.Tag{Key: "amenity", Value: "emergency_phone"}, To: osm.Tags{{Key: "emergency", Value: "phone"}}},
+	{From: osm.Tag{Key: "amenity", Value: "fire_hydrant"}, To: osm.Tags{{Key: "emergency", Value: "fire_hydrant"}}},
+	{From: osm.Tag{Key: "amenity", Value: "public_building"}, To: osm.Tags{{Key: "building", Value: "public"}}},
+	{From: osm.Tag{Key: "amenity", Value: "register_office"}, To: osm.Tags{{Key: "office", Value: "government"}, {Key: "government", Value: "register_office"}}},
+	{From: osm.Tag{Key: "barrier", Value: "wire_fence"}, To: osm.Tags{{Key: "barrier", Value: "fence"}, {Key: "fence_type", Value: "wire"}}},
+	{From: osm.Tag{Key: "barrier", Value: "wood_fence"}, To: osm.Tags{{Key: "barrier", Value: "fence"}, {Key: "fence_type", Value: "wood"}}},
+	{From: osm.Tag{Key: "building", Value: "entrance"}, To: osm.Tags{{Key: "entrance", Value: "yes"}}},
+	{From: osm.Tag{Key: "highway", Value: "ford"}, To: osm.Tags{{Key: "ford", Value: "yes"}}},
+	{From: osm.Tag{Key: "highway", Value: "stile"}, To: osm.Tags{{Key: "barrier", Value: "stile"}}},
+	{From: osm.Tag{Key: "highway", Value: "unsurfaced"}, To: osm.Tags{{Key: "highway", Value: "road"}, {Key: "surface", Value: "unpaved"}}},
+	{From: osm.Tag{Key: "landuse", Value: "farm"}, To: osm.Tags{{Key: "landuse", Value: "farmland"}}},
+	{From: osm.Tag{Key: "leisure", Value: "video_arcade"}, To: osm.Tags{{Key: "leisure", Value: "amusement_arcade"}}},
+	{From: osm.Tag{Key: "man_made", Value: "well"}, To: osm.Tags{{Key: "man_made", Value: "water_well"}}},
+	{From: osm.Tag{Key: "natural", Value: "marsh"}, To: osm.Tags{{Key: "natural", Value: "wetland"}, {Key: "wetland", Value: "marsh"}}},
+	{From: osm.Tag{Key: "power", Value: "sub_station"}, To: osm.Tags{{Key: "power", Value: "substation"}}},
+	{From: osm.Tag{Key: "shop", Value: "antique"}, To: osm.Tags{{Key: "shop", Value: "antiques"}}},
+	{From: osm.Tag{Key: "shop", Value: "betting"}, To: osm.Tags{{Key: "shop", Value: "bookmaker"}}},
+	{From: osm.Tag{Key: "shop", Value: "fishmonger"}, To: osm.Tags{{Key: "shop", Value: "seafood"}}},
+	{From: osm.Tag{Key: "shop", Value: "organic"}, To: osm.Tags{{Key: "shop", Value: "supermarket"}, {Key: "organic", Value: "only"}}},
+	{From: osm.Tag{Key: "sport", Value: "gaelic_football"}, To: osm.Tags{{Key: "sport", Value: "gaelic_games"}}},
+	{From: osm.Tag{Key: "color", Value: Any}, To: osm.Tags{{Key: "colour", Value: Any}}},
+	{From: osm.Tag{Key: "name_1", Value: Any}, To: osm.Tags{{Key: "alt_name", Value: Any}}},
+}
@@ -0,0 +1,174 @@
+// Package tagnorm normalizes deprecated tags to their modern equivalents,
+// for example highway=ford to ford=yes.
+package tagnorm
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/paulmach/osm"
+)
+
+// A Rewrite is a rule applied to an element.
+type Rewrite struct {
+	ElementID osm.ElementID
+	Rule      Rule
+}
+
+// A ReportEntry is the number of times a rule was applied.
+type ReportEntry struct {
+	Rule  Rule
+	Count int
+}
+
+// Normalizer applies a mapping to the tags of elements and keeps
+// a count of the rules applied. It is safe for concurrent use.
+type Normalizer struct {
+	mapping   Mapping
+	onRewrite func(Rewrite)
+
+	lk     sync.Mutex
+	counts []int
+}
+
+// New creates a normalizer using the DefaultMapping
+// unless one is provided as an option.
+func New(opts ...Option) (*Normalizer, error) {
+	n := &Normalizer{
+		mapping: DefaultMapping,
+	}
+
+	for _, opt := range opts {
+		if err := opt(n); err != nil {
+			return nil, err
+		}
+	}
+
+	n.counts = make([]int, len(n.mapping))
+	return n, nil
+}
+
+// Normalize returns the normalized tags and the rules that were applied.
+// The input tags are not modified. A rule does not overwrite the value
+// of an existing tag, so highway=ford + ford=stepping_stones results in
+// just ford=stepping_stones.
+func (n *Normalizer) Normalize(tags osm.Tags) (osm.Tags, []Rule) {
+	var (
+		result  osm.Tags
+		applied []Rule
+	)
+
+	for i, t := range tags {
+		r := n.match(t)
+		if r < 0 {
+			if result != nil {
+				result = append(result, t)
+			}
+			continue
+		}
+
+		if result == nil {
+			result = make(osm.Tags, i, len(tags)+1)
+			copy(result, tags[:i])
+		}
+
+		rule := n.mapping[r]
+		applied = append(applied, rule)
+
+		n.lk.Lock()
+		n.counts[r]++
+		n.lk.Unlock()
+
+		for _, to := range rule.To {
+			if to.Value == Any {
+				to.Value = t.Value
+			}
+
+			if !hasKey(tags, i, to.Key) && result.Find(to.Key) == "" {
+				result = append(result, to)
+			}
+		}
+	}
+
+	if result == nil {
+		return tags, nil
+	}
+
+	return result, applied
+}
+
+// hasKey returns true if the key is in the tags, ignoring the tag
+// at the skip index that is being replaced.
+func hasKey(tags osm.Tags, skip int, key string) bool {
+	for i, t := range tags {
+		if i != skip && t.Key == key {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (n *Normalizer) match(t osm.Tag) int {
+	for i, r := range n.mapping {
+		if r.From.Key == t.Key && (r.From.Value == Any || r.From.Value == t.Value) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// Transform normalizes the tags of nodes, ways and relations. The tags
+// are replaced with a new slice if modified. It matches the
+// pipeline.TransformFunc signature so it can be used as:
+//
+//	pipeline.New(ctx, scanner).Transform(n.Transform)
+func (n *Normalizer) Transform(o osm.Object) (osm.Object, error) {
+	var tags *osm.Tags
+	switch e := o.(type) {
+	case *osm.Node:
+		tags = &e.Tags
+	case *osm.Way:
+		tags = &e.Tags
+	case *osm.Relation:
+		tags = &e.Tags
+	default:
+		return o, nil
+	}
+
+	result, applied := n.Normalize(*tags)
+	if len(applied) == 0 {
+		return o, nil
+	}
+
+	*tags = result
+	if n.onRewrite != nil {
+		id := o.(osm.Element).ElementID()
+		for _, r := range applied {
+			n.onRewrite(Rewrite{ElementID: id, Rule: r})
+		}
+	}
+
+	return o, nil
+}
+
+// Report returns the rules that have been applied and the number of
+// times, sorted by most applied first.
+func (n *Normalizer) Report() []ReportEntry {
+	n.lk.Lock()
+	defer n.lk.Unlock()
+
+	var result []ReportEntry
+	for i, c := range n.counts {
+		if c > 0 {
+			result = append(result, ReportEntry{Rule: n.mapping[i], Count: c})
+		}
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Count > result[j].Count
+	})
+
+	return result
+}
@@ -0,0 +1,178 @@
+package tagnorm
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/paulmach/osm"
+)
+
+func TestNormalizer_Normalize(t *testing.T) {
+	n, err := New()
+	if err != nil {
+		t.Fatalf("new error: %v", err)
+	}
+
+	cases := []struct {
+		name     string
+		tags     osm.Tags
+		expected osm.Tags
+		applied  int
+	}{
+		{
+			name:     "no changes",
+			tags:     osm.Tags{{Key: "highway", Value: "primary"}},
+			expected: osm.Tags{{Key: "highway", Value: "primary"}},
+		},
+		{
+			name: "replace",
+			tags: osm.Tags{
+				{Key: "name", Value: "Main"},
+				{Key: "highway", Value: "ford"},
+			},
+			expected: osm.Tags{
+				{Key: "name", Value: "Main"},
+				{Key: "ford", Value: "yes"},
+			},
+			applied: 1,
+		},
+		{
+			name: "keep existing value",
+			tags: osm.Tags{
+				{Key: "highway", Value: "ford"},
+				{Key: "ford", Value: "stepping_stones"},
+			},
+			expected: osm.Tags{
+				{Key: "ford", Value: "stepping_stones"},
+			},
+			applied: 1,
+		},
+		{
+			name: "multiple tags",
+			tags: osm.Tags{
+				{Key: "shop", Value: "organic"},
+			},
+			expected: osm.Tags{
+				{Key: "shop", Value: "supermarket"},
+				{Key: "organic", Value: "only"},
+			},
+			applied: 1,
+		},
+		{
+			name:     "rename key",
+			tags:     osm.Tags{{Key: "color", Value: "red"}},
+			expected: osm.Tags{{Key: "colour", Value: "red"}},
+			applied:  1,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, applied := n.Normalize(tc.tags)
+			if !reflect.DeepEqual(result, tc.expected) {
+				t.Errorf("incorrect tags: %v", result)
+			}
+
+			if len(applied) != tc.applied {
+				t.Errorf("incorrect applied: %v", applied)
+			}
+		})
+	}
+}
+
+func TestNormalizer_Transform(t *testing.T) {
+	mapping, err := ReadMapping(strings.NewReader(`[
+		{"from": "highway=ford", "to": ["ford=yes"]},
+		{"from": "fixme=*", "to": ["FIXME=*"]}
+	]`))
+	if err != nil {
+		t.Fatalf("read mapping error: %v", err)
+	}
+
+	var rewrites []Rewrite
+	n, err := New(
+		WithMapping(mapping),
+		OnRewrite(func(r Rewrite) { rewrites = append(rewrites, r) }),
+	)
+	if err != nil {
+		t.Fatalf("new error: %v", err)
+	}
+
+	tags := osm.Tags{{Key: "highway", Value: "ford"}}
+	node := &osm.Node{ID: 1, Version: 2, Tags: tags}
+	way := &osm.Way{ID: 1, Tags: osm.Tags{{Key: "fixme", Value: "check"}}}
+	other := &osm.Way{ID: 2, Tags: osm.Tags{{Key: "power", Value: "sub_station"}}}
+
+	for _, o := range []osm.Object{node, way, other, &osm.Changeset{ID: 1}} {
+		if _, err := n.Transform(o); err != nil {
+			t.Fatalf("transform error: %v", err)
+		}
+	}
+
+	if v := node.Tags.Find("ford"); v != "yes" {
+		t.Errorf("node not normalized: %v", node.Tags)
+	}
+
+	if v := tags.Find("highway"); v != "ford" {
+		t.Errorf("original tags should not be modified: %v", tags)
+	}
+
+	if v := way.Tags.Find("FIXME"); v != "check" {
+		t.Errorf("way not normalized: %v", way.Tags)
+	}
+
+	if v := other.Tags.Find("power"); v != "sub_station" {
+		t.Errorf("default mapping should not be used: %v", other.Tags)
+	}
+
+	if len(rewrites) != 2 {
+		t.Fatalf("incorrect rewrites: %v", rewrites)
+	}
+
+	if id := rewrites[0].ElementID; id != node.ElementID() {
+		t.Errorf("incorrect rewrite id: %v", id)
+	}
+
+	report := n.Report()
+	if len(report) != 2 {
+		t.Fatalf("incorrect report: %v", report)
+	}
+
+	if v := report[0].Rule.String(); v != "highway=ford -> ford=yes" {
+		t.Errorf("incorrect rule string: %v", v)
+	}
+}
+
+func TestReadMapping_errors(t *testing.T) {
+	cases := []struct {
+		name string
+		data string
+	}{
+		{
+			name: "invalid json",
+			data: `{`,
+		},
+		{
+			name: "invalid from",
+			data: `[{"from": "highway", "to": ["ford=yes"]}]`,
+		},
+		{
+			name: "invalid to",
+			data: `[{"from": "highway=ford", "to": ["=yes"]}]`,
+		},
+		{
+			name: "keep value without any",
+			data: `[{"from": "highway=ford", "to": ["ford=*"]}]`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ReadMapping(strings.NewReader(tc.data))
+			if err == nil {
+				t.Errorf("expected error")
+			}
+		})
+	}
+}
@@ -0,0 +1,28 @@
+package tagnorm
+
+import "errors"
+
+// An Option is a setting for creating the normalizer.
+type Option func(*Normalizer) error
+
+// WithMapping overrides the default mapping.
+func WithMapping(m Mapping) Option {
+	return func(n *Normalizer) error {
+		if m == nil {
+			return errors.New("tagnorm: mapping is nil")
+		}
+
+		n.mapping = m
+		return nil
+	}
+}
+
+// OnRewrite sets a function called for every tag rewrite,
+// for example to log the changes. It must be safe for concurrent use
+// if the normalizer is used concurrently.
+func OnRewrite(f func(Rewrite)) Option {
+	return func(n *Normalizer) error {
+		n.onRewrite = f
+		return nil
+	}
+}
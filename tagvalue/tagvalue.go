@@ -0,0 +1,252 @@
+// Package tagvalue parses speed, weight and dimension tag values,
+// e.g. maxspeed, maxweight and maxheight, into normalized SI values
+// for building routing profiles.
+package tagvalue
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/paulmach/osm"
+)
+
+// Conversion factors to the SI units.
+const (
+	kmhToMS   = 1000.0 / 3600.0
+	mphToMS   = mileToMeter / 3600.0
+	knotsToMS = 1852.0 / 3600.0
+
+	tonneToKg    = 1000.0
+	shortTonToKg = 907.18474
+	poundToKg    = 0.45359237
+
+	footToMeter = 0.3048
+	inchToMeter = 0.0254
+	mileToMeter = 1609.344
+
+	// walkSpeedKmh is used for maxspeed=walk, walking pace.
+	walkSpeedKmh = 5.0
+)
+
+// speedUnits are the speed units and their factor to meters per second.
+var speedUnits = map[string]float64{
+	"":      kmhToMS,
+	"km/h":  kmhToMS,
+	"kmh":   kmhToMS,
+	"kph":   kmhToMS,
+	"mph":   mphToMS,
+	"knots": knotsToMS,
+	"kn":    knotsToMS,
+}
+
+// weightUnits are the weight units and their factor to kilograms.
+var weightUnits = map[string]float64{
+	"":    tonneToKg,
+	"t":   tonneToKg,
+	"kg":  1,
+	"st":  shortTonToKg,
+	"lbs": poundToKg,
+	"lb":  poundToKg,
+}
+
+// lengthUnits are the length units and their factor to meters.
+var lengthUnits = map[string]float64{
+	"":   1,
+	"m":  1,
+	"cm": 0.01,
+	"mm": 0.001,
+	"km": 1000,
+	"ft": footToMeter,
+	"in": inchToMeter,
+	"mi": mileToMeter,
+}
+
+// zoneSpeeds are the implicit country speed limits in km/h,
+// e.g. maxspeed=DE:urban. A value of zero is no limit.
+var zoneSpeeds = map[string]float64{
+	"AT:urban":      50,
+	"AT:rural":      100,
+	"AT:trunk":      100,
+	"AT:motorway":   130,
+	"BE:urban":      50,
+	"BE:rural":      70,
+	"BE:motorway":   120,
+	"CH:urban":      50,
+	"CH:rural":      80,
+	"CH:trunk":      100,
+	"CH:motorway":   120,
+	"DE:urban":      50,
+	"DE:rural":      100,
+	"DE:motorway":   0,
+	"DE:zone30":     30,
+	"DE:zone:30":    30,
+	"DK:urban":      50,
+	"DK:rural":      80,
+	"DK:motorway":   130,
+	"FR:urban":      50,
+	"FR:rural":      80,
+	"FR:motorway":   130,
+	"GB:nsl_single": 60 * mileToMeter / 1000,
+	"GB:nsl_dual":   70 * mileToMeter / 1000,
+	"GB:motorway":   70 * mileToMeter / 1000,
+	"IT:urban":      50,
+	"IT:rural":      90,
+	"IT:motorway":   130,
+	"NL:urban":      50,
+	"NL:rural":      80,
+	"NL:motorway":   100,
+}
+
+// Speed parses a speed, e.g. maxspeed=50, "30 mph", "10 knots" or "walk",
+// into meters per second. The default unit is km/h. Implicit country
+// limits, e.g. "DE:urban", are supported for some countries. "none" returns
+// +Inf. Values that can't be parsed, e.g. "signals" or multiple values,
+// return false.
+func Speed(v string) (float64, bool) {
+	v = strings.TrimSpace(v)
+	switch v {
+	case "":
+		return 0, false
+	case "none":
+		return math.Inf(1), true
+	case "walk":
+		return walkSpeedKmh * kmhToMS, true
+	}
+
+	if kmh, ok := zoneSpeeds[v]; ok {
+		if kmh == 0 {
+			return math.Inf(1), true
+		}
+		return kmh * kmhToMS, true
+	}
+
+	n, unit, ok := split(v)
+	if !ok {
+		return 0, false
+	}
+
+	f, ok := speedUnits[unit]
+	if !ok {
+		return 0, false
+	}
+
+	return n * f, true
+}
+
+// Weight parses a weight, e.g. maxweight=7.5, "7500 kg" or "10 st",
+// into kilograms. The default unit is metric tonnes.
+func Weight(v string) (float64, bool) {
+	n, unit, ok := split(strings.TrimSpace(v))
+	if !ok {
+		return 0, false
+	}
+
+	f, ok := weightUnits[unit]
+	if !ok {
+		return 0, false
+	}
+
+	return n * f, true
+}
+
+// Length parses a length, e.g. maxheight=3.5, "350 cm" or `12'6"`,
+// into meters. The default unit is meters.
+func Length(v string) (float64, bool) {
+	v = strings.TrimSpace(v)
+	if strings.ContainsAny(v, `'"`) {
+		return feetInches(v)
+	}
+
+	n, unit, ok := split(v)
+	if !ok {
+		return 0, false
+	}
+
+	f, ok := lengthUnits[unit]
+	if !ok {
+		return 0, false
+	}
+
+	return n * f, true
+}
+
+// MaxSpeed returns the maxspeed of the tags in meters per second.
+func MaxSpeed(ts osm.Tags) (float64, bool) {
+	return Speed(ts.Find("maxspeed"))
+}
+
+// MaxWeight returns the maxweight of the tags in kilograms.
+func MaxWeight(ts osm.Tags) (float64, bool) {
+	return Weight(ts.Find("maxweight"))
+}
+
+// MaxHeight returns the maxheight of the tags in meters.
+func MaxHeight(ts osm.Tags) (float64, bool) {
+	return Length(ts.Find("maxheight"))
+}
+
+// MaxWidth returns the maxwidth of the tags in meters.
+func MaxWidth(ts osm.Tags) (float64, bool) {
+	return Length(ts.Find("maxwidth"))
+}
+
+// MaxLength returns the maxlength of the tags in meters.
+func MaxLength(ts osm.Tags) (float64, bool) {
+	return Length(ts.Find("maxlength"))
+}
+
+// Width returns the width of the tags in meters.
+func Width(ts osm.Tags) (float64, bool) {
+	return Length(ts.Find("width"))
+}
+
+// split separates a value like "30 mph" or "30mph" into the number
+// and the unit. The number must be non-negative.
+func split(v string) (float64, string, bool) {
+	i := 0
+	for i < len(v) && (v[i] >= '0' && v[i] <= '9' || v[i] == '.') {
+		i++
+	}
+
+	if i == 0 {
+		return 0, "", false
+	}
+
+	n, err := strconv.ParseFloat(v[:i], 64)
+	if err != nil {
+		return 0, "", false
+	}
+
+	return n, strings.TrimSpace(v[i:]), true
+}
+
+// feetInches parses imperial lengths like 12'6", 12' or 6".
+func feetInches(v string) (float64, bool) {
+	var total float64
+
+	if i := strings.IndexByte(v, '\''); i >= 0 {
+		n, unit, ok := split(strings.TrimSpace(v[:i]))
+		if !ok || unit != "" {
+			return 0, false
+		}
+
+		total += n * footToMeter
+		v = strings.TrimSpace(v[i+1:])
+	}
+
+	if v == "" {
+		return total, true
+	}
+
+	if !strings.HasSuffix(v, `"`) {
+		return 0, false
+	}
+
+	n, unit, ok := split(strings.TrimSpace(v[:len(v)-1]))
+	if !ok || unit != "" {
+		return 0, false
+	}
+
+	return total + n*inchToMeter, true
+}
@@ -0,0 +1,156 @@
+package tagvalue
+
+import (
+	"math"
+	"testing"
+
+	"github.com/paulmach/osm"
+)
+
+func TestSpeed(t *testing.T) {
+	cases := []struct {
+		value    string
+		expected float64 // km/h
+		ok       bool
+	}{
+		{value: "50", expected: 50, ok: true},
+		{value: "50 km/h", expected: 50, ok: true},
+		{value: "50kmh", expected: 50, ok: true},
+		{value: "30 mph", expected: 48.28032, ok: true},
+		{value: "30mph", expected: 48.28032, ok: true},
+		{value: "10 knots", expected: 18.52, ok: true},
+		{value: "walk", expected: 5, ok: true},
+		{value: "DE:urban", expected: 50, ok: true},
+		{value: "GB:nsl_single", expected: 96.56064, ok: true},
+		{value: "none", expected: math.Inf(1), ok: true},
+		{value: "DE:motorway", expected: math.Inf(1), ok: true},
+		{value: "signals"},
+		{value: "50;30"},
+		{value: "50 m/s"},
+		{value: "-50"},
+		{value: ""},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.value, func(t *testing.T) {
+			v, ok := Speed(tc.value)
+			if ok != tc.ok {
+				t.Fatalf("incorrect ok: %v", ok)
+			}
+
+			if kmh := v / kmhToMS; !floatEqual(kmh, tc.expected) {
+				t.Errorf("incorrect speed: %v km/h", kmh)
+			}
+		})
+	}
+}
+
+func TestWeight(t *testing.T) {
+	cases := []struct {
+		value    string
+		expected float64
+		ok       bool
+	}{
+		{value: "7.5", expected: 7500, ok: true},
+		{value: "7.5 t", expected: 7500, ok: true},
+		{value: "3500 kg", expected: 3500, ok: true},
+		{value: "10 st", expected: 9071.8474, ok: true},
+		{value: "5000 lbs", expected: 2267.96185, ok: true},
+		{value: "7,5"},
+		{value: "heavy"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.value, func(t *testing.T) {
+			v, ok := Weight(tc.value)
+			if ok != tc.ok {
+				t.Fatalf("incorrect ok: %v", ok)
+			}
+
+			if !floatEqual(v, tc.expected) {
+				t.Errorf("incorrect weight: %v", v)
+			}
+		})
+	}
+}
+
+func TestLength(t *testing.T) {
+	cases := []struct {
+		value    string
+		expected float64
+		ok       bool
+	}{
+		{value: "3.5", expected: 3.5, ok: true},
+		{value: "3.5 m", expected: 3.5, ok: true},
+		{value: "350 cm", expected: 3.5, ok: true},
+		{value: "12 ft", expected: 3.6576, ok: true},
+		{value: `12'6"`, expected: 3.81, ok: true},
+		{value: `12' 6"`, expected: 3.81, ok: true},
+		{value: "12'", expected: 3.6576, ok: true},
+		{value: `6"`, expected: 0.1524, ok: true},
+		{value: "default"},
+		{value: "below_default"},
+		{value: `12'6`},
+		{value: `a'6"`},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.value, func(t *testing.T) {
+			v, ok := Length(tc.value)
+			if ok != tc.ok {
+				t.Fatalf("incorrect ok: %v", ok)
+			}
+
+			if !floatEqual(v, tc.expected) {
+				t.Errorf("incorrect length: %v", v)
+			}
+		})
+	}
+}
+
+func TestTags(t *testing.T) {
+	ts := osm.Tags{
+		{Key: "maxspeed", Value: "36"},
+		{Key: "maxweight", Value: "3.5"},
+		{Key: "maxheight", Value: "4"},
+		{Key: "maxwidth", Value: "2.5"},
+		{Key: "maxlength", Value: "12"},
+		{Key: "width", Value: "6"},
+	}
+
+	if v, ok := MaxSpeed(ts); !ok || !floatEqual(v, 10) {
+		t.Errorf("incorrect maxspeed: %v %v", v, ok)
+	}
+
+	if v, ok := MaxWeight(ts); !ok || v != 3500 {
+		t.Errorf("incorrect maxweight: %v %v", v, ok)
+	}
+
+	if v, ok := MaxHeight(ts); !ok || v != 4 {
+		t.Errorf("incorrect maxheight: %v %v", v, ok)
+	}
+
+	if v, ok := MaxWidth(ts); !ok || v != 2.5 {
+		t.Errorf("incorrect maxwidth: %v %v", v, ok)
+	}
+
+	if v, ok := MaxLength(ts); !ok || v != 12 {
+		t.Errorf("incorrect maxlength: %v %v", v, ok)
+	}
+
+	if v, ok := Width(ts); !ok || v != 6 {
+		t.Errorf("incorrect width: %v %v", v, ok)
+	}
+
+	if _, ok := MaxSpeed(nil); ok {
+		t.Errorf("missing tag should not be ok")
+	}
+}
+
+func floatEqual(a, b float64) bool {
+	if math.IsInf(a, 0) || math.IsInf(b, 0) {
+		return a == b
+	}
+
+	return math.Abs(a-b) < 1e-6
+}
@@ -0,0 +1,314 @@
+// Package tilesplit splits a stream of osm data into per tile PBF files.
+package tilesplit
+
+import (
+	"container/list"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/maptile"
+	"github.com/paulmach/osm"
+	"github.com/paulmach/osm/osmpbf"
+)
+
+// CreateFunc is called once for every tile that contains data and should
+// return the writer for that tile's PBF file. If the writer is also an
+// io.Closer it will be closed when the Writer is closed.
+type CreateFunc func(maptile.Tile) (io.Writer, error)
+
+// Writer splits osm data into tiles at a given zoom level. Nodes are written
+// to the tile they're located in. Ways are written to every tile one of their
+// nodes is in, along with all their nodes, so every tile contains complete ways.
+// Relations are written to every tile containing one of their node or way members.
+//
+// Splitting requires two passes over the data, the first to index the tiles
+// and the second to write the elements. Both passes expect the data to be
+// sorted with nodes first, then ways, then relations, as in planet files.
+// The index requires memory proportional to the number of nodes and ways.
+//
+// By default the writer of every tile is kept open until Close, at higher
+// zooms that can be more files than the open file limit, ulimit -n, of the
+// process allows. Set MaxOpen and Reopen to limit them.
+type Writer struct {
+	// MaxOpen, if set before writing, limits the number of tile writers open
+	// at the same time. The least recently used one is flushed and closed when
+	// the limit is reached, and reopened using Reopen if the tile gets more
+	// data. Every reopen starts a new data block, so a limit much lower than
+	// the number of tiles written at once creates many small blocks.
+	MaxOpen int
+
+	// Reopen must return a writer that appends to the data already written
+	// for the tile, e.g. using os.OpenFile with os.O_APPEND. It is required
+	// if MaxOpen is set.
+	Reopen CreateFunc
+
+	zoom   maptile.Zoom
+	create CreateFunc
+	opts   []osmpbf.EncoderOption
+
+	nodes     map[osm.NodeID]maptile.Tile
+	extra     map[osm.NodeID][]maptile.Tile
+	ways      map[osm.WayID][]maptile.Tile
+	relations map[osm.RelationID][]maptile.Tile
+
+	indexed bool
+	written map[maptile.Tile]bool
+	open    *list.List // of *tileWriter, most recently used first
+	writers map[maptile.Tile]*list.Element
+}
+
+type tileWriter struct {
+	tile maptile.Tile
+	w    io.Writer
+	enc  *osmpbf.Encoder
+}
+
+// New creates a writer splitting data into tiles at the given zoom.
+// The encoder options are used when creating the encoder for every tile,
+// the header bounds are set to the tile bounds.
+func New(z maptile.Zoom, create CreateFunc, opts ...osmpbf.EncoderOption) *Writer {
+	return &Writer{
+		zoom:   z,
+		create: create,
+		opts:   opts,
+
+		nodes:     make(map[osm.NodeID]maptile.Tile),
+		extra:     make(map[osm.NodeID][]maptile.Tile),
+		ways:      make(map[osm.WayID][]maptile.Tile),
+		relations: make(map[osm.RelationID][]maptile.Tile),
+
+		written: make(map[maptile.Tile]bool),
+		open:    list.New(),
+		writers: make(map[maptile.Tile]*list.Element),
+	}
+}
+
+// Index is the first pass over the data. It figures out the tiles for
+// every element and which nodes need to be duplicated so ways are complete.
+func (w *Writer) Index(s osm.Scanner) error {
+	for s.Scan() {
+		switch o := s.Object().(type) {
+		case *osm.Node:
+			w.nodes[o.ID] = maptile.At(orb.Point{o.Lon, o.Lat}, w.zoom)
+		case *osm.Way:
+			w.indexWay(o)
+		case *osm.Relation:
+			w.indexRelation(o)
+		}
+	}
+
+	if err := s.Err(); err != nil {
+		return err
+	}
+
+	w.indexed = true
+	return nil
+}
+
+func (w *Writer) indexWay(way *osm.Way) {
+	var tiles []maptile.Tile
+	for _, wn := range way.Nodes {
+		if t, ok := w.nodes[wn.ID]; ok {
+			tiles = addTile(tiles, t)
+		}
+	}
+
+	if len(tiles) == 0 {
+		return
+	}
+	w.ways[way.ID] = tiles
+
+	if len(tiles) == 1 {
+		return
+	}
+
+	// nodes need to be in all the tiles of the way
+	for _, wn := range way.Nodes {
+		t, ok := w.nodes[wn.ID]
+		if !ok {
+			continue
+		}
+
+		for _, wt := range tiles {
+			if wt != t {
+				w.extra[wn.ID] = addTile(w.extra[wn.ID], wt)
+			}
+		}
+	}
+}
+
+func (w *Writer) indexRelation(r *osm.Relation) {
+	var tiles []maptile.Tile
+	for _, m := range r.Members {
+		switch m.Type {
+		case osm.TypeNode:
+			if t, ok := w.nodes[osm.NodeID(m.Ref)]; ok {
+				tiles = addTile(tiles, t)
+			}
+		case osm.TypeWay:
+			for _, t := range w.ways[osm.WayID(m.Ref)] {
+				tiles = addTile(tiles, t)
+			}
+		case osm.TypeRelation:
+			// only relations that have already been indexed
+			for _, t := range w.relations[osm.RelationID(m.Ref)] {
+				tiles = addTile(tiles, t)
+			}
+		}
+	}
+
+	if len(tiles) > 0 {
+		w.relations[r.ID] = tiles
+	}
+}
+
+// Write is the second pass over the data. It writes every element
+// to the tiles found during indexing. Elements without a location,
+// eg. ways without any of their nodes in the data, are skipped.
+func (w *Writer) Write(s osm.Scanner) error {
+	if !w.indexed {
+		return errors.New("tilesplit: data must be indexed before writing")
+	}
+
+	if w.MaxOpen > 0 && w.Reopen == nil {
+		return errors.New("tilesplit: Reopen is required with MaxOpen")
+	}
+
+	for s.Scan() {
+		var (
+			o     = s.Object()
+			tiles []maptile.Tile
+		)
+
+		switch o := o.(type) {
+		case *osm.Node:
+			t, ok := w.nodes[o.ID]
+			if !ok {
+				continue
+			}
+			tiles = append([]maptile.Tile{t}, w.extra[o.ID]...)
+		case *osm.Way:
+			tiles = w.ways[o.ID]
+		case *osm.Relation:
+			tiles = w.relations[o.ID]
+		default:
+			continue
+		}
+
+		for _, t := range tiles {
+			if err := w.encode(t, o); err != nil {
+				return err
+			}
+		}
+	}
+
+	return s.Err()
+}
+
+func (w *Writer) encode(t maptile.Tile, o osm.Object) error {
+	tw, err := w.writer(t)
+	if err != nil {
+		return err
+	}
+
+	return tw.enc.Encode(o)
+}
+
+// writer returns the open writer for the tile, creating or reopening it.
+func (w *Writer) writer(t maptile.Tile) (*tileWriter, error) {
+	if e, ok := w.writers[t]; ok {
+		w.open.MoveToFront(e)
+		return e.Value.(*tileWriter), nil
+	}
+
+	if w.MaxOpen > 0 && w.open.Len() >= w.MaxOpen {
+		if err := w.close(w.open.Back()); err != nil {
+			return nil, err
+		}
+	}
+
+	bounds, err := osm.NewBoundsFromTile(t)
+	if err != nil {
+		return nil, err
+	}
+
+	// only set the bounds to keep the other header fields of the options
+	opts := append(w.opts[:len(w.opts):len(w.opts)], osmpbf.HeaderBounds(bounds))
+
+	create := w.create
+	if w.written[t] {
+		create = w.Reopen
+		opts = append(opts, osmpbf.Append(true))
+	}
+
+	wr, err := create(t)
+	if err != nil {
+		return nil, err
+	}
+
+	enc, err := osmpbf.NewEncoder(wr, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	tw := &tileWriter{tile: t, w: wr, enc: enc}
+	w.writers[t] = w.open.PushFront(tw)
+	w.written[t] = true
+
+	return tw, nil
+}
+
+// close flushes the data of the tile and closes its writer.
+func (w *Writer) close(e *list.Element) error {
+	tw := w.open.Remove(e).(*tileWriter)
+	delete(w.writers, tw.tile)
+
+	t := tw.tile
+	var result error
+	if err := tw.enc.Close(); err != nil {
+		result = fmt.Errorf("tilesplit: tile %d/%d/%d: %v", t.Z, t.X, t.Y, err)
+	}
+
+	if c, ok := tw.w.(io.Closer); ok {
+		if err := c.Close(); err != nil && result == nil {
+			result = fmt.Errorf("tilesplit: tile %d/%d/%d: %v", t.Z, t.X, t.Y, err)
+		}
+	}
+
+	return result
+}
+
+// Tiles returns the tiles that have been written to.
+func (w *Writer) Tiles() []maptile.Tile {
+	tiles := make([]maptile.Tile, 0, len(w.written))
+	for t := range w.written {
+		tiles = append(tiles, t)
+	}
+
+	return tiles
+}
+
+// Close flushes the data for every tile and closes the tile writers.
+// The first error encountered is returned.
+func (w *Writer) Close() error {
+	var result error
+	for w.open.Len() > 0 {
+		if err := w.close(w.open.Front()); err != nil && result == nil {
+			result = err
+		}
+	}
+
+	return result
+}
+
+func addTile(tiles []maptile.Tile, t maptile.Tile) []maptile.Tile {
+	for _, tile := range tiles {
+		if tile == t {
+			return tiles
+		}
+	}
+
+	return append(tiles, t)
+}
@@ -0,0 +1,246 @@
+package tilesplit
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"math"
+	"testing"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/maptile"
+	"github.com/paulmach/osm"
+	"github.com/paulmach/osm/osmpbf"
+	"github.com/paulmach/osm/osmtest"
+)
+
+func testObjects() osm.Objects {
+	return osm.Objects{
+		&osm.Node{ID: 1, Lat: 1, Lon: 1, Version: 1, Visible: true},
+		&osm.Node{ID: 2, Lat: 1, Lon: 1.1, Version: 1, Visible: true},
+		&osm.Node{ID: 3, Lat: 1, Lon: -1, Version: 1, Visible: true},
+		&osm.Node{ID: 4, Lat: -1, Lon: -1, Version: 1, Visible: true},
+		&osm.Way{ID: 10, Version: 1, Visible: true, Nodes: osm.WayNodes{{ID: 1}, {ID: 2}}},
+		&osm.Way{ID: 11, Version: 1, Visible: true, Nodes: osm.WayNodes{{ID: 2}, {ID: 3}}},
+		&osm.Way{ID: 12, Version: 1, Visible: true, Nodes: osm.WayNodes{{ID: 100}}},
+		&osm.Relation{ID: 20, Version: 1, Visible: true, Members: osm.Members{
+			{Type: osm.TypeWay, Ref: 10},
+			{Type: osm.TypeNode, Ref: 4},
+		}},
+	}
+}
+
+func TestWriter(t *testing.T) {
+	buffers := map[maptile.Tile]*bytes.Buffer{}
+	w := New(1, func(t maptile.Tile) (io.Writer, error) {
+		buffers[t] = &bytes.Buffer{}
+		return buffers[t], nil
+	})
+
+	if err := w.Index(osmtest.NewScanner(testObjects())); err != nil {
+		t.Fatalf("index error: %v", err)
+	}
+
+	if err := w.Write(osmtest.NewScanner(testObjects())); err != nil {
+		t.Fatalf("write error: %v", err)
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatalf("close error: %v", err)
+	}
+
+	if len(w.Tiles()) != 3 {
+		t.Errorf("incorrect number of tiles: %v", w.Tiles())
+	}
+
+	checkTiles(t, buffers)
+}
+
+func TestWriter_headerOptions(t *testing.T) {
+	buffers := map[maptile.Tile]*bytes.Buffer{}
+	w := New(1, func(t maptile.Tile) (io.Writer, error) {
+		buffers[t] = &bytes.Buffer{}
+		return buffers[t], nil
+	}, osmpbf.WritingProgram("tiler"), osmpbf.ReplicationSeqNum(5))
+
+	if err := w.Index(osmtest.NewScanner(testObjects())); err != nil {
+		t.Fatalf("index error: %v", err)
+	}
+
+	if err := w.Write(osmtest.NewScanner(testObjects())); err != nil {
+		t.Fatalf("write error: %v", err)
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatalf("close error: %v", err)
+	}
+
+	for tile, buf := range buffers {
+		scanner := osmpbf.New(context.Background(), buf, 1)
+		header, err := scanner.Header()
+		scanner.Close()
+		if err != nil {
+			t.Fatalf("header error: %v", err)
+		}
+
+		if header.WritingProgram != "tiler" || header.ReplicationSeqNum != 5 {
+			t.Errorf("should keep the header options: %+v", header)
+		}
+
+		b, _ := osm.NewBoundsFromTile(tile)
+		if header.Bounds == nil || header.Bounds.MinLon != b.MinLon {
+			t.Errorf("should set the tile bounds: %v", header.Bounds)
+		}
+	}
+}
+
+func TestWriter_MaxOpen(t *testing.T) {
+	buffers := map[maptile.Tile]*bytes.Buffer{}
+	open, reopens := 0, 0
+
+	w := New(1, func(t maptile.Tile) (io.Writer, error) {
+		buffers[t] = &bytes.Buffer{}
+		open++
+		return &countingCloser{Writer: buffers[t], open: &open}, nil
+	})
+
+	w.MaxOpen = 1
+	w.Reopen = func(t maptile.Tile) (io.Writer, error) {
+		open++
+		reopens++
+		return &countingCloser{Writer: buffers[t], open: &open}, nil
+	}
+
+	if err := w.Index(osmtest.NewScanner(testObjects())); err != nil {
+		t.Fatalf("index error: %v", err)
+	}
+
+	scanner := &checkingScanner{Scanner: osmtest.NewScanner(testObjects()), check: func() {
+		if open > 1 {
+			t.Errorf("too many open writers: %v", open)
+		}
+	}}
+
+	if err := w.Write(scanner); err != nil {
+		t.Fatalf("write error: %v", err)
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatalf("close error: %v", err)
+	}
+
+	if open != 0 {
+		t.Errorf("should close all writers: %v", open)
+	}
+
+	if reopens == 0 {
+		t.Errorf("should reopen tiles")
+	}
+
+	checkTiles(t, buffers)
+}
+
+func TestWriter_MaxOpen_noReopen(t *testing.T) {
+	w := New(1, func(t maptile.Tile) (io.Writer, error) {
+		return &bytes.Buffer{}, nil
+	})
+	w.MaxOpen = 1
+
+	if err := w.Index(osmtest.NewScanner(testObjects())); err != nil {
+		t.Fatalf("index error: %v", err)
+	}
+
+	if err := w.Write(osmtest.NewScanner(testObjects())); err == nil {
+		t.Errorf("should require reopen")
+	}
+}
+
+type countingCloser struct {
+	io.Writer
+	open *int
+}
+
+func (c *countingCloser) Close() error {
+	*c.open--
+	return nil
+}
+
+type checkingScanner struct {
+	osm.Scanner
+	check func()
+}
+
+func (s *checkingScanner) Scan() bool {
+	s.check()
+	return s.Scanner.Scan()
+}
+
+func checkTiles(t testing.TB, buffers map[maptile.Tile]*bytes.Buffer) {
+	t.Helper()
+
+	ne := maptile.At(orb.Point{1, 1}, 1)
+	nw := maptile.At(orb.Point{-1, 1}, 1)
+	sw := maptile.At(orb.Point{-1, -1}, 1)
+
+	expected := map[maptile.Tile][]osm.FeatureID{
+		ne: {
+			osm.NodeID(1).FeatureID(), osm.NodeID(2).FeatureID(), osm.NodeID(3).FeatureID(),
+			osm.WayID(10).FeatureID(), osm.WayID(11).FeatureID(),
+			osm.RelationID(20).FeatureID(),
+		},
+		nw: {
+			osm.NodeID(2).FeatureID(), osm.NodeID(3).FeatureID(),
+			osm.WayID(11).FeatureID(),
+		},
+		sw: {
+			osm.NodeID(4).FeatureID(),
+			osm.RelationID(20).FeatureID(),
+		},
+	}
+
+	for tile, ids := range expected {
+		buf := buffers[tile]
+		if buf == nil {
+			t.Fatalf("tile not written: %v", tile)
+		}
+
+		scanner := osmpbf.New(context.Background(), buf, 1)
+		header, err := scanner.Header()
+		if err != nil {
+			t.Fatalf("header error: %v", err)
+		}
+
+		// header bounds are stored in nanodegrees
+		b, _ := osm.NewBoundsFromTile(tile)
+		if math.Abs(header.Bounds.MinLat-b.MinLat) > 1e-9 || header.Bounds.MinLon != b.MinLon ||
+			math.Abs(header.Bounds.MaxLat-b.MaxLat) > 1e-9 || header.Bounds.MaxLon != b.MaxLon {
+			t.Errorf("incorrect bounds: %v != %v", header.Bounds, b)
+		}
+
+		var result []osm.FeatureID
+		for scanner.Scan() {
+			result = append(result, scanner.Object().(osm.Element).FeatureID())
+		}
+		scanner.Close()
+
+		if len(result) != len(ids) {
+			t.Fatalf("incorrect elements for %v: %v", tile, result)
+		}
+
+		for i := range ids {
+			if result[i] != ids[i] {
+				t.Errorf("incorrect elements for %v: %v", tile, result)
+			}
+		}
+	}
+}
+
+func TestWriter_notIndexed(t *testing.T) {
+	w := New(1, func(t maptile.Tile) (io.Writer, error) {
+		return &bytes.Buffer{}, nil
+	})
+
+	if err := w.Write(osmtest.NewScanner(testObjects())); err == nil {
+		t.Errorf("should require indexing first")
+	}
+}
@@ -0,0 +1,269 @@
+// Package timeline builds per user contribution timelines from history
+// or changeset streams, for community analysis tools.
+package timeline
+
+import (
+	"sort"
+	"time"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/maptile"
+	"github.com/paulmach/osm"
+)
+
+// TileZoom is the zoom of the tiles counted for the geographic spread,
+// zoom 10 tiles are about 40km wide at the equator.
+const TileZoom maptile.Zoom = 10
+
+// A Timeline is the contribution history of a user.
+type Timeline struct {
+	UserID osm.UserID `json:"uid"`
+
+	// User is the most recent user name seen.
+	User string `json:"user"`
+
+	FirstEdit time.Time `json:"first_edit"`
+	LastEdit  time.Time `json:"last_edit"`
+
+	// Edits is the number of element versions, or the sum of the changes
+	// counts when built from changesets.
+	Edits      int `json:"edits"`
+	Changesets int `json:"changesets"`
+
+	// Months are the months with edits, in order.
+	Months []*Month `json:"months"`
+
+	// Spread is the geographic extent of the edits,
+	// nil if none had a location.
+	Spread *Spread `json:"spread,omitempty"`
+
+	userTime   time.Time
+	changesets map[osm.ChangesetID]struct{}
+	months     map[string]*Month
+	tiles      map[maptile.Tile]struct{}
+}
+
+// A Month is the activity of a user in a calendar month, in UTC.
+type Month struct {
+	// Month is formatted as 2006-01.
+	Month      string `json:"month"`
+	Edits      int    `json:"edits"`
+	Changesets int    `json:"changesets"`
+}
+
+// Spread is the geographic extent of the edits of a user.
+type Spread struct {
+	MinLat float64 `json:"min_lat"`
+	MaxLat float64 `json:"max_lat"`
+	MinLon float64 `json:"min_lon"`
+	MaxLon float64 `json:"max_lon"`
+
+	// Tiles is the number of distinct TileZoom tiles with edits,
+	// a measure of how local or widespread the user is.
+	Tiles int `json:"tiles"`
+}
+
+// A Builder aggregates the timelines. Elements, from a history stream, and
+// changesets should not both be added for the same edits since they would
+// be counted twice. It is not safe for concurrent use.
+type Builder struct {
+	timelines map[osm.UserID]*Timeline
+}
+
+// NewBuilder creates a new timeline builder.
+func NewBuilder() *Builder {
+	return &Builder{timelines: make(map[osm.UserID]*Timeline)}
+}
+
+// Add adds the object to the timelines. Nodes, ways, relations and
+// changesets are supported, other objects are ignored.
+func (b *Builder) Add(o osm.Object) {
+	switch o := o.(type) {
+	case *osm.Node:
+		b.AddElement(o)
+	case *osm.Way:
+		b.AddElement(o)
+	case *osm.Relation:
+		b.AddElement(o)
+	case *osm.Changeset:
+		b.AddChangeset(o)
+	}
+}
+
+// AddElement adds an element version to the timeline of its user.
+// Way nodes and relation members must be annotated to count
+// towards the geographic spread.
+func (b *Builder) AddElement(e osm.Element) {
+	var (
+		uid  osm.UserID
+		user string
+		t    time.Time
+		cs   osm.ChangesetID
+	)
+
+	switch e := e.(type) {
+	case *osm.Node:
+		uid, user, t, cs = e.UserID, e.User, e.Timestamp, e.ChangesetID
+	case *osm.Way:
+		uid, user, t, cs = e.UserID, e.User, e.Timestamp, e.ChangesetID
+	case *osm.Relation:
+		uid, user, t, cs = e.UserID, e.User, e.Timestamp, e.ChangesetID
+	default:
+		return
+	}
+
+	tl := b.timeline(uid, user, t)
+	m := tl.month(t)
+
+	tl.Edits++
+	m.Edits++
+
+	if _, ok := tl.changesets[cs]; !ok && cs != 0 {
+		tl.changesets[cs] = struct{}{}
+		tl.Changesets++
+		m.Changesets++
+	}
+
+	if p, ok := osm.EditCentroid(e); ok {
+		tl.extend(p)
+		tl.tiles[maptile.At(p, TileZoom)] = struct{}{}
+	}
+}
+
+// AddChangeset adds a changeset to the timeline of its user,
+// its changes count are the edits.
+func (b *Builder) AddChangeset(c *osm.Changeset) {
+	tl := b.timeline(c.UserID, c.User, c.CreatedAt)
+	if _, ok := tl.changesets[c.ID]; ok {
+		return
+	}
+	tl.changesets[c.ID] = struct{}{}
+
+	m := tl.month(c.CreatedAt)
+
+	tl.Edits += c.ChangesCount
+	tl.Changesets++
+	m.Edits += c.ChangesCount
+	m.Changesets++
+
+	if c.HasBounds() {
+		b := c.Bounds()
+		tl.extend(orb.Point{b.MinLon, b.MinLat})
+		tl.extend(orb.Point{b.MaxLon, b.MaxLat})
+
+		center := orb.Point{(b.MinLon + b.MaxLon) / 2, (b.MinLat + b.MaxLat) / 2}
+		tl.tiles[maptile.At(center, TileZoom)] = struct{}{}
+	}
+}
+
+// Timeline returns the timeline of the user, nil if the user has no edits.
+// The timeline should not be modified and is only current until the next add.
+func (b *Builder) Timeline(id osm.UserID) *Timeline {
+	tl := b.timelines[id]
+	if tl == nil {
+		return nil
+	}
+
+	return tl.finish()
+}
+
+// Timelines returns the timelines of all the users, sorted by first edit.
+func (b *Builder) Timelines() []*Timeline {
+	result := make([]*Timeline, 0, len(b.timelines))
+	for _, tl := range b.timelines {
+		result = append(result, tl.finish())
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if !result[i].FirstEdit.Equal(result[j].FirstEdit) {
+			return result[i].FirstEdit.Before(result[j].FirstEdit)
+		}
+
+		return result[i].UserID < result[j].UserID
+	})
+
+	return result
+}
+
+func (b *Builder) timeline(uid osm.UserID, user string, t time.Time) *Timeline {
+	tl := b.timelines[uid]
+	if tl == nil {
+		tl = &Timeline{
+			UserID:     uid,
+			FirstEdit:  t,
+			LastEdit:   t,
+			changesets: make(map[osm.ChangesetID]struct{}),
+			months:     make(map[string]*Month),
+			tiles:      make(map[maptile.Tile]struct{}),
+		}
+		b.timelines[uid] = tl
+	}
+
+	if t.Before(tl.FirstEdit) {
+		tl.FirstEdit = t
+	}
+
+	if t.After(tl.LastEdit) {
+		tl.LastEdit = t
+	}
+
+	// users can change their name, keep the latest
+	if user != "" && !t.Before(tl.userTime) {
+		tl.User = user
+		tl.userTime = t
+	}
+
+	return tl
+}
+
+func (tl *Timeline) month(t time.Time) *Month {
+	key := t.UTC().Format("2006-01")
+	m := tl.months[key]
+	if m == nil {
+		m = &Month{Month: key}
+		tl.months[key] = m
+	}
+
+	return m
+}
+
+func (tl *Timeline) extend(p orb.Point) {
+	if tl.Spread == nil {
+		tl.Spread = &Spread{
+			MinLat: p.Lat(), MaxLat: p.Lat(),
+			MinLon: p.Lon(), MaxLon: p.Lon(),
+		}
+	}
+
+	s := tl.Spread
+	if p.Lat() < s.MinLat {
+		s.MinLat = p.Lat()
+	}
+	if p.Lat() > s.MaxLat {
+		s.MaxLat = p.Lat()
+	}
+	if p.Lon() < s.MinLon {
+		s.MinLon = p.Lon()
+	}
+	if p.Lon() > s.MaxLon {
+		s.MaxLon = p.Lon()
+	}
+}
+
+// finish updates the exported fields from the aggregates.
+func (tl *Timeline) finish() *Timeline {
+	tl.Months = tl.Months[:0]
+	for _, m := range tl.months {
+		tl.Months = append(tl.Months, m)
+	}
+
+	sort.Slice(tl.Months, func(i, j int) bool {
+		return tl.Months[i].Month < tl.Months[j].Month
+	})
+
+	if tl.Spread != nil {
+		tl.Spread.Tiles = len(tl.tiles)
+	}
+
+	return tl
+}
@@ -0,0 +1,108 @@
+package timeline
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/paulmach/osm"
+)
+
+func TestBuilder_elements(t *testing.T) {
+	jan := time.Date(2020, 1, 10, 0, 0, 0, 0, time.UTC)
+	mar := time.Date(2020, 3, 5, 0, 0, 0, 0, time.UTC)
+
+	b := NewBuilder()
+	b.Add(&osm.Node{ID: 1, UserID: 1, User: "new", ChangesetID: 11, Timestamp: mar, Lat: 50, Lon: 5})
+	b.Add(&osm.Node{ID: 2, UserID: 1, User: "old", ChangesetID: 10, Timestamp: jan, Lat: 51, Lon: 4})
+	b.Add(&osm.Way{ID: 1, UserID: 1, User: "old", ChangesetID: 10, Timestamp: jan})
+	b.Add(&osm.Relation{ID: 1, UserID: 2, User: "other", ChangesetID: 12, Timestamp: mar})
+	b.Add(&osm.Note{})
+
+	tl := b.Timeline(1)
+	if tl == nil {
+		t.Fatalf("should have timeline")
+	}
+
+	if tl.User != "new" {
+		t.Errorf("should use the latest user name: %v", tl.User)
+	}
+
+	if !tl.FirstEdit.Equal(jan) || !tl.LastEdit.Equal(mar) {
+		t.Errorf("incorrect edit times: %v %v", tl.FirstEdit, tl.LastEdit)
+	}
+
+	if tl.Edits != 3 || tl.Changesets != 2 {
+		t.Errorf("incorrect counts: %v %v", tl.Edits, tl.Changesets)
+	}
+
+	if len(tl.Months) != 2 {
+		t.Fatalf("incorrect months: %v", tl.Months)
+	}
+
+	if m := tl.Months[0]; m.Month != "2020-01" || m.Edits != 2 || m.Changesets != 1 {
+		t.Errorf("incorrect month: %+v", m)
+	}
+
+	if m := tl.Months[1]; m.Month != "2020-03" || m.Edits != 1 || m.Changesets != 1 {
+		t.Errorf("incorrect month: %+v", m)
+	}
+
+	s := tl.Spread
+	if s == nil || s.MinLat != 50 || s.MaxLat != 51 || s.MinLon != 4 || s.MaxLon != 5 || s.Tiles != 2 {
+		t.Errorf("incorrect spread: %+v", s)
+	}
+
+	if tl := b.Timeline(2); tl == nil || tl.Spread != nil {
+		t.Errorf("should not have spread without locations: %+v", tl)
+	}
+
+	if tl := b.Timeline(3); tl != nil {
+		t.Errorf("unknown user should be nil")
+	}
+
+	timelines := b.Timelines()
+	if len(timelines) != 2 || timelines[0].UserID != 1 || timelines[1].UserID != 2 {
+		t.Errorf("incorrect timelines: %v", timelines)
+	}
+}
+
+func TestBuilder_changesets(t *testing.T) {
+	b := NewBuilder()
+	c := &osm.Changeset{
+		ID:           1,
+		UserID:       5,
+		User:         "mapper",
+		CreatedAt:    time.Date(2019, 12, 31, 23, 0, 0, 0, time.UTC),
+		ChangesCount: 20,
+		MinLat:       1,
+		MaxLat:       2,
+		MinLon:       3,
+		MaxLon:       4,
+	}
+	b.Add(c)
+	b.Add(c) // duplicates are ignored
+	b.Add(&osm.Changeset{ID: 2, UserID: 5, CreatedAt: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), ChangesCount: 5})
+
+	tl := b.Timeline(5)
+	if tl.Edits != 25 || tl.Changesets != 2 || len(tl.Months) != 2 {
+		t.Errorf("incorrect timeline: %+v", tl)
+	}
+
+	if s := tl.Spread; s == nil || s.MinLat != 1 || s.MaxLon != 4 || s.Tiles != 1 {
+		t.Errorf("incorrect spread: %+v", s)
+	}
+
+	data, err := json.Marshal(tl)
+	if err != nil {
+		t.Fatalf("marshal error: %v", err)
+	}
+
+	expected := `{"uid":5,"user":"mapper","first_edit":"2019-12-31T23:00:00Z","last_edit":"2020-01-01T00:00:00Z",` +
+		`"edits":25,"changesets":2,` +
+		`"months":[{"month":"2019-12","edits":20,"changesets":1},{"month":"2020-01","edits":5,"changesets":1}],` +
+		`"spread":{"min_lat":1,"max_lat":2,"min_lon":3,"max_lon":4,"tiles":1}}`
+	if string(data) != expected {
+		t.Errorf("incorrect json: %s", data)
+	}
+}
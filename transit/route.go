@@ -0,0 +1,178 @@
+// Package transit extracts public transport routes, using the
+// Public Transport Version 2 (PTv2) tagging scheme, from osm relations.
+// https://wiki.openstreetmap.org/wiki/Public_transport
+package transit
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/paulmach/osm"
+)
+
+// ErrNotPublicTransport is returned when creating a route, or route master,
+// from a relation that is not a public transport route.
+var ErrNotPublicTransport = errors.New("transit: not a public transport route")
+
+// Modes are the route tag values of public transport routes.
+var Modes = map[string]bool{
+	"bus":        true,
+	"trolleybus": true,
+	"minibus":    true,
+	"share_taxi": true,
+	"coach":      true,
+	"train":      true,
+	"subway":     true,
+	"light_rail": true,
+	"monorail":   true,
+	"tram":       true,
+	"ferry":      true,
+	"funicular":  true,
+}
+
+// A Stop is a stop position or platform of the route, in order.
+type Stop struct {
+	// Index is the position of the member in the relation.
+	Index int
+	Type  osm.Type
+	Ref   int64
+
+	// EntryOnly and ExitOnly are set by the
+	// *_entry_only and *_exit_only roles.
+	EntryOnly bool
+	ExitOnly  bool
+}
+
+// A Route is a single variant, in one direction,
+// of a public transport line.
+type Route struct {
+	ID osm.RelationID
+
+	// Mode is the route tag, e.g. bus or tram.
+	Mode     string
+	Ref      string
+	Name     string
+	From     string
+	To       string
+	Network  string
+	Operator string
+
+	// PTv2 is true if tagged with public_transport:version=2.
+	PTv2 bool
+
+	// Stops are the stop positions, usually on the ways, where
+	// the vehicle stops. Platforms are where the passengers wait.
+	Stops     []Stop
+	Platforms []Stop
+
+	// Ways are the path of the route, in order.
+	Ways []osm.WayID
+
+	relation *osm.Relation
+}
+
+// NewRoute creates a route from a type=route relation with
+// a public transport mode.
+func NewRoute(r *osm.Relation) (*Route, error) {
+	mode := r.Tags.Find("route")
+	if r.Tags.Find("type") != "route" || !Modes[mode] {
+		return nil, ErrNotPublicTransport
+	}
+
+	route := &Route{
+		ID:       r.ID,
+		Mode:     mode,
+		Ref:      r.Tags.Find("ref"),
+		Name:     r.Tags.Find("name"),
+		From:     r.Tags.Find("from"),
+		To:       r.Tags.Find("to"),
+		Network:  r.Tags.Find("network"),
+		Operator: r.Tags.Find("operator"),
+		PTv2:     r.Tags.Find("public_transport:version") == "2",
+		relation: r,
+	}
+
+	for i, m := range r.Members {
+		role := m.Role
+		stop := Stop{
+			Index:     i,
+			Type:      m.Type,
+			Ref:       m.Ref,
+			EntryOnly: strings.HasSuffix(role, "_entry_only"),
+			ExitOnly:  strings.HasSuffix(role, "_exit_only"),
+		}
+
+		switch {
+		case strings.HasPrefix(role, "stop"):
+			route.Stops = append(route.Stops, stop)
+		case strings.HasPrefix(role, "platform"):
+			route.Platforms = append(route.Platforms, stop)
+		case m.Type == osm.TypeWay:
+			route.Ways = append(route.Ways, osm.WayID(m.Ref))
+		}
+	}
+
+	return route, nil
+}
+
+// Tags returns the tags of the route relation.
+func (r *Route) Tags() osm.Tags {
+	return r.relation.Tags
+}
+
+// A RouteMaster groups the route variants of a public transport line.
+type RouteMaster struct {
+	ID       osm.RelationID
+	Mode     string
+	Ref      string
+	Name     string
+	Network  string
+	Operator string
+
+	Routes []osm.RelationID
+}
+
+// NewRouteMaster creates a route master from a type=route_master relation
+// with a public transport mode.
+func NewRouteMaster(r *osm.Relation) (*RouteMaster, error) {
+	mode := r.Tags.Find("route_master")
+	if r.Tags.Find("type") != "route_master" || !Modes[mode] {
+		return nil, ErrNotPublicTransport
+	}
+
+	rm := &RouteMaster{
+		ID:       r.ID,
+		Mode:     mode,
+		Ref:      r.Tags.Find("ref"),
+		Name:     r.Tags.Find("name"),
+		Network:  r.Tags.Find("network"),
+		Operator: r.Tags.Find("operator"),
+	}
+
+	for _, m := range r.Members {
+		if m.Type == osm.TypeRelation {
+			rm.Routes = append(rm.Routes, osm.RelationID(m.Ref))
+		}
+	}
+
+	return rm, nil
+}
+
+// Extract returns the public transport routes and route masters
+// in the relations. Other relations are skipped.
+func Extract(relations osm.Relations) ([]*Route, []*RouteMaster) {
+	var (
+		routes  []*Route
+		masters []*RouteMaster
+	)
+
+	for _, r := range relations {
+		if route, err := NewRoute(r); err == nil {
+			routes = append(routes, route)
+		} else if rm, err := NewRouteMaster(r); err == nil {
+			masters = append(masters, rm)
+		}
+	}
+
+	return routes, masters
+}
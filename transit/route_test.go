@@ -0,0 +1,113 @@
+package transit
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/paulmach/osm"
+)
+
+func testRoute() *osm.Relation {
+	return &osm.Relation{
+		ID: 1,
+		Tags: osm.Tags{
+			{Key: "type", Value: "route"},
+			{Key: "route", Value: "bus"},
+			{Key: "ref", Value: "42"},
+			{Key: "name", Value: "Bus 42: A => B"},
+			{Key: "from", Value: "A"},
+			{Key: "to", Value: "B"},
+			{Key: "public_transport:version", Value: "2"},
+		},
+		Members: osm.Members{
+			{Type: osm.TypeNode, Ref: 1, Role: "stop_entry_only"},
+			{Type: osm.TypeWay, Ref: 2, Role: "platform"},
+			{Type: osm.TypeNode, Ref: 3, Role: "stop_exit_only"},
+			{Type: osm.TypeNode, Ref: 4, Role: "platform"},
+			{Type: osm.TypeWay, Ref: 10, Role: ""},
+			{Type: osm.TypeWay, Ref: 11, Role: ""},
+			{Type: osm.TypeWay, Ref: 12, Role: "forward"},
+		},
+	}
+}
+
+func TestNewRoute(t *testing.T) {
+	route, err := NewRoute(testRoute())
+	if err != nil {
+		t.Fatalf("create error: %v", err)
+	}
+
+	if route.Mode != "bus" || route.Ref != "42" || route.From != "A" || route.To != "B" || !route.PTv2 {
+		t.Errorf("incorrect route: %+v", route)
+	}
+
+	expected := []Stop{
+		{Index: 0, Type: osm.TypeNode, Ref: 1, EntryOnly: true},
+		{Index: 2, Type: osm.TypeNode, Ref: 3, ExitOnly: true},
+	}
+	if !reflect.DeepEqual(route.Stops, expected) {
+		t.Errorf("incorrect stops: %v", route.Stops)
+	}
+
+	expected = []Stop{
+		{Index: 1, Type: osm.TypeWay, Ref: 2},
+		{Index: 3, Type: osm.TypeNode, Ref: 4},
+	}
+	if !reflect.DeepEqual(route.Platforms, expected) {
+		t.Errorf("incorrect platforms: %v", route.Platforms)
+	}
+
+	if !reflect.DeepEqual(route.Ways, []osm.WayID{10, 11, 12}) {
+		t.Errorf("incorrect ways: %v", route.Ways)
+	}
+
+	hiking := &osm.Relation{Tags: osm.Tags{{Key: "type", Value: "route"}, {Key: "route", Value: "hiking"}}}
+	if _, err := NewRoute(hiking); err != ErrNotPublicTransport {
+		t.Errorf("incorrect error: %v", err)
+	}
+}
+
+func TestNewRouteMaster(t *testing.T) {
+	r := &osm.Relation{
+		ID: 5,
+		Tags: osm.Tags{
+			{Key: "type", Value: "route_master"},
+			{Key: "route_master", Value: "tram"},
+			{Key: "ref", Value: "7"},
+		},
+		Members: osm.Members{
+			{Type: osm.TypeRelation, Ref: 1},
+			{Type: osm.TypeRelation, Ref: 2},
+		},
+	}
+
+	rm, err := NewRouteMaster(r)
+	if err != nil {
+		t.Fatalf("create error: %v", err)
+	}
+
+	if rm.Mode != "tram" || rm.Ref != "7" || !reflect.DeepEqual(rm.Routes, []osm.RelationID{1, 2}) {
+		t.Errorf("incorrect route master: %+v", rm)
+	}
+
+	if _, err := NewRouteMaster(testRoute()); err != ErrNotPublicTransport {
+		t.Errorf("incorrect error: %v", err)
+	}
+}
+
+func TestExtract(t *testing.T) {
+	relations := osm.Relations{
+		testRoute(),
+		{ID: 2, Tags: osm.Tags{{Key: "type", Value: "multipolygon"}}},
+		{ID: 3, Tags: osm.Tags{{Key: "type", Value: "route_master"}, {Key: "route_master", Value: "bus"}}},
+	}
+
+	routes, masters := Extract(relations)
+	if len(routes) != 1 || routes[0].ID != 1 {
+		t.Errorf("incorrect routes: %v", routes)
+	}
+
+	if len(masters) != 1 || masters[0].ID != 3 {
+		t.Errorf("incorrect route masters: %v", masters)
+	}
+}
@@ -0,0 +1,135 @@
+package transit
+
+import (
+	"strings"
+
+	"github.com/paulmach/osm"
+)
+
+// ProblemReason is why a route does not follow the PTv2 scheme.
+type ProblemReason string
+
+// The route problems.
+const (
+	// NotPTv2 is a route without public_transport:version=2.
+	NotPTv2 ProblemReason = "not_ptv2"
+
+	// NoWays is a route without any ways.
+	NoWays ProblemReason = "no_ways"
+
+	// StopAfterWay is a stop or platform member after the first way,
+	// PTv2 requires the stops and platforms to come first.
+	StopAfterWay ProblemReason = "stop_after_way"
+
+	// UnknownMember is a member that is not a stop, platform or way.
+	UnknownMember ProblemReason = "unknown_member"
+
+	// Gap is a way that does not connect to the previous way.
+	Gap ProblemReason = "gap"
+
+	// MissingWay is a way not in the data, so the
+	// route continuity could not be checked.
+	MissingWay ProblemReason = "missing_way"
+)
+
+// A Problem is an issue with the route.
+type Problem struct {
+	Reason ProblemReason
+
+	// Index of the relation member, -1 for the whole route.
+	Index int
+}
+
+// Validate checks the route follows the PTv2 scheme. The members must be
+// ordered as the stops and platforms first, in the order they are served,
+// followed by the ways.
+func (r *Route) Validate() []Problem {
+	var problems []Problem
+	if !r.PTv2 {
+		problems = append(problems, Problem{Reason: NotPTv2, Index: -1})
+	}
+
+	if len(r.Ways) == 0 {
+		problems = append(problems, Problem{Reason: NoWays, Index: -1})
+	}
+
+	seenWay := false
+	for i, m := range r.relation.Members {
+		switch {
+		case isStopRole(m.Role):
+			if seenWay {
+				problems = append(problems, Problem{Reason: StopAfterWay, Index: i})
+			}
+		case m.Type == osm.TypeWay:
+			seenWay = true
+		default:
+			problems = append(problems, Problem{Reason: UnknownMember, Index: i})
+		}
+	}
+
+	return problems
+}
+
+// ValidateWays checks the ways of the route form a continuous path.
+// Consecutive ways must share an end node. Roundabouts, closed ways,
+// can be entered and exited at any node.
+func (r *Route) ValidateWays(ways map[osm.WayID]*osm.Way) []Problem {
+	var problems []Problem
+
+	// the possible end nodes of the path so far
+	var ends []osm.NodeID
+	for i, m := range r.relation.Members {
+		if isStopRole(m.Role) || m.Type != osm.TypeWay {
+			continue
+		}
+
+		w := ways[osm.WayID(m.Ref)]
+		if w == nil || len(w.Nodes) == 0 {
+			problems = append(problems, Problem{Reason: MissingWay, Index: i})
+			ends = nil
+			continue
+		}
+
+		first, last := w.Nodes[0].ID, w.Nodes[len(w.Nodes)-1].ID
+		closed := first == last
+
+		next := []osm.NodeID{first, last}
+		if len(ends) > 0 {
+			switch {
+			case closed:
+				next = w.Nodes.NodeIDs()
+				if !containsAny(next, ends) {
+					problems = append(problems, Problem{Reason: Gap, Index: i})
+				}
+			case containsAny(ends, []osm.NodeID{first}):
+				next = []osm.NodeID{last}
+			case containsAny(ends, []osm.NodeID{last}):
+				next = []osm.NodeID{first}
+			default:
+				problems = append(problems, Problem{Reason: Gap, Index: i})
+			}
+		} else if closed {
+			next = w.Nodes.NodeIDs()
+		}
+
+		ends = next
+	}
+
+	return problems
+}
+
+func isStopRole(role string) bool {
+	return strings.HasPrefix(role, "stop") || strings.HasPrefix(role, "platform")
+}
+
+func containsAny(a, b []osm.NodeID) bool {
+	for _, x := range a {
+		for _, y := range b {
+			if x == y {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,98 @@
+package transit
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/paulmach/osm"
+)
+
+func TestRoute_Validate(t *testing.T) {
+	route, _ := NewRoute(testRoute())
+	if problems := route.Validate(); len(problems) != 0 {
+		t.Errorf("should be valid: %v", problems)
+	}
+
+	r := testRoute()
+	r.Tags = r.Tags[:len(r.Tags)-1]
+	r.Members = append(r.Members,
+		osm.Member{Type: osm.TypeNode, Ref: 5, Role: "stop"},
+		osm.Member{Type: osm.TypeNode, Ref: 6, Role: ""},
+	)
+
+	route, _ = NewRoute(r)
+	problems := route.Validate()
+	expected := []Problem{
+		{Reason: NotPTv2, Index: -1},
+		{Reason: StopAfterWay, Index: 7},
+		{Reason: UnknownMember, Index: 8},
+	}
+	if !reflect.DeepEqual(problems, expected) {
+		t.Errorf("incorrect problems: %v", problems)
+	}
+
+	r.Members = r.Members[:4]
+	route, _ = NewRoute(r)
+	problems = route.Validate()
+	expected = []Problem{
+		{Reason: NotPTv2, Index: -1},
+		{Reason: NoWays, Index: -1},
+	}
+	if !reflect.DeepEqual(problems, expected) {
+		t.Errorf("incorrect problems: %v", problems)
+	}
+}
+
+func TestRoute_ValidateWays(t *testing.T) {
+	way := func(id osm.WayID, nodes ...osm.NodeID) *osm.Way {
+		w := &osm.Way{ID: id}
+		for _, n := range nodes {
+			w.Nodes = append(w.Nodes, osm.WayNode{ID: n})
+		}
+		return w
+	}
+
+	cases := []struct {
+		name     string
+		ways     []*osm.Way
+		expected []Problem
+	}{
+		{
+			name: "connected",
+			ways: []*osm.Way{way(10, 1, 2), way(11, 2, 3), way(12, 3, 4)},
+		},
+		{
+			name: "first way reversed",
+			ways: []*osm.Way{way(10, 2, 1), way(11, 2, 3), way(12, 4, 3)},
+		},
+		{
+			name:     "gap",
+			ways:     []*osm.Way{way(10, 1, 2), way(11, 3, 4), way(12, 4, 5)},
+			expected: []Problem{{Reason: Gap, Index: 5}},
+		},
+		{
+			name: "roundabout",
+			ways: []*osm.Way{way(10, 1, 2), way(11, 2, 3, 4, 5, 2), way(12, 4, 6)},
+		},
+		{
+			name:     "missing",
+			ways:     []*osm.Way{way(10, 1, 2), way(12, 7, 8)},
+			expected: []Problem{{Reason: MissingWay, Index: 5}},
+		},
+	}
+
+	route, _ := NewRoute(testRoute())
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ways := make(map[osm.WayID]*osm.Way)
+			for _, w := range tc.ways {
+				ways[w.ID] = w
+			}
+
+			problems := route.ValidateWays(ways)
+			if !reflect.DeepEqual(problems, tc.expected) {
+				t.Errorf("incorrect problems: %v", problems)
+			}
+		})
+	}
+}
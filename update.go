@@ -86,7 +86,7 @@ func (us updatesSortIndex) Less(i, j int) bool {
 	return us[i].Timestamp.Before(us[j].Timestamp)
 }
 
-func marshalUpdates(updates Updates) *osmpb.DenseMembers {
+func marshalUpdates(updates Updates, mode RoundingMode) *osmpb.DenseMembers {
 	if len(updates) == 0 {
 		return nil
 	}
@@ -109,8 +109,8 @@ func marshalUpdates(updates Updates) *osmpb.DenseMembers {
 		changesetIDs[i] = int64(u.ChangesetID)
 		if u.Lat != 0 || u.Lon != 0 {
 			hasLoc = true
-			lats[i] = geoToInt64(u.Lat)
-			lons[i] = geoToInt64(u.Lon)
+			lats[i] = GeoToInt64(u.Lat, mode)
+			lons[i] = GeoToInt64(u.Lon, mode)
 		}
 
 		if u.Reverse {
@@ -120,10 +120,10 @@ func marshalUpdates(updates Updates) *osmpb.DenseMembers {
 	}
 
 	result := &osmpb.DenseMembers{
-		Indexes:      encodeInt32(indexes),
-		Versions:     versions,
-		ChangesetIds: encodeInt64(changesetIDs),
-		Timestamps:   encodeInt64(timestamps),
+		Indexes:       encodeInt32(indexes),
+		VersionDeltas: encodeInt32(versions),
+		ChangesetIds:  encodeInt64(changesetIDs),
+		Timestamps:    encodeInt64(timestamps),
 	}
 
 	if hasLoc {
@@ -138,31 +138,47 @@ func marshalUpdates(updates Updates) *osmpb.DenseMembers {
 	return result
 }
 
-func unmarshalUpdates(encoded *osmpb.DenseMembers) Updates {
+func unmarshalUpdates(encoded *osmpb.DenseMembers) (Updates, error) {
 	if encoded == nil {
-		return nil
+		return nil, nil
+	}
+
+	// data before schema version 3 has the raw versions.
+	versions := encoded.Versions
+	if versions == nil {
+		versions = encoded.VersionDeltas
+	}
+
+	l := len(encoded.Indexes)
+	if len(versions) != l || len(encoded.ChangesetIds) != l || len(encoded.Timestamps) != l ||
+		len(encoded.Lats) != len(encoded.Lons) {
+		return nil, errDenseLength
+	}
+
+	if encoded.Versions == nil {
+		versions = decodeInt32(versions)
 	}
 
 	result := make([]Update, len(encoded.Indexes))
 
-	decodeInt32(encoded.Indexes)
-	decodeInt64(encoded.ChangesetIds)
-	decodeInt64(encoded.Timestamps)
+	indexes := decodeInt32(encoded.Indexes)
+	changesetIDs := decodeInt64(encoded.ChangesetIds)
+	timestamps := decodeInt64(encoded.Timestamps)
 
-	decodeInt64(encoded.Lats)
-	decodeInt64(encoded.Lons)
+	lats := decodeInt64(encoded.Lats)
+	lons := decodeInt64(encoded.Lons)
 
-	for i := range encoded.Indexes {
+	for i := range indexes {
 		result[i] = Update{
-			Index:       int(encoded.Indexes[i]),
-			Version:     int(encoded.Versions[i]),
-			ChangesetID: ChangesetID(encoded.ChangesetIds[i]),
-			Timestamp:   unixToTime(encoded.Timestamps[i]),
+			Index:       int(indexes[i]),
+			Version:     int(versions[i]),
+			ChangesetID: ChangesetID(changesetIDs[i]),
+			Timestamp:   unixToTime(timestamps[i]),
 		}
 
-		if len(encoded.Lats) > i {
-			result[i].Lat = float64(encoded.Lats[i]) / locMultiple
-			result[i].Lon = float64(encoded.Lons[i]) / locMultiple
+		if len(lats) > i {
+			result[i].Lat = float64(lats[i]) / locMultiple
+			result[i].Lon = float64(lons[i]) / locMultiple
 		}
 
 		if len(encoded.Orientation) > i && encoded.Orientation[i] > 0 {
@@ -170,5 +186,5 @@ func unmarshalUpdates(encoded *osmpb.DenseMembers) Updates {
 		}
 	}
 
-	return result
+	return result, nil
 }
@@ -0,0 +1,89 @@
+package osm
+
+import (
+	"fmt"
+	"strings"
+)
+
+// WebsiteURL is the base of the urls returned by the URL and EditURL
+// helpers. It can be changed to point to a different osm website,
+// for example https://master.apis.dev.openstreetmap.org.
+var WebsiteURL = "https://www.openstreetmap.org"
+
+// JOSMRemoteControlURL is the base of the urls returned by the JOSMURL
+// helpers. JOSM listens on this address when remote control is enabled.
+var JOSMRemoteControlURL = "http://127.0.0.1:8111"
+
+// URL returns the osm website url of the node.
+func (n *Node) URL() string {
+	return elementURL(TypeNode, int64(n.ID))
+}
+
+// EditURL returns the url to edit the node in the default web editor.
+func (n *Node) EditURL() string {
+	return editURL(TypeNode, int64(n.ID))
+}
+
+// JOSMURL returns the remote control url to load the node into JOSM.
+func (n *Node) JOSMURL() string {
+	return josmURL(TypeNode, int64(n.ID))
+}
+
+// URL returns the osm website url of the way.
+func (w *Way) URL() string {
+	return elementURL(TypeWay, int64(w.ID))
+}
+
+// EditURL returns the url to edit the way in the default web editor.
+func (w *Way) EditURL() string {
+	return editURL(TypeWay, int64(w.ID))
+}
+
+// JOSMURL returns the remote control url to load the way into JOSM.
+func (w *Way) JOSMURL() string {
+	return josmURL(TypeWay, int64(w.ID))
+}
+
+// URL returns the osm website url of the relation.
+func (r *Relation) URL() string {
+	return elementURL(TypeRelation, int64(r.ID))
+}
+
+// EditURL returns the url to edit the relation in the default web editor.
+func (r *Relation) EditURL() string {
+	return editURL(TypeRelation, int64(r.ID))
+}
+
+// JOSMURL returns the remote control url to load the relation, and its
+// members, into JOSM.
+func (r *Relation) JOSMURL() string {
+	return josmURL(TypeRelation, int64(r.ID)) + "&relation_members=true"
+}
+
+// URL returns the osm website url of the changeset.
+func (c *Changeset) URL() string {
+	return elementURL(TypeChangeset, int64(c.ID))
+}
+
+// JOSMURL returns the remote control url to load the changeset
+// into JOSM. The changeset is loaded as it was after the edit.
+func (c *Changeset) JOSMURL() string {
+	return fmt.Sprintf("%s/import?url=%s/api/0.6/changeset/%d/download",
+		baseURL(JOSMRemoteControlURL), baseURL(WebsiteURL), c.ID)
+}
+
+func elementURL(t Type, ref int64) string {
+	return fmt.Sprintf("%s/%s/%d", baseURL(WebsiteURL), t, ref)
+}
+
+func editURL(t Type, ref int64) string {
+	return fmt.Sprintf("%s/edit?%s=%d", baseURL(WebsiteURL), t, ref)
+}
+
+func josmURL(t Type, ref int64) string {
+	return fmt.Sprintf("%s/load_object?objects=%s%d", baseURL(JOSMRemoteControlURL), t[:1], ref)
+}
+
+func baseURL(u string) string {
+	return strings.TrimRight(u, "/")
+}
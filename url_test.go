@@ -0,0 +1,92 @@
+package osm
+
+import "testing"
+
+func TestURLs(t *testing.T) {
+	cases := []struct {
+		name     string
+		url      string
+		expected string
+	}{
+		{
+			name:     "node",
+			url:      (&Node{ID: 1}).URL(),
+			expected: "https://www.openstreetmap.org/node/1",
+		},
+		{
+			name:     "node edit",
+			url:      (&Node{ID: 1}).EditURL(),
+			expected: "https://www.openstreetmap.org/edit?node=1",
+		},
+		{
+			name:     "node josm",
+			url:      (&Node{ID: 1}).JOSMURL(),
+			expected: "http://127.0.0.1:8111/load_object?objects=n1",
+		},
+		{
+			name:     "way",
+			url:      (&Way{ID: 2}).URL(),
+			expected: "https://www.openstreetmap.org/way/2",
+		},
+		{
+			name:     "way edit",
+			url:      (&Way{ID: 2}).EditURL(),
+			expected: "https://www.openstreetmap.org/edit?way=2",
+		},
+		{
+			name:     "way josm",
+			url:      (&Way{ID: 2}).JOSMURL(),
+			expected: "http://127.0.0.1:8111/load_object?objects=w2",
+		},
+		{
+			name:     "relation",
+			url:      (&Relation{ID: 3}).URL(),
+			expected: "https://www.openstreetmap.org/relation/3",
+		},
+		{
+			name:     "relation edit",
+			url:      (&Relation{ID: 3}).EditURL(),
+			expected: "https://www.openstreetmap.org/edit?relation=3",
+		},
+		{
+			name:     "relation josm",
+			url:      (&Relation{ID: 3}).JOSMURL(),
+			expected: "http://127.0.0.1:8111/load_object?objects=r3&relation_members=true",
+		},
+		{
+			name:     "changeset",
+			url:      (&Changeset{ID: 4}).URL(),
+			expected: "https://www.openstreetmap.org/changeset/4",
+		},
+		{
+			name:     "changeset josm",
+			url:      (&Changeset{ID: 4}).JOSMURL(),
+			expected: "http://127.0.0.1:8111/import?url=https://www.openstreetmap.org/api/0.6/changeset/4/download",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.url != tc.expected {
+				t.Errorf("incorrect url: %v != %v", tc.url, tc.expected)
+			}
+		})
+	}
+}
+
+func TestURLs_base(t *testing.T) {
+	defer func(w, j string) {
+		WebsiteURL, JOSMRemoteControlURL = w, j
+	}(WebsiteURL, JOSMRemoteControlURL)
+
+	WebsiteURL = "https://master.apis.dev.openstreetmap.org/"
+	JOSMRemoteControlURL = "https://127.0.0.1:8112"
+
+	if v := (&Way{ID: 2}).URL(); v != "https://master.apis.dev.openstreetmap.org/way/2" {
+		t.Errorf("incorrect url: %v", v)
+	}
+
+	if v := (&Way{ID: 2}).JOSMURL(); v != "https://127.0.0.1:8112/load_object?objects=w2" {
+		t.Errorf("incorrect url: %v", v)
+	}
+}
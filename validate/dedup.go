@@ -0,0 +1,233 @@
+package validate
+
+import (
+	"sort"
+
+	"github.com/paulmach/osm"
+)
+
+// Dedup adds the edits to remove the duplicates to the change builder.
+// Duplicate ways are deleted, relations are updated to reference the kept
+// way. Stacked nodes are merged into the kept node, see osm.MergeNodes.
+// Missing tags are added to the kept element. Near duplicate ways are not
+// fixed since their nodes would need to be merged individually.
+// The data is the source of the parent ways and relations of the
+// duplicates, it is not modified.
+func Dedup(o *osm.OSM, dups []Duplicate, b *osm.ChangeBuilder) error {
+	d := &deduper{
+		ways:      make(map[osm.WayID]*osm.Way, len(o.Ways)),
+		relations: make(map[osm.RelationID]*osm.Relation, len(o.Relations)),
+		nodes:     make(map[osm.NodeID]*osm.Node),
+		modified:  make(map[osm.Type]map[int64]bool),
+	}
+
+	for _, w := range o.Ways {
+		d.ways[w.ID] = w
+	}
+
+	for _, r := range o.Relations {
+		d.relations[r.ID] = r
+	}
+
+	var deletes osm.Elements
+
+	// ways first so merged nodes are not added to deleted ways
+	for _, dup := range dups {
+		if dup.Type != DuplicateWay {
+			continue
+		}
+
+		keep := dup.Elements[0].(*osm.Way)
+		for _, e := range dup.Elements[1:] {
+			discard := e.(*osm.Way)
+			d.mergeWay(keep.ID, discard)
+			deletes = append(deletes, discard)
+		}
+	}
+
+	for _, dup := range dups {
+		if dup.Type != StackedNodes {
+			continue
+		}
+
+		keep := dup.Elements[0].(*osm.Node)
+		for _, e := range dup.Elements[1:] {
+			discard := e.(*osm.Node)
+			if d.nodes[keep.ID] == nil {
+				d.nodes[keep.ID] = keep
+			}
+
+			if err := d.mergeNode(keep.ID, discard); err != nil {
+				return err
+			}
+			deletes = append(deletes, discard)
+		}
+	}
+
+	for _, id := range d.sorted(osm.TypeNode) {
+		if err := createOrModify(b, d.nodes[osm.NodeID(id)]); err != nil {
+			return err
+		}
+	}
+
+	for _, id := range d.sorted(osm.TypeWay) {
+		if err := createOrModify(b, d.ways[osm.WayID(id)]); err != nil {
+			return err
+		}
+	}
+
+	for _, id := range d.sorted(osm.TypeRelation) {
+		if err := createOrModify(b, d.relations[osm.RelationID(id)]); err != nil {
+			return err
+		}
+	}
+
+	for _, e := range deletes {
+		if version(e) == 0 {
+			continue
+		}
+
+		if err := b.Delete(e); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// deduper tracks the current version of the elements as the
+// duplicates are merged one by one.
+type deduper struct {
+	ways      map[osm.WayID]*osm.Way
+	relations map[osm.RelationID]*osm.Relation
+	nodes     map[osm.NodeID]*osm.Node
+	modified  map[osm.Type]map[int64]bool
+}
+
+func (d *deduper) markModified(t osm.Type, id int64) {
+	if d.modified[t] == nil {
+		d.modified[t] = make(map[int64]bool)
+	}
+	d.modified[t][id] = true
+}
+
+// sorted returns the modified ids of the type so the change is deterministic.
+func (d *deduper) sorted(t osm.Type) []int64 {
+	ids := make([]int64, 0, len(d.modified[t]))
+	for id := range d.modified[t] {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	return ids
+}
+
+func (d *deduper) mergeWay(keep osm.WayID, discard *osm.Way) {
+	w := d.ways[keep].Copy()
+	if added := addMissingTags(&w.Tags, discard.Tags); added {
+		d.ways[keep] = w
+		d.markModified(osm.TypeWay, int64(keep))
+	}
+	delete(d.ways, discard.ID)
+	delete(d.modified[osm.TypeWay], int64(discard.ID))
+
+	for id, r := range d.relations {
+		changed := false
+		members := make(osm.Members, 0, len(r.Members))
+		seen := make(map[osm.Member]bool)
+		for _, m := range r.Members {
+			if m.Type == osm.TypeWay && m.Ref == int64(discard.ID) {
+				m.Ref = int64(keep)
+				changed = true
+			}
+
+			if m.Type == osm.TypeWay && m.Ref == int64(keep) {
+				k := osm.Member{Type: m.Type, Ref: m.Ref, Role: m.Role}
+				if seen[k] {
+					continue
+				}
+				seen[k] = true
+			}
+
+			members = append(members, m)
+		}
+
+		if changed {
+			r = r.Copy()
+			r.Members = members
+			d.relations[id] = r
+			d.markModified(osm.TypeRelation, int64(id))
+		}
+	}
+}
+
+func (d *deduper) mergeNode(keepID osm.NodeID, discard *osm.Node) error {
+	keep := d.nodes[keepID]
+
+	var ways osm.Ways
+	for _, w := range d.ways {
+		ways = append(ways, w)
+	}
+
+	var relations osm.Relations
+	for _, r := range d.relations {
+		relations = append(relations, r)
+	}
+
+	result, err := osm.MergeNodes(keep, discard, ways, relations)
+	if err != nil {
+		return err
+	}
+
+	if len(result.Node.Tags) != len(keep.Tags) {
+		d.nodes[keepID] = result.Node
+		d.markModified(osm.TypeNode, int64(keepID))
+	}
+
+	for _, w := range result.Ways {
+		d.ways[w.ID] = w
+		d.markModified(osm.TypeWay, int64(w.ID))
+	}
+
+	for _, r := range result.Relations {
+		d.relations[r.ID] = r
+		d.markModified(osm.TypeRelation, int64(r.ID))
+	}
+
+	return nil
+}
+
+func addMissingTags(tags *osm.Tags, other osm.Tags) bool {
+	m := tags.Map()
+
+	added := false
+	for _, t := range other {
+		if _, ok := m[t.Key]; !ok {
+			*tags = append(*tags, t)
+			added = true
+		}
+	}
+
+	return added
+}
+
+func createOrModify(b *osm.ChangeBuilder, e osm.Element) error {
+	if version(e) == 0 {
+		return b.Create(e)
+	}
+
+	return b.Modify(e)
+}
+
+func version(e osm.Element) int {
+	switch e := e.(type) {
+	case *osm.Node:
+		return e.Version
+	case *osm.Way:
+		return e.Version
+	case *osm.Relation:
+		return e.Version
+	}
+
+	return 0
+}
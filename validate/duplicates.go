@@ -0,0 +1,276 @@
+package validate
+
+import (
+	"math"
+	"sort"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geo"
+	"github.com/paulmach/osm"
+)
+
+// DuplicateType is the kind of duplicate found.
+type DuplicateType string
+
+// The duplicate types.
+const (
+	// StackedNodes are nodes at the same location, within the epsilon.
+	StackedNodes DuplicateType = "stacked_nodes"
+
+	// DuplicateWay are ways with the same nodes, in the same
+	// or reverse order.
+	DuplicateWay DuplicateType = "duplicate_way"
+
+	// NearDuplicateWay are ways with different nodes at the same
+	// locations, within the epsilon, for example imported twice.
+	NearDuplicateWay DuplicateType = "near_duplicate_way"
+)
+
+// A Duplicate is a set of elements that are duplicates of each other.
+// Only elements with overlapping tags, no keys with different values,
+// are considered duplicates.
+type Duplicate struct {
+	Type DuplicateType
+
+	// Elements are the duplicates, the first is the one kept when
+	// deduplicating. Existing elements are sorted before new ones,
+	// then by id.
+	Elements osm.Elements
+
+	// Point is the location of the first element, or its first node.
+	Point orb.Point
+}
+
+// Duplicates finds stacked nodes and duplicate ways in the data. Ways
+// must be annotated, have way node locations, to find near duplicates.
+// Nodes are compared exactly unless the Epsilon option is set.
+func Duplicates(o *osm.OSM, opts ...Option) []Duplicate {
+	opt := newOptions(opts)
+
+	var result []Duplicate
+	result = append(result, stackedNodes(o.Nodes, opt.epsilon)...)
+	result = append(result, duplicateWays(o.Ways, opt.epsilon)...)
+
+	return result
+}
+
+func stackedNodes(nodes osm.Nodes, epsilon float64) []Duplicate {
+	nodes = append(osm.Nodes(nil), nodes...)
+	sort.Slice(nodes, func(i, j int) bool { return keepFirst(int64(nodes[i].ID), int64(nodes[j].ID)) })
+
+	// the grid cell is at least epsilon wide so only the
+	// neighbouring cells need to be checked.
+	cell := epsilon / orb.EarthRadius * 180 / math.Pi
+	type key struct{ x, y int64 }
+	cellOf := func(p orb.Point) key {
+		if cell == 0 {
+			return key{int64(math.Float64bits(p[0])), int64(math.Float64bits(p[1]))}
+		}
+		x := p[0] * math.Cos(p[1]*math.Pi/180)
+		return key{int64(math.Floor(x / cell)), int64(math.Floor(p[1] / cell))}
+	}
+
+	grid := make(map[key][]int)
+	groups := make([]osm.Nodes, 0, len(nodes))
+
+	for _, n := range nodes {
+		p := n.Point()
+		k := cellOf(p)
+
+		group := -1
+		for dx := int64(-1); dx <= 1 && group == -1; dx++ {
+			for dy := int64(-1); dy <= 1 && group == -1; dy++ {
+				if cell == 0 && (dx != 0 || dy != 0) {
+					continue
+				}
+
+				for _, g := range grid[key{k.x + dx, k.y + dy}] {
+					first := groups[g][0]
+					if geo.Distance(first.Point(), p) <= epsilon && overlappingTags(first.Tags, n.Tags) {
+						group = g
+						break
+					}
+				}
+			}
+		}
+
+		if group == -1 {
+			grid[k] = append(grid[k], len(groups))
+			groups = append(groups, osm.Nodes{n})
+			continue
+		}
+
+		groups[group] = append(groups[group], n)
+	}
+
+	var result []Duplicate
+	for _, g := range groups {
+		if len(g) < 2 {
+			continue
+		}
+
+		d := Duplicate{Type: StackedNodes, Point: g[0].Point()}
+		for _, n := range g {
+			d.Elements = append(d.Elements, n)
+		}
+		result = append(result, d)
+	}
+
+	return result
+}
+
+func duplicateWays(ways osm.Ways, epsilon float64) []Duplicate {
+	ways = append(osm.Ways(nil), ways...)
+	sort.Slice(ways, func(i, j int) bool { return keepFirst(int64(ways[i].ID), int64(ways[j].ID)) })
+
+	var result []Duplicate
+	used := make(map[osm.WayID]bool)
+
+	// exact duplicates have the same node ids
+	exact := make(map[string]int)
+	for _, w := range ways {
+		if len(w.Nodes) < 2 {
+			continue
+		}
+
+		k := wayKey(w)
+		if i, ok := exact[k]; ok && overlappingTags(result[i].Elements[0].(*osm.Way).Tags, w.Tags) {
+			result[i].Elements = append(result[i].Elements, w)
+			used[w.ID] = true
+			continue
+		}
+
+		exact[k] = len(result)
+		result = append(result, Duplicate{
+			Type:     DuplicateWay,
+			Elements: osm.Elements{w},
+			Point:    orb.Point{w.Nodes[0].Lon, w.Nodes[0].Lat},
+		})
+	}
+
+	filtered := result[:0]
+	for _, d := range result {
+		if len(d.Elements) > 1 {
+			used[d.Elements[0].(*osm.Way).ID] = true
+			filtered = append(filtered, d)
+		}
+	}
+	result = filtered
+
+	// near duplicates have nodes at the same locations
+	var located osm.Ways
+	for _, w := range ways {
+		if !used[w.ID] && len(w.Nodes) >= 2 && annotated(w) {
+			located = append(located, w)
+		}
+	}
+
+	for i, w1 := range located {
+		if used[w1.ID] {
+			continue
+		}
+
+		d := Duplicate{
+			Type:     NearDuplicateWay,
+			Elements: osm.Elements{w1},
+			Point:    orb.Point{w1.Nodes[0].Lon, w1.Nodes[0].Lat},
+		}
+
+		for _, w2 := range located[i+1:] {
+			if used[w2.ID] || !overlappingTags(w1.Tags, w2.Tags) || !nearSameNodes(w1.Nodes, w2.Nodes, epsilon) {
+				continue
+			}
+
+			used[w2.ID] = true
+			d.Elements = append(d.Elements, w2)
+		}
+
+		if len(d.Elements) > 1 {
+			result = append(result, d)
+		}
+	}
+
+	return result
+}
+
+// keepFirst sorts existing elements, with positive ids, before new ones.
+func keepFirst(a, b int64) bool {
+	if (a > 0) != (b > 0) {
+		return a > 0
+	}
+
+	if a > 0 {
+		return a < b
+	}
+
+	return a > b
+}
+
+// overlappingTags returns true if no key has different values.
+func overlappingTags(a, b osm.Tags) bool {
+	m := a.Map()
+	for _, t := range b {
+		if v, ok := m[t.Key]; ok && v != t.Value {
+			return false
+		}
+	}
+
+	return true
+}
+
+// wayKey is the node ids in the direction with the smallest first id,
+// so reversed ways have the same key.
+func wayKey(w *osm.Way) string {
+	ids := w.Nodes.NodeIDs()
+	if ids[len(ids)-1] < ids[0] {
+		for i, j := 0, len(ids)-1; i < j; i, j = i+1, j-1 {
+			ids[i], ids[j] = ids[j], ids[i]
+		}
+	}
+
+	b := make([]byte, 0, 8*len(ids))
+	for _, id := range ids {
+		v := uint64(id)
+		b = append(b, byte(v), byte(v>>8), byte(v>>16), byte(v>>24),
+			byte(v>>32), byte(v>>40), byte(v>>48), byte(v>>56))
+	}
+
+	return string(b)
+}
+
+func annotated(w *osm.Way) bool {
+	for _, n := range w.Nodes {
+		if n.Lat == 0 && n.Lon == 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// nearSameNodes returns true if the way nodes are at the same locations,
+// in the same or reverse order.
+func nearSameNodes(a, b osm.WayNodes, epsilon float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	near := func(i, j int) bool {
+		return geo.Distance(
+			orb.Point{a[i].Lon, a[i].Lat},
+			orb.Point{b[j].Lon, b[j].Lat},
+		) <= epsilon
+	}
+
+	forward, backward := true, true
+	for i := range a {
+		forward = forward && near(i, i)
+		backward = backward && near(i, len(b)-1-i)
+
+		if !forward && !backward {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,105 @@
+package validate
+
+import (
+	"testing"
+
+	"github.com/paulmach/osm"
+)
+
+func TestDuplicates(t *testing.T) {
+	o := &osm.OSM{
+		Nodes: osm.Nodes{
+			{ID: 3, Version: 1, Lat: 1, Lon: 1, Tags: osm.Tags{{Key: "amenity", Value: "bench"}}},
+			{ID: 1, Version: 1, Lat: 1, Lon: 1},
+			{ID: -1, Lat: 1, Lon: 1},
+			{ID: 4, Version: 1, Lat: 1.0000001, Lon: 1},
+			{ID: 5, Version: 1, Lat: 2, Lon: 2, Tags: osm.Tags{{Key: "shop", Value: "bakery"}}},
+			{ID: 6, Version: 1, Lat: 2, Lon: 2, Tags: osm.Tags{{Key: "shop", Value: "florist"}}},
+		},
+		Ways: osm.Ways{
+			{ID: 1, Version: 1, Nodes: osm.WayNodes{{ID: 10}, {ID: 11}}},
+			{ID: 2, Version: 1, Nodes: osm.WayNodes{{ID: 11}, {ID: 10}}, Tags: osm.Tags{{Key: "highway", Value: "path"}}},
+			{ID: 3, Version: 1, Nodes: osm.WayNodes{{ID: 20, Lat: 5, Lon: 5}, {ID: 21, Lat: 6, Lon: 6}}},
+			{ID: 4, Version: 1, Nodes: osm.WayNodes{{ID: 31, Lat: 6, Lon: 6}, {ID: 30, Lat: 5, Lon: 5}}},
+		},
+	}
+
+	dups := Duplicates(o)
+	if len(dups) != 3 {
+		t.Fatalf("incorrect number of duplicates: %v", dups)
+	}
+
+	if d := dups[0]; d.Type != StackedNodes || len(d.Elements) != 3 ||
+		d.Elements[0].(*osm.Node).ID != 1 || d.Elements[2].(*osm.Node).ID != -1 {
+		t.Errorf("incorrect stacked nodes: %v", d.Elements)
+	}
+
+	if d := dups[1]; d.Type != DuplicateWay || len(d.Elements) != 2 {
+		t.Errorf("incorrect duplicate ways: %v", d)
+	}
+
+	if d := dups[2]; d.Type != NearDuplicateWay || len(d.Elements) != 2 {
+		t.Errorf("incorrect near duplicate ways: %v", d)
+	}
+
+	// with an epsilon the nearby node is included
+	dups = Duplicates(o, Epsilon(0.1))
+	if len(dups[0].Elements) != 4 {
+		t.Errorf("should include nearby node: %v", dups[0].Elements)
+	}
+}
+
+func TestDedup(t *testing.T) {
+	o := &osm.OSM{
+		Nodes: osm.Nodes{
+			{ID: 1, Version: 1, Lat: 1, Lon: 1},
+			{ID: 2, Version: 1, Lat: 1, Lon: 1, Tags: osm.Tags{{Key: "barrier", Value: "gate"}}},
+		},
+		Ways: osm.Ways{
+			{ID: 1, Version: 1, Nodes: osm.WayNodes{{ID: 1}, {ID: 3}}},
+			{ID: 2, Version: 1, Nodes: osm.WayNodes{{ID: 4}, {ID: 2}, {ID: 5}}, Tags: osm.Tags{{Key: "highway", Value: "track"}}},
+			{ID: 3, Version: 1, Nodes: osm.WayNodes{{ID: 5}, {ID: 2}, {ID: 4}}},
+		},
+		Relations: osm.Relations{
+			{ID: 1, Version: 1, Members: osm.Members{
+				{Type: osm.TypeWay, Ref: 2},
+				{Type: osm.TypeWay, Ref: 3},
+			}},
+		},
+	}
+
+	b := osm.NewChangeBuilder()
+	if err := Dedup(o, Duplicates(o), b); err != nil {
+		t.Fatalf("dedup error: %v", err)
+	}
+
+	changes, err := b.Build()
+	if err != nil {
+		t.Fatalf("build error: %v", err)
+	}
+
+	c := changes[0]
+	if len(c.Delete.Nodes) != 1 || c.Delete.Nodes[0].ID != 2 {
+		t.Errorf("should delete stacked node: %v", c.Delete.Nodes)
+	}
+
+	if len(c.Delete.Ways) != 1 || c.Delete.Ways[0].ID != 3 {
+		t.Errorf("should delete duplicate way: %v", c.Delete.Ways)
+	}
+
+	if len(c.Modify.Nodes) != 1 || c.Modify.Nodes[0].Tags.Find("barrier") != "gate" {
+		t.Errorf("should add tags to kept node: %v", c.Modify.Nodes)
+	}
+
+	if len(c.Modify.Ways) != 1 || c.Modify.Ways[0].ID != 2 || c.Modify.Ways[0].Nodes[1].ID != 1 {
+		t.Errorf("should update way nodes: %v", c.Modify.Ways)
+	}
+
+	if len(c.Modify.Relations) != 1 || len(c.Modify.Relations[0].Members) != 1 {
+		t.Errorf("should remove duplicate member: %v", c.Modify.Relations)
+	}
+
+	if len(o.Relations[0].Members) != 2 {
+		t.Errorf("should not modify the input")
+	}
+}
@@ -0,0 +1,288 @@
+// Package validate checks osm data for common problems, like invalid
+// polygon geometry, so they can be reported or fixed before export.
+package validate
+
+import (
+	"github.com/paulmach/orb"
+	"github.com/paulmach/osm"
+)
+
+// ProblemType is the kind of geometry problem found.
+type ProblemType string
+
+// The geometry problems.
+const (
+	// NotClosed rings do not have matching first and last points.
+	NotClosed ProblemType = "not_closed"
+
+	// TooFewPoints rings need at least 4 points, including the repeated one.
+	TooFewPoints ProblemType = "too_few_points"
+
+	// MissingLocation is for ways with nodes without a lat/lon,
+	// the ways need to be annotated to be checked.
+	MissingLocation ProblemType = "missing_location"
+
+	// SelfIntersection is a ring that crosses or touches itself.
+	SelfIntersection ProblemType = "self_intersection"
+
+	// Spike is a point where the ring goes back along the previous segment.
+	Spike ProblemType = "spike"
+
+	// DuplicateRing is a ring with the same points as a previous ring.
+	DuplicateRing ProblemType = "duplicate_ring"
+
+	// WrongWinding is an outer ring that is not counter-clockwise
+	// or an inner ring that is not clockwise.
+	WrongWinding ProblemType = "wrong_winding"
+)
+
+// A Problem is an issue found with a geometry.
+type Problem struct {
+	Type ProblemType
+
+	// Polygon is the index of the polygon in a multipolygon.
+	Polygon int
+
+	// Ring is the index of the ring in the polygon, 0 is the outer ring.
+	Ring int
+
+	// Point is the location of the problem, if known.
+	Point orb.Point
+}
+
+// Way checks the closed way as a polygon ring. The way nodes must have
+// locations, i.e. be annotated. The winding order is not checked
+// since it is not meaningful for ways.
+func Way(w *osm.Way) []Problem {
+	ring := make(orb.Ring, 0, len(w.Nodes))
+	for _, n := range w.Nodes {
+		if n.Lat == 0 && n.Lon == 0 {
+			return []Problem{{Type: MissingLocation}}
+		}
+
+		ring = append(ring, orb.Point{n.Lon, n.Lat})
+	}
+
+	return checkRing(ring, 0, 0)
+}
+
+// Ring checks the ring for problems. The winding order is not checked.
+func Ring(r orb.Ring) []Problem {
+	return checkRing(r, 0, 0)
+}
+
+// Polygon checks the rings of the polygon. The outer ring should be
+// counter-clockwise and the inner rings clockwise, the right-hand rule.
+// If the FixWinding option is set, the rings with the wrong winding order
+// are reversed in place and not reported.
+func Polygon(p orb.Polygon, opts ...Option) []Problem {
+	o := newOptions(opts)
+	return checkPolygon(p, 0, o, nil)
+}
+
+// MultiPolygon checks the rings of every polygon. Duplicate rings are
+// checked across all the polygons.
+func MultiPolygon(mp orb.MultiPolygon, opts ...Option) []Problem {
+	o := newOptions(opts)
+
+	var problems []Problem
+	var seen []orb.Ring
+	for i, p := range mp {
+		problems = append(problems, checkPolygon(p, i, o, &seen)...)
+	}
+
+	return problems
+}
+
+func checkPolygon(p orb.Polygon, pi int, o *options, seen *[]orb.Ring) []Problem {
+	if seen == nil {
+		seen = &[]orb.Ring{}
+	}
+
+	var problems []Problem
+	for ri, r := range p {
+		if len(r) == 0 {
+			// missing outer ring, see osmgeojson.IncludeInvalidPolygons
+			continue
+		}
+
+		problems = append(problems, checkRing(r, pi, ri)...)
+
+		for _, s := range *seen {
+			if sameRing(r, s) {
+				problems = append(problems, Problem{Type: DuplicateRing, Polygon: pi, Ring: ri, Point: r[0]})
+				break
+			}
+		}
+		*seen = append(*seen, r)
+
+		expected := orb.CW
+		if ri == 0 {
+			expected = orb.CCW
+		}
+
+		if orient := r.Orientation(); orient != 0 && orient != expected {
+			if o.fixWinding {
+				r.Reverse()
+				continue
+			}
+
+			problems = append(problems, Problem{Type: WrongWinding, Polygon: pi, Ring: ri, Point: r[0]})
+		}
+	}
+
+	return problems
+}
+
+func checkRing(r orb.Ring, pi, ri int) []Problem {
+	problem := func(t ProblemType, p orb.Point) Problem {
+		return Problem{Type: t, Polygon: pi, Ring: ri, Point: p}
+	}
+
+	if len(r) < 4 {
+		var p orb.Point
+		if len(r) > 0 {
+			p = r[0]
+		}
+
+		return []Problem{problem(TooFewPoints, p)}
+	}
+
+	if !r.Closed() {
+		return []Problem{problem(NotClosed, r[len(r)-1])}
+	}
+
+	var problems []Problem
+
+	// without the repeated closing point
+	pts := r[:len(r)-1]
+	n := len(pts)
+	for i := range pts {
+		a, b, c := pts[(i+n-1)%n], pts[i], pts[(i+1)%n]
+		if isSpike(a, b, c) {
+			problems = append(problems, problem(Spike, b))
+		}
+	}
+
+	// O(n^2), but rings are usually small
+	reported := map[orb.Point]bool{}
+	for i := 0; i < n; i++ {
+		a1, a2 := pts[i], pts[(i+1)%n]
+		for j := i + 2; j < n; j++ {
+			if i == 0 && j == n-1 {
+				// first and last segments share the closing point
+				continue
+			}
+
+			b1, b2 := pts[j], pts[(j+1)%n]
+			if p, ok := intersection(a1, a2, b1, b2); ok && !reported[p] {
+				reported[p] = true
+				problems = append(problems, problem(SelfIntersection, p))
+			}
+		}
+	}
+
+	return problems
+}
+
+// isSpike returns true if the path a->b->c turns back on itself at b.
+func isSpike(a, b, c orb.Point) bool {
+	if a == b || b == c {
+		return false
+	}
+
+	if cross(a, b, c) != 0 {
+		return false
+	}
+
+	// collinear, pointing in opposite directions
+	return (b[0]-a[0])*(c[0]-b[0])+(b[1]-a[1])*(c[1]-b[1]) < 0
+}
+
+// intersection returns a point where the segments a1-a2 and b1-b2 touch.
+func intersection(a1, a2, b1, b2 orb.Point) (orb.Point, bool) {
+	d1 := cross(b1, b2, a1)
+	d2 := cross(b1, b2, a2)
+	d3 := cross(a1, a2, b1)
+	d4 := cross(a1, a2, b2)
+
+	if ((d1 > 0 && d2 < 0) || (d1 < 0 && d2 > 0)) &&
+		((d3 > 0 && d4 < 0) || (d3 < 0 && d4 > 0)) {
+		t := d1 / (d1 - d2)
+		return orb.Point{
+			a1[0] + t*(a2[0]-a1[0]),
+			a1[1] + t*(a2[1]-a1[1]),
+		}, true
+	}
+
+	switch {
+	case d1 == 0 && onSegment(b1, b2, a1):
+		return a1, true
+	case d2 == 0 && onSegment(b1, b2, a2):
+		return a2, true
+	case d3 == 0 && onSegment(a1, a2, b1):
+		return b1, true
+	case d4 == 0 && onSegment(a1, a2, b2):
+		return b2, true
+	}
+
+	return orb.Point{}, false
+}
+
+// cross is the z component of (b-a) x (c-a).
+func cross(a, b, c orb.Point) float64 {
+	return (b[0]-a[0])*(c[1]-a[1]) - (b[1]-a[1])*(c[0]-a[0])
+}
+
+// onSegment returns true if the collinear point p is within the bounds of a-b.
+func onSegment(a, b, p orb.Point) bool {
+	return min(a[0], b[0]) <= p[0] && p[0] <= max(a[0], b[0]) &&
+		min(a[1], b[1]) <= p[1] && p[1] <= max(a[1], b[1])
+}
+
+// sameRing returns true if the rings have the same points, in either
+// direction and starting at any point.
+func sameRing(a, b orb.Ring) bool {
+	if len(a) != len(b) || len(a) < 2 || !a.Closed() || !b.Closed() {
+		return false
+	}
+
+	pa, pb := a[:len(a)-1], b[:len(b)-1]
+	n := len(pa)
+	for offset := 0; offset < n; offset++ {
+		if pa[0] != pb[offset] {
+			continue
+		}
+
+		forward, backward := true, true
+		for i := 0; i < n && (forward || backward); i++ {
+			if pa[i] != pb[(offset+i)%n] {
+				forward = false
+			}
+
+			if pa[i] != pb[(offset-i+n)%n] {
+				backward = false
+			}
+		}
+
+		if forward || backward {
+			return true
+		}
+	}
+
+	return false
+}
+
+func min(a, b float64) float64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max(a, b float64) float64 {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,162 @@
+package validate
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/osm"
+)
+
+func TestRing(t *testing.T) {
+	cases := []struct {
+		name     string
+		ring     orb.Ring
+		expected []Problem
+	}{
+		{
+			name: "valid",
+			ring: orb.Ring{{0, 0}, {1, 0}, {1, 1}, {0, 1}, {0, 0}},
+		},
+		{
+			name:     "too few points",
+			ring:     orb.Ring{{0, 0}, {1, 0}, {0, 0}},
+			expected: []Problem{{Type: TooFewPoints, Point: orb.Point{0, 0}}},
+		},
+		{
+			name:     "not closed",
+			ring:     orb.Ring{{0, 0}, {1, 0}, {1, 1}, {0, 1}},
+			expected: []Problem{{Type: NotClosed, Point: orb.Point{0, 1}}},
+		},
+		{
+			name:     "bow tie",
+			ring:     orb.Ring{{0, 0}, {2, 2}, {2, 0}, {0, 2}, {0, 0}},
+			expected: []Problem{{Type: SelfIntersection, Point: orb.Point{1, 1}}},
+		},
+		{
+			name:     "touching",
+			ring:     orb.Ring{{0, 0}, {2, 0}, {1, 1}, {2, 2}, {0, 2}, {1, 1}, {0, 0}},
+			expected: []Problem{{Type: SelfIntersection, Point: orb.Point{1, 1}}},
+		},
+		{
+			name: "spike",
+			ring: orb.Ring{{0, 0}, {2, 0}, {3, 0}, {2, 0}, {2, 2}, {0, 2}, {0, 0}},
+			expected: []Problem{
+				{Type: Spike, Point: orb.Point{3, 0}},
+				{Type: SelfIntersection, Point: orb.Point{2, 0}},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			problems := Ring(tc.ring)
+			if !reflect.DeepEqual(problems, tc.expected) {
+				t.Errorf("incorrect problems: %v", problems)
+			}
+		})
+	}
+}
+
+func TestWay(t *testing.T) {
+	w := &osm.Way{
+		ID: 1,
+		Nodes: osm.WayNodes{
+			{ID: 1, Lon: 1, Lat: 1},
+			{ID: 2, Lon: 2, Lat: 2},
+			{ID: 3, Lon: 1, Lat: 2},
+			{ID: 4, Lon: 2, Lat: 1},
+			{ID: 1, Lon: 1, Lat: 1},
+		},
+	}
+
+	problems := Way(w)
+	expected := []Problem{{Type: SelfIntersection, Point: orb.Point{1.5, 1.5}}}
+	if !reflect.DeepEqual(problems, expected) {
+		t.Errorf("incorrect problems: %v", problems)
+	}
+
+	w.Nodes[1].Lat, w.Nodes[1].Lon = 0, 0
+	problems = Way(w)
+	expected = []Problem{{Type: MissingLocation}}
+	if !reflect.DeepEqual(problems, expected) {
+		t.Errorf("incorrect problems: %v", problems)
+	}
+}
+
+func TestPolygon(t *testing.T) {
+	outer := func() orb.Ring { return orb.Ring{{0, 0}, {4, 0}, {4, 4}, {0, 4}, {0, 0}} }
+	inner := func() orb.Ring { return orb.Ring{{1, 1}, {1, 2}, {2, 2}, {2, 1}, {1, 1}} }
+
+	p := orb.Polygon{outer(), inner()}
+	if problems := Polygon(p); len(problems) != 0 {
+		t.Errorf("should be valid: %v", problems)
+	}
+
+	// reversed rings
+	p = orb.Polygon{outer(), inner()}
+	p[0].Reverse()
+	p[1].Reverse()
+
+	problems := Polygon(p)
+	expected := []Problem{
+		{Type: WrongWinding, Ring: 0, Point: orb.Point{0, 0}},
+		{Type: WrongWinding, Ring: 1, Point: orb.Point{1, 1}},
+	}
+	if !reflect.DeepEqual(problems, expected) {
+		t.Errorf("incorrect problems: %v", problems)
+	}
+
+	if problems := Polygon(p, FixWinding(true)); len(problems) != 0 {
+		t.Errorf("should fix winding: %v", problems)
+	}
+
+	if !reflect.DeepEqual(p, orb.Polygon{outer(), inner()}) {
+		t.Errorf("should reverse the rings: %v", p)
+	}
+
+	// duplicate inner ring, starting at a different point
+	dup := orb.Ring{{2, 2}, {2, 1}, {1, 1}, {1, 2}, {2, 2}}
+	problems = Polygon(orb.Polygon{outer(), inner(), dup})
+	expected = []Problem{{Type: DuplicateRing, Ring: 2, Point: orb.Point{2, 2}}}
+	if !reflect.DeepEqual(problems, expected) {
+		t.Errorf("incorrect problems: %v", problems)
+	}
+}
+
+func TestMultiPolygon(t *testing.T) {
+	ring := orb.Ring{{0, 0}, {1, 0}, {1, 1}, {0, 1}, {0, 0}}
+	reversed := orb.Ring{{0, 0}, {0, 1}, {1, 1}, {1, 0}, {0, 0}}
+
+	mp := orb.MultiPolygon{{ring}, {nil, reversed}}
+	problems := MultiPolygon(mp)
+
+	expected := []Problem{{Type: DuplicateRing, Polygon: 1, Ring: 1, Point: orb.Point{0, 0}}}
+	if !reflect.DeepEqual(problems, expected) {
+		t.Errorf("incorrect problems: %v", problems)
+	}
+}
+
+func TestSameRing(t *testing.T) {
+	a := orb.Ring{{0, 0}, {1, 0}, {1, 1}, {0, 0}}
+
+	cases := []struct {
+		name string
+		b    orb.Ring
+		same bool
+	}{
+		{name: "same", b: orb.Ring{{0, 0}, {1, 0}, {1, 1}, {0, 0}}, same: true},
+		{name: "rotated", b: orb.Ring{{1, 0}, {1, 1}, {0, 0}, {1, 0}}, same: true},
+		{name: "reversed", b: orb.Ring{{1, 1}, {1, 0}, {0, 0}, {1, 1}}, same: true},
+		{name: "different", b: orb.Ring{{0, 0}, {2, 0}, {1, 1}, {0, 0}}, same: false},
+		{name: "length", b: orb.Ring{{0, 0}, {1, 0}, {0, 0}}, same: false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if v := sameRing(a, tc.b); v != tc.same {
+				t.Errorf("incorrect result: %v", v)
+			}
+		})
+	}
+}
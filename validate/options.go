@@ -0,0 +1,35 @@
+package validate
+
+// An Option is a setting for the checks.
+type Option func(*options)
+
+type options struct {
+	fixWinding bool
+	epsilon    float64
+}
+
+// FixWinding will reverse the polygon rings with the wrong winding order,
+// in place, instead of reporting them.
+func FixWinding(yes bool) Option {
+	return func(o *options) {
+		o.fixWinding = yes
+	}
+}
+
+// Epsilon is the distance, in meters, within which nodes are considered
+// to be at the same location when looking for duplicates.
+// The default is 0, only exactly stacked nodes.
+func Epsilon(meters float64) Option {
+	return func(o *options) {
+		o.epsilon = meters
+	}
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
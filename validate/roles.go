@@ -0,0 +1,156 @@
+package validate
+
+import (
+	"github.com/paulmach/osm"
+)
+
+// A RoleSchema defines the member roles allowed in a type of relation.
+type RoleSchema struct {
+	// Roles are the allowed roles for every member type. Members of
+	// a type not in the map are not allowed. Use "" for the empty role.
+	Roles map[osm.Type][]string
+
+	// Required roles must be used by at least one member.
+	Required []string
+}
+
+// RoleSchemas are the role schemas keyed by the relation type tag.
+type RoleSchemas map[string]*RoleSchema
+
+// DefaultRoleSchemas are the schemas for the common relation types.
+// It can be copied and extended with custom schemas.
+var DefaultRoleSchemas = RoleSchemas{
+	"multipolygon": {
+		Roles: map[osm.Type][]string{
+			osm.TypeWay: {"outer", "inner"},
+		},
+		Required: []string{"outer"},
+	},
+	"boundary": {
+		Roles: map[osm.Type][]string{
+			osm.TypeNode:     {"admin_centre", "label"},
+			osm.TypeWay:      {"outer", "inner"},
+			osm.TypeRelation: {"subarea"},
+		},
+		Required: []string{"outer"},
+	},
+	"restriction": {
+		Roles: map[osm.Type][]string{
+			osm.TypeNode: {"via"},
+			osm.TypeWay:  {"from", "via", "to"},
+		},
+		Required: []string{"from", "via", "to"},
+	},
+	"route": {
+		Roles: map[osm.Type][]string{
+			osm.TypeNode: {
+				"stop", "stop_entry_only", "stop_exit_only",
+				"platform", "platform_entry_only", "platform_exit_only",
+			},
+			osm.TypeWay: {
+				"", "forward", "backward",
+				"platform", "platform_entry_only", "platform_exit_only",
+			},
+			osm.TypeRelation: {"platform"},
+		},
+	},
+}
+
+// ViolationReason is why a relation does not match its role schema.
+type ViolationReason string
+
+// The reasons for a role violation.
+const (
+	// UnknownRole is a member with a role not allowed by the schema.
+	UnknownRole ViolationReason = "unknown_role"
+
+	// WrongMemberType is a member with a known role
+	// that is not allowed for the type of member.
+	WrongMemberType ViolationReason = "wrong_member_type"
+
+	// MissingRole is a required role not used by any member.
+	MissingRole ViolationReason = "missing_role"
+)
+
+// A RoleViolation is a member, or relation, that does not match
+// the role schema.
+type RoleViolation struct {
+	RelationID osm.RelationID
+	Reason     ViolationReason
+
+	// Index is the index of the member, it is -1 for missing roles.
+	Index int
+	Role  string
+	Type  osm.Type
+}
+
+// Roles checks the roles of the relation members using the default schemas.
+// Returns nil if the relation type does not have a schema.
+func Roles(r *osm.Relation) []RoleViolation {
+	return DefaultRoleSchemas.Validate(r)
+}
+
+// Validate checks the roles of the relation members using the schema
+// for the relation type. Returns nil if the relation type does not
+// have a schema.
+func (s RoleSchemas) Validate(r *osm.Relation) []RoleViolation {
+	schema := s[r.Tags.Find("type")]
+	if schema == nil {
+		return nil
+	}
+
+	return schema.Validate(r)
+}
+
+// Validate checks the roles of the relation members.
+func (s *RoleSchema) Validate(r *osm.Relation) []RoleViolation {
+	var violations []RoleViolation
+
+	used := make(map[string]bool, len(s.Required))
+	for i, m := range r.Members {
+		used[m.Role] = true
+
+		if contains(s.Roles[m.Type], m.Role) {
+			continue
+		}
+
+		reason := UnknownRole
+		for _, roles := range s.Roles {
+			if contains(roles, m.Role) {
+				reason = WrongMemberType
+				break
+			}
+		}
+
+		violations = append(violations, RoleViolation{
+			RelationID: r.ID,
+			Reason:     reason,
+			Index:      i,
+			Role:       m.Role,
+			Type:       m.Type,
+		})
+	}
+
+	for _, role := range s.Required {
+		if !used[role] {
+			violations = append(violations, RoleViolation{
+				RelationID: r.ID,
+				Reason:     MissingRole,
+				Index:      -1,
+				Role:       role,
+			})
+		}
+	}
+
+	return violations
+}
+
+func contains(roles []string, role string) bool {
+	for _, r := range roles {
+		if r == role {
+			return true
+		}
+	}
+
+	return false
+}
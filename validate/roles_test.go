@@ -0,0 +1,140 @@
+package validate
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/paulmach/osm"
+)
+
+func TestRoles(t *testing.T) {
+	cases := []struct {
+		name     string
+		relation *osm.Relation
+		expected []RoleViolation
+	}{
+		{
+			name: "valid multipolygon",
+			relation: &osm.Relation{
+				ID:   1,
+				Tags: osm.Tags{{Key: "type", Value: "multipolygon"}},
+				Members: osm.Members{
+					{Type: osm.TypeWay, Ref: 1, Role: "outer"},
+					{Type: osm.TypeWay, Ref: 2, Role: "inner"},
+				},
+			},
+		},
+		{
+			name: "multipolygon problems",
+			relation: &osm.Relation{
+				ID:   1,
+				Tags: osm.Tags{{Key: "type", Value: "multipolygon"}},
+				Members: osm.Members{
+					{Type: osm.TypeWay, Ref: 1, Role: ""},
+					{Type: osm.TypeNode, Ref: 2, Role: "inner"},
+				},
+			},
+			expected: []RoleViolation{
+				{RelationID: 1, Reason: UnknownRole, Index: 0, Role: "", Type: osm.TypeWay},
+				{RelationID: 1, Reason: WrongMemberType, Index: 1, Role: "inner", Type: osm.TypeNode},
+				{RelationID: 1, Reason: MissingRole, Index: -1, Role: "outer"},
+			},
+		},
+		{
+			name: "valid restriction",
+			relation: &osm.Relation{
+				ID:   2,
+				Tags: osm.Tags{{Key: "type", Value: "restriction"}},
+				Members: osm.Members{
+					{Type: osm.TypeWay, Ref: 1, Role: "from"},
+					{Type: osm.TypeNode, Ref: 2, Role: "via"},
+					{Type: osm.TypeWay, Ref: 3, Role: "to"},
+				},
+			},
+		},
+		{
+			name: "restriction missing to",
+			relation: &osm.Relation{
+				ID:   2,
+				Tags: osm.Tags{{Key: "type", Value: "restriction"}},
+				Members: osm.Members{
+					{Type: osm.TypeWay, Ref: 1, Role: "from"},
+					{Type: osm.TypeNode, Ref: 2, Role: "via"},
+					{Type: osm.TypeNode, Ref: 3, Role: "to"},
+				},
+			},
+			expected: []RoleViolation{
+				{RelationID: 2, Reason: WrongMemberType, Index: 2, Role: "to", Type: osm.TypeNode},
+			},
+		},
+		{
+			name: "route",
+			relation: &osm.Relation{
+				ID:   3,
+				Tags: osm.Tags{{Key: "type", Value: "route"}},
+				Members: osm.Members{
+					{Type: osm.TypeNode, Ref: 1, Role: "stop"},
+					{Type: osm.TypeWay, Ref: 2, Role: "platform"},
+					{Type: osm.TypeWay, Ref: 3, Role: ""},
+					{Type: osm.TypeWay, Ref: 4, Role: "forward"},
+					{Type: osm.TypeWay, Ref: 5, Role: "stop"},
+					{Type: osm.TypeWay, Ref: 6, Role: "bogus"},
+				},
+			},
+			expected: []RoleViolation{
+				{RelationID: 3, Reason: WrongMemberType, Index: 4, Role: "stop", Type: osm.TypeWay},
+				{RelationID: 3, Reason: UnknownRole, Index: 5, Role: "bogus", Type: osm.TypeWay},
+			},
+		},
+		{
+			name: "no schema",
+			relation: &osm.Relation{
+				ID:      4,
+				Tags:    osm.Tags{{Key: "type", Value: "site"}},
+				Members: osm.Members{{Type: osm.TypeWay, Ref: 1, Role: "anything"}},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			violations := Roles(tc.relation)
+			if !reflect.DeepEqual(violations, tc.expected) {
+				t.Errorf("incorrect violations: %v", violations)
+			}
+		})
+	}
+}
+
+func TestRoleSchemas_custom(t *testing.T) {
+	schemas := RoleSchemas{}
+	for k, v := range DefaultRoleSchemas {
+		schemas[k] = v
+	}
+
+	schemas["site"] = &RoleSchema{
+		Roles: map[osm.Type][]string{
+			osm.TypeNode: {"entrance", "label"},
+			osm.TypeWay:  {"perimeter", ""},
+		},
+		Required: []string{"perimeter"},
+	}
+
+	r := &osm.Relation{
+		ID:   1,
+		Tags: osm.Tags{{Key: "type", Value: "site"}},
+		Members: osm.Members{
+			{Type: osm.TypeNode, Ref: 1, Role: "entrance"},
+			{Type: osm.TypeRelation, Ref: 2, Role: ""},
+		},
+	}
+
+	violations := schemas.Validate(r)
+	expected := []RoleViolation{
+		{RelationID: 1, Reason: WrongMemberType, Index: 1, Role: "", Type: osm.TypeRelation},
+		{RelationID: 1, Reason: MissingRole, Index: -1, Role: "perimeter"},
+	}
+	if !reflect.DeepEqual(violations, expected) {
+		t.Errorf("incorrect violations: %v", violations)
+	}
+}
@@ -2,6 +2,7 @@ package osm
 
 import (
 	"encoding/json"
+	"encoding/xml"
 	"math"
 	"sort"
 	"time"
@@ -38,6 +39,7 @@ type Way struct {
 	Version     int                `xml:"version,attr" json:"version,omitempty"`
 	ChangesetID ChangesetID        `xml:"changeset,attr" json:"changeset,omitempty"`
 	Timestamp   time.Time          `xml:"timestamp,attr" json:"timestamp"`
+	Redacted    RedactionID        `xml:"redacted,attr,omitempty" json:"redacted,omitempty"`
 	Nodes       WayNodes           `xml:"nd" json:"nodes"`
 	Tags        Tags               `xml:"tag" json:"tags,omitempty"`
 
@@ -52,6 +54,10 @@ type Way struct {
 
 	// Bounds are included by overpass, and maybe others
 	Bounds *Bounds `xml:"bounds,omitempty" json:"bounds,omitempty"`
+	// XMLExtra are the xml attributes and child elements that are not
+	// part of the osm format. They are only set by the osmxml scanner
+	// with the PreserveUnknown option and written out again by MarshalXML.
+	XMLExtra *XMLExtra `xml:"-" json:"-"`
 }
 
 // WayNodes represents a collection of way nodes.
@@ -198,6 +204,80 @@ func (w *Way) LineStringAt(t time.Time) orb.LineString {
 	return ls[:count]
 }
 
+// MarshalXML implements the xml.Marshaler interface to write out the
+// XMLExtra attributes and elements with the way. The attributes of
+// the start element are kept so wrappers can add their own.
+func (w Way) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	start.Name = xml.Name{Local: "way"}
+
+	type plain Way
+	if w.XMLExtra == nil {
+		return e.EncodeElement(plain(w), start)
+	}
+
+	return e.EncodeElement(struct {
+		plain
+		*XMLExtra
+	}{plain(w), w.XMLExtra}, start)
+}
+
+// Copy returns a deep copy of the way, the nodes, tags
+// and updates are not shared.
+func (w *Way) Copy() *Way {
+	c := *w
+	c.Tags = w.Tags.copy()
+	c.Updates = w.Updates.copy()
+	c.Committed = copyTimePtr(w.Committed)
+	c.XMLExtra = w.XMLExtra.copy()
+	c.Bounds = copyBounds(w.Bounds)
+
+	if w.Nodes != nil {
+		c.Nodes = append(WayNodes(nil), w.Nodes...)
+	}
+
+	return &c
+}
+
+// Equal compares the ways. The tags are compared ignoring their order.
+// Use the IgnoreMetadata option to only compare the data, way nodes
+// are then compared using only their ids.
+func (w *Way) Equal(o *Way, opts ...EqualOption) bool {
+	if w == nil || o == nil {
+		return w == o
+	}
+
+	if w.ID != o.ID || w.Visible != o.Visible ||
+		len(w.Nodes) != len(o.Nodes) || !equalTags(w.Tags, o.Tags) {
+		return false
+	}
+
+	if newEqualOptions(opts).ignoreMetadata {
+		for i := range w.Nodes {
+			if w.Nodes[i].ID != o.Nodes[i].ID {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	for i := range w.Nodes {
+		if w.Nodes[i] != o.Nodes[i] {
+			return false
+		}
+	}
+
+	return w.User == o.User &&
+		w.UserID == o.UserID &&
+		w.Version == o.Version &&
+		w.ChangesetID == o.ChangesetID &&
+		w.Timestamp.Equal(o.Timestamp) &&
+		w.Redacted == o.Redacted &&
+		equalTimePtr(w.Committed, o.Committed) &&
+		equalUpdates(w.Updates, o.Updates) &&
+		equalBounds(w.Bounds, o.Bounds)
+}
+
 // Bounds computes the bounds for the given way nodes.
 func (wn WayNodes) Bounds() *Bounds {
 	b := &Bounds{
@@ -270,6 +350,43 @@ func (wn WayNodes) NodeIDs() []NodeID {
 	return ids
 }
 
+// Clean returns the way nodes without consecutive duplicate node ids and
+// zero length segments, consecutive nodes at the same location. Locations
+// are only compared if both nodes have one, e.g. after annotation.
+// The first node of a run is kept, except at the end of the way where the
+// last node is kept so closed ways stay closed. The result may have less
+// than 2 nodes, making the way invalid. The original way nodes are not modified.
+func (wn WayNodes) Clean() WayNodes {
+	if len(wn) == 0 {
+		return wn
+	}
+
+	result := make(WayNodes, 0, len(wn))
+	result = append(result, wn[0])
+	for i := 1; i < len(wn); i++ {
+		prev := result[len(result)-1]
+		if !sameWayNode(prev, wn[i]) {
+			result = append(result, wn[i])
+			continue
+		}
+
+		if i == len(wn)-1 && len(result) > 1 {
+			result[len(result)-1] = wn[i]
+		}
+	}
+
+	return result
+}
+
+func sameWayNode(a, b WayNode) bool {
+	if a.ID == b.ID {
+		return true
+	}
+
+	hasLoc := func(n WayNode) bool { return n.Lat != 0 || n.Lon != 0 }
+	return hasLoc(a) && hasLoc(b) && a.Lat == b.Lat && a.Lon == b.Lon
+}
+
 // MarshalJSON allows the waynodes to be marshalled as an array of ids,
 // as defined by the overpass osmjson.
 func (wn WayNodes) MarshalJSON() ([]byte, error) {
@@ -332,13 +449,31 @@ func (ws Ways) ElementIDs() ElementIDs {
 	return r
 }
 
+// Marshal encodes the way using protocol buffers. The encoding is smaller
+// than a list of one way so it is useful for values in key value stores.
+// It can be decoded using UnmarshalWay.
+func (w *Way) Marshal(opts ...MarshalOption) ([]byte, error) {
+	return marshalElement(w, opts)
+}
+
+// UnmarshalWay will unmarshal the data, encoded by Way.Marshal,
+// into a single way.
+func UnmarshalWay(data []byte) (*Way, error) {
+	o, err := unmarshalElement(data, TypeWay)
+	if err != nil {
+		return nil, err
+	}
+
+	return o.Ways[0], nil
+}
+
 // Marshal encodes the ways using protocol buffers.
-func (ws Ways) Marshal() ([]byte, error) {
+func (ws Ways) Marshal(opts ...MarshalOption) ([]byte, error) {
 	o := OSM{
 		Ways: ws,
 	}
 
-	return o.Marshal()
+	return o.Marshal(opts...)
 }
 
 // UnmarshalWays will unmarshal the data into a list of ways.
@@ -0,0 +1,106 @@
+package osm
+
+import "strings"
+
+// Reverse reverses the order of the way nodes and flips the direction
+// dependent tags so the way keeps the same meaning. Naively reversing
+// the nodes of a oneway street, for example, would reverse the traffic flow.
+// The following tags are updated:
+//   - oneway and oneway:* values yes/1/true become -1 and -1 becomes yes
+//   - incline values up/down are swapped and slopes, like 5%, are negated
+//   - forward/backward and left/right key parts are swapped,
+//     e.g. maxspeed:forward, turn:lanes:backward and sidewalk:left
+//   - sidewalk values left/right are swapped
+//
+// The way is modified in place, use Copy first to keep the original.
+// The forward/backward roles of the way in route relations are not updated.
+func (w *Way) Reverse() {
+	for i, j := 0, len(w.Nodes)-1; i < j; i, j = i+1, j-1 {
+		w.Nodes[i], w.Nodes[j] = w.Nodes[j], w.Nodes[i]
+	}
+
+	for i := range w.Updates {
+		w.Updates[i].Index = len(w.Nodes) - 1 - w.Updates[i].Index
+	}
+
+	for i, t := range w.Tags {
+		w.Tags[i] = reverseTag(t)
+	}
+}
+
+var reverseKeyParts = map[string]string{
+	"forward":  "backward",
+	"backward": "forward",
+	"left":     "right",
+	"right":    "left",
+}
+
+func reverseTag(t Tag) Tag {
+	key := t.Key
+	if strings.Contains(key, ":") {
+		parts := strings.Split(key, ":")
+		for i, p := range parts {
+			if r, ok := reverseKeyParts[p]; ok {
+				parts[i] = r
+			}
+		}
+		key = strings.Join(parts, ":")
+	}
+
+	// the value is reversed based on the original key
+	// so oneway:backward=yes becomes oneway:forward=-1.
+	base := t.Key
+	if i := strings.IndexByte(base, ':'); i > 0 {
+		base = base[:i]
+	}
+
+	value := t.Value
+	switch base {
+	case "oneway":
+		value = reverseOneway(value)
+	case "incline":
+		value = reverseIncline(value)
+	case "sidewalk":
+		if value == "left" || value == "right" {
+			value = reverseKeyParts[value]
+		}
+	}
+
+	return Tag{Key: key, Value: value}
+}
+
+func reverseOneway(v string) string {
+	switch v {
+	case "yes", "1", "true":
+		return "-1"
+	case "-1":
+		return "yes"
+	}
+
+	return v
+}
+
+func reverseIncline(v string) string {
+	switch v {
+	case "up":
+		return "down"
+	case "down":
+		return "up"
+	case "":
+		return v
+	}
+
+	if !strings.HasSuffix(v, "%") && !strings.HasSuffix(v, "°") {
+		return v
+	}
+
+	if v[0] == '-' {
+		return v[1:]
+	}
+
+	if strings.Trim(v, "0.%°") == "" {
+		return v
+	}
+
+	return "-" + v
+}
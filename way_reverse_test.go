@@ -0,0 +1,125 @@
+package osm
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWay_Reverse(t *testing.T) {
+	w := &Way{
+		ID:    1,
+		Nodes: WayNodes{{ID: 1}, {ID: 2}, {ID: 3}},
+		Tags: Tags{
+			{Key: "highway", Value: "residential"},
+			{Key: "oneway", Value: "yes"},
+			{Key: "maxspeed:forward", Value: "50"},
+			{Key: "turn:lanes:backward", Value: "left|through"},
+			{Key: "sidewalk", Value: "left"},
+		},
+		Updates: Updates{{Index: 0}, {Index: 2}},
+	}
+
+	w.Reverse()
+
+	if ids := w.Nodes.NodeIDs(); !reflect.DeepEqual(ids, []NodeID{3, 2, 1}) {
+		t.Errorf("incorrect nodes: %v", ids)
+	}
+
+	if w.Updates[0].Index != 2 || w.Updates[1].Index != 0 {
+		t.Errorf("incorrect update indexes: %v", w.Updates)
+	}
+
+	expected := Tags{
+		{Key: "highway", Value: "residential"},
+		{Key: "oneway", Value: "-1"},
+		{Key: "maxspeed:backward", Value: "50"},
+		{Key: "turn:lanes:forward", Value: "left|through"},
+		{Key: "sidewalk", Value: "right"},
+	}
+	if !reflect.DeepEqual(w.Tags, expected) {
+		t.Errorf("incorrect tags: %v", w.Tags)
+	}
+
+	// reversing twice should return the original
+	w.Reverse()
+	if w.Tags.Find("oneway") != "yes" || w.Tags.Find("maxspeed:forward") != "50" {
+		t.Errorf("incorrect double reverse: %v", w.Tags)
+	}
+}
+
+func TestReverseTag(t *testing.T) {
+	cases := []struct {
+		name     string
+		tag      Tag
+		expected Tag
+	}{
+		{
+			name:     "oneway -1",
+			tag:      Tag{Key: "oneway", Value: "-1"},
+			expected: Tag{Key: "oneway", Value: "yes"},
+		},
+		{
+			name:     "oneway no",
+			tag:      Tag{Key: "oneway", Value: "no"},
+			expected: Tag{Key: "oneway", Value: "no"},
+		},
+		{
+			name:     "oneway reversible",
+			tag:      Tag{Key: "oneway", Value: "reversible"},
+			expected: Tag{Key: "oneway", Value: "reversible"},
+		},
+		{
+			name:     "oneway bicycle",
+			tag:      Tag{Key: "oneway:bicycle", Value: "1"},
+			expected: Tag{Key: "oneway:bicycle", Value: "-1"},
+		},
+		{
+			name:     "incline up",
+			tag:      Tag{Key: "incline", Value: "up"},
+			expected: Tag{Key: "incline", Value: "down"},
+		},
+		{
+			name:     "incline percent",
+			tag:      Tag{Key: "incline", Value: "12%"},
+			expected: Tag{Key: "incline", Value: "-12%"},
+		},
+		{
+			name:     "incline negative degrees",
+			tag:      Tag{Key: "incline", Value: "-5°"},
+			expected: Tag{Key: "incline", Value: "5°"},
+		},
+		{
+			name:     "incline zero",
+			tag:      Tag{Key: "incline", Value: "0%"},
+			expected: Tag{Key: "incline", Value: "0%"},
+		},
+		{
+			name:     "incline yes",
+			tag:      Tag{Key: "incline", Value: "yes"},
+			expected: Tag{Key: "incline", Value: "yes"},
+		},
+		{
+			name:     "side key",
+			tag:      Tag{Key: "cycleway:right", Value: "lane"},
+			expected: Tag{Key: "cycleway:left", Value: "lane"},
+		},
+		{
+			name:     "forward lanes",
+			tag:      Tag{Key: "lanes:forward", Value: "2"},
+			expected: Tag{Key: "lanes:backward", Value: "2"},
+		},
+		{
+			name:     "unrelated",
+			tag:      Tag{Key: "name", Value: "left"},
+			expected: Tag{Key: "name", Value: "left"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if v := reverseTag(tc.tag); v != tc.expected {
+				t.Errorf("incorrect tag: %v", v)
+			}
+		})
+	}
+}
@@ -156,6 +156,71 @@ func TestWayNodes_Bound(t *testing.T) {
 	}
 }
 
+func TestWayNodes_Clean(t *testing.T) {
+	cases := []struct {
+		name     string
+		input    WayNodes
+		expected WayNodes
+	}{
+		{
+			name:     "empty",
+			input:    WayNodes{},
+			expected: WayNodes{},
+		},
+		{
+			name:     "no duplicates",
+			input:    WayNodes{{ID: 1}, {ID: 2}, {ID: 3}},
+			expected: WayNodes{{ID: 1}, {ID: 2}, {ID: 3}},
+		},
+		{
+			name:     "consecutive duplicates",
+			input:    WayNodes{{ID: 1}, {ID: 1}, {ID: 2}, {ID: 2}, {ID: 2}, {ID: 3}},
+			expected: WayNodes{{ID: 1}, {ID: 2}, {ID: 3}},
+		},
+		{
+			name:     "closed way stays closed",
+			input:    WayNodes{{ID: 1}, {ID: 2}, {ID: 3}, {ID: 1}},
+			expected: WayNodes{{ID: 1}, {ID: 2}, {ID: 3}, {ID: 1}},
+		},
+		{
+			name:     "zero length segment",
+			input:    WayNodes{{ID: 1, Lat: 1, Lon: 1}, {ID: 2, Lat: 1, Lon: 1}, {ID: 3, Lat: 2, Lon: 2}},
+			expected: WayNodes{{ID: 1, Lat: 1, Lon: 1}, {ID: 3, Lat: 2, Lon: 2}},
+		},
+		{
+			name:     "zero length segment at the end",
+			input:    WayNodes{{ID: 1, Lat: 1, Lon: 1}, {ID: 2, Lat: 2, Lon: 2}, {ID: 3, Lat: 1, Lon: 1}, {ID: 1, Lat: 1, Lon: 1}},
+			expected: WayNodes{{ID: 1, Lat: 1, Lon: 1}, {ID: 2, Lat: 2, Lon: 2}, {ID: 1, Lat: 1, Lon: 1}},
+		},
+		{
+			name:     "no locations",
+			input:    WayNodes{{ID: 1}, {ID: 2, Lat: 0, Lon: 0}},
+			expected: WayNodes{{ID: 1}, {ID: 2}},
+		},
+		{
+			name:     "becomes invalid",
+			input:    WayNodes{{ID: 1}, {ID: 1}},
+			expected: WayNodes{{ID: 1}},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			input := make(WayNodes, len(tc.input))
+			copy(input, tc.input)
+
+			result := tc.input.Clean()
+			if !reflect.DeepEqual(result, tc.expected) {
+				t.Errorf("incorrect result: %v", result)
+			}
+
+			if !reflect.DeepEqual(input, tc.input) {
+				t.Errorf("should not modify input")
+			}
+		})
+	}
+}
+
 func TestWay_LineString(t *testing.T) {
 	w := &Way{
 		ID: 1,
@@ -453,3 +518,61 @@ func TestWays_SortByIDVersion(t *testing.T) {
 		t.Errorf("incorrect sort: %v", eids)
 	}
 }
+
+func TestWay_Copy(t *testing.T) {
+	w := &Way{
+		ID:      1,
+		Nodes:   WayNodes{{ID: 1}, {ID: 2}},
+		Tags:    Tags{{Key: "a", Value: "b"}},
+		Updates: Updates{{Index: 0}},
+		Bounds:  &Bounds{MinLat: 1},
+	}
+
+	c := w.Copy()
+	if !reflect.DeepEqual(c, w) {
+		t.Errorf("copy should be equal")
+	}
+
+	c.Nodes[0].ID = 3
+	c.Tags[0].Value = "c"
+	c.Updates[0].Index = 1
+	c.Bounds.MinLat = 2
+	if w.Nodes[0].ID != 1 || w.Tags[0].Value != "b" || w.Updates[0].Index != 0 || w.Bounds.MinLat != 1 {
+		t.Errorf("should not share data with the copy")
+	}
+}
+
+func TestWay_Equal(t *testing.T) {
+	w := &Way{
+		ID:      1,
+		Version: 2,
+		Nodes:   WayNodes{{ID: 1}, {ID: 2}},
+		Tags:    Tags{{Key: "a", Value: "b"}},
+	}
+
+	if !w.Equal(w.Copy()) {
+		t.Errorf("copy should be equal")
+	}
+
+	c := w.Copy()
+	c.Nodes[0].Lat = 10
+	c.Updates = Updates{{Index: 1}}
+	if w.Equal(c) {
+		t.Errorf("should compare annotations and updates")
+	}
+
+	if !w.Equal(c, IgnoreMetadata()) {
+		t.Errorf("should ignore annotations and updates")
+	}
+
+	c.Nodes[1].ID = 3
+	if w.Equal(c, IgnoreMetadata()) {
+		t.Errorf("should compare node ids")
+	}
+
+	c = w.Copy()
+	c.Nodes = c.Nodes[:1]
+	if w.Equal(c, IgnoreMetadata()) {
+		t.Errorf("should compare number of nodes")
+	}
+}
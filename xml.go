@@ -0,0 +1,52 @@
+package osm
+
+import "encoding/xml"
+
+// XMLExtra are the xml attributes and child elements of a node, way or
+// relation that are not part of the osm format, for example vendor
+// extensions. They are kept so they can be written out again.
+type XMLExtra struct {
+	Attrs    []xml.Attr   `xml:",any,attr"`
+	Elements []XMLElement `xml:",any"`
+}
+
+// An XMLElement is an xml child element that is not part of the osm format,
+// for example a vendor extension. It is kept so it can be written out again.
+type XMLElement struct {
+	XMLName  xml.Name
+	Attrs    []xml.Attr `xml:",any,attr"`
+	InnerXML string     `xml:",innerxml"`
+}
+
+func (x *XMLExtra) copy() *XMLExtra {
+	if x == nil {
+		return nil
+	}
+
+	return &XMLExtra{
+		Attrs:    copyXMLAttrs(x.Attrs),
+		Elements: copyXMLElements(x.Elements),
+	}
+}
+
+func copyXMLAttrs(attrs []xml.Attr) []xml.Attr {
+	if attrs == nil {
+		return nil
+	}
+
+	return append([]xml.Attr(nil), attrs...)
+}
+
+func copyXMLElements(elements []XMLElement) []XMLElement {
+	if elements == nil {
+		return nil
+	}
+
+	result := make([]XMLElement, len(elements))
+	for i, e := range elements {
+		result[i] = e
+		result[i].Attrs = copyXMLAttrs(e.Attrs)
+	}
+
+	return result
+}